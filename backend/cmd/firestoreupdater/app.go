@@ -3,9 +3,11 @@ package firestoreupdater
 import (
 	"cloud.google.com/go/firestore"
 	"github.com/NathanielJBrown97/LeeTutoringApp/internal/config"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/students"
 )
 
 type App struct {
 	Config          *config.Config
 	FirestoreClient *firestore.Client
+	Students        students.Repository
 }
@@ -9,6 +9,7 @@ import (
 	"os"
 
 	"cloud.google.com/go/firestore"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/students"
 )
 
 type StudentData struct {
@@ -65,64 +66,47 @@ func InitializeNewStudent(w http.ResponseWriter, r *http.Request) {
 	}
 	defer client.Close()
 
-	// Generate a unique ID for the new student document
-	docRef := client.Collection("students").NewDoc()
-	studentID := docRef.ID
-
-	// Log the generated student ID
-	log.Printf("Generated student ID: %s", studentID)
-
-	// Prepare 'personal' data
-	personalData := map[string]interface{}{
-		"name":           studentData.Name,
-		"student_email":  studentData.StudentEmail,
-		"student_number": studentData.StudentNumber,
-		"parent_email":   studentData.ParentEmail,
-		"parent_number":  studentData.ParentNumber,
-		"high_school":    studentData.School,
-		"grade":          studentData.Grade,
-		"accommodations": studentData.Accommodations,
-		"interests":      studentData.Interests,
-		// "firestore_id":   studentID, // Optionally include firestore_id
-	}
-
-	// Prepare 'test_appointment' data
-	testAppointmentData := map[string]interface{}{
-		"registered_for_test": studentData.RegisteredForTest,
-		"test_date":           studentData.TestDate,
-	}
-
-	// Prepare 'business' data
-	businessData := map[string]interface{}{
-		"firebase_id":       studentID,
-		"scheduler":         studentData.Scheduler,
-		"test_focus":        studentData.TestFocus,
-		"test_appointment":  testAppointmentData,
-		"associated_tutors": []string{}, // Initialize as empty array
-		"team_lead":         "",         // Initialize as empty string
-		"remaining_hours":   0,          // Initialize as zero
-		"lifetime_hours":    0,          // Initialize as zero
-		"classroom_id":      studentData.ClassroomID,
-		"drive_url":         studentData.DriveURL,
-	}
-
-	// Combine 'personal' and 'business' into the student document
-	studentDocData := map[string]interface{}{
-		"personal": personalData,
-		"business": businessData,
+	// Build the student document; the repository generates the ID and
+	// fills in business.firebase_id.
+	student := &students.Student{
+		Personal: students.Personal{
+			Name:           studentData.Name,
+			StudentEmail:   studentData.StudentEmail,
+			StudentNumber:  studentData.StudentNumber,
+			ParentEmail:    studentData.ParentEmail,
+			ParentNumber:   studentData.ParentNumber,
+			HighSchool:     studentData.School,
+			Grade:          studentData.Grade,
+			Accommodations: studentData.Accommodations,
+			Interests:      studentData.Interests,
+		},
+		Business: students.Business{
+			Scheduler: studentData.Scheduler,
+			TestFocus: studentData.TestFocus,
+			TestAppointment: students.TestAppointment{
+				RegisteredForTest: studentData.RegisteredForTest,
+				TestDate:          studentData.TestDate,
+			},
+			AssociatedTutors: []string{}, // Initialize as empty array
+			ClassroomID:      studentData.ClassroomID,
+			DriveURL:         studentData.DriveURL,
+		},
 	}
 
 	// Log the data to be written to Firestore
-	log.Printf("studentDocData to be written: %+v", studentDocData)
+	log.Printf("student to be written: %+v", student)
 
 	// Write the student document to Firestore
-	_, err = docRef.Set(ctx, studentDocData)
+	studentID, err := students.NewFirestoreRepository(client).Create(ctx, student)
 	if err != nil {
 		log.Printf("Failed to save student data: %v", err)
 		http.Error(w, "Failed to save student data: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	// Log the generated student ID
+	log.Printf("Generated student ID: %s", studentID)
+
 	// Prepare the JSON response
 	responseData := ResponseData{
 		Message:   fmt.Sprintf("Student %s initialized successfully", studentData.Name),
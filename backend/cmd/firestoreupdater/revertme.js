@@ -212,10 +212,15 @@ function onFormSubmit(e) {
       "test_date": student_data["registered_tests"] || null
     };
   
-    // Set the options for the POST request
+    // Set the options for the POST request. The backend only accepts calls
+    // with an API key (scope "firestoreupdater"), kept in the script
+    // properties as API_KEY.
     let options = {
       'method': 'post',
       'contentType': 'application/json',
+      'headers': {
+        'X-API-Key': PropertiesService.getScriptProperties().getProperty('API_KEY')
+      },
       'payload': JSON.stringify(payload)
     };
   
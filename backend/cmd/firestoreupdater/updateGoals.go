@@ -3,10 +3,12 @@ package firestoreupdater
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 
-	"google.golang.org/api/iterator"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/students"
 	"google.golang.org/api/sheets/v4"
 )
 
@@ -25,12 +27,10 @@ func (app *App) UpdateGoalsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	ctx := context.Background()
-	client := app.FirestoreClient
 
 	// Find the student in 'students' collection where 'personal.name' == payload.StudentName
-	iter := client.Collection("students").Where("personal.name", "==", payload.StudentName).Documents(ctx)
-	doc, err := iter.Next()
-	if err == iterator.Done {
+	student, err := app.Students.FindByName(ctx, payload.StudentName)
+	if errors.Is(err, students.ErrNotFound) {
 		http.Error(w, "Student not found", http.StatusNotFound)
 		return
 	}
@@ -40,11 +40,6 @@ func (app *App) UpdateGoalsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	studentDocRef := doc.Ref
-
-	// Access the 'Goals' subcollection
-	goalsCollection := studentDocRef.Collection("Goals")
-
 	// Set up Google Sheets client
 	sheetsService, err := sheets.NewService(ctx)
 	if err != nil {
@@ -66,7 +61,7 @@ func (app *App) UpdateGoalsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Build a map from school name to the data
-	schoolDataMap := make(map[string]map[string]interface{})
+	schoolDataMap := make(map[string]students.Goal)
 
 	for _, row := range resp.Values {
 		if len(row) == 0 {
@@ -80,30 +75,29 @@ func (app *App) UpdateGoalsHandler(w http.ResponseWriter, r *http.Request) {
 		// Columns B,C,D: ACT 25th,50th,75th percentiles
 		// Columns F,G,H: SAT 25th,50th,75th percentiles
 
-		// Initialize the percentiles slices with nil values
-		actPercentiles := []interface{}{nil, nil, nil}
-		satPercentiles := []interface{}{nil, nil, nil}
+		// Initialize the percentiles slices with empty values
+		actPercentiles := []string{"", "", ""}
+		satPercentiles := []string{"", "", ""}
 
 		// Handle ACT percentiles
 		for i := 1; i <= 3; i++ {
 			if len(row) > i {
-				actPercentileValue := row[i]
-				actPercentiles[i-1] = actPercentileValue
+				actPercentiles[i-1] = fmt.Sprint(row[i])
 			}
 		}
 
 		// Handle SAT percentiles
 		for i := 5; i <= 7; i++ {
 			if len(row) > i {
-				satPercentileValue := row[i]
-				satPercentiles[i-5] = satPercentileValue
+				satPercentiles[i-5] = fmt.Sprint(row[i])
 			}
 		}
 
-		schoolDataMap[name] = map[string]interface{}{
-			"university":      name,
-			"ACT_percentiles": actPercentiles,
-			"SAT_percentiles": satPercentiles,
+		schoolDataMap[name] = students.Goal{
+			ID:             name,
+			College:        name,
+			ACTPercentiles: actPercentiles,
+			SATPercentiles: satPercentiles,
 		}
 	}
 
@@ -117,10 +111,7 @@ func (app *App) UpdateGoalsHandler(w http.ResponseWriter, r *http.Request) {
 		}
 
 		// Create or update the document in 'Goals' subcollection
-		docRef := goalsCollection.Doc(schoolName)
-
-		_, err = docRef.Set(ctx, schoolData)
-		if err != nil {
+		if err := app.Students.SaveGoal(ctx, student.ID, schoolData); err != nil {
 			log.Printf("Failed to write to Firestore for school %s: %v", schoolName, err)
 			continue // Or handle error as needed
 		}
@@ -3,13 +3,13 @@ package firestoreupdater
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
-	"strings"
 	"time"
 
-	"google.golang.org/api/iterator"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/students"
 )
 
 // HomeworkPayload is the JSON body we expect.
@@ -45,15 +45,10 @@ func (app *App) UpdateHomeworkCompletionHandler(w http.ResponseWriter, r *http.R
 	log.Printf("Received homework completion data: %+v\n", payload)
 
 	ctx := context.Background()
-	client := app.FirestoreClient
 
 	// 1) Query 'students' collection to find the doc with the matching name
-	iter := client.Collection("students").
-		Where("personal.name", "==", payload.StudentName).
-		Documents(ctx)
-
-	doc, err := iter.Next()
-	if err == iterator.Done {
+	student, err := app.Students.FindByName(ctx, payload.StudentName)
+	if errors.Is(err, students.ErrNotFound) {
 		http.Error(w, "Student not found", http.StatusNotFound)
 		return
 	}
@@ -63,26 +58,20 @@ func (app *App) UpdateHomeworkCompletionHandler(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	// 2) Access the subcollection "Homework Completion"
-	studentDocRef := doc.Ref
-	homeworkCollection := studentDocRef.Collection("Homework Completion")
-
-	// 3) Create a doc name from the date by replacing / with -
-	docName := strings.ReplaceAll(payload.Date, "/", "-")
-
-	// 4) Exactly these 7 fields (no extra, no less)
-	data := map[string]interface{}{
-		"date":                payload.Date,
-		"percentage_complete": payload.Percentage,
-		"tutor":               payload.Tutor,
-		"duration":            payload.Duration,
-		"attendance":          payload.Attendance,
-		"feedback":            payload.Feedback,
-		"timestamp":           time.Now().Format(time.RFC3339), // store as string
+	// 2) Store the session in "Homework Completion" under the date with dashes,
+	//    with exactly these 7 fields (no extra, no less)
+	hw := students.HomeworkCompletion{
+		ID:                 students.HomeworkDocID(payload.Date),
+		Date:               payload.Date,
+		PercentageComplete: payload.Percentage,
+		Tutor:              payload.Tutor,
+		Duration:           payload.Duration,
+		Attendance:         payload.Attendance,
+		Feedback:           payload.Feedback,
+		Timestamp:          time.Now().Format(time.RFC3339), // store as string
 	}
 
-	_, err = homeworkCollection.Doc(docName).Set(ctx, data)
-	if err != nil {
+	if err := app.Students.SaveHomework(ctx, student.ID, hw); err != nil {
 		log.Printf("Failed to write to Firestore: %v", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
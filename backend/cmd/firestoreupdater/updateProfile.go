@@ -3,12 +3,11 @@ package firestoreupdater
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 
-	"cloud.google.com/go/firestore"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/students"
 )
 
 type ProfileData struct {
@@ -38,15 +37,11 @@ func (app *App) UpdateProfileHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	ctx := context.Background()
-	client := app.FirestoreClient
 
 	// Use the FirebaseID as the document ID
-	studentDocRef := client.Collection("students").Doc(profileData.FirebaseID)
-
-	// Check if the document exists
-	docSnap, err := studentDocRef.Get(ctx)
+	student, err := app.Students.Get(ctx, profileData.FirebaseID)
 	if err != nil {
-		if status.Code(err) == codes.NotFound {
+		if errors.Is(err, students.ErrNotFound) {
 			http.Error(w, "Student not found", http.StatusNotFound)
 			return
 		}
@@ -55,11 +50,6 @@ func (app *App) UpdateProfileHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !docSnap.Exists() {
-		http.Error(w, "Student not found", http.StatusNotFound)
-		return
-	}
-
 	// Handle test appointment logic
 	registeredForTest := false
 	testDate := ""
@@ -74,28 +64,33 @@ func (app *App) UpdateProfileHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	// If empty, also false and no date (already defaulted)
 
-	// Prepare the list of updates
-	updates := []firestore.Update{
-		// personal fields
-		{Path: "personal.student_email", Value: profileData.StudentEmail},
-		{Path: "personal.parent_email", Value: profileData.ParentEmail},
-		{Path: "personal.name", Value: profileData.Name},
-		{Path: "personal.high_school", Value: profileData.HighSchool},
-		{Path: "personal.grade", Value: profileData.Grade},
-		{Path: "personal.accommodations", Value: profileData.Accommodations},
-		{Path: "personal.interests", Value: profileData.Interests},
-		// availability not stored yet
+	// personal fields; the phone numbers are not on the sheet and are kept
+	personal := student.Personal
+	personal.StudentEmail = profileData.StudentEmail
+	personal.ParentEmail = profileData.ParentEmail
+	personal.Name = profileData.Name
+	personal.HighSchool = profileData.HighSchool
+	personal.Grade = profileData.Grade
+	personal.Accommodations = profileData.Accommodations
+	personal.Interests = profileData.Interests
+	// availability not stored yet
 
-		// business fields
-		{Path: "business.test_focus", Value: profileData.TestFocus},
-		{Path: "business.scheduler", Value: profileData.WhoSchedules},
-		{Path: "business.notes", Value: profileData.Notes},
-		{Path: "business.test_appointment.registered_for_test", Value: registeredForTest},
-		{Path: "business.test_appointment.test_date", Value: testDate},
+	// business fields
+	details := student.Business.Details()
+	details.TestFocus = profileData.TestFocus
+	details.Scheduler = profileData.WhoSchedules
+	details.Notes = profileData.Notes
+	details.TestAppointment = students.TestAppointment{
+		RegisteredForTest: registeredForTest,
+		TestDate:          testDate,
 	}
 
-	_, err = studentDocRef.Update(ctx, updates)
-	if err != nil {
+	if err := app.Students.UpdatePersonal(ctx, student.ID, personal); err != nil {
+		log.Printf("Failed to update profile data in Firestore: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if err := app.Students.UpdateBusinessDetails(ctx, student.ID, details); err != nil {
 		log.Printf("Failed to update profile data in Firestore: %v", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
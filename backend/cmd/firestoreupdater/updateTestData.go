@@ -3,12 +3,15 @@ package firestoreupdater
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
-	"google.golang.org/api/iterator"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/students"
 )
 
 type TestData struct {
@@ -30,12 +33,10 @@ func (app *App) UpdateTestDataHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	ctx := context.Background()
-	client := app.FirestoreClient
 
 	// Query the 'students' collection for the student with matching name
-	iter := client.Collection("students").Where("personal.name", "==", testData.StudentName).Documents(ctx)
-	doc, err := iter.Next()
-	if err == iterator.Done {
+	student, err := app.Students.FindByName(ctx, testData.StudentName)
+	if errors.Is(err, students.ErrNotFound) {
 		http.Error(w, "Student not found", http.StatusNotFound)
 		return
 	}
@@ -45,87 +46,40 @@ func (app *App) UpdateTestDataHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	studentDocRef := doc.Ref
-
-	// Access the 'Test Data' subcollection
-	testDataCollection := studentDocRef.Collection("Test Data")
-
-	// Create document ID with quality prefix
-	docID := fmt.Sprintf("%s %s %s", testData.Quality, testData.Test, testData.Date)
-
-	// Initialize data to store
-	data := map[string]interface{}{
-		"date":       testData.Date,
-		"test":       testData.Test,
-		"type":       testData.Quality,
-		"baseline":   testData.Baseline,
-		"updated_at": time.Now().Format(time.RFC3339),
-	}
-
-	// Prepare scores subdocuments
-	actScores := map[string]interface{}{
-		"English":   nil,
-		"Math":      nil,
-		"Reading":   nil,
-		"Science":   nil,
-		"ACT_Total": nil,
-	}
-	satScores := map[string]interface{}{
-		"EBRW":      nil,
-		"Math":      nil,
-		"Reading":   nil,
-		"Writing":   nil,
-		"SAT_Total": nil,
+	// Both score subdocuments are always written; only the one matching the test is filled in.
+	td := students.TestData{
+		ID:        fmt.Sprintf("%s %s %s", testData.Quality, testData.Test, testData.Date),
+		Date:      testData.Date,
+		Test:      testData.Test,
+		Type:      testData.Quality,
+		Baseline:  testData.Baseline,
+		UpdatedAt: time.Now().Format(time.RFC3339),
+		ACTScores: &students.ACTScores{},
+		SATScores: &students.SATScores{},
 	}
 
 	scores := testData.Scores
 
 	if testData.Test == "ACT" {
-		// Populate ACT_Scores
-		if english, ok := scores["english"]; ok {
-			actScores["English"] = english
+		td.ACTScores = &students.ACTScores{
+			English: scoreValue(scores["english"]),
+			Math:    scoreValue(scores["math"]),
+			Reading: scoreValue(scores["reading"]),
+			Science: scoreValue(scores["science"]),
+			Total:   scoreValue(scores["actTotal"]),
 		}
-		if math, ok := scores["math"]; ok {
-			actScores["Math"] = math
-		}
-		if reading, ok := scores["reading"]; ok {
-			actScores["Reading"] = reading
-		}
-		if science, ok := scores["science"]; ok {
-			actScores["Science"] = science
-		}
-
-		if actTotal, ok := scores["actTotal"].(float64); ok {
-			actScores["ACT_Total"] = actTotal
-		}
-
 	} else if testData.Test == "SAT" || testData.Test == "PSAT" {
-		// Populate SAT_Scores
-		if ebrw, ok := scores["ebrw"]; ok {
-			satScores["EBRW"] = ebrw
-		}
-		if math, ok := scores["math"]; ok {
-			satScores["Math"] = math
-		}
-		if reading, ok := scores["reading"]; ok {
-			satScores["Reading"] = reading
-		}
-		if writing, ok := scores["writing"]; ok {
-			satScores["Writing"] = writing
-		}
-
-		if satTotal, ok := scores["satTotal"].(float64); ok {
-			satScores["SAT_Total"] = satTotal
+		td.SATScores = &students.SATScores{
+			EBRW:    scoreValue(scores["ebrw"]),
+			Math:    scoreValue(scores["math"]),
+			Reading: scoreValue(scores["reading"]),
+			Writing: scoreValue(scores["writing"]),
+			Total:   scoreValue(scores["satTotal"]),
 		}
 	}
 
-	// Add scores subdocuments to data
-	data["ACT_Scores"] = actScores
-	data["SAT_Scores"] = satScores
-
-	// Create or update the document
-	_, err = testDataCollection.Doc(docID).Set(ctx, data)
-	if err != nil {
+	// Create or update the document in the 'Test Data' subcollection
+	if err := app.Students.SaveTestData(ctx, student.ID, td); err != nil {
 		log.Printf("Failed to write to Firestore: %v", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
@@ -134,3 +88,20 @@ func (app *App) UpdateTestDataHandler(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("Success"))
 }
+
+// scoreValue reads a section score sent by the sheet, which may be a number or
+// a numeric string. Anything else is treated as no score.
+func scoreValue(v interface{}) *float64 {
+	switch t := v.(type) {
+	case float64:
+		return &t
+	case string:
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(t), 64)
+		if err != nil {
+			return nil
+		}
+		return &parsed
+	default:
+		return nil
+	}
+}
@@ -3,13 +3,14 @@ package firestoreupdater
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
 	"strings"
 
-	"google.golang.org/api/iterator"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/students"
 	"google.golang.org/api/option"
 	"google.golang.org/api/sheets/v4"
 )
@@ -90,46 +91,32 @@ func (app *App) processSpreadsheet(spreadsheetID string, sheetName string) error
 		scoreReleaseDate := fmt.Sprintf("%v", row[4])
 
 		// Construct document name and data
-		docName := fmt.Sprintf("%s %s", testType, sanitizedDate)
-		data := map[string]interface{}{
-			"Test Type":                     testType,
-			"Test Date":                     sanitizedDate,
-			"Regular Registration Deadline": regDeadline,
-			"Late Registration Deadline":    lateRegDeadline,
-			"Score Release Date":            scoreReleaseDate,
-			"Notes":                         "", // Initialize to blank or retain existing
+		testDateDoc := students.TestDate{
+			ID:                          fmt.Sprintf("%s %s", testType, sanitizedDate),
+			TestType:                    testType,
+			TestDate:                    sanitizedDate,
+			RegularRegistrationDeadline: regDeadline,
+			LateRegistrationDeadline:    lateRegDeadline,
+			ScoreReleaseDate:            scoreReleaseDate,
+			Notes:                       "", // Initialize to blank; existing documents are not touched
 		}
 
 		// Iterate over each student
-		iter := app.FirestoreClient.Collection("students").Documents(ctx)
-		for {
-			doc, err := iter.Next()
-			if err == iterator.Done {
-				break
-			}
-			if err != nil {
-				log.Printf("Failed to iterate students: %v", err)
-				break
-			}
-
-			studentID := doc.Ref.ID
-
-			// Reference to the student's 'Test Dates' subcollection
-			subDocRef := doc.Ref.Collection("Test Dates").Doc(docName)
-
-			// Check if the document already exists
-			_, err = subDocRef.Get(ctx)
-			if err != nil {
-				// Document does not exist, create it
-				_, err = subDocRef.Set(ctx, data)
-				if err != nil {
-					log.Printf("Failed to create document '%s' for student '%s': %v", docName, studentID, err)
-				} else {
-					log.Printf("Created document '%s' for student '%s'", docName, studentID)
-				}
-			} else {
-				// Document exists, skip
-				log.Printf("Document '%s' already exists for student '%s', skipping", docName, studentID)
+		all, err := app.Students.List(ctx)
+		if err != nil {
+			log.Printf("Failed to iterate students: %v", err)
+			continue
+		}
+		for _, student := range all {
+			// Create the document unless the student already has it
+			err := app.Students.CreateTestDate(ctx, student.ID, testDateDoc)
+			switch {
+			case errors.Is(err, students.ErrExists):
+				log.Printf("Document '%s' already exists for student '%s', skipping", testDateDoc.ID, student.ID)
+			case err != nil:
+				log.Printf("Failed to create document '%s' for student '%s': %v", testDateDoc.ID, student.ID, err)
+			default:
+				log.Printf("Created document '%s' for student '%s'", testDateDoc.ID, student.ID)
 			}
 		}
 	}
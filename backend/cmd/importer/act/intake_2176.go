@@ -10,37 +10,13 @@ import (
 	"strings"
 
 	"cloud.google.com/go/firestore"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/students"
 	"github.com/joho/godotenv"
 	"google.golang.org/api/drive/v3"
-	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 	"google.golang.org/api/sheets/v4"
 )
 
-// Iterate over each student in Firestore before querying Google Drive
-func iterateStudentsFromDB(firestoreClient *firestore.Client, ctx context.Context) ([]*firestore.DocumentSnapshot, error) {
-	iter := firestoreClient.Collection("students").Documents(ctx)
-	defer iter.Stop()
-
-	var studentDocs []*firestore.DocumentSnapshot
-
-	// Fetch each student document
-	for {
-		doc, err := iter.Next()
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			log.Printf("Error iterating through students: %v", err)
-			return nil, err
-		}
-
-		studentDocs = append(studentDocs, doc)
-	}
-
-	return studentDocs, nil
-}
-
 func main() {
 	// Load environment variables from the .env file located one directory up
 	err := godotenv.Load("../.env")
@@ -90,6 +66,12 @@ func main() {
 	}
 	defer firestoreClient.Close()
 
+	// Load the students once; folders are matched to them by name
+	allStudents, err := students.NewFirestoreRepository(firestoreClient).List(ctx)
+	if err != nil {
+		log.Fatalf("Error listing students: %v", err)
+	}
+
 	// List all subfolders in the root folder
 	subFolderIDs, err := listSubFolders(driveService, rootFolderID)
 	if err != nil {
@@ -123,12 +105,13 @@ func main() {
 
 		log.Printf("Successfully fetched ACT scores for folder ID %s: %v", folderID, scores)
 
-		// Retrieve the student reference from Firestore based on folder details
-		studentRef, err := getStudentRef(firestoreClient, ctx, folderID, driveService)
+		// Find the student whose name matches the folder
+		studentID, err := findStudentID(allStudents, folderID, driveService)
 		if err != nil {
 			log.Printf("Error retrieving student document for folder ID %s: %v", folderID, err)
 			continue
 		}
+		studentRef := firestoreClient.Collection("students").Doc(studentID)
 
 		// Update Firestore with ACT scores
 		err = updateACTScores(firestoreClient, ctx, studentRef, scores)
@@ -193,54 +176,35 @@ func findAnswerSheet(driveService *drive.Service, testPrepFolderID string) (stri
 	return sheets.Files[0].Id, nil
 }
 
-// Retrieve the student reference from Firestore by iterating through each document in the 'students' collection
-func getStudentRef(firestoreClient *firestore.Client, ctx context.Context, folderID string, driveService *drive.Service) (*firestore.DocumentRef, error) {
+// findStudentID returns the ID of the student whose name matches the name of the folder
+func findStudentID(all []*students.Student, folderID string, driveService *drive.Service) (string, error) {
 	// Fetch the folder details, ensuring all drives are included
 	file, err := driveService.Files.Get(folderID).SupportsAllDrives(true).Fields("name").Do()
 	if err != nil {
 		log.Printf("Error retrieving folder details for folder ID %s: %v", folderID, err)
-		return nil, err
+		return "", err
 	}
 
 	folderName := strings.TrimSpace(file.Name)
 	log.Printf("Processing folder: %s", folderName)
 
-	// Retrieve all documents in the 'students' collection
-	iter := firestoreClient.Collection("students").Documents(ctx)
-	defer iter.Stop()
-
-	for {
-		doc, err := iter.Next()
-		if err != nil {
-			if err == iterator.Done {
-				break
-			}
-			log.Printf("Error iterating through students collection: %v", err)
-			return nil, err
-		}
-
-		// Access the 'personal' sub-document and check the 'name' field
-		personal, ok := doc.Data()["personal"].(map[string]interface{})
-		if !ok {
-			log.Printf("No 'personal' sub-document found for document ID: %s", doc.Ref.ID)
-			continue
-		}
+	if student := matchStudent(all, folderName); student != nil {
+		log.Printf("Found matching student document for folder name %s: Document ID %s", folderName, student.ID)
+		return student.ID, nil
+	}
 
-		studentName, ok := personal["name"].(string)
-		if !ok {
-			log.Printf("No 'name' field found in 'personal' sub-document for document ID: %s", doc.Ref.ID)
-			continue
-		}
+	log.Printf("No matching student document found for folder ID %s with name %s", folderID, folderName)
+	return "", errors.New("no matching student document found")
+}
 
-		// Check if the student name matches the folder name
-		if strings.EqualFold(studentName, folderName) {
-			log.Printf("Found matching student document for folder name %s: Document ID %s", folderName, doc.Ref.ID)
-			return doc.Ref, nil
+// matchStudent returns the student whose name matches folderName, ignoring case, or nil
+func matchStudent(all []*students.Student, folderName string) *students.Student {
+	for _, student := range all {
+		if student.Personal.Name != "" && strings.EqualFold(student.Personal.Name, folderName) {
+			return student
 		}
 	}
-
-	log.Printf("No matching student document found for folder ID %s with name %s", folderID, folderName)
-	return nil, errors.New("no matching student document found")
+	return nil
 }
 
 // Fetch ACT scores from the '2176' tab in the Google Sheet
@@ -284,7 +248,8 @@ func fetchACTScores(sheetService *sheets.Service, sheetID string) ([]int64, erro
 	return scores, nil
 }
 
-// Update Firestore with ACT scores in the 'tests' sub-document
+// Update Firestore with ACT scores in the 'tests' sub-document. This legacy
+// subcollection is not part of the students model, so it is written directly.
 func updateACTScores(firestoreClient *firestore.Client, ctx context.Context, studentRef *firestore.DocumentRef, scores []int64) error {
 	_, err := studentRef.Collection("tests").Doc("most_recent_act").Set(ctx, map[string]interface{}{
 		"most_recent_act": scores,
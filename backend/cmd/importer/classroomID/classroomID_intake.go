@@ -9,23 +9,15 @@ import (
 	"time"
 
 	"cloud.google.com/go/firestore"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/students"
 	"github.com/joho/godotenv"
-	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 	"google.golang.org/api/sheets/v4"
 )
 
 // FirestoreUpdater handles Firestore operations
 type FirestoreUpdater struct {
-	Client *firestore.Client
-}
-
-// StudentDocument represents the structure of a student document in Firestore
-type StudentDocument struct {
-	Business struct {
-		DriveURL    string `firestore:"drive_url"`
-		ClassroomID string `firestore:"classroom_id"`
-	} `firestore:"business"`
+	Students students.Repository
 }
 
 func main() {
@@ -64,7 +56,7 @@ func main() {
 	}
 
 	// Initialize FirestoreUpdater
-	fu := FirestoreUpdater{Client: firestoreClient}
+	fu := FirestoreUpdater{Students: students.NewFirestoreRepository(firestoreClient)}
 
 	// Process students
 	err = fu.ProcessStudents(ctx, sheetsService)
@@ -85,48 +77,31 @@ func initializeSheetsService(serviceAccountPath string) (*sheets.Service, error)
 	return sheetsService, nil
 }
 
-// ProcessStudents iterates through the 'students' collection and updates classroom_id
+// ProcessStudents iterates through the students and updates classroom_id
 func (fu *FirestoreUpdater) ProcessStudents(ctx context.Context, sheetsService *sheets.Service) error {
-	// Reference to the 'students' collection
-	studentsCollection := fu.Client.Collection("students")
-
-	// Retrieve all student documents
-	iter := studentsCollection.Documents(ctx)
-	defer iter.Stop()
+	// Retrieve all students
+	all, err := fu.Students.List(ctx)
+	if err != nil {
+		return fmt.Errorf("error listing students: %v", err)
+	}
 
 	count := 0
 	skipped := 0
-	for {
-		doc, err := iter.Next()
-		if err != nil {
-			if err == iterator.Done { // Corrected error check
-				break
-			}
-			return fmt.Errorf("error iterating through students: %v", err)
-		}
-
+	for _, student := range all {
 		count++
 
-		// Parse the student document into StudentDocument struct
-		var student StudentDocument
-		err = doc.DataTo(&student)
-		if err != nil {
-			log.Printf("Error parsing student document ID=%s: %v", doc.Ref.ID, err)
-			continue
-		}
-
 		// Check if 'classroom_id' is already set
 		if student.Business.ClassroomID != "" {
-			log.Printf("Student ID=%s already has 'classroom_id' set to '%s'. Skipping.", doc.Ref.ID, student.Business.ClassroomID)
+			log.Printf("Student ID=%s already has 'classroom_id' set to '%s'. Skipping.", student.ID, student.Business.ClassroomID)
 			skipped++
 			continue
 		}
 
-		log.Printf("Processing student %d: ID=%s", count, doc.Ref.ID)
+		log.Printf("Processing student %d: ID=%s", count, student.ID)
 
 		driveURL := student.Business.DriveURL
 		if driveURL == "" {
-			log.Printf("No 'drive_url' found for student ID=%s. Skipping.", doc.Ref.ID)
+			log.Printf("No 'drive_url' found for student ID=%s. Skipping.", student.ID)
 			continue
 		}
 
@@ -141,23 +116,23 @@ func (fu *FirestoreUpdater) ProcessStudents(ctx context.Context, sheetsService *
 			return innerErr
 		})
 		if err != nil {
-			log.Printf("Error reading classroom ID for student ID=%s after retries: %v", doc.Ref.ID, err)
+			log.Printf("Error reading classroom ID for student ID=%s after retries: %v", student.ID, err)
 			continue
 		}
 
 		if classroomID == "" {
-			log.Printf("No value found in 'data!A1' for student ID=%s. Skipping update.", doc.Ref.ID)
+			log.Printf("No value found in 'data!A1' for student ID=%s. Skipping update.", student.ID)
 			continue
 		}
 
 		// Update the 'classroom_id' field in Firestore
-		err = fu.UpdateClassroomID(ctx, doc.Ref, classroomID)
+		err = fu.Students.SetClassroomID(ctx, student.ID, classroomID)
 		if err != nil {
-			log.Printf("Error updating 'classroom_id' for student ID=%s: %v", doc.Ref.ID, err)
+			log.Printf("Error updating 'classroom_id' for student ID=%s: %v", student.ID, err)
 			continue
 		}
 
-		log.Printf("Successfully updated 'classroom_id' for student ID=%s to '%s'.", doc.Ref.ID, classroomID)
+		log.Printf("Successfully updated 'classroom_id' for student ID=%s to '%s'.", student.ID, classroomID)
 	}
 
 	log.Printf("Processed %d students. Skipped %d students who already had 'classroom_id' set.", count, skipped)
@@ -187,21 +162,6 @@ func readClassroomID(ctx context.Context, sheetsService *sheets.Service, spreads
 	return classroomID, nil
 }
 
-// UpdateClassroomID updates the 'business.classroom_id' field in the student document
-func (fu *FirestoreUpdater) UpdateClassroomID(ctx context.Context, docRef *firestore.DocumentRef, classroomID string) error {
-	_, err := docRef.Update(ctx, []firestore.Update{
-		{
-			Path:  "business.classroom_id",
-			Value: classroomID,
-		},
-	})
-	if err != nil {
-		return fmt.Errorf("failed to update 'classroom_id': %v", err)
-	}
-
-	return nil
-}
-
 // retry executes a function up to 'maxRetries' times with exponential backoff
 func retry(maxRetries int, initialDelay time.Duration, fn func() error) error {
 	delay := initialDelay
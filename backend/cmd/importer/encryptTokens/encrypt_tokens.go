@@ -0,0 +1,143 @@
+package main
+
+// Encrypts the OAuth tokens stored on tutors, parents and students and the
+// QuickBooks tokens in intuit/globalTokens with the primary key in
+// TOKEN_ENCRYPTION_KEYS. Plaintext values written before encryption was turned
+// on are encrypted, and values wrapped with an older key are re-encrypted, so
+// run it once when encryption is first deployed and again after every key
+// rotation before the old key is dropped. Values already under the primary key
+// are left alone, so it is safe to run more than once. Without -apply it only
+// prints what it would do.
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+
+	"cloud.google.com/go/firestore"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/identity"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/tokencrypt"
+	"github.com/joho/godotenv"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+)
+
+func main() {
+	apply := flag.Bool("apply", false, "write the changes instead of printing them")
+	flag.Parse()
+
+	// Load environment variables from the .env file located one directory up
+	err := godotenv.Load("../.env")
+	if err != nil {
+		log.Fatalf("Error loading .env file: %v", err)
+	}
+
+	serviceAccountPath := os.Getenv("SERVICE_ACCOUNT_PATH")
+	if serviceAccountPath == "" {
+		log.Fatal("SERVICE_ACCOUNT_PATH is not set in the environment variables")
+	}
+
+	firestoreProjectID := os.Getenv("FIRESTORE_PROJECT_ID")
+	if firestoreProjectID == "" {
+		log.Fatal("FIRESTORE_PROJECT_ID is not set in the environment variables")
+	}
+
+	keys, err := tokencrypt.ParseKeyring(os.Getenv("TOKEN_ENCRYPTION_KEYS"))
+	if err != nil {
+		log.Fatalf("Failed to load token encryption keys: %v", err)
+	}
+
+	ctx := context.Background()
+	client, err := firestore.NewClient(ctx, firestoreProjectID, option.WithCredentialsFile(serviceAccountPath))
+	if err != nil {
+		log.Fatalf("Failed to create Firestore client: %v", err)
+	}
+	defer client.Close()
+
+	total := 0
+	for _, collection := range []string{identity.TutorsCollection, identity.ParentsCollection, identity.StudentsCollection} {
+		iter := client.Collection(collection).Documents(ctx)
+		for {
+			doc, err := iter.Next()
+			if err == iterator.Done {
+				break
+			}
+			if err != nil {
+				log.Fatalf("Failed to iterate %s: %v", collection, err)
+			}
+
+			var updates []firestore.Update
+			for _, field := range []string{"access_token", "refresh_token"} {
+				value, _ := doc.Data()[field].(string)
+				rewritten, ok := rewrite(keys, value)
+				if !ok {
+					continue
+				}
+				updates = append(updates, firestore.Update{Path: field, Value: rewritten})
+			}
+			if len(updates) == 0 {
+				continue
+			}
+
+			total++
+			log.Printf("%s/%s: %d token(s) to encrypt", collection, doc.Ref.ID, len(updates))
+			if !*apply {
+				continue
+			}
+			if _, err := doc.Ref.Update(ctx, updates); err != nil {
+				log.Fatalf("Failed to update %s/%s: %v", collection, doc.Ref.ID, err)
+			}
+		}
+	}
+
+	// QuickBooks tokens live in the "intuitoauth" map of intuit/globalTokens
+	globalRef := client.Collection("intuit").Doc("globalTokens")
+	globalSnap, err := globalRef.Get(ctx)
+	if err != nil {
+		log.Printf("No QuickBooks tokens to encrypt: %v", err)
+	} else {
+		intuitoauth, _ := globalSnap.Data()["intuitoauth"].(map[string]interface{})
+		var updates []firestore.Update
+		for _, field := range []string{"accessToken", "refreshToken"} {
+			value, _ := intuitoauth[field].(string)
+			rewritten, ok := rewrite(keys, value)
+			if !ok {
+				continue
+			}
+			updates = append(updates, firestore.Update{FieldPath: firestore.FieldPath{"intuitoauth", field}, Value: rewritten})
+		}
+		if len(updates) > 0 {
+			total++
+			log.Printf("intuit/globalTokens: %d token(s) to encrypt", len(updates))
+			if *apply {
+				if _, err := globalRef.Update(ctx, updates); err != nil {
+					log.Fatalf("Failed to update intuit/globalTokens: %v", err)
+				}
+			}
+		}
+	}
+
+	if *apply {
+		log.Printf("Encrypted tokens in %d document(s)", total)
+	} else {
+		log.Printf("%d document(s) have tokens to encrypt; run with -apply to write them", total)
+	}
+}
+
+// rewrite returns value encrypted with the primary key, and false if it is
+// empty or already encrypted with it.
+func rewrite(keys *tokencrypt.Keyring, value string) (string, bool) {
+	if !keys.NeedsRewrite(value) {
+		return "", false
+	}
+	plaintext, err := keys.Decrypt(value)
+	if err != nil {
+		log.Fatalf("Failed to decrypt a stored token: %v", err)
+	}
+	encrypted, err := keys.Encrypt(plaintext)
+	if err != nil {
+		log.Fatalf("Failed to encrypt a stored token: %v", err)
+	}
+	return encrypted, true
+}
@@ -11,27 +11,16 @@ import (
 	"time"
 
 	"cloud.google.com/go/firestore"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/students"
 	"github.com/joho/godotenv"
 	"google.golang.org/api/drive/v3"
-	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 	"google.golang.org/api/sheets/v4"
 )
 
 // FirestoreUpdater handles Firestore operations
 type FirestoreUpdater struct {
-	Client *firestore.Client
-}
-
-// StudentDocument represents the structure of a student document in Firestore
-type StudentDocument struct {
-	Personal struct {
-		Name string `firestore:"name"`
-	} `firestore:"personal"`
-	Business struct {
-		DriveURL    string `firestore:"drive_url"`
-		ClassroomID string `firestore:"classroom_id"`
-	} `firestore:"business"`
+	Students students.Repository
 }
 
 // retry executes a function up to 'maxRetries' times with exponential backoff
@@ -113,7 +102,7 @@ func main() {
 	defer firestoreClient.Close()
 
 	// Initialize FirestoreUpdater
-	fu := FirestoreUpdater{Client: firestoreClient}
+	fu := FirestoreUpdater{Students: students.NewFirestoreRepository(firestoreClient)}
 
 	// Process students
 	err = fu.ProcessStudents(ctx, sheetService, driveService)
@@ -124,41 +113,24 @@ func main() {
 	log.Println("Goals intake process completed.")
 }
 
-// ProcessStudents iterates through the 'students' collection and processes their Goals
+// ProcessStudents iterates through the students and processes their Goals
 func (fu *FirestoreUpdater) ProcessStudents(ctx context.Context, sheetsService *sheets.Service, driveService *drive.Service) error {
-	// Reference to the 'students' collection
-	studentsCollection := fu.Client.Collection("students")
-
-	// Retrieve all student documents
-	iter := studentsCollection.Documents(ctx)
-	defer iter.Stop()
+	// Retrieve all students
+	all, err := fu.Students.List(ctx)
+	if err != nil {
+		return fmt.Errorf("error listing students: %v", err)
+	}
 
 	count := 0
 	skipped := 0
-	for {
-		doc, err := iter.Next()
-		if err != nil {
-			if err == iterator.Done {
-				break
-			}
-			return fmt.Errorf("error iterating through students: %v", err)
-		}
-
+	for _, student := range all {
 		count++
 
-		// Parse the student document into StudentDocument struct
-		var student StudentDocument
-		err = doc.DataTo(&student)
-		if err != nil {
-			log.Printf("Error parsing student document ID=%s: %v", doc.Ref.ID, err)
-			continue
-		}
-
-		log.Printf("Processing student %d: ID=%s, Name=%s", count, doc.Ref.ID, student.Personal.Name)
+		log.Printf("Processing student %d: ID=%s, Name=%s", count, student.ID, student.Personal.Name)
 
 		driveURL := student.Business.DriveURL
 		if driveURL == "" {
-			log.Printf("No 'drive_url' found for student ID=%s. Skipping.", doc.Ref.ID)
+			log.Printf("No 'drive_url' found for student ID=%s. Skipping.", student.ID)
 			skipped++
 			continue
 		}
@@ -167,71 +139,77 @@ func (fu *FirestoreUpdater) ProcessStudents(ctx context.Context, sheetsService *
 		spreadsheetID := driveURL
 
 		// Read data from the student's 'Goals' sheet with retry
-		var goalsDataRows []map[string]interface{}
+		var goals []students.Goal
 		err = retry(5, 1*time.Second, func() error {
 			var innerErr error
-			goalsDataRows, innerErr = fetchGoalsSheetData(sheetsService, spreadsheetID)
+			goals, innerErr = fetchGoalsSheetData(sheetsService, spreadsheetID)
 			return innerErr
 		})
 		if err != nil {
-			log.Printf("Error reading Goals sheet for student ID=%s after retries: %v", doc.Ref.ID, err)
+			log.Printf("Error reading Goals sheet for student ID=%s after retries: %v", student.ID, err)
 			continue
 		}
-		if len(goalsDataRows) == 0 {
-			log.Printf("No data found in Goals sheet for student ID=%s", doc.Ref.ID)
+		if len(goals) == 0 {
+			log.Printf("No data found in Goals sheet for student ID=%s", student.ID)
 			continue
 		}
 
-		// Process and store each row of goals into Firestore with retry
-		for _, goalData := range goalsDataRows {
-			collegeName, ok := goalData["College"].(string)
-			if !ok || collegeName == "" {
-				continue
-			}
+		fu.ImportGoals(ctx, student.ID, goals)
+	}
 
-			// Sanitize the college name to create a valid document ID
-			sanitizedCollegeName := sanitizeForFirestoreID(collegeName)
+	log.Printf("Processed %d students. Skipped %d students.", count, skipped)
+	return nil
+}
 
-			goalsCollection := doc.Ref.Collection("Goals")
-			goalDocRef := goalsCollection.Doc(sanitizedCollegeName)
+// ImportGoals stores each of a student's goals under its sanitized college
+// name, with retry. It returns how many were written.
+func (fu *FirestoreUpdater) ImportGoals(ctx context.Context, studentID string, goals []students.Goal) int {
+	written := 0
+	for _, goal := range goals {
+		if goal.College == "" {
+			continue
+		}
 
-			// Define the write operation as a function to be retried
-			writeOperation := func() error {
-				_, err := goalDocRef.Set(ctx, goalData)
-				if err != nil {
-					return fmt.Errorf("failed to set goal document: %v", err)
-				}
-				return nil
-			}
+		// Sanitize the college name to create a valid document ID
+		goal.ID = sanitizeForFirestoreID(goal.College)
 
-			// Execute the write operation with retry
-			err = retry(5, 1*time.Second, writeOperation)
-			if err != nil {
-				log.Printf("Failed to write goal data for student ID=%s, college %s after retries: %v", doc.Ref.ID, collegeName, err)
-				continue
+		// Execute the write operation with retry
+		err := retry(5, 1*time.Second, func() error {
+			if err := fu.Students.SaveGoal(ctx, studentID, goal); err != nil {
+				return fmt.Errorf("failed to set goal document: %v", err)
 			}
-
-			log.Printf("Successfully wrote goal data for student ID=%s, college %s", doc.Ref.ID, collegeName)
+			return nil
+		})
+		if err != nil {
+			log.Printf("Failed to write goal data for student ID=%s, college %s after retries: %v", studentID, goal.College, err)
+			continue
 		}
-	}
 
-	log.Printf("Processed %d students. Skipped %d students.", count, skipped)
-	return nil
+		log.Printf("Successfully wrote goal data for student ID=%s, college %s", studentID, goal.College)
+		written++
+	}
+	return written
 }
 
-// fetchGoalsSheetData reads data from a student's 'Goals' sheet in Google Sheets
-func fetchGoalsSheetData(sheetService *sheets.Service, sheetID string) ([]map[string]interface{}, error) {
-	var goalsDataRows []map[string]interface{}
-
+// fetchGoalsSheetData reads the goals from a student's 'Goals' sheet in Google Sheets
+func fetchGoalsSheetData(sheetService *sheets.Service, sheetID string) ([]students.Goal, error) {
 	// Read data from the 'Goals' sheet
 	readRange := "Goals!A:G" // Adjusted to read columns A to G
 	resp, err := sheetService.Spreadsheets.Values.Get(sheetID, readRange).Do()
 	if err != nil {
 		return nil, fmt.Errorf("unable to retrieve data from 'Goals' tab: %v", err)
 	}
+	return goalsFromRows(resp.Values), nil
+}
+
+// goalsFromRows reads the rows of a Goals sheet: a college in column A and
+// two sets of 25th, 50th and 75th percentile scores in B-D and E-G, each of
+// which may be ACT or SAT.
+func goalsFromRows(rows [][]interface{}) []students.Goal {
+	var goals []students.Goal
 
 	// Process each row
-	for i, row := range resp.Values {
+	for i, row := range rows {
 		// Skipping header rows if any
 		if i < 2 {
 			continue
@@ -320,10 +298,6 @@ func fetchGoalsSheetData(sheetService *sheets.Service, sheetID string) ([]map[st
 			return countValid >= 2 // At least 2 valid scores
 		}
 
-		data := map[string]interface{}{
-			"College": college,
-		}
-
 		// Initialize empty slices for ACT and SAT percentiles
 		var ACT_percentiles []float64
 		var SAT_percentiles []float64
@@ -350,23 +324,29 @@ func fetchGoalsSheetData(sheetService *sheets.Service, sheetID string) ([]map[st
 			}
 		}
 
-		// Add percentiles to data if they exist
-		if len(ACT_percentiles) > 0 {
-			data["ACT_percentiles"] = ACT_percentiles
-		}
-		if len(SAT_percentiles) > 0 {
-			data["SAT_percentiles"] = SAT_percentiles
-		}
-
 		if len(ACT_percentiles) == 0 && len(SAT_percentiles) == 0 {
 			log.Printf("No valid ACT or SAT scores found for college '%s', skipping", college)
 			continue
 		}
 
-		goalsDataRows = append(goalsDataRows, data)
+		goals = append(goals, students.Goal{
+			College:        college,
+			ACTPercentiles: formatScores(ACT_percentiles),
+			SATPercentiles: formatScores(SAT_percentiles),
+		})
 	}
 
-	return goalsDataRows, nil
+	return goals
+}
+
+// formatScores writes scores the way goals entered on the dashboard store
+// them, e.g. "1450" rather than "1450.000000".
+func formatScores(scores []float64) []string {
+	out := make([]string, 0, len(scores))
+	for _, score := range scores {
+		out = append(out, strconv.FormatFloat(score, 'f', -1, 64))
+	}
+	return out
 }
 
 // sanitizeForFirestoreID sanitizes a string to be a valid Firestore document ID
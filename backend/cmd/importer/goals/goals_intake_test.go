@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/students"
+)
+
+func TestImportGoals(t *testing.T) {
+	ctx := context.Background()
+	repo := students.NewMemoryRepository()
+	id, err := repo.Create(ctx, &students.Student{Personal: students.Personal{Name: "Ada Lovelace"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fu := FirestoreUpdater{Students: repo}
+
+	rows := [][]interface{}{
+		{"Goals"},
+		{"", "25th", "50th", "75th", "25th", "50th", "75th"},
+		{"College", "ACT", "", "", "SAT", "", ""},
+		{"M.I.T.", float64(34), float64(35), float64(36), float64(1520), float64(1550), float64(1580)},
+		{"State University", "1200", "1300", "1400"},
+		{""},
+	}
+	goals := goalsFromRows(rows)
+	if written := fu.ImportGoals(ctx, id, goals); written != 2 {
+		t.Errorf("ImportGoals wrote %d goals, want 2", written)
+	}
+
+	got, err := repo.ListGoals(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []students.Goal{
+		{ID: "M_I_T_", College: "M.I.T.", ACTPercentiles: []string{"34", "35", "36"}, SATPercentiles: []string{"1520", "1550", "1580"}},
+		{ID: "State University", College: "State University", ACTPercentiles: []string{}, SATPercentiles: []string{"1200", "1300", "1400"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("goals =\n%+v\nwant\n%+v", got, want)
+	}
+}
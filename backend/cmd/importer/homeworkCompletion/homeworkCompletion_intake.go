@@ -9,22 +9,15 @@ import (
 	"time"
 
 	"cloud.google.com/go/firestore"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/students"
 	"github.com/joho/godotenv"
-	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 	"google.golang.org/api/sheets/v4"
 )
 
 // FirestoreUpdater handles Firestore operations
 type FirestoreUpdater struct {
-	Client *firestore.Client
-}
-
-// StudentDocument represents the structure of a student document in Firestore
-type StudentDocument struct {
-	Business struct {
-		DriveURL string `firestore:"drive_url"`
-	} `firestore:"business"`
+	Students students.Repository
 }
 
 func main() {
@@ -63,7 +56,7 @@ func main() {
 	}
 
 	// Initialize FirestoreUpdater
-	fu := FirestoreUpdater{Client: firestoreClient}
+	fu := FirestoreUpdater{Students: students.NewFirestoreRepository(firestoreClient)}
 
 	// Process students
 	err = fu.ProcessStudents(ctx, sheetsService)
@@ -84,39 +77,22 @@ func initializeSheetsService(serviceAccountPath string) (*sheets.Service, error)
 	return sheetsService, nil
 }
 
-// ProcessStudents iterates through the 'students' collection and updates Homework Completion subcollection
+// ProcessStudents iterates through the students and updates their Homework Completion subcollection
 func (fu *FirestoreUpdater) ProcessStudents(ctx context.Context, sheetsService *sheets.Service) error {
-	// Reference to the 'students' collection
-	studentsCollection := fu.Client.Collection("students")
-
-	// Retrieve all student documents
-	iter := studentsCollection.Documents(ctx)
-	defer iter.Stop()
+	// Retrieve all students
+	all, err := fu.Students.List(ctx)
+	if err != nil {
+		return fmt.Errorf("error listing students: %v", err)
+	}
 
 	count := 0
 	skipped := 0
-	for {
-		doc, err := iter.Next()
-		if err != nil {
-			if err == iterator.Done { // Corrected error check
-				break
-			}
-			return fmt.Errorf("error iterating through students: %v", err)
-		}
-
+	for _, student := range all {
 		count++
 
-		// Parse the student document into StudentDocument struct
-		var student StudentDocument
-		err = doc.DataTo(&student)
-		if err != nil {
-			log.Printf("Error parsing student document ID=%s: %v", doc.Ref.ID, err)
-			continue
-		}
-
 		driveURL := student.Business.DriveURL
 		if driveURL == "" {
-			log.Printf("No 'drive_url' found for student ID=%s. Skipping.", doc.Ref.ID)
+			log.Printf("No 'drive_url' found for student ID=%s. Skipping.", student.ID)
 			skipped++
 			continue
 		}
@@ -129,59 +105,62 @@ func (fu *FirestoreUpdater) ProcessStudents(ctx context.Context, sheetsService *
 			return readHomeworkCompletion(ctx, sheetsService, spreadsheetID)
 		})
 		if err != nil {
-			log.Printf("Error reading homework completion for student ID=%s after retries: %v", doc.Ref.ID, err)
+			log.Printf("Error reading homework completion for student ID=%s after retries: %v", student.ID, err)
 			continue
 		}
 
 		hwRows, ok := rows.([][]interface{})
 		if !ok {
-			log.Printf("Unexpected data format for student ID=%s. Skipping.", doc.Ref.ID)
+			log.Printf("Unexpected data format for student ID=%s. Skipping.", student.ID)
 			continue
 		}
 
-		// Process each row in the homework completion sheet
-		for _, row := range hwRows {
-			if len(row) < 2 {
-				log.Printf("Row has insufficient columns for student ID=%s. Skipping row.", doc.Ref.ID)
-				continue
-			}
-
-			// Extract date and percentage_complete from columns A and B
-			dateRaw, ok := row[0].(string)
-			if !ok {
-				// If not a string, try to convert to string
-				dateRaw = fmt.Sprintf("%v", row[0])
-			}
-			percentageComplete, ok := row[1].(string)
-			if !ok {
-				percentageComplete = fmt.Sprintf("%v", row[1])
-			}
-
-			// Convert date format from MM/DD/YYYY to MM-DD-YYYY
-			formattedDate := strings.ReplaceAll(dateRaw, "/", "-")
-
-			// Reference to the 'Homework Completion' subcollection
-			hwCollection := doc.Ref.Collection("Homework Completion")
-
-			// Create or update the subdocument with the formatted date as the document ID
-			hwDocRef := hwCollection.Doc(formattedDate)
-			_, err := hwDocRef.Set(ctx, map[string]string{
-				"date":                dateRaw,
-				"percentage_complete": percentageComplete,
-			})
-			if err != nil {
-				log.Printf("Error writing Homework Completion for student ID=%s, date=%s: %v", doc.Ref.ID, formattedDate, err)
-				continue
-			}
-
-			log.Printf("Successfully wrote Homework Completion for student ID=%s, date=%s.", doc.Ref.ID, formattedDate)
-		}
+		fu.ImportHomeworkCompletion(ctx, student.ID, hwRows)
 	}
 
 	log.Printf("Processed %d students. Skipped %d students due to missing 'drive_url'.", count, skipped)
 	return nil
 }
 
+// ImportHomeworkCompletion writes a session for each row of a student's
+// homework completion sheet: the date in column A and the percentage
+// complete in column B. It returns how many were written.
+func (fu *FirestoreUpdater) ImportHomeworkCompletion(ctx context.Context, studentID string, rows [][]interface{}) int {
+	written := 0
+	for _, row := range rows {
+		if len(row) < 2 {
+			log.Printf("Row has insufficient columns for student ID=%s. Skipping row.", studentID)
+			continue
+		}
+
+		// Extract date and percentage_complete from columns A and B
+		dateRaw, ok := row[0].(string)
+		if !ok {
+			// If not a string, try to convert to string
+			dateRaw = fmt.Sprintf("%v", row[0])
+		}
+		percentageComplete, ok := row[1].(string)
+		if !ok {
+			percentageComplete = fmt.Sprintf("%v", row[1])
+		}
+
+		// Create or update the session with the date (MM-DD-YYYY) as the document ID
+		hw := students.HomeworkCompletion{
+			ID:                 students.HomeworkDocID(dateRaw),
+			Date:               dateRaw,
+			PercentageComplete: percentageComplete,
+		}
+		if err := fu.Students.SaveHomework(ctx, studentID, hw); err != nil {
+			log.Printf("Error writing Homework Completion for student ID=%s, date=%s: %v", studentID, hw.ID, err)
+			continue
+		}
+
+		log.Printf("Successfully wrote Homework Completion for student ID=%s, date=%s.", studentID, hw.ID)
+		written++
+	}
+	return written
+}
+
 // readHomeworkCompletion reads all rows from the 'homework completion' tab of the spreadsheet
 func readHomeworkCompletion(ctx context.Context, sheetsService *sheets.Service, spreadsheetID string) ([][]interface{}, error) {
 	readRange := "homework completion"
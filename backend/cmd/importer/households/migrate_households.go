@@ -0,0 +1,101 @@
+package main
+
+// One-time migration to households. Before households, each parents document
+// had its own associated_students and business.qboCustomerId. This makes a
+// household from every parents document that has either, with the parent as
+// its only guardian and the parent's ID as its ID, so the family's hours
+// ledger carries over. Parents are otherwise moved the first time they are
+// looked up; this does it for everyone at once.
+//
+// Run it after mergeParents. Without -apply it only prints what it would do.
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"os"
+
+	"cloud.google.com/go/firestore"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/households"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/identity"
+	"github.com/joho/godotenv"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+)
+
+func main() {
+	apply := flag.Bool("apply", false, "write the households instead of printing them")
+	flag.Parse()
+
+	// Load environment variables from the .env file located one directory up
+	err := godotenv.Load("../.env")
+	if err != nil {
+		log.Fatalf("Error loading .env file: %v", err)
+	}
+
+	serviceAccountPath := os.Getenv("SERVICE_ACCOUNT_PATH")
+	if serviceAccountPath == "" {
+		log.Fatal("SERVICE_ACCOUNT_PATH is not set in the environment variables")
+	}
+
+	firestoreProjectID := os.Getenv("FIRESTORE_PROJECT_ID")
+	if firestoreProjectID == "" {
+		log.Fatal("FIRESTORE_PROJECT_ID is not set in the environment variables")
+	}
+
+	ctx := context.Background()
+	client, err := firestore.NewClient(ctx, firestoreProjectID, option.WithCredentialsFile(serviceAccountPath))
+	if err != nil {
+		log.Fatalf("Failed to create Firestore client: %v", err)
+	}
+	defer client.Close()
+
+	stored := households.NewFirestoreStore(client)
+	// A dry run makes the households in memory instead.
+	target := households.Store(households.NewMemoryStore())
+	if *apply {
+		target = stored
+	}
+	manager := households.NewManager(target, client)
+
+	made, existing, empty := 0, 0, 0
+	iter := client.Collection(identity.ParentsCollection).Documents(ctx)
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			log.Fatalf("Failed to iterate parents: %v", err)
+		}
+		parentID := doc.Ref.ID
+
+		if h, found, err := stored.FindByGuardian(ctx, parentID); err != nil {
+			log.Printf("Skipping parents/%s: %v", parentID, err)
+			continue
+		} else if found {
+			log.Printf("parents/%s: already in household %s", parentID, h.ID)
+			existing++
+			continue
+		}
+
+		h, err := manager.ForGuardian(ctx, parentID)
+		if errors.Is(err, households.ErrNotFound) {
+			empty++
+			continue
+		}
+		if err != nil {
+			log.Printf("Skipping parents/%s: %v", parentID, err)
+			continue
+		}
+		log.Printf("parents/%s: household %s, students %v, customer %q", parentID, h.ID, h.StudentIDs, h.QBOCustomerID)
+		made++
+	}
+
+	if !*apply {
+		log.Printf("Dry run: %d household(s) would be made, %d parent(s) already have one, %d have no students or customer. Run with -apply to write.", made, existing, empty)
+		return
+	}
+	log.Printf("Made %d household(s); %d parent(s) already had one, %d have no students or customer.", made, existing, empty)
+}
@@ -0,0 +1,265 @@
+package main
+
+// One-time migration for linked identities. Before accounts had their own IDs,
+// a parent who signed in with two providers got a parents document for each.
+// This merges every group of parent documents sharing an email into one:
+//
+//   - the primary is the document with a QuickBooks customer, else the one with
+//     the most students, else the oldest;
+//   - associated_students are combined, and business fields the primary lacks
+//     are copied from the others;
+//   - each other document's ID is linked to the primary as a "legacy" identity,
+//     so its provider signs in to the primary from then on;
+//   - manual adjustments and refunds move to the primary's hours ledger, the
+//     other documents and their ledgers are deleted, and the primary's ledger
+//     is reconciled.
+//
+// Groups whose documents belong to different QuickBooks customers are skipped
+// for an admin to sort out. Without -apply it only prints what it would do.
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/identity"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/ledger"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/students"
+	"github.com/joho/godotenv"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+)
+
+func main() {
+	apply := flag.Bool("apply", false, "write the changes instead of printing them")
+	flag.Parse()
+
+	// Load environment variables from the .env file located one directory up
+	err := godotenv.Load("../.env")
+	if err != nil {
+		log.Fatalf("Error loading .env file: %v", err)
+	}
+
+	serviceAccountPath := os.Getenv("SERVICE_ACCOUNT_PATH")
+	if serviceAccountPath == "" {
+		log.Fatal("SERVICE_ACCOUNT_PATH is not set in the environment variables")
+	}
+
+	firestoreProjectID := os.Getenv("FIRESTORE_PROJECT_ID")
+	if firestoreProjectID == "" {
+		log.Fatal("FIRESTORE_PROJECT_ID is not set in the environment variables")
+	}
+
+	ctx := context.Background()
+	client, err := firestore.NewClient(ctx, firestoreProjectID, option.WithCredentialsFile(serviceAccountPath))
+	if err != nil {
+		log.Fatalf("Failed to create Firestore client: %v", err)
+	}
+	defer client.Close()
+
+	// Group parent documents by email
+	groups := map[string][]*firestore.DocumentSnapshot{}
+	iter := client.Collection(identity.ParentsCollection).Documents(ctx)
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			log.Fatalf("Failed to iterate parents: %v", err)
+		}
+		email, _ := doc.Data()["email"].(string)
+		email = strings.ToLower(strings.TrimSpace(email))
+		if email == "" {
+			continue
+		}
+		groups[email] = append(groups[email], doc)
+	}
+
+	m := &merger{
+		client: client,
+		store:  identity.NewFirestoreStore(client),
+		hours:  ledger.New(client, students.NewFirestoreRepository(client)),
+		apply:  *apply,
+	}
+
+	merged, skipped := 0, 0
+	for email, docs := range groups {
+		if len(docs) < 2 {
+			continue
+		}
+		if err := m.merge(ctx, email, docs); err != nil {
+			log.Printf("Skipping %s: %v", email, err)
+			skipped++
+			continue
+		}
+		merged++
+	}
+
+	if !*apply {
+		log.Printf("Dry run: %d group(s) would be merged, %d skipped. Run with -apply to write.", merged, skipped)
+		return
+	}
+	log.Printf("Merged %d group(s), skipped %d.", merged, skipped)
+}
+
+type merger struct {
+	client *firestore.Client
+	store  identity.Store
+	hours  *ledger.Ledger
+	apply  bool
+}
+
+func customerID(doc *firestore.DocumentSnapshot) string {
+	business, _ := doc.Data()["business"].(map[string]interface{})
+	id, _ := business["qboCustomerId"].(string)
+	return id
+}
+
+func associatedStudents(doc *firestore.DocumentSnapshot) []string {
+	raw, _ := doc.Data()["associated_students"].([]interface{})
+	ids := make([]string, 0, len(raw))
+	for _, v := range raw {
+		if id, ok := v.(string); ok && id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// merge folds docs, the parent documents sharing email, into one.
+func (m *merger) merge(ctx context.Context, email string, docs []*firestore.DocumentSnapshot) error {
+	customer := ""
+	for _, doc := range docs {
+		id := customerID(doc)
+		if id == "" {
+			continue
+		}
+		if customer != "" && id != customer {
+			return fmt.Errorf("documents belong to QuickBooks customers %s and %s", customer, id)
+		}
+		customer = id
+	}
+
+	sort.SliceStable(docs, func(i, j int) bool {
+		ci, cj := customerID(docs[i]) != "", customerID(docs[j]) != ""
+		if ci != cj {
+			return ci
+		}
+		si, sj := len(associatedStudents(docs[i])), len(associatedStudents(docs[j]))
+		if si != sj {
+			return si > sj
+		}
+		return docs[i].CreateTime.Before(docs[j].CreateTime)
+	})
+	primary, others := docs[0], docs[1:]
+
+	studentIDs := associatedStudents(primary)
+	seen := map[string]bool{}
+	for _, id := range studentIDs {
+		seen[id] = true
+	}
+	business, _ := primary.Data()["business"].(map[string]interface{})
+	if business == nil {
+		business = map[string]interface{}{}
+	}
+	for _, other := range others {
+		for _, id := range associatedStudents(other) {
+			if !seen[id] {
+				seen[id] = true
+				studentIDs = append(studentIDs, id)
+			}
+		}
+		otherBusiness, _ := other.Data()["business"].(map[string]interface{})
+		for k, v := range otherBusiness {
+			if _, ok := business[k]; !ok {
+				business[k] = v
+			}
+		}
+	}
+
+	otherIDs := make([]string, len(others))
+	for i, other := range others {
+		otherIDs[i] = other.Ref.ID
+	}
+	log.Printf("%s: keeping parents/%s, merging %s, students %v", email, primary.Ref.ID, strings.Join(otherIDs, ", "), studentIDs)
+	if !m.apply {
+		return nil
+	}
+
+	_, err := primary.Ref.Update(ctx, []firestore.Update{
+		{Path: "associated_students", Value: studentIDs},
+		{Path: "business", Value: business},
+	})
+	if err != nil {
+		return fmt.Errorf("update parents/%s: %w", primary.Ref.ID, err)
+	}
+
+	for _, other := range others {
+		if err := m.fold(ctx, primary.Ref.ID, other); err != nil {
+			return fmt.Errorf("merge parents/%s: %w", other.Ref.ID, err)
+		}
+	}
+
+	if _, err := m.hours.Reconcile(ctx, primary.Ref.ID, true); err != nil {
+		return fmt.Errorf("reconcile ledger of parents/%s: %w", primary.Ref.ID, err)
+	}
+	return nil
+}
+
+// fold links other's sign-in to the primary, moves its manual ledger entries
+// and deletes it.
+func (m *merger) fold(ctx context.Context, primaryID string, other *firestore.DocumentSnapshot) error {
+	email, _ := other.Data()["email"].(string)
+	err := m.store.AddLinkedIdentity(ctx, identity.ParentsCollection, primaryID, identity.LinkedIdentity{
+		Key:      identity.IdentityKey(identity.LegacyProvider, other.Ref.ID),
+		Provider: identity.LegacyProvider,
+		Subject:  other.Ref.ID,
+		Email:    email,
+		LinkedAt: other.CreateTime,
+	})
+	if err != nil {
+		return fmt.Errorf("link identity: %w", err)
+	}
+
+	entries, err := m.hours.Entries(ctx, other.Ref.ID)
+	if err != nil {
+		return fmt.Errorf("read ledger: %w", err)
+	}
+	for _, e := range entries {
+		// Sessions and purchases are rebuilt by reconciling the primary.
+		if e.Type != ledger.Adjustment && e.Type != ledger.Refund {
+			continue
+		}
+		e.Note = strings.TrimSpace(fmt.Sprintf("%s (merged from parents/%s)", e.Note, other.Ref.ID))
+		if _, err := m.hours.Append(ctx, primaryID, e); err != nil {
+			return fmt.Errorf("move ledger entry %s: %w", e.ID, err)
+		}
+	}
+
+	ledgerRef := m.client.Collection("hours_ledger").Doc(other.Ref.ID)
+	entryRefs, err := ledgerRef.Collection("entries").DocumentRefs(ctx).GetAll()
+	if err != nil {
+		return fmt.Errorf("list ledger entries: %w", err)
+	}
+	for _, ref := range entryRefs {
+		if _, err := ref.Delete(ctx); err != nil {
+			return fmt.Errorf("delete ledger entry %s: %w", ref.ID, err)
+		}
+	}
+	for _, ref := range []*firestore.DocumentRef{
+		ledgerRef,
+		m.client.Collection("hours_alerts").Doc(other.Ref.ID),
+		other.Ref,
+	} {
+		if _, err := ref.Delete(ctx); err != nil {
+			return fmt.Errorf("delete %s: %w", ref.Path, err)
+		}
+	}
+	return nil
+}
@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"cloud.google.com/go/firestore"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/students"
 	"github.com/joho/godotenv"
 	"google.golang.org/api/drive/v3"
 	"google.golang.org/api/option"
@@ -36,7 +37,7 @@ type StudentData struct {
 
 // FirestoreUpdater handles Firestore operations
 type FirestoreUpdater struct {
-	Client *firestore.Client
+	Students students.Repository
 }
 
 func main() {
@@ -79,7 +80,7 @@ func main() {
 	testDriveAccess(ctx, driveService, studentsFolderID)
 
 	// Initialize FirestoreUpdater
-	fu := FirestoreUpdater{Client: firestoreClient}
+	fu := FirestoreUpdater{Students: students.NewFirestoreRepository(firestoreClient)}
 
 	// Iterate through student folders
 	err = iterateStudentFolders(ctx, driveService, sheetsService, fu, studentsFolderID)
@@ -385,53 +386,8 @@ func prepareStudentData(allData map[string]string, spreadsheetID string) (Studen
 
 // InitializeNewStudent initializes a new student in Firestore
 func (fu *FirestoreUpdater) InitializeNewStudent(ctx context.Context, studentData StudentData) error {
-	// Generate a unique ID for the new student document
-	docRef := fu.Client.Collection("students").NewDoc()
-	studentID := docRef.ID
-
-	log.Printf("Generated student ID: %s for student: %s", studentID, studentData.Name)
-
-	// Prepare 'personal' data
-	personalData := map[string]interface{}{
-		"name":           studentData.Name,
-		"student_email":  studentData.StudentEmail,
-		"student_number": studentData.StudentNumber,
-		"parent_email":   studentData.ParentEmail,
-		"parent_number":  studentData.ParentNumber,
-		"high_school":    studentData.School,
-		"grade":          studentData.Grade,
-		"accommodations": studentData.Accommodations,
-		"interests":      studentData.Interests,
-	}
-
-	// Prepare 'test_appointment' data
-	testAppointmentData := map[string]interface{}{
-		"registered_for_test": studentData.RegisteredForTest,
-		"test_date":           studentData.TestDate,
-	}
-
-	// Prepare 'business' data
-	businessData := map[string]interface{}{
-		"firebase_id":       studentID,
-		"scheduler":         studentData.Scheduler,
-		"test_focus":        studentData.TestFocus,
-		"test_appointment":  testAppointmentData,
-		"associated_tutors": []string{}, // Initialize as empty array
-		"team_lead":         "",         // Initialize as empty string
-		"remaining_hours":   0,          // Initialize as zero
-		"lifetime_hours":    0,          // Initialize as zero
-		"classroom_id":      studentData.ClassroomID,
-		"drive_url":         studentData.DriveURL, // Only the unique ID
-	}
-
-	// Combine 'personal' and 'business' into the student document
-	studentDocData := map[string]interface{}{
-		"personal": personalData,
-		"business": businessData,
-	}
-
-	// Write the student document to Firestore
-	_, err := docRef.Set(ctx, studentDocData)
+	// The repository generates the document ID and sets business.firebase_id to it
+	studentID, err := fu.Students.Create(ctx, newStudent(studentData))
 	if err != nil {
 		return fmt.Errorf("failed to save student data: %v", err)
 	}
@@ -440,6 +396,35 @@ func (fu *FirestoreUpdater) InitializeNewStudent(ctx context.Context, studentDat
 	return nil
 }
 
+// newStudent builds the student document for studentData. Associated tutors,
+// team lead and hours start out empty.
+func newStudent(studentData StudentData) *students.Student {
+	return &students.Student{
+		Personal: students.Personal{
+			Name:           studentData.Name,
+			StudentEmail:   studentData.StudentEmail,
+			StudentNumber:  studentData.StudentNumber,
+			ParentEmail:    studentData.ParentEmail,
+			ParentNumber:   studentData.ParentNumber,
+			HighSchool:     studentData.School,
+			Grade:          studentData.Grade,
+			Accommodations: studentData.Accommodations,
+			Interests:      studentData.Interests,
+		},
+		Business: students.Business{
+			Scheduler: studentData.Scheduler,
+			TestFocus: studentData.TestFocus,
+			TestAppointment: students.TestAppointment{
+				RegisteredForTest: studentData.RegisteredForTest,
+				TestDate:          studentData.TestDate,
+			},
+			AssociatedTutors: []string{},
+			ClassroomID:      studentData.ClassroomID,
+			DriveURL:         studentData.DriveURL, // Only the unique ID
+		},
+	}
+}
+
 // Helper function to safely extract string values from a cell
 func getCellStringValue(values [][]interface{}, index int) (string, bool) {
 	if index >= len(values) {
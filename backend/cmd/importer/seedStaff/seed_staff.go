@@ -0,0 +1,166 @@
+package main
+
+// One-time migration to the staff directory. Tutors used to be recognised by
+// their @leetutoring.com address, admins by the ADMIN_EMAILS setting and
+// tutor names by a hardcoded map. This adds a member to the "staff"
+// collection for every tutor document, so everyone who signs in as a tutor
+// today still does after the deploy:
+//
+//   - the name is the one from the old map, else the first name on the tutor
+//     document, which is how students' associated_tutors list tutors;
+//   - the role is admin for addresses in ADMIN_EMAILS, team lead for tutors
+//     whose document says so, and tutor otherwise.
+//
+// Addresses in ADMIN_EMAILS without a tutor document are added as admins too.
+// Emails that are already in the directory are skipped, so it can be run
+// again. Without -apply it only prints what it would do.
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/identity"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/middleware"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/staff"
+	"github.com/joho/godotenv"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+)
+
+// legacyNames is the map AssociateStudentsForTutor used before the directory,
+// less its nathaniel@ -> Edward entry. That was a developer alias for viewing
+// Edward's students; seeding it would give Nathaniel Edward's name, so they
+// get the name from their own tutor document like everyone else.
+var legacyNames = map[string]string{
+	"edward@leetutoring.com": "Edward",
+	"eli@leetutoring.com":    "Eli",
+	"ben@leetutoring.com":    "Ben",
+}
+
+func main() {
+	apply := flag.Bool("apply", false, "write the changes instead of printing them")
+	flag.Parse()
+
+	// Load environment variables from the .env file located one directory up
+	err := godotenv.Load("../.env")
+	if err != nil {
+		log.Fatalf("Error loading .env file: %v", err)
+	}
+
+	serviceAccountPath := os.Getenv("SERVICE_ACCOUNT_PATH")
+	if serviceAccountPath == "" {
+		log.Fatal("SERVICE_ACCOUNT_PATH is not set in the environment variables")
+	}
+
+	firestoreProjectID := os.Getenv("FIRESTORE_PROJECT_ID")
+	if firestoreProjectID == "" {
+		log.Fatal("FIRESTORE_PROJECT_ID is not set in the environment variables")
+	}
+
+	admins := map[string]bool{}
+	for _, email := range strings.Split(os.Getenv("ADMIN_EMAILS"), ",") {
+		if email = staff.NormalizeEmail(email); email != "" {
+			admins[email] = true
+		}
+	}
+
+	ctx := context.Background()
+	client, err := firestore.NewClient(ctx, firestoreProjectID, option.WithCredentialsFile(serviceAccountPath))
+	if err != nil {
+		log.Fatalf("Failed to create Firestore client: %v", err)
+	}
+	defer client.Close()
+
+	store := staff.NewFirestoreStore(client)
+	if !*apply {
+		// Check for duplicates against the real directory, but write nowhere.
+		store = &dryRunStore{Store: store, pending: staff.NewMemoryStore()}
+	}
+	directory := staff.NewDirectory(store, nil, nil, nil)
+
+	added := 0
+	add := func(m *staff.Member) {
+		if _, found, err := directory.Lookup(ctx, m.Email); err != nil {
+			log.Fatalf("Failed to look up %s: %v", m.Email, err)
+		} else if found {
+			log.Printf("%s is already in the staff directory", m.Email)
+			return
+		}
+		if err := directory.Create(ctx, m); err != nil {
+			log.Printf("Skipping %s: %v", m.Email, err)
+			return
+		}
+		added++
+		log.Printf("Added %s as %s (%s)", m.Email, m.Name, m.Role)
+	}
+
+	iter := client.Collection(identity.TutorsCollection).Documents(ctx)
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			log.Fatalf("Failed to iterate tutors: %v", err)
+		}
+		data := doc.Data()
+		email, _ := data["email"].(string)
+		if email == "" {
+			log.Printf("Skipping tutor %s without an email", doc.Ref.ID)
+			continue
+		}
+		key := staff.NormalizeEmail(email)
+
+		name := legacyNames[key]
+		if name == "" {
+			fullName, _ := data["name"].(string)
+			if fields := strings.Fields(fullName); len(fields) > 0 {
+				name = fields[0]
+			}
+		}
+		role := middleware.RoleTutor
+		if staffRole, _ := data["staff_role"].(string); staffRole == middleware.RoleTeamLead {
+			role = middleware.RoleTeamLead
+		}
+		if admins[key] {
+			role = middleware.RoleAdmin
+			delete(admins, key)
+		}
+		add(&staff.Member{Email: email, Name: name, Role: role, Active: true})
+	}
+
+	for email := range admins {
+		name := legacyNames[email]
+		if name == "" {
+			name = strings.Split(email, "@")[0]
+		}
+		add(&staff.Member{Email: email, Name: name, Role: middleware.RoleAdmin, Active: true})
+	}
+
+	if *apply {
+		log.Printf("Added %d staff member(s)", added)
+	} else {
+		log.Printf("Would add %d staff member(s); run with -apply to write them", added)
+	}
+}
+
+// dryRunStore reads from the directory and keeps new members in memory.
+type dryRunStore struct {
+	staff.Store
+	pending *staff.MemoryStore
+}
+
+func (s *dryRunStore) FindByEmail(ctx context.Context, email string) (*staff.Member, bool, error) {
+	if m, found, err := s.pending.FindByEmail(ctx, email); err != nil || found {
+		return m, found, err
+	}
+	return s.Store.FindByEmail(ctx, email)
+}
+
+func (s *dryRunStore) Create(ctx context.Context, m *staff.Member) error {
+	return s.pending.Create(ctx, m)
+}
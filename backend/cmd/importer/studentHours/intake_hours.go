@@ -8,8 +8,8 @@ import (
 	"strings"
 
 	"cloud.google.com/go/firestore"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/students"
 	"github.com/joho/godotenv"
-	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 	"google.golang.org/api/sheets/v4"
 )
@@ -103,82 +103,43 @@ func main() {
 		studentHoursMap[name] = hours
 	}
 
-	// Iterate over all student documents in Firestore
-	iter := firestoreClient.Collection("students").Documents(ctx)
-	defer iter.Stop()
+	// Update every student whose name appears in the sheet
+	repo := students.NewFirestoreRepository(firestoreClient)
+	if err := updateLifetimeHours(ctx, repo, studentHoursMap); err != nil {
+		log.Fatalf("Error listing students: %v", err)
+	}
 
-	for {
-		doc, err := iter.Next()
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			log.Printf("Error iterating through students: %v", err)
-			continue
-		}
+	log.Println("Data intake process completed.")
+}
 
-		// Access the 'personal' subdocument to get the student's name
-		personalData, ok := doc.Data()["personal"].(map[string]interface{})
-		if !ok {
-			log.Printf("Error reading 'personal' subdocument for document ID %s", doc.Ref.ID)
-			continue
-		}
+// updateLifetimeHours sets business.lifetime_hours for each student found in
+// hoursByName, keyed by the student's name.
+func updateLifetimeHours(ctx context.Context, repo students.Repository, hoursByName map[string]float64) error {
+	all, err := repo.List(ctx)
+	if err != nil {
+		return err
+	}
 
-		studentName, ok := personalData["name"].(string)
-		if !ok || studentName == "" {
-			log.Printf("Error reading student name from 'personal' subdocument in document ID %s", doc.Ref.ID)
+	for _, student := range all {
+		studentName := student.Personal.Name
+		if studentName == "" {
+			log.Printf("Error reading student name from 'personal' subdocument in document ID %s", student.ID)
 			continue
 		}
 
 		// Look up the student's lifetime hours from the map
-		hours, found := studentHoursMap[studentName]
+		hours, found := hoursByName[studentName]
 		if !found {
 			log.Printf("Student %s not found in the Google Sheet, skipping.", studentName)
 			continue
 		}
 
-		// Prepare the data to update in the 'business' subdocument
-		businessUpdate := map[string]interface{}{
-			"lifetime_hours": hours,
-		}
-
-		// Update the 'business' subdocument with 'lifetime_hours'
-		err = updateSubdocument(firestoreClient, ctx, doc.Ref, "business", businessUpdate)
-		if err != nil {
+		if err := repo.SetLifetimeHours(ctx, student.ID, hours); err != nil {
 			log.Printf("Failed to update lifetime_hours for student %s: %v", studentName, err)
 			continue
 		}
 
 		log.Printf("Successfully updated lifetime_hours for student: %s", studentName)
 	}
-
-	log.Println("Data intake process completed.")
-}
-
-// Helper function to update a subdocument for a student document
-func updateSubdocument(firestoreClient *firestore.Client, ctx context.Context, docRef *firestore.DocumentRef, subDocName string, data map[string]interface{}) error {
-	// Use a transaction to update the subdocument
-	err := firestoreClient.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
-		// Get the existing data
-		_, err := tx.Get(docRef)
-		if err != nil {
-			return err
-		}
-
-		// Prepare the update data
-		updates := []firestore.Update{
-			{
-				Path:  subDocName + ".lifetime_hours",
-				Value: data["lifetime_hours"],
-			},
-		}
-
-		// Update the document
-		return tx.Update(docRef, updates)
-	})
-	if err != nil {
-		log.Printf("Failed to update subdocument %s: %v", subDocName, err)
-		return err
-	}
 	return nil
 }
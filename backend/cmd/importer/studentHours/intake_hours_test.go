@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/students"
+)
+
+func TestUpdateLifetimeHours(t *testing.T) {
+	ctx := context.Background()
+	repo := students.NewMemoryRepository()
+	ids := map[string]string{}
+	for _, name := range []string{"Ada Lovelace", "Alan Turing"} {
+		id, err := repo.Create(ctx, &students.Student{
+			Personal: students.Personal{Name: name},
+			Business: students.Business{LifetimeHours: 5},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids[name] = id
+	}
+
+	err := updateLifetimeHours(ctx, repo, map[string]float64{"Ada Lovelace": 42.5, "Grace Hopper": 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]float64{"Ada Lovelace": 42.5, "Alan Turing": 5}
+	for name, hours := range want {
+		s, err := repo.Get(ctx, ids[name])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s.Business.LifetimeHours != hours {
+			t.Errorf("%s lifetime hours = %v, want %v", name, s.Business.LifetimeHours, hours)
+		}
+	}
+}
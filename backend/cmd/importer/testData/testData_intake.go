@@ -9,22 +9,23 @@ import (
 	"time"
 
 	"cloud.google.com/go/firestore"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/students"
 	"github.com/joho/godotenv"
-	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 	"google.golang.org/api/sheets/v4"
 )
 
 // FirestoreUpdater handles Firestore operations
 type FirestoreUpdater struct {
-	Client *firestore.Client
+	Students students.Repository
 }
 
-// StudentDocument represents the structure of a student document in Firestore
-type StudentDocument struct {
-	Business struct {
-		DriveURL string `firestore:"drive_url"`
-	} `firestore:"business"`
+// allowedTestTypes are the values of the Type column that are imported.
+var allowedTestTypes = map[string]bool{
+	"Practice":      true,
+	"Official":      true,
+	"Unofficial SS": true,
+	"Official SS":   true,
 }
 
 func main() {
@@ -63,7 +64,7 @@ func main() {
 	}
 
 	// Initialize FirestoreUpdater
-	fu := FirestoreUpdater{Client: firestoreClient}
+	fu := FirestoreUpdater{Students: students.NewFirestoreRepository(firestoreClient)}
 
 	// Process Test Data
 	err = fu.ProcessTestData(ctx, sheetsService)
@@ -84,47 +85,22 @@ func initializeSheetsService(serviceAccountPath string) (*sheets.Service, error)
 	return sheetsService, nil
 }
 
-// ProcessTestData iterates through the 'students' collection and updates Test Data subcollection
+// ProcessTestData iterates through the students and updates their Test Data subcollection
 func (fu *FirestoreUpdater) ProcessTestData(ctx context.Context, sheetsService *sheets.Service) error {
-	// Reference to the 'students' collection
-	studentsCollection := fu.Client.Collection("students")
-
-	// Define allowed test types
-	allowedTestTypes := map[string]bool{
-		"Practice":      true,
-		"Official":      true,
-		"Unofficial SS": true,
-		"Official SS":   true,
+	// Retrieve all students
+	all, err := fu.Students.List(ctx)
+	if err != nil {
+		return fmt.Errorf("error listing students: %v", err)
 	}
 
-	// Retrieve all student documents
-	iter := studentsCollection.Documents(ctx)
-	defer iter.Stop()
-
 	count := 0
 	skipped := 0
-	for {
-		doc, err := iter.Next()
-		if err != nil {
-			if err == iterator.Done {
-				break
-			}
-			return fmt.Errorf("error iterating through students: %v", err)
-		}
-
+	for _, student := range all {
 		count++
 
-		// Parse the student document into StudentDocument struct
-		var student StudentDocument
-		err = doc.DataTo(&student)
-		if err != nil {
-			log.Printf("Error parsing student document ID=%s: %v", doc.Ref.ID, err)
-			continue
-		}
-
 		driveURL := student.Business.DriveURL
 		if driveURL == "" {
-			log.Printf("No 'drive_url' found for student ID=%s. Skipping.", doc.Ref.ID)
+			log.Printf("No 'drive_url' found for student ID=%s. Skipping.", student.ID)
 			skipped++
 			continue
 		}
@@ -137,90 +113,91 @@ func (fu *FirestoreUpdater) ProcessTestData(ctx context.Context, sheetsService *
 			return readTestData(ctx, sheetsService, spreadsheetID)
 		})
 		if err != nil {
-			log.Printf("Error reading Test Data for student ID=%s after retries: %v", doc.Ref.ID, err)
+			log.Printf("Error reading Test Data for student ID=%s after retries: %v", student.ID, err)
 			continue
 		}
 
 		if len(rows) < 2 {
-			log.Printf("No data rows found in 'Test Data' for student ID=%s. Skipping.", doc.Ref.ID)
+			log.Printf("No data rows found in 'Test Data' for student ID=%s. Skipping.", student.ID)
 			continue
 		}
 
-		// Process each row in the Test Data sheet, skipping the header row
-		for i, row := range rows {
-			if i == 0 {
-				// Skip header row
-				continue
-			}
-
-			if len(row) < 14 { // Ensure there are at least 14 columns (A to N)
-				log.Printf("Row %d has insufficient columns for student ID=%s. Skipping row.", i+1, doc.Ref.ID)
-				continue
-			}
-
-			// Extract and process necessary columns
-			baseline := parseBoolean(row[0])
-			typeStr := parseString(row[1])
-			testStr := parseString(row[2])
-			dateRaw := parseString(row[3])
-			formattedDate := strings.ReplaceAll(dateRaw, "/", "-")
-
-			// Validate the test type
-			if !allowedTestTypes[typeStr] {
-				log.Printf("Invalid test type '%s' for student ID=%s. Skipping row.", typeStr, doc.Ref.ID)
-				continue
-			}
-
-			// Document ID: Combine B + C + D with spaces, date formatted
-			docID := fmt.Sprintf("%s %s %s", typeStr, testStr, formattedDate)
-
-			// Prepare ACT_Scores and SAT_Scores maps
-			actScores := map[string]interface{}{
-				"English":   parseNumber(row[8]),
-				"Math":      parseNumber(row[9]),
-				"Reading":   parseNumber(row[10]),
-				"Science":   parseNumber(row[11]),
-				"ACT_Total": parseNumber(row[13]),
-			}
-
-			satScores := map[string]interface{}{
-				"EBRW":      parseNumber(row[4]),
-				"Math":      parseNumber(row[5]),
-				"Reading":   parseNumber(row[6]),
-				"Writing":   parseNumber(row[7]),
-				"SAT_Total": parseNumber(row[12]),
-			}
-
-			// Prepare data for the main Test Data document
-			mainDocData := map[string]interface{}{
-				"baseline":   baseline,
-				"type":       typeStr,
-				"test":       testStr,
-				"date":       dateRaw,
-				"ACT_Scores": actScores,
-				"SAT_Scores": satScores,
-				"updated_at": time.Now(), // Optional: Timestamp for when the data was updated
-			}
-
-			// Reference to the 'Test Data' subcollection
-			testDataCollection := doc.Ref.Collection("Test Data")
-
-			// Create or update the main Test Data document
-			testDataDocRef := testDataCollection.Doc(docID)
-			_, err = testDataDocRef.Set(ctx, mainDocData)
-			if err != nil {
-				log.Printf("Error writing Test Data for student ID=%s, docID=%s: %v", doc.Ref.ID, docID, err)
-				continue
-			}
-
-			log.Printf("Successfully wrote Test Data for student ID=%s, docID=%s.", doc.Ref.ID, docID)
-		}
+		fu.ImportTestData(ctx, student.ID, rows)
 	}
 
 	log.Printf("Processed %d students. Skipped %d students due to missing 'drive_url'.", count, skipped)
 	return nil
 }
 
+// ImportTestData writes the rows of a student's Test Data sheet, skipping the
+// header row and rows that cannot be read. It returns how many were written.
+func (fu *FirestoreUpdater) ImportTestData(ctx context.Context, studentID string, rows [][]interface{}) int {
+	written := 0
+	for i, row := range rows {
+		if i == 0 {
+			// Skip header row
+			continue
+		}
+
+		td, ok := testDataFromRow(row)
+		if !ok {
+			log.Printf("Row %d of Test Data for student ID=%s is incomplete or has an invalid test type. Skipping row.", i+1, studentID)
+			continue
+		}
+
+		// Create or update the Test Data document
+		if err := fu.Students.SaveTestData(ctx, studentID, td); err != nil {
+			log.Printf("Error writing Test Data for student ID=%s, docID=%s: %v", studentID, td.ID, err)
+			continue
+		}
+
+		log.Printf("Successfully wrote Test Data for student ID=%s, docID=%s.", studentID, td.ID)
+		written++
+	}
+	return written
+}
+
+// testDataFromRow reads a row of columns A to N: baseline, type, test, date,
+// the SAT sections, the ACT sections and the two totals.
+func testDataFromRow(row []interface{}) (students.TestData, bool) {
+	if len(row) < 14 { // Ensure there are at least 14 columns (A to N)
+		return students.TestData{}, false
+	}
+
+	typeStr := parseString(row[1])
+	testStr := parseString(row[2])
+	dateRaw := parseString(row[3])
+
+	// Validate the test type
+	if !allowedTestTypes[typeStr] {
+		return students.TestData{}, false
+	}
+
+	return students.TestData{
+		// Document ID: Combine B + C + D with spaces, date formatted
+		ID:        students.TestDataDocID(typeStr, testStr, dateRaw),
+		Baseline:  parseBoolean(row[0]),
+		Type:      typeStr,
+		Test:      testStr,
+		Date:      dateRaw,
+		UpdatedAt: time.Now().Format(time.RFC3339), // When the data was imported
+		ACTScores: &students.ACTScores{
+			English: parseNumber(row[8]),
+			Math:    parseNumber(row[9]),
+			Reading: parseNumber(row[10]),
+			Science: parseNumber(row[11]),
+			Total:   parseNumber(row[13]),
+		},
+		SATScores: &students.SATScores{
+			EBRW:    parseNumber(row[4]),
+			Math:    parseNumber(row[5]),
+			Reading: parseNumber(row[6]),
+			Writing: parseNumber(row[7]),
+			Total:   parseNumber(row[12]),
+		},
+	}, true
+}
+
 // readTestData reads all rows from the 'Test Data' tab of the spreadsheet
 func readTestData(ctx context.Context, sheetsService *sheets.Service, spreadsheetID string) ([][]interface{}, error) {
 	readRange := "Test Data"
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/students"
+)
+
+func TestImportTestData(t *testing.T) {
+	ctx := context.Background()
+	repo := students.NewMemoryRepository()
+	id, err := repo.Create(ctx, &students.Student{Personal: students.Personal{Name: "Ada Lovelace"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fu := FirestoreUpdater{Students: repo}
+
+	rows := [][]interface{}{
+		{"Baseline", "Type", "Test", "Date", "EBRW", "Math", "Reading", "Writing", "English", "Math", "Reading", "Science", "SAT Total", "ACT Total"},
+		{"TRUE", "Official", "ACT", "10/26/2024", "", "", "", "", float64(30), "28", "-", "", "", float64(29)},
+		{"FALSE", "Practice", "SAT", "11/02/2024", "700", "720", "", "", "", "", "", "", "1420", ""},
+		{"FALSE", "Mock", "SAT", "11/09/2024", "700", "720", "", "", "", "", "", "", "1420", ""},
+		{"FALSE", "Official", "ACT"},
+	}
+	if written := fu.ImportTestData(ctx, id, rows); written != 2 {
+		t.Errorf("ImportTestData wrote %d documents, want 2", written)
+	}
+
+	got, err := repo.ListTestData(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("test data = %+v, want 2 documents", got)
+	}
+	act, sat := got[0], got[1]
+	if act.ID != "Official ACT 10-26-2024" || !act.Baseline || act.Date != "10/26/2024" {
+		t.Errorf("ACT document = %+v", act)
+	}
+	if s := act.ACTScores; *s.English != 30 || *s.Math != 28 || s.Reading != nil || s.Science != nil || *s.Total != 29 {
+		t.Errorf("ACT scores = %+v", s)
+	}
+	if sat.ID != "Practice SAT 11-02-2024" || sat.Baseline || *sat.SATScores.Total != 1420 || sat.SATScores.Reading != nil {
+		t.Errorf("SAT document = %+v, scores %+v", sat, sat.SATScores)
+	}
+}
@@ -9,25 +9,15 @@ import (
 	"time"
 
 	"cloud.google.com/go/firestore"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/students"
 	"github.com/joho/godotenv"
-	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 	"google.golang.org/api/sheets/v4"
 )
 
 // FirestoreUpdater handles Firestore operations
 type FirestoreUpdater struct {
-	Client *firestore.Client
-}
-
-// TestDate represents the structure of a test date entry
-type TestDate struct {
-	TestType                    string `firestore:"test_type"`
-	TestDate                    string `firestore:"test_date"`
-	RegularRegistrationDeadline string `firestore:"regular_registration_deadline"`
-	LateRegistrationDeadline    string `firestore:"late_registration_deadline"`
-	ScoreReleaseDate            string `firestore:"score_release_date"`
-	Notes                       string `firestore:"notes"` // New field for notes
+	Students students.Repository
 }
 
 func main() {
@@ -66,7 +56,7 @@ func main() {
 	}
 
 	// Initialize FirestoreUpdater
-	fu := FirestoreUpdater{Client: firestoreClient}
+	fu := FirestoreUpdater{Students: students.NewFirestoreRepository(firestoreClient)}
 
 	// Process students
 	err = fu.ProcessStudents(ctx, sheetsService)
@@ -87,41 +77,24 @@ func initializeSheetsService(serviceAccountPath string) (*sheets.Service, error)
 	return sheetsService, nil
 }
 
-// ProcessStudents iterates through the 'students' collection and updates 'Test Dates' subcollection
+// ProcessStudents iterates through the students and updates their 'Test Dates' subcollection
 func (fu *FirestoreUpdater) ProcessStudents(ctx context.Context, sheetsService *sheets.Service) error {
-	// Reference to the 'students' collection
-	studentsCollection := fu.Client.Collection("students")
-
-	// Retrieve all student documents
-	iter := studentsCollection.Documents(ctx)
-	defer iter.Stop()
+	// Retrieve all students
+	all, err := fu.Students.List(ctx)
+	if err != nil {
+		return fmt.Errorf("error listing students: %v", err)
+	}
 
 	count := 0
 	skipped := 0
-	for {
-		doc, err := iter.Next()
-		if err != nil {
-			if err == iterator.Done { // Corrected error check
-				break
-			}
-			return fmt.Errorf("error iterating through students: %v", err)
-		}
-
+	for _, student := range all {
 		count++
 
-		log.Printf("Processing student %d: ID=%s", count, doc.Ref.ID)
+		log.Printf("Processing student %d: ID=%s", count, student.ID)
 
-		// Safely extract 'drive_url' from the nested 'business' map
-		businessData, ok := doc.Data()["business"].(map[string]interface{})
-		if !ok {
-			log.Printf("Invalid or missing 'business' field for student ID=%s. Skipping.", doc.Ref.ID)
-			skipped++
-			continue
-		}
-
-		driveURL, ok := businessData["drive_url"].(string)
-		if !ok || driveURL == "" {
-			log.Printf("No valid 'drive_url' found for student ID=%s. Skipping.", doc.Ref.ID)
+		driveURL := student.Business.DriveURL
+		if driveURL == "" {
+			log.Printf("No valid 'drive_url' found for student ID=%s. Skipping.", student.ID)
 			skipped++
 			continue
 		}
@@ -131,26 +104,26 @@ func (fu *FirestoreUpdater) ProcessStudents(ctx context.Context, sheetsService *
 		// Read 'Test Dates' sheet with retry
 		testDates, err := retryTestDates(ctx, sheetsService, spreadsheetID)
 		if err != nil {
-			log.Printf("Error reading 'Test Dates' for student ID=%s after retries: %v", doc.Ref.ID, err)
+			log.Printf("Error reading 'Test Dates' for student ID=%s after retries: %v", student.ID, err)
 			skipped++
 			continue
 		}
 
 		if len(testDates) == 0 {
-			log.Printf("No 'Test Dates' data found for student ID=%s. Skipping.", doc.Ref.ID)
+			log.Printf("No 'Test Dates' data found for student ID=%s. Skipping.", student.ID)
 			skipped++
 			continue
 		}
 
 		// Create or update 'Test Dates' subcollection
-		err = fu.UpdateTestDates(ctx, doc.Ref, testDates)
+		err = fu.UpdateTestDates(ctx, student.ID, testDates)
 		if err != nil {
-			log.Printf("Error updating 'Test Dates' for student ID=%s: %v", doc.Ref.ID, err)
+			log.Printf("Error updating 'Test Dates' for student ID=%s: %v", student.ID, err)
 			skipped++
 			continue
 		}
 
-		log.Printf("Successfully updated 'Test Dates' for student ID=%s.", doc.Ref.ID)
+		log.Printf("Successfully updated 'Test Dates' for student ID=%s.", student.ID)
 	}
 
 	log.Printf("Processed %d students. Skipped %d students due to errors or missing data.", count, skipped)
@@ -158,8 +131,8 @@ func (fu *FirestoreUpdater) ProcessStudents(ctx context.Context, sheetsService *
 }
 
 // retryTestDates retries reading the 'Test Dates' sheet with exponential backoff
-func retryTestDates(ctx context.Context, sheetsService *sheets.Service, spreadsheetID string) ([]TestDate, error) {
-	var testDates []TestDate
+func retryTestDates(ctx context.Context, sheetsService *sheets.Service, spreadsheetID string) ([]students.TestDate, error) {
+	var testDates []students.TestDate
 	err := retry(5, 1*time.Second, func() error {
 		var innerErr error
 		testDates, innerErr = readTestDates(ctx, sheetsService, spreadsheetID)
@@ -172,7 +145,7 @@ func retryTestDates(ctx context.Context, sheetsService *sheets.Service, spreadsh
 }
 
 // readTestDates reads all rows from the 'Test Dates' sheet
-func readTestDates(ctx context.Context, sheetsService *sheets.Service, spreadsheetID string) ([]TestDate, error) {
+func readTestDates(ctx context.Context, sheetsService *sheets.Service, spreadsheetID string) ([]students.TestDate, error) {
 	readRange := "Test Dates!A2:F" // Updated to include column F
 	resp, err := sheetsService.Spreadsheets.Values.Get(spreadsheetID, readRange).Do()
 	if err != nil {
@@ -183,9 +156,13 @@ func readTestDates(ctx context.Context, sheetsService *sheets.Service, spreadshe
 		log.Printf("No data found in '%s'.", readRange)
 		return nil, nil
 	}
+	return testDatesFromRows(resp.Values), nil
+}
 
-	var testDates []TestDate
-	for i, row := range resp.Values {
+// testDatesFromRows reads the rows of a Test Dates sheet, starting at row 2.
+func testDatesFromRows(rows [][]interface{}) []students.TestDate {
+	var testDates []students.TestDate
+	for i, row := range rows {
 		// Ensure the row has at least 5 columns (A-E). Column F is optional.
 		if len(row) < 5 {
 			log.Printf("Row %d has insufficient columns. Expected at least 5, got %d. Skipping.", i+2, len(row))
@@ -204,7 +181,9 @@ func readTestDates(ctx context.Context, sheetsService *sheets.Service, spreadshe
 			notes = fmt.Sprintf("%v", row[5])
 		}
 
-		testDates = append(testDates, TestDate{
+		testDates = append(testDates, students.TestDate{
+			// Document ID: Test Type and Test Date with '-' instead of '/'
+			ID:                          students.TestDateDocID(testType, testDate),
 			TestType:                    testType,
 			TestDate:                    testDate,
 			RegularRegistrationDeadline: regularRegDeadline,
@@ -214,36 +193,16 @@ func readTestDates(ctx context.Context, sheetsService *sheets.Service, spreadshe
 		})
 	}
 
-	return testDates, nil
+	return testDates
 }
 
-// UpdateTestDates creates or updates the 'Test Dates' subcollection for a student
-func (fu *FirestoreUpdater) UpdateTestDates(ctx context.Context, docRef *firestore.DocumentRef, testDates []TestDate) error {
-	// Reference to the 'Test Dates' subcollection
-	testDatesCollection := docRef.Collection("Test Dates")
-
+// UpdateTestDates creates or replaces the 'Test Dates' documents of a student
+func (fu *FirestoreUpdater) UpdateTestDates(ctx context.Context, studentID string, testDates []students.TestDate) error {
 	for _, td := range testDates {
-		// Create document ID by concatenating Test Type and Test Date with '-' instead of '/'
-		sanitizedDate := strings.ReplaceAll(td.TestDate, "/", "-")
-		docID := fmt.Sprintf("%s %s", td.TestType, sanitizedDate)
-
-		// Prepare the data map
-		data := map[string]interface{}{
-			"test_type":                     td.TestType,
-			"test_date":                     td.TestDate,
-			"regular_registration_deadline": td.RegularRegistrationDeadline,
-			"late_registration_deadline":    td.LateRegistrationDeadline,
-			"score_release_date":            td.ScoreReleaseDate,
-			"notes":                         td.Notes, // Include notes
-		}
-
-		// Set the document (creates or updates)
-		_, err := testDatesCollection.Doc(docID).Set(ctx, data)
-		if err != nil {
-			return fmt.Errorf("failed to set Test Date document '%s': %v", docID, err)
+		if err := fu.Students.SaveTestDate(ctx, studentID, td); err != nil {
+			return fmt.Errorf("failed to set Test Date document '%s': %v", td.ID, err)
 		}
 	}
-
 	return nil
 }
 
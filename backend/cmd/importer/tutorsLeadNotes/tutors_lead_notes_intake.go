@@ -9,28 +9,15 @@ import (
 	"time"
 
 	"cloud.google.com/go/firestore"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/students"
 	"github.com/joho/godotenv"
-	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 	"google.golang.org/api/sheets/v4"
 )
 
 // FirestoreUpdater handles Firestore operations
 type FirestoreUpdater struct {
-	Client *firestore.Client
-}
-
-// StudentDocument represents the structure of a student document in Firestore
-type StudentDocument struct {
-	Personal struct {
-		Name string `firestore:"name"`
-	} `firestore:"personal"`
-	Business struct {
-		Status           string   `firestore:"status"`
-		AssociatedTutors []string `firestore:"associated_tutors"`
-		Notes            string   `firestore:"notes"`
-		TeamLead         string   `firestore:"team_lead"`
-	} `firestore:"business"`
+	Students students.Repository
 }
 
 func main() {
@@ -79,7 +66,7 @@ func main() {
 	}
 
 	// Initialize FirestoreUpdater
-	fu := FirestoreUpdater{Client: firestoreClient}
+	fu := FirestoreUpdater{Students: students.NewFirestoreRepository(firestoreClient)}
 
 	// Process students
 	err = fu.ProcessStudents(ctx, sheetsService, spreadsheetID, sheetName)
@@ -100,46 +87,29 @@ func initializeSheetsService(serviceAccountPath string) (*sheets.Service, error)
 	return sheetsService, nil
 }
 
-// ProcessStudents iterates through the 'students' collection and updates relevant fields
+// ProcessStudents iterates through the students and updates relevant fields
 func (fu *FirestoreUpdater) ProcessStudents(ctx context.Context, sheetsService *sheets.Service, spreadsheetID, sheetName string) error {
-	// Reference to the 'students' collection
-	studentsCollection := fu.Client.Collection("students")
-
-	// Retrieve all student documents
-	iter := studentsCollection.Documents(ctx)
-	defer iter.Stop()
+	// Retrieve all students
+	all, err := fu.Students.List(ctx)
+	if err != nil {
+		return fmt.Errorf("error listing students: %v", err)
+	}
 
 	count := 0
 	skipped := 0
 	updated := 0
 
-	for {
-		doc, err := iter.Next()
-		if err != nil {
-			if err == iterator.Done { // Corrected error check
-				break
-			}
-			return fmt.Errorf("error iterating through students: %v", err)
-		}
-
+	for _, student := range all {
 		count++
 
-		// Parse the student document into StudentDocument struct
-		var student StudentDocument
-		err = doc.DataTo(&student)
-		if err != nil {
-			log.Printf("Error parsing student document ID=%s: %v", doc.Ref.ID, err)
-			continue
-		}
-
 		studentName := strings.TrimSpace(student.Personal.Name)
 		if studentName == "" {
-			log.Printf("Student ID=%s has an empty name in 'personal' subdocument. Skipping.", doc.Ref.ID)
+			log.Printf("Student ID=%s has an empty name in 'personal' subdocument. Skipping.", student.ID)
 			skipped++
 			continue
 		}
 
-		log.Printf("Processing student %d: ID=%s, Name=%s", count, doc.Ref.ID, studentName)
+		log.Printf("Processing student %d: ID=%s, Name=%s", count, student.ID, studentName)
 
 		// Find the row in the spreadsheet that matches the student's name in column B
 		var row []interface{}
@@ -149,53 +119,24 @@ func (fu *FirestoreUpdater) ProcessStudents(ctx context.Context, sheetsService *
 			return innerErr
 		})
 		if err != nil {
-			log.Printf("Error finding row for student ID=%s, Name=%s after retries: %v", doc.Ref.ID, studentName, err)
+			log.Printf("Error finding row for student ID=%s, Name=%s after retries: %v", student.ID, studentName, err)
 			continue
 		}
 
 		if row == nil {
-			log.Printf("No matching row found in spreadsheet for student ID=%s, Name=%s. Skipping.", doc.Ref.ID, studentName)
+			log.Printf("No matching row found in spreadsheet for student ID=%s, Name=%s. Skipping.", student.ID, studentName)
 			skipped++
 			continue
 		}
 
-		// Extract data from the row
-		status, _ := getStringFromRow(row, 2)              // Column C (index 2)
-		associatedTutorsStr, _ := getStringFromRow(row, 3) // Column D (index 3)
-		notes, _ := getStringFromRow(row, 4)               // Column E (index 4)
-		teamLead, _ := getStringFromRow(row, 5)            // Column F (index 5)
-
-		// Process associated_tutors
-		associatedTutors := processAssociatedTutors(associatedTutorsStr)
-
-		// Prepare updates
-		updates := []firestore.Update{
-			{
-				Path:  "business.status",
-				Value: status,
-			},
-			{
-				Path:  "business.associated_tutors",
-				Value: associatedTutors,
-			},
-			{
-				Path:  "business.notes",
-				Value: notes,
-			},
-			{
-				Path:  "business.team_lead",
-				Value: teamLead,
-			},
-		}
-
 		// Update the Firestore document
-		err = fu.UpdateBusinessFields(ctx, doc.Ref, updates)
+		err = fu.Students.UpdateBusinessDetails(ctx, student.ID, businessDetailsFromRow(student.Business.Details(), row))
 		if err != nil {
-			log.Printf("Error updating business fields for student ID=%s: %v", doc.Ref.ID, err)
+			log.Printf("Error updating business fields for student ID=%s: %v", student.ID, err)
 			continue
 		}
 
-		log.Printf("Successfully updated business fields for student ID=%s.", doc.Ref.ID)
+		log.Printf("Successfully updated business fields for student ID=%s.", student.ID)
 		updated++
 	}
 
@@ -203,6 +144,21 @@ func (fu *FirestoreUpdater) ProcessStudents(ctx context.Context, sheetsService *
 	return nil
 }
 
+// businessDetailsFromRow returns d with the status, associated tutors, notes
+// and team lead taken from a spreadsheet row (columns A to F).
+func businessDetailsFromRow(d students.BusinessDetails, row []interface{}) students.BusinessDetails {
+	status, _ := getStringFromRow(row, 2)              // Column C (index 2)
+	associatedTutorsStr, _ := getStringFromRow(row, 3) // Column D (index 3)
+	notes, _ := getStringFromRow(row, 4)               // Column E (index 4)
+	teamLead, _ := getStringFromRow(row, 5)            // Column F (index 5)
+
+	d.Status = status
+	d.AssociatedTutors = processAssociatedTutors(associatedTutorsStr)
+	d.Notes = notes
+	d.TeamLead = teamLead
+	return d
+}
+
 // findRowByName searches for the student's name in column B and returns the entire row if found
 func findRowByName(ctx context.Context, sheetsService *sheets.Service, spreadsheetID, sheetName, name string) ([]interface{}, error) {
 	readRange := fmt.Sprintf("%s!B:B", sheetName) // e.g., "Current Students!B:B"
@@ -313,15 +269,6 @@ func capitalizeWords(s string) string {
 	return strings.Join(words, " ")
 }
 
-// UpdateBusinessFields updates multiple fields in the 'business' subdocument
-func (fu *FirestoreUpdater) UpdateBusinessFields(ctx context.Context, docRef *firestore.DocumentRef, updates []firestore.Update) error {
-	_, err := docRef.Update(ctx, updates)
-	if err != nil {
-		return fmt.Errorf("failed to update business fields: %v", err)
-	}
-	return nil
-}
-
 // retry executes a function up to 'maxRetries' times with exponential backoff
 func retry(maxRetries int, initialDelay time.Duration, fn func() error) error {
 	delay := initialDelay
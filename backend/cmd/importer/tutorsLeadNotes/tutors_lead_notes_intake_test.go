@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/students"
+)
+
+func TestBusinessDetailsFromRow(t *testing.T) {
+	ctx := context.Background()
+	repo := students.NewMemoryRepository()
+	id, err := repo.Create(ctx, &students.Student{
+		Personal: students.Personal{Name: "Ada Lovelace"},
+		Business: students.Business{
+			Scheduler:       "Pat",
+			TestFocus:       "ACT",
+			TestAppointment: students.TestAppointment{RegisteredForTest: true, TestDate: "10/26/2024"},
+			Status:          "Inactive",
+			LifetimeHours:   12,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := repo.Get(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	row := []interface{}{"", "Ada Lovelace", "Active", "edward/kyra only", " Weekly check-in ", "Edward"}
+	if err := repo.UpdateBusinessDetails(ctx, id, businessDetailsFromRow(s.Business.Details(), row)); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = repo.Get(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := students.Business{
+		FirebaseID:       id,
+		Scheduler:        "Pat",
+		TestFocus:        "ACT",
+		TestAppointment:  students.TestAppointment{RegisteredForTest: true, TestDate: "10/26/2024"},
+		AssociatedTutors: []string{"Edward", "Kyra"},
+		TeamLead:         "Edward",
+		Status:           "Active",
+		Notes:            "Weekly check-in",
+		LifetimeHours:    12,
+	}
+	if !reflect.DeepEqual(s.Business, want) {
+		t.Errorf("business =\n%+v\nwant\n%+v", s.Business, want)
+	}
+}
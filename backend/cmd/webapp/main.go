@@ -9,15 +9,33 @@ import (
 	"os"
 
 	firestoreupdater "github.com/NathanielJBrown97/LeeTutoringApp/cmd/firestoreupdater"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/apikeys"
 	"github.com/NathanielJBrown97/LeeTutoringApp/internal/appleauth"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/audit"
 	"github.com/NathanielJBrown97/LeeTutoringApp/internal/auth"
 	"github.com/NathanielJBrown97/LeeTutoringApp/internal/config"
 	"github.com/NathanielJBrown97/LeeTutoringApp/internal/dashboard"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/emailauth"
 	"github.com/NathanielJBrown97/LeeTutoringApp/internal/facebookauth"
 	googleauth "github.com/NathanielJBrown97/LeeTutoringApp/internal/googleauth"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/households"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/identity"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/impersonation"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/invitations"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/ledger"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/mfa"
 	microsoftauth "github.com/NathanielJBrown97/LeeTutoringApp/internal/microsoftauth"
 	"github.com/NathanielJBrown97/LeeTutoringApp/internal/middleware"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/notify"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/oauthstate"
 	parentpkg "github.com/NathanielJBrown97/LeeTutoringApp/internal/parent"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/securitylog"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/session"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/staff"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/studentdashboard"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/studentlinks"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/students"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/tokencrypt"
 	"github.com/NathanielJBrown97/LeeTutoringApp/internal/tutordashboard"
 	"github.com/NathanielJBrown97/LeeTutoringApp/internal/yahooauth"
 	"github.com/gorilla/mux"
@@ -44,9 +62,73 @@ func main() {
 	}
 	defer firestoreClient.Close()
 
+	// Typed access to the "students" collection
+	studentRepo := students.NewFirestoreRepository(firestoreClient)
+
+	// Households own a family's students and QuickBooks customer
+	householdManager := households.NewManager(households.NewFirestoreStore(firestoreClient), firestoreClient)
+
+	// Hours ledger per family
+	hoursLedger := ledger.New(firestoreClient, studentRepo)
+
+	// Low balance alerts, checked every time a family's hours change
+	mailer := notify.NewMailer(cfg)
+	lowBalanceAlerts, err := notify.NewAlerter(cfg, firestoreClient, mailer)
+	if err != nil {
+		log.Fatalf("Error loading low balance alerts: %v", err)
+	}
+	hoursLedger.Watch(lowBalanceAlerts.CheckBalance)
+
 	// Secret key for JWT
 	secretKey := cfg.SESSION_SECRET
 
+	// Sessions behind the short-lived access tokens and their refresh cookies
+	sessions := session.NewManager(secretKey, session.NewFirestoreStore(firestoreClient))
+
+	// Keys that encrypt provider and QuickBooks tokens at rest
+	tokenKeys, err := tokencrypt.ParseKeyring(cfg.TOKEN_ENCRYPTION_KEYS)
+	if err != nil {
+		log.Fatalf("Error loading token encryption keys: %v", err)
+	}
+
+	// Sign-ins, rejected tokens and changes to who can do what
+	securityLog := securitylog.NewFirestoreLog(firestoreClient)
+	securityEvents := securitylog.NewRecorder(securityLog)
+
+	// Who signs in as a tutor, team lead or admin
+	staffDirectory := staff.NewDirectory(staff.NewFirestoreStore(firestoreClient), firestoreClient, sessions, securityEvents)
+
+	// Admin actions on other users' accounts
+	auditLog := audit.NewFirestoreLog(firestoreClient)
+
+	// Authenticator apps staff sign in with as a second factor
+	mfaManager := mfa.NewManager(mfa.NewFirestoreStore(firestoreClient), tokenKeys)
+	mfaApp := &mfa.App{
+		MFA:      mfaManager,
+		Sessions: sessions,
+		Staff:    staffDirectory,
+		Audit:    auditLog,
+		Security: securityEvents,
+	}
+
+	// Keys that Apps Script and Cloud Scheduler call the backend with
+	serviceKeys := apikeys.NewManager(apikeys.NewFirestoreStore(firestoreClient))
+	apiKeysApp := &apikeys.App{Keys: serviceKeys, Audit: auditLog}
+
+	// Decides whether a login is a tutor, student or parent, for every provider
+	identityStore := identity.NewFirestoreStore(firestoreClient)
+	identityResolver := identity.NewResolver(staffDirectory, identityStore, sessions, tokenKeys, mfaManager, securityEvents)
+
+	// Admins viewing the app as a parent or student, recorded in the audit log
+	impersonationApp := &impersonation.App{
+		Sessions: sessions,
+		Accounts: identityStore,
+		Audit:    auditLog,
+		Security: securityEvents,
+	}
+	// Signed, single-use state for every OAuth flow
+	oauthStates := oauthstate.NewManager(secretKey, oauthstate.NewFirestoreStore(firestoreClient))
+
 	// Google OAuth2 configuration
 	googleConf := &oauth2.Config{
 		ClientID:     cfg.GOOGLE_CLIENT_ID,
@@ -61,6 +143,8 @@ func main() {
 		OAuthConfig:     googleConf,
 		FirestoreClient: firestoreClient,
 		SecretKey:       secretKey,
+		Identity:        identityResolver,
+		States:          oauthStates,
 	}
 
 	// Microsoft OAuth2 configuration
@@ -80,6 +164,9 @@ func main() {
 		OAuthConfig:     microsoftOauthConfig,
 		FirestoreClient: firestoreClient,
 		SecretKey:       secretKey,
+		Identity:        identityResolver,
+		States:          oauthStates,
+		Tokens:          tokenKeys,
 	}
 
 	// Yahoo OAuth2 configuration
@@ -96,6 +183,8 @@ func main() {
 		OAuthConfig:     yahooConf,
 		FirestoreClient: firestoreClient,
 		SecretKey:       secretKey,
+		Identity:        identityResolver,
+		States:          oauthStates,
 	}
 
 	// Facebook OAuth2 configuration
@@ -112,6 +201,8 @@ func main() {
 		OAuthConfig:     facebookConf,
 		FirestoreClient: firestoreClient,
 		SecretKey:       secretKey,
+		Identity:        identityResolver,
+		States:          oauthStates,
 	}
 
 	// Apple OAuth2 configuration
@@ -130,18 +221,52 @@ func main() {
 		OAuthConfig:     appleConf,
 		FirestoreClient: firestoreClient,
 		SecretKey:       secretKey,
+		Identity:        identityResolver,
+		States:          oauthStates,
 	}
 
+	// Emailed sign-in links for families without an OAuth provider
+	emailApp := emailauth.App{
+		Config:   cfg,
+		Store:    emailauth.NewFirestoreStore(firestoreClient),
+		Mailer:   mailer,
+		Identity: identityResolver,
+		Staff:    staffDirectory,
+	}
+
+	// Single-use codes tutors give parents to link a student
+	studentInvitations := invitations.NewManager(invitations.NewFirestoreStore(firestoreClient))
+	// Automatic parent links that team leads and admins have to approve
+	linkRequests := studentlinks.NewQueue(studentlinks.NewFirestoreStore(firestoreClient), func(ctx context.Context, parentID, studentID string) error {
+		_, err := householdManager.AddStudents(ctx, parentID, studentID)
+		return err
+	}, mailer)
+
 	// Initialize parent App
 	parentApp := parentpkg.App{
 		Config:          cfg,
 		FirestoreClient: firestoreClient,
+		Students:        studentRepo,
+		Households:      householdManager,
+		Invitations:     studentInvitations,
+		Accounts:        identityStore,
+		LinkRequests:    linkRequests,
 	}
 
 	// Initialize dashboard App
 	dashboardApp := dashboard.App{
 		Config:          cfg,
 		FirestoreClient: firestoreClient,
+		Students:        studentRepo,
+		Hours:           hoursLedger,
+		Alerts:          lowBalanceAlerts,
+		Households:      householdManager,
+	}
+
+	// Student portal
+	studentDashboardApp := studentdashboard.App{
+		Students:    studentRepo,
+		Assignments: studentdashboard.ClassroomAssignments,
 	}
 
 	// Initialize auth App
@@ -154,24 +279,53 @@ func main() {
 	firestoreUpdaterApp := firestoreupdater.App{
 		Config:          cfg,
 		FirestoreClient: firestoreClient,
+		Students:        studentRepo,
 	}
 
 	// Create an instance of the tutor dashboard app.
 	tutorDashboardApp := tutordashboard.App{
 		FirestoreClient: firestoreClient,
+		Students:        studentRepo,
+		Tokens:          tokenKeys,
+		Invitations:     studentInvitations,
+		LinkRequests:    linkRequests,
 		// ... initialize other fields if necessary
 	}
 	// Initialize Intuit OAuth Services
-	intuitOAuthSvc, err := intuitoauth.NewOAuthService(context.Background(), firestoreClient)
+	intuitOAuthSvc, err := intuitoauth.NewOAuthService(context.Background(), cfg, firestoreClient, hoursLedger, householdManager, oauthStates, tokenKeys)
 	if err != nil {
 		log.Fatalf("Failed to init Intuit OAuth Service: %v", err)
 	}
+	// Process queued QuickBooks webhook events in the background. Without
+	// always-on CPU this only runs during requests; /internal/intuit/drain-inbox
+	// covers the time in between.
+	go intuitOAuthSvc.RunInboxWorker(context.Background())
 
 	// Set up the HTTP server and routes using gorilla/mux
 	r := mux.NewRouter()
 
 	// Use the AuthMiddleware for protected routes
-	authMiddleware := middleware.AuthMiddleware(secretKey)
+	authMiddleware := middleware.AuthMiddleware(secretKey, sessions, securityEvents)
+
+	// Role-aware chains on top of authMiddleware. Every protected route declares one of these.
+	// tutorAuth also resolves the caller's tutor document into the request context.
+	resolveTutor := tutordashboard.ResolveTutor(firestoreClient)
+	tutorAuth := func(next http.Handler) http.Handler {
+		return authMiddleware(middleware.RequireRole(middleware.RoleTutor, middleware.RoleAdmin)(resolveTutor(next)))
+	}
+	parentAuth := func(next http.Handler) http.Handler {
+		return authMiddleware(middleware.RequireRole(middleware.RoleParent)(next))
+	}
+	adminAuth := func(next http.Handler) http.Handler {
+		return authMiddleware(middleware.RequireRole(middleware.RoleAdmin)(next))
+	}
+	studentAuth := func(next http.Handler) http.Handler {
+		return authMiddleware(middleware.RequireRole(middleware.RoleStudent)(next))
+	}
+	// Machine callers send an API key instead of a session token
+	updaterAuth := serviceKeys.Require(apikeys.ScopeFirestoreUpdater)
+	pollAuth := serviceKeys.Require(apikeys.ScopeIntuitPoll)
+	inboxAuth := serviceKeys.Require(apikeys.ScopeIntuitInbox)
 
 	// TUTOR DASHBOARD HANDLERS
 	// TUTOR TOOLS - Assign Homework route
@@ -181,12 +335,12 @@ func main() {
 			return
 		}
 		// Wrap with your auth middleware if needed.
-		authMiddleware(http.HandlerFunc(tutordashboard.AssignHomeworkHandler)).ServeHTTP(w, r)
+		tutorAuth(http.HandlerFunc(tutordashboard.AssignHomeworkHandler(firestoreClient, studentRepo))).ServeHTTP(w, r)
 	}).Methods("POST", "OPTIONS")
 
 	// Tutor Calendar Events route
 	r.HandleFunc("/api/tutor/calendar-events", func(w http.ResponseWriter, r *http.Request) {
-		authMiddleware(http.HandlerFunc(tutorDashboardApp.CalendarEventsHandler)).ServeHTTP(w, r)
+		tutorAuth(http.HandlerFunc(tutorDashboardApp.CalendarEventsHandler)).ServeHTTP(w, r)
 	}).Methods("GET", "OPTIONS")
 
 	// associate students route
@@ -196,7 +350,7 @@ func main() {
 			return
 		}
 		// Wrap with the auth middleware to ensure only authenticated tutors can trigger it.
-		authMiddleware(http.HandlerFunc(tutordashboard.AssociateStudentsHandler(firestoreClient))).ServeHTTP(w, r)
+		tutorAuth(http.HandlerFunc(tutordashboard.AssociateStudentsHandler(firestoreClient, studentRepo, staffDirectory))).ServeHTTP(w, r)
 	}).Methods("GET", "OPTIONS")
 
 	// Tutor Profile Route
@@ -205,7 +359,7 @@ func main() {
 			w.WriteHeader(http.StatusNoContent)
 			return
 		}
-		authMiddleware(http.HandlerFunc(tutordashboard.FetchTutorProfileHandler(firestoreClient))).ServeHTTP(w, r)
+		tutorAuth(http.HandlerFunc(tutordashboard.FetchTutorProfileHandler(firestoreClient))).ServeHTTP(w, r)
 	}).Methods("GET", "OPTIONS")
 
 	// Tutor Student Detail route - returns detailed student info only if the student is associated with the tutor.
@@ -214,17 +368,51 @@ func main() {
 			w.WriteHeader(http.StatusNoContent)
 			return
 		}
-		authMiddleware(http.HandlerFunc(tutorDashboardApp.TutorStudentDetailHandler)).ServeHTTP(w, r)
+		tutorAuth(http.HandlerFunc(tutorDashboardApp.TutorStudentDetailHandler)).ServeHTTP(w, r)
 	}).Methods("GET", "OPTIONS")
 
+	// Invitation codes a parent can redeem to link the student
+	r.HandleFunc("/api/tutor/students/{student_id}/invitations", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		tutorAuth(http.HandlerFunc(tutorDashboardApp.InvitationsHandler)).ServeHTTP(w, r)
+	}).Methods("GET", "POST", "OPTIONS")
+
+	// Parent links waiting for a team lead or admin to approve or reject them
+	r.HandleFunc("/api/tutor/link-requests", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		tutorAuth(http.HandlerFunc(tutorDashboardApp.LinkRequestsHandler)).ServeHTTP(w, r)
+	}).Methods("GET", "OPTIONS")
+
+	r.HandleFunc("/api/tutor/link-requests/{request_id}/approve", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		tutorAuth(http.HandlerFunc(tutorDashboardApp.ApproveLinkRequestHandler)).ServeHTTP(w, r)
+	}).Methods("POST", "OPTIONS")
+
+	r.HandleFunc("/api/tutor/link-requests/{request_id}/reject", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		tutorAuth(http.HandlerFunc(tutorDashboardApp.RejectLinkRequestHandler)).ServeHTTP(w, r)
+	}).Methods("POST", "OPTIONS")
+
 	// Tutor get students by name
 	r.HandleFunc("/api/tutor/fetch-students-by-names", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusNoContent)
 			return
 		}
-		// Wrap with authMiddleware so only authenticated tutors can access
-		authMiddleware(http.HandlerFunc(tutorDashboardApp.FetchStudentsByNamesHandler)).ServeHTTP(w, r)
+		// Wrap with tutorAuth so only authenticated tutors can access
+		tutorAuth(http.HandlerFunc(tutorDashboardApp.FetchStudentsByNamesHandler)).ServeHTTP(w, r)
 	}).Methods("POST", "OPTIONS")
 
 	// This endpoint returns the list of associated students (IDs and optionally names) for the tutor.
@@ -233,7 +421,7 @@ func main() {
 			w.WriteHeader(http.StatusNoContent)
 			return
 		}
-		authMiddleware(http.HandlerFunc(tutordashboard.FetchAssociatedStudentsHandler(firestoreClient))).ServeHTTP(w, r)
+		tutorAuth(http.HandlerFunc(tutordashboard.FetchAssociatedStudentsHandler(firestoreClient))).ServeHTTP(w, r)
 	}).Methods("GET", "OPTIONS")
 
 	// TUTOR TOOLS
@@ -243,7 +431,7 @@ func main() {
 			w.WriteHeader(http.StatusNoContent)
 			return
 		}
-		authMiddleware(http.HandlerFunc(tutordashboard.EditPersonalDetailsHandler(firestoreClient))).ServeHTTP(w, r)
+		tutorAuth(http.HandlerFunc(tutordashboard.EditPersonalDetailsHandler(firestoreClient, studentRepo))).ServeHTTP(w, r)
 	}).Methods("POST", "OPTIONS")
 
 	// get personal detalis:
@@ -252,7 +440,7 @@ func main() {
 			w.WriteHeader(http.StatusNoContent)
 			return
 		}
-		authMiddleware(http.HandlerFunc(tutordashboard.GetPersonalDetailsHandler(firestoreClient))).ServeHTTP(w, r)
+		tutorAuth(http.HandlerFunc(tutordashboard.GetPersonalDetailsHandler(firestoreClient, studentRepo))).ServeHTTP(w, r)
 	}).Methods("GET", "OPTIONS")
 
 	// Get Business Details
@@ -261,7 +449,7 @@ func main() {
 			w.WriteHeader(http.StatusNoContent)
 			return
 		}
-		authMiddleware(http.HandlerFunc(tutordashboard.GetBusinessDetailsHandler(firestoreClient))).ServeHTTP(w, r)
+		tutorAuth(http.HandlerFunc(tutordashboard.GetBusinessDetailsHandler(firestoreClient, studentRepo))).ServeHTTP(w, r)
 	}).Methods("GET", "OPTIONS")
 
 	// edit business data
@@ -270,7 +458,7 @@ func main() {
 			w.WriteHeader(http.StatusNoContent)
 			return
 		}
-		authMiddleware(http.HandlerFunc(tutordashboard.EditBusinessDetailsHandler(firestoreClient))).ServeHTTP(w, r)
+		tutorAuth(http.HandlerFunc(tutordashboard.EditBusinessDetailsHandler(firestoreClient, studentRepo))).ServeHTTP(w, r)
 	}).Methods("POST", "OPTIONS")
 
 	// delete test data
@@ -279,7 +467,7 @@ func main() {
 			w.WriteHeader(http.StatusNoContent)
 			return
 		}
-		authMiddleware(http.HandlerFunc(tutordashboard.DeleteTestDataHandler(firestoreClient))).ServeHTTP(w, r)
+		tutorAuth(http.HandlerFunc(tutordashboard.DeleteTestDataHandler(firestoreClient, studentRepo))).ServeHTTP(w, r)
 	}).Methods("POST", "OPTIONS")
 
 	// Create Test Data
@@ -288,7 +476,7 @@ func main() {
 			w.WriteHeader(http.StatusNoContent)
 			return
 		}
-		authMiddleware(http.HandlerFunc(tutordashboard.CreateTestDataHandler(firestoreClient))).ServeHTTP(w, r)
+		tutorAuth(http.HandlerFunc(tutordashboard.CreateTestDataHandler(firestoreClient, studentRepo))).ServeHTTP(w, r)
 	}).Methods("POST", "OPTIONS")
 
 	// Create Homework Completion
@@ -297,7 +485,7 @@ func main() {
 			w.WriteHeader(http.StatusNoContent)
 			return
 		}
-		authMiddleware(http.HandlerFunc(tutordashboard.CreateHomeworkCompletionHandler(firestoreClient))).ServeHTTP(w, r)
+		tutorAuth(http.HandlerFunc(tutordashboard.CreateHomeworkCompletionHandler(firestoreClient, studentRepo, hoursLedger))).ServeHTTP(w, r)
 	}).Methods("POST", "OPTIONS")
 
 	// edit Homework Completion
@@ -306,7 +494,7 @@ func main() {
 			w.WriteHeader(http.StatusNoContent)
 			return
 		}
-		authMiddleware(http.HandlerFunc(tutordashboard.EditHomeworkCompletionHandler(firestoreClient))).ServeHTTP(w, r)
+		tutorAuth(http.HandlerFunc(tutordashboard.EditHomeworkCompletionHandler(firestoreClient, studentRepo, hoursLedger))).ServeHTTP(w, r)
 	}).Methods("POST", "OPTIONS")
 	// delete Homework Completion
 	r.HandleFunc("/api/tutor/delete-homework-completion", func(w http.ResponseWriter, r *http.Request) {
@@ -314,7 +502,7 @@ func main() {
 			w.WriteHeader(http.StatusNoContent)
 			return
 		}
-		authMiddleware(http.HandlerFunc(tutordashboard.DeleteHomeworkCompletionHandler(firestoreClient))).ServeHTTP(w, r)
+		tutorAuth(http.HandlerFunc(tutordashboard.DeleteHomeworkCompletionHandler(firestoreClient, studentRepo, hoursLedger))).ServeHTTP(w, r)
 	}).Methods("POST", "OPTIONS")
 
 	// Create Test Data
@@ -323,7 +511,7 @@ func main() {
 			w.WriteHeader(http.StatusNoContent)
 			return
 		}
-		authMiddleware(http.HandlerFunc(tutordashboard.EditTestDataHandler(firestoreClient))).ServeHTTP(w, r)
+		tutorAuth(http.HandlerFunc(tutordashboard.EditTestDataHandler(firestoreClient, studentRepo))).ServeHTTP(w, r)
 	}).Methods("POST", "OPTIONS")
 
 	// create goals
@@ -332,7 +520,7 @@ func main() {
 			w.WriteHeader(http.StatusNoContent)
 			return
 		}
-		authMiddleware(http.HandlerFunc(tutordashboard.CreateGoalHandler(firestoreClient))).ServeHTTP(w, r)
+		tutorAuth(http.HandlerFunc(tutordashboard.CreateGoalHandler(firestoreClient, studentRepo))).ServeHTTP(w, r)
 	}).Methods("POST", "OPTIONS")
 
 	// delete goals
@@ -341,7 +529,7 @@ func main() {
 			w.WriteHeader(http.StatusNoContent)
 			return
 		}
-		authMiddleware(http.HandlerFunc(tutordashboard.DeleteGoalHandler(firestoreClient))).ServeHTTP(w, r)
+		tutorAuth(http.HandlerFunc(tutordashboard.DeleteGoalHandler(firestoreClient, studentRepo))).ServeHTTP(w, r)
 	}).Methods("POST", "OPTIONS")
 
 	// Edit Test Data Notes
@@ -350,9 +538,255 @@ func main() {
 			w.WriteHeader(http.StatusNoContent)
 			return
 		}
-		authMiddleware(http.HandlerFunc(tutordashboard.EditTestDatesNotesHandler(firestoreClient))).ServeHTTP(w, r)
+		tutorAuth(http.HandlerFunc(tutordashboard.EditTestDatesNotesHandler(firestoreClient, studentRepo))).ServeHTTP(w, r)
+	}).Methods("POST", "OPTIONS")
+
+	// The caller's authenticator: GET its status, DELETE turns it off
+	r.HandleFunc("/api/tutor/mfa", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		tutorAuth(http.HandlerFunc(mfaApp.StatusHandler)).ServeHTTP(w, r)
+	}).Methods("GET", "DELETE", "OPTIONS")
+
+	// Start setting up an authenticator
+	r.HandleFunc("/api/tutor/mfa/enroll", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		tutorAuth(http.HandlerFunc(mfaApp.EnrollHandler)).ServeHTTP(w, r)
+	}).Methods("POST", "OPTIONS")
+
+	// Confirm a new authenticator with its first code
+	r.HandleFunc("/api/tutor/mfa/confirm", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		tutorAuth(http.HandlerFunc(mfaApp.ConfirmHandler)).ServeHTTP(w, r)
+	}).Methods("POST", "OPTIONS")
+
+	// Replace the caller's recovery codes
+	r.HandleFunc("/api/tutor/mfa/recovery-codes", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		tutorAuth(http.HandlerFunc(mfaApp.RecoveryCodesHandler)).ServeHTTP(w, r)
+	}).Methods("POST", "OPTIONS")
+
+	// ADMIN routes
+	// Hours ledger of a family: GET lists entries, POST appends an adjustment or refund
+	r.HandleFunc("/api/admin/ledger/{family_id}/entries", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		adminAuth(http.HandlerFunc(hoursLedger.EntriesHandler)).ServeHTTP(w, r)
+	}).Methods("GET", "POST", "OPTIONS")
+
+	// Reconcile a family's ledger: GET reports, POST applies the corrections
+	r.HandleFunc("/api/admin/ledger/{family_id}/reconcile", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		adminAuth(http.HandlerFunc(hoursLedger.ReconcileHandler)).ServeHTTP(w, r)
+	}).Methods("GET", "POST", "OPTIONS")
+
+	// QuickBooks webhook inbox: GET lists events, dead-lettered ones by default
+	r.HandleFunc("/api/admin/intuit/inbox", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		adminAuth(http.HandlerFunc(intuitOAuthSvc.InboxHandler)).ServeHTTP(w, r)
+	}).Methods("GET", "OPTIONS")
+
+	// Queue a QuickBooks webhook event again with a fresh set of attempts
+	r.HandleFunc("/api/admin/intuit/inbox/{event_id}/replay", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		adminAuth(http.HandlerFunc(intuitOAuthSvc.ReplayInboxEventHandler)).ServeHTTP(w, r)
 	}).Methods("POST", "OPTIONS")
 
+	// Staff directory: GET lists members, POST adds one
+	r.HandleFunc("/api/admin/staff", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		adminAuth(http.HandlerFunc(staffDirectory.StaffHandler)).ServeHTTP(w, r)
+	}).Methods("GET", "POST", "OPTIONS")
+
+	// Staff member: PUT replaces it, DELETE removes it
+	r.HandleFunc("/api/admin/staff/{staff_id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		adminAuth(http.HandlerFunc(staffDirectory.MemberHandler)).ServeHTTP(w, r)
+	}).Methods("PUT", "DELETE", "OPTIONS")
+
+	// Start a read-only session as a parent or student
+	r.HandleFunc("/api/admin/impersonate", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		adminAuth(http.HandlerFunc(impersonationApp.StartHandler)).ServeHTTP(w, r)
+	}).Methods("POST", "OPTIONS")
+
+	// End an impersonation session early
+	r.HandleFunc("/api/admin/impersonate/{session_id}/stop", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		adminAuth(http.HandlerFunc(impersonationApp.StopHandler)).ServeHTTP(w, r)
+	}).Methods("POST", "OPTIONS")
+
+	// Staff roles that must use an authenticator: GET reads, PUT replaces
+	r.HandleFunc("/api/admin/mfa/policy", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		adminAuth(http.HandlerFunc(mfaApp.PolicyHandler)).ServeHTTP(w, r)
+	}).Methods("GET", "PUT", "OPTIONS")
+
+	// Remove a staff member's authenticator, e.g. after a lost phone
+	r.HandleFunc("/api/admin/mfa/users/{user_id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		adminAuth(http.HandlerFunc(mfaApp.ResetHandler)).ServeHTTP(w, r)
+	}).Methods("DELETE", "OPTIONS")
+
+	// API keys for machine callers: GET lists, POST creates
+	r.HandleFunc("/api/admin/api-keys", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		adminAuth(http.HandlerFunc(apiKeysApp.KeysHandler)).ServeHTTP(w, r)
+	}).Methods("GET", "POST", "OPTIONS")
+
+	// Revoke an API key
+	r.HandleFunc("/api/admin/api-keys/{key_id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		adminAuth(http.HandlerFunc(apiKeysApp.KeyHandler)).ServeHTTP(w, r)
+	}).Methods("DELETE", "OPTIONS")
+
+	// Sign-ins and other security events, filtered by type, user_id, since and until
+	r.HandleFunc("/api/admin/security-events", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		adminAuth(securitylog.EventsHandler(securityLog)).ServeHTTP(w, r)
+	}).Methods("GET", "OPTIONS")
+
+	// Sign a user out everywhere
+	r.HandleFunc("/api/admin/users/{user_id}/sessions/revoke", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		adminAuth(http.HandlerFunc(sessions.RevokeUserSessionsHandler)).ServeHTTP(w, r)
+	}).Methods("POST", "OPTIONS")
+
+	// ACCOUNT routes, for every role
+	// Login providers linked to the caller's account
+	r.HandleFunc("/api/account/identities", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		authMiddleware(http.HandlerFunc(identityResolver.IdentitiesHandler)).ServeHTTP(w, r)
+	}).Methods("GET", "OPTIONS")
+
+	// Start linking another login provider; returns the URL to sign in with it
+	r.HandleFunc("/api/account/identities/link", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		authMiddleware(http.HandlerFunc(identityResolver.LinkIdentityHandler)).ServeHTTP(w, r)
+	}).Methods("POST", "OPTIONS")
+
+	// Unlink a login provider, unless it is the account's last one
+	r.HandleFunc("/api/account/identities/{identity_id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		authMiddleware(http.HandlerFunc(identityResolver.UnlinkIdentityHandler)).ServeHTTP(w, r)
+	}).Methods("DELETE", "OPTIONS")
+
+	// STUDENT routes
+	// The student's own record with sessions, scores, test dates and goals
+	r.HandleFunc("/api/student/dashboard", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		studentAuth(http.HandlerFunc(studentDashboardApp.DashboardHandler)).ServeHTTP(w, r)
+	}).Methods("GET", "OPTIONS")
+
+	// Coursework in the student's Google Classroom that is not due yet
+	r.HandleFunc("/api/student/assignments", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		studentAuth(http.HandlerFunc(studentDashboardApp.AssignmentsHandler)).ServeHTTP(w, r)
+	}).Methods("GET", "OPTIONS")
+
+	// Session history
+	r.HandleFunc("/api/student/sessions", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		studentAuth(http.HandlerFunc(studentDashboardApp.SessionsHandler)).ServeHTTP(w, r)
+	}).Methods("GET", "OPTIONS")
+
+	// Upcoming and past test dates
+	r.HandleFunc("/api/student/test-dates", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		studentAuth(http.HandlerFunc(studentDashboardApp.TestDatesHandler)).ServeHTTP(w, r)
+	}).Methods("GET", "OPTIONS")
+
+	// Test scores
+	r.HandleFunc("/api/student/scores", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		studentAuth(http.HandlerFunc(studentDashboardApp.ScoresHandler)).ServeHTTP(w, r)
+	}).Methods("GET", "OPTIONS")
+
+	// Target colleges
+	r.HandleFunc("/api/student/goals", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		studentAuth(http.HandlerFunc(studentDashboardApp.GoalsHandler)).ServeHTTP(w, r)
+	}).Methods("GET", "OPTIONS")
+
 	// PARENT Dashboard route
 	r.HandleFunc("/api/dashboard", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == "OPTIONS" {
@@ -360,7 +794,7 @@ func main() {
 			w.WriteHeader(http.StatusNoContent)
 			return
 		}
-		authMiddleware(http.HandlerFunc(dashboardApp.Handler)).ServeHTTP(w, r)
+		parentAuth(http.HandlerFunc(dashboardApp.Handler)).ServeHTTP(w, r)
 	}).Methods("GET", "OPTIONS")
 
 	// Associated students route
@@ -370,7 +804,7 @@ func main() {
 			w.WriteHeader(http.StatusNoContent)
 			return
 		}
-		authMiddleware(http.HandlerFunc(dashboardApp.AssociatedStudentsHandler)).ServeHTTP(w, r)
+		parentAuth(http.HandlerFunc(dashboardApp.AssociatedStudentsHandler)).ServeHTTP(w, r)
 	}).Methods("GET", "OPTIONS")
 
 	// Student detail route
@@ -380,7 +814,7 @@ func main() {
 			w.WriteHeader(http.StatusNoContent)
 			return
 		}
-		authMiddleware(http.HandlerFunc(dashboardApp.StudentDetailHandler)).ServeHTTP(w, r)
+		parentAuth(http.HandlerFunc(dashboardApp.StudentDetailHandler)).ServeHTTP(w, r)
 	}).Methods("GET", "OPTIONS")
 
 	// Parent routes
@@ -390,7 +824,7 @@ func main() {
 			w.WriteHeader(http.StatusNoContent)
 			return
 		}
-		authMiddleware(http.HandlerFunc(parentApp.StudentIntakeHandler)).ServeHTTP(w, r)
+		parentAuth(http.HandlerFunc(parentApp.StudentIntakeHandler)).ServeHTTP(w, r)
 	}).Methods("POST", "OPTIONS")
 
 	r.HandleFunc("/api/confirmLinkStudents", func(w http.ResponseWriter, r *http.Request) {
@@ -399,7 +833,7 @@ func main() {
 			w.WriteHeader(http.StatusNoContent)
 			return
 		}
-		authMiddleware(http.HandlerFunc(parentApp.ConfirmLinkStudentsHandler)).ServeHTTP(w, r)
+		parentAuth(http.HandlerFunc(parentApp.ConfirmLinkStudentsHandler)).ServeHTTP(w, r)
 	}).Methods("POST", "OPTIONS")
 
 	r.HandleFunc("/api/attemptAutomaticAssociation", func(w http.ResponseWriter, r *http.Request) {
@@ -408,7 +842,7 @@ func main() {
 			w.WriteHeader(http.StatusNoContent)
 			return
 		}
-		authMiddleware(http.HandlerFunc(parentApp.AttemptAutomaticAssociation)).ServeHTTP(w, r)
+		parentAuth(http.HandlerFunc(parentApp.AttemptAutomaticAssociation)).ServeHTTP(w, r)
 	}).Methods("POST", "OPTIONS")
 
 	// api request for hours/and balance
@@ -418,16 +852,25 @@ func main() {
 			w.WriteHeader(http.StatusNoContent)
 			return
 		}
-		authMiddleware(http.HandlerFunc(dashboardApp.TotalHoursAndBalanceHandler)).ServeHTTP(w, r)
+		parentAuth(http.HandlerFunc(dashboardApp.TotalHoursAndBalanceHandler)).ServeHTTP(w, r)
 	}).Methods("GET", "OPTIONS")
 
+	// api to read and change how the family's hours are split between students
+	r.HandleFunc("/api/dashboard/hours-allocation", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		parentAuth(http.HandlerFunc(dashboardApp.HoursAllocationHandler)).ServeHTTP(w, r)
+	}).Methods("GET", "POST", "OPTIONS")
+
 	// api to update STUDENTS lifetime hours
 	r.HandleFunc("/api/students/{student_id}/update_student_lifetime_hours", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusNoContent)
 			return
 		}
-		authMiddleware(http.HandlerFunc(dashboardApp.UpdateStudentLifetimeHoursHandler)).ServeHTTP(w, r)
+		parentAuth(http.HandlerFunc(dashboardApp.UpdateStudentLifetimeHoursHandler)).ServeHTTP(w, r)
 	}).Methods("POST", "OPTIONS")
 
 	// api endpoint, updates parents used hours - iteratively updates each associated students lifetiem hours
@@ -436,7 +879,7 @@ func main() {
 			w.WriteHeader(http.StatusNoContent)
 			return
 		}
-		authMiddleware(http.HandlerFunc(dashboardApp.UpdateParentUsedHoursHandler)).ServeHTTP(w, r)
+		parentAuth(http.HandlerFunc(dashboardApp.UpdateParentUsedHoursHandler)).ServeHTTP(w, r)
 	}).Methods("POST", "OPTIONS")
 
 	// gets all parent data related to invoices, payments, voids, ect.
@@ -445,12 +888,90 @@ func main() {
 			w.WriteHeader(http.StatusNoContent)
 			return
 		}
-		authMiddleware(http.HandlerFunc(dashboardApp.GetParentInvoicesHandler)).ServeHTTP(w, r)
+		parentAuth(http.HandlerFunc(dashboardApp.GetParentInvoicesHandler)).ServeHTTP(w, r)
 	}).Methods("GET", "OPTIONS")
 
+	// The parent's household and its guardians
+	r.HandleFunc("/api/household", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		parentAuth(http.HandlerFunc(householdManager.HouseholdHandler)).ServeHTTP(w, r)
+	}).Methods("GET", "OPTIONS")
+
+	r.HandleFunc("/api/household/guardians", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		parentAuth(http.HandlerFunc(householdManager.GuardiansHandler)).ServeHTTP(w, r)
+	}).Methods("POST", "OPTIONS")
+
+	r.HandleFunc("/api/household/guardians/{guardian_id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		parentAuth(http.HandlerFunc(householdManager.GuardianHandler)).ServeHTTP(w, r)
+	}).Methods("PUT", "DELETE", "OPTIONS")
+
+	// Invitations to join another parent's household
+	r.HandleFunc("/api/household/invitations", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		parentAuth(http.HandlerFunc(householdManager.InvitationsHandler)).ServeHTTP(w, r)
+	}).Methods("GET", "OPTIONS")
+
+	r.HandleFunc("/api/household/invitations/{household_id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		parentAuth(http.HandlerFunc(householdManager.InvitationHandler)).ServeHTTP(w, r)
+	}).Methods("POST", "DELETE", "OPTIONS")
+
 	// Auth status route
 	r.Handle("/api/auth/status", authMiddleware(http.HandlerFunc(authApp.StatusHandler))).Methods("GET", "OPTIONS")
 
+	// Swap the refresh cookie for a new access token
+	r.HandleFunc("/api/auth/refresh", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		sessions.RefreshHandler(w, r)
+	}).Methods("POST", "OPTIONS")
+
+	// End the session of the refresh cookie
+	r.HandleFunc("/api/auth/logout", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		sessions.LogoutHandler(w, r)
+	}).Methods("POST", "OPTIONS")
+
+	// Set up an authenticator during a sign-in that requires one
+	r.HandleFunc("/api/auth/mfa/enroll", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		mfaApp.ChallengeEnrollHandler(w, r)
+	}).Methods("POST", "OPTIONS")
+
+	// Finish a staff sign-in with a code from their authenticator
+	r.HandleFunc("/api/auth/mfa/verify", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		mfaApp.ChallengeVerifyHandler(w, r)
+	}).Methods("POST", "OPTIONS")
+
 	// ParentHandler route
 	r.HandleFunc("/api/parent", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == "OPTIONS" {
@@ -458,7 +979,7 @@ func main() {
 			w.WriteHeader(http.StatusNoContent)
 			return
 		}
-		authMiddleware(http.HandlerFunc(dashboardApp.ParentHandler)).ServeHTTP(w, r)
+		parentAuth(http.HandlerFunc(dashboardApp.ParentHandler)).ServeHTTP(w, r)
 	}).Methods("GET", "OPTIONS")
 
 	r.HandleFunc("/api/updateInvoiceEmail", func(w http.ResponseWriter, r *http.Request) {
@@ -467,7 +988,7 @@ func main() {
 			return
 		}
 		// Wrap with your auth middleware if required
-		authMiddleware(http.HandlerFunc(dashboardApp.UpdateInvoiceEmailHandler)).ServeHTTP(w, r)
+		parentAuth(http.HandlerFunc(dashboardApp.UpdateInvoiceEmailHandler)).ServeHTTP(w, r)
 	}).Methods("POST", "OPTIONS")
 
 	r.HandleFunc("/api/getInvoiceEmail", func(w http.ResponseWriter, r *http.Request) {
@@ -476,7 +997,7 @@ func main() {
 			return
 		}
 		// Wrap with your auth middleware if required
-		authMiddleware(http.HandlerFunc(dashboardApp.GetInvoiceEmailHandler)).ServeHTTP(w, r)
+		parentAuth(http.HandlerFunc(dashboardApp.GetInvoiceEmailHandler)).ServeHTTP(w, r)
 	}).Methods("GET", "OPTIONS")
 
 	// intuit related handlers including oauth
@@ -490,16 +1011,17 @@ func main() {
 
 	// intuit webhook for keeping invoices up to date
 	r.HandleFunc("/internal/intuit/webhook", intuitOAuthSvc.HandleWebhook).Methods("POST")
-	// intuit daily poll endpoint (GCP Cloud Scheduler)
+	// intuit daily poll endpoint (GCP Cloud Scheduler, with an API key)
 	r.HandleFunc("/internal/intuit/daily-poll", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == "OPTIONS" {
 			// Handle preflight request
 			w.WriteHeader(http.StatusNoContent)
 			return
 		}
-		// Just call the new poll handler method you added in webhook.go
-		intuitOAuthSvc.HandleDailyPoll(w, r)
+		pollAuth(http.HandlerFunc(intuitOAuthSvc.HandleDailyPoll)).ServeHTTP(w, r)
 	}).Methods("GET", "OPTIONS")
+	// intuit webhook inbox drain (GCP Cloud Scheduler every few minutes, with an API key)
+	r.Handle("/internal/intuit/drain-inbox", inboxAuth(http.HandlerFunc(intuitOAuthSvc.DrainInboxHandler))).Methods("GET")
 	// OAUTH HANDLERS
 
 	// Google OAuth handlers
@@ -524,7 +1046,18 @@ func main() {
 	r.HandleFunc("/internal/appleauth/oauth", appleApp.OAuthHandler).Methods("GET")
 	r.HandleFunc("/internal/appleauth/callback", appleApp.OAuthCallbackHandler).Methods("POST")
 
-	// Firestore Updater Routes with CORS and OPTIONS handling
+	// Email magic-link handlers
+	r.HandleFunc("/api/auth/magic-link", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		emailApp.RequestLinkHandler(w, r)
+	}).Methods("POST", "OPTIONS")
+	r.HandleFunc("/internal/emailauth/callback", emailApp.CallbackHandler).Methods("GET", "POST")
+
+	// Firestore Updater Routes with CORS and OPTIONS handling, called from
+	// Apps Script with an API key
 
 	// Initialize New Student
 	r.HandleFunc("/cmd/firestoreupdater/initializeNewStudent", func(w http.ResponseWriter, r *http.Request) {
@@ -533,7 +1066,7 @@ func main() {
 			w.WriteHeader(http.StatusNoContent)
 			return
 		}
-		firestoreupdater.InitializeNewStudent(w, r)
+		updaterAuth(http.HandlerFunc(firestoreupdater.InitializeNewStudent)).ServeHTTP(w, r)
 	}).Methods("POST", "OPTIONS")
 
 	// Homework Completion
@@ -543,7 +1076,7 @@ func main() {
 			w.WriteHeader(http.StatusNoContent)
 			return
 		}
-		firestoreUpdaterApp.UpdateHomeworkCompletionHandler(w, r)
+		updaterAuth(http.HandlerFunc(firestoreUpdaterApp.UpdateHomeworkCompletionHandler)).ServeHTTP(w, r)
 	}).Methods("POST", "OPTIONS")
 
 	// Test Data
@@ -553,7 +1086,7 @@ func main() {
 			w.WriteHeader(http.StatusNoContent)
 			return
 		}
-		firestoreUpdaterApp.UpdateTestDataHandler(w, r)
+		updaterAuth(http.HandlerFunc(firestoreUpdaterApp.UpdateTestDataHandler)).ServeHTTP(w, r)
 	}).Methods("POST", "OPTIONS")
 
 	// Test Dates Trigger
@@ -563,7 +1096,7 @@ func main() {
 			w.WriteHeader(http.StatusNoContent)
 			return
 		}
-		firestoreUpdaterApp.UpdateTestDatesHandler(w, r)
+		updaterAuth(http.HandlerFunc(firestoreUpdaterApp.UpdateTestDatesHandler)).ServeHTTP(w, r)
 	}).Methods("POST", "OPTIONS")
 
 	// Update Goals
@@ -573,7 +1106,7 @@ func main() {
 			w.WriteHeader(http.StatusNoContent)
 			return
 		}
-		firestoreUpdaterApp.UpdateGoalsHandler(w, r)
+		updaterAuth(http.HandlerFunc(firestoreUpdaterApp.UpdateGoalsHandler)).ServeHTTP(w, r)
 	}).Methods("POST", "OPTIONS")
 
 	// Profile Data
@@ -583,7 +1116,7 @@ func main() {
 			w.WriteHeader(http.StatusNoContent)
 			return
 		}
-		firestoreUpdaterApp.UpdateProfileHandler(w, r)
+		updaterAuth(http.HandlerFunc(firestoreUpdaterApp.UpdateProfileHandler)).ServeHTTP(w, r)
 	}).Methods("POST", "OPTIONS")
 
 	// Use CORS middleware
@@ -591,6 +1124,7 @@ func main() {
 		AllowedOrigins:   []string{"https://lee-tutoring-webapp.web.app", "http://localhost:3000"},
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Content-Type", "Authorization"},
+		ExposedHeaders:   []string{middleware.ImpersonatedByHeader},
 		AllowCredentials: true,
 		Debug:            false, // Disable debug mode in production
 	})
@@ -0,0 +1,204 @@
+// backend/internal/apikeys/apikeys.go
+
+package apikeys
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// Machine callers, such as the Apps Script behind the intake form and Cloud
+// Scheduler, authenticate with an API key instead of a user session. A key
+// is shown once when an admin creates it; only its hash is stored. Each key
+// carries the scopes it may be used for, and can be revoked.
+
+// Scopes a key can be granted.
+const (
+	// ScopeFirestoreUpdater allows the /cmd/firestoreupdater routes the
+	// Google Sheets scripts call.
+	ScopeFirestoreUpdater = "firestoreupdater"
+	// ScopeIntuitPoll allows the daily QuickBooks invoice poll.
+	ScopeIntuitPoll = "intuit.daily_poll"
+	// ScopeIntuitInbox allows draining the QuickBooks webhook inbox.
+	ScopeIntuitInbox = "intuit.inbox"
+)
+
+// Scopes lists every scope, for validating new keys.
+var Scopes = []string{ScopeFirestoreUpdater, ScopeIntuitPoll, ScopeIntuitInbox}
+
+const (
+	// Header is the request header a key is sent in.
+	Header = "X-API-Key"
+	// prefix starts every key, so a leaked one is easy to recognise.
+	prefix = "ltk"
+	// touchInterval is how stale last_used_at may get before a request
+	// writes it again, so busy keys don't write on every call.
+	touchInterval = time.Minute
+)
+
+var (
+	ErrNotFound     = errors.New("API key not found")
+	ErrInvalidKey   = errors.New("invalid API key")
+	ErrRevoked      = errors.New("API key revoked")
+	ErrScope        = errors.New("API key not allowed for this scope")
+	ErrInvalidScope = errors.New("invalid API key scope")
+	ErrNameRequired = errors.New("API key name is required")
+)
+
+// Key is a document in the "api_keys" collection, keyed by the ID part of
+// the key.
+type Key struct {
+	ID string `firestore:"-" json:"id"`
+	// Name says what uses the key, e.g. "intake form script".
+	Name   string   `firestore:"name" json:"name"`
+	Scopes []string `firestore:"scopes" json:"scopes"`
+	// SecretHash is the SHA-256 of the secret part of the key.
+	SecretHash string     `firestore:"secret_hash" json:"-"`
+	CreatedBy  string     `firestore:"created_by" json:"created_by"`
+	CreatedAt  time.Time  `firestore:"created_at" json:"created_at"`
+	LastUsedAt *time.Time `firestore:"last_used_at,omitempty" json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `firestore:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+	RevokedBy  string     `firestore:"revoked_by,omitempty" json:"revoked_by,omitempty"`
+}
+
+// Allows reports whether k was granted scope.
+func (k *Key) Allows(scope string) bool {
+	return contains(k.Scopes, scope)
+}
+
+// Manager issues and checks API keys.
+type Manager struct {
+	store Store
+	now   func() time.Time
+}
+
+// NewManager returns a Manager over store.
+func NewManager(store Store) *Manager {
+	return &Manager{store: store, now: time.Now}
+}
+
+// Create issues a key named name for scopes and returns it along with the
+// key itself, which cannot be recovered later.
+func (m *Manager) Create(ctx context.Context, name string, scopes []string, adminID string) (*Key, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, "", ErrNameRequired
+	}
+	if len(scopes) == 0 {
+		return nil, "", fmt.Errorf("%w: at least one is required", ErrInvalidScope)
+	}
+	granted := []string{}
+	for _, s := range scopes {
+		if !contains(Scopes, s) {
+			return nil, "", fmt.Errorf("%w: %q", ErrInvalidScope, s)
+		}
+		if !contains(granted, s) {
+			granted = append(granted, s)
+		}
+	}
+
+	id, err := randomHex(8)
+	if err != nil {
+		return nil, "", err
+	}
+	secret, err := randomHex(32)
+	if err != nil {
+		return nil, "", err
+	}
+	k := &Key{
+		ID:         id,
+		Name:       name,
+		Scopes:     granted,
+		SecretHash: hash(secret),
+		CreatedBy:  adminID,
+		CreatedAt:  m.now(),
+	}
+	if err := m.store.Create(ctx, k); err != nil {
+		return nil, "", err
+	}
+	return k, prefix + "_" + id + "_" + secret, nil
+}
+
+// List returns every key, revoked ones included.
+func (m *Manager) List(ctx context.Context) ([]*Key, error) {
+	return m.store.List(ctx)
+}
+
+// Revoke stops key id from working. Revoking a revoked key does nothing.
+func (m *Manager) Revoke(ctx context.Context, id, adminID string) (*Key, error) {
+	var revoked *Key
+	err := m.store.Update(ctx, id, func(k *Key) error {
+		if k.RevokedAt == nil {
+			now := m.now()
+			k.RevokedAt, k.RevokedBy = &now, adminID
+		}
+		revoked = k
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return revoked, nil
+}
+
+// Authenticate returns the key that raw is, if it is valid, not revoked and
+// granted scope.
+func (m *Manager) Authenticate(ctx context.Context, raw, scope string) (*Key, error) {
+	parts := strings.Split(strings.TrimSpace(raw), "_")
+	if len(parts) != 3 || parts[0] != prefix || parts[1] == "" || parts[2] == "" {
+		return nil, ErrInvalidKey
+	}
+	k, found, err := m.store.Get(ctx, parts[1])
+	if err != nil {
+		return nil, err
+	}
+	if !found || subtle.ConstantTimeCompare([]byte(hash(parts[2])), []byte(k.SecretHash)) != 1 {
+		return nil, ErrInvalidKey
+	}
+	if k.RevokedAt != nil {
+		return nil, ErrRevoked
+	}
+	if !k.Allows(scope) {
+		return nil, ErrScope
+	}
+
+	now := m.now()
+	if k.LastUsedAt == nil || now.Sub(*k.LastUsedAt) >= touchInterval {
+		// The call goes ahead even if this fails.
+		if err := m.store.Touch(ctx, k.ID, now); err != nil {
+			log.Printf("Error recording use of API key %s: %v", k.ID, err)
+		}
+		k.LastUsedAt = &now
+	}
+	return k, nil
+}
+
+func hash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
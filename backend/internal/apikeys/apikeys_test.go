@@ -0,0 +1,138 @@
+// backend/internal/apikeys/apikeys_test.go
+
+package apikeys
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestKey creates a key for the daily poll and returns it with the raw
+// key string.
+func newTestKey(t *testing.T, m *Manager) (*Key, string) {
+	t.Helper()
+	k, raw, err := m.Create(context.Background(), "scheduler", []string{ScopeIntuitPoll}, "admin-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k, raw
+}
+
+func TestCreate(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	m := NewManager(store)
+
+	k, raw, err := m.Create(ctx, " intake form ", []string{ScopeFirestoreUpdater, ScopeIntuitPoll, ScopeFirestoreUpdater}, "admin-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !regexp.MustCompile(`^ltk_[0-9a-f]{16}_[0-9a-f]{64}$`).MatchString(raw) || !strings.HasPrefix(raw, "ltk_"+k.ID+"_") {
+		t.Errorf("key = %q, want ltk_<id>_<secret> for %s", raw, k.ID)
+	}
+	if k.Name != "intake form" || len(k.Scopes) != 2 || k.CreatedBy != "admin-1" {
+		t.Errorf("key = %+v, want two scopes under the trimmed name", k)
+	}
+
+	// Only the SHA-256 of the secret is stored.
+	secret := raw[strings.LastIndex(raw, "_")+1:]
+	sum := sha256.Sum256([]byte(secret))
+	stored, _, _ := store.Get(ctx, k.ID)
+	if stored.SecretHash != hex.EncodeToString(sum[:]) || strings.Contains(stored.SecretHash, secret) {
+		t.Errorf("stored secret_hash = %q, want the SHA-256 of the secret", stored.SecretHash)
+	}
+
+	if _, _, err := m.Create(ctx, " ", []string{ScopeIntuitPoll}, "admin-1"); !errors.Is(err, ErrNameRequired) {
+		t.Errorf("Create without a name error = %v, want %v", err, ErrNameRequired)
+	}
+	for _, scopes := range [][]string{nil, {"admin"}, {ScopeIntuitPoll, "everything"}} {
+		if _, _, err := m.Create(ctx, "script", scopes, "admin-1"); !errors.Is(err, ErrInvalidScope) {
+			t.Errorf("Create with scopes %v error = %v, want %v", scopes, err, ErrInvalidScope)
+		}
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	ctx := context.Background()
+	m := NewManager(NewMemoryStore())
+	k, raw := newTestKey(t, m)
+	secret := raw[strings.LastIndex(raw, "_")+1:]
+	other, otherRaw := newTestKey(t, m)
+	if _, err := m.Revoke(ctx, other.ID, "admin-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		raw   string
+		scope string
+		want  error
+	}{
+		{"valid", raw, ScopeIntuitPoll, nil},
+		{"surrounding space", " " + raw + "\n", ScopeIntuitPoll, nil},
+		{"empty", "", ScopeIntuitPoll, ErrInvalidKey},
+		{"prefix only", "ltk", ScopeIntuitPoll, ErrInvalidKey},
+		{"wrong prefix", "key_" + k.ID + "_" + secret, ScopeIntuitPoll, ErrInvalidKey},
+		{"no ID", "ltk__" + secret, ScopeIntuitPoll, ErrInvalidKey},
+		{"no secret", "ltk_" + k.ID + "_", ScopeIntuitPoll, ErrInvalidKey},
+		{"extra part", raw + "_extra", ScopeIntuitPoll, ErrInvalidKey},
+		{"unknown ID", "ltk_0000000000000000_" + secret, ScopeIntuitPoll, ErrInvalidKey},
+		{"wrong secret", "ltk_" + k.ID + "_" + strings.Repeat("0", 64), ScopeIntuitPoll, ErrInvalidKey},
+		{"secret of another key", "ltk_" + k.ID + "_" + otherRaw[strings.LastIndex(otherRaw, "_")+1:], ScopeIntuitPoll, ErrInvalidKey},
+		{"revoked", otherRaw, ScopeIntuitPoll, ErrRevoked},
+		{"wrong scope", raw, ScopeFirestoreUpdater, ErrScope},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := m.Authenticate(ctx, tt.raw, tt.scope)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Authenticate error = %v, want %v", err, tt.want)
+			}
+			if err == nil && got.ID != k.ID {
+				t.Errorf("Authenticate = %s, want %s", got.ID, k.ID)
+			}
+		})
+	}
+}
+
+func TestAuthenticateTouchesLastUsed(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	m := NewManager(store)
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+	k, raw := newTestKey(t, m)
+
+	lastUsed := func() time.Time {
+		stored, _, _ := store.Get(ctx, k.ID)
+		if stored.LastUsedAt == nil {
+			return time.Time{}
+		}
+		return *stored.LastUsedAt
+	}
+
+	first := now
+	if _, err := m.Authenticate(ctx, raw, ScopeIntuitPoll); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(touchInterval / 2)
+	if _, err := m.Authenticate(ctx, raw, ScopeIntuitPoll); err != nil {
+		t.Fatal(err)
+	}
+	if got := lastUsed(); !got.Equal(first) {
+		t.Errorf("last_used_at = %v, want %v until the touch interval passes", got, first)
+	}
+	now = first.Add(touchInterval)
+	if _, err := m.Authenticate(ctx, raw, ScopeIntuitPoll); err != nil {
+		t.Fatal(err)
+	}
+	if got := lastUsed(); !got.Equal(now) {
+		t.Errorf("last_used_at = %v, want %v", got, now)
+	}
+}
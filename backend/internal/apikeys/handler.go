@@ -0,0 +1,139 @@
+// backend/internal/apikeys/handler.go
+
+package apikeys
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/audit"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/middleware"
+	"github.com/gorilla/mux"
+)
+
+// App holds the dependencies of the API key handlers.
+type App struct {
+	Keys  *Manager
+	Audit audit.Log
+}
+
+type createRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+type createResponse struct {
+	*Key
+	// APIKey is the key to give the caller. It is only returned here.
+	APIKey string `json:"api_key"`
+}
+
+// Require returns middleware that only lets the request through when it
+// carries a valid, unrevoked key granted scope in the X-API-Key header.
+func (m *Manager) Require(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			raw := r.Header.Get(Header)
+			if raw == "" {
+				http.Error(w, "Missing API key", http.StatusUnauthorized)
+				return
+			}
+			k, err := m.Authenticate(r.Context(), raw, scope)
+			switch {
+			case errors.Is(err, ErrInvalidKey), errors.Is(err, ErrRevoked):
+				log.Printf("Rejected %s %s: %v", r.Method, r.URL.Path, err)
+				http.Error(w, "Invalid API key", http.StatusUnauthorized)
+				return
+			case errors.Is(err, ErrScope):
+				log.Printf("Rejected %s %s for an API key without scope %q", r.Method, r.URL.Path, scope)
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			case err != nil:
+				log.Printf("Error checking API key: %v", err)
+				http.Error(w, "Failed to check API key", http.StatusInternalServerError)
+				return
+			}
+			log.Printf("API key %s (%s) called %s %s", k.ID, k.Name, r.Method, r.URL.Path)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// KeysHandler handles /api/admin/api-keys. GET lists the keys; POST with
+// {"name": "...", "scopes": ["firestoreupdater"]} creates one and returns it
+// with the key itself, which is not shown again.
+func (a *App) KeysHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if r.Method != http.MethodPost {
+		keys, err := a.Keys.List(ctx)
+		if err != nil {
+			log.Printf("Error listing API keys: %v", err)
+			http.Error(w, "Failed to list API keys", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(keys)
+		return
+	}
+
+	var req createRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	adminID, _ := middleware.ExtractUserIDFromContext(ctx)
+	k, raw, err := a.Keys.Create(ctx, req.Name, req.Scopes, adminID)
+	if errors.Is(err, ErrNameRequired) || errors.Is(err, ErrInvalidScope) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("Error creating API key: %v", err)
+		http.Error(w, "Failed to create API key", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Admin %s created API key %s (%s) with scopes %v", adminID, k.ID, k.Name, k.Scopes)
+	a.record(ctx, audit.Event{Action: audit.APIKeyCreate, ActorID: adminID, TargetID: k.ID, Reason: k.Name})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(createResponse{Key: k, APIKey: raw})
+}
+
+// KeyHandler handles DELETE /api/admin/api-keys/{key_id}, revoking the key.
+// The key stays listed, with when and by whom it was revoked.
+func (a *App) KeyHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := mux.Vars(r)["key_id"]
+	if id == "" {
+		http.Error(w, "Key ID is required", http.StatusBadRequest)
+		return
+	}
+	adminID, _ := middleware.ExtractUserIDFromContext(ctx)
+	k, err := a.Keys.Revoke(ctx, id, adminID)
+	if errors.Is(err, ErrNotFound) {
+		http.Error(w, "API key not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error revoking API key %s: %v", id, err)
+		http.Error(w, "Failed to revoke API key", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Admin %s revoked API key %s (%s)", adminID, k.ID, k.Name)
+	a.record(ctx, audit.Event{Action: audit.APIKeyRevoke, ActorID: adminID, TargetID: k.ID, Reason: k.Name})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(k)
+}
+
+// record writes e to the audit log. A failure is logged: what the admin did
+// has already happened.
+func (a *App) record(ctx context.Context, e audit.Event) {
+	if err := a.Audit.Record(ctx, e); err != nil {
+		log.Printf("Error recording %s by %s in the audit log: %v", e.Action, e.ActorID, err)
+	}
+}
@@ -0,0 +1,50 @@
+// backend/internal/apikeys/handler_test.go
+
+package apikeys
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequire(t *testing.T) {
+	m := NewManager(NewMemoryStore())
+	_, raw := newTestKey(t, m)
+	revoked, revokedRaw := newTestKey(t, m)
+	if _, err := m.Revoke(context.Background(), revoked.ID, "admin-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		key   string
+		scope string
+		want  int
+	}{
+		{"allowed", raw, ScopeIntuitPoll, http.StatusOK},
+		{"missing", "", ScopeIntuitPoll, http.StatusUnauthorized},
+		{"malformed", "ltk_" + raw, ScopeIntuitPoll, http.StatusUnauthorized},
+		{"revoked", revokedRaw, ScopeIntuitPoll, http.StatusUnauthorized},
+		{"wrong scope", raw, ScopeFirestoreUpdater, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			h := m.Require(tt.scope)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { called = true }))
+			req := httptest.NewRequest(http.MethodGet, "/internal/intuit/daily-poll", nil)
+			if tt.key != "" {
+				req.Header.Set(Header, tt.key)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if called != (tt.want == http.StatusOK) {
+				t.Errorf("handler called = %v after status %d", called, rec.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+// backend/internal/apikeys/store.go
+
+package apikeys
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Store keeps API keys by ID.
+type Store interface {
+	Create(ctx context.Context, k *Key) error
+	Get(ctx context.Context, id string) (*Key, bool, error)
+	// List returns every key, newest first.
+	List(ctx context.Context) ([]*Key, error)
+	// Update applies fn to the key atomically, failing with ErrNotFound if
+	// there is none.
+	Update(ctx context.Context, id string, fn func(*Key) error) error
+	// Touch sets when the key was last used.
+	Touch(ctx context.Context, id string, at time.Time) error
+}
+
+type firestoreStore struct {
+	client *firestore.Client
+}
+
+// NewFirestoreStore returns a Store backed by the "api_keys" collection.
+func NewFirestoreStore(client *firestore.Client) Store {
+	return &firestoreStore{client: client}
+}
+
+func (f *firestoreStore) key(id string) *firestore.DocumentRef {
+	return f.client.Collection("api_keys").Doc(id)
+}
+
+func (f *firestoreStore) Create(ctx context.Context, k *Key) error {
+	_, err := f.key(k.ID).Create(ctx, k)
+	return err
+}
+
+func (f *firestoreStore) Get(ctx context.Context, id string) (*Key, bool, error) {
+	snap, err := f.key(id).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	var k Key
+	if err := snap.DataTo(&k); err != nil {
+		return nil, false, err
+	}
+	k.ID = id
+	return &k, true, nil
+}
+
+func (f *firestoreStore) List(ctx context.Context) ([]*Key, error) {
+	snaps, err := f.client.Collection("api_keys").OrderBy("created_at", firestore.Desc).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]*Key, 0, len(snaps))
+	for _, snap := range snaps {
+		var k Key
+		if err := snap.DataTo(&k); err != nil {
+			return nil, err
+		}
+		k.ID = snap.Ref.ID
+		keys = append(keys, &k)
+	}
+	return keys, nil
+}
+
+func (f *firestoreStore) Update(ctx context.Context, id string, fn func(*Key) error) error {
+	ref := f.key(id)
+	return f.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		snap, err := tx.Get(ref)
+		if status.Code(err) == codes.NotFound {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		var k Key
+		if err := snap.DataTo(&k); err != nil {
+			return err
+		}
+		k.ID = id
+		if err := fn(&k); err != nil {
+			return err
+		}
+		return tx.Set(ref, &k)
+	})
+}
+
+func (f *firestoreStore) Touch(ctx context.Context, id string, at time.Time) error {
+	_, err := f.key(id).Update(ctx, []firestore.Update{{Path: "last_used_at", Value: at}})
+	return err
+}
+
+// MemoryStore is an in-memory Store for tests and local runs.
+type MemoryStore struct {
+	mu   sync.Mutex
+	keys map[string]Key
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{keys: map[string]Key{}}
+}
+
+func (m *MemoryStore) Create(ctx context.Context, k *Key) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.keys[k.ID]; ok {
+		return status.Errorf(codes.AlreadyExists, "API key %s already exists", k.ID)
+	}
+	m.keys[k.ID] = copyKey(k)
+	return nil
+}
+
+func (m *MemoryStore) Get(ctx context.Context, id string) (*Key, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	k, ok := m.keys[id]
+	if !ok {
+		return nil, false, nil
+	}
+	k = copyKey(&k)
+	return &k, true, nil
+}
+
+func (m *MemoryStore) List(ctx context.Context) ([]*Key, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	keys := make([]*Key, 0, len(m.keys))
+	for _, k := range m.keys {
+		k = copyKey(&k)
+		keys = append(keys, &k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.After(keys[j].CreatedAt) })
+	return keys, nil
+}
+
+func (m *MemoryStore) Update(ctx context.Context, id string, fn func(*Key) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	k, ok := m.keys[id]
+	if !ok {
+		return ErrNotFound
+	}
+	k = copyKey(&k)
+	if err := fn(&k); err != nil {
+		return err
+	}
+	m.keys[id] = k
+	return nil
+}
+
+func (m *MemoryStore) Touch(ctx context.Context, id string, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	k, ok := m.keys[id]
+	if !ok {
+		return ErrNotFound
+	}
+	k.LastUsedAt = &at
+	m.keys[id] = k
+	return nil
+}
+
+// copyKey returns a copy of k that shares nothing with it.
+func copyKey(k *Key) Key {
+	c := *k
+	c.Scopes = append([]string(nil), k.Scopes...)
+	return c
+}
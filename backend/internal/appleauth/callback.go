@@ -15,12 +15,11 @@ import (
 	"strings"
 	"time"
 
-	"cloud.google.com/go/firestore"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/identity"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/oauthstate"
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/lestrrat-go/jwx/jwk"
 	"golang.org/x/oauth2"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 )
 
 func (a *App) OAuthCallbackHandler(w http.ResponseWriter, r *http.Request) {
@@ -40,6 +39,12 @@ func (a *App) OAuthCallbackHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Failed to parse form data", http.StatusBadRequest)
 		return
 	}
+	flow, err := a.States.Verify(w, r, "apple", r.FormValue("state"))
+	if err != nil {
+		oauthstate.Error(w, err)
+		return
+	}
+
 	code := r.FormValue("code")
 	if code == "" {
 		http.Error(w, "Code not found in the request", http.StatusBadRequest)
@@ -90,7 +95,7 @@ func (a *App) OAuthCallbackHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	log.Printf("Apple OAuth exchange successful. AccessToken=%s", token.AccessToken)
+	log.Printf("Apple OAuth exchange successful")
 
 	// Apple should also return an ID token
 	idTokenStr, ok := token.Extra("id_token").(string)
@@ -107,6 +112,11 @@ func (a *App) OAuthCallbackHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if nonce, _ := claims["nonce"].(string); nonce != flow.Nonce {
+		http.Error(w, "ID token nonce does not match", http.StatusBadRequest)
+		return
+	}
+
 	// Extract "sub" (Apple's unique user ID)
 	userID, ok := claims["sub"].(string)
 	if !ok || userID == "" {
@@ -116,127 +126,25 @@ func (a *App) OAuthCallbackHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Attempt to retrieve email from the token claims
 	email, _ := claims["email"].(string)
+	// Apple sends email_verified as a boolean or as the string "true".
 	emailVerified, _ := claims["email_verified"].(bool)
+	if s, ok := claims["email_verified"].(string); ok {
+		emailVerified = s == "true"
+	}
 	if !emailVerified {
 		log.Println("Warning: Apple user email is not verified")
 	}
 
-	// If Apple doesn't provide email this time, we can see if we have it stored from a prior login
-	firestoreClient := a.FirestoreClient
-	if email == "" {
-		docRef := firestoreClient.Collection("parents").Doc(userID)
-		docSnap, err := docRef.Get(context.Background())
-		if err != nil {
-			if status.Code(err) == codes.NotFound {
-				// This is the user's first login but Apple didn't provide an email => can't proceed
-				http.Error(w, "Email not provided by Apple and user does not exist", http.StatusBadRequest)
-				return
-			}
-			http.Error(w, "Error checking user record in Firestore", http.StatusInternalServerError)
-			return
-		}
-		oldData := docSnap.Data()
-		storedEmail, ok := oldData["email"].(string)
-		if !ok || storedEmail == "" {
-			http.Error(w, "User record found but no email stored", http.StatusBadRequest)
-			return
-		}
-		email = storedEmail
-	}
-
-	// Apple doesn't provide a picture in the claims
-	pictureURL := ""
-
-	// Create your own JWT for the front-end, storing userID and email
-	tokenClaims := jwt.MapClaims{
-		"user_id": userID,
-		"email":   email,
-		"exp":     time.Now().Add(7 * 24 * time.Hour).Unix(), // one-week expiry
-	}
-	myJWT := jwt.NewWithClaims(jwt.SigningMethodHS256, tokenClaims)
-	signedJWT, err := myJWT.SignedString([]byte(a.SecretKey))
-	if err != nil {
-		log.Printf("Failed to create session JWT token: %v", err)
-		http.Error(w, "Failed to create session token", http.StatusInternalServerError)
-		return
-	}
-
-	// Determine the Firestore collection based on the email domain.
-	// If the email ends with "@leetutoring.com", use the "tutors" collection.
-	collectionName := "parents"
-	if strings.HasSuffix(email, "@leetutoring.com") {
-		collectionName = "tutors"
-	}
-
-	// Now store/update the user in Firestore using the determined collection
-	docRef := firestoreClient.Collection(collectionName).Doc(userID)
-	docSnap, err := docRef.Get(context.Background())
-	if err != nil {
-		if status.Code(err) == codes.NotFound {
-			// New user
-			_, err = docRef.Set(context.Background(), map[string]interface{}{
-				"user_id":             userID,
-				"email":               email,
-				"name":                name,
-				"picture":             pictureURL,
-				"access_token":        token.AccessToken,
-				"refresh_token":       token.RefreshToken,
-				"expiry":              token.Expiry,
-				"associated_students": []interface{}{},
-				"created_at":          time.Now(),
-			})
-			if err != nil {
-				log.Printf("Failed to create user doc: %v", err)
-				http.Error(w, "Failed to create user document in Firestore", http.StatusInternalServerError)
-				return
-			}
-		} else {
-			log.Printf("Error checking user existence in Firestore: %v", err)
-			http.Error(w, "Failed to check user existence in Firestore", http.StatusInternalServerError)
-			return
-		}
-	} else {
-		// Existing user, update as needed
-		existingData := docSnap.Data()
-		updates := []firestore.Update{}
-		needsUpdate := false
-
-		if existingData["access_token"] != token.AccessToken {
-			updates = append(updates, firestore.Update{Path: "access_token", Value: token.AccessToken})
-			needsUpdate = true
-		}
-		if existingData["refresh_token"] != token.RefreshToken && token.RefreshToken != "" {
-			updates = append(updates, firestore.Update{Path: "refresh_token", Value: token.RefreshToken})
-			needsUpdate = true
-		}
-		if existingData["expiry"] != token.Expiry {
-			updates = append(updates, firestore.Update{Path: "expiry", Value: token.Expiry})
-			needsUpdate = true
-		}
-		if name != "" && existingData["name"] != name {
-			updates = append(updates, firestore.Update{Path: "name", Value: name})
-			needsUpdate = true
-		}
-		if existingData["picture"] != pictureURL {
-			updates = append(updates, firestore.Update{Path: "picture", Value: pictureURL})
-			needsUpdate = true
-		}
-		if needsUpdate && len(updates) > 0 {
-			_, err := docRef.Update(context.Background(), updates)
-			if err != nil {
-				log.Printf("Failed to update user doc: %v", err)
-				http.Error(w, "Failed to update user document in Firestore", http.StatusInternalServerError)
-				return
-			}
-		}
-	}
-
-	log.Printf("OAuthCallbackHandler: userID=%s email=%s name=%s", userID, email, name)
-	log.Printf("OAuthCallbackHandler: Redirecting to front-end with session token")
-
-	// Redirect the user to your front-end with your newly created JWT in the fragment
-	redirectURL := fmt.Sprintf("%s/auth-redirect#%s", "https://lee-tutoring-webapp.web.app", signedJWT)
-	http.Redirect(w, r, redirectURL, http.StatusSeeOther)
+	// Apple only sends the email on the first login; the resolver falls back
+	// to the one stored then. Apple doesn't provide a picture in the claims.
+	a.Identity.Complete(w, r, flow, identity.Profile{
+		Provider:      "apple",
+		Subject:       userID,
+		Email:         email,
+		EmailVerified: emailVerified,
+		Name:          strings.TrimSpace(name),
+		Token:         token,
+	})
 }
 
 // generateClientSecret: signs the JWT Apple needs for token exchange
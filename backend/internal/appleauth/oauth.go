@@ -4,6 +4,7 @@ import (
 	"log"
 	"net/http"
 
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/oauthstate"
 	"golang.org/x/oauth2"
 )
 
@@ -11,14 +12,20 @@ import (
 func (a *App) OAuthHandler(w http.ResponseWriter, r *http.Request) {
 	log.Println("Apple OAuthHandler triggered")
 
+	state, stateOpts, err := a.States.Begin(w, r, "apple", oauthstate.Options{OpenIDNonce: true})
+	if err != nil {
+		oauthstate.Error(w, err)
+		return
+	}
+
 	// Request "code id_token", "form_post", plus "name email" so Apple can pass user data on first login.
-	url := a.OAuthConfig.AuthCodeURL("state",
+	// Apple does not support PKCE; the state's nonce is checked against the ID token instead.
+	opts := append([]oauth2.AuthCodeOption{
 		oauth2.SetAuthURLParam("response_type", "code id_token"),
 		oauth2.SetAuthURLParam("response_mode", "form_post"),
 		oauth2.SetAuthURLParam("scope", "name email"),
-		// Typically you also set a random nonce if you're verifying ID token integrity
-		oauth2.SetAuthURLParam("nonce", "random_nonce"),
-	)
+	}, stateOpts...)
+	url := a.OAuthConfig.AuthCodeURL(state, opts...)
 
 	log.Println("Redirecting to:", url)
 	http.Redirect(w, r, url, http.StatusTemporaryRedirect)
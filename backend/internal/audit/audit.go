@@ -0,0 +1,87 @@
+// backend/internal/audit/audit.go
+
+package audit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/firestore"
+)
+
+// Actions recorded in the audit log.
+const (
+	ImpersonationStart = "impersonation.start"
+	ImpersonationStop  = "impersonation.stop"
+	MFAPolicyChange    = "mfa.policy"
+	MFAReset           = "mfa.reset"
+	APIKeyCreate       = "api_key.create"
+	APIKeyRevoke       = "api_key.revoke"
+)
+
+// Event is a document in the "audit_log" collection: something an admin did
+// that has to be accounted for later.
+type Event struct {
+	ID     string `firestore:"-" json:"id"`
+	Action string `firestore:"action" json:"action"`
+	// ActorID is the user ID of the admin.
+	ActorID    string `firestore:"actor_id" json:"actor_id"`
+	TargetID   string `firestore:"target_id,omitempty" json:"target_id,omitempty"`
+	TargetRole string `firestore:"target_role,omitempty" json:"target_role,omitempty"`
+	SessionID  string `firestore:"session_id,omitempty" json:"session_id,omitempty"`
+	Reason     string `firestore:"reason,omitempty" json:"reason,omitempty"`
+	// ExpiresAt is when whatever the event started ends on its own.
+	ExpiresAt *time.Time `firestore:"expires_at,omitempty" json:"expires_at,omitempty"`
+	At        time.Time  `firestore:"at" json:"at"`
+}
+
+// Log records events. Entries are never updated or deleted.
+type Log interface {
+	Record(ctx context.Context, e Event) error
+}
+
+type firestoreLog struct {
+	client *firestore.Client
+}
+
+// NewFirestoreLog returns a Log backed by the "audit_log" collection.
+func NewFirestoreLog(client *firestore.Client) Log {
+	return &firestoreLog{client: client}
+}
+
+func (l *firestoreLog) Record(ctx context.Context, e Event) error {
+	if e.At.IsZero() {
+		e.At = time.Now()
+	}
+	_, _, err := l.client.Collection("audit_log").Add(ctx, e)
+	return err
+}
+
+// MemoryLog is an in-memory Log for tests and local runs.
+type MemoryLog struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+// NewMemoryLog returns an empty MemoryLog.
+func NewMemoryLog() *MemoryLog {
+	return &MemoryLog{}
+}
+
+func (l *MemoryLog) Record(ctx context.Context, e Event) error {
+	if e.At.IsZero() {
+		e.At = time.Now()
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, e)
+	return nil
+}
+
+// Events returns the recorded events, oldest first.
+func (l *MemoryLog) Events() []Event {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]Event(nil), l.events...)
+}
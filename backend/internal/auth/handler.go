@@ -15,6 +15,7 @@ type AuthStatus struct {
 	Authenticated bool   `json:"authenticated"`
 	UserID        string `json:"user_id,omitempty"`
 	Email         string `json:"email,omitempty"`
+	Role          string `json:"role,omitempty"`
 }
 
 func (a *App) StatusHandler(w http.ResponseWriter, r *http.Request) {
@@ -37,5 +38,6 @@ func (a *App) StatusHandler(w http.ResponseWriter, r *http.Request) {
 		Authenticated: true,
 		UserID:        userID,
 		Email:         email,
+		Role:          middleware.GetRoleFromContext(r.Context()),
 	})
 }
@@ -31,6 +31,7 @@ type Config struct {
 	APPLE_REDIRECT_URL             string
 	APPLE_PRIVATE_KEY              string
 	APPLE_PRIVATE_KEY_B64          string
+	MAGIC_LINK_REDIRECT_URL        string
 	SESSION_SECRET                 string
 	SECRET_KEY                     string
 	GOOGLE_CLOUD_PROJECT           string
@@ -38,6 +39,18 @@ type Config struct {
 	JWT_SECRET                     string
 	FIREBASE_SERVICE_ACCOUNT       string
 	INTUIT_REALM_ID                string
+	QBO_ENVIRONMENT                string
+	QBO_BASE_URL                   string
+	QBO_MINOR_VERSION              string
+	SMTP_HOST                      string
+	SMTP_PORT                      string
+	SMTP_USERNAME                  string
+	SMTP_PASSWORD                  string
+	NOTIFY_FROM                    string
+	NOTIFY_BCC                     string
+	NOTIFY_OUTBOX                  string
+	LOW_BALANCE_THRESHOLDS         string
+	TOKEN_ENCRYPTION_KEYS          string
 }
 
 func LoadConfig() (*Config, error) {
@@ -62,6 +75,7 @@ func LoadConfig() (*Config, error) {
 		APPLE_REDIRECT_URL:             os.Getenv("APPLE_REDIRECT_URL"),
 		APPLE_PRIVATE_KEY:              os.Getenv("APPLE_PRIVATE_KEY"),
 		APPLE_PRIVATE_KEY_B64:          os.Getenv("APPLE_PRIVATE_KEY_B64"),
+		MAGIC_LINK_REDIRECT_URL:        os.Getenv("MAGIC_LINK_REDIRECT_URL"),
 		SESSION_SECRET:                 os.Getenv("SESSION_SECRET"),
 		JWT_SECRET:                     os.Getenv("JWT_SECRET"),
 		SECRET_KEY:                     os.Getenv("SECRET_KEY"),
@@ -69,6 +83,18 @@ func LoadConfig() (*Config, error) {
 		GOOGLE_APPLICATION_CREDENTIALS: os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"),
 		FIREBASE_SERVICE_ACCOUNT:       os.Getenv("FIREBASE_SERVICE_ACCOUNT"),
 		INTUIT_REALM_ID:                os.Getenv("INTUIT_REALM_ID"),
+		QBO_ENVIRONMENT:                os.Getenv("QBO_ENVIRONMENT"),
+		QBO_BASE_URL:                   os.Getenv("QBO_BASE_URL"),
+		QBO_MINOR_VERSION:              os.Getenv("QBO_MINOR_VERSION"),
+		SMTP_HOST:                      os.Getenv("SMTP_HOST"),
+		SMTP_PORT:                      os.Getenv("SMTP_PORT"),
+		SMTP_USERNAME:                  os.Getenv("SMTP_USERNAME"),
+		SMTP_PASSWORD:                  os.Getenv("SMTP_PASSWORD"),
+		NOTIFY_FROM:                    os.Getenv("NOTIFY_FROM"),
+		NOTIFY_BCC:                     os.Getenv("NOTIFY_BCC"),
+		NOTIFY_OUTBOX:                  os.Getenv("NOTIFY_OUTBOX"),
+		LOW_BALANCE_THRESHOLDS:         os.Getenv("LOW_BALANCE_THRESHOLDS"),
+		TOKEN_ENCRYPTION_KEYS:          os.Getenv("TOKEN_ENCRYPTION_KEYS"),
 	}, nil
 }
 
@@ -5,10 +5,19 @@ package dashboard
 import (
 	"cloud.google.com/go/firestore"
 	"github.com/NathanielJBrown97/LeeTutoringApp/internal/config"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/households"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/ledger"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/notify"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/students"
 )
 
 // App holds the dependencies for the dashboard package
 type App struct {
 	Config          *config.Config
 	FirestoreClient *firestore.Client
+	Students        students.Repository
+	Hours           *ledger.Ledger
+	Alerts          *notify.Alerter
+	// Households decide which students and invoices a parent can see.
+	Households *households.Manager
 }
@@ -6,17 +6,23 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
+
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/households"
 )
 
-// DashboardData represents the data structure for rendering the dashboard
+// DashboardData represents the data structure for rendering the dashboard.
+// RemainingHours is what the selected student can still use and
+// FamilyRemainingHours what the whole family has left; both go negative on
+// overage, and both are left out for guardians without billing permission.
 type DashboardData struct {
-	StudentName        string    `json:"studentName"`
-	RemainingHours     int       `json:"remainingHours"`
-	TeamLead           string    `json:"teamLead"`
-	AssociatedTutors   []string  `json:"associatedTutors"`
-	AssociatedStudents []Student `json:"associatedStudents"`
-	RecentActScores    []int64   `json:"recentActScores"`
-	NeedsStudentIntake bool      `json:"needsStudentIntake"`
+	StudentName          string    `json:"studentName"`
+	RemainingHours       *float64  `json:"remainingHours,omitempty"`
+	FamilyRemainingHours *float64  `json:"familyRemainingHours,omitempty"`
+	TeamLead             string    `json:"teamLead"`
+	AssociatedTutors     []string  `json:"associatedTutors"`
+	AssociatedStudents   []Student `json:"associatedStudents"`
+	RecentActScores      []int64   `json:"recentActScores"`
+	NeedsStudentIntake   bool      `json:"needsStudentIntake"`
 }
 
 // Student represents a student's basic details.
@@ -27,20 +33,12 @@ type Student struct {
 
 // Handler serves the dashboard data as JSON
 func (a *App) Handler(w http.ResponseWriter, r *http.Request) {
-	// Retrieve credentials from the JWT token
-	parentDocumentID, parentEmail := a.getParentCredentials(r)
-	if parentDocumentID == "" || parentEmail == "" {
-		http.Error(w, "Unable to identify parent user", http.StatusUnauthorized)
-		return
-	}
-
-	// Fetch associated_students from Firestore
-	associatedStudents, err := a.getAssociatedStudents(parentDocumentID)
-	if err != nil {
-		log.Printf("Error fetching associated students: %v", err)
-		http.Error(w, "Unable to fetch associated students", http.StatusInternalServerError)
+	// Resolve the parent's household from the JWT token
+	parentID, household, ok := a.requireHousehold(w, r, households.PermissionAcademic)
+	if !ok {
 		return
 	}
+	associatedStudents := household.StudentIDs
 
 	if len(associatedStudents) == 0 {
 		// Return a JSON response indicating that student intake is needed
@@ -59,7 +57,8 @@ func (a *App) Handler(w http.ResponseWriter, r *http.Request) {
 	selectedStudentID := r.URL.Query().Get("student_id")
 
 	// Fetch the student data using associatedStudents and selectedStudentID
-	data, err := a.fetchStudentData(associatedStudents, selectedStudentID)
+	billing := household.Can(parentID, households.PermissionBilling)
+	data, err := a.fetchStudentData(r.Context(), household.ID, associatedStudents, selectedStudentID, billing)
 	if err != nil {
 		log.Printf("Error fetching student data: %v", err)
 		http.Error(w, "Unable to fetch student data", http.StatusInternalServerError)
@@ -0,0 +1,68 @@
+// backend/internal/dashboard/hours_allocation_handler.go
+
+package dashboard
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/households"
+)
+
+// HoursAllocationRequest sets aside hours for one student. A null hours puts
+// the student back on the family's shared pool.
+type HoursAllocationRequest struct {
+	StudentID string   `json:"student_id"`
+	Hours     *float64 `json:"hours"`
+}
+
+// HoursAllocationHandler handles /api/dashboard/hours-allocation.
+// GET returns how the family's hours are split between its students;
+// POST changes one student's allocation and returns the new split.
+func (a *App) HoursAllocationHandler(w http.ResponseWriter, r *http.Request) {
+	_, household, ok := a.requireHousehold(w, r, households.PermissionBilling)
+	if !ok {
+		return
+	}
+	associatedStudents := household.StudentIDs
+
+	ctx := r.Context()
+
+	if r.Method == http.MethodPost {
+		var req HoursAllocationRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+		if req.StudentID == "" {
+			http.Error(w, "Student ID is required", http.StatusBadRequest)
+			return
+		}
+		if req.Hours != nil && *req.Hours < 0 {
+			http.Error(w, "Hours cannot be negative", http.StatusBadRequest)
+			return
+		}
+
+		if !household.HasStudent(req.StudentID) {
+			http.Error(w, "Unauthorized access to student data", http.StatusForbidden)
+			return
+		}
+
+		if _, err := a.Hours.SetAllocation(ctx, household.ID, req.StudentID, req.Hours); err != nil {
+			log.Printf("Error setting allocation for student %s of household %s: %v", req.StudentID, household.ID, err)
+			http.Error(w, "Failed to update allocation", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	balance, err := a.Hours.Balance(ctx, household.ID)
+	if err != nil {
+		log.Printf("Error fetching hours ledger for household %s: %v", household.ID, err)
+		http.Error(w, "Failed to fetch hours ledger", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(balance.Breakdown(associatedStudents))
+}
@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
+
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/households"
 )
 
 type FamilyBillingData struct {
@@ -13,37 +15,16 @@ type FamilyBillingData struct {
 }
 
 func (a *App) TotalHoursAndBalanceHandler(w http.ResponseWriter, r *http.Request) {
-	userID, _ := a.getParentCredentials(r)
-	if userID == "" {
-		http.Error(w, "The parent's User ID was not found...", http.StatusUnauthorized)
+	_, household, ok := a.requireHousehold(w, r, households.PermissionBilling)
+	if !ok {
 		return
 	}
 
 	ctx := context.Background()
-	parentDocRef := a.FirestoreClient.Collection("parents").Doc(userID)
-	parentDocSnap, err := parentDocRef.Get(ctx)
-	if err != nil {
-		log.Printf("Error fetchign parent doc for userID %s: %v", userID, err)
-		http.Error(w, "Failed to fetch parent data", http.StatusInternalServerError)
-		return
-	}
-	if !parentDocSnap.Exists() {
-		log.Printf("Parent document does not exist for userID %s", userID)
-		http.Error(w, "Parent data not found", http.StatusNotFound)
-		return
-	}
-
-	parentBusinessData := parentDocSnap.Data()
-	businessField, ok := parentBusinessData["business"].(map[string]interface{})
-	if !ok {
-		log.Printf("no business sub doc found for user id %s", userID)
-		http.Error(w, "business data not foudn for parent", http.StatusNotFound)
-		return
-	}
-	qboCustomerId, ok := businessField["qboCustomerId"].(string)
-	if !ok || qboCustomerId == "" {
-		log.Printf("No valid qbocustomer id in business sub doc for userID %s", userID)
-		http.Error(w, "qboCustomerId not found in businessfield", http.StatusNotFound)
+	qboCustomerId := household.QBOCustomerID
+	if qboCustomerId == "" {
+		log.Printf("No qbocustomer id on household %s", household.ID)
+		http.Error(w, "qboCustomerId not found for household", http.StatusNotFound)
 		return
 	}
 	intuitDocRef := a.FirestoreClient.Collection("intuit").Doc(qboCustomerId)
@@ -7,6 +7,8 @@ import (
 	"log"
 	"net/http"
 	"time"
+
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/households"
 )
 
 // InvoiceData holds one invoice's information plus a list of associated payments.
@@ -37,35 +39,19 @@ type ParentInvoicesResponse struct {
 	Invoices []InvoiceData `json:"invoices"`
 }
 
-// GetParentInvoicesHandler fetches all invoice docs (and payments sub-docs) for a given parent.
+// GetParentInvoicesHandler fetches all invoice docs (and payments sub-docs) for a given parent's household.
 func (a *App) GetParentInvoicesHandler(w http.ResponseWriter, r *http.Request) {
-	// 1. Identify parent from JWT
-	parentID, _ := a.getParentCredentials(r)
-	if parentID == "" {
-		http.Error(w, "Unable to identify parent user", http.StatusUnauthorized)
+	// 1. Identify parent from JWT and resolve their household
+	parentID, household, ok := a.requireHousehold(w, r, households.PermissionBilling)
+	if !ok {
 		return
 	}
 
-	// 2. Get parent's doc in 'parents' collection
+	// 2. Invoices belong to the household's QuickBooks customer
 	ctx := context.Background()
-	parentDocRef := a.FirestoreClient.Collection("parents").Doc(parentID)
-	parentSnap, err := parentDocRef.Get(ctx)
-	if err != nil || !parentSnap.Exists() {
-		log.Printf("Error or missing parent doc for user %s: %v", parentID, err)
-		http.Error(w, "Parent document not found", http.StatusNotFound)
-		return
-	}
-	parentData := parentSnap.Data()
-
-	// Extract qboCustomerId from parent's business sub-doc
-	businessData, ok := parentData["business"].(map[string]interface{})
-	if !ok {
-		http.Error(w, "No business sub-doc found for parent", http.StatusBadRequest)
-		return
-	}
-	qboCustomerID, ok := businessData["qboCustomerId"].(string)
-	if !ok || qboCustomerID == "" {
-		http.Error(w, "Invalid or missing qboCustomerId in parent's business sub-doc", http.StatusBadRequest)
+	qboCustomerID := household.QBOCustomerID
+	if qboCustomerID == "" {
+		http.Error(w, "Household has no qboCustomerId", http.StatusBadRequest)
 		return
 	}
 
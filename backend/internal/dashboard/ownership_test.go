@@ -0,0 +1,200 @@
+// backend/internal/dashboard/ownership_test.go
+
+package dashboard
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/households"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/middleware"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/students"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/gorilla/mux"
+)
+
+const testSecret = "test-secret"
+
+// activeSessions treats every session as active.
+type activeSessions struct{}
+
+func (activeSessions) Active(ctx context.Context, sid string) (bool, error) { return true, nil }
+
+// newTestApp returns an App over in-memory students and households, with a
+// household "family" of two students whose guardians are "manager" (manage),
+// "bills" (billing only) and "grades" (academic only). A third student
+// belongs to no household.
+func newTestApp(t *testing.T) (*App, []string) {
+	t.Helper()
+	ctx := context.Background()
+	repo := students.NewMemoryRepository()
+	var ids []string
+	for _, name := range []string{"Ada", "Ben", "Cy"} {
+		id, err := repo.Create(ctx, &students.Student{Personal: students.Personal{Name: name}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+
+	store := households.NewMemoryStore()
+	err := store.Create(ctx, &households.Household{
+		ID:          "family",
+		StudentIDs:  ids[:2],
+		GuardianIDs: []string{"manager", "bills", "grades"},
+		Guardians: map[string]households.Guardian{
+			"manager": {Permissions: []string{households.PermissionManage}},
+			"bills":   {Permissions: []string{households.PermissionBilling}},
+			"grades":  {Permissions: []string{households.PermissionAcademic}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &App{Students: repo, Households: households.NewManager(store, nil)}, ids
+}
+
+// serve sends a GET for path through the auth middleware and router main.go
+// uses, with an access token for userID and role.
+func serve(t *testing.T, route, path string, h http.HandlerFunc, userID, role string) int {
+	t.Helper()
+	return get(t, route, path, h, userID, role).Code
+}
+
+// get is serve returning the whole response.
+func get(t *testing.T, route, path string, h http.HandlerFunc, userID, role string) *httptest.ResponseRecorder {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": userID,
+		"role":    role,
+		"sid":     "session-1",
+	}).SignedString([]byte(testSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := mux.NewRouter()
+	r.Handle(route, middleware.AuthMiddleware(testSecret, activeSessions{}, nil)(h))
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestStudentDetailHandlerOwnership(t *testing.T) {
+	app, ids := newTestApp(t)
+	own, sibling, stranger := ids[0], ids[1], ids[2]
+
+	tests := []struct {
+		name    string
+		userID  string
+		role    string
+		student string
+		want    int
+	}{
+		{"managing guardian", "manager", middleware.RoleParent, own, http.StatusOK},
+		{"managing guardian, second student", "manager", middleware.RoleParent, sibling, http.StatusOK},
+		{"academic guardian", "grades", middleware.RoleParent, own, http.StatusOK},
+		{"student outside the household", "manager", middleware.RoleParent, stranger, http.StatusForbidden},
+		{"unknown student", "manager", middleware.RoleParent, "student-9999", http.StatusForbidden},
+		{"billing-only guardian", "bills", middleware.RoleParent, own, http.StatusForbidden},
+		{"parent without a household", "newcomer", middleware.RoleParent, own, http.StatusUnauthorized},
+		{"tutor", "manager", middleware.RoleTutor, own, http.StatusUnauthorized},
+		{"student", own, middleware.RoleStudent, own, http.StatusUnauthorized},
+		{"admin", "manager", middleware.RoleAdmin, own, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := serve(t, "/api/students/{student_id}", "/api/students/"+tt.student, app.StudentDetailHandler, tt.userID, tt.role)
+			if got != tt.want {
+				t.Errorf("status = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBillingHandlersNeedBillingPermission(t *testing.T) {
+	app, _ := newTestApp(t)
+	handlers := map[string]http.HandlerFunc{
+		"hours allocation":       app.HoursAllocationHandler,
+		"hours and balance":      app.TotalHoursAndBalanceHandler,
+		"invoices":               app.GetParentInvoicesHandler,
+		"used hours":             app.UpdateParentUsedHoursHandler,
+		"student lifetime hours": app.UpdateStudentLifetimeHoursHandler,
+	}
+	for name, h := range handlers {
+		t.Run(name+", academic-only guardian", func(t *testing.T) {
+			if got := serve(t, "/", "/", h, "grades", middleware.RoleParent); got != http.StatusForbidden {
+				t.Errorf("status = %d, want %d", got, http.StatusForbidden)
+			}
+		})
+		t.Run(name+", tutor", func(t *testing.T) {
+			if got := serve(t, "/", "/", h, "grades", middleware.RoleTutor); got != http.StatusUnauthorized {
+				t.Errorf("status = %d, want %d", got, http.StatusUnauthorized)
+			}
+		})
+	}
+}
+
+func TestHoursNeedBillingPermission(t *testing.T) {
+	app, ids := newTestApp(t)
+	ctx := context.Background()
+	if err := app.Students.SetLifetimeHours(ctx, ids[0], 12); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.Students.SetRemainingHours(ctx, ids[0], 3.5); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		userID    string
+		wantHours bool
+	}{
+		{"academic-only guardian", "grades", false},
+		{"managing guardian", "manager", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := get(t, "/api/students/{student_id}", "/api/students/"+ids[0], app.StudentDetailHandler, tt.userID, middleware.RoleParent)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+			}
+			var resp struct {
+				Business map[string]interface{} `json:"business"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			for field, want := range map[string]float64{"lifetime_hours": 12, "remaining_hours": 3.5} {
+				got, ok := resp.Business[field]
+				if ok != tt.wantHours || (ok && got != want) {
+					t.Errorf("%s = %v (present %v), want present %v", field, got, ok, tt.wantHours)
+				}
+			}
+		})
+	}
+
+	// The dashboard leaves the ledger alone for an academic-only guardian;
+	// app.Hours is nil, so reading it would panic.
+	rec := get(t, "/api/dashboard", "/api/dashboard?student_id="+ids[0], app.Handler, "grades", middleware.RoleParent)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("dashboard status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	var resp map[string]interface{}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range []string{"remainingHours", "familyRemainingHours"} {
+		if v, ok := resp[field]; ok {
+			t.Errorf("dashboard %s = %v, want it left out", field, v)
+		}
+	}
+	if resp["studentName"] != "Ada" {
+		t.Errorf("dashboard studentName = %v, want Ada", resp["studentName"])
+	}
+}
@@ -30,6 +30,17 @@ func (app *App) UpdateInvoiceEmailHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	// Parents may only change their own invoice email.
+	userID, _ := app.getParentCredentials(r)
+	if userID == "" {
+		http.Error(w, "Unable to identify parent user", http.StatusUnauthorized)
+		return
+	}
+	if req.ParentID != userID {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
 
@@ -68,6 +79,16 @@ func (app *App) GetInvoiceEmailHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	userID, _ := app.getParentCredentials(r)
+	if userID == "" {
+		http.Error(w, "Unable to identify parent user", http.StatusUnauthorized)
+		return
+	}
+	if parentID != userID {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
 
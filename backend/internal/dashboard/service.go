@@ -6,14 +6,16 @@ import (
 	"context"
 	"errors"
 	"log"
+
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/ledger"
 )
 
 var ErrNoAssociatedStudents = errors.New("no associated students found")
 
 // fetchStudentData fetches the student data based on the associated_students and selected student ID.
-func (a *App) fetchStudentData(associatedStudents []string, selectedStudentID string) (*DashboardData, error) {
-	ctx := context.Background()
-
+// Remaining hours come from the family's hours ledger, which is only read
+// when billing is set.
+func (a *App) fetchStudentData(ctx context.Context, familyID string, associatedStudents []string, selectedStudentID string, billing bool) (*DashboardData, error) {
 	if len(associatedStudents) == 0 {
 		log.Println("No associated students found for parent")
 		return nil, ErrNoAssociatedStudents
@@ -21,104 +23,61 @@ func (a *App) fetchStudentData(associatedStudents []string, selectedStudentID st
 
 	var students []Student
 	var studentName, teamLead string
-	var remainingHours int
+	var remainingHours, familyRemaining float64
 	var associatedTutors []string
 	var actScores []int64
 
 	studentFound := false
 
+	// Split the family's hours between its students
+	var breakdown *ledger.Breakdown
+	if billing {
+		if balance, err := a.Hours.Balance(ctx, familyID); err == nil {
+			bd := balance.Breakdown(associatedStudents)
+			breakdown = &bd
+			familyRemaining = bd.FamilyRemaining
+		} else {
+			log.Printf("Error fetching hours ledger for family %s: %v", familyID, err)
+		}
+	}
+
 	for _, studentID := range associatedStudents {
 		log.Printf("Processing student ID: %s", studentID)
 
 		// Access the student document in Firestore
-		studentDoc, err := a.FirestoreClient.Collection("students").Doc(studentID).Get(ctx)
+		student, err := a.Students.Get(ctx, studentID)
 		if err != nil {
 			log.Printf("Error fetching student document with ID %s: %v", studentID, err)
 			continue
 		}
 
-		studentData := studentDoc.Data()
-
-		// Fetch 'personal' data
-		personalData, ok := studentData["personal"].(map[string]interface{})
-		if !ok {
-			log.Printf("Error fetching 'personal' data for student ID %s", studentID)
-			personalData = make(map[string]interface{})
-		}
-
-		// Fetch 'business' data
-		businessData, ok := studentData["business"].(map[string]interface{})
-		if !ok {
-			log.Printf("Error fetching 'business' data for student ID %s", studentID)
-			businessData = make(map[string]interface{})
-		}
-
-		// Extract name
-		name, nameOk := personalData["name"].(string)
-		if !nameOk {
-			log.Printf("Name not found or not a string for student ID %s", studentID)
+		name := student.Personal.Name
+		if name == "" {
+			log.Printf("Name not found for student ID %s", studentID)
 			name = "Unknown Student"
 		}
 
-		// Extract remaining_hours
-		var remainingHoursInt int
-		if remainingHoursVal, ok := businessData["remaining_hours"]; ok {
-			switch v := remainingHoursVal.(type) {
-			case float64:
-				remainingHoursInt = int(v)
-			case int64:
-				remainingHoursInt = int(v)
-			case int:
-				remainingHoursInt = v
-			default:
-				log.Printf("Unexpected type for remaining_hours: %T", remainingHoursVal)
+		studentRemaining := student.Business.RemainingHours
+		if breakdown != nil {
+			if share, ok := breakdown.Student(studentID); ok {
+				studentRemaining = share.Remaining
 			}
-		} else {
-			log.Printf("remaining_hours not found for student ID %s", studentID)
 		}
 
-		// Extract team_lead
-		lead, leadOk := businessData["team_lead"].(string)
-		if !leadOk {
-			log.Printf("team_lead not found or not a string for student ID %s", studentID)
+		lead := student.Business.TeamLead
+		if lead == "" {
+			log.Printf("team_lead not found for student ID %s", studentID)
 			lead = "Unknown Team Lead"
 		}
 
-		// Extract associated_tutors
-		var studentTutors []string
-		if tutorsInterface, tutorsOk := businessData["associated_tutors"]; tutorsOk {
-			switch v := tutorsInterface.(type) {
-			case []interface{}:
-				for _, tutor := range v {
-					if tutorStr, ok := tutor.(string); ok {
-						studentTutors = append(studentTutors, tutorStr)
-					}
-				}
-			case []string:
-				studentTutors = v
-			default:
-				log.Printf("Unexpected type for associated_tutors: %T", tutorsInterface)
-			}
-		}
+		studentTutors := student.Business.AssociatedTutors
 
 		// Fetch the most recent ACT scores
-		testDataDocs, err := studentDoc.Ref.Collection("Test Data").Documents(ctx).GetAll()
+		testData, err := a.Students.ListTestData(ctx, studentID)
 		if err == nil {
-			for _, doc := range testDataDocs {
-				data := doc.Data()
-				if actData, ok := data["ACT"].(map[string]interface{}); ok {
-					if totalScoreVal, ok := actData["Total"]; ok {
-						switch v := totalScoreVal.(type) {
-						case float64:
-							actScores = append(actScores, int64(v))
-						case int64:
-							actScores = append(actScores, v)
-						case int:
-							actScores = append(actScores, int64(v))
-						default:
-							log.Printf("Unexpected type for ACT Total score: %T", totalScoreVal)
-						}
-					}
+			for _, td := range testData {
+				if td.ACTScores != nil && td.ACTScores.Total != nil {
+					actScores = append(actScores, int64(*td.ACTScores.Total))
 				}
 			}
 		} else {
@@ -129,7 +88,7 @@ func (a *App) fetchStudentData(associatedStudents []string, selectedStudentID st
 
 		if selectedStudentID == "" || selectedStudentID == studentID {
 			studentName = name
-			remainingHours = remainingHoursInt
+			remainingHours = studentRemaining
 			teamLead = lead
 			associatedTutors = studentTutors
 			studentFound = true
@@ -140,13 +99,17 @@ func (a *App) fetchStudentData(associatedStudents []string, selectedStudentID st
 		return nil, errors.New("selected student not found")
 	}
 
-	return &DashboardData{
+	data := &DashboardData{
 		StudentName:        studentName,
-		RemainingHours:     remainingHours,
 		TeamLead:           teamLead,
 		AssociatedTutors:   associatedTutors,
 		AssociatedStudents: students,
 		RecentActScores:    actScores,
 		NeedsStudentIntake: false,
-	}, nil
+	}
+	if billing {
+		data.RemainingHours = &remainingHours
+		data.FamilyRemainingHours = &familyRemaining
+	}
+	return data, nil
 }
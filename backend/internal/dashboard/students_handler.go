@@ -4,7 +4,6 @@ package dashboard
 
 import (
 	"encoding/json"
-	"log"
 	"net/http"
 )
 
@@ -15,22 +14,17 @@ type AssociatedStudentsResponse struct {
 
 // AssociatedStudentsHandler handles the GET /api/associated-students endpoint
 func (a *App) AssociatedStudentsHandler(w http.ResponseWriter, r *http.Request) {
-	// Retrieve credentials from the JWT token
-	userID, _ := a.getParentCredentials(r)
-	if userID == "" {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
-	}
-
-	// Fetch associated_students from Firestore
-	associatedStudents, err := a.getAssociatedStudents(userID)
-	if err != nil {
-		log.Printf("Error fetching associated students: %v", err)
-		http.Error(w, "Unable to fetch associated students", http.StatusInternalServerError)
+	// Every guardian of the household can see which students it has
+	_, household, ok := a.requireHousehold(w, r, "")
+	if !ok {
 		return
 	}
 
 	// Return the associated students
+	associatedStudents := household.StudentIDs
+	if associatedStudents == nil {
+		associatedStudents = []string{}
+	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(AssociatedStudentsResponse{
 		AssociatedStudents: associatedStudents,
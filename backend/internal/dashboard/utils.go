@@ -3,23 +3,26 @@
 package dashboard
 
 import (
-	"context"
-	"fmt"
+	"errors"
 	"log"
 	"net/http"
 
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/households"
 	"github.com/NathanielJBrown97/LeeTutoringApp/internal/middleware"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 )
 
-// getParentCredentials retrieves credentials from the JWT token
+// getParentCredentials retrieves credentials from the JWT token.
+// Callers without the parent role get empty credentials.
 func (a *App) getParentCredentials(r *http.Request) (string, string) {
 	claims, ok := middleware.GetUserFromContext(r.Context())
 	if !ok {
 		log.Println("User is not authenticated.")
 		return "", ""
 	}
+	if !middleware.HasRole(r.Context(), middleware.RoleParent) {
+		log.Printf("Rejected parent credentials for role %q", middleware.GetRoleFromContext(r.Context()))
+		return "", ""
+	}
 
 	userID, _ := claims["user_id"].(string)
 	email, _ := claims["email"].(string)
@@ -28,38 +31,31 @@ func (a *App) getParentCredentials(r *http.Request) (string, string) {
 	return userID, email
 }
 
-// getAssociatedStudents retrieves the associated_students array from Firestore
-func (a *App) getAssociatedStudents(userID string) ([]string, error) {
-	ctx := context.Background()
-	parentDocRef := a.FirestoreClient.Collection("parents").Doc(userID)
-	parentDocSnap, err := parentDocRef.Get(ctx)
-	if err != nil {
-		if status.Code(err) == codes.NotFound {
-			// Parent document doesn't exist
-			return []string{}, nil
-		}
-		return nil, err
+// requireHousehold writes an error and returns false unless the parent
+// behind the request has permission in their household; an empty permission
+// only needs them to be a guardian. A parent without a household yet gets an
+// empty one keyed by their ID, so they are sent to student intake instead of
+// being refused.
+func (a *App) requireHousehold(w http.ResponseWriter, r *http.Request, permission string) (string, *households.Household, bool) {
+	parentID, _ := a.getParentCredentials(r)
+	if parentID == "" {
+		http.Error(w, "Unable to identify parent user", http.StatusUnauthorized)
+		return "", nil, false
 	}
 
-	associatedStudentsInterface, ok := parentDocSnap.Data()["associated_students"]
-	if !ok {
-		return []string{}, nil
+	h, err := a.Households.ForGuardian(r.Context(), parentID)
+	if errors.Is(err, households.ErrNotFound) {
+		return parentID, &households.Household{ID: parentID}, true
 	}
-
-	var associatedStudents []string
-	switch v := associatedStudentsInterface.(type) {
-	case []interface{}:
-		for _, s := range v {
-			if studentID, ok := s.(string); ok {
-				associatedStudents = append(associatedStudents, studentID)
-			}
-		}
-	case []string:
-		associatedStudents = v
-	default:
-		log.Printf("Invalid data type for 'associated_students' field: %T", associatedStudentsInterface)
-		return nil, fmt.Errorf("invalid data type for 'associated_students' field")
+	if err != nil {
+		log.Printf("Error fetching household of parent %s: %v", parentID, err)
+		http.Error(w, "Unable to fetch household", http.StatusInternalServerError)
+		return "", nil, false
 	}
-
-	return associatedStudents, nil
+	if permission != "" && !h.Can(parentID, permission) {
+		log.Printf("Parent %s lacks %s permission in household %s", parentID, permission, h.ID)
+		http.Error(w, "Your household has not given you access to this", http.StatusForbidden)
+		return "", nil, false
+	}
+	return parentID, h, true
 }
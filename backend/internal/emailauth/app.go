@@ -0,0 +1,37 @@
+// backend/internal/emailauth/app.go
+
+package emailauth
+
+import (
+	"time"
+
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/config"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/identity"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/notify"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/staff"
+)
+
+// Families without an account at one of the OAuth providers sign in with a
+// link emailed to them. Each link holds a random token that can be used once
+// within LinkTTL; only its hash is stored. The email address is the
+// identity's subject, and the mailbox proves it, so it resolves like a
+// verified email from any other provider.
+
+// Provider is the provider name of identities signed in by email.
+const Provider = "email"
+
+// LinkTTL is how long a sign-in link works.
+const LinkTTL = 15 * time.Minute
+
+// maxLinksPerTTL caps the links sent to one address within LinkTTL, so the
+// endpoint cannot be used to flood someone's inbox.
+const maxLinksPerTTL = 5
+
+// App holds the dependencies for the emailauth package
+type App struct {
+	Config   *config.Config
+	Store    Store
+	Mailer   notify.Mailer
+	Identity *identity.Resolver
+	Staff    *staff.Directory
+}
@@ -0,0 +1,75 @@
+// backend/internal/emailauth/callback.go
+
+package emailauth
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/identity"
+)
+
+// confirmPage asks the user to press a button before the link is used up.
+// Mail scanners that open every link only ever send the GET.
+var confirmPage = template.Must(template.New("confirm").Parse(`<!DOCTYPE html>
+<html>
+<head><meta name="viewport" content="width=device-width, initial-scale=1"><title>Sign in to Lee Tutoring</title></head>
+<body style="font-family: sans-serif; text-align: center; padding-top: 4rem;">
+<form method="POST">
+<input type="hidden" name="token" value="{{.}}">
+<button type="submit" style="font-size: 1.2rem; padding: 0.75rem 2rem;">Sign in to Lee Tutoring</button>
+</form>
+</body>
+</html>
+`))
+
+// CallbackHandler handles the link in the email. GET shows a button that
+// POSTs the token back here; the POST uses up the link and signs the user in
+// like an OAuth callback.
+func (a *App) CallbackHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		token := r.URL.Query().Get("token")
+		if token == "" {
+			http.Error(w, "Sign-in link is missing its token", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Referrer-Policy", "no-referrer")
+		if err := confirmPage.Execute(w, token); err != nil {
+			log.Printf("Failed to render sign-in page: %v", err)
+		}
+		return
+	}
+
+	token := r.PostFormValue("token")
+	if token == "" {
+		http.Error(w, "Sign-in link is missing its token", http.StatusBadRequest)
+		return
+	}
+	ctx := r.Context()
+	link, found, err := a.Store.Take(ctx, hashToken(token))
+	if err != nil {
+		log.Printf("Failed to load sign-in link: %v", err)
+		http.Error(w, "Failed to sign in", http.StatusInternalServerError)
+		return
+	}
+	if !found || time.Now().After(link.ExpiresAt) {
+		http.Error(w, "This sign-in link has expired or was already used. Please request a new one.", http.StatusBadRequest)
+		return
+	}
+
+	profile := identity.Profile{
+		Provider:      Provider,
+		Subject:       link.Email,
+		Email:         link.Email,
+		EmailVerified: true,
+	}
+	acct, err := a.Identity.Resolve(ctx, profile)
+	if err != nil {
+		a.Identity.ResolveError(w, r, profile, err)
+		return
+	}
+	a.Identity.CompleteLogin(w, r, acct, link.ReturnTo)
+}
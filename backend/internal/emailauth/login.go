@@ -0,0 +1,113 @@
+// backend/internal/emailauth/login.go
+
+package emailauth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/notify"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/oauthstate"
+)
+
+// RequestLinkHandler handles POST /api/auth/magic-link with
+// {"email": "...", "return": "/path"}. It answers 202 whether or not a link
+// was sent, so it does not reveal who has an account. Staff sign in with
+// Google, which their calendar needs, so no link is sent to an address in the
+// staff directory.
+func (a *App) RequestLinkHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Email  string `json:"email"`
+		Return string `json:"return"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	addr, err := mail.ParseAddress(strings.TrimSpace(req.Email))
+	if err != nil || addr.Name != "" {
+		http.Error(w, "Invalid email address", http.StatusBadRequest)
+		return
+	}
+	email := strings.ToLower(addr.Address)
+
+	ctx := r.Context()
+	if _, isStaff, err := a.Staff.Lookup(ctx, email); err != nil {
+		log.Printf("Failed to look up staff by email %s: %v", email, err)
+		http.Error(w, "Failed to send sign-in link", http.StatusInternalServerError)
+		return
+	} else if isStaff {
+		log.Printf("Not sending a sign-in link to staff address %s", email)
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	now := time.Now()
+	sent, err := a.Store.CountSince(ctx, email, now.Add(-LinkTTL))
+	if err != nil {
+		log.Printf("Failed to count sign-in links for %s: %v", email, err)
+		http.Error(w, "Failed to send sign-in link", http.StatusInternalServerError)
+		return
+	}
+	if sent >= maxLinksPerTTL {
+		log.Printf("Not sending another sign-in link to %s: %d sent in the last %v", email, sent, LinkTTL)
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		log.Printf("Failed to generate sign-in token: %v", err)
+		http.Error(w, "Failed to send sign-in link", http.StatusInternalServerError)
+		return
+	}
+	link := Link{
+		Email:     email,
+		ReturnTo:  oauthstate.SafeReturnPath(req.Return),
+		CreatedAt: now,
+		ExpiresAt: now.Add(LinkTTL),
+	}
+	if err := a.Store.Create(ctx, hashToken(token), link); err != nil {
+		log.Printf("Failed to store sign-in link for %s: %v", email, err)
+		http.Error(w, "Failed to send sign-in link", http.StatusInternalServerError)
+		return
+	}
+
+	msg := notify.Message{
+		To:      []string{email},
+		Subject: "Your Lee Tutoring sign-in link",
+		Body: fmt.Sprintf("Click the link below to sign in to Lee Tutoring. It works once and expires in %d minutes.\n\n%s?token=%s\n\nIf you did not ask to sign in, you can ignore this email.\n",
+			int(LinkTTL.Minutes()), a.Config.MAGIC_LINK_REDIRECT_URL, token),
+	}
+	if err := a.Mailer.Send(ctx, msg); err != nil {
+		log.Printf("Failed to email sign-in link to %s: %v", email, err)
+		http.Error(w, "Failed to send sign-in link", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Sent sign-in link to %s", email)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// hashToken is the ID a link is stored under, so a leaked store does not
+// give away working links.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
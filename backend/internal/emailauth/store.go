@@ -0,0 +1,130 @@
+// backend/internal/emailauth/store.go
+
+package emailauth
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Link is a sign-in link that has been emailed and not used yet.
+type Link struct {
+	Email     string    `firestore:"email"`
+	ReturnTo  string    `firestore:"return_to"`
+	CreatedAt time.Time `firestore:"created_at"`
+	ExpiresAt time.Time `firestore:"expires_at"`
+}
+
+// Store keeps sign-in links by the hash of their token.
+type Store interface {
+	Create(ctx context.Context, hash string, link Link) error
+	// Take returns and deletes a link, so each can be used once.
+	Take(ctx context.Context, hash string) (*Link, bool, error)
+	// CountSince counts the unused links sent to email since the given time.
+	CountSince(ctx context.Context, email string, since time.Time) (int, error)
+}
+
+type firestoreStore struct {
+	client *firestore.Client
+}
+
+// NewFirestoreStore returns a Store that keeps links in the "magic_links"
+// collection. Their expires_at field can drive a Firestore TTL policy for
+// links that were never used.
+func NewFirestoreStore(client *firestore.Client) Store {
+	return &firestoreStore{client: client}
+}
+
+func (s *firestoreStore) Create(ctx context.Context, hash string, link Link) error {
+	_, err := s.client.Collection("magic_links").Doc(hash).Create(ctx, link)
+	return err
+}
+
+func (s *firestoreStore) Take(ctx context.Context, hash string) (*Link, bool, error) {
+	ref := s.client.Collection("magic_links").Doc(hash)
+	var link *Link
+	err := s.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		link = nil
+		snap, err := tx.Get(ref)
+		if status.Code(err) == codes.NotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var got Link
+		if err := snap.DataTo(&got); err != nil {
+			return err
+		}
+		link = &got
+		return tx.Delete(ref)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return link, link != nil, nil
+}
+
+func (s *firestoreStore) CountSince(ctx context.Context, email string, since time.Time) (int, error) {
+	// Filtering the time here keeps the query on a single-field index.
+	snaps, err := s.client.Collection("magic_links").Where("email", "==", email).Documents(ctx).GetAll()
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, snap := range snaps {
+		if created, ok := snap.Data()["created_at"].(time.Time); ok && created.After(since) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// MemoryStore is an in-memory Store for tests and local runs.
+type MemoryStore struct {
+	mu    sync.Mutex
+	links map[string]Link
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{links: map[string]Link{}}
+}
+
+func (m *MemoryStore) Create(ctx context.Context, hash string, link Link) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.links[hash]; ok {
+		return status.Errorf(codes.AlreadyExists, "magic link %s already exists", hash)
+	}
+	m.links[hash] = link
+	return nil
+}
+
+func (m *MemoryStore) Take(ctx context.Context, hash string) (*Link, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	link, ok := m.links[hash]
+	if !ok {
+		return nil, false, nil
+	}
+	delete(m.links, hash)
+	return &link, true, nil
+}
+
+func (m *MemoryStore) CountSince(ctx context.Context, email string, since time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	count := 0
+	for _, link := range m.links {
+		if link.Email == email && link.CreatedAt.After(since) {
+			count++
+		}
+	}
+	return count, nil
+}
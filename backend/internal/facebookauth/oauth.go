@@ -4,6 +4,7 @@ import (
 	"log"
 	"net/http"
 
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/oauthstate"
 	"golang.org/x/oauth2"
 )
 
@@ -11,8 +12,15 @@ func (a *App) OAuthHandler(w http.ResponseWriter, r *http.Request) {
 	// Log that the OAuthHandler was triggered
 	log.Println("Facebook OAuthHandler triggered")
 
+	state, stateOpts, err := a.States.Begin(w, r, "facebook", oauthstate.Options{PKCE: true})
+	if err != nil {
+		oauthstate.Error(w, err)
+		return
+	}
+
 	// Generate the OAuth URL for Facebook
-	url := a.OAuthConfig.AuthCodeURL("state", oauth2.SetAuthURLParam("scope", "public_profile,email"))
+	opts := append([]oauth2.AuthCodeOption{oauth2.SetAuthURLParam("scope", "public_profile,email")}, stateOpts...)
+	url := a.OAuthConfig.AuthCodeURL(state, opts...)
 
 	// Log the generated URL
 	log.Println("Redirecting to:", url)
@@ -5,6 +5,7 @@ import (
 	"log"
 	"net/http"
 
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/oauthstate"
 	"golang.org/x/oauth2"
 )
 
@@ -13,8 +14,15 @@ func (a *App) OAuthHandler(w http.ResponseWriter, r *http.Request) {
 	// Log that the OAuthHandler was triggered
 	log.Println("OAuthHandler triggered")
 
+	state, stateOpts, err := a.States.Begin(w, r, "google", oauthstate.Options{PKCE: true, OpenIDNonce: true})
+	if err != nil {
+		oauthstate.Error(w, err)
+		return
+	}
+
 	// Generate the OAuth URL for Google with prompt=consent to ensure refresh_token is received
-	url := a.OAuthConfig.AuthCodeURL("state", oauth2.AccessTypeOffline, oauth2.SetAuthURLParam("prompt", "consent"))
+	opts := append([]oauth2.AuthCodeOption{oauth2.AccessTypeOffline, oauth2.SetAuthURLParam("prompt", "consent")}, stateOpts...)
+	url := a.OAuthConfig.AuthCodeURL(state, opts...)
 
 	// Log the generated URL
 	log.Println("Redirecting to:", url)
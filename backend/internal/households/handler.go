@@ -0,0 +1,282 @@
+// backend/internal/households/handler.go
+
+package households
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/middleware"
+	"github.com/gorilla/mux"
+)
+
+// guardianResponse is a guardian as the parent dashboard shows it.
+type guardianResponse struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Email       string    `json:"email"`
+	Permissions []string  `json:"permissions"`
+	JoinedAt    time.Time `json:"joined_at"`
+}
+
+// householdResponse is what a guardian sees of their household.
+type householdResponse struct {
+	ID         string             `json:"id"`
+	StudentIDs []string           `json:"students"`
+	Guardians  []guardianResponse `json:"guardians"`
+	// Permissions are the caller's own.
+	Permissions []string `json:"permissions"`
+	// Invitations are shown to guardians who can manage the household.
+	Invitations []Invitation `json:"invitations,omitempty"`
+}
+
+// HouseholdHandler handles GET /api/household, returning the caller's
+// household and its guardians.
+func (m *Manager) HouseholdHandler(w http.ResponseWriter, r *http.Request) {
+	parentID, _ := middleware.ExtractUserIDFromContext(r.Context())
+	h, err := m.ForGuardian(r.Context(), parentID)
+	if errors.Is(err, ErrNotFound) {
+		http.Error(w, "No household yet", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error fetching household of parent %s: %v", parentID, err)
+		http.Error(w, "Failed to fetch household", http.StatusInternalServerError)
+		return
+	}
+	m.writeHousehold(r.Context(), w, h, parentID, http.StatusOK)
+}
+
+// GuardiansHandler handles POST /api/household/guardians with
+// {"email": "...", "permissions": ["academic"]}, inviting whoever signs in
+// with that email to the caller's household. The response is the same
+// whether or not anyone has an account with that email, so it cannot be
+// used to find out who does.
+func (m *Manager) GuardiansHandler(w http.ResponseWriter, r *http.Request) {
+	parentID, _ := middleware.ExtractUserIDFromContext(r.Context())
+	var req struct {
+		Email       string   `json:"email"`
+		Permissions []string `json:"permissions"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	email := NormalizeEmail(req.Email)
+	if email == "" || !strings.Contains(email, "@") {
+		http.Error(w, "Email is required", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	h, err := m.Ensure(ctx, parentID)
+	if err != nil {
+		log.Printf("Error fetching household of parent %s: %v", parentID, err)
+		http.Error(w, "Failed to fetch household", http.StatusInternalServerError)
+		return
+	}
+	if !h.Can(parentID, PermissionManage) {
+		http.Error(w, "You cannot manage this household", http.StatusForbidden)
+		return
+	}
+
+	if err := m.Invite(ctx, h.ID, parentID, email, req.Permissions); err != nil {
+		writeError(w, "invite guardian to", h, err)
+		return
+	}
+	log.Printf("Parent %s invited a guardian to household %s", parentID, h.ID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Invitation sent. It will be waiting for them the next time they sign in with that email.",
+	})
+}
+
+// invitationResponse is an invitation as the invited parent sees it.
+type invitationResponse struct {
+	HouseholdID   string    `json:"household_id"`
+	InvitedByName string    `json:"invited_by_name"`
+	Permissions   []string  `json:"permissions"`
+	InvitedAt     time.Time `json:"invited_at"`
+}
+
+// InvitationsHandler handles GET /api/household/invitations, listing the
+// invitations for the email the caller signed in with.
+func (m *Manager) InvitationsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	email := callerEmail(ctx)
+	resp := []invitationResponse{}
+	if email != "" {
+		hs, err := m.Invitations(ctx, email)
+		if err != nil {
+			log.Printf("Error fetching household invitations: %v", err)
+			http.Error(w, "Failed to fetch invitations", http.StatusInternalServerError)
+			return
+		}
+		for _, h := range hs {
+			inv, _ := h.invitation(NormalizeEmail(email), m.now())
+			name, _ := m.parentContact(ctx, inv.InvitedBy)
+			resp = append(resp, invitationResponse{
+				HouseholdID:   h.ID,
+				InvitedByName: name,
+				Permissions:   inv.Permissions,
+				InvitedAt:     inv.InvitedAt,
+			})
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// InvitationHandler handles /api/household/invitations/{household_id}.
+// POST accepts the household's invitation for the caller's email; DELETE
+// declines it.
+func (m *Manager) InvitationHandler(w http.ResponseWriter, r *http.Request) {
+	parentID, _ := middleware.ExtractUserIDFromContext(r.Context())
+	householdID := mux.Vars(r)["household_id"]
+	ctx := r.Context()
+	email := callerEmail(ctx)
+	h := &Household{ID: householdID}
+
+	if r.Method == http.MethodDelete {
+		if err := m.Decline(ctx, householdID, email); err != nil {
+			writeError(w, "decline invitation to", h, err)
+			return
+		}
+		log.Printf("Parent %s declined an invitation to household %s", parentID, householdID)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	updated, err := m.Accept(ctx, householdID, parentID, email)
+	if err != nil {
+		writeError(w, "accept invitation to", h, err)
+		return
+	}
+	log.Printf("Parent %s joined household %s", parentID, householdID)
+	m.writeHousehold(ctx, w, updated, parentID, http.StatusOK)
+}
+
+// callerEmail is the email the caller signed in with.
+func callerEmail(ctx context.Context) string {
+	claims, _ := middleware.GetUserFromContext(ctx)
+	email, _ := claims["email"].(string)
+	return email
+}
+
+// GuardianHandler handles /api/household/guardians/{guardian_id}. PUT with
+// {"permissions": [...]} changes a guardian's permissions; DELETE removes
+// them. Guardians can always remove themselves.
+func (m *Manager) GuardianHandler(w http.ResponseWriter, r *http.Request) {
+	parentID, _ := middleware.ExtractUserIDFromContext(r.Context())
+	guardianID := mux.Vars(r)["guardian_id"]
+
+	ctx := r.Context()
+	h, err := m.ForGuardian(ctx, parentID)
+	if errors.Is(err, ErrNotFound) {
+		http.Error(w, "No household yet", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error fetching household of parent %s: %v", parentID, err)
+		http.Error(w, "Failed to fetch household", http.StatusInternalServerError)
+		return
+	}
+	leaving := r.Method == http.MethodDelete && guardianID == parentID
+	if !leaving && !h.Can(parentID, PermissionManage) {
+		http.Error(w, "You cannot manage this household", http.StatusForbidden)
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		if _, err := m.RemoveGuardian(ctx, h.ID, guardianID); err != nil {
+			writeError(w, "remove guardian from", h, err)
+			return
+		}
+		log.Printf("Parent %s removed guardian %s from household %s", parentID, guardianID, h.ID)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	var req struct {
+		Permissions []string `json:"permissions"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	updated, err := m.SetPermissions(ctx, h.ID, guardianID, req.Permissions)
+	if err != nil {
+		writeError(w, "change guardian of", h, err)
+		return
+	}
+	log.Printf("Parent %s set permissions of guardian %s in household %s to %v", parentID, guardianID, h.ID, req.Permissions)
+	m.writeHousehold(ctx, w, updated, parentID, http.StatusOK)
+}
+
+func (m *Manager) writeHousehold(ctx context.Context, w http.ResponseWriter, h *Household, parentID string, code int) {
+	resp := householdResponse{
+		ID:          h.ID,
+		StudentIDs:  h.StudentIDs,
+		Guardians:   make([]guardianResponse, 0, len(h.GuardianIDs)),
+		Permissions: h.Guardians[parentID].Permissions,
+	}
+	if h.Can(parentID, PermissionManage) {
+		for _, inv := range h.Invitations {
+			if _, ok := h.invitation(inv.Email, m.now()); ok {
+				resp.Invitations = append(resp.Invitations, inv)
+			}
+		}
+	}
+	for _, id := range h.GuardianIDs {
+		g := h.Guardians[id]
+		name, email := m.parentContact(ctx, id)
+		resp.Guardians = append(resp.Guardians, guardianResponse{
+			ID:          id,
+			Name:        name,
+			Email:       email,
+			Permissions: g.Permissions,
+			JoinedAt:    g.JoinedAt,
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// parentContact returns the name and email on a parents document.
+func (m *Manager) parentContact(ctx context.Context, parentID string) (string, string) {
+	data, err := m.parent(ctx, parentID)
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			log.Printf("Error fetching parent %s: %v", parentID, err)
+		}
+		return "", ""
+	}
+	name, _ := data["name"].(string)
+	email, _ := data["email"].(string)
+	return name, email
+}
+
+func writeError(w http.ResponseWriter, action string, h *Household, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidPermission):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, ErrGuardianNotFound):
+		http.Error(w, "Guardian not found", http.StatusNotFound)
+	case errors.Is(err, ErrInHousehold):
+		http.Error(w, "You already belong to a household. Leave it before joining another.", http.StatusConflict)
+	case errors.Is(err, ErrNoInvitation):
+		http.Error(w, "Invitation not found or expired", http.StatusNotFound)
+	case errors.Is(err, ErrLastManager):
+		http.Error(w, "The household needs another guardian who can manage it first", http.StatusConflict)
+	default:
+		log.Printf("Error trying to %s household %s: %v", action, h.ID, err)
+		http.Error(w, "Failed to update household", http.StatusInternalServerError)
+	}
+}
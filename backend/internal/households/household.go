@@ -0,0 +1,536 @@
+// backend/internal/households/household.go
+
+package households
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/middleware"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// A household owns a family's students and its QuickBooks customer, and is
+// what the hours ledger is kept for. Parents join a household as guardians,
+// each with their own permissions, so parents who live apart can share a
+// family without sharing an account. A parent belongs to at most one
+// household, and only joins one by accepting an invitation sent to the
+// email they sign in with.
+//
+// Before households, each parents document had its own associated_students
+// and business.qboCustomerId. The first time such a parent is looked up a
+// household is made from those fields, with the parent's ID as its ID so
+// that the family's hours ledger carries over; cmd/importer/households does
+// the same for every parent at once.
+
+// Collection is the Firestore collection of households.
+const Collection = "households"
+
+// Guardian permissions.
+const (
+	// PermissionBilling lets a guardian see invoices, hours and balances.
+	PermissionBilling = "billing"
+	// PermissionAcademic lets a guardian see sessions, scores and goals.
+	PermissionAcademic = "academic"
+	// PermissionManage lets a guardian add and remove guardians and change
+	// their permissions. It includes the other two.
+	PermissionManage = "manage"
+)
+
+// InvitationTTL is how long an invitation to join a household can be
+// accepted.
+const InvitationTTL = 14 * 24 * time.Hour
+
+var (
+	ErrNotFound          = errors.New("household not found")
+	ErrGuardianNotFound  = errors.New("guardian not found in household")
+	ErrInHousehold       = errors.New("parent already belongs to a household")
+	ErrLastManager       = errors.New("household must keep a guardian who can manage it")
+	ErrInvalidPermission = errors.New("invalid permission")
+	ErrNoInvitation      = errors.New("no invitation to household")
+)
+
+// Guardian is a parent's membership of a household.
+type Guardian struct {
+	Permissions []string  `firestore:"permissions" json:"permissions"`
+	JoinedAt    time.Time `firestore:"joined_at" json:"joined_at"`
+}
+
+// Invitation asks whoever signs in with Email to join a household.
+type Invitation struct {
+	Email       string    `firestore:"email" json:"email"`
+	Permissions []string  `firestore:"permissions" json:"permissions"`
+	InvitedBy   string    `firestore:"invited_by" json:"invited_by"`
+	InvitedAt   time.Time `firestore:"invited_at" json:"invited_at"`
+}
+
+// Household is a document in the "households" collection.
+type Household struct {
+	ID            string   `firestore:"-" json:"id"`
+	StudentIDs    []string `firestore:"students" json:"students"`
+	QBOCustomerID string   `firestore:"qbo_customer_id,omitempty" json:"qbo_customer_id,omitempty"`
+	// GuardianIDs lists the keys of Guardians, so households can be queried
+	// by guardian.
+	GuardianIDs []string            `firestore:"guardian_ids" json:"-"`
+	Guardians   map[string]Guardian `firestore:"guardians" json:"guardians"`
+	// Invitations are pending guardians. InvitedEmails lists their emails,
+	// so households can be queried by invitee.
+	Invitations    []Invitation `firestore:"invitations" json:"-"`
+	InvitedEmails  []string     `firestore:"invited_emails" json:"-"`
+	RemainingHours float64      `firestore:"remaining_hours" json:"remaining_hours"`
+	CreatedAt      time.Time    `firestore:"created_at" json:"created_at"`
+	UpdatedAt      time.Time    `firestore:"updated_at" json:"updated_at"`
+}
+
+// Can reports whether parentID is a guardian with permission.
+func (h *Household) Can(parentID, permission string) bool {
+	g, ok := h.Guardians[parentID]
+	if !ok {
+		return false
+	}
+	return contains(g.Permissions, permission) || contains(g.Permissions, PermissionManage)
+}
+
+// HasStudent reports whether the household has the student.
+func (h *Household) HasStudent(studentID string) bool {
+	return contains(h.StudentIDs, studentID)
+}
+
+func (h *Household) setGuardian(parentID string, g Guardian) {
+	if _, ok := h.Guardians[parentID]; !ok {
+		h.GuardianIDs = append(h.GuardianIDs, parentID)
+	}
+	h.Guardians[parentID] = g
+}
+
+// invitation returns the unexpired invitation for email.
+func (h *Household) invitation(email string, now time.Time) (Invitation, bool) {
+	for _, inv := range h.Invitations {
+		if inv.Email == email && now.Sub(inv.InvitedAt) < InvitationTTL {
+			return inv, true
+		}
+	}
+	return Invitation{}, false
+}
+
+// setInvitations replaces the invitations, dropping expired ones.
+func (h *Household) setInvitations(invs []Invitation, now time.Time) {
+	h.Invitations, h.InvitedEmails = []Invitation{}, []string{}
+	for _, inv := range invs {
+		if now.Sub(inv.InvitedAt) < InvitationTTL {
+			h.Invitations = append(h.Invitations, inv)
+			h.InvitedEmails = append(h.InvitedEmails, inv.Email)
+		}
+	}
+}
+
+// withoutInvitation returns the invitations other than the one for email.
+func (h *Household) withoutInvitation(email string) []Invitation {
+	var out []Invitation
+	for _, inv := range h.Invitations {
+		if inv.Email != email {
+			out = append(out, inv)
+		}
+	}
+	return out
+}
+
+func (h *Household) managers() int {
+	n := 0
+	for _, g := range h.Guardians {
+		if contains(g.Permissions, PermissionManage) {
+			n++
+		}
+	}
+	return n
+}
+
+// NormalizePermissions checks perms and drops duplicates.
+func NormalizePermissions(perms []string) ([]string, error) {
+	var out []string
+	for _, p := range perms {
+		switch p {
+		case PermissionBilling, PermissionAcademic, PermissionManage:
+		default:
+			return nil, fmt.Errorf("%w: %q", ErrInvalidPermission, p)
+		}
+		if !contains(out, p) {
+			out = append(out, p)
+		}
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("%w: a guardian needs at least one permission", ErrInvalidPermission)
+	}
+	return out, nil
+}
+
+// NormalizeEmail is how invitation emails are stored and compared.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// allPermissions are those of the parent a household is made for.
+func allPermissions() []string {
+	return []string{PermissionManage, PermissionBilling, PermissionAcademic}
+}
+
+// Manager finds and changes households.
+type Manager struct {
+	store Store
+	// parent reads a parents document, or returns ErrNotFound.
+	parent func(ctx context.Context, parentID string) (map[string]interface{}, error)
+	now    func() time.Time
+}
+
+// NewManager returns a Manager over store. client is used to read the
+// parents documents households are made from, and the names and emails of
+// guardians; with a nil client neither happens.
+func NewManager(store Store, client *firestore.Client) *Manager {
+	parent := func(ctx context.Context, parentID string) (map[string]interface{}, error) {
+		if client == nil {
+			return nil, ErrNotFound
+		}
+		snap, err := client.Collection("parents").Doc(parentID).Get(ctx)
+		if status.Code(err) == codes.NotFound {
+			return nil, ErrNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+		return snap.Data(), nil
+	}
+	return &Manager{store: store, parent: parent, now: time.Now}
+}
+
+// ForGuardian returns the household of parentID, or ErrNotFound.
+func (m *Manager) ForGuardian(ctx context.Context, parentID string) (*Household, error) {
+	h, found, err := m.store.FindByGuardian(ctx, parentID)
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		return h, nil
+	}
+	return m.migrate(ctx, parentID)
+}
+
+// ForCustomer returns the household billed to a QuickBooks customer, or
+// ErrNotFound.
+func (m *Manager) ForCustomer(ctx context.Context, customerID string) (*Household, error) {
+	h, found, err := m.store.FindByCustomer(ctx, customerID)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ErrNotFound
+	}
+	return h, nil
+}
+
+// ListByStudent returns the households that have the student.
+func (m *Manager) ListByStudent(ctx context.Context, studentID string) ([]*Household, error) {
+	return m.store.ListByStudent(ctx, studentID)
+}
+
+// Ensure returns the household of parentID, making one with them as its
+// only guardian if they have none.
+func (m *Manager) Ensure(ctx context.Context, parentID string) (*Household, error) {
+	h, err := m.ForGuardian(ctx, parentID)
+	if !errors.Is(err, ErrNotFound) {
+		return h, err
+	}
+
+	now := m.now()
+	h = &Household{
+		ID:         parentID,
+		StudentIDs: []string{},
+		Guardians:  map[string]Guardian{},
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	h.setGuardian(parentID, Guardian{Permissions: allPermissions(), JoinedAt: now})
+	err = m.store.Create(ctx, h)
+	if status.Code(err) == codes.AlreadyExists {
+		// The parent left the household made for them; start a new one.
+		h.ID = m.store.NewID()
+		err = m.store.Create(ctx, h)
+	}
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Created household %s for parent %s", h.ID, parentID)
+	return h, nil
+}
+
+// AddStudents adds students to the household of parentID, making the
+// household if they have none.
+func (m *Manager) AddStudents(ctx context.Context, parentID string, studentIDs ...string) (*Household, error) {
+	h, err := m.Ensure(ctx, parentID)
+	if err != nil {
+		return nil, err
+	}
+	return m.update(ctx, h.ID, func(h *Household) error {
+		for _, id := range studentIDs {
+			if id != "" && !h.HasStudent(id) {
+				h.StudentIDs = append(h.StudentIDs, id)
+			}
+		}
+		return nil
+	})
+}
+
+// LinkCustomer bills the household of parentID to a QuickBooks customer,
+// unless it already has one. It reports whether the customer was linked.
+func (m *Manager) LinkCustomer(ctx context.Context, parentID, customerID string) (bool, error) {
+	h, err := m.Ensure(ctx, parentID)
+	if err != nil {
+		return false, err
+	}
+	linked := false
+	_, err = m.update(ctx, h.ID, func(h *Household) error {
+		if h.QBOCustomerID == "" {
+			h.QBOCustomerID, linked = customerID, true
+		}
+		return nil
+	})
+	return linked, err
+}
+
+// SetRemainingHours records the family's remaining hours on the household.
+func (m *Manager) SetRemainingHours(ctx context.Context, householdID string, hours float64) error {
+	_, err := m.update(ctx, householdID, func(h *Household) error {
+		h.RemainingHours = hours
+		return nil
+	})
+	return err
+}
+
+// Invite asks whoever signs in with email to join a household with perms.
+// Inviting the same email again replaces the earlier invitation. Nothing
+// changes for the invitee until they accept.
+func (m *Manager) Invite(ctx context.Context, householdID, invitedBy, email string, perms []string) error {
+	perms, err := NormalizePermissions(perms)
+	if err != nil {
+		return err
+	}
+	email = NormalizeEmail(email)
+	_, err = m.update(ctx, householdID, func(h *Household) error {
+		now := m.now()
+		invs := append(h.withoutInvitation(email), Invitation{
+			Email:       email,
+			Permissions: perms,
+			InvitedBy:   invitedBy,
+			InvitedAt:   now,
+		})
+		h.setInvitations(invs, now)
+		return nil
+	})
+	return err
+}
+
+// Invitations returns the households with an unexpired invitation for
+// email.
+func (m *Manager) Invitations(ctx context.Context, email string) ([]*Household, error) {
+	email = NormalizeEmail(email)
+	hs, err := m.store.ListByInvitedEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	var out []*Household
+	for _, h := range hs {
+		if _, ok := h.invitation(email, m.now()); ok {
+			out = append(out, h)
+		}
+	}
+	return out, nil
+}
+
+// Accept makes parentID a guardian of a household that invited email, the
+// address they signed in with. A parent whose own household has nothing in
+// it yet leaves it; one whose household has students, a QuickBooks
+// customer or other guardians gets ErrInHousehold and has to leave it
+// first.
+func (m *Manager) Accept(ctx context.Context, householdID, parentID, email string) (*Household, error) {
+	email = NormalizeEmail(email)
+	h, found, err := m.store.Get(ctx, householdID)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ErrNoInvitation
+	}
+	if _, ok := h.invitation(email, m.now()); !ok {
+		return nil, ErrNoInvitation
+	}
+
+	if own, err := m.ForGuardian(ctx, parentID); err == nil {
+		if own.ID == householdID {
+			return nil, ErrInHousehold
+		}
+		if len(own.StudentIDs) > 0 || own.QBOCustomerID != "" || len(own.Guardians) > 1 {
+			return nil, ErrInHousehold
+		}
+		if _, err := m.removeGuardian(ctx, own.ID, parentID, true); err != nil {
+			return nil, err
+		}
+	} else if !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+
+	return m.update(ctx, householdID, func(h *Household) error {
+		now := m.now()
+		inv, ok := h.invitation(email, now)
+		if !ok {
+			return ErrNoInvitation
+		}
+		h.setGuardian(parentID, Guardian{Permissions: inv.Permissions, JoinedAt: now})
+		h.setInvitations(h.withoutInvitation(email), now)
+		return nil
+	})
+}
+
+// Decline drops a household's invitation for email.
+func (m *Manager) Decline(ctx context.Context, householdID, email string) error {
+	email = NormalizeEmail(email)
+	_, err := m.update(ctx, householdID, func(h *Household) error {
+		if _, ok := h.invitation(email, m.now()); !ok {
+			return ErrNoInvitation
+		}
+		h.setInvitations(h.withoutInvitation(email), m.now())
+		return nil
+	})
+	if errors.Is(err, ErrNotFound) {
+		return ErrNoInvitation
+	}
+	return err
+}
+
+// SetPermissions changes the permissions of a guardian.
+func (m *Manager) SetPermissions(ctx context.Context, householdID, parentID string, perms []string) (*Household, error) {
+	perms, err := NormalizePermissions(perms)
+	if err != nil {
+		return nil, err
+	}
+	return m.update(ctx, householdID, func(h *Household) error {
+		g, ok := h.Guardians[parentID]
+		if !ok {
+			return ErrGuardianNotFound
+		}
+		wasManager := contains(g.Permissions, PermissionManage)
+		if wasManager && !contains(perms, PermissionManage) && h.managers() == 1 {
+			return ErrLastManager
+		}
+		g.Permissions = perms
+		h.Guardians[parentID] = g
+		return nil
+	})
+}
+
+// RemoveGuardian takes parentID out of a household. The last guardian who
+// can manage it cannot be removed while others remain.
+func (m *Manager) RemoveGuardian(ctx context.Context, householdID, parentID string) (*Household, error) {
+	return m.removeGuardian(ctx, householdID, parentID, false)
+}
+
+func (m *Manager) removeGuardian(ctx context.Context, householdID, parentID string, allowEmpty bool) (*Household, error) {
+	return m.update(ctx, householdID, func(h *Household) error {
+		g, ok := h.Guardians[parentID]
+		if !ok {
+			return ErrGuardianNotFound
+		}
+		if contains(g.Permissions, PermissionManage) && h.managers() == 1 && (len(h.Guardians) > 1 || !allowEmpty) {
+			return ErrLastManager
+		}
+		delete(h.Guardians, parentID)
+		ids := h.GuardianIDs[:0]
+		for _, id := range h.GuardianIDs {
+			if id != parentID {
+				ids = append(ids, id)
+			}
+		}
+		h.GuardianIDs = ids
+		return nil
+	})
+}
+
+func (m *Manager) update(ctx context.Context, id string, fn func(*Household) error) (*Household, error) {
+	var updated *Household
+	err := m.store.Update(ctx, id, func(h *Household) error {
+		if err := fn(h); err != nil {
+			return err
+		}
+		h.UpdatedAt = m.now()
+		updated = h
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// migrate makes a household from the associated_students and
+// business.qboCustomerId of a parents document written before households.
+// While an admin is impersonating the household is only previewed: it is
+// returned but not stored, so looking at a family changes nothing.
+func (m *Manager) migrate(ctx context.Context, parentID string) (*Household, error) {
+	data, err := m.parent(ctx, parentID)
+	if err != nil {
+		return nil, err
+	}
+
+	var studentIDs []string
+	raw, _ := data["associated_students"].([]interface{})
+	for _, v := range raw {
+		if id, ok := v.(string); ok && id != "" && !contains(studentIDs, id) {
+			studentIDs = append(studentIDs, id)
+		}
+	}
+	var customerID string
+	if business, ok := data["business"].(map[string]interface{}); ok {
+		customerID, _ = business["qboCustomerId"].(string)
+	}
+	if len(studentIDs) == 0 && customerID == "" {
+		return nil, ErrNotFound
+	}
+
+	now := m.now()
+	h := &Household{
+		ID:            parentID,
+		StudentIDs:    studentIDs,
+		QBOCustomerID: customerID,
+		Guardians:     map[string]Guardian{},
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	h.setGuardian(parentID, Guardian{Permissions: allPermissions(), JoinedAt: now})
+	if admin, ok := middleware.ImpersonatorFromContext(ctx); ok {
+		log.Printf("Not storing household of parent %s: admin %s is impersonating", parentID, admin)
+		return h, nil
+	}
+	err = m.store.Create(ctx, h)
+	if status.Code(err) == codes.AlreadyExists {
+		// Made already, and the parent has since left it.
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Made household %s from parent %s (%d students)", h.ID, parentID, len(studentIDs))
+	return h, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,272 @@
+// backend/internal/households/household_test.go
+
+package households
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/middleware"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const testSecret = "test-secret"
+
+// activeSessions treats every session as active.
+type activeSessions struct{}
+
+func (activeSessions) Active(ctx context.Context, sid string) (bool, error) { return true, nil }
+
+// newTestManager returns a Manager over a memory store with two households:
+// "family", managed by "alice" with one student, and "bob-home", which
+// "bob" is the only guardian of and which has nothing in it yet.
+func newTestManager(t *testing.T) (*Manager, *MemoryStore) {
+	t.Helper()
+	ctx := context.Background()
+	store := NewMemoryStore()
+	for _, h := range []*Household{
+		{ID: "family", StudentIDs: []string{"student-0001"}},
+		{ID: "bob-home", StudentIDs: []string{}},
+	} {
+		owner := map[string]string{"family": "alice", "bob-home": "bob"}[h.ID]
+		h.Guardians = map[string]Guardian{}
+		h.setGuardian(owner, Guardian{Permissions: allPermissions()})
+		if err := store.Create(ctx, h); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return NewManager(store, nil), store
+}
+
+func TestInviteChangesNothingUntilAccepted(t *testing.T) {
+	ctx := context.Background()
+	m, store := newTestManager(t)
+
+	if err := m.Invite(ctx, "family", "alice", " Bob@Example.com ", []string{PermissionAcademic}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Bob keeps his own household, and new students still land in it.
+	own, err := m.ForGuardian(ctx, "bob")
+	if err != nil || own.ID != "bob-home" {
+		t.Fatalf("ForGuardian(bob) = %v, %v; want bob-home", own, err)
+	}
+	if _, err := m.AddStudents(ctx, "bob", "student-0002"); err != nil {
+		t.Fatal(err)
+	}
+	family, _, _ := store.Get(ctx, "family")
+	if family.HasStudent("student-0002") || family.Can("bob", PermissionAcademic) {
+		t.Errorf("family = %+v, want it unchanged by the invitation", family)
+	}
+
+	invited, err := m.Invitations(ctx, "bob@example.com")
+	if err != nil || len(invited) != 1 || invited[0].ID != "family" {
+		t.Errorf("Invitations = %v, %v; want [family]", invited, err)
+	}
+	if invited, _ := m.Invitations(ctx, "carol@example.com"); len(invited) != 0 {
+		t.Errorf("Invitations for another email = %v, want none", invited)
+	}
+}
+
+func TestAccept(t *testing.T) {
+	ctx := context.Background()
+	m, store := newTestManager(t)
+	if err := m.Invite(ctx, "family", "alice", "bob@example.com", []string{PermissionAcademic}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.Accept(ctx, "family", "mallory", "mallory@example.com"); !errors.Is(err, ErrNoInvitation) {
+		t.Errorf("Accept with another email error = %v, want %v", err, ErrNoInvitation)
+	}
+	if _, err := m.Accept(ctx, "bob-home", "bob", "bob@example.com"); !errors.Is(err, ErrNoInvitation) {
+		t.Errorf("Accept of an uninvited household error = %v, want %v", err, ErrNoInvitation)
+	}
+
+	h, err := m.Accept(ctx, "family", "bob", "BOB@example.com")
+	if err != nil {
+		t.Fatalf("Accept: %v", err)
+	}
+	if !h.Can("bob", PermissionAcademic) || h.Can("bob", PermissionBilling) {
+		t.Errorf("bob's permissions = %v, want [academic]", h.Guardians["bob"].Permissions)
+	}
+	if len(h.Invitations) != 0 || len(h.InvitedEmails) != 0 {
+		t.Errorf("invitation kept after accepting: %+v", h.Invitations)
+	}
+	if own, err := m.ForGuardian(ctx, "bob"); err != nil || own.ID != "family" {
+		t.Errorf("ForGuardian(bob) = %v, %v; want family", own, err)
+	}
+	// Bob's empty household was given up on accepting, not before.
+	if left, _, _ := store.Get(ctx, "bob-home"); len(left.Guardians) != 0 {
+		t.Errorf("bob-home guardians = %v, want none", left.Guardians)
+	}
+
+	if _, err := m.Accept(ctx, "family", "bob", "bob@example.com"); !errors.Is(err, ErrNoInvitation) {
+		t.Errorf("second Accept error = %v, want %v", err, ErrNoInvitation)
+	}
+}
+
+func TestAcceptKeepsNonEmptyHousehold(t *testing.T) {
+	ctx := context.Background()
+	m, store := newTestManager(t)
+	if _, err := m.AddStudents(ctx, "bob", "student-0002"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Invite(ctx, "family", "alice", "bob@example.com", []string{PermissionAcademic}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.Accept(ctx, "family", "bob", "bob@example.com"); !errors.Is(err, ErrInHousehold) {
+		t.Fatalf("Accept error = %v, want %v", err, ErrInHousehold)
+	}
+	own, _, _ := store.Get(ctx, "bob-home")
+	if !own.Can("bob", PermissionManage) || !own.HasStudent("student-0002") {
+		t.Errorf("bob-home = %+v, want it untouched", own)
+	}
+}
+
+func TestInvitationExpiresAndDeclines(t *testing.T) {
+	ctx := context.Background()
+	m, _ := newTestManager(t)
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+
+	for _, email := range []string{"bob@example.com", "carol@example.com"} {
+		if err := m.Invite(ctx, "family", "alice", email, []string{PermissionAcademic}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := m.Decline(ctx, "family", "carol@example.com"); err != nil {
+		t.Fatalf("Decline: %v", err)
+	}
+	if err := m.Decline(ctx, "family", "carol@example.com"); !errors.Is(err, ErrNoInvitation) {
+		t.Errorf("second Decline error = %v, want %v", err, ErrNoInvitation)
+	}
+	if err := m.Decline(ctx, "missing", "carol@example.com"); !errors.Is(err, ErrNoInvitation) {
+		t.Errorf("Decline of a missing household error = %v, want %v", err, ErrNoInvitation)
+	}
+
+	now = now.Add(InvitationTTL)
+	if invited, _ := m.Invitations(ctx, "bob@example.com"); len(invited) != 0 {
+		t.Errorf("Invitations after expiry = %v, want none", invited)
+	}
+	if _, err := m.Accept(ctx, "family", "bob", "bob@example.com"); !errors.Is(err, ErrNoInvitation) {
+		t.Errorf("Accept after expiry error = %v, want %v", err, ErrNoInvitation)
+	}
+}
+
+// serve sends a request through the auth middleware with an access token
+// for userID and email.
+func serve(t *testing.T, h http.HandlerFunc, method, body, userID, email string) *httptest.ResponseRecorder {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": userID,
+		"email":   email,
+		"role":    middleware.RoleParent,
+		"sid":     "session-1",
+	}).SignedString([]byte(testSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(method, "/api/household/guardians", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	middleware.AuthMiddleware(testSecret, activeSessions{}, nil)(h).ServeHTTP(rec, req)
+	return rec
+}
+
+func TestGuardiansHandlerDoesNotRevealAccounts(t *testing.T) {
+	m, store := newTestManager(t)
+
+	// One email has an account and the other does not; the answers match.
+	var bodies []string
+	for _, email := range []string{"bob@example.com", "nobody@example.com"} {
+		rec := serve(t, m.GuardiansHandler, http.MethodPost, `{"email": "`+email+`", "permissions": ["academic"]}`, "alice", "alice@example.com")
+		if rec.Code != http.StatusAccepted {
+			t.Fatalf("invite %s: status = %d, want %d", email, rec.Code, http.StatusAccepted)
+		}
+		bodies = append(bodies, rec.Body.String())
+	}
+	if bodies[0] != bodies[1] {
+		t.Errorf("responses differ: %q and %q", bodies[0], bodies[1])
+	}
+	if strings.Contains(bodies[0], "bob") {
+		t.Errorf("response %q names the invitee", bodies[0])
+	}
+
+	family, _, _ := store.Get(context.Background(), "family")
+	if len(family.Guardians) != 1 || len(family.Invitations) != 2 {
+		t.Errorf("family guardians = %v, invitations = %v; want alice and two invitations", family.GuardianIDs, family.InvitedEmails)
+	}
+
+	// Bob cannot invite people into a household he only sees.
+	if _, err := m.Accept(context.Background(), "family", "bob", "bob@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	rec := serve(t, m.GuardiansHandler, http.MethodPost, `{"email": "carol@example.com", "permissions": ["manage"]}`, "bob", "bob@example.com")
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("invite by an academic guardian: status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
+
+func TestInvitationsHandler(t *testing.T) {
+	m, _ := newTestManager(t)
+	if err := m.Invite(context.Background(), "family", "alice", "bob@example.com", []string{PermissionBilling}); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := serve(t, m.InvitationsHandler, http.MethodGet, "", "bob", "Bob@Example.com")
+	var got []invitationResponse
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].HouseholdID != "family" {
+		t.Errorf("invitations = %+v, want one from family", got)
+	}
+
+	rec = serve(t, m.InvitationsHandler, http.MethodGet, "", "mallory", "mallory@example.com")
+	if body := strings.TrimSpace(rec.Body.String()); body != "[]" {
+		t.Errorf("invitations for another email = %s, want []", body)
+	}
+}
+
+func TestMigrateOnlyPreviewsWhileImpersonating(t *testing.T) {
+	m, store := newTestManager(t)
+	m.parent = func(ctx context.Context, parentID string) (map[string]interface{}, error) {
+		if parentID != "legacy" {
+			return nil, ErrNotFound
+		}
+		return map[string]interface{}{
+			"associated_students": []interface{}{"student-0003", "student-0003", "student-0004"},
+			"business":            map[string]interface{}{"qboCustomerId": "58"},
+		}, nil
+	}
+
+	ctx := middleware.WithImpersonator(context.Background(), "admin-1")
+	h, err := m.ForGuardian(ctx, "legacy")
+	if err != nil {
+		t.Fatalf("ForGuardian while impersonating: %v", err)
+	}
+	if len(h.StudentIDs) != 2 || h.QBOCustomerID != "58" || !h.Can("legacy", PermissionManage) {
+		t.Errorf("previewed household = %+v, want two students billed to 58", h)
+	}
+	if _, found, _ := store.Get(ctx, "legacy"); found {
+		t.Fatal("household stored while impersonating")
+	}
+
+	// The parent's own visit stores it.
+	if _, err := m.ForGuardian(context.Background(), "legacy"); err != nil {
+		t.Fatal(err)
+	}
+	if _, found, _ := store.Get(context.Background(), "legacy"); !found {
+		t.Error("household not stored for the parent")
+	}
+	if _, err := m.ForGuardian(ctx, "nobody"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("ForGuardian of a parent without a document error = %v, want %v", err, ErrNotFound)
+	}
+}
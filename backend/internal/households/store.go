@@ -0,0 +1,264 @@
+// backend/internal/households/store.go
+
+package households
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Store keeps households.
+type Store interface {
+	Get(ctx context.Context, id string) (*Household, bool, error)
+	// Create stores h, failing with codes.AlreadyExists if its ID is taken.
+	Create(ctx context.Context, h *Household) error
+	// Update applies fn to the household atomically. If fn returns an error
+	// the household is left as it was.
+	Update(ctx context.Context, id string, fn func(*Household) error) error
+	// FindByGuardian returns the household parentID belongs to.
+	FindByGuardian(ctx context.Context, parentID string) (*Household, bool, error)
+	// ListByStudent returns the households that have the student.
+	ListByStudent(ctx context.Context, studentID string) ([]*Household, error)
+	// FindByCustomer returns the household billed to a QuickBooks customer.
+	FindByCustomer(ctx context.Context, customerID string) (*Household, bool, error)
+	// ListByInvitedEmail returns the households that have invited email.
+	ListByInvitedEmail(ctx context.Context, email string) ([]*Household, error)
+	// NewID returns an unused household ID.
+	NewID() string
+}
+
+type firestoreStore struct {
+	client *firestore.Client
+}
+
+// NewFirestoreStore returns a Store backed by the "households" collection.
+func NewFirestoreStore(client *firestore.Client) Store {
+	return &firestoreStore{client: client}
+}
+
+func (f *firestoreStore) households() *firestore.CollectionRef {
+	return f.client.Collection(Collection)
+}
+
+func (f *firestoreStore) Get(ctx context.Context, id string) (*Household, bool, error) {
+	snap, err := f.households().Doc(id).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	h, err := decode(snap)
+	return h, err == nil, err
+}
+
+func (f *firestoreStore) Create(ctx context.Context, h *Household) error {
+	_, err := f.households().Doc(h.ID).Create(ctx, h)
+	return err
+}
+
+func (f *firestoreStore) Update(ctx context.Context, id string, fn func(*Household) error) error {
+	ref := f.households().Doc(id)
+	return f.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		snap, err := tx.Get(ref)
+		if status.Code(err) == codes.NotFound {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		h, err := decode(snap)
+		if err != nil {
+			return err
+		}
+		if err := fn(h); err != nil {
+			return err
+		}
+		return tx.Set(ref, h)
+	})
+}
+
+func (f *firestoreStore) FindByGuardian(ctx context.Context, parentID string) (*Household, bool, error) {
+	return f.first(ctx, f.households().Where("guardian_ids", "array-contains", parentID))
+}
+
+func (f *firestoreStore) ListByStudent(ctx context.Context, studentID string) ([]*Household, error) {
+	return f.list(ctx, f.households().Where("students", "array-contains", studentID))
+}
+
+func (f *firestoreStore) ListByInvitedEmail(ctx context.Context, email string) ([]*Household, error) {
+	return f.list(ctx, f.households().Where("invited_emails", "array-contains", email))
+}
+
+func (f *firestoreStore) list(ctx context.Context, q firestore.Query) ([]*Household, error) {
+	snaps, err := q.Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*Household, 0, len(snaps))
+	for _, snap := range snaps {
+		h, err := decode(snap)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, h)
+	}
+	sortOldestFirst(out)
+	return out, nil
+}
+
+func (f *firestoreStore) FindByCustomer(ctx context.Context, customerID string) (*Household, bool, error) {
+	return f.first(ctx, f.households().Where("qbo_customer_id", "==", customerID))
+}
+
+func (f *firestoreStore) NewID() string {
+	return f.households().NewDoc().ID
+}
+
+func (f *firestoreStore) first(ctx context.Context, q firestore.Query) (*Household, bool, error) {
+	snaps, err := q.Limit(1).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, false, err
+	}
+	if len(snaps) == 0 {
+		return nil, false, nil
+	}
+	h, err := decode(snaps[0])
+	return h, err == nil, err
+}
+
+func decode(snap *firestore.DocumentSnapshot) (*Household, error) {
+	var h Household
+	if err := snap.DataTo(&h); err != nil {
+		return nil, err
+	}
+	h.ID = snap.Ref.ID
+	if h.Guardians == nil {
+		h.Guardians = map[string]Guardian{}
+	}
+	return &h, nil
+}
+
+func sortOldestFirst(hs []*Household) {
+	sort.SliceStable(hs, func(i, j int) bool {
+		return hs[i].CreatedAt.Before(hs[j].CreatedAt)
+	})
+}
+
+// MemoryStore is an in-memory Store for tests and local runs.
+type MemoryStore struct {
+	mu         sync.Mutex
+	households map[string]Household
+	nextID     int
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{households: map[string]Household{}}
+}
+
+// copyHousehold returns h with its own slices and map, so callers cannot
+// change what is stored.
+func copyHousehold(h Household) *Household {
+	h.StudentIDs = append([]string(nil), h.StudentIDs...)
+	h.GuardianIDs = append([]string(nil), h.GuardianIDs...)
+	h.InvitedEmails = append([]string(nil), h.InvitedEmails...)
+	invs := make([]Invitation, len(h.Invitations))
+	for i, inv := range h.Invitations {
+		inv.Permissions = append([]string(nil), inv.Permissions...)
+		invs[i] = inv
+	}
+	h.Invitations = invs
+	guardians := make(map[string]Guardian, len(h.Guardians))
+	for id, g := range h.Guardians {
+		g.Permissions = append([]string(nil), g.Permissions...)
+		guardians[id] = g
+	}
+	h.Guardians = guardians
+	return &h
+}
+
+func (m *MemoryStore) Get(ctx context.Context, id string) (*Household, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	h, ok := m.households[id]
+	if !ok {
+		return nil, false, nil
+	}
+	return copyHousehold(h), true, nil
+}
+
+func (m *MemoryStore) Create(ctx context.Context, h *Household) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.households[h.ID]; ok {
+		return status.Errorf(codes.AlreadyExists, "household %s already exists", h.ID)
+	}
+	m.households[h.ID] = *copyHousehold(*h)
+	return nil
+}
+
+func (m *MemoryStore) Update(ctx context.Context, id string, fn func(*Household) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored, ok := m.households[id]
+	if !ok {
+		return ErrNotFound
+	}
+	h := copyHousehold(stored)
+	if err := fn(h); err != nil {
+		return err
+	}
+	m.households[id] = *h
+	return nil
+}
+
+func (m *MemoryStore) FindByGuardian(ctx context.Context, parentID string) (*Household, bool, error) {
+	hs := m.filter(func(h *Household) bool { return contains(h.GuardianIDs, parentID) })
+	if len(hs) == 0 {
+		return nil, false, nil
+	}
+	return hs[0], true, nil
+}
+
+func (m *MemoryStore) ListByStudent(ctx context.Context, studentID string) ([]*Household, error) {
+	return m.filter(func(h *Household) bool { return contains(h.StudentIDs, studentID) }), nil
+}
+
+func (m *MemoryStore) ListByInvitedEmail(ctx context.Context, email string) ([]*Household, error) {
+	return m.filter(func(h *Household) bool { return contains(h.InvitedEmails, email) }), nil
+}
+
+func (m *MemoryStore) FindByCustomer(ctx context.Context, customerID string) (*Household, bool, error) {
+	hs := m.filter(func(h *Household) bool { return h.QBOCustomerID == customerID })
+	if len(hs) == 0 {
+		return nil, false, nil
+	}
+	return hs[0], true, nil
+}
+
+func (m *MemoryStore) NewID() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextID++
+	return "household-" + strconv.Itoa(m.nextID)
+}
+
+func (m *MemoryStore) filter(keep func(*Household) bool) []*Household {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []*Household
+	for _, h := range m.households {
+		if c := copyHousehold(h); keep(c) {
+			out = append(out, c)
+		}
+	}
+	sortOldestFirst(out)
+	return out
+}
@@ -0,0 +1,372 @@
+// backend/internal/identity/identity.go
+
+package identity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/mfa"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/middleware"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/securitylog"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/session"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/staff"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/tokencrypt"
+	"golang.org/x/oauth2"
+)
+
+// Collections accounts are stored in.
+const (
+	TutorsCollection   = "tutors"
+	StudentsCollection = "students"
+	ParentsCollection  = "parents"
+)
+
+var (
+	// ErrNoSubject is returned for a profile without a provider user ID.
+	ErrNoSubject = errors.New("profile has no user ID")
+	// ErrNoEmail is returned when the provider gave no email and none was
+	// stored on an earlier login.
+	ErrNoEmail = errors.New("profile has no email")
+	// ErrNotStaff is returned for a tutor account whose email is no longer
+	// an active member of the staff directory.
+	ErrNotStaff = errors.New("not an active staff member")
+	// ErrStaffEmailUnverified is returned for a staff email the provider
+	// does not vouch for.
+	ErrStaffEmailUnverified = errors.New("staff email is not verified")
+)
+
+// Profile is what a provider tells us about the person signing in.
+type Profile struct {
+	// Provider is "google", "microsoft", "yahoo", "facebook" or "apple".
+	Provider string
+	// Subject is the provider's ID for the person.
+	Subject string
+	Email   string
+	// EmailVerified is true when the provider vouches for the email. Only a
+	// verified email signs in to an account first created with another provider.
+	EmailVerified bool
+	Name          string
+	PictureURL    string
+	// HasProfilePicture is set by providers that cannot give a picture URL.
+	HasProfilePicture *bool
+	// Token is the provider's OAuth token, kept for later API calls.
+	Token *oauth2.Token
+}
+
+// Account is the document a profile resolved to.
+type Account struct {
+	// UserID is the "user_id" claim of the JWT. It is the account's document
+	// ID, which stays the same whichever provider is used to sign in.
+	UserID string
+	Email  string
+	Role   string
+	// Collection and ID locate the account's document.
+	Collection string
+	ID         string
+	// StaffRole is the staff directory role of a tutor account.
+	StaffRole string
+	// Provider is the login provider the account was resolved from.
+	Provider string
+	Created  bool
+}
+
+// Resolver decides who a profile belongs to and keeps their document in step.
+type Resolver struct {
+	staff    *staff.Directory
+	store    Store
+	sessions *session.Manager
+	tokens   *tokencrypt.Keyring
+	mfa      *mfa.Manager
+	events   *securitylog.Recorder
+}
+
+// NewResolver returns a Resolver over store. Tutors and admins are read from
+// the staff directory, sign-ins start sessions with sessions, and provider
+// tokens are encrypted with tokens before they are stored. Staff sign-ins
+// wait for a code from their authenticator when factors asks for one.
+// Sign-ins and linked logins are recorded in events.
+func NewResolver(directory *staff.Directory, store Store, sessions *session.Manager, tokens *tokencrypt.Keyring, factors *mfa.Manager, events *securitylog.Recorder) *Resolver {
+	return &Resolver{staff: directory, store: store, sessions: sessions, tokens: tokens, mfa: factors, events: events}
+}
+
+// Resolve finds the account for p, creating or updating its document.
+//
+// A provider identity that is already linked signs in to its account.
+// Otherwise an active member of the staff directory is a tutor (or an
+// admin), a verified email matching a student's personal.student_email is
+// that student, and anyone else is a parent. Tutors and parents are matched to an
+// existing account by the document created before accounts had their own
+// IDs, then by verified email; failing both, a new account is created. The
+// identity is then linked to the account. A tutor account whose member is
+// removed or made inactive can no longer sign in.
+func (res *Resolver) Resolve(ctx context.Context, p Profile) (*Account, error) {
+	if p.Subject == "" {
+		return nil, ErrNoSubject
+	}
+	key := IdentityKey(p.Provider, p.Subject)
+	p.Email = strings.TrimSpace(p.Email)
+
+	acct, linked, err := res.linkedAccount(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+
+	if acct == nil {
+		if p.Email == "" {
+			if p.Email, err = res.legacyEmail(ctx, p.Subject); err != nil {
+				return nil, err
+			}
+		}
+		if acct, err = res.match(ctx, p); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := res.save(ctx, acct, p); err != nil {
+		return nil, err
+	}
+
+	if !linked {
+		if err := res.store.AddLinkedIdentity(ctx, acct.Collection, acct.ID, newLinkedIdentity(p)); err != nil {
+			return nil, fmt.Errorf("link %s to %s/%s: %w", key, acct.Collection, acct.ID, err)
+		}
+	}
+	acct.Provider = p.Provider
+	return acct, nil
+}
+
+// linkedAccount returns the account p's identity is linked to, or nil. An
+// identity recorded by the parent migration without its provider is relinked
+// under the provider now that it is known.
+func (res *Resolver) linkedAccount(ctx context.Context, p Profile) (*Account, bool, error) {
+	key := IdentityKey(p.Provider, p.Subject)
+	collection, id, found, err := res.store.FindLinkedIdentity(ctx, key)
+	if err != nil {
+		return nil, false, fmt.Errorf("look up %s: %w", key, err)
+	}
+	linked := found
+	if !found {
+		legacyKey := IdentityKey(LegacyProvider, p.Subject)
+		collection, id, found, err = res.store.FindLinkedIdentity(ctx, legacyKey)
+		if err != nil {
+			return nil, false, fmt.Errorf("look up %s: %w", legacyKey, err)
+		}
+		if !found {
+			return nil, false, nil
+		}
+		if err := res.store.RemoveLinkedIdentity(ctx, collection, id, legacyKey); err != nil {
+			return nil, false, fmt.Errorf("relink %s: %w", legacyKey, err)
+		}
+	}
+
+	data, exists, err := res.store.Get(ctx, collection, id)
+	if err != nil {
+		return nil, false, err
+	}
+	if !exists {
+		// The account was deleted; treat the identity as new.
+		if err := res.store.RemoveLinkedIdentity(ctx, collection, id, key); err != nil {
+			return nil, false, err
+		}
+		return nil, false, nil
+	}
+
+	email, _ := data["email"].(string)
+	if email == "" {
+		email = p.Email
+	}
+	acct := &Account{UserID: id, Email: email, Collection: collection, ID: id}
+	switch collection {
+	case TutorsCollection:
+		member, err := res.activeStaff(ctx, email, p.Email)
+		if err != nil {
+			return nil, false, err
+		}
+		acct.Role, acct.StaffRole = member.SessionRole(), member.Role
+	case StudentsCollection:
+		acct.Role = middleware.RoleStudent
+	default:
+		acct.Role = middleware.RoleParent
+	}
+	return acct, linked, nil
+}
+
+// match finds or allocates the account for an identity that is not linked yet.
+func (res *Resolver) match(ctx context.Context, p Profile) (*Account, error) {
+	acct := &Account{Email: p.Email}
+	member, isStaff, err := res.staff.Lookup(ctx, p.Email)
+	if err != nil {
+		return nil, fmt.Errorf("look up staff by email: %w", err)
+	}
+	if isStaff {
+		if !member.Active {
+			return nil, ErrNotStaff
+		}
+		if !p.EmailVerified {
+			return nil, ErrStaffEmailUnverified
+		}
+		acct.Role, acct.StaffRole = member.SessionRole(), member.Role
+		acct.Collection = TutorsCollection
+	} else {
+		// A student's email is typed in by staff, so only a provider that
+		// vouches for it may sign in as the student.
+		if p.EmailVerified {
+			studentID, found, err := res.store.FindStudentByEmail(ctx, p.Email)
+			if err != nil {
+				return nil, fmt.Errorf("look up student by email: %w", err)
+			}
+			if found {
+				acct.Role = middleware.RoleStudent
+				acct.Collection, acct.ID, acct.UserID = StudentsCollection, studentID, studentID
+				return acct, nil
+			}
+		}
+		acct.Role = middleware.RoleParent
+		acct.Collection = ParentsCollection
+	}
+
+	// Accounts created before linking was introduced use the subject as ID.
+	_, legacy, err := res.store.Get(ctx, acct.Collection, p.Subject)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case legacy:
+		acct.ID = p.Subject
+	case p.EmailVerified:
+		ids, err := res.store.FindByEmail(ctx, acct.Collection, p.Email)
+		if err != nil {
+			return nil, fmt.Errorf("look up %s by email: %w", acct.Collection, err)
+		}
+		if len(ids) > 0 {
+			acct.ID = ids[0]
+		}
+	}
+	if acct.ID == "" {
+		acct.ID = res.store.NewID(acct.Collection)
+	}
+	acct.UserID = acct.ID
+	return acct, nil
+}
+
+// activeStaff returns the active staff member with any of emails.
+func (res *Resolver) activeStaff(ctx context.Context, emails ...string) (*staff.Member, error) {
+	for _, email := range emails {
+		member, found, err := res.staff.Lookup(ctx, email)
+		if err != nil {
+			return nil, fmt.Errorf("look up staff by email: %w", err)
+		}
+		if found && member.Active {
+			return member, nil
+		}
+	}
+	return nil, ErrNotStaff
+}
+
+// legacyEmail returns the email saved on an earlier login by a document
+// keyed by the subject. Apple only sends the email the first time someone
+// signs in; linked accounts have theirs on the account document.
+func (res *Resolver) legacyEmail(ctx context.Context, subject string) (string, error) {
+	for _, collection := range []string{ParentsCollection, TutorsCollection} {
+		data, found, err := res.store.Get(ctx, collection, subject)
+		if err != nil {
+			return "", err
+		}
+		if !found {
+			continue
+		}
+		if email, _ := data["email"].(string); email != "" {
+			return email, nil
+		}
+	}
+	return "", ErrNoEmail
+}
+
+// save creates the account's document, or updates the fields that changed.
+func (res *Resolver) save(ctx context.Context, acct *Account, p Profile) error {
+	data, found, err := res.store.Get(ctx, acct.Collection, acct.ID)
+	if err != nil {
+		return fmt.Errorf("get %s/%s: %w", acct.Collection, acct.ID, err)
+	}
+
+	var accessToken, refreshToken string
+	if p.Token != nil {
+		if accessToken, err = res.tokens.Encrypt(p.Token.AccessToken); err != nil {
+			return fmt.Errorf("encrypt access token: %w", err)
+		}
+		if refreshToken, err = res.tokens.Encrypt(p.Token.RefreshToken); err != nil {
+			return fmt.Errorf("encrypt refresh token: %w", err)
+		}
+	}
+
+	if !found {
+		doc := map[string]interface{}{
+			"user_id":    acct.ID,
+			"email":      acct.Email,
+			"name":       p.Name,
+			"userType":   acct.Role,
+			"created_at": time.Now(),
+		}
+		if p.HasProfilePicture != nil {
+			doc["has_profile_picture"] = *p.HasProfilePicture
+		} else {
+			doc["picture"] = p.PictureURL
+		}
+		if p.Token != nil {
+			doc["access_token"] = accessToken
+			doc["refresh_token"] = refreshToken
+			doc["expiry"] = p.Token.Expiry
+		}
+		if acct.StaffRole != "" {
+			doc["staff_role"] = acct.StaffRole
+		}
+		if err := res.store.Create(ctx, acct.Collection, acct.ID, doc); err != nil {
+			return fmt.Errorf("create %s/%s: %w", acct.Collection, acct.ID, err)
+		}
+		acct.Created = true
+		return nil
+	}
+
+	updates := map[string]interface{}{}
+	set := func(field string, value interface{}) {
+		if data[field] != value {
+			updates[field] = value
+		}
+	}
+	if p.Token != nil {
+		updates["access_token"] = accessToken
+		if refreshToken != "" {
+			updates["refresh_token"] = refreshToken
+		}
+		if exp, ok := data["expiry"].(time.Time); !ok || !exp.Equal(p.Token.Expiry) {
+			updates["expiry"] = p.Token.Expiry
+		}
+	}
+	// Providers that only send some fields on the first login must not
+	// blank them out afterwards.
+	if p.Name != "" {
+		set("name", p.Name)
+	}
+	if p.PictureURL != "" {
+		set("picture", p.PictureURL)
+	}
+	if p.HasProfilePicture != nil {
+		set("has_profile_picture", *p.HasProfilePicture)
+	}
+	set("userType", acct.Role)
+	if acct.StaffRole != "" {
+		set("staff_role", acct.StaffRole)
+	}
+
+	if len(updates) == 0 {
+		return nil
+	}
+	if err := res.store.Update(ctx, acct.Collection, acct.ID, updates); err != nil {
+		return fmt.Errorf("update %s/%s: %w", acct.Collection, acct.ID, err)
+	}
+	return nil
+}
@@ -0,0 +1,214 @@
+// backend/internal/identity/identity_test.go
+
+package identity
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/middleware"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/staff"
+)
+
+// newTestResolver returns a Resolver over an in-memory store holding a
+// student, a parent from before accounts had their own IDs and a parent
+// keyed by ID, and a staff directory with an active tutor, an active team
+// lead, an admin and a tutor who has left.
+func newTestResolver(t *testing.T) (*Resolver, *MemoryStore, *staff.Directory) {
+	t.Helper()
+	ctx := context.Background()
+
+	directory := staff.NewDirectory(staff.NewMemoryStore(), nil, nil, nil)
+	for _, m := range []*staff.Member{
+		{Name: "Tess Tutor", Email: "tess@leetutoring.com", Aliases: []string{"tess@gmail.com"}, Role: middleware.RoleTutor, Active: true},
+		{Name: "Lee Lead", Email: "lee@leetutoring.com", Role: middleware.RoleTeamLead, Active: true},
+		{Name: "Ada Admin", Email: "ada@leetutoring.com", Role: middleware.RoleAdmin, Active: true},
+		{Name: "Gone Tutor", Email: "gone@leetutoring.com", Role: middleware.RoleTutor, Active: false},
+	} {
+		if err := directory.Create(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	store := NewMemoryStore()
+	store.Put(StudentsCollection, "student-1", map[string]interface{}{
+		"personal": map[string]interface{}{"name": "Sam Student", "student_email": "sam@school.org"},
+	})
+	store.Put(ParentsCollection, "legacy-subject", map[string]interface{}{"user_id": "legacy-subject", "email": "old@example.com"})
+	store.Put(ParentsCollection, "parent-1", map[string]interface{}{"user_id": "parent-1", "email": "pat@example.com"})
+
+	return NewResolver(directory, store, nil, nil, nil, nil), store, directory
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile Profile
+		// wantID is the expected account ID; "new" means a newly created one.
+		wantID         string
+		wantCollection string
+		wantRole       string
+		wantStaffRole  string
+		wantErr        error
+	}{
+		{
+			name:           "staff",
+			profile:        Profile{Provider: "google", Subject: "g-tess", Email: "tess@leetutoring.com", EmailVerified: true},
+			wantID:         "new",
+			wantCollection: TutorsCollection,
+			wantRole:       middleware.RoleTutor,
+			wantStaffRole:  middleware.RoleTutor,
+		},
+		{
+			name:           "staff alias",
+			profile:        Profile{Provider: "google", Subject: "g-tess-alias", Email: "Tess@Gmail.com", EmailVerified: true},
+			wantID:         "new",
+			wantCollection: TutorsCollection,
+			wantRole:       middleware.RoleTutor,
+			wantStaffRole:  middleware.RoleTutor,
+		},
+		{
+			name:           "team lead",
+			profile:        Profile{Provider: "google", Subject: "g-lee", Email: "lee@leetutoring.com", EmailVerified: true},
+			wantID:         "new",
+			wantCollection: TutorsCollection,
+			wantRole:       middleware.RoleTutor,
+			wantStaffRole:  middleware.RoleTeamLead,
+		},
+		{
+			name:           "admin",
+			profile:        Profile{Provider: "microsoft", Subject: "m-ada", Email: "ada@leetutoring.com", EmailVerified: true},
+			wantID:         "new",
+			wantCollection: TutorsCollection,
+			wantRole:       middleware.RoleAdmin,
+			wantStaffRole:  middleware.RoleAdmin,
+		},
+		{
+			name:    "inactive staff",
+			profile: Profile{Provider: "google", Subject: "g-gone", Email: "gone@leetutoring.com", EmailVerified: true},
+			wantErr: ErrNotStaff,
+		},
+		{
+			name:    "unverified staff",
+			profile: Profile{Provider: "facebook", Subject: "f-tess", Email: "tess@leetutoring.com"},
+			wantErr: ErrStaffEmailUnverified,
+		},
+		{
+			name:           "student",
+			profile:        Profile{Provider: "google", Subject: "g-sam", Email: "sam@school.org", EmailVerified: true},
+			wantID:         "student-1",
+			wantCollection: StudentsCollection,
+			wantRole:       middleware.RoleStudent,
+		},
+		{
+			name:           "unverified student email",
+			profile:        Profile{Provider: "yahoo", Subject: "y-sam", Email: "sam@school.org"},
+			wantID:         "new",
+			wantCollection: ParentsCollection,
+			wantRole:       middleware.RoleParent,
+		},
+		{
+			name:           "new parent",
+			profile:        Profile{Provider: "google", Subject: "g-new", Email: "new@example.com", EmailVerified: true},
+			wantID:         "new",
+			wantCollection: ParentsCollection,
+			wantRole:       middleware.RoleParent,
+		},
+		{
+			name:           "legacy subject",
+			profile:        Profile{Provider: "google", Subject: "legacy-subject", Email: "old@example.com"},
+			wantID:         "legacy-subject",
+			wantCollection: ParentsCollection,
+			wantRole:       middleware.RoleParent,
+		},
+		{
+			name:           "email merge",
+			profile:        Profile{Provider: "apple", Subject: "a-pat", Email: "pat@example.com", EmailVerified: true},
+			wantID:         "parent-1",
+			wantCollection: ParentsCollection,
+			wantRole:       middleware.RoleParent,
+		},
+		{
+			name:           "unverified email does not merge",
+			profile:        Profile{Provider: "facebook", Subject: "f-pat", Email: "pat@example.com"},
+			wantID:         "new",
+			wantCollection: ParentsCollection,
+			wantRole:       middleware.RoleParent,
+		},
+		{
+			name:    "no subject",
+			profile: Profile{Provider: "google", Email: "new@example.com", EmailVerified: true},
+			wantErr: ErrNoSubject,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, store, _ := newTestResolver(t)
+			ctx := context.Background()
+
+			acct, err := res.Resolve(ctx, tt.profile)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Resolve error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resolve: %v", err)
+			}
+
+			if acct.Collection != tt.wantCollection || acct.Role != tt.wantRole || acct.StaffRole != tt.wantStaffRole {
+				t.Errorf("account = %s %s (staff role %q), want %s %s (staff role %q)",
+					acct.Collection, acct.Role, acct.StaffRole, tt.wantCollection, tt.wantRole, tt.wantStaffRole)
+			}
+			if tt.wantID == "new" {
+				if !acct.Created {
+					t.Errorf("account %s was not created", acct.ID)
+				}
+			} else if acct.ID != tt.wantID || acct.Created {
+				t.Errorf("account ID = %s (created %v), want existing %s", acct.ID, acct.Created, tt.wantID)
+			}
+			if acct.UserID != acct.ID || acct.Provider != tt.profile.Provider {
+				t.Errorf("user ID = %s, provider = %s; want %s, %s", acct.UserID, acct.Provider, acct.ID, tt.profile.Provider)
+			}
+
+			collection, id, found, err := store.FindLinkedIdentity(ctx, IdentityKey(tt.profile.Provider, tt.profile.Subject))
+			if err != nil || !found || collection != acct.Collection || id != acct.ID {
+				t.Errorf("identity linked to %s/%s (found %v, err %v), want %s/%s", collection, id, found, err, acct.Collection, acct.ID)
+			}
+
+			// Signing in again with the same identity reaches the same account.
+			again, err := res.Resolve(ctx, tt.profile)
+			if err != nil {
+				t.Fatalf("second Resolve: %v", err)
+			}
+			if again.ID != acct.ID || again.Created {
+				t.Errorf("second sign-in reached %s (created %v), want %s", again.ID, again.Created, acct.ID)
+			}
+		})
+	}
+}
+
+func TestResolveStaffRemovedAfterLinking(t *testing.T) {
+	res, _, directory := newTestResolver(t)
+	ctx := context.Background()
+	p := Profile{Provider: "google", Subject: "g-tess", Email: "tess@leetutoring.com", EmailVerified: true}
+
+	if _, err := res.Resolve(ctx, p); err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	member, _, err := directory.Lookup(ctx, p.Email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	member.Active = false
+	if err := directory.Update(ctx, member); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := res.Resolve(ctx, p); !errors.Is(err, ErrNotStaff) {
+		t.Errorf("Resolve after leaving error = %v, want %v", err, ErrNotStaff)
+	}
+}
@@ -0,0 +1,360 @@
+// backend/internal/identity/links.go
+
+package identity
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/middleware"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/oauthstate"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/securitylog"
+	"github.com/gorilla/mux"
+)
+
+// Every provider identity that can sign in to an account is a document in the
+// account's "linked_identities" subcollection, keyed by "provider:subject".
+// Signing in with an identity that is not linked yet links it to the account
+// it resolves to; a signed-in user can also link one explicitly.
+
+// LinkedIdentitiesCollection is the subcollection of an account document
+// holding its identities.
+const LinkedIdentitiesCollection = "linked_identities"
+
+// LegacyProvider marks identities recorded by the parent merge migration,
+// which only knew the subject. They are relinked under the real provider on
+// the next sign-in.
+const LegacyProvider = "legacy"
+
+// Providers are the login providers an identity can come from.
+var Providers = []string{"google", "microsoft", "yahoo", "facebook", "apple"}
+
+// linkRequestTTL is how long a user has to finish signing in with the
+// provider they are linking.
+const linkRequestTTL = 10 * time.Minute
+
+var (
+	// ErrIdentityInUse is returned when linking an identity that already
+	// signs in to a different account.
+	ErrIdentityInUse = errors.New("identity is linked to another account")
+	// ErrLastIdentity is returned when unlinking the only way into an account.
+	ErrLastIdentity = errors.New("cannot unlink the last identity")
+	// ErrIdentityNotFound is returned when unlinking an identity the account
+	// does not have.
+	ErrIdentityNotFound = errors.New("identity not found")
+)
+
+// LinkedIdentity is one provider identity that signs in to an account.
+type LinkedIdentity struct {
+	Key           string    `firestore:"key" json:"id"`
+	Provider      string    `firestore:"provider" json:"provider"`
+	Subject       string    `firestore:"subject" json:"subject"`
+	Email         string    `firestore:"email" json:"email"`
+	EmailVerified bool      `firestore:"email_verified" json:"email_verified"`
+	LinkedAt      time.Time `firestore:"linked_at" json:"linked_at"`
+}
+
+// LinkRequest is a signed-in user's request to link another provider.
+// SessionID is the session it was made from, which must still belong to the
+// account when the provider's callback comes back.
+type LinkRequest struct {
+	Collection string    `firestore:"collection"`
+	AccountID  string    `firestore:"account_id"`
+	SessionID  string    `firestore:"session_id"`
+	Provider   string    `firestore:"provider"`
+	ExpiresAt  time.Time `firestore:"expires_at"`
+}
+
+// IdentityKey is the ID of the linked identity for a provider's subject.
+func IdentityKey(provider, subject string) string {
+	return provider + ":" + subject
+}
+
+func newLinkedIdentity(p Profile) LinkedIdentity {
+	return LinkedIdentity{
+		Key:           IdentityKey(p.Provider, p.Subject),
+		Provider:      p.Provider,
+		Subject:       p.Subject,
+		Email:         p.Email,
+		EmailVerified: p.EmailVerified,
+		LinkedAt:      time.Now(),
+	}
+}
+
+// CollectionForRole returns the collection holding the accounts of role.
+func CollectionForRole(role string) string {
+	switch role {
+	case middleware.RoleTutor, middleware.RoleAdmin:
+		return TutorsCollection
+	case middleware.RoleStudent:
+		return StudentsCollection
+	default:
+		return ParentsCollection
+	}
+}
+
+// Link links p's identity to the account at collection/id. Linking an
+// identity that is already linked to the account does nothing.
+func (res *Resolver) Link(ctx context.Context, collection, id string, p Profile) error {
+	if p.Subject == "" {
+		return ErrNoSubject
+	}
+	for _, key := range []string{IdentityKey(p.Provider, p.Subject), IdentityKey(LegacyProvider, p.Subject)} {
+		linkedCollection, linkedID, found, err := res.store.FindLinkedIdentity(ctx, key)
+		if err != nil {
+			return fmt.Errorf("look up %s: %w", key, err)
+		}
+		if !found {
+			continue
+		}
+		if linkedCollection != collection || linkedID != id {
+			return ErrIdentityInUse
+		}
+		if key == IdentityKey(p.Provider, p.Subject) {
+			return nil
+		}
+		if err := res.store.RemoveLinkedIdentity(ctx, collection, id, key); err != nil {
+			return fmt.Errorf("relink %s: %w", key, err)
+		}
+	}
+	return res.store.AddLinkedIdentity(ctx, collection, id, newLinkedIdentity(p))
+}
+
+// Unlink removes an identity from an account, unless it is the last one.
+func (res *Resolver) Unlink(ctx context.Context, collection, id, key string) error {
+	links, err := res.store.ListLinkedIdentities(ctx, collection, id)
+	if err != nil {
+		return err
+	}
+	found := false
+	for _, li := range links {
+		if li.Key == key {
+			found = true
+		}
+	}
+	if !found {
+		return ErrIdentityNotFound
+	}
+	if len(links) == 1 {
+		return ErrLastIdentity
+	}
+	return res.store.RemoveLinkedIdentity(ctx, collection, id, key)
+}
+
+// Complete finishes a provider callback. If the flow was started from the
+// link endpoint, p's identity is linked to the user's account and they are
+// sent back to the app; otherwise they are signed in to the account p
+// resolves to.
+//
+// A link is only made from the browser that holds the request's link cookie,
+// while the session that asked for it is still signed in to the account.
+func (res *Resolver) Complete(w http.ResponseWriter, r *http.Request, flow *oauthstate.Flow, p Profile) {
+	ctx := r.Context()
+
+	if flow.Link != "" {
+		oauthstate.ClearLinkCookie(w)
+		cookie, err := r.Cookie(oauthstate.LinkCookie)
+		if err != nil || cookie.Value != flow.Link {
+			http.Error(w, "Start linking from your account settings in this browser", http.StatusForbidden)
+			return
+		}
+		req, found, err := res.store.TakeLinkRequest(ctx, flow.Link)
+		if err != nil {
+			log.Printf("Failed to load link request: %v", err)
+			http.Error(w, "Failed to link account", http.StatusInternalServerError)
+			return
+		}
+		if !found || req.Provider != p.Provider || time.Now().After(req.ExpiresAt) {
+			http.Error(w, "Link request expired, please try again", http.StatusBadRequest)
+			return
+		}
+		active, err := res.linkSessionActive(ctx, req)
+		if err != nil {
+			log.Printf("Failed to check session of link request for %s/%s: %v", req.Collection, req.AccountID, err)
+			http.Error(w, "Failed to link account", http.StatusInternalServerError)
+			return
+		}
+		if !active {
+			http.Error(w, "You were signed out, please sign in and try again", http.StatusUnauthorized)
+			return
+		}
+		res.completeLink(w, r, req, p)
+		return
+	}
+
+	acct, err := res.Resolve(ctx, p)
+	if err != nil {
+		res.ResolveError(w, r, p, err)
+		return
+	}
+	res.CompleteLogin(w, r, acct, flow.ReturnTo)
+}
+
+// linkSessionActive reports whether the session a link request was made from
+// is still active and still the account's own.
+func (res *Resolver) linkSessionActive(ctx context.Context, req *LinkRequest) (bool, error) {
+	if req.SessionID == "" {
+		return false, nil
+	}
+	sess, found, err := res.sessions.Get(ctx, req.SessionID)
+	if err != nil || !found {
+		return false, err
+	}
+	return sess.UserID == req.AccountID && sess.ImpersonatorID == "" && CollectionForRole(sess.Role) == req.Collection, nil
+}
+
+func (res *Resolver) completeLink(w http.ResponseWriter, r *http.Request, req *LinkRequest, p Profile) {
+	err := res.Link(r.Context(), req.Collection, req.AccountID, p)
+	if errors.Is(err, ErrIdentityInUse) {
+		http.Error(w, "This login is already used by another account", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to link %s to %s/%s: %v", IdentityKey(p.Provider, p.Subject), req.Collection, req.AccountID, err)
+		http.Error(w, "Failed to link account", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Linked %s identity to %s/%s", p.Provider, req.Collection, req.AccountID)
+	res.events.RecordRequest(r, securitylog.Event{
+		Type:     securitylog.IdentityLink,
+		UserID:   req.AccountID,
+		Email:    p.Email,
+		Provider: p.Provider,
+	})
+	http.Redirect(w, r, FrontendURL+"/?linked="+url.QueryEscape(p.Provider), http.StatusSeeOther)
+}
+
+// accountFromContext returns the account of the signed-in user.
+func accountFromContext(ctx context.Context) (collection, id string, ok bool) {
+	userID, err := middleware.ExtractUserIDFromContext(ctx)
+	if err != nil {
+		return "", "", false
+	}
+	return CollectionForRole(middleware.GetRoleFromContext(ctx)), userID, true
+}
+
+// IdentitiesHandler handles GET /api/account/identities, listing the
+// identities that sign in to the caller's account.
+func (res *Resolver) IdentitiesHandler(w http.ResponseWriter, r *http.Request) {
+	collection, id, ok := accountFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	links, err := res.store.ListLinkedIdentities(r.Context(), collection, id)
+	if err != nil {
+		log.Printf("Error listing identities of %s/%s: %v", collection, id, err)
+		http.Error(w, "Failed to list identities", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(links)
+}
+
+// LinkIdentityHandler handles POST /api/account/identities/link with
+// {"provider": "microsoft"}. It returns the URL to send the browser to; once
+// the user signs in there, that identity is linked to their account. The
+// request is bound to the caller's browser by a cookie, so the app has to
+// call this with credentials included, and to their session.
+func (res *Resolver) LinkIdentityHandler(w http.ResponseWriter, r *http.Request) {
+	collection, id, ok := accountFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	claims, _ := middleware.GetUserFromContext(r.Context())
+	sid, _ := claims["sid"].(string)
+	if sid == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var body struct {
+		Provider string `json:"provider"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	known := false
+	for _, provider := range Providers {
+		if body.Provider == provider {
+			known = true
+		}
+	}
+	if !known {
+		http.Error(w, "Unknown provider", http.StatusBadRequest)
+		return
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		http.Error(w, "Failed to start linking", http.StatusInternalServerError)
+		return
+	}
+	nonce := hex.EncodeToString(b)
+	req := LinkRequest{
+		Collection: collection,
+		AccountID:  id,
+		SessionID:  sid,
+		Provider:   body.Provider,
+		ExpiresAt:  time.Now().Add(linkRequestTTL),
+	}
+	if err := res.store.CreateLinkRequest(r.Context(), nonce, req); err != nil {
+		log.Printf("Error creating link request for %s/%s: %v", collection, id, err)
+		http.Error(w, "Failed to start linking", http.StatusInternalServerError)
+		return
+	}
+	oauthstate.SetLinkCookie(w, nonce, req.ExpiresAt)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"url": fmt.Sprintf("/internal/%sauth/oauth?link=%s", body.Provider, nonce),
+	})
+}
+
+// UnlinkIdentityHandler handles DELETE /api/account/identities/{identity_id}.
+func (res *Resolver) UnlinkIdentityHandler(w http.ResponseWriter, r *http.Request) {
+	collection, id, ok := accountFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	key := mux.Vars(r)["identity_id"]
+	if key == "" {
+		http.Error(w, "Identity ID is required", http.StatusBadRequest)
+		return
+	}
+
+	err := res.Unlink(r.Context(), collection, id, key)
+	if errors.Is(err, ErrIdentityNotFound) {
+		http.Error(w, "Identity not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, ErrLastIdentity) {
+		http.Error(w, "Cannot unlink the only login of an account", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("Error unlinking %s from %s/%s: %v", key, collection, id, err)
+		http.Error(w, "Failed to unlink identity", http.StatusInternalServerError)
+		return
+	}
+	res.events.RecordRequest(r, securitylog.Event{
+		Type:   securitylog.IdentityUnlink,
+		UserID: id,
+		Detail: key,
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,162 @@
+// backend/internal/identity/links_test.go
+
+package identity
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/middleware"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/oauthstate"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/session"
+)
+
+const testSecret = "test-secret"
+
+// startLink signs parent-1 in and asks to link a Microsoft login, returning
+// the link cookie and the nonce in the returned URL.
+func startLink(t *testing.T, res *Resolver) (*http.Cookie, string) {
+	t.Helper()
+	access, _, err := res.sessions.Start(context.Background(), session.User{UserID: "parent-1", Email: "pat@example.com", Role: middleware.RoleParent}, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/account/identities/link", strings.NewReader(`{"provider": "microsoft"}`))
+	req.Header.Set("Authorization", "Bearer "+access)
+	rec := httptest.NewRecorder()
+	middleware.AuthMiddleware(testSecret, res.sessions, nil)(http.HandlerFunc(res.LinkIdentityHandler)).ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("link status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+
+	var body struct {
+		URL string `json:"url"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(body.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := u.Query().Get("link")
+
+	var cookie *http.Cookie
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == oauthstate.LinkCookie {
+			cookie = c
+		}
+	}
+	if cookie == nil || cookie.Value != nonce || !cookie.HttpOnly || !cookie.Secure {
+		t.Fatalf("link cookie = %+v, want an HttpOnly, Secure cookie holding %q", cookie, nonce)
+	}
+	return cookie, nonce
+}
+
+func TestCompleteLink(t *testing.T) {
+	msProfile := Profile{Provider: "microsoft", Subject: "ms-pat", Email: "pat@outlook.com", EmailVerified: true}
+
+	tests := []struct {
+		name string
+		// prepare runs after the link was started, before the callback.
+		prepare    func(t *testing.T, res *Resolver)
+		sendCookie bool
+		profile    Profile
+		want       int
+		wantLinked bool
+	}{
+		{name: "same browser and session", sendCookie: true, profile: msProfile, want: http.StatusSeeOther, wantLinked: true},
+		{name: "another browser", sendCookie: false, profile: msProfile, want: http.StatusForbidden},
+		{name: "other provider", sendCookie: true, profile: Profile{Provider: "google", Subject: "g-pat"}, want: http.StatusBadRequest},
+		{
+			name: "signed out since",
+			prepare: func(t *testing.T, res *Resolver) {
+				if _, err := res.sessions.RevokeUser(context.Background(), "parent-1", "logout"); err != nil {
+					t.Fatal(err)
+				}
+			},
+			sendCookie: true,
+			profile:    msProfile,
+			want:       http.StatusUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, store, _ := newTestResolver(t)
+			res.sessions = session.NewManager(testSecret, session.NewMemoryStore())
+			cookie, nonce := startLink(t, res)
+			if tt.prepare != nil {
+				tt.prepare(t, res)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/internal/microsoftauth/callback", nil)
+			if tt.sendCookie {
+				req.AddCookie(cookie)
+			}
+			rec := httptest.NewRecorder()
+			res.Complete(rec, req, &oauthstate.Flow{Provider: tt.profile.Provider, Link: nonce}, tt.profile)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+
+			collection, id, found, err := store.FindLinkedIdentity(context.Background(), IdentityKey(tt.profile.Provider, tt.profile.Subject))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if found != tt.wantLinked {
+				t.Errorf("linked = %v (%s/%s), want %v", found, collection, id, tt.wantLinked)
+			}
+			if found && (collection != ParentsCollection || id != "parent-1") {
+				t.Errorf("linked to %s/%s, want %s/parent-1", collection, id, ParentsCollection)
+			}
+		})
+	}
+}
+
+func TestLinkSessionActive(t *testing.T) {
+	ctx := context.Background()
+	res, _, _ := newTestResolver(t)
+	sessions := session.NewMemoryStore()
+	res.sessions = session.NewManager(testSecret, sessions)
+
+	own := &session.Session{UserID: "parent-1", Role: middleware.RoleParent}
+	other := &session.Session{UserID: "parent-2", Role: middleware.RoleParent}
+	impersonated := &session.Session{UserID: "parent-1", Role: middleware.RoleParent, ImpersonatorID: "admin-1"}
+	for _, s := range []*session.Session{own, other, impersonated} {
+		s.ExpiresAt = time.Now().Add(time.Hour)
+		if err := sessions.Create(ctx, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		req  LinkRequest
+		want bool
+	}{
+		{"own session", LinkRequest{Collection: ParentsCollection, AccountID: "parent-1", SessionID: own.ID}, true},
+		{"another user's session", LinkRequest{Collection: ParentsCollection, AccountID: "parent-1", SessionID: other.ID}, false},
+		{"impersonation session", LinkRequest{Collection: ParentsCollection, AccountID: "parent-1", SessionID: impersonated.ID}, false},
+		{"other collection", LinkRequest{Collection: TutorsCollection, AccountID: "parent-1", SessionID: own.ID}, false},
+		{"no session", LinkRequest{Collection: ParentsCollection, AccountID: "parent-1"}, false},
+		{"unknown session", LinkRequest{Collection: ParentsCollection, AccountID: "parent-1", SessionID: "missing"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := res.linkSessionActive(ctx, &tt.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("linkSessionActive = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
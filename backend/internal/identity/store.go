@@ -0,0 +1,359 @@
+// backend/internal/identity/store.go
+
+package identity
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Store is the storage the Resolver needs. Documents are plain field maps so
+// that fields written by other parts of the app are left alone.
+type Store interface {
+	// FindStudentByEmail returns the ID of the student whose
+	// personal.student_email is email.
+	FindStudentByEmail(ctx context.Context, email string) (id string, found bool, err error)
+	Get(ctx context.Context, collection, id string) (data map[string]interface{}, found bool, err error)
+	Create(ctx context.Context, collection, id string, data map[string]interface{}) error
+	// Update sets top-level fields of an existing document.
+	Update(ctx context.Context, collection, id string, fields map[string]interface{}) error
+	// FindByEmail returns the IDs of the documents in collection whose email
+	// field is email, oldest first.
+	FindByEmail(ctx context.Context, collection, email string) ([]string, error)
+	// NewID returns an unused document ID for collection.
+	NewID(collection string) string
+
+	// FindLinkedIdentity returns the account the identity key is linked to.
+	FindLinkedIdentity(ctx context.Context, key string) (collection, id string, found bool, err error)
+	ListLinkedIdentities(ctx context.Context, collection, id string) ([]LinkedIdentity, error)
+	AddLinkedIdentity(ctx context.Context, collection, id string, li LinkedIdentity) error
+	RemoveLinkedIdentity(ctx context.Context, collection, id, key string) error
+
+	CreateLinkRequest(ctx context.Context, nonce string, req LinkRequest) error
+	// TakeLinkRequest returns and deletes a link request, so each can be used once.
+	TakeLinkRequest(ctx context.Context, nonce string) (*LinkRequest, bool, error)
+}
+
+// linkRequestsCollection holds link requests between the link endpoint and
+// the provider callback.
+const linkRequestsCollection = "identity_links"
+
+type firestoreStore struct {
+	client *firestore.Client
+}
+
+// NewFirestoreStore returns a Store backed by Firestore.
+func NewFirestoreStore(client *firestore.Client) Store {
+	return &firestoreStore{client: client}
+}
+
+func (s *firestoreStore) FindStudentByEmail(ctx context.Context, email string) (string, bool, error) {
+	docs, err := s.client.Collection(StudentsCollection).Where("personal.student_email", "==", email).Limit(1).Documents(ctx).GetAll()
+	if err != nil {
+		return "", false, err
+	}
+	if len(docs) == 0 {
+		return "", false, nil
+	}
+	return docs[0].Ref.ID, true, nil
+}
+
+func (s *firestoreStore) Get(ctx context.Context, collection, id string) (map[string]interface{}, bool, error) {
+	snap, err := s.client.Collection(collection).Doc(id).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return snap.Data(), true, nil
+}
+
+func (s *firestoreStore) Create(ctx context.Context, collection, id string, data map[string]interface{}) error {
+	_, err := s.client.Collection(collection).Doc(id).Create(ctx, data)
+	return err
+}
+
+func (s *firestoreStore) Update(ctx context.Context, collection, id string, fields map[string]interface{}) error {
+	updates := make([]firestore.Update, 0, len(fields))
+	for path, value := range fields {
+		updates = append(updates, firestore.Update{FieldPath: firestore.FieldPath{path}, Value: value})
+	}
+	_, err := s.client.Collection(collection).Doc(id).Update(ctx, updates)
+	return err
+}
+
+func (s *firestoreStore) FindByEmail(ctx context.Context, collection, email string) ([]string, error) {
+	docs, err := s.client.Collection(collection).Where("email", "==", email).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(docs, func(i, j int) bool { return docs[i].CreateTime.Before(docs[j].CreateTime) })
+	ids := make([]string, len(docs))
+	for i, doc := range docs {
+		ids[i] = doc.Ref.ID
+	}
+	return ids, nil
+}
+
+func (s *firestoreStore) NewID(collection string) string {
+	return s.client.Collection(collection).NewDoc().ID
+}
+
+func (s *firestoreStore) linked(collection, id string) *firestore.CollectionRef {
+	return s.client.Collection(collection).Doc(id).Collection(LinkedIdentitiesCollection)
+}
+
+// FindLinkedIdentity uses a collection group query, which needs a single-field
+// index on "key" enabled for the linked_identities collection group.
+func (s *firestoreStore) FindLinkedIdentity(ctx context.Context, key string) (string, string, bool, error) {
+	docs, err := s.client.CollectionGroup(LinkedIdentitiesCollection).Where("key", "==", key).Limit(1).Documents(ctx).GetAll()
+	if err != nil {
+		return "", "", false, err
+	}
+	if len(docs) == 0 {
+		return "", "", false, nil
+	}
+	account := docs[0].Ref.Parent.Parent
+	return account.Parent.ID, account.ID, true, nil
+}
+
+func (s *firestoreStore) ListLinkedIdentities(ctx context.Context, collection, id string) ([]LinkedIdentity, error) {
+	docs, err := s.linked(collection, id).OrderBy("linked_at", firestore.Asc).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]LinkedIdentity, 0, len(docs))
+	for _, doc := range docs {
+		var li LinkedIdentity
+		if err := doc.DataTo(&li); err != nil {
+			return nil, err
+		}
+		out = append(out, li)
+	}
+	return out, nil
+}
+
+func (s *firestoreStore) AddLinkedIdentity(ctx context.Context, collection, id string, li LinkedIdentity) error {
+	_, err := s.linked(collection, id).Doc(li.Key).Set(ctx, li)
+	return err
+}
+
+func (s *firestoreStore) RemoveLinkedIdentity(ctx context.Context, collection, id, key string) error {
+	_, err := s.linked(collection, id).Doc(key).Delete(ctx)
+	return err
+}
+
+func (s *firestoreStore) CreateLinkRequest(ctx context.Context, nonce string, req LinkRequest) error {
+	_, err := s.client.Collection(linkRequestsCollection).Doc(nonce).Create(ctx, req)
+	return err
+}
+
+func (s *firestoreStore) TakeLinkRequest(ctx context.Context, nonce string) (*LinkRequest, bool, error) {
+	ref := s.client.Collection(linkRequestsCollection).Doc(nonce)
+	var req *LinkRequest
+	err := s.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		req = nil
+		snap, err := tx.Get(ref)
+		if status.Code(err) == codes.NotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var got LinkRequest
+		if err := snap.DataTo(&got); err != nil {
+			return err
+		}
+		req = &got
+		return tx.Delete(ref)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return req, req != nil, nil
+}
+
+// MemoryStore is an in-memory Store for tests and local runs.
+type MemoryStore struct {
+	mu   sync.Mutex
+	docs map[string]map[string]map[string]interface{}
+	// links maps "collection/id" to the account's identities by key.
+	links    map[string]map[string]LinkedIdentity
+	requests map[string]LinkRequest
+	created  map[string]time.Time
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		docs:     map[string]map[string]map[string]interface{}{},
+		links:    map[string]map[string]LinkedIdentity{},
+		requests: map[string]LinkRequest{},
+		created:  map[string]time.Time{},
+	}
+}
+
+// Put stores a document as is, replacing any existing one.
+func (m *MemoryStore) Put(collection, id string, data map[string]interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.docs[collection] == nil {
+		m.docs[collection] = map[string]map[string]interface{}{}
+	}
+	m.docs[collection][id] = copyFields(data)
+	if _, ok := m.created[collection+"/"+id]; !ok {
+		m.created[collection+"/"+id] = time.Now()
+	}
+}
+
+func (m *MemoryStore) FindStudentByEmail(ctx context.Context, email string) (string, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id, data := range m.docs[StudentsCollection] {
+		personal, _ := data["personal"].(map[string]interface{})
+		if personal != nil && personal["student_email"] == email {
+			return id, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+func (m *MemoryStore) Get(ctx context.Context, collection, id string) (map[string]interface{}, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok := m.docs[collection][id]
+	if !ok {
+		return nil, false, nil
+	}
+	return copyFields(data), true, nil
+}
+
+func (m *MemoryStore) Create(ctx context.Context, collection, id string, data map[string]interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.docs[collection][id]; ok {
+		return status.Errorf(codes.AlreadyExists, "%s/%s already exists", collection, id)
+	}
+	if m.docs[collection] == nil {
+		m.docs[collection] = map[string]map[string]interface{}{}
+	}
+	m.docs[collection][id] = copyFields(data)
+	m.created[collection+"/"+id] = time.Now()
+	return nil
+}
+
+func (m *MemoryStore) Update(ctx context.Context, collection, id string, fields map[string]interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok := m.docs[collection][id]
+	if !ok {
+		return status.Errorf(codes.NotFound, "%s/%s not found", collection, id)
+	}
+	for k, v := range fields {
+		data[k] = v
+	}
+	return nil
+}
+
+func (m *MemoryStore) FindByEmail(ctx context.Context, collection, email string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var ids []string
+	for id, data := range m.docs[collection] {
+		if data["email"] == email {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return m.created[collection+"/"+ids[i]].Before(m.created[collection+"/"+ids[j]])
+	})
+	return ids, nil
+}
+
+func (m *MemoryStore) NewID(collection string) string {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+func (m *MemoryStore) FindLinkedIdentity(ctx context.Context, key string) (string, string, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for path, links := range m.links {
+		if _, ok := links[key]; ok {
+			collection, id, _ := strings.Cut(path, "/")
+			return collection, id, true, nil
+		}
+	}
+	return "", "", false, nil
+}
+
+func (m *MemoryStore) ListLinkedIdentities(ctx context.Context, collection, id string) ([]LinkedIdentity, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := []LinkedIdentity{}
+	for _, li := range m.links[collection+"/"+id] {
+		out = append(out, li)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].LinkedAt.Before(out[j].LinkedAt) })
+	return out, nil
+}
+
+func (m *MemoryStore) AddLinkedIdentity(ctx context.Context, collection, id string, li LinkedIdentity) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	path := collection + "/" + id
+	if m.links[path] == nil {
+		m.links[path] = map[string]LinkedIdentity{}
+	}
+	m.links[path][li.Key] = li
+	return nil
+}
+
+func (m *MemoryStore) RemoveLinkedIdentity(ctx context.Context, collection, id, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.links[collection+"/"+id], key)
+	return nil
+}
+
+func (m *MemoryStore) CreateLinkRequest(ctx context.Context, nonce string, req LinkRequest) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.requests[nonce]; ok {
+		return status.Errorf(codes.AlreadyExists, "link request %s already exists", nonce)
+	}
+	m.requests[nonce] = req
+	return nil
+}
+
+func (m *MemoryStore) TakeLinkRequest(ctx context.Context, nonce string) (*LinkRequest, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	req, ok := m.requests[nonce]
+	if !ok {
+		return nil, false, nil
+	}
+	delete(m.requests, nonce)
+	return &req, true, nil
+}
+
+func copyFields(data map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(data))
+	for k, v := range data {
+		out[k] = v
+	}
+	return out
+}
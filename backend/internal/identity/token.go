@@ -0,0 +1,99 @@
+// backend/internal/identity/token.go
+
+package identity
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/mfa"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/securitylog"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/session"
+)
+
+// FrontendURL is where the React app is served.
+const FrontendURL = "https://lee-tutoring-webapp.web.app"
+
+// CompleteLogin starts a session for acct and redirects to the React app's
+// auth-redirect route with the access token in the URL fragment. A returnTo
+// path is passed on as ?next= for the app to go to instead of the user's
+// dashboard.
+//
+// A staff member who has an authenticator, or whose role requires one, is
+// sent to the app's mfa route instead, with a challenge token in the
+// fragment and ?enroll=1 if they have to set one up first; the session
+// starts once they enter their code.
+func (res *Resolver) CompleteLogin(w http.ResponseWriter, r *http.Request, acct *Account, returnTo string) {
+	if acct.Collection == TutorsCollection && res.mfa != nil {
+		token, challenge, err := res.mfa.StepUp(r.Context(), mfa.Login{
+			UserID:    acct.UserID,
+			Email:     acct.Email,
+			Role:      acct.Role,
+			StaffRole: acct.StaffRole,
+			Provider:  acct.Provider,
+			ReturnTo:  returnTo,
+		})
+		if err != nil {
+			log.Printf("Failed to check second factor for %s: %v", acct.UserID, err)
+			http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+			return
+		}
+		if challenge != nil {
+			log.Printf("Second factor required for %s (%s)", acct.UserID, acct.Email)
+			redirectURL := FrontendURL + "/mfa"
+			if challenge.Enroll {
+				redirectURL += "?enroll=1"
+			}
+			http.Redirect(w, r, redirectURL+"#"+token, http.StatusSeeOther)
+			return
+		}
+	}
+
+	accessToken, err := res.sessions.Login(w, r, session.User{UserID: acct.UserID, Email: acct.Email, Role: acct.Role})
+	if err != nil {
+		log.Printf("Failed to start session: %v", err)
+		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("User authenticated: %s (%s), role: %s", acct.UserID, acct.Email, acct.Role)
+	res.events.RecordRequest(r, securitylog.Event{
+		Type:     securitylog.Login,
+		UserID:   acct.UserID,
+		Email:    acct.Email,
+		Role:     acct.Role,
+		Provider: acct.Provider,
+	})
+	redirectURL := FrontendURL + "/auth-redirect"
+	if returnTo != "" {
+		redirectURL += "?next=" + url.QueryEscape(returnTo)
+	}
+	redirectURL += "#" + accessToken
+	http.Redirect(w, r, redirectURL, http.StatusSeeOther)
+}
+
+// ResolveError writes the response for an error p got from Resolve, and
+// records the failed sign-in.
+func (res *Resolver) ResolveError(w http.ResponseWriter, r *http.Request, p Profile, err error) {
+	log.Printf("Failed to resolve identity: %v", err)
+	res.events.RecordRequest(r, securitylog.Event{
+		Type:     securitylog.LoginFailed,
+		Email:    p.Email,
+		Provider: p.Provider,
+		Detail:   err.Error(),
+	})
+	switch {
+	case errors.Is(err, ErrNoSubject):
+		http.Error(w, "Invalid user ID from provider", http.StatusBadRequest)
+	case errors.Is(err, ErrNoEmail):
+		http.Error(w, "Email not provided and user does not exist", http.StatusBadRequest)
+	case errors.Is(err, ErrNotStaff):
+		http.Error(w, "This staff account is not active", http.StatusForbidden)
+	case errors.Is(err, ErrStaffEmailUnverified):
+		http.Error(w, "Please sign in to your staff account with Google", http.StatusForbidden)
+	default:
+		http.Error(w, "Failed to save user in Firestore", http.StatusInternalServerError)
+	}
+}
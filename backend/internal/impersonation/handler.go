@@ -0,0 +1,171 @@
+// backend/internal/impersonation/handler.go
+
+package impersonation
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/audit"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/identity"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/middleware"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/securitylog"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/session"
+	"github.com/gorilla/mux"
+)
+
+// An admin can view the app as a parent or student to see what their
+// dashboard shows. The admin gets a read-only session as that user, which
+// ends after session.ImpersonationTTL or when stopped; the middleware flags
+// every response to it and blocks writes. Starting and stopping are written
+// to the audit log and the security log.
+
+// App holds the dependencies for the impersonation handlers.
+type App struct {
+	Sessions *session.Manager
+	Accounts identity.Store
+	Audit    audit.Log
+	// Security records impersonation alongside the user's own sign-ins.
+	Security *securitylog.Recorder
+}
+
+type startRequest struct {
+	UserID string `json:"user_id"`
+	Role   string `json:"role"`
+	Reason string `json:"reason"`
+}
+
+type startResponse struct {
+	Token     string    `json:"token"`
+	SessionID string    `json:"session_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+	// URL signs in to the app with the token. Open it in a private window so
+	// the admin's own sign-in is left alone.
+	URL string `json:"url"`
+}
+
+// StartHandler handles POST /api/admin/impersonate with
+// {"user_id": "...", "role": "parent"|"student", "reason": "..."}.
+func (a *App) StartHandler(w http.ResponseWriter, r *http.Request) {
+	var req startRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.UserID == "" || req.Reason == "" {
+		http.Error(w, "User ID and reason are required", http.StatusBadRequest)
+		return
+	}
+	if req.Role != middleware.RoleParent && req.Role != middleware.RoleStudent {
+		http.Error(w, "Only parents and students can be viewed as", http.StatusBadRequest)
+		return
+	}
+	ctx := r.Context()
+	adminID, _ := middleware.ExtractUserIDFromContext(ctx)
+
+	collection := identity.CollectionForRole(req.Role)
+	data, found, err := a.Accounts.Get(ctx, collection, req.UserID)
+	if err != nil {
+		log.Printf("Error loading %s/%s to impersonate: %v", collection, req.UserID, err)
+		http.Error(w, "Failed to load user", http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	email, _ := data["email"].(string)
+	if personal, ok := data["personal"].(map[string]interface{}); ok && email == "" {
+		email, _ = personal["student_email"].(string)
+	}
+
+	target := session.User{UserID: req.UserID, Email: email, Role: req.Role}
+	token, sess, err := a.Sessions.Impersonate(ctx, adminID, target, r.UserAgent())
+	if err != nil {
+		log.Printf("Error starting impersonation of %s by %s: %v", req.UserID, adminID, err)
+		http.Error(w, "Failed to start impersonation", http.StatusInternalServerError)
+		return
+	}
+
+	// No audit entry, no session.
+	if err := a.Audit.Record(ctx, audit.Event{
+		Action:     audit.ImpersonationStart,
+		ActorID:    adminID,
+		TargetID:   req.UserID,
+		TargetRole: req.Role,
+		SessionID:  sess.ID,
+		Reason:     req.Reason,
+		ExpiresAt:  &sess.ExpiresAt,
+	}); err != nil {
+		log.Printf("Error recording impersonation of %s by %s: %v", req.UserID, adminID, err)
+		if _, err := a.Sessions.StopImpersonation(ctx, sess.ID, "audit log unavailable"); err != nil {
+			log.Printf("Error ending unrecorded impersonation session %s: %v", sess.ID, err)
+		}
+		http.Error(w, "Failed to start impersonation", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Admin %s started impersonating %s %s (session %s): %s", adminID, req.Role, req.UserID, sess.ID, req.Reason)
+	a.Security.RecordRequest(r, securitylog.Event{
+		Type:    securitylog.ImpersonationStart,
+		UserID:  req.UserID,
+		Email:   email,
+		Role:    req.Role,
+		ActorID: adminID,
+		Detail:  req.Reason,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(startResponse{
+		Token:     token,
+		SessionID: sess.ID,
+		ExpiresAt: sess.ExpiresAt,
+		URL:       identity.FrontendURL + "/auth-redirect#" + token,
+	})
+}
+
+// StopHandler handles POST /api/admin/impersonate/{session_id}/stop.
+func (a *App) StopHandler(w http.ResponseWriter, r *http.Request) {
+	sid := mux.Vars(r)["session_id"]
+	if sid == "" {
+		http.Error(w, "Session ID is required", http.StatusBadRequest)
+		return
+	}
+	ctx := r.Context()
+	adminID, _ := middleware.ExtractUserIDFromContext(ctx)
+
+	sess, err := a.Sessions.StopImpersonation(ctx, sid, "stopped by admin "+adminID)
+	if errors.Is(err, session.ErrSessionNotFound) || errors.Is(err, session.ErrNotImpersonation) {
+		http.Error(w, "Impersonation session not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error stopping impersonation session %s: %v", sid, err)
+		http.Error(w, "Failed to stop impersonation", http.StatusInternalServerError)
+		return
+	}
+
+	if err := a.Audit.Record(ctx, audit.Event{
+		Action:     audit.ImpersonationStop,
+		ActorID:    adminID,
+		TargetID:   sess.UserID,
+		TargetRole: sess.Role,
+		SessionID:  sid,
+	}); err != nil {
+		// The session has ended either way; the start entry stands.
+		log.Printf("Error recording end of impersonation session %s: %v", sid, err)
+	}
+	log.Printf("Admin %s stopped impersonation session %s of %s", adminID, sid, sess.UserID)
+	a.Security.RecordRequest(r, securitylog.Event{
+		Type:    securitylog.ImpersonationStop,
+		UserID:  sess.UserID,
+		Role:    sess.Role,
+		ActorID: adminID,
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,350 @@
+// backend/internal/intuit/inbox.go
+
+package intuit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/gorilla/mux"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Webhook entity changes are written to the "intuit_webhook_inbox" collection
+// and processed by RunInboxWorker. Where CPU is only allocated during
+// requests, as on Cloud Run by default, the worker stalls between requests,
+// so Cloud Scheduler also calls DrainInboxHandler every few minutes. An
+// event is retried with exponential
+// backoff until it succeeds or runs out of attempts, at which point it is
+// dead-lettered until an admin replays it. Finished events carry an
+// expires_at that a Firestore TTL policy can use to clean them up.
+
+// Inbox event states.
+const (
+	InboxPending = "pending"
+	InboxDone    = "done"
+	InboxDead    = "dead"
+)
+
+const (
+	inboxMaxAttempts = 8
+	inboxBaseBackoff = 30 * time.Second
+	inboxMaxBackoff  = time.Hour
+	// inboxLease is how long a claimed event is hidden from other workers.
+	// An event whose worker dies comes back once the lease runs out.
+	inboxLease        = 5 * time.Minute
+	inboxPollInterval = 30 * time.Second
+	inboxBatchSize    = 20
+	inboxRetention    = 30 * 24 * time.Hour
+)
+
+// ErrInboxEventNotFound is returned when replaying an event that does not exist.
+var ErrInboxEventNotFound = errors.New("inbox event not found")
+
+// InboxEvent is one entity change from a webhook notification.
+type InboxEvent struct {
+	ID              string     `firestore:"-" json:"id"`
+	RealmID         string     `firestore:"realm_id" json:"realm_id"`
+	Entity          string     `firestore:"entity" json:"entity"`
+	EntityID        string     `firestore:"entity_id" json:"entity_id"`
+	Operation       string     `firestore:"operation" json:"operation"`
+	LastUpdatedTime string     `firestore:"last_updated_time" json:"last_updated_time"`
+	Status          string     `firestore:"status" json:"status"`
+	Attempts        int        `firestore:"attempts" json:"attempts"`
+	NextAttemptAt   time.Time  `firestore:"next_attempt_at" json:"next_attempt_at"`
+	LastError       string     `firestore:"last_error,omitempty" json:"last_error,omitempty"`
+	ReceivedAt      time.Time  `firestore:"received_at" json:"received_at"`
+	UpdatedAt       time.Time  `firestore:"updated_at" json:"updated_at"`
+	ExpiresAt       *time.Time `firestore:"expires_at,omitempty" json:"-"`
+}
+
+// inboxKey identifies a change: the same entity changed at the same time is
+// the same event, however many notifications it arrives in.
+func inboxKey(entity, entityID, lastUpdatedTime string) string {
+	key := fmt.Sprintf("%s_%s_%s", strings.ToLower(entity), entityID, lastUpdatedTime)
+	return strings.ReplaceAll(key, "/", "_")
+}
+
+func (s *OAuthService) inbox() *firestore.CollectionRef {
+	return s.firestore.Collection("intuit_webhook_inbox")
+}
+
+// enqueue stores ev as pending. It returns false if the event was already in
+// the inbox, whatever its state.
+func (s *OAuthService) enqueue(ctx context.Context, ev *InboxEvent) (bool, error) {
+	now := time.Now()
+	ev.Status = InboxPending
+	ev.NextAttemptAt = now
+	ev.ReceivedAt = now
+	ev.UpdatedAt = now
+
+	_, err := s.inbox().Doc(inboxKey(ev.Entity, ev.EntityID, ev.LastUpdatedTime)).Create(ctx, ev)
+	if status.Code(err) == codes.AlreadyExists {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// wakeInboxWorker makes the worker look at the inbox now rather than at its
+// next poll.
+func (s *OAuthService) wakeInboxWorker() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// RunInboxWorker processes due inbox events until ctx is done.
+func (s *OAuthService) RunInboxWorker(ctx context.Context) {
+	log.Println("[Inbox] Worker started")
+	ticker := time.NewTicker(inboxPollInterval)
+	defer ticker.Stop()
+
+	for {
+		s.drainInbox(ctx)
+		select {
+		case <-ctx.Done():
+			log.Println("[Inbox] Worker stopped")
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+	}
+}
+
+// drainInbox processes due events until none are left and returns how many
+// it found.
+func (s *OAuthService) drainInbox(ctx context.Context) int {
+	total := 0
+	for {
+		n := s.processDue(ctx)
+		total += n
+		// A full batch means there may be more waiting.
+		if n < inboxBatchSize {
+			return total
+		}
+	}
+}
+
+// processDue processes a batch of pending events whose next attempt is due
+// and returns how many it found.
+func (s *OAuthService) processDue(ctx context.Context) int {
+	snaps, err := s.inbox().
+		Where("status", "==", InboxPending).
+		Where("next_attempt_at", "<=", time.Now()).
+		OrderBy("next_attempt_at", firestore.Asc).
+		Limit(inboxBatchSize).
+		Documents(ctx).GetAll()
+	if err != nil {
+		log.Printf("[Inbox] Failed to query due events: %v", err)
+		return 0
+	}
+
+	for _, snap := range snaps {
+		ev, ok, err := s.claim(ctx, snap.Ref)
+		if err != nil {
+			log.Printf("[Inbox] Failed to claim event %s: %v", snap.Ref.ID, err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		s.process(ctx, snap.Ref, ev)
+	}
+	return len(snaps)
+}
+
+// claim takes a lease on a due event and counts the attempt. It returns false
+// if another worker got there first.
+func (s *OAuthService) claim(ctx context.Context, ref *firestore.DocumentRef) (*InboxEvent, bool, error) {
+	var ev InboxEvent
+	claimed := false
+	err := s.firestore.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		claimed = false
+		snap, err := tx.Get(ref)
+		if err != nil {
+			return err
+		}
+		if err := snap.DataTo(&ev); err != nil {
+			return err
+		}
+		now := time.Now()
+		if ev.Status != InboxPending || ev.NextAttemptAt.After(now) {
+			return nil
+		}
+		ev.Attempts++
+		ev.NextAttemptAt = now.Add(inboxLease)
+		ev.UpdatedAt = now
+		claimed = true
+		return tx.Set(ref, &ev)
+	})
+	ev.ID = ref.ID
+	return &ev, claimed, err
+}
+
+// process runs a claimed event and records the outcome.
+func (s *OAuthService) process(ctx context.Context, ref *firestore.DocumentRef, ev *InboxEvent) {
+	customerRef, err := s.processEntity(ctx, ev)
+	if err == nil && customerRef != "" {
+		if rerr := s.recalcTotalBalance(ctx, customerRef); rerr != nil {
+			err = fmt.Errorf("recalc total_balance for customerRef=%s: %w", customerRef, rerr)
+		}
+	}
+
+	now := time.Now()
+	ev.UpdatedAt = now
+	switch {
+	case err == nil:
+		ev.Status = InboxDone
+		ev.LastError = ""
+		expires := now.Add(inboxRetention)
+		ev.ExpiresAt = &expires
+		log.Printf("[Inbox] Processed %s %s (%s) after %d attempt(s)", ev.Entity, ev.EntityID, ev.Operation, ev.Attempts)
+	case ev.Attempts >= inboxMaxAttempts:
+		ev.Status = InboxDead
+		ev.LastError = err.Error()
+		log.Printf("[Inbox] Dead-lettered %s %s after %d attempts: %v", ev.Entity, ev.EntityID, ev.Attempts, err)
+	default:
+		ev.Status = InboxPending
+		ev.LastError = err.Error()
+		ev.NextAttemptAt = now.Add(inboxBackoff(ev.Attempts))
+		log.Printf("[Inbox] Attempt %d of %s %s failed, retrying at %s: %v",
+			ev.Attempts, ev.Entity, ev.EntityID, ev.NextAttemptAt.Format(time.RFC3339), err)
+	}
+
+	if _, werr := ref.Set(ctx, ev); werr != nil {
+		// The lease runs out and the event is tried again.
+		log.Printf("[Inbox] Failed to record outcome of event %s: %v", ref.ID, werr)
+	}
+}
+
+// inboxBackoff doubles the wait after every failed attempt, up to an hour.
+func inboxBackoff(attempts int) time.Duration {
+	d := inboxBaseBackoff
+	for i := 1; i < attempts && d < inboxMaxBackoff; i++ {
+		d *= 2
+	}
+	if d > inboxMaxBackoff {
+		d = inboxMaxBackoff
+	}
+	return d
+}
+
+// ReplayInboxEvent puts a dead-lettered (or finished) event back in the queue
+// with a fresh set of attempts.
+func (s *OAuthService) ReplayInboxEvent(ctx context.Context, eventID string) (*InboxEvent, error) {
+	ref := s.inbox().Doc(eventID)
+	var ev InboxEvent
+	err := s.firestore.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		snap, err := tx.Get(ref)
+		if status.Code(err) == codes.NotFound {
+			return ErrInboxEventNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if err := snap.DataTo(&ev); err != nil {
+			return err
+		}
+		now := time.Now()
+		ev.Status = InboxPending
+		ev.Attempts = 0
+		ev.NextAttemptAt = now
+		ev.UpdatedAt = now
+		ev.ExpiresAt = nil
+		return tx.Set(ref, &ev)
+	})
+	if err != nil {
+		return nil, err
+	}
+	ev.ID = eventID
+	s.wakeInboxWorker()
+	return &ev, nil
+}
+
+// InboxHandler handles GET /api/admin/intuit/inbox. It lists the events in
+// the ?status= state, dead-lettered ones by default, newest first.
+func (s *OAuthService) InboxHandler(w http.ResponseWriter, r *http.Request) {
+	state := r.URL.Query().Get("status")
+	if state == "" {
+		state = InboxDead
+	}
+	if state != InboxPending && state != InboxDone && state != InboxDead {
+		http.Error(w, "Status must be pending, done or dead", http.StatusBadRequest)
+		return
+	}
+
+	iter := s.inbox().Where("status", "==", state).OrderBy("updated_at", firestore.Desc).Limit(100).Documents(r.Context())
+	defer iter.Stop()
+
+	events := []InboxEvent{}
+	for {
+		snap, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			log.Printf("Error listing %s inbox events: %v", state, err)
+			http.Error(w, "Failed to list inbox events", http.StatusInternalServerError)
+			return
+		}
+		var ev InboxEvent
+		if err := snap.DataTo(&ev); err != nil {
+			log.Printf("Skipping inbox event %s: %v", snap.Ref.ID, err)
+			continue
+		}
+		ev.ID = snap.Ref.ID
+		events = append(events, ev)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(events)
+}
+
+// DrainInboxHandler handles GET /internal/intuit/drain-inbox, which Cloud
+// Scheduler calls with an API key. It processes the due events within the
+// request, so they do not wait on a worker that has no CPU.
+func (s *OAuthService) DrainInboxHandler(w http.ResponseWriter, r *http.Request) {
+	found := s.drainInbox(r.Context())
+	if found > 0 {
+		log.Printf("[Inbox] Drain found %d due event(s)", found)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"found": found})
+}
+
+// ReplayInboxEventHandler handles POST /api/admin/intuit/inbox/{event_id}/replay.
+func (s *OAuthService) ReplayInboxEventHandler(w http.ResponseWriter, r *http.Request) {
+	eventID := mux.Vars(r)["event_id"]
+	if eventID == "" {
+		http.Error(w, "Event ID is required", http.StatusBadRequest)
+		return
+	}
+
+	ev, err := s.ReplayInboxEvent(r.Context(), eventID)
+	if errors.Is(err, ErrInboxEventNotFound) {
+		http.Error(w, "Inbox event not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error replaying inbox event %s: %v", eventID, err)
+		http.Error(w, "Failed to replay inbox event", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Replaying inbox event %s (%s %s)", eventID, ev.Entity, ev.EntityID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ev)
+}
@@ -9,6 +9,12 @@ import (
 	"time"
 
 	"cloud.google.com/go/firestore"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/config"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/households"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/ledger"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/oauthstate"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/qbo"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/tokencrypt"
 
 	"golang.org/x/oauth2"
 )
@@ -28,20 +34,51 @@ type TokenInfo struct {
 	RealmID      string    `firestore:"realmID,omitempty"`
 }
 
-// OAuthService holds the oauth2.Config and the Firestore client.
+// OAuthService holds the oauth2.Config, the QuickBooks API client, the
+// Firestore client, the hours ledger that invoices are recorded in, the
+// households invoices are billed to and the token webhooks are signed with. The stored QuickBooks tokens are encrypted
+// with tokens.
 type OAuthService struct {
-	config    *oauth2.Config
-	firestore *firestore.Client
+	config        *oauth2.Config
+	states        *oauthstate.Manager
+	tokens        *tokencrypt.Keyring
+	qbo           *qbo.Client
+	firestore     *firestore.Client
+	hours         *ledger.Ledger
+	households    *households.Manager
+	verifierToken string
+	// httpClient, if set, makes the QuickBooks API and OAuth token requests.
+	httpClient *http.Client
+	// wake nudges the inbox worker when a webhook queues new events.
+	wake chan struct{}
 }
 
-// NewOAuthService sets up the OAuth config from env vars and holds Firestore ref.
-func NewOAuthService(ctx context.Context, fsClient *firestore.Client) (*OAuthService, error) {
+// Option configures an OAuthService.
+type Option func(*OAuthService)
+
+// WithHTTPClient sends the QuickBooks API and OAuth token requests through c
+// instead of the default clients, e.g. to point them at a test server.
+func WithHTTPClient(c *http.Client) Option {
+	return func(s *OAuthService) {
+		s.httpClient = c
+	}
+}
+
+// NewOAuthService sets up the OAuth config from env vars, the QuickBooks
+// environment from cfg, and holds Firestore ref. states secures the connect flow
+// and tokens encrypts the QuickBooks tokens at rest. Invoices are linked to
+// families through households.
+func NewOAuthService(ctx context.Context, cfg *config.Config, fsClient *firestore.Client, hours *ledger.Ledger, households *households.Manager, states *oauthstate.Manager, tokens *tokencrypt.Keyring, opts ...Option) (*OAuthService, error) {
 	clientID := os.Getenv("INTUIT_CLIENT_ID")
 	clientSecret := os.Getenv("INTUIT_CLIENT_SECRET")
 	redirectURL := os.Getenv("INTUIT_REDIRECT_URL")
 	if clientID == "" || clientSecret == "" || redirectURL == "" {
 		return nil, fmt.Errorf("missing INTUIT_CLIENT_ID, INTUIT_CLIENT_SECRET, or INTUIT_REDIRECT_URL")
 	}
+	verifierToken := os.Getenv("INTUIT_WEBHOOK_VERIFIER_TOKEN")
+	if verifierToken == "" {
+		return nil, fmt.Errorf("missing INTUIT_WEBHOOK_VERIFIER_TOKEN")
+	}
 
 	conf := &oauth2.Config{
 		ClientID:     clientID,
@@ -53,16 +90,37 @@ func NewOAuthService(ctx context.Context, fsClient *firestore.Client) (*OAuthSer
 		},
 	}
 
-	return &OAuthService{
-		config:    conf,
-		firestore: fsClient,
-	}, nil
+	settings, err := qbo.SettingsFromConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &OAuthService{
+		config:        conf,
+		states:        states,
+		tokens:        tokens,
+		firestore:     fsClient,
+		hours:         hours,
+		households:    households,
+		verifierToken: verifierToken,
+		wake:          make(chan struct{}, 1),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.qbo = qbo.NewClient(settings, s.validToken, s.httpClient)
+	log.Printf("QuickBooks %s environment at %s", settings.Environment, settings.BaseURL)
+	return s, nil
 }
 
 // HandleAuthRedirect initiates the OAuth flow by redirecting the user to Intuit.
 func (s *OAuthService) HandleAuthRedirect(w http.ResponseWriter, r *http.Request) {
-	state := "someRandomState"
-	url := s.config.AuthCodeURL(state, oauth2.AccessTypeOffline)
+	state, stateOpts, err := s.states.Begin(w, r, "intuit", oauthstate.Options{})
+	if err != nil {
+		oauthstate.Error(w, err)
+		return
+	}
+	url := s.config.AuthCodeURL(state, append([]oauth2.AuthCodeOption{oauth2.AccessTypeOffline}, stateOpts...)...)
 	http.Redirect(w, r, url, http.StatusTemporaryRedirect)
 }
 
@@ -72,9 +130,9 @@ func (s *OAuthService) HandleCallback(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query()
 	code := query.Get("code")
 	realmID := query.Get("realmId")
-	state := query.Get("state")
-	if state != "someRandomState" {
-		http.Error(w, "State parameter does not match, or is missing", http.StatusBadRequest)
+	flow, err := s.states.Verify(w, r, "intuit", query.Get("state"))
+	if err != nil {
+		oauthstate.Error(w, err)
 		return
 	}
 	if code == "" || realmID == "" {
@@ -83,7 +141,7 @@ func (s *OAuthService) HandleCallback(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Exchange the code for access/refresh tokens
-	token, err := s.config.Exchange(context.Background(), code)
+	token, err := s.config.Exchange(s.oauthContext(context.Background()), code, flow.ExchangeOptions()...)
 	if err != nil {
 		log.Printf("Error exchanging code: %v\n", err)
 		http.Error(w, "Token exchange failed", http.StatusInternalServerError)
@@ -106,21 +164,33 @@ func (s *OAuthService) HandleCallback(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Redirect user to your chosen success page.
-	http.Redirect(w, r, "https://lee-tutoring-webapp.web.app/booking", http.StatusSeeOther)
+	returnTo := flow.ReturnTo
+	if returnTo == "" {
+		returnTo = "/booking"
+	}
+	http.Redirect(w, r, "https://lee-tutoring-webapp.web.app"+returnTo, http.StatusSeeOther)
 }
 
 // storeTokens: we store the data under a field "intuitoauth" in the
 // doc "intuit/globalTokens". That is effectively a "sub-document" approach.
+// The access and refresh tokens are encrypted before they are written.
 func (s *OAuthService) storeTokens(ctx context.Context, ti TokenInfo) error {
+	var err error
+	if ti.AccessToken, err = s.tokens.Encrypt(ti.AccessToken); err != nil {
+		return fmt.Errorf("failed to encrypt access token: %w", err)
+	}
+	if ti.RefreshToken, err = s.tokens.Encrypt(ti.RefreshToken); err != nil {
+		return fmt.Errorf("failed to encrypt refresh token: %w", err)
+	}
 	// We'll do a merge so we don't overwrite other fields if they exist.
-	_, err := s.firestore.Collection("intuit").Doc("globalTokens").
+	_, err = s.firestore.Collection("intuit").Doc("globalTokens").
 		Set(ctx, map[string]interface{}{
 			"intuitoauth": ti,
 		}, firestore.MergeAll)
 	return err
 }
 
-// retrieveTokens loads from sub-document "intuitoauth"
+// retrieveTokens loads from sub-document "intuitoauth" and decrypts the tokens.
 func (s *OAuthService) retrieveTokens(ctx context.Context) (*TokenInfo, error) {
 	docSnap, err := s.firestore.Collection("intuit").Doc("globalTokens").Get(ctx)
 	if err != nil {
@@ -132,7 +202,23 @@ func (s *OAuthService) retrieveTokens(ctx context.Context) (*TokenInfo, error) {
 	if err := docSnap.DataTo(&temp); err != nil {
 		return nil, err
 	}
-	return &temp.Intuitoauth, nil
+	ti := &temp.Intuitoauth
+	if ti.AccessToken, err = s.tokens.Decrypt(ti.AccessToken); err != nil {
+		return nil, fmt.Errorf("failed to decrypt access token: %w", err)
+	}
+	if ti.RefreshToken, err = s.tokens.Decrypt(ti.RefreshToken); err != nil {
+		return nil, fmt.Errorf("failed to decrypt refresh token: %w", err)
+	}
+	return ti, nil
+}
+
+// oauthContext makes the OAuth token requests made with ctx go through the
+// injected HTTP client, if there is one.
+func (s *OAuthService) oauthContext(ctx context.Context) context.Context {
+	if s.httpClient == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, oauth2.HTTPClient, s.httpClient)
 }
 
 // Token returns a fresh or valid token, refreshing if expired
@@ -147,7 +233,7 @@ func (s *OAuthService) Token(ctx context.Context) (*oauth2.Token, error) {
 		TokenType:    ti.TokenType,
 		Expiry:       ti.Expiry,
 	}
-	ts := s.config.TokenSource(ctx, tok)
+	ts := s.config.TokenSource(s.oauthContext(ctx), tok)
 	newTok, err := ts.Token()
 	if err != nil {
 		return nil, err
@@ -0,0 +1,58 @@
+// backend/internal/intuit/signature.go
+
+package intuit
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"time"
+)
+
+// signatureHeader carries the base64 HMAC-SHA256 of the webhook body, keyed
+// with the verifier token shown on the app's Webhooks page in the Intuit
+// developer portal.
+const signatureHeader = "intuit-signature"
+
+// webhookMaxAge is how old the newest change in a notification may be.
+// Intuit retries failed deliveries for a few hours; anything older is a replay.
+const webhookMaxAge = 24 * time.Hour
+
+// verifySignature reports whether signature is the HMAC of body under token.
+func verifySignature(body []byte, signature, token string) bool {
+	if signature == "" || token == "" {
+		return false
+	}
+	got, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(token))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+// lastUpdatedLayouts are the formats of an entity's lastUpdated: Intuit
+// sends a zone offset without a colon, but RFC 3339 is accepted too.
+var lastUpdatedLayouts = []string{"2006-01-02T15:04:05-0700", "2006-01-02T15:04:05.000-0700", time.RFC3339}
+
+// newestChange returns the latest lastUpdated of the notification's
+// entities, or the zero time if none can be parsed.
+func (e *WebhookEvent) newestChange() time.Time {
+	var newest time.Time
+	for _, note := range e.EventNotifications {
+		for _, entity := range note.DataChangeEvent.Entities {
+			for _, layout := range lastUpdatedLayouts {
+				t, err := time.Parse(layout, entity.LastUpdatedTime)
+				if err != nil {
+					continue
+				}
+				if t.After(newest) {
+					newest = t
+				}
+				break
+			}
+		}
+	}
+	return newest
+}
@@ -0,0 +1,112 @@
+// backend/internal/intuit/signature_test.go
+
+package intuit
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// A notification as Intuit delivers it, with the intuit-signature it came
+// with under the sandbox app's verifier token.
+const (
+	recordedVerifierToken = "3f1c9a52-8d7e-4b6a-9e21-c04d5b7f8a13"
+	recordedPayload       = `{"eventNotifications":[{"realmId":"9341452538591482","dataChangeEvent":{"entities":[{"name":"Invoice","id":"1042","operation":"Update","lastUpdated":"2024-11-04T09:15:27-0800"},{"name":"Payment","id":"877","operation":"Create","lastUpdated":"2024-11-04T09:16:02-0800"}]}}]}`
+	recordedSignature     = "UpFLLVpi2EyIInB3smsRRngBY7hyeclMXz/Bzkh+XZE="
+)
+
+func TestVerifySignature(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		signature string
+		token     string
+		want      bool
+	}{
+		{"recorded", recordedPayload, recordedSignature, recordedVerifierToken, true},
+		{"tampered body", strings.Replace(recordedPayload, `"id":"1042"`, `"id":"1043"`, 1), recordedSignature, recordedVerifierToken, false},
+		{"trailing newline", recordedPayload + "\n", recordedSignature, recordedVerifierToken, false},
+		{"missing signature", recordedPayload, "", recordedVerifierToken, false},
+		{"signature not base64", recordedPayload, "not a signature!", recordedVerifierToken, false},
+		{"wrong verifier token", recordedPayload, recordedSignature, "a-different-token", false},
+		{"no verifier token", recordedPayload, recordedSignature, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := verifySignature([]byte(tt.body), tt.signature, tt.token); got != tt.want {
+				t.Errorf("verifySignature = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewestChange(t *testing.T) {
+	var event WebhookEvent
+	if err := json.Unmarshal([]byte(recordedPayload), &event); err != nil {
+		t.Fatal(err)
+	}
+	want := time.Date(2024, 11, 4, 17, 16, 2, 0, time.UTC)
+	if got := event.newestChange(); !got.Equal(want) {
+		t.Errorf("newestChange = %v, want %v", got, want)
+	}
+
+	event.EventNotifications[0].DataChangeEvent.Entities[1].LastUpdatedTime = "2024-11-05T01:00:00Z"
+	if got, want := event.newestChange(), time.Date(2024, 11, 5, 1, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("newestChange with an RFC 3339 time = %v, want %v", got, want)
+	}
+}
+
+func TestHandleWebhookRejects(t *testing.T) {
+	sign := func(body, token string) string {
+		mac := hmac.New(sha256.New, []byte(token))
+		mac.Write([]byte(body))
+		return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	}
+	s := &OAuthService{verifierToken: recordedVerifierToken}
+
+	tests := []struct {
+		name      string
+		body      string
+		signature string
+		want      int
+	}{
+		{"tampered body", strings.Replace(recordedPayload, `"operation":"Update"`, `"operation":"Delete"`, 1), recordedSignature, http.StatusUnauthorized},
+		{"missing signature", recordedPayload, "", http.StatusUnauthorized},
+		{"signed with another token", recordedPayload, sign(recordedPayload, "a-different-token"), http.StatusUnauthorized},
+		// The recorded notification is correctly signed but long past the
+		// window in which Intuit retries, so it can only be a replay.
+		{"stale lastUpdated", recordedPayload, recordedSignature, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/intuit/webhook", strings.NewReader(tt.body))
+			if tt.signature != "" {
+				req.Header.Set(signatureHeader, tt.signature)
+			}
+			rec := httptest.NewRecorder()
+			s.HandleWebhook(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestNewestChangeWithinMaxAge(t *testing.T) {
+	fresh := time.Now().Add(-time.Hour).Format("2006-01-02T15:04:05-0700")
+	body := strings.ReplaceAll(recordedPayload, "2024-11-04T09:16:02-0800", fresh)
+	var event WebhookEvent
+	if err := json.Unmarshal([]byte(body), &event); err != nil {
+		t.Fatal(err)
+	}
+	if age := time.Since(event.newestChange()); age > webhookMaxAge {
+		t.Errorf("notification from an hour ago is %v old, over the %v limit", age, webhookMaxAge)
+	}
+}
@@ -3,6 +3,7 @@ package intuit
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -11,6 +12,8 @@ import (
 	"time"
 
 	"cloud.google.com/go/firestore"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/households"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/ledger"
 	"golang.org/x/oauth2"
 )
 
@@ -22,12 +25,15 @@ type WebhookEvent struct {
 				Name            string `json:"name"`
 				ID              string `json:"id"`
 				Operation       string `json:"operation"`
-				LastUpdatedTime string `json:"lastUpdatedTime"`
+				LastUpdatedTime string `json:"lastUpdated"` // e.g. "2015-10-05T14:42:19-0700"
 			} `json:"entities"`
 		} `json:"dataChangeEvent"`
 	} `json:"eventNotifications"`
 }
 
+// HandleWebhook queues QuickBooks change notifications in the inbox. Only
+// bodies signed with the verifier token are accepted, and each entity change
+// is queued once however often Intuit delivers it.
 func (s *OAuthService) HandleWebhook(w http.ResponseWriter, r *http.Request) {
 	bodyBytes, err := io.ReadAll(r.Body)
 	if err != nil {
@@ -36,197 +42,192 @@ func (s *OAuthService) HandleWebhook(w http.ResponseWriter, r *http.Request) {
 	}
 	defer r.Body.Close()
 
+	if !verifySignature(bodyBytes, r.Header.Get(signatureHeader), s.verifierToken) {
+		log.Printf("Rejected Intuit webhook with a missing or invalid signature from %s", r.RemoteAddr)
+		http.Error(w, "Invalid signature", http.StatusUnauthorized)
+		return
+	}
+
 	var webhook WebhookEvent
-	var lastCustomerRef string
 	if err := json.Unmarshal(bodyBytes, &webhook); err != nil {
 		http.Error(w, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
 		return
 	}
 
-	for _, note := range webhook.EventNotifications {
-		realmID := note.RealmID
+	if newest := webhook.newestChange(); !newest.IsZero() && time.Since(newest) > webhookMaxAge {
+		log.Printf("Rejected Intuit webhook whose newest change is from %s", newest.Format(time.RFC3339))
+		http.Error(w, "Stale notification", http.StatusBadRequest)
+		return
+	}
 
+	// Persist every entity change and acknowledge right away. The inbox worker
+	// does the QuickBooks fetches and Firestore writes, with retries.
+	queued := 0
+	for _, note := range webhook.EventNotifications {
 		for _, entity := range note.DataChangeEvent.Entities {
-
 			log.Printf("[Webhook Debug] entity.Name=%s, entity.ID=%s, entity.Operation=%s",
 				entity.Name, entity.ID, entity.Operation)
 
-			//---------------------------------------------------
-			// 1) HANDLE INVOICES
-			//---------------------------------------------------
-			if strings.EqualFold(entity.Name, "Invoice") {
-				invoiceID := entity.ID
-				operation := entity.Operation
-
-				if isDeleteOperation(operation) {
-					cRef, err := s.deleteInvoiceDoc(r.Context(), invoiceID)
-					if err != nil {
-						log.Printf("Failed to delete invoice doc %s: %v\n", invoiceID, err)
-					} else {
-						log.Printf("Webhook: successfully handled delete event for invoice %s\n", invoiceID)
-						if cRef != "" {
-							lastCustomerRef = cRef
-						}
-					}
-					continue
-				}
+			created, err := s.enqueue(r.Context(), &InboxEvent{
+				RealmID:         note.RealmID,
+				Entity:          entity.Name,
+				EntityID:        entity.ID,
+				Operation:       entity.Operation,
+				LastUpdatedTime: entity.LastUpdatedTime,
+			})
+			if err != nil {
+				// Intuit retries the whole notification; the events that did
+				// make it into the inbox are not queued twice.
+				log.Printf("Failed to queue %s %s: %v", entity.Name, entity.ID, err)
+				http.Error(w, "Failed to queue notification", http.StatusInternalServerError)
+				return
+			}
+			if created {
+				queued++
+			}
+		}
+	}
+	if queued > 0 {
+		s.wakeInboxWorker()
+	}
 
-				// Otherwise handle create/update/void
-				invData, err := s.fetchInvoiceFromQBO(r.Context(), realmID, invoiceID)
-				if err != nil {
-					log.Printf("Failed to fetch invoice %s from realm %s: %v\n", invoiceID, realmID, err)
-					continue
-				}
+	// Respond 200 to acknowledge receipt
+	w.WriteHeader(http.StatusOK)
+}
 
-				lastCustomerRef = invData.CustomerRef
-				invData.RealmID = realmID
-				err = s.storeInvoice(r.Context(), invData)
-				if err != nil {
-					log.Printf("Failed to store invoice in Firestore: %v\n", err)
-				} else {
-					log.Printf("Webhook: successfully handled %s event for invoice %s\n", operation, invoiceID)
-				}
+// processEntity applies one QuickBooks change to Firestore and returns the
+// customer whose totals need recalculating, if any. Every step is safe to
+// repeat, so a failed event can simply be processed again.
+func (s *OAuthService) processEntity(ctx context.Context, ev *InboxEvent) (string, error) {
+	realmID := ev.RealmID
+
+	switch {
+	//---------------------------------------------------
+	// 1) HANDLE INVOICES
+	//---------------------------------------------------
+	case strings.EqualFold(ev.Entity, "Invoice"):
+		invoiceID := ev.EntityID
+
+		if isDeleteOperation(ev.Operation) {
+			cRef, err := s.deleteInvoiceDoc(ctx, invoiceID)
+			if err != nil {
+				return "", fmt.Errorf("delete invoice doc %s: %w", invoiceID, err)
 			}
+			log.Printf("Webhook: successfully handled delete event for invoice %s\n", invoiceID)
+			return cRef, nil
+		}
 
-			//---------------------------------------------------
-			// 2) HANDLE PAYMENTS
-			//---------------------------------------------------
-			if strings.EqualFold(entity.Name, "Payment") {
-				paymentID := entity.ID
-				operation := entity.Operation
-
-				if isDeleteOperation(operation) {
-					// If removing a Payment doc
-					err := s.deletePaymentDoc(r.Context(), paymentID)
-					if err != nil {
-						log.Printf("Failed to delete payment doc %s: %v", paymentID, err)
-					} else {
-						// (Optional) Re-fetch or re-store logic here
-						payData, err := s.fetchPaymentFromQBO(r.Context(), realmID, paymentID)
-						if err != nil {
-							log.Printf("Failed to fetch payment %s: %v", paymentID, err)
-							continue
-						}
-						if err := s.storePayment(r.Context(), realmID, payData); err != nil {
-							log.Printf("Failed to store payment doc after delete: %v", err)
-						} else {
-							log.Printf("Re-stored payment doc for PaymentID=%s", paymentID)
-						}
-					}
-					continue
-				}
+		// Otherwise handle create/update/void
+		invData, err := s.fetchInvoiceFromQBO(ctx, realmID, invoiceID)
+		if err != nil {
+			return "", fmt.Errorf("fetch invoice %s from realm %s: %w", invoiceID, realmID, err)
+		}
+		invData.RealmID = realmID
+		if err := s.storeInvoice(ctx, invData); err != nil {
+			return "", fmt.Errorf("store invoice %s: %w", invoiceID, err)
+		}
+		log.Printf("Webhook: successfully handled %s event for invoice %s\n", ev.Operation, invoiceID)
+		return invData.CustomerRef, nil
+
+	//---------------------------------------------------
+	// 2) HANDLE PAYMENTS
+	//---------------------------------------------------
+	case strings.EqualFold(ev.Entity, "Payment"):
+		paymentID := ev.EntityID
+
+		if isDeleteOperation(ev.Operation) {
+			if err := s.deletePaymentDoc(ctx, paymentID); err != nil {
+				return "", fmt.Errorf("delete payment doc %s: %w", paymentID, err)
+			}
+			log.Printf("Deleted payment doc for PaymentID=%s", paymentID)
+			return "", nil
+		}
 
-				// Otherwise, a new or updated Payment
-				payData, err := s.fetchPaymentFromQBO(r.Context(), realmID, paymentID)
-				if err != nil {
-					log.Printf("Failed to fetch payment %s: %v", paymentID, err)
-					continue
-				}
+		// Otherwise, a new or updated Payment
+		payData, err := s.fetchPaymentFromQBO(ctx, realmID, paymentID)
+		if err != nil {
+			return "", fmt.Errorf("fetch payment %s: %w", paymentID, err)
+		}
 
-				// 2a) If total is zero, skip storePayment -> only fetch & store each invoice
-				if payData.TotalAmt == 0 {
-					log.Printf("Detected a $0 Payment for PaymentID=%s -> credit usage. Will only refresh each invoice", paymentID)
+		// 2a) If total is zero, skip storePayment -> only fetch & store each invoice
+		if payData.TotalAmt == 0 {
+			log.Printf("Detected a $0 Payment for PaymentID=%s -> credit usage. Will only refresh each invoice", paymentID)
 
-					for _, line := range payData.Lines {
-						if line.InvoiceID == "" {
-							continue
-						}
-						freshInv, ferr := s.fetchInvoiceFromQBO(r.Context(), realmID, line.InvoiceID)
-						if ferr != nil {
-							log.Printf("Failed to fetch invoice %s for zero-dollar Payment: %v", line.InvoiceID, ferr)
-							continue
-						}
-						if err2 := s.storeInvoice(r.Context(), freshInv); err2 != nil {
-							log.Printf("Failed to store invoice %s after zero-dollar Payment fetch: %v", line.InvoiceID, err2)
-						} else {
-							log.Printf("Updated invoice %s from QBO after zero-dollar Payment", line.InvoiceID)
-							lastCustomerRef = freshInv.CustomerRef
-						}
-					}
+			var customerRef string
+			for _, line := range payData.Lines {
+				if line.InvoiceID == "" {
 					continue
 				}
-
-				// 2b) Otherwise, normal non-zero Payment
-				// Optionally set lastCustomerRef
-				if len(payData.Lines) > 0 && payData.Lines[0].InvoiceID != "" {
-					invSnap, _ := s.findInvoiceDocByID(r.Context(), payData.Lines[0].InvoiceID)
-					if invSnap != nil {
-						var inv InvoiceRecord
-						if e := invSnap.DataTo(&inv); e == nil {
-							lastCustomerRef = inv.CustomerRef
-						}
-					}
+				freshInv, err := s.fetchInvoiceFromQBO(ctx, realmID, line.InvoiceID)
+				if err != nil {
+					return "", fmt.Errorf("fetch invoice %s for zero-dollar payment: %w", line.InvoiceID, err)
 				}
-
-				if err := s.storePayment(r.Context(), realmID, payData); err != nil {
-					log.Printf("Failed to store payment doc: %v", err)
-				} else {
-					log.Printf("Stored payment doc for PaymentID=%s (Amount=%.2f)", paymentID, payData.TotalAmt)
+				if err := s.storeInvoice(ctx, freshInv); err != nil {
+					return "", fmt.Errorf("store invoice %s after zero-dollar payment: %w", line.InvoiceID, err)
 				}
+				log.Printf("Updated invoice %s from QBO after zero-dollar Payment", line.InvoiceID)
+				customerRef = freshInv.CustomerRef
 			}
+			return customerRef, nil
+		}
 
-			//---------------------------------------------------
-			// 3) HANDLE CREDIT MEMOS
-			//---------------------------------------------------
-			if strings.EqualFold(entity.Name, "CreditMemo") {
-				creditMemoID := entity.ID
-				operation := entity.Operation
-
-				if isDeleteOperation(operation) {
-					err := s.deleteCreditMemoDoc(r.Context(), creditMemoID)
-					if err != nil {
-						// re-fetch & store if you wish
-						cmData, err := s.fetchCreditMemoFromQBO(r.Context(), realmID, creditMemoID)
-						if err != nil {
-							log.Printf("Failed to fetch creditMemo %s: %v", creditMemoID, err)
-							continue
-						}
-						if err := s.storeCreditMemo(r.Context(), realmID, cmData); err != nil {
-							log.Printf("Failed to store credit memo doc: %v", err)
-						} else {
-							log.Printf("Stored credit memo doc for creditMemoID=%s after delete error", creditMemoID)
-						}
-					} else {
-						log.Printf("Deleted credit memo doc for creditMemoID=%s", creditMemoID)
-					}
-					continue
+		// 2b) Otherwise, normal non-zero Payment
+		var customerRef string
+		if len(payData.Lines) > 0 && payData.Lines[0].InvoiceID != "" {
+			invSnap, _ := s.findInvoiceDocByID(ctx, payData.Lines[0].InvoiceID)
+			if invSnap != nil {
+				var inv InvoiceRecord
+				if e := invSnap.DataTo(&inv); e == nil {
+					customerRef = inv.CustomerRef
 				}
+			}
+		}
 
-				cmData, err := s.fetchCreditMemoFromQBO(r.Context(), realmID, creditMemoID)
-				if err != nil {
-					log.Printf("Failed to fetch creditMemo %s: %v", creditMemoID, err)
-					continue
-				}
+		if err := s.storePayment(ctx, realmID, payData); err != nil {
+			return "", fmt.Errorf("store payment %s: %w", paymentID, err)
+		}
+		log.Printf("Stored payment doc for PaymentID=%s (Amount=%.2f)", paymentID, payData.TotalAmt)
+		return customerRef, nil
+
+	//---------------------------------------------------
+	// 3) HANDLE CREDIT MEMOS
+	//---------------------------------------------------
+	case strings.EqualFold(ev.Entity, "CreditMemo"):
+		creditMemoID := ev.EntityID
+
+		if isDeleteOperation(ev.Operation) {
+			if err := s.deleteCreditMemoDoc(ctx, creditMemoID); err != nil {
+				return "", fmt.Errorf("delete credit memo doc %s: %w", creditMemoID, err)
+			}
+			log.Printf("Deleted credit memo doc for creditMemoID=%s", creditMemoID)
+			return "", nil
+		}
 
-				if len(cmData.Lines) > 0 && cmData.Lines[0].InvoiceID != "" {
-					invSnap, _ := s.findInvoiceDocByID(r.Context(), cmData.Lines[0].InvoiceID)
-					if invSnap != nil {
-						var inv InvoiceRecord
-						if e := invSnap.DataTo(&inv); e == nil {
-							lastCustomerRef = inv.CustomerRef
-						}
-					}
-				}
+		cmData, err := s.fetchCreditMemoFromQBO(ctx, realmID, creditMemoID)
+		if err != nil {
+			return "", fmt.Errorf("fetch credit memo %s: %w", creditMemoID, err)
+		}
 
-				if err := s.storeCreditMemo(r.Context(), realmID, cmData); err != nil {
-					log.Printf("Failed to store credit memo doc: %v", err)
-				} else {
-					log.Printf("Stored credit memo doc for creditMemoID=%s", creditMemoID)
+		var customerRef string
+		if len(cmData.Lines) > 0 && cmData.Lines[0].InvoiceID != "" {
+			invSnap, _ := s.findInvoiceDocByID(ctx, cmData.Lines[0].InvoiceID)
+			if invSnap != nil {
+				var inv InvoiceRecord
+				if e := invSnap.DataTo(&inv); e == nil {
+					customerRef = inv.CustomerRef
 				}
 			}
+		}
 
-		} // end entity loop
-	} // end note loop
-
-	if lastCustomerRef != "" {
-		err := s.recalcTotalBalance(r.Context(), lastCustomerRef)
-		if err != nil {
-			log.Printf("Failed to recalc total_balance for customerRef=%s: %v", lastCustomerRef, err)
+		if err := s.storeCreditMemo(ctx, realmID, cmData); err != nil {
+			return "", fmt.Errorf("store credit memo %s: %w", creditMemoID, err)
 		}
+		log.Printf("Stored credit memo doc for creditMemoID=%s", creditMemoID)
+		return customerRef, nil
 	}
 
-	// Respond 200 to acknowledge receipt
-	w.WriteHeader(http.StatusOK)
+	log.Printf("Ignoring webhook event for unhandled entity %s", ev.Entity)
+	return "", nil
 }
 
 // GENERAL HELPER
@@ -267,8 +268,8 @@ func (s *OAuthService) recalcTotalBalance(ctx context.Context, customerRef strin
 	for _, snap := range snaps {
 		var inv InvoiceRecord
 		if err := snap.DataTo(&inv); err == nil {
-			if inv.RealmID == "" {
-				inv.RealmID = "9341453759932406" //TEMPORARY -> Just the sandbox id. Manually set later.
+			if inv.RealmID == "" && s.qbo.DefaultRealm() != "" {
+				inv.RealmID = s.qbo.DefaultRealm()
 				snap.Ref.Set(ctx, inv, firestore.MergeAll)
 			}
 			totalBalance += inv.Balance
@@ -308,34 +309,6 @@ type CreditMemoLine struct {
 }
 
 func (s *OAuthService) fetchCreditMemoFromQBO(ctx context.Context, realmID, creditMemoID string) (*CreditMemoRecord, error) {
-	token, err := s.getGlobalTokens(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get tokens: %w", err)
-	}
-	validToken, err := s.refreshAccessTokenIfExpired(ctx, token, realmID)
-	if err != nil {
-		return nil, fmt.Errorf("refresh token error: %w", err)
-	}
-
-	client := s.config.Client(ctx, validToken)
-	url := fmt.Sprintf("https://sandbox-quickbooks.api.intuit.com/v3/company/%s/creditmemo/%s?minorversion=65", realmID, creditMemoID)
-
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create creditmemo request: %w", err)
-	}
-	req.Header.Set("Accept", "application/json")
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("creditmemo fetch error: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("creditmemo fetch returned status %d", resp.StatusCode)
-	}
-
 	var cmResp struct {
 		CreditMemo struct {
 			ID        string  `json:"Id"`
@@ -353,8 +326,8 @@ func (s *OAuthService) fetchCreditMemoFromQBO(ctx context.Context, realmID, cred
 		Time string `json:"time"`
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&cmResp); err != nil {
-		return nil, fmt.Errorf("failed to decode creditmemo JSON: %w", err)
+	if err := s.qbo.Get(ctx, realmID, "creditmemo", creditMemoID, &cmResp); err != nil {
+		return nil, err
 	}
 
 	c := cmResp.CreditMemo
@@ -460,11 +433,12 @@ func (s *OAuthService) storeCreditMemo(ctx context.Context, realmID string, cm *
 			log.Printf("Warning: fetchInvoiceFromQBO failed after credit application for invoiceID=%s: %v", line.InvoiceID, ferr)
 			continue
 		}
-		if err2 := s.storeInvoice(ctx, freshInv); err2 != nil {
-			log.Printf("Warning: storeInvoice failed after creditMemo for invoiceID=%s: %v", line.InvoiceID, err2)
-		} else {
-			log.Printf("Synced invoice %s from QBO after creditMemo usage", line.InvoiceID)
+		if err := s.storeInvoice(ctx, freshInv); err != nil {
+			// The refetched invoice overwrites the local decrement, so the
+			// whole event can be retried.
+			return fmt.Errorf("store invoice %s after creditMemo: %w", line.InvoiceID, err)
 		}
+		log.Printf("Synced invoice %s from QBO after creditMemo usage", line.InvoiceID)
 	}
 
 	return nil
@@ -553,34 +527,6 @@ type PaymentLine struct {
 }
 
 func (s *OAuthService) fetchPaymentFromQBO(ctx context.Context, realmID, paymentID string) (*PaymentRecord, error) {
-	token, err := s.getGlobalTokens(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get tokens: %w", err)
-	}
-	validToken, err := s.refreshAccessTokenIfExpired(ctx, token, realmID)
-	if err != nil {
-		return nil, fmt.Errorf("refresh token error: %w", err)
-	}
-
-	client := s.config.Client(ctx, validToken)
-	url := fmt.Sprintf("https://sandbox-quickbooks.api.intuit.com/v3/company/%s/payment/%s?minorversion=65", realmID, paymentID)
-
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create payment request: %w", err)
-	}
-	req.Header.Set("Accept", "application/json")
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("payment fetch error: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("payment fetch returned status %d", resp.StatusCode)
-	}
-
 	var payResp struct {
 		Payment struct {
 			ID               string  `json:"Id"`
@@ -603,8 +549,8 @@ func (s *OAuthService) fetchPaymentFromQBO(ctx context.Context, realmID, payment
 		Time string `json:"time"`
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&payResp); err != nil {
-		return nil, fmt.Errorf("failed to decode payment JSON: %w", err)
+	if err := s.qbo.Get(ctx, realmID, "payment", paymentID, &payResp); err != nil {
+		return nil, err
 	}
 
 	p := payResp.Payment
@@ -697,11 +643,12 @@ func (s *OAuthService) storePayment(ctx context.Context, realmID string, pay *Pa
 			log.Printf("Warning: fetchInvoiceFromQBO failed for invoiceID=%s: %v", line.InvoiceID, ferr)
 			continue
 		}
-		if err2 := s.storeInvoice(ctx, freshInv); err2 != nil {
-			log.Printf("Warning: storeInvoice failed after Payment for invoiceID=%s: %v", line.InvoiceID, err2)
-		} else {
-			log.Printf("Synced invoice %s from QBO after Payment application", line.InvoiceID)
+		if err := s.storeInvoice(ctx, freshInv); err != nil {
+			// The refetched invoice overwrites the local decrement, so the
+			// whole event can be retried.
+			return fmt.Errorf("store invoice %s after Payment: %w", line.InvoiceID, err)
 		}
+		log.Printf("Synced invoice %s from QBO after Payment application", line.InvoiceID)
 	}
 
 	return nil
@@ -738,6 +685,9 @@ type InvoiceRecord struct {
 	HoursPurchased float64   `firestore:"hoursPurchased,omitempty"`
 }
 
+// deleteInvoiceDoc deletes the stored invoice and returns its customer. The
+// invoice's hours are taken out of the family's ledger first, so that if
+// that fails the event is retried while the invoice can still be found.
 func (s *OAuthService) deleteInvoiceDoc(ctx context.Context, invoiceID string) (string, error) {
 	log.Printf("deleteInvoiceDoc called for invoiceID=%s", invoiceID)
 	colRef := s.firestore.CollectionGroup("invoices").
@@ -757,6 +707,13 @@ func (s *OAuthService) deleteInvoiceDoc(ctx context.Context, invoiceID string) (
 		log.Printf("Failed to parse invoice data for doc %s: %v", snaps[0].Ref.ID, err)
 		// but we can still delete it
 	}
+	if inv.CustomerRef == "" {
+		// Invoices are stored under intuit/{customerRef}/invoices.
+		inv.CustomerRef = snaps[0].Ref.Parent.Parent.ID
+	}
+	if err := s.recordPurchase(ctx, inv.CustomerRef, invoiceID, 0); err != nil {
+		return "", err
+	}
 
 	docRef := snaps[0].Ref
 	if _, err := docRef.Delete(ctx); err != nil {
@@ -766,34 +723,6 @@ func (s *OAuthService) deleteInvoiceDoc(ctx context.Context, invoiceID string) (
 }
 
 func (s *OAuthService) fetchCustomerFromQBO(ctx context.Context, realmID, customerID string) (string, error) {
-	token, err := s.getGlobalTokens(ctx)
-	if err != nil {
-		return "", fmt.Errorf("failed to get tokens: %w", err)
-	}
-	validToken, err := s.refreshAccessTokenIfExpired(ctx, token, realmID)
-	if err != nil {
-		return "", fmt.Errorf("refresh token error: %w", err)
-	}
-
-	client := s.config.Client(ctx, validToken)
-	url := fmt.Sprintf("https://sandbox-quickbooks.api.intuit.com/v3/company/%s/customer/%s?minorversion=65", realmID, customerID)
-
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return "", fmt.Errorf("failed to create customer request: %w", err)
-	}
-	req.Header.Set("Accept", "application/json")
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("customer fetch error: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("customer fetch returned status %d", resp.StatusCode)
-	}
-
 	var custResp struct {
 		Customer struct {
 			Id               string `json:"Id"`
@@ -801,43 +730,14 @@ func (s *OAuthService) fetchCustomerFromQBO(ctx context.Context, realmID, custom
 		} `json:"Customer"`
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&custResp); err != nil {
-		return "", fmt.Errorf("failed to decode customer JSON: %w", err)
+	if err := s.qbo.Get(ctx, realmID, "customer", customerID, &custResp); err != nil {
+		return "", err
 	}
 
 	return custResp.Customer.PrimaryEmailAddr, nil
 }
 
 func (s *OAuthService) fetchInvoiceFromQBO(ctx context.Context, realmID, invoiceID string) (*InvoiceRecord, error) {
-	token, err := s.getGlobalTokens(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get global tokens: %w", err)
-	}
-
-	validToken, err := s.refreshAccessTokenIfExpired(ctx, token, realmID)
-	if err != nil {
-		return nil, fmt.Errorf("refresh token error: %w", err)
-	}
-
-	client := s.config.Client(ctx, validToken)
-	url := fmt.Sprintf("https://sandbox-quickbooks.api.intuit.com/v3/company/%s/invoice/%s?minorversion=65", realmID, invoiceID)
-
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to build invoice fetch request: %w", err)
-	}
-	req.Header.Set("Accept", "application/json")
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("invoice fetch error: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("invoice fetch returned status %d", resp.StatusCode)
-	}
-
 	var invoiceResp struct {
 		Invoice struct {
 			ID          string `json:"Id"`
@@ -864,8 +764,8 @@ func (s *OAuthService) fetchInvoiceFromQBO(ctx context.Context, realmID, invoice
 		Time string `json:"time"`
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&invoiceResp); err != nil {
-		return nil, fmt.Errorf("failed to decode invoice JSON: %w", err)
+	if err := s.qbo.Get(ctx, realmID, "invoice", invoiceID, &invoiceResp); err != nil {
+		return nil, err
 	}
 
 	invoice := invoiceResp.Invoice
@@ -931,15 +831,37 @@ func (s *OAuthService) storeInvoice(ctx context.Context, inv *InvoiceRecord) err
 		log.Printf("AutoAssociateParent fucntion error: %v\n", err)
 	}
 
+	return s.recordPurchase(ctx, inv.CustomerRef, inv.InvoiceID, inv.HoursPurchased)
+}
+
+// recordPurchase brings the family's hours ledger in line with an invoice.
+// Invoices of customers that are not linked to a household yet are picked up
+// when that household's ledger is first opened. Payments re-store their invoices,
+// so they come through here too and trigger the ledger's low balance check.
+// Any other failure is returned, so that the inbox retries the event.
+func (s *OAuthService) recordPurchase(ctx context.Context, customerRef, invoiceID string, hours float64) error {
+	familyID, err := s.hours.FamilyForCustomer(ctx, customerRef)
+	if errors.Is(err, ledger.ErrNoFamily) {
+		log.Printf("No household linked to customerRef=%s yet; invoice %s is not in any ledger", customerRef, invoiceID)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("find household for customerRef=%s: %w", customerRef, err)
+	}
+	if _, err := s.hours.SetPurchaseHours(ctx, familyID, invoiceID, hours); err != nil {
+		return fmt.Errorf("record invoice %s in ledger %s: %w", invoiceID, familyID, err)
+	}
 	return nil
 }
 
+// autoAssociateParent bills the household of the parent whose email the
+// invoice was sent to, if neither has been linked yet.
 func (s *OAuthService) autoAssociateParent(ctx context.Context, inv *InvoiceRecord) error {
 	if inv.BillEmail == "" {
 		return nil
 	}
 
-	alreadyLinked, err := s.findParentWithQboId(ctx, inv.CustomerRef)
+	alreadyLinked, err := s.findHouseholdWithQboId(ctx, inv.CustomerRef)
 	if err != nil {
 		return err
 	}
@@ -959,48 +881,30 @@ func (s *OAuthService) autoAssociateParent(ctx context.Context, inv *InvoiceReco
 		return nil // no parent with that email
 	}
 
-	parentDoc := parentsSnap[0]
-	pData := parentDoc.Data()
-
-	var businessData map[string]interface{}
-	if rawBiz, ok := pData["business"]; ok {
-		if casted, ok2 := rawBiz.(map[string]interface{}); ok2 {
-			businessData = casted
-		}
-	} else {
-		businessData = make(map[string]interface{})
+	parentID := parentsSnap[0].Ref.ID
+	linked, err := s.households.LinkCustomer(ctx, parentID, inv.CustomerRef)
+	if err != nil {
+		return fmt.Errorf("failed to set qboCustomerId on household of parent %s: %w", parentID, err)
 	}
-
-	qboId, _ := businessData["qboCustomerId"].(string)
-	if qboId == "" {
-		businessData["qboCustomerId"] = inv.CustomerRef
-		_, err := parentDoc.Ref.Set(ctx, map[string]interface{}{
-			"business": businessData,
-		}, firestore.MergeAll)
-		if err != nil {
-			return fmt.Errorf("failed to set qboCustomerId in parent doc sub-document: %w", err)
-		}
-		log.Printf("Auto-associated parent doc %s with qboCustomerId=%s\n", parentDoc.Ref.ID, inv.CustomerRef)
-	} else if qboId != inv.CustomerRef {
-		log.Printf("Parent doc %s has business.qboCustomerId=%s but invoice uses %s; skipping.\n",
-			parentDoc.Ref.ID, qboId, inv.CustomerRef)
+	if linked {
+		log.Printf("Auto-associated household of parent %s with qboCustomerId=%s\n", parentID, inv.CustomerRef)
+	} else {
+		log.Printf("Household of parent %s already has a qboCustomerId but invoice uses %s; skipping.\n",
+			parentID, inv.CustomerRef)
 	}
 
 	return nil
 }
 
-func (s *OAuthService) findParentWithQboId(ctx context.Context, qboId string) (bool, error) {
+func (s *OAuthService) findHouseholdWithQboId(ctx context.Context, qboId string) (bool, error) {
 	if qboId == "" {
 		return false, nil
 	}
-	snap, err := s.firestore.Collection("parents").
-		Where("business.qboCustomerId", "==", qboId).
-		Limit(1).
-		Documents(ctx).GetAll()
-	if err != nil {
-		return false, err
+	_, err := s.households.ForCustomer(ctx, qboId)
+	if errors.Is(err, households.ErrNotFound) {
+		return false, nil
 	}
-	return len(snap) > 0, nil
+	return err == nil, err
 }
 
 // GLOBAL TOKENS / OAUTH
@@ -1022,20 +926,42 @@ func (s *OAuthService) getGlobalTokens(ctx context.Context) (*oauth2.Token, erro
 		return nil, err
 	}
 
+	accessToken, err := s.tokens.Decrypt(data.Intuitoauth.AccessToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt access token: %w", err)
+	}
+	refreshToken, err := s.tokens.Decrypt(data.Intuitoauth.RefreshToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt refresh token: %w", err)
+	}
 	return &oauth2.Token{
-		AccessToken:  data.Intuitoauth.AccessToken,
-		RefreshToken: data.Intuitoauth.RefreshToken,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
 		TokenType:    data.Intuitoauth.TokenType,
 		Expiry:       data.Intuitoauth.Expiry,
 	}, nil
 }
 
+// validToken returns the stored access token, refreshed if it has expired.
+// It is the qbo.Client's token source.
+func (s *OAuthService) validToken(ctx context.Context, realmID string) (*oauth2.Token, error) {
+	token, err := s.getGlobalTokens(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get global tokens: %w", err)
+	}
+	validToken, err := s.refreshAccessTokenIfExpired(ctx, token, realmID)
+	if err != nil {
+		return nil, fmt.Errorf("refresh token error: %w", err)
+	}
+	return validToken, nil
+}
+
 func (s *OAuthService) refreshAccessTokenIfExpired(ctx context.Context, tok *oauth2.Token, oldRealmID string) (*oauth2.Token, error) {
 	if tok.Valid() {
 		return tok, nil
 	}
 
-	ts := s.config.TokenSource(ctx, tok)
+	ts := s.config.TokenSource(s.oauthContext(ctx), tok)
 	newTok, err := ts.Token()
 	if err != nil {
 		return nil, fmt.Errorf("failed to refresh token: %w", err)
@@ -1058,11 +984,7 @@ func (s *OAuthService) updateGlobalTokens(ctx context.Context, newTok *oauth2.To
 		RealmID:      realmID,
 	}
 
-	_, err := s.firestore.Collection("intuit").Doc("globalTokens").
-		Set(ctx, map[string]interface{}{
-			"intuitoauth": updated,
-		}, firestore.MergeAll)
-	if err != nil {
+	if err := s.storeTokens(ctx, updated); err != nil {
 		return fmt.Errorf("failed to update global tokens sub-document: %w", err)
 	}
 	return nil
@@ -1106,10 +1028,10 @@ func (s *OAuthService) performDailyPoll(ctx context.Context) error {
 			continue
 		}
 
-		// If RealmID is empty, set the sandbox ID (or your real realm)
+		// If RealmID is empty, set the configured default realm (INTUIT_REALM_ID)
 		// in Firestore. This ensures future polls will have a realm ID.
-		if inv.RealmID == "" {
-			inv.RealmID = "9341453759932406" // your single QBO realm ID
+		if inv.RealmID == "" && s.qbo.DefaultRealm() != "" {
+			inv.RealmID = s.qbo.DefaultRealm()
 			if _, e2 := snap.Ref.Set(ctx, inv, firestore.MergeAll); e2 != nil {
 				log.Printf("[DailyPoll] Failed to set realm on doc %s: %v", snap.Ref.Path, e2)
 				// but we can still keep going
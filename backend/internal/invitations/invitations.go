@@ -0,0 +1,207 @@
+// backend/internal/invitations/invitations.go
+
+package invitations
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Parents link themselves to a student by redeeming an invitation code that
+// a tutor made for that student. Codes are random, expire after
+// InvitationTTL and can be redeemed once. Only the hash of a code is stored,
+// and a redeemed invitation keeps who redeemed it and when.
+
+const (
+	// InvitationTTL is how long a code can be redeemed after it is made.
+	InvitationTTL = 14 * 24 * time.Hour
+	// AttemptWindow and maxFailedAttempts limit how many wrong codes a parent
+	// can try, so codes cannot be guessed.
+	AttemptWindow     = time.Hour
+	maxFailedAttempts = 10
+
+	// codeAlphabet leaves out 0, O, 1 and I, which are easily mistyped.
+	codeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	codeLength   = 12
+	groupLength  = 4
+)
+
+var (
+	ErrNotFound        = errors.New("invitation not found")
+	ErrExpired         = errors.New("invitation has expired")
+	ErrRedeemed        = errors.New("invitation has already been used")
+	ErrTooManyAttempts = errors.New("too many invalid invitation codes")
+)
+
+// Invitation is a document in the "student_invitations" collection, keyed
+// by the hash of its code.
+type Invitation struct {
+	ID        string    `firestore:"-" json:"id"`
+	StudentID string    `firestore:"student_id" json:"student_id"`
+	CreatedBy string    `firestore:"created_by" json:"created_by"`
+	CreatedAt time.Time `firestore:"created_at" json:"created_at"`
+	ExpiresAt time.Time `firestore:"expires_at" json:"expires_at"`
+	// RedeemedBy is the user ID of the parent who used the code.
+	RedeemedBy string     `firestore:"redeemed_by,omitempty" json:"redeemed_by,omitempty"`
+	RedeemedAt *time.Time `firestore:"redeemed_at,omitempty" json:"redeemed_at,omitempty"`
+}
+
+// Manager makes and redeems invitations.
+type Manager struct {
+	store Store
+	now   func() time.Time
+}
+
+// NewManager returns a Manager that keeps invitations in store.
+func NewManager(store Store) *Manager {
+	return &Manager{store: store, now: time.Now}
+}
+
+// Create makes an invitation to link studentID and returns its code, which
+// is shown once to the tutor who made it.
+func (m *Manager) Create(ctx context.Context, studentID, createdBy string) (string, *Invitation, error) {
+	code, err := randomCode()
+	if err != nil {
+		return "", nil, err
+	}
+	now := m.now()
+	inv := &Invitation{
+		ID:        hashCode(code),
+		StudentID: studentID,
+		CreatedBy: createdBy,
+		CreatedAt: now,
+		ExpiresAt: now.Add(InvitationTTL),
+	}
+	if err := m.store.Create(ctx, inv); err != nil {
+		return "", nil, err
+	}
+	return code, inv, nil
+}
+
+// Check returns the invitation for code without using it up, so the parent
+// can confirm the student before linking. A code that cannot be redeemed
+// counts as a failed attempt of userID.
+func (m *Manager) Check(ctx context.Context, userID, code string) (*Invitation, error) {
+	if err := m.checkAttempts(ctx, userID); err != nil {
+		return nil, err
+	}
+	inv, ok, err := m.store.Get(ctx, hashCode(code))
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, m.fail(ctx, userID, ErrNotFound)
+	}
+	if err := m.redeemable(inv); err != nil {
+		return nil, m.fail(ctx, userID, err)
+	}
+	return inv, nil
+}
+
+// Redeem marks the invitation for code as used by userID and returns it.
+func (m *Manager) Redeem(ctx context.Context, userID, code string) (*Invitation, error) {
+	if err := m.checkAttempts(ctx, userID); err != nil {
+		return nil, err
+	}
+	var redeemed Invitation
+	err := m.store.Update(ctx, hashCode(code), func(inv *Invitation) error {
+		if err := m.redeemable(inv); err != nil {
+			return err
+		}
+		now := m.now()
+		inv.RedeemedBy = userID
+		inv.RedeemedAt = &now
+		redeemed = *inv
+		return nil
+	})
+	if errors.Is(err, ErrNotFound) || errors.Is(err, ErrExpired) || errors.Is(err, ErrRedeemed) {
+		return nil, m.fail(ctx, userID, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &redeemed, nil
+}
+
+// Release undoes Redeem, for when the student could not be linked after all.
+func (m *Manager) Release(ctx context.Context, code string) error {
+	return m.store.Update(ctx, hashCode(code), func(inv *Invitation) error {
+		inv.RedeemedBy = ""
+		inv.RedeemedAt = nil
+		return nil
+	})
+}
+
+// ListForStudent returns the invitations made for studentID, newest first.
+func (m *Manager) ListForStudent(ctx context.Context, studentID string) ([]*Invitation, error) {
+	return m.store.ListByStudent(ctx, studentID)
+}
+
+func (m *Manager) redeemable(inv *Invitation) error {
+	if inv.RedeemedAt != nil {
+		return ErrRedeemed
+	}
+	if m.now().After(inv.ExpiresAt) {
+		return ErrExpired
+	}
+	return nil
+}
+
+func (m *Manager) checkAttempts(ctx context.Context, userID string) error {
+	failed, err := m.store.CountFailuresSince(ctx, userID, m.now().Add(-AttemptWindow))
+	if err != nil {
+		return err
+	}
+	if failed >= maxFailedAttempts {
+		return ErrTooManyAttempts
+	}
+	return nil
+}
+
+// fail records a failed attempt of userID and returns cause, or the error
+// from recording it.
+func (m *Manager) fail(ctx context.Context, userID string, cause error) error {
+	if err := m.store.RecordFailure(ctx, userID, m.now()); err != nil {
+		return err
+	}
+	return cause
+}
+
+// NormalizeCode uppercases code and drops everything that is not part of
+// the alphabet, such as the dashes between groups and stray spaces.
+func NormalizeCode(code string) string {
+	var b strings.Builder
+	for _, c := range strings.ToUpper(code) {
+		if strings.ContainsRune(codeAlphabet, c) {
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
+
+// randomCode returns a code such as "K7QM-3XRA-PW9D".
+func randomCode() (string, error) {
+	buf := make([]byte, codeLength)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	for i, v := range buf {
+		if i > 0 && i%groupLength == 0 {
+			b.WriteByte('-')
+		}
+		// 256 is a multiple of the alphabet's 32 characters, so this is uniform.
+		b.WriteByte(codeAlphabet[int(v)%len(codeAlphabet)])
+	}
+	return b.String(), nil
+}
+
+func hashCode(code string) string {
+	sum := sha256.Sum256([]byte(NormalizeCode(code)))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,218 @@
+// backend/internal/invitations/store.go
+
+package invitations
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Store keeps invitations by the hash of their code, and the failed
+// redemption attempts of each parent.
+type Store interface {
+	Create(ctx context.Context, inv *Invitation) error
+	Get(ctx context.Context, hash string) (*Invitation, bool, error)
+	// Update applies fn to the invitation atomically. If fn returns an error
+	// the invitation is left as it was.
+	Update(ctx context.Context, hash string, fn func(*Invitation) error) error
+	// ListByStudent returns the invitations for a student, newest first.
+	ListByStudent(ctx context.Context, studentID string) ([]*Invitation, error)
+	RecordFailure(ctx context.Context, userID string, at time.Time) error
+	// CountFailuresSince counts the failed attempts of userID since the given time.
+	CountFailuresSince(ctx context.Context, userID string, since time.Time) (int, error)
+}
+
+type firestoreStore struct {
+	client *firestore.Client
+}
+
+// NewFirestoreStore returns a Store backed by the "student_invitations" and
+// "invitation_failures" collections. Redeemed invitations are kept as the
+// record of who linked which student; the expires_at field of a failure can
+// drive a Firestore TTL policy.
+func NewFirestoreStore(client *firestore.Client) Store {
+	return &firestoreStore{client: client}
+}
+
+func (f *firestoreStore) invitations() *firestore.CollectionRef {
+	return f.client.Collection("student_invitations")
+}
+
+func (f *firestoreStore) Create(ctx context.Context, inv *Invitation) error {
+	_, err := f.invitations().Doc(inv.ID).Create(ctx, inv)
+	return err
+}
+
+func (f *firestoreStore) Get(ctx context.Context, hash string) (*Invitation, bool, error) {
+	snap, err := f.invitations().Doc(hash).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	var inv Invitation
+	if err := snap.DataTo(&inv); err != nil {
+		return nil, false, err
+	}
+	inv.ID = hash
+	return &inv, true, nil
+}
+
+func (f *firestoreStore) Update(ctx context.Context, hash string, fn func(*Invitation) error) error {
+	ref := f.invitations().Doc(hash)
+	return f.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		snap, err := tx.Get(ref)
+		if status.Code(err) == codes.NotFound {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		var inv Invitation
+		if err := snap.DataTo(&inv); err != nil {
+			return err
+		}
+		inv.ID = hash
+		if err := fn(&inv); err != nil {
+			return err
+		}
+		return tx.Set(ref, &inv)
+	})
+}
+
+func (f *firestoreStore) ListByStudent(ctx context.Context, studentID string) ([]*Invitation, error) {
+	snaps, err := f.invitations().Where("student_id", "==", studentID).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*Invitation, 0, len(snaps))
+	for _, snap := range snaps {
+		var inv Invitation
+		if err := snap.DataTo(&inv); err != nil {
+			return nil, err
+		}
+		inv.ID = snap.Ref.ID
+		out = append(out, &inv)
+	}
+	sortNewestFirst(out)
+	return out, nil
+}
+
+func (f *firestoreStore) RecordFailure(ctx context.Context, userID string, at time.Time) error {
+	_, _, err := f.client.Collection("invitation_failures").Add(ctx, map[string]interface{}{
+		"user_id":    userID,
+		"at":         at,
+		"expires_at": at.Add(AttemptWindow),
+	})
+	return err
+}
+
+func (f *firestoreStore) CountFailuresSince(ctx context.Context, userID string, since time.Time) (int, error) {
+	// Filtering the time here keeps the query on a single-field index.
+	snaps, err := f.client.Collection("invitation_failures").Where("user_id", "==", userID).Documents(ctx).GetAll()
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, snap := range snaps {
+		if at, ok := snap.Data()["at"].(time.Time); ok && at.After(since) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func sortNewestFirst(invs []*Invitation) {
+	sort.SliceStable(invs, func(i, j int) bool {
+		return invs[i].CreatedAt.After(invs[j].CreatedAt)
+	})
+}
+
+// MemoryStore is an in-memory Store for tests and local runs.
+type MemoryStore struct {
+	mu          sync.Mutex
+	invitations map[string]Invitation
+	failures    map[string][]time.Time
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		invitations: map[string]Invitation{},
+		failures:    map[string][]time.Time{},
+	}
+}
+
+func (m *MemoryStore) Create(ctx context.Context, inv *Invitation) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.invitations[inv.ID]; ok {
+		return status.Errorf(codes.AlreadyExists, "invitation %s already exists", inv.ID)
+	}
+	m.invitations[inv.ID] = *inv
+	return nil
+}
+
+func (m *MemoryStore) Get(ctx context.Context, hash string) (*Invitation, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	inv, ok := m.invitations[hash]
+	if !ok {
+		return nil, false, nil
+	}
+	return &inv, true, nil
+}
+
+func (m *MemoryStore) Update(ctx context.Context, hash string, fn func(*Invitation) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	inv, ok := m.invitations[hash]
+	if !ok {
+		return ErrNotFound
+	}
+	if err := fn(&inv); err != nil {
+		return err
+	}
+	m.invitations[hash] = inv
+	return nil
+}
+
+func (m *MemoryStore) ListByStudent(ctx context.Context, studentID string) ([]*Invitation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []*Invitation
+	for _, inv := range m.invitations {
+		if inv.StudentID == studentID {
+			inv := inv
+			out = append(out, &inv)
+		}
+	}
+	sortNewestFirst(out)
+	return out, nil
+}
+
+func (m *MemoryStore) RecordFailure(ctx context.Context, userID string, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.failures[userID] = append(m.failures[userID], at)
+	return nil
+}
+
+func (m *MemoryStore) CountFailuresSince(ctx context.Context, userID string, since time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	count := 0
+	for _, at := range m.failures[userID] {
+		if at.After(since) {
+			count++
+		}
+	}
+	return count, nil
+}
@@ -0,0 +1,109 @@
+// backend/internal/ledger/allocation.go
+
+package ledger
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/firestore"
+)
+
+// StudentHours is one student's share of the family's hours.
+type StudentHours struct {
+	StudentID string `json:"student_id"`
+	// Allocated is nil for students who draw on the shared pool.
+	Allocated *float64 `json:"allocated,omitempty"`
+	Used      float64  `json:"used"`
+	// Remaining is what the student can still use. For students on the shared
+	// pool it is the pool's remaining hours, which their siblings draw on too.
+	Remaining float64 `json:"remaining"`
+}
+
+// Breakdown splits a family's balance between its students. Balances are not
+// clamped: a negative number is an overage.
+type Breakdown struct {
+	FamilyID        string         `json:"family_id"`
+	FamilyRemaining float64        `json:"family_remaining"`
+	SharedPool      float64        `json:"shared_pool"`
+	SharedRemaining float64        `json:"shared_remaining"`
+	Students        []StudentHours `json:"students"`
+}
+
+// Breakdown computes the share of each of the given students.
+//
+// The hours available to the family are its purchases less refunds plus
+// adjustments. Allocations are taken out of that first; the rest is the
+// shared pool, which the students without an allocation use together.
+func (b *Balance) Breakdown(studentIDs []string) Breakdown {
+	available := b.PurchasedHours - b.RefundedHours + b.AdjustedHours
+
+	var allocated, sharedUsed float64
+	for _, id := range studentIDs {
+		if hours, ok := b.Allocations[id]; ok {
+			allocated += hours
+		} else {
+			sharedUsed += b.StudentUsage[id]
+		}
+	}
+
+	out := Breakdown{
+		FamilyID:        b.FamilyID,
+		FamilyRemaining: round(b.Remaining),
+		SharedPool:      round(available - allocated),
+		SharedRemaining: round(available - allocated - sharedUsed),
+		Students:        make([]StudentHours, 0, len(studentIDs)),
+	}
+	for _, id := range studentIDs {
+		sh := StudentHours{StudentID: id, Used: round(b.StudentUsage[id])}
+		if hours, ok := b.Allocations[id]; ok {
+			hours := hours
+			sh.Allocated = &hours
+			sh.Remaining = round(hours - sh.Used)
+		} else {
+			sh.Remaining = out.SharedRemaining
+		}
+		out.Students = append(out.Students, sh)
+	}
+	return out
+}
+
+// Student returns the share of one student, or false if the student is not in the breakdown.
+func (bd Breakdown) Student(studentID string) (StudentHours, bool) {
+	for _, sh := range bd.Students {
+		if sh.StudentID == studentID {
+			return sh, true
+		}
+	}
+	return StudentHours{}, false
+}
+
+// SetAllocation sets aside hours for a student. A nil hours puts the student
+// back on the shared pool. Allocations do not add or remove hours, so they are
+// stored on the balance rather than as entries.
+func (l *Ledger) SetAllocation(ctx context.Context, familyID, studentID string, hours *float64) (*Balance, error) {
+	if err := l.openForWrite(ctx, familyID); err != nil {
+		return nil, err
+	}
+
+	var bal *Balance
+	err := l.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		b, err := readBalance(tx, l.doc(familyID))
+		if err != nil {
+			return err
+		}
+		if hours == nil {
+			delete(b.Allocations, studentID)
+		} else {
+			b.Allocations[studentID] = round(*hours)
+		}
+		b.UpdatedAt = time.Now()
+		if err := tx.Set(l.doc(familyID), b); err != nil {
+			return err
+		}
+		b.FamilyID = familyID
+		bal = b
+		return nil
+	})
+	return bal, err
+}
@@ -0,0 +1,111 @@
+// backend/internal/ledger/handler.go
+
+package ledger
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/middleware"
+	"github.com/gorilla/mux"
+)
+
+// ReconcileHandler handles /api/admin/ledger/{family_id}/reconcile.
+// GET reports what is out of line without writing anything; POST also appends the corrections.
+func (l *Ledger) ReconcileHandler(w http.ResponseWriter, r *http.Request) {
+	familyID := mux.Vars(r)["family_id"]
+	if familyID == "" {
+		http.Error(w, "Family ID is required", http.StatusBadRequest)
+		return
+	}
+
+	report, err := l.Reconcile(r.Context(), familyID, r.Method == http.MethodPost)
+	if errors.Is(err, ErrNoFamily) {
+		http.Error(w, "Family not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error reconciling ledger of family %s: %v", familyID, err)
+		http.Error(w, "Failed to reconcile ledger", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// AppendEntryRequest is the body of POST /api/admin/ledger/{family_id}/entries.
+type AppendEntryRequest struct {
+	Type      EntryType `json:"type"` // "adjustment" or "refund"
+	Hours     float64   `json:"hours"`
+	StudentID string    `json:"student_id"`
+	Note      string    `json:"note"`
+}
+
+// EntriesHandler handles /api/admin/ledger/{family_id}/entries.
+// GET returns the balance and every entry; POST appends an adjustment or a refund.
+func (l *Ledger) EntriesHandler(w http.ResponseWriter, r *http.Request) {
+	familyID := mux.Vars(r)["family_id"]
+	if familyID == "" {
+		http.Error(w, "Family ID is required", http.StatusBadRequest)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		var req AppendEntryRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+		if req.Type != Adjustment && req.Type != Refund {
+			http.Error(w, "Type must be adjustment or refund", http.StatusBadRequest)
+			return
+		}
+		if req.Hours == 0 || req.Note == "" {
+			http.Error(w, "Hours and note are required", http.StatusBadRequest)
+			return
+		}
+		adminID, _ := middleware.ExtractUserIDFromContext(r.Context())
+
+		if _, err := l.Append(r.Context(), familyID, Entry{
+			Type:      req.Type,
+			Hours:     req.Hours,
+			StudentID: req.StudentID,
+			Note:      req.Note,
+			CreatedBy: adminID,
+		}); err != nil {
+			if errors.Is(err, ErrNoFamily) {
+				http.Error(w, "Family not found", http.StatusNotFound)
+				return
+			}
+			log.Printf("Error appending %s to ledger of family %s: %v", req.Type, familyID, err)
+			http.Error(w, "Failed to append entry", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	balance, err := l.Balance(r.Context(), familyID)
+	if errors.Is(err, ErrNoFamily) {
+		http.Error(w, "Family not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error fetching balance of family %s: %v", familyID, err)
+		http.Error(w, "Failed to fetch ledger", http.StatusInternalServerError)
+		return
+	}
+	entries, err := l.Entries(r.Context(), familyID)
+	if err != nil {
+		log.Printf("Error fetching entries of family %s: %v", familyID, err)
+		http.Error(w, "Failed to fetch ledger", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Balance *Balance `json:"balance"`
+		Entries []Entry  `json:"entries"`
+	}{balance, entries})
+}
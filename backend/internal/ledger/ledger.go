@@ -0,0 +1,374 @@
+// backend/internal/ledger/ledger.go
+
+package ledger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/households"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/middleware"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/students"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// The hours ledger is kept per family, where a family is a document in the
+// "households" collection. Each family has a balance document in "hours_ledger"
+// and an append-only "entries" subcollection. Entries are never edited or
+// deleted: changing a session or an invoice appends the difference, so the
+// history of every balance stays visible.
+
+// EntryType is the kind of a ledger entry.
+type EntryType string
+
+const (
+	// Purchase adds hours bought on a QuickBooks invoice.
+	Purchase EntryType = "purchase"
+	// SessionDebit removes the hours of a tutoring session.
+	SessionDebit EntryType = "session_debit"
+	// Adjustment is a manual correction by an admin, positive or negative.
+	Adjustment EntryType = "adjustment"
+	// Refund removes purchased hours that were given back to the family.
+	Refund EntryType = "refund"
+)
+
+// ErrNoFamily is returned when no household owns the student or customer.
+var ErrNoFamily = errors.New("no family found")
+
+// ErrImpersonating is returned by writes made while an admin is impersonating
+// someone, which are read-only.
+var ErrImpersonating = errors.New("the hours ledger is read-only while impersonating")
+
+// Entry is a document in a family's "entries" subcollection. Hours are signed:
+// purchases are positive, session debits and refunds negative.
+type Entry struct {
+	ID        string    `firestore:"-" json:"id"`
+	Type      EntryType `firestore:"type" json:"type"`
+	Hours     float64   `firestore:"hours" json:"hours"`
+	StudentID string    `firestore:"student_id,omitempty" json:"student_id,omitempty"`
+	// Ref identifies what the entry is about, e.g. "session:<student>/<homework>"
+	// or "invoice:<id>". The sum of the entries with one ref is its current value.
+	Ref       string    `firestore:"ref,omitempty" json:"ref,omitempty"`
+	Note      string    `firestore:"note,omitempty" json:"note,omitempty"`
+	CreatedBy string    `firestore:"created_by,omitempty" json:"created_by,omitempty"`
+	CreatedAt time.Time `firestore:"created_at" json:"created_at"`
+}
+
+// Balance is the running total of a family's entries.
+type Balance struct {
+	FamilyID       string  `firestore:"-" json:"family_id"`
+	PurchasedHours float64 `firestore:"purchased_hours" json:"purchased_hours"`
+	RefundedHours  float64 `firestore:"refunded_hours" json:"refunded_hours"`
+	AdjustedHours  float64 `firestore:"adjusted_hours" json:"adjusted_hours"`
+	UsedHours      float64 `firestore:"used_hours" json:"used_hours"`
+	Remaining      float64 `firestore:"remaining_hours" json:"remaining_hours"`
+	// StudentUsage is the number of session hours used by each student.
+	StudentUsage map[string]float64 `firestore:"student_usage" json:"student_usage"`
+	// Allocations are hours set aside for one student. Students without an
+	// allocation share what is left of the family's hours.
+	Allocations map[string]float64 `firestore:"allocations" json:"allocations"`
+	UpdatedAt   time.Time          `firestore:"updated_at" json:"updated_at"`
+}
+
+// Ledger reads and writes the hours ledger.
+type Ledger struct {
+	client   *firestore.Client
+	students students.Repository
+	watchers []func(context.Context, *Balance)
+}
+
+// New returns a Ledger stored in Firestore. The students repository is used to
+// reconcile the ledger against the "Homework Completion" subcollections.
+func New(client *firestore.Client, repo students.Repository) *Ledger {
+	return &Ledger{client: client, students: repo}
+}
+
+// Watch registers fn to be called with the family's balance every time a
+// session, invoice or manual entry is recorded, whether or not it changed
+// anything. It must be called before the ledger is used.
+func (l *Ledger) Watch(fn func(ctx context.Context, b *Balance)) {
+	l.watchers = append(l.watchers, fn)
+}
+
+func (l *Ledger) notify(ctx context.Context, b *Balance, err error) (*Balance, error) {
+	if err != nil {
+		return nil, err
+	}
+	if admin, ok := middleware.ImpersonatorFromContext(ctx); ok {
+		log.Printf("Not notifying watchers of ledger %s: admin %s is impersonating", b.FamilyID, admin)
+		return b, nil
+	}
+	for _, fn := range l.watchers {
+		fn(ctx, b)
+	}
+	return b, nil
+}
+
+// SessionRef is the ref of the entries for one session of a student.
+func SessionRef(studentID, homeworkID string) string {
+	return fmt.Sprintf("session:%s/%s", studentID, homeworkID)
+}
+
+// InvoiceRef is the ref of the entries for one QuickBooks invoice.
+func InvoiceRef(invoiceID string) string {
+	return "invoice:" + invoiceID
+}
+
+func (l *Ledger) doc(familyID string) *firestore.DocumentRef {
+	return l.client.Collection("hours_ledger").Doc(familyID)
+}
+
+// FamilyForStudent returns the family the student's sessions are billed to.
+// If several households have the student, the first one found is used.
+func (l *Ledger) FamilyForStudent(ctx context.Context, studentID string) (string, error) {
+	return l.findFamily(ctx, l.client.Collection(households.Collection).Where("students", "array-contains", studentID))
+}
+
+// FamilyForCustomer returns the family linked to a QuickBooks customer.
+func (l *Ledger) FamilyForCustomer(ctx context.Context, customerID string) (string, error) {
+	return l.findFamily(ctx, l.client.Collection(households.Collection).Where("qbo_customer_id", "==", customerID))
+}
+
+func (l *Ledger) findFamily(ctx context.Context, q firestore.Query) (string, error) {
+	snaps, err := q.Limit(1).Documents(ctx).GetAll()
+	if err != nil {
+		return "", err
+	}
+	if len(snaps) == 0 {
+		return "", ErrNoFamily
+	}
+	return snaps[0].Ref.ID, nil
+}
+
+// Balance returns the family's balance. A family whose ledger has not been
+// opened yet is first reconciled from its sessions and invoices. While an
+// admin is impersonating, such a family gets the balance the reconciliation
+// would produce, and the ledger is left unopened.
+func (l *Ledger) Balance(ctx context.Context, familyID string) (*Balance, error) {
+	preview, err := l.open(ctx, familyID)
+	if err != nil {
+		return nil, err
+	}
+	if preview != nil {
+		return preview, nil
+	}
+	snap, err := l.doc(familyID).Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return decodeBalance(familyID, snap)
+}
+
+// Entries returns the family's entries, oldest first.
+func (l *Ledger) Entries(ctx context.Context, familyID string) ([]Entry, error) {
+	snaps, err := l.doc(familyID).Collection("entries").OrderBy("created_at", firestore.Asc).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]Entry, 0, len(snaps))
+	for _, snap := range snaps {
+		var e Entry
+		if err := snap.DataTo(&e); err != nil {
+			return nil, fmt.Errorf("decode entry %s: %w", snap.Ref.ID, err)
+		}
+		e.ID = snap.Ref.ID
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// SetSessionHours makes the session count for hours against the family.
+// Deleting a session is setting it to zero.
+func (l *Ledger) SetSessionHours(ctx context.Context, familyID, studentID, homeworkID string, hours float64) (*Balance, error) {
+	if err := l.openForWrite(ctx, familyID); err != nil {
+		return nil, err
+	}
+	bal, err := l.settle(ctx, familyID, Entry{
+		Type:      SessionDebit,
+		StudentID: studentID,
+		Ref:       SessionRef(studentID, homeworkID),
+	}, -hours)
+	return l.notify(ctx, bal, err)
+}
+
+// SetPurchaseHours makes the invoice count for hours purchased by the family.
+// Deleting an invoice is setting it to zero.
+func (l *Ledger) SetPurchaseHours(ctx context.Context, familyID, invoiceID string, hours float64) (*Balance, error) {
+	if err := l.openForWrite(ctx, familyID); err != nil {
+		return nil, err
+	}
+	bal, err := l.settle(ctx, familyID, Entry{
+		Type: Purchase,
+		Ref:  InvoiceRef(invoiceID),
+	}, hours)
+	return l.notify(ctx, bal, err)
+}
+
+// Append adds an adjustment or a refund to the family's ledger.
+func (l *Ledger) Append(ctx context.Context, familyID string, e Entry) (*Balance, error) {
+	if e.Type != Adjustment && e.Type != Refund {
+		return nil, fmt.Errorf("entries of type %q cannot be appended by hand", e.Type)
+	}
+	if e.Type == Refund && e.Hours > 0 {
+		e.Hours = -e.Hours
+	}
+	if err := l.openForWrite(ctx, familyID); err != nil {
+		return nil, err
+	}
+
+	var bal *Balance
+	err := l.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		b, err := readBalance(tx, l.doc(familyID))
+		if err != nil {
+			return err
+		}
+		bal, err = l.write(tx, familyID, b, e)
+		return err
+	})
+	return l.notify(ctx, bal, err)
+}
+
+// settle appends whatever is needed for the entries with e.Ref to add up to
+// target. It appends nothing when they already do, so it is safe to repeat.
+func (l *Ledger) settle(ctx context.Context, familyID string, e Entry, target float64) (*Balance, error) {
+	famRef := l.doc(familyID)
+
+	var bal *Balance
+	err := l.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		b, err := readBalance(tx, famRef)
+		if err != nil {
+			return err
+		}
+		snaps, err := tx.Documents(famRef.Collection("entries").Where("ref", "==", e.Ref)).GetAll()
+		if err != nil {
+			return err
+		}
+		var current float64
+		for _, snap := range snaps {
+			var prior Entry
+			if err := snap.DataTo(&prior); err != nil {
+				return fmt.Errorf("decode entry %s: %w", snap.Ref.ID, err)
+			}
+			current += prior.Hours
+		}
+
+		delta := round(target - current)
+		if delta == 0 {
+			bal = b
+			return nil
+		}
+		entry := e
+		entry.Hours = delta
+		bal, err = l.write(tx, familyID, b, entry)
+		return err
+	})
+	return bal, err
+}
+
+// write appends e and stores the updated balance. Reads must be done before calling it.
+func (l *Ledger) write(tx *firestore.Transaction, familyID string, b *Balance, e Entry) (*Balance, error) {
+	e.CreatedAt = time.Now()
+	e.Hours = round(e.Hours)
+	b.add(e)
+	b.UpdatedAt = e.CreatedAt
+
+	famRef := l.doc(familyID)
+	if err := tx.Create(famRef.Collection("entries").NewDoc(), e); err != nil {
+		return nil, err
+	}
+	if err := tx.Set(famRef, b); err != nil {
+		return nil, err
+	}
+	log.Printf("Ledger %s: %s %+.2f hours (%s), remaining %.2f", familyID, e.Type, e.Hours, e.Ref, b.Remaining)
+	b.FamilyID = familyID
+	return b, nil
+}
+
+// open reconciles a family the first time its ledger is used, so that the
+// hours recorded before the ledger existed are carried over. While an admin is
+// impersonating, it reconciles without applying anything and returns the
+// balance that would result; otherwise the returned balance is nil.
+func (l *Ledger) open(ctx context.Context, familyID string) (*Balance, error) {
+	_, err := l.doc(familyID).Get(ctx)
+	if err == nil {
+		return nil, nil
+	}
+	if status.Code(err) != codes.NotFound {
+		return nil, err
+	}
+	if middleware.Impersonating(ctx) {
+		report, err := l.Reconcile(ctx, familyID, false)
+		if err != nil {
+			return nil, err
+		}
+		return report.preview(), nil
+	}
+	log.Printf("Opening hours ledger for family %s", familyID)
+	_, err = l.Reconcile(ctx, familyID, true)
+	return nil, err
+}
+
+// openForWrite opens the family's ledger before an entry or allocation is
+// written, refusing while an admin is impersonating.
+func (l *Ledger) openForWrite(ctx context.Context, familyID string) error {
+	if middleware.Impersonating(ctx) {
+		return ErrImpersonating
+	}
+	_, err := l.open(ctx, familyID)
+	return err
+}
+
+// add counts the hours of e in the balance.
+func (b *Balance) add(e Entry) {
+	switch e.Type {
+	case Purchase:
+		b.PurchasedHours = round(b.PurchasedHours + e.Hours)
+	case Refund:
+		b.RefundedHours = round(b.RefundedHours - e.Hours)
+	case Adjustment:
+		b.AdjustedHours = round(b.AdjustedHours + e.Hours)
+	case SessionDebit:
+		b.UsedHours = round(b.UsedHours - e.Hours)
+		if e.StudentID != "" {
+			b.StudentUsage[e.StudentID] = round(b.StudentUsage[e.StudentID] - e.Hours)
+		}
+	}
+	b.Remaining = round(b.Remaining + e.Hours)
+}
+
+func readBalance(tx *firestore.Transaction, ref *firestore.DocumentRef) (*Balance, error) {
+	snap, err := tx.Get(ref)
+	if status.Code(err) == codes.NotFound {
+		return &Balance{FamilyID: ref.ID, StudentUsage: map[string]float64{}, Allocations: map[string]float64{}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodeBalance(ref.ID, snap)
+}
+
+func decodeBalance(familyID string, snap *firestore.DocumentSnapshot) (*Balance, error) {
+	var b Balance
+	if err := snap.DataTo(&b); err != nil {
+		return nil, fmt.Errorf("decode balance of %s: %w", familyID, err)
+	}
+	b.FamilyID = familyID
+	if b.StudentUsage == nil {
+		b.StudentUsage = map[string]float64{}
+	}
+	if b.Allocations == nil {
+		b.Allocations = map[string]float64{}
+	}
+	return &b, nil
+}
+
+// round drops the float noise that adding quarter hours accumulates.
+func round(hours float64) float64 {
+	return math.Round(hours*1e6) / 1e6
+}
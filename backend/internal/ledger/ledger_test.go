@@ -0,0 +1,71 @@
+// backend/internal/ledger/ledger_test.go
+
+package ledger
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/middleware"
+)
+
+// A Ledger without a Firestore client is enough here: everything under test
+// has to give up before it reads or writes anything.
+func TestWritesRefusedWhileImpersonating(t *testing.T) {
+	ctx := middleware.WithImpersonator(context.Background(), "admin-1")
+	l := &Ledger{}
+	hours := 2.0
+
+	writes := map[string]func() error{
+		"session": func() error {
+			_, err := l.SetSessionHours(ctx, "family", "student-1", "10-01-2024", 1.5)
+			return err
+		},
+		"purchase": func() error {
+			_, err := l.SetPurchaseHours(ctx, "family", "1042", 10)
+			return err
+		},
+		"adjustment": func() error {
+			_, err := l.Append(ctx, "family", Entry{Type: Adjustment, Hours: 1})
+			return err
+		},
+		"allocation": func() error {
+			_, err := l.SetAllocation(ctx, "family", "student-1", &hours)
+			return err
+		},
+		"applied reconciliation": func() error {
+			_, err := l.Reconcile(ctx, "family", true)
+			return err
+		},
+	}
+	for name, write := range writes {
+		t.Run(name, func(t *testing.T) {
+			if err := write(); !errors.Is(err, ErrImpersonating) {
+				t.Errorf("error = %v, want %v", err, ErrImpersonating)
+			}
+		})
+	}
+}
+
+func TestNotifySkippedWhileImpersonating(t *testing.T) {
+	l := &Ledger{}
+	var notified int
+	l.Watch(func(ctx context.Context, b *Balance) { notified++ })
+	b := &Balance{FamilyID: "family", Remaining: -1}
+
+	if got, err := l.notify(context.Background(), b, nil); got != b || err != nil {
+		t.Fatalf("notify = %v, %v", got, err)
+	}
+	if notified != 1 {
+		t.Fatalf("watchers called %d times, want 1", notified)
+	}
+
+	ctx := middleware.WithImpersonator(context.Background(), "admin-1")
+	if got, err := l.notify(ctx, b, nil); got != b || err != nil {
+		t.Fatalf("notify while impersonating = %v, %v", got, err)
+	}
+	if notified != 1 {
+		t.Errorf("watchers called while impersonating")
+	}
+}
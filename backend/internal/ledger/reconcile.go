@@ -0,0 +1,234 @@
+// backend/internal/ledger/reconcile.go
+
+package ledger
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/households"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/middleware"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// UnparseableRow is a session whose duration is not a number. It is left out
+// of the ledger until someone fixes the duration.
+type UnparseableRow struct {
+	StudentID  string `json:"student_id"`
+	HomeworkID string `json:"homework_id"`
+	Date       string `json:"date"`
+	Duration   string `json:"duration"`
+}
+
+// UnparseableInvoice is an invoice whose hoursPurchased is not a number. Like
+// an unparseable session, it is left out of the ledger until it is fixed.
+type UnparseableInvoice struct {
+	InvoiceID      string `json:"invoice_id"`
+	HoursPurchased string `json:"hours_purchased"`
+}
+
+// Correction is a ref whose entries do not add up to what the source data says.
+type Correction struct {
+	Ref           string    `json:"ref"`
+	Type          EntryType `json:"type"`
+	StudentID     string    `json:"student_id,omitempty"`
+	LedgerHours   float64   `json:"ledger_hours"`
+	ExpectedHours float64   `json:"expected_hours"`
+}
+
+// Report is the result of reconciling a family's ledger.
+type Report struct {
+	FamilyID            string               `json:"family_id"`
+	Unparseable         []UnparseableRow     `json:"unparseable"`
+	UnparseableInvoices []UnparseableInvoice `json:"unparseable_invoices"`
+	Corrections         []Correction         `json:"corrections"`
+	Applied             bool                 `json:"applied"`
+	Balance             *Balance             `json:"balance,omitempty"`
+}
+
+// Reconcile compares the family's ledger with the "Homework Completion"
+// sessions of its students and the invoices of its QuickBooks customer.
+// With apply set, it appends the entries needed to make them agree, which is
+// refused while an admin is impersonating.
+func (l *Ledger) Reconcile(ctx context.Context, familyID string, apply bool) (*Report, error) {
+	if apply && middleware.Impersonating(ctx) {
+		return nil, ErrImpersonating
+	}
+	householdSnap, err := l.client.Collection(households.Collection).Doc(familyID).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, ErrNoFamily
+	}
+	if err != nil {
+		return nil, err
+	}
+	var household households.Household
+	if err := householdSnap.DataTo(&household); err != nil {
+		return nil, fmt.Errorf("decode household %s: %w", familyID, err)
+	}
+
+	report := &Report{FamilyID: familyID, Unparseable: []UnparseableRow{}, UnparseableInvoices: []UnparseableInvoice{}, Corrections: []Correction{}}
+
+	// What the ledger currently says, per ref.
+	ledgerHours := map[string]float64{}
+	ledgerEntries := map[string]Entry{}
+	entrySnaps, err := l.doc(familyID).Collection("entries").Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	for _, snap := range entrySnaps {
+		var e Entry
+		if err := snap.DataTo(&e); err != nil {
+			return nil, fmt.Errorf("decode entry %s: %w", snap.Ref.ID, err)
+		}
+		if e.Ref == "" {
+			continue
+		}
+		ledgerHours[e.Ref] += e.Hours
+		ledgerEntries[e.Ref] = e
+	}
+
+	// What the sessions and invoices say.
+	expected := map[string]Correction{}
+	skipped := map[string]bool{}
+
+	for _, studentID := range household.StudentIDs {
+		if studentID == "" {
+			continue
+		}
+		sessions, err := l.students.ListHomework(ctx, studentID)
+		if err != nil {
+			return nil, fmt.Errorf("list sessions of %s: %w", studentID, err)
+		}
+		for _, hw := range sessions {
+			ref := SessionRef(studentID, hw.ID)
+			hours, ok := hw.Hours()
+			if !ok {
+				report.Unparseable = append(report.Unparseable, UnparseableRow{
+					StudentID:  studentID,
+					HomeworkID: hw.ID,
+					Date:       hw.Date,
+					Duration:   hw.Duration,
+				})
+				skipped[ref] = true
+				continue
+			}
+			expected[ref] = Correction{Ref: ref, Type: SessionDebit, StudentID: studentID, ExpectedHours: -hours}
+		}
+	}
+
+	if customerID := household.QBOCustomerID; customerID != "" {
+		invoices, err := l.client.Collection("intuit").Doc(customerID).Collection("invoices").Documents(ctx).GetAll()
+		if err != nil {
+			return nil, fmt.Errorf("list invoices of %s: %w", customerID, err)
+		}
+		for _, snap := range invoices {
+			ref := InvoiceRef(snap.Ref.ID)
+			raw := snap.Data()["hoursPurchased"]
+			hours, ok := purchasedHours(raw)
+			if !ok {
+				report.UnparseableInvoices = append(report.UnparseableInvoices, UnparseableInvoice{
+					InvoiceID:      snap.Ref.ID,
+					HoursPurchased: fmt.Sprint(raw),
+				})
+				skipped[ref] = true
+				continue
+			}
+			expected[ref] = Correction{Ref: ref, Type: Purchase, ExpectedHours: hours}
+		}
+	}
+
+	// Sessions and invoices that were removed should count for nothing.
+	for ref, e := range ledgerEntries {
+		if _, ok := expected[ref]; ok || skipped[ref] {
+			continue
+		}
+		if !strings.HasPrefix(ref, "session:") && !strings.HasPrefix(ref, "invoice:") {
+			continue
+		}
+		expected[ref] = Correction{Ref: ref, Type: e.Type, StudentID: e.StudentID}
+	}
+
+	for ref, c := range expected {
+		c.LedgerHours = round(ledgerHours[ref])
+		c.ExpectedHours = round(c.ExpectedHours)
+		if c.LedgerHours != c.ExpectedHours {
+			report.Corrections = append(report.Corrections, c)
+		}
+	}
+	sort.Slice(report.Corrections, func(i, j int) bool { return report.Corrections[i].Ref < report.Corrections[j].Ref })
+
+	if !apply {
+		return report, nil
+	}
+
+	for _, c := range report.Corrections {
+		if _, err := l.settle(ctx, familyID, Entry{
+			Type:      c.Type,
+			StudentID: c.StudentID,
+			Ref:       c.Ref,
+			Note:      "reconciliation",
+		}, c.ExpectedHours); err != nil {
+			return nil, fmt.Errorf("settle %s: %w", c.Ref, err)
+		}
+	}
+	report.Applied = true
+
+	// A family with nothing to carry over still gets a balance document, so
+	// that it is not reconciled again on every read.
+	_, err = l.doc(familyID).Create(ctx, &Balance{StudentUsage: map[string]float64{}, Allocations: map[string]float64{}, UpdatedAt: time.Now()})
+	if err != nil && status.Code(err) != codes.AlreadyExists {
+		return nil, err
+	}
+
+	snap, err := l.doc(familyID).Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if report.Balance, err = decodeBalance(familyID, snap); err != nil {
+		return nil, err
+	}
+	// Watchers only see the final balance, not the steps in between, which
+	// can dip below zero when sessions are settled before the invoices.
+	l.notify(ctx, report.Balance, nil)
+	return report, nil
+}
+
+// preview returns the balance of a new ledger once the corrections are
+// applied, without writing anything.
+func (r *Report) preview() *Balance {
+	b := &Balance{FamilyID: r.FamilyID, StudentUsage: map[string]float64{}, Allocations: map[string]float64{}}
+	for _, c := range r.Corrections {
+		b.add(Entry{Type: c.Type, StudentID: c.StudentID, Hours: round(c.ExpectedHours - c.LedgerHours)})
+	}
+	return b
+}
+
+// purchasedHours reads an invoice's hoursPurchased. The webhook leaves it out
+// for invoices without tutoring hours, and Firestore hands back whole numbers
+// written by other clients as int64. ok is false for anything that is not a
+// number.
+func purchasedHours(v interface{}) (hours float64, ok bool) {
+	switch t := v.(type) {
+	case nil:
+		return 0, true
+	case float64:
+		return t, true
+	case int64:
+		return float64(t), true
+	case int:
+		return float64(t), true
+	case string:
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(t), 64)
+		if err != nil {
+			return 0, false
+		}
+		return parsed, true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,50 @@
+// backend/internal/ledger/reconcile_test.go
+
+package ledger
+
+import "testing"
+
+func TestPurchasedHours(t *testing.T) {
+	tests := []struct {
+		name   string
+		v      interface{}
+		want   float64
+		wantOK bool
+	}{
+		{"missing", nil, 0, true},
+		{"float", 7.5, 7.5, true},
+		{"integer", int64(10), 10, true},
+		{"int", 4, 4, true},
+		{"numeric string", " 2.25 ", 2.25, true},
+		{"text", "ten", 0, false},
+		{"empty string", "", 0, false},
+		{"boolean", true, 0, false},
+		{"map", map[string]interface{}{"hours": 10}, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := purchasedHours(tt.v)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("purchasedHours(%#v) = %v, %v; want %v, %v", tt.v, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestReportPreview(t *testing.T) {
+	report := &Report{
+		FamilyID: "family",
+		Corrections: []Correction{
+			{Ref: InvoiceRef("1042"), Type: Purchase, ExpectedHours: 10},
+			{Ref: SessionRef("student-1", "10-01-2024"), Type: SessionDebit, StudentID: "student-1", ExpectedHours: -1.5},
+			{Ref: SessionRef("student-2", "10-02-2024"), Type: SessionDebit, StudentID: "student-2", ExpectedHours: -2, LedgerHours: -0.5},
+		},
+	}
+	b := report.preview()
+	if b.FamilyID != "family" || b.PurchasedHours != 10 || b.UsedHours != 3 || b.Remaining != 7 {
+		t.Errorf("preview = %+v, want 10 purchased, 3 used, 7 remaining", b)
+	}
+	if b.StudentUsage["student-1"] != 1.5 || b.StudentUsage["student-2"] != 1.5 {
+		t.Errorf("student usage = %v", b.StudentUsage)
+	}
+}
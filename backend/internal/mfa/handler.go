@@ -0,0 +1,355 @@
+// backend/internal/mfa/handler.go
+
+package mfa
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/audit"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/middleware"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/securitylog"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/session"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/staff"
+	"github.com/gorilla/mux"
+)
+
+// App holds the dependencies of the MFA handlers.
+type App struct {
+	MFA      *Manager
+	Sessions *session.Manager
+	// Staff gives the directory role of the caller, which the policy is
+	// checked against, and signs out staff whose role becomes required.
+	Staff *staff.Directory
+	Audit audit.Log
+	// Security records sign-ins and wrong codes.
+	Security *securitylog.Recorder
+}
+
+type enrollResponse struct {
+	Secret string `json:"secret"`
+	// URI is the otpauth:// URI to show as a QR code.
+	URI string `json:"uri"`
+}
+
+type recoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type loginResponse struct {
+	Token string `json:"token"`
+	// Next is the app path the sign-in was started from, if any.
+	Next string `json:"next,omitempty"`
+	// RecoveryCodes are set when the sign-in enrolled a new authenticator.
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+}
+
+type codeRequest struct {
+	Challenge string `json:"challenge"`
+	Code      string `json:"code"`
+}
+
+func decodeCode(w http.ResponseWriter, r *http.Request) (*codeRequest, bool) {
+	var req codeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return nil, false
+	}
+	return &req, true
+}
+
+// ChallengeEnrollHandler handles POST /api/auth/mfa/enroll with
+// {"challenge": "..."}, for staff whose role requires an authenticator and
+// who have none yet. It returns the secret to add to their app.
+func (a *App) ChallengeEnrollHandler(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeCode(w, r)
+	if !ok {
+		return
+	}
+	c, err := a.MFA.Challenge(r.Context(), req.Challenge)
+	if err != nil {
+		writeError(w, "load sign-in challenge", "", err)
+		return
+	}
+	if !c.Enroll {
+		http.Error(w, "An authenticator is already enrolled", http.StatusConflict)
+		return
+	}
+	a.enroll(w, r, c.Login.UserID, c.Login.Email)
+}
+
+// ChallengeVerifyHandler handles POST /api/auth/mfa/verify with
+// {"challenge": "...", "code": "123456"}. The code may also be a recovery
+// code. On success the session starts as any other sign-in does, and
+// {"token": "<access token>"} is returned.
+func (a *App) ChallengeVerifyHandler(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeCode(w, r)
+	if !ok {
+		return
+	}
+	login, recoveryCodes, err := a.MFA.Complete(r.Context(), req.Challenge, req.Code)
+	if err != nil {
+		if login != nil {
+			a.Security.RecordRequest(r, securitylog.Event{
+				Type:     securitylog.LoginFailed,
+				UserID:   login.UserID,
+				Email:    login.Email,
+				Role:     login.Role,
+				Provider: login.Provider,
+				Detail:   err.Error(),
+			})
+		}
+		writeError(w, "complete sign-in challenge", "", err)
+		return
+	}
+	if recoveryCodes != nil {
+		log.Printf("Staff %s enrolled an authenticator while signing in", login.UserID)
+	}
+
+	token, err := a.Sessions.Login(w, r, session.User{UserID: login.UserID, Email: login.Email, Role: login.Role})
+	if err != nil {
+		log.Printf("Failed to start session: %v", err)
+		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("User authenticated with MFA: %s (%s), role: %s", login.UserID, login.Email, login.Role)
+	a.Security.RecordRequest(r, securitylog.Event{
+		Type:     securitylog.Login,
+		UserID:   login.UserID,
+		Email:    login.Email,
+		Role:     login.Role,
+		Provider: login.Provider,
+		Detail:   "with authenticator",
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(loginResponse{Token: token, Next: login.ReturnTo, RecoveryCodes: recoveryCodes})
+}
+
+// StatusHandler handles /api/tutor/mfa. GET returns whether the caller has
+// an authenticator and whether their role requires one; DELETE with
+// {"code": "123456"} removes their authenticator, unless it is required.
+func (a *App) StatusHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, _ := middleware.ExtractUserIDFromContext(ctx)
+	staffRole, err := a.staffRole(ctx)
+	if err != nil {
+		log.Printf("Error looking up staff role of %s: %v", userID, err)
+		http.Error(w, "Failed to load staff member", http.StatusInternalServerError)
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		req, ok := decodeCode(w, r)
+		if !ok {
+			return
+		}
+		s, err := a.MFA.Status(ctx, userID, staffRole)
+		if err != nil {
+			writeError(w, "load authenticator", userID, err)
+			return
+		}
+		if s.Required {
+			writeError(w, "disable authenticator", userID, ErrRequired)
+			return
+		}
+		if err := a.MFA.Verify(ctx, userID, req.Code); err != nil {
+			writeError(w, "disable authenticator", userID, err)
+			return
+		}
+		if err := a.MFA.Disable(ctx, userID); err != nil {
+			writeError(w, "disable authenticator", userID, err)
+			return
+		}
+		log.Printf("Staff %s removed their authenticator", userID)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	s, err := a.MFA.Status(ctx, userID, staffRole)
+	if err != nil {
+		writeError(w, "load authenticator", userID, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s)
+}
+
+// EnrollHandler handles POST /api/tutor/mfa/enroll, starting to enrol an
+// authenticator for the signed-in staff member. It returns the secret to
+// add to their app; ConfirmHandler turns it on.
+func (a *App) EnrollHandler(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.ExtractUserIDFromContext(r.Context())
+	claims, _ := middleware.GetUserFromContext(r.Context())
+	email, _ := claims["email"].(string)
+	a.enroll(w, r, userID, email)
+}
+
+// ConfirmHandler handles POST /api/tutor/mfa/confirm with
+// {"code": "123456"}, the first code from the new authenticator. It returns
+// the recovery codes, which are only shown this once.
+func (a *App) ConfirmHandler(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeCode(w, r)
+	if !ok {
+		return
+	}
+	userID, _ := middleware.ExtractUserIDFromContext(r.Context())
+	codes, err := a.MFA.Confirm(r.Context(), userID, req.Code)
+	if err != nil {
+		writeError(w, "confirm authenticator", userID, err)
+		return
+	}
+	log.Printf("Staff %s enrolled an authenticator", userID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(recoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// RecoveryCodesHandler handles POST /api/tutor/mfa/recovery-codes with
+// {"code": "123456"}, replacing the caller's recovery codes with new ones.
+func (a *App) RecoveryCodesHandler(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeCode(w, r)
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+	userID, _ := middleware.ExtractUserIDFromContext(ctx)
+	if err := a.MFA.Verify(ctx, userID, req.Code); err != nil {
+		writeError(w, "regenerate recovery codes", userID, err)
+		return
+	}
+	codes, err := a.MFA.RegenerateRecoveryCodes(ctx, userID)
+	if err != nil {
+		writeError(w, "regenerate recovery codes", userID, err)
+		return
+	}
+	log.Printf("Staff %s regenerated their recovery codes", userID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(recoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// PolicyHandler handles /api/admin/mfa/policy. GET returns the roles that
+// must use an authenticator; PUT with {"required_roles": ["admin"]}
+// replaces them. Staff in a newly required role are signed out, so that
+// they set up an authenticator when they sign in again.
+func (a *App) PolicyHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if r.Method != http.MethodPut {
+		p, err := a.MFA.Policy(ctx)
+		if err != nil {
+			log.Printf("Error loading MFA policy: %v", err)
+			http.Error(w, "Failed to load MFA policy", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(p)
+		return
+	}
+
+	var req struct {
+		RequiredRoles []string `json:"required_roles"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	adminID, _ := middleware.ExtractUserIDFromContext(ctx)
+	p, added, err := a.MFA.SetPolicy(ctx, req.RequiredRoles, adminID)
+	if errors.Is(err, ErrInvalidRole) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("Error saving MFA policy: %v", err)
+		http.Error(w, "Failed to save MFA policy", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Admin %s set the roles that require MFA to %v", adminID, p.RequiredRoles)
+	a.record(ctx, audit.Event{
+		Action:  audit.MFAPolicyChange,
+		ActorID: adminID,
+		Reason:  "required roles: " + strings.Join(p.RequiredRoles, ", "),
+	})
+	if len(added) > 0 {
+		if err := a.Staff.SignOut(ctx, "MFA now required", added...); err != nil {
+			log.Printf("Error signing out staff in roles %v: %v", added, err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(p)
+}
+
+// ResetHandler handles DELETE /api/admin/mfa/users/{user_id}, removing the
+// authenticator of a staff member who lost theirs. If their role requires
+// one they set up a new one when they next sign in.
+func (a *App) ResetHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := mux.Vars(r)["user_id"]
+	if userID == "" {
+		http.Error(w, "User ID is required", http.StatusBadRequest)
+		return
+	}
+	adminID, _ := middleware.ExtractUserIDFromContext(ctx)
+	if err := a.MFA.Disable(ctx, userID); err != nil {
+		writeError(w, "reset authenticator", userID, err)
+		return
+	}
+	log.Printf("Admin %s reset the authenticator of %s", adminID, userID)
+	a.record(ctx, audit.Event{Action: audit.MFAReset, ActorID: adminID, TargetID: userID})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *App) enroll(w http.ResponseWriter, r *http.Request, userID, email string) {
+	secret, uri, err := a.MFA.Enroll(r.Context(), userID, email)
+	if err != nil {
+		writeError(w, "enrol authenticator", userID, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(enrollResponse{Secret: secret, URI: uri})
+}
+
+// staffRole returns the staff directory role of the caller.
+func (a *App) staffRole(ctx context.Context) (string, error) {
+	claims, _ := middleware.GetUserFromContext(ctx)
+	email, _ := claims["email"].(string)
+	member, found, err := a.Staff.Lookup(ctx, email)
+	if err != nil || !found {
+		return "", err
+	}
+	return member.Role, nil
+}
+
+// record writes e to the audit log. A failure is logged: what the admin did
+// has already happened.
+func (a *App) record(ctx context.Context, e audit.Event) {
+	if err := a.Audit.Record(ctx, e); err != nil {
+		log.Printf("Error recording %s by %s in the audit log: %v", e.Action, e.ActorID, err)
+	}
+}
+
+func writeError(w http.ResponseWriter, action, userID string, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidCode):
+		http.Error(w, "Invalid code", http.StatusBadRequest)
+	case errors.Is(err, ErrChallengeNotFound):
+		http.Error(w, "Sign-in expired. Please sign in again.", http.StatusUnauthorized)
+	case errors.Is(err, ErrTooManyAttempts):
+		http.Error(w, "Too many invalid codes. Please sign in again.", http.StatusTooManyRequests)
+	case errors.Is(err, ErrNotEnrolled):
+		http.Error(w, "No authenticator is enrolled", http.StatusNotFound)
+	case errors.Is(err, ErrAlreadyEnrolled):
+		http.Error(w, "An authenticator is already enrolled", http.StatusConflict)
+	case errors.Is(err, ErrRequired):
+		http.Error(w, "Your role requires an authenticator", http.StatusForbidden)
+	default:
+		log.Printf("Error trying to %s for %q: %v", action, userID, err)
+		http.Error(w, "Failed to "+action, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,457 @@
+// backend/internal/mfa/mfa.go
+
+package mfa
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/middleware"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/tokencrypt"
+)
+
+// Staff can protect their account with an authenticator app. Once they have
+// enrolled one, or once an admin requires it for their role, signing in with
+// a provider is not enough: the login waits as a challenge until they enter
+// a code from the app, or one of their recovery codes, and only then is a
+// session started. Secrets are stored encrypted with the token keyring and
+// recovery codes only as hashes.
+
+const (
+	// ChallengeTTL is how long a staff member has to enter their code after
+	// signing in with a provider.
+	ChallengeTTL = 5 * time.Minute
+	// maxChallengeAttempts is how many wrong codes end a challenge, after
+	// which the staff member has to sign in with their provider again.
+	maxChallengeAttempts = 5
+
+	recoveryCodeCount = 10
+	// recoveryAlphabet leaves out 0, O, 1 and I, which are easily mistyped.
+	recoveryAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	recoveryLength   = 10
+	recoveryGroup    = 5
+)
+
+var (
+	ErrNotEnrolled     = errors.New("no authenticator enrolled")
+	ErrAlreadyEnrolled = errors.New("an authenticator is already enrolled")
+	ErrInvalidCode     = errors.New("invalid authentication code")
+	// ErrChallengeNotFound is returned for a challenge that does not exist,
+	// has expired or was already completed.
+	ErrChallengeNotFound = errors.New("sign-in challenge not found or expired")
+	ErrTooManyAttempts   = errors.New("too many invalid authentication codes")
+	// ErrRequired is returned when disabling an authenticator the staff
+	// member's role requires.
+	ErrRequired    = errors.New("an authenticator is required for this role")
+	ErrInvalidRole = errors.New("invalid staff role")
+)
+
+// Enrollment is a document in the "mfa_enrollments" collection, keyed by
+// user ID.
+type Enrollment struct {
+	UserID string `firestore:"-" json:"user_id"`
+	// Secret is the TOTP secret, encrypted.
+	Secret string `firestore:"secret" json:"-"`
+	// Confirmed is set once a code from the app has been entered. Until then
+	// the enrollment does not protect anything.
+	Confirmed bool `firestore:"confirmed" json:"confirmed"`
+	// RecoveryHashes are the hashes of the recovery codes not used yet.
+	RecoveryHashes []string `firestore:"recovery_hashes" json:"-"`
+	// LastStep is the time step of the last code used, so no code works twice.
+	LastStep    int64      `firestore:"last_step" json:"-"`
+	CreatedAt   time.Time  `firestore:"created_at" json:"created_at"`
+	ConfirmedAt *time.Time `firestore:"confirmed_at,omitempty" json:"confirmed_at,omitempty"`
+}
+
+// Policy is which staff roles must use an authenticator. It is the "mfa"
+// document of the "settings" collection.
+type Policy struct {
+	// RequiredRoles are staff directory roles: tutor, team_lead or admin.
+	RequiredRoles []string  `firestore:"required_roles" json:"required_roles"`
+	UpdatedBy     string    `firestore:"updated_by,omitempty" json:"updated_by,omitempty"`
+	UpdatedAt     time.Time `firestore:"updated_at" json:"updated_at"`
+}
+
+// Requires reports whether staffRole must use an authenticator.
+func (p *Policy) Requires(staffRole string) bool {
+	for _, r := range p.RequiredRoles {
+		if r == staffRole {
+			return true
+		}
+	}
+	return false
+}
+
+// Login is a provider sign-in waiting for its second factor.
+type Login struct {
+	UserID string `firestore:"user_id"`
+	Email  string `firestore:"email"`
+	// Role is the role the session will carry.
+	Role string `firestore:"role"`
+	// StaffRole is the staff directory role the policy is checked against.
+	StaffRole string `firestore:"staff_role"`
+	// Provider is the login provider the sign-in started with.
+	Provider string `firestore:"provider,omitempty"`
+	// ReturnTo is the app path to go to once signed in.
+	ReturnTo string `firestore:"return_to,omitempty"`
+}
+
+// Challenge is a document in the "mfa_challenges" collection, keyed by the
+// hash of the token the browser holds. Its expires_at field can drive a
+// Firestore TTL policy for challenges that were never completed.
+type Challenge struct {
+	ID    string `firestore:"-"`
+	Login Login  `firestore:"login"`
+	// Enroll is set when the staff member has no authenticator yet and has
+	// to set one up before they can sign in.
+	Enroll    bool      `firestore:"enroll"`
+	Attempts  int       `firestore:"attempts"`
+	CreatedAt time.Time `firestore:"created_at"`
+	ExpiresAt time.Time `firestore:"expires_at"`
+}
+
+// Status is what a staff member sees of their own authenticator.
+type Status struct {
+	Enrolled          bool `json:"enrolled"`
+	Required          bool `json:"required"`
+	RecoveryCodesLeft int  `json:"recovery_codes_left"`
+}
+
+// Manager enrols authenticators and checks their codes.
+type Manager struct {
+	store Store
+	keys  *tokencrypt.Keyring
+	now   func() time.Time
+}
+
+// NewManager returns a Manager over store. Secrets are encrypted with keys.
+func NewManager(store Store, keys *tokencrypt.Keyring) *Manager {
+	return &Manager{store: store, keys: keys, now: time.Now}
+}
+
+// Policy returns the roles that must use an authenticator.
+func (m *Manager) Policy(ctx context.Context) (*Policy, error) {
+	return m.store.GetPolicy(ctx)
+}
+
+// SetPolicy replaces the roles that must use an authenticator and returns
+// the new policy along with the roles that were not required before.
+func (m *Manager) SetPolicy(ctx context.Context, roles []string, adminID string) (*Policy, []string, error) {
+	required := []string{}
+	for _, r := range roles {
+		switch r {
+		case middleware.RoleTutor, middleware.RoleTeamLead, middleware.RoleAdmin:
+		default:
+			return nil, nil, fmt.Errorf("%w: %q", ErrInvalidRole, r)
+		}
+		if !contains(required, r) {
+			required = append(required, r)
+		}
+	}
+
+	before, err := m.store.GetPolicy(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	var added []string
+	for _, r := range required {
+		if !before.Requires(r) {
+			added = append(added, r)
+		}
+	}
+
+	p := &Policy{RequiredRoles: required, UpdatedBy: adminID, UpdatedAt: m.now()}
+	if err := m.store.SetPolicy(ctx, p); err != nil {
+		return nil, nil, err
+	}
+	return p, added, nil
+}
+
+// Status returns whether userID has an authenticator and whether staffRole
+// requires one.
+func (m *Manager) Status(ctx context.Context, userID, staffRole string) (*Status, error) {
+	p, err := m.store.GetPolicy(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s := &Status{Required: p.Requires(staffRole)}
+	e, found, err := m.store.GetEnrollment(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if found && e.Confirmed {
+		s.Enrolled = true
+		s.RecoveryCodesLeft = len(e.RecoveryHashes)
+	}
+	return s, nil
+}
+
+// StepUp decides whether l needs a second factor. If it does, it stores a
+// challenge and returns it with the token that completes it; otherwise the
+// challenge is nil.
+func (m *Manager) StepUp(ctx context.Context, l Login) (string, *Challenge, error) {
+	e, found, err := m.store.GetEnrollment(ctx, l.UserID)
+	if err != nil {
+		return "", nil, err
+	}
+	enrolled := found && e.Confirmed
+	if !enrolled {
+		p, err := m.store.GetPolicy(ctx)
+		if err != nil {
+			return "", nil, err
+		}
+		if !p.Requires(l.StaffRole) {
+			return "", nil, nil
+		}
+	}
+
+	token, err := randomHex(32)
+	if err != nil {
+		return "", nil, err
+	}
+	now := m.now()
+	c := &Challenge{
+		ID:        hash(token),
+		Login:     l,
+		Enroll:    !enrolled,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ChallengeTTL),
+	}
+	if err := m.store.CreateChallenge(ctx, c); err != nil {
+		return "", nil, err
+	}
+	return token, c, nil
+}
+
+// Challenge returns the pending challenge of token.
+func (m *Manager) Challenge(ctx context.Context, token string) (*Challenge, error) {
+	c, found, err := m.store.GetChallenge(ctx, hash(token))
+	if err != nil {
+		return nil, err
+	}
+	if !found || !m.now().Before(c.ExpiresAt) {
+		return nil, ErrChallengeNotFound
+	}
+	return c, nil
+}
+
+// Complete checks a code against the challenge of token. When the challenge
+// was for enrolling, the code confirms the new authenticator and its
+// recovery codes are returned. On success the challenge is used up and the
+// login it was holding is returned. A wrong code returns the login along
+// with the error, so the failure can be put down to the user.
+func (m *Manager) Complete(ctx context.Context, token, code string) (*Login, []string, error) {
+	id := hash(token)
+	var c Challenge
+	err := m.store.UpdateChallenge(ctx, id, func(stored *Challenge) error {
+		if !m.now().Before(stored.ExpiresAt) || stored.Attempts >= maxChallengeAttempts {
+			return ErrChallengeNotFound
+		}
+		stored.Attempts++
+		c = *stored
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	e, found, err := m.store.GetEnrollment(ctx, c.Login.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	var recoveryCodes []string
+	switch {
+	case !found:
+		err = ErrNotEnrolled
+	case e.Confirmed:
+		err = m.Verify(ctx, c.Login.UserID, code)
+	default:
+		recoveryCodes, err = m.Confirm(ctx, c.Login.UserID, code)
+	}
+	if errors.Is(err, ErrInvalidCode) && c.Attempts >= maxChallengeAttempts {
+		m.store.DeleteChallenge(ctx, id)
+		return &c.Login, nil, ErrTooManyAttempts
+	}
+	if err != nil {
+		return &c.Login, nil, err
+	}
+
+	if err := m.store.DeleteChallenge(ctx, id); err != nil {
+		return nil, nil, err
+	}
+	return &c.Login, recoveryCodes, nil
+}
+
+// Enroll starts enrolling a new authenticator for userID, replacing any that
+// was started and not confirmed. It returns the secret and its provisioning
+// URI. The authenticator protects nothing until Confirm.
+func (m *Manager) Enroll(ctx context.Context, userID, email string) (string, string, error) {
+	e, found, err := m.store.GetEnrollment(ctx, userID)
+	if err != nil {
+		return "", "", err
+	}
+	if found && e.Confirmed {
+		return "", "", ErrAlreadyEnrolled
+	}
+
+	secret, err := NewSecret()
+	if err != nil {
+		return "", "", err
+	}
+	sealed, err := m.keys.Encrypt(secret)
+	if err != nil {
+		return "", "", fmt.Errorf("encrypt secret: %w", err)
+	}
+	e = &Enrollment{UserID: userID, Secret: sealed, CreatedAt: m.now()}
+	if err := m.store.SetEnrollment(ctx, e); err != nil {
+		return "", "", err
+	}
+	return secret, ProvisioningURI(secret, email), nil
+}
+
+// Confirm checks the first code from a new authenticator and turns it on.
+// It returns the recovery codes, which are shown once.
+func (m *Manager) Confirm(ctx context.Context, userID, code string) ([]string, error) {
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	err = m.store.UpdateEnrollment(ctx, userID, func(e *Enrollment) error {
+		if e.Confirmed {
+			return ErrAlreadyEnrolled
+		}
+		if err := m.checkCode(e, code, false); err != nil {
+			return err
+		}
+		now := m.now()
+		e.Confirmed, e.ConfirmedAt, e.RecoveryHashes = true, &now, hashes
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Verify checks a code from userID's authenticator or one of their recovery
+// codes, which is then used up.
+func (m *Manager) Verify(ctx context.Context, userID, code string) error {
+	return m.store.UpdateEnrollment(ctx, userID, func(e *Enrollment) error {
+		if !e.Confirmed {
+			return ErrNotEnrolled
+		}
+		return m.checkCode(e, code, true)
+	})
+}
+
+// RegenerateRecoveryCodes replaces userID's recovery codes with new ones,
+// which are shown once.
+func (m *Manager) RegenerateRecoveryCodes(ctx context.Context, userID string) ([]string, error) {
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	err = m.store.UpdateEnrollment(ctx, userID, func(e *Enrollment) error {
+		if !e.Confirmed {
+			return ErrNotEnrolled
+		}
+		e.RecoveryHashes = hashes
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Disable removes userID's authenticator.
+func (m *Manager) Disable(ctx context.Context, userID string) error {
+	return m.store.DeleteEnrollment(ctx, userID)
+}
+
+// checkCode checks code against e, recording the time step it was for. With
+// recovery set, a recovery code is accepted too and removed from e.
+func (m *Manager) checkCode(e *Enrollment, code string, recovery bool) error {
+	secret, err := m.keys.Decrypt(e.Secret)
+	if err != nil {
+		return fmt.Errorf("decrypt secret of %s: %w", e.UserID, err)
+	}
+	if s, ok := match(secret, code, m.now(), e.LastStep); ok {
+		e.LastStep = s
+		return nil
+	}
+	if recovery {
+		h := hash(normalizeRecoveryCode(code))
+		for i, stored := range e.RecoveryHashes {
+			if stored == h {
+				e.RecoveryHashes = append(e.RecoveryHashes[:i:i], e.RecoveryHashes[i+1:]...)
+				return nil
+			}
+		}
+	}
+	return ErrInvalidCode
+}
+
+// newRecoveryCodes returns fresh recovery codes such as "K7QM3-XRAPW" and
+// their hashes.
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for len(codes) < recoveryCodeCount {
+		buf := make([]byte, recoveryLength)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, err
+		}
+		var b strings.Builder
+		for i, v := range buf {
+			if i > 0 && i%recoveryGroup == 0 {
+				b.WriteByte('-')
+			}
+			// 256 is a multiple of the alphabet's 32 characters, so this is uniform.
+			b.WriteByte(recoveryAlphabet[int(v)%len(recoveryAlphabet)])
+		}
+		codes = append(codes, b.String())
+		hashes = append(hashes, hash(normalizeRecoveryCode(b.String())))
+	}
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode uppercases code and drops everything that is not
+// part of the alphabet, such as the dash and stray spaces.
+func normalizeRecoveryCode(code string) string {
+	var b strings.Builder
+	for _, c := range strings.ToUpper(code) {
+		if strings.ContainsRune(recoveryAlphabet, c) {
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
+
+func hash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
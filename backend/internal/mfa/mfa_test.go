@@ -0,0 +1,259 @@
+// backend/internal/mfa/mfa_test.go
+
+package mfa
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/middleware"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/tokencrypt"
+)
+
+// testClock is a settable clock for a Manager.
+type testClock struct{ t time.Time }
+
+func (c *testClock) now() time.Time          { return c.t }
+func (c *testClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+func (c *testClock) codeFor(secret string) string {
+	key, _ := encoding.DecodeString(secret)
+	return code(key, step(c.t))
+}
+
+// newTestManager returns a Manager over a memory store whose clock the test
+// controls.
+func newTestManager(t *testing.T) (*Manager, *testClock) {
+	t.Helper()
+	key, err := tokencrypt.NewLocalKey("test", bytes.Repeat([]byte{7}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewManager(NewMemoryStore(), tokencrypt.NewKeyring(key))
+	clock := &testClock{t: time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)}
+	m.now = clock.now
+	return m, clock
+}
+
+// enroll enrolls and confirms an authenticator for userID, returning its
+// secret and recovery codes.
+func enroll(t *testing.T, m *Manager, clock *testClock, userID string) (string, []string) {
+	t.Helper()
+	secret, _, err := m.Enroll(context.Background(), userID, userID+"@leetutoring.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	codes, err := m.Confirm(context.Background(), userID, clock.codeFor(secret))
+	if err != nil {
+		t.Fatalf("Confirm: %v", err)
+	}
+	return secret, codes
+}
+
+func TestConfirm(t *testing.T) {
+	ctx := context.Background()
+	m, clock := newTestManager(t)
+	secret, uri, err := m.Enroll(ctx, "tess", "tess@leetutoring.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(uri, "secret="+secret) {
+		t.Errorf("provisioning URI %q does not carry the secret", uri)
+	}
+
+	if _, err := m.Confirm(ctx, "tess", "000000"); !errors.Is(err, ErrInvalidCode) {
+		t.Fatalf("Confirm with a wrong code error = %v, want %v", err, ErrInvalidCode)
+	}
+	if s, _ := m.Status(ctx, "tess", middleware.RoleTutor); s.Enrolled {
+		t.Error("authenticator enrolled after a wrong code")
+	}
+	if err := m.Verify(ctx, "tess", clock.codeFor(secret)); !errors.Is(err, ErrNotEnrolled) {
+		t.Errorf("Verify before confirming error = %v, want %v", err, ErrNotEnrolled)
+	}
+
+	codes, err := m.Confirm(ctx, "tess", clock.codeFor(secret))
+	if err != nil {
+		t.Fatalf("Confirm: %v", err)
+	}
+	if len(codes) != recoveryCodeCount {
+		t.Errorf("got %d recovery codes, want %d", len(codes), recoveryCodeCount)
+	}
+	if s, _ := m.Status(ctx, "tess", middleware.RoleTutor); !s.Enrolled || s.RecoveryCodesLeft != recoveryCodeCount {
+		t.Errorf("status = %+v, want enrolled with %d recovery codes", s, recoveryCodeCount)
+	}
+	if _, err := m.Confirm(ctx, "tess", clock.codeFor(secret)); !errors.Is(err, ErrAlreadyEnrolled) {
+		t.Errorf("second Confirm error = %v, want %v", err, ErrAlreadyEnrolled)
+	}
+	if _, _, err := m.Enroll(ctx, "tess", "tess@leetutoring.com"); !errors.Is(err, ErrAlreadyEnrolled) {
+		t.Errorf("Enroll over a confirmed authenticator error = %v, want %v", err, ErrAlreadyEnrolled)
+	}
+}
+
+func TestVerifyRejectsReusedStep(t *testing.T) {
+	ctx := context.Background()
+	m, clock := newTestManager(t)
+	secret, _ := enroll(t, m, clock, "tess")
+
+	// The code that confirmed the authenticator cannot sign in too.
+	if err := m.Verify(ctx, "tess", clock.codeFor(secret)); !errors.Is(err, ErrInvalidCode) {
+		t.Fatalf("Verify with the confirming code error = %v, want %v", err, ErrInvalidCode)
+	}
+
+	clock.advance(period * time.Second)
+	next := clock.codeFor(secret)
+	if err := m.Verify(ctx, "tess", next); err != nil {
+		t.Fatalf("Verify with the next code: %v", err)
+	}
+	if err := m.Verify(ctx, "tess", next); !errors.Is(err, ErrInvalidCode) {
+		t.Errorf("Verify with the same code twice error = %v, want %v", err, ErrInvalidCode)
+	}
+
+	// Nor can an earlier code still within the skew once a later one is used.
+	clock.advance(period * time.Second)
+	if err := m.Verify(ctx, "tess", clock.codeFor(secret)); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Verify(ctx, "tess", next); !errors.Is(err, ErrInvalidCode) {
+		t.Errorf("Verify with an earlier code error = %v, want %v", err, ErrInvalidCode)
+	}
+}
+
+func TestRecoveryCodesAreSingleUse(t *testing.T) {
+	ctx := context.Background()
+	m, clock := newTestManager(t)
+	_, codes := enroll(t, m, clock, "tess")
+
+	// Typed without the dash and in lower case.
+	typed := strings.ToLower(strings.ReplaceAll(codes[0], "-", ""))
+	if err := m.Verify(ctx, "tess", typed); err != nil {
+		t.Fatalf("Verify with a recovery code: %v", err)
+	}
+	if err := m.Verify(ctx, "tess", codes[0]); !errors.Is(err, ErrInvalidCode) {
+		t.Errorf("Verify with a used recovery code error = %v, want %v", err, ErrInvalidCode)
+	}
+	if err := m.Verify(ctx, "tess", codes[1]); err != nil {
+		t.Errorf("Verify with another recovery code: %v", err)
+	}
+	if s, _ := m.Status(ctx, "tess", middleware.RoleTutor); s.RecoveryCodesLeft != recoveryCodeCount-2 {
+		t.Errorf("recovery codes left = %d, want %d", s.RecoveryCodesLeft, recoveryCodeCount-2)
+	}
+
+	// Recovery codes do not confirm a new authenticator.
+	secret, _, err := m.Enroll(ctx, "lee", "lee@leetutoring.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Confirm(ctx, "lee", codes[2]); !errors.Is(err, ErrInvalidCode) {
+		t.Errorf("Confirm with a recovery code error = %v, want %v", err, ErrInvalidCode)
+	}
+	if _, err := m.Confirm(ctx, "lee", clock.codeFor(secret)); err != nil {
+		t.Errorf("Confirm: %v", err)
+	}
+}
+
+func TestCompleteLocksOut(t *testing.T) {
+	ctx := context.Background()
+	m, clock := newTestManager(t)
+	secret, _ := enroll(t, m, clock, "tess")
+	clock.advance(period * time.Second)
+
+	login := Login{UserID: "tess", Email: "tess@leetutoring.com", Role: middleware.RoleTutor, StaffRole: middleware.RoleTutor}
+	token, c, err := m.StepUp(ctx, login)
+	if err != nil || c == nil {
+		t.Fatalf("StepUp = %v, %v; want a challenge", c, err)
+	}
+
+	for i := 1; i < maxChallengeAttempts; i++ {
+		got, _, err := m.Complete(ctx, token, "000000")
+		if !errors.Is(err, ErrInvalidCode) {
+			t.Fatalf("attempt %d error = %v, want %v", i, err, ErrInvalidCode)
+		}
+		if got == nil || got.UserID != "tess" {
+			t.Errorf("attempt %d login = %+v, want tess's", i, got)
+		}
+	}
+	if _, _, err := m.Complete(ctx, token, "000000"); !errors.Is(err, ErrTooManyAttempts) {
+		t.Fatalf("attempt %d error = %v, want %v", maxChallengeAttempts, err, ErrTooManyAttempts)
+	}
+
+	// The challenge is gone, so even the right code no longer signs in.
+	if _, _, err := m.Complete(ctx, token, clock.codeFor(secret)); !errors.Is(err, ErrChallengeNotFound) {
+		t.Errorf("Complete after lockout error = %v, want %v", err, ErrChallengeNotFound)
+	}
+	if _, err := m.Challenge(ctx, token); !errors.Is(err, ErrChallengeNotFound) {
+		t.Errorf("Challenge after lockout error = %v, want %v", err, ErrChallengeNotFound)
+	}
+}
+
+func TestComplete(t *testing.T) {
+	ctx := context.Background()
+	m, clock := newTestManager(t)
+	secret, _ := enroll(t, m, clock, "tess")
+	clock.advance(period * time.Second)
+
+	login := Login{UserID: "tess", Role: middleware.RoleTutor, StaffRole: middleware.RoleTutor, ReturnTo: "/tutordashboard"}
+	token, _, err := m.StepUp(ctx, login)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := m.Complete(ctx, token, "000000"); !errors.Is(err, ErrInvalidCode) {
+		t.Fatalf("Complete with a wrong code error = %v, want %v", err, ErrInvalidCode)
+	}
+	got, _, err := m.Complete(ctx, token, clock.codeFor(secret))
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if *got != login {
+		t.Errorf("login = %+v, want %+v", got, login)
+	}
+	if _, _, err := m.Complete(ctx, token, clock.codeFor(secret)); !errors.Is(err, ErrChallengeNotFound) {
+		t.Errorf("second Complete error = %v, want %v", err, ErrChallengeNotFound)
+	}
+
+	// A challenge expires.
+	token, _, err = m.StepUp(ctx, login)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock.advance(ChallengeTTL)
+	if _, _, err := m.Complete(ctx, token, clock.codeFor(secret)); !errors.Is(err, ErrChallengeNotFound) {
+		t.Errorf("Complete after expiry error = %v, want %v", err, ErrChallengeNotFound)
+	}
+}
+
+func TestStepUp(t *testing.T) {
+	ctx := context.Background()
+	m, clock := newTestManager(t)
+	enroll(t, m, clock, "enrolled")
+	if _, _, err := m.SetPolicy(ctx, []string{middleware.RoleAdmin}, "ada"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		login         Login
+		wantChallenge bool
+		wantEnroll    bool
+	}{
+		{"enrolled tutor", Login{UserID: "enrolled", StaffRole: middleware.RoleTutor}, true, false},
+		{"tutor not required", Login{UserID: "tess", StaffRole: middleware.RoleTutor}, false, false},
+		{"admin required to enroll", Login{UserID: "ada", StaffRole: middleware.RoleAdmin}, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, c, err := m.StepUp(ctx, tt.login)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (c != nil) != tt.wantChallenge {
+				t.Fatalf("challenge = %+v, want one %v", c, tt.wantChallenge)
+			}
+			if c != nil && (c.Enroll != tt.wantEnroll || token == "" || c.ID == token) {
+				t.Errorf("challenge = %+v, want enroll %v and an ID that is not the token", c, tt.wantEnroll)
+			}
+		})
+	}
+}
@@ -0,0 +1,289 @@
+// backend/internal/mfa/store.go
+
+package mfa
+
+import (
+	"context"
+	"sync"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Store keeps enrollments, challenges and the policy.
+type Store interface {
+	GetEnrollment(ctx context.Context, userID string) (*Enrollment, bool, error)
+	// SetEnrollment replaces the enrollment of e.UserID.
+	SetEnrollment(ctx context.Context, e *Enrollment) error
+	// UpdateEnrollment applies fn to the enrollment atomically, failing with
+	// ErrNotEnrolled if there is none. If fn returns an error the enrollment
+	// is left as it was.
+	UpdateEnrollment(ctx context.Context, userID string, fn func(*Enrollment) error) error
+	DeleteEnrollment(ctx context.Context, userID string) error
+
+	// GetPolicy returns the policy, which requires nothing until it is set.
+	GetPolicy(ctx context.Context) (*Policy, error)
+	SetPolicy(ctx context.Context, p *Policy) error
+
+	CreateChallenge(ctx context.Context, c *Challenge) error
+	GetChallenge(ctx context.Context, id string) (*Challenge, bool, error)
+	// UpdateChallenge applies fn to the challenge atomically, failing with
+	// ErrChallengeNotFound if there is none.
+	UpdateChallenge(ctx context.Context, id string, fn func(*Challenge) error) error
+	DeleteChallenge(ctx context.Context, id string) error
+}
+
+type firestoreStore struct {
+	client *firestore.Client
+}
+
+// NewFirestoreStore returns a Store backed by the "mfa_enrollments" and
+// "mfa_challenges" collections, with the policy in settings/mfa.
+func NewFirestoreStore(client *firestore.Client) Store {
+	return &firestoreStore{client: client}
+}
+
+func (f *firestoreStore) enrollment(userID string) *firestore.DocumentRef {
+	return f.client.Collection("mfa_enrollments").Doc(userID)
+}
+
+func (f *firestoreStore) challenge(id string) *firestore.DocumentRef {
+	return f.client.Collection("mfa_challenges").Doc(id)
+}
+
+func (f *firestoreStore) policy() *firestore.DocumentRef {
+	return f.client.Collection("settings").Doc("mfa")
+}
+
+func (f *firestoreStore) GetEnrollment(ctx context.Context, userID string) (*Enrollment, bool, error) {
+	snap, err := f.enrollment(userID).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	var e Enrollment
+	if err := snap.DataTo(&e); err != nil {
+		return nil, false, err
+	}
+	e.UserID = userID
+	return &e, true, nil
+}
+
+func (f *firestoreStore) SetEnrollment(ctx context.Context, e *Enrollment) error {
+	_, err := f.enrollment(e.UserID).Set(ctx, e)
+	return err
+}
+
+func (f *firestoreStore) UpdateEnrollment(ctx context.Context, userID string, fn func(*Enrollment) error) error {
+	ref := f.enrollment(userID)
+	return f.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		snap, err := tx.Get(ref)
+		if status.Code(err) == codes.NotFound {
+			return ErrNotEnrolled
+		}
+		if err != nil {
+			return err
+		}
+		var e Enrollment
+		if err := snap.DataTo(&e); err != nil {
+			return err
+		}
+		e.UserID = userID
+		if err := fn(&e); err != nil {
+			return err
+		}
+		return tx.Set(ref, &e)
+	})
+}
+
+func (f *firestoreStore) DeleteEnrollment(ctx context.Context, userID string) error {
+	_, err := f.enrollment(userID).Delete(ctx)
+	return err
+}
+
+func (f *firestoreStore) GetPolicy(ctx context.Context) (*Policy, error) {
+	snap, err := f.policy().Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return &Policy{RequiredRoles: []string{}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var p Policy
+	if err := snap.DataTo(&p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (f *firestoreStore) SetPolicy(ctx context.Context, p *Policy) error {
+	_, err := f.policy().Set(ctx, p)
+	return err
+}
+
+func (f *firestoreStore) CreateChallenge(ctx context.Context, c *Challenge) error {
+	_, err := f.challenge(c.ID).Create(ctx, c)
+	return err
+}
+
+func (f *firestoreStore) GetChallenge(ctx context.Context, id string) (*Challenge, bool, error) {
+	snap, err := f.challenge(id).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	var c Challenge
+	if err := snap.DataTo(&c); err != nil {
+		return nil, false, err
+	}
+	c.ID = id
+	return &c, true, nil
+}
+
+func (f *firestoreStore) UpdateChallenge(ctx context.Context, id string, fn func(*Challenge) error) error {
+	ref := f.challenge(id)
+	return f.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		snap, err := tx.Get(ref)
+		if status.Code(err) == codes.NotFound {
+			return ErrChallengeNotFound
+		}
+		if err != nil {
+			return err
+		}
+		var c Challenge
+		if err := snap.DataTo(&c); err != nil {
+			return err
+		}
+		c.ID = id
+		if err := fn(&c); err != nil {
+			return err
+		}
+		return tx.Set(ref, &c)
+	})
+}
+
+func (f *firestoreStore) DeleteChallenge(ctx context.Context, id string) error {
+	_, err := f.challenge(id).Delete(ctx)
+	return err
+}
+
+// MemoryStore is an in-memory Store for tests and local runs.
+type MemoryStore struct {
+	mu          sync.Mutex
+	enrollments map[string]Enrollment
+	challenges  map[string]Challenge
+	policy      Policy
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		enrollments: map[string]Enrollment{},
+		challenges:  map[string]Challenge{},
+		policy:      Policy{RequiredRoles: []string{}},
+	}
+}
+
+func (m *MemoryStore) GetEnrollment(ctx context.Context, userID string) (*Enrollment, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.enrollments[userID]
+	if !ok {
+		return nil, false, nil
+	}
+	e.RecoveryHashes = append([]string(nil), e.RecoveryHashes...)
+	return &e, true, nil
+}
+
+func (m *MemoryStore) SetEnrollment(ctx context.Context, e *Enrollment) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored := *e
+	stored.RecoveryHashes = append([]string(nil), e.RecoveryHashes...)
+	m.enrollments[e.UserID] = stored
+	return nil
+}
+
+func (m *MemoryStore) UpdateEnrollment(ctx context.Context, userID string, fn func(*Enrollment) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.enrollments[userID]
+	if !ok {
+		return ErrNotEnrolled
+	}
+	e.RecoveryHashes = append([]string(nil), e.RecoveryHashes...)
+	if err := fn(&e); err != nil {
+		return err
+	}
+	m.enrollments[userID] = e
+	return nil
+}
+
+func (m *MemoryStore) DeleteEnrollment(ctx context.Context, userID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.enrollments, userID)
+	return nil
+}
+
+func (m *MemoryStore) GetPolicy(ctx context.Context) (*Policy, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	p := m.policy
+	p.RequiredRoles = append([]string{}, p.RequiredRoles...)
+	return &p, nil
+}
+
+func (m *MemoryStore) SetPolicy(ctx context.Context, p *Policy) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.policy = *p
+	m.policy.RequiredRoles = append([]string{}, p.RequiredRoles...)
+	return nil
+}
+
+func (m *MemoryStore) CreateChallenge(ctx context.Context, c *Challenge) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.challenges[c.ID]; ok {
+		return status.Errorf(codes.AlreadyExists, "challenge %s already exists", c.ID)
+	}
+	m.challenges[c.ID] = *c
+	return nil
+}
+
+func (m *MemoryStore) GetChallenge(ctx context.Context, id string) (*Challenge, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c, ok := m.challenges[id]
+	if !ok {
+		return nil, false, nil
+	}
+	return &c, true, nil
+}
+
+func (m *MemoryStore) UpdateChallenge(ctx context.Context, id string, fn func(*Challenge) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c, ok := m.challenges[id]
+	if !ok {
+		return ErrChallengeNotFound
+	}
+	if err := fn(&c); err != nil {
+		return err
+	}
+	m.challenges[id] = c
+	return nil
+}
+
+func (m *MemoryStore) DeleteChallenge(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.challenges, id)
+	return nil
+}
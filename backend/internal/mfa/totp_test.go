@@ -0,0 +1,83 @@
+// backend/internal/mfa/totp_test.go
+
+package mfa
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+// The SHA-1 test vectors of RFC 6238, appendix B. The RFC gives eight
+// digits; six-digit codes are their last six.
+var rfc6238 = []struct {
+	unix int64
+	want string
+}{
+	{59, "287082"},
+	{1111111109, "081804"},
+	{1111111111, "050471"},
+	{1234567890, "005924"},
+	{2000000000, "279037"},
+	{20000000000, "353130"},
+}
+
+// rfcSecret is the RFC's ASCII key "12345678901234567890", base32-encoded.
+var rfcSecret = encoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestCodeRFC6238(t *testing.T) {
+	for _, tt := range rfc6238 {
+		got := code([]byte("12345678901234567890"), step(time.Unix(tt.unix, 0)))
+		if got != tt.want {
+			t.Errorf("code at %d = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := step(now)
+
+	tests := []struct {
+		name     string
+		secret   string
+		given    string
+		after    int64
+		wantStep int64
+		wantOK   bool
+	}{
+		{"current step", rfcSecret, "050471", 0, current, true},
+		{"spaces and lower-case secret", "gezdgnbvgy3tqojqgezdgnbvgy3tqojq", "050 471", 0, current, true},
+		{"previous step within skew", rfcSecret, code([]byte("12345678901234567890"), current-1), 0, current - 1, true},
+		{"next step within skew", rfcSecret, code([]byte("12345678901234567890"), current+1), 0, current + 1, true},
+		{"outside skew", rfcSecret, code([]byte("12345678901234567890"), current-2), 0, 0, false},
+		{"step already used", rfcSecret, "050471", current, 0, false},
+		{"wrong code", rfcSecret, "123456", 0, 0, false},
+		{"too short", rfcSecret, "50471", 0, 0, false},
+		{"bad secret", "not base32!", "050471", 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotStep, ok := match(tt.secret, tt.given, now, tt.after)
+			if ok != tt.wantOK || gotStep != tt.wantStep {
+				t.Errorf("match = %d, %v; want %d, %v", gotStep, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestProvisioningURI(t *testing.T) {
+	u, err := url.Parse(ProvisioningURI(rfcSecret, "tess@leetutoring.com"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Scheme != "otpauth" || u.Host != "totp" || u.Path != "/Lee Tutoring:tess@leetutoring.com" {
+		t.Errorf("URI = %s, want otpauth://totp/Lee Tutoring:tess@leetutoring.com", u)
+	}
+	q := u.Query()
+	for key, want := range map[string]string{"secret": rfcSecret, "issuer": Issuer, "algorithm": "SHA1", "digits": "6", "period": "30"} {
+		if got := q.Get(key); got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+}
@@ -5,6 +5,9 @@ package microsoftauth
 import (
 	"cloud.google.com/go/firestore"
 	"github.com/NathanielJBrown97/LeeTutoringApp/internal/config"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/identity"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/oauthstate"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/tokencrypt"
 	"golang.org/x/oauth2"
 )
 
@@ -14,4 +17,8 @@ type App struct {
 	OAuthConfig     *oauth2.Config
 	FirestoreClient *firestore.Client
 	SecretKey       string
+	Identity        *identity.Resolver
+	States          *oauthstate.Manager
+	// Tokens decrypts the Microsoft access tokens stored on parent documents.
+	Tokens *tokencrypt.Keyring
 }
@@ -5,6 +5,7 @@ package microsoftauth
 import (
 	"context"
 	"io/ioutil"
+	"log"
 	"net/http"
 )
 
@@ -24,11 +25,17 @@ func (a *App) ProfilePictureHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	data := doc.Data()
-	accessToken, ok := data["access_token"].(string)
-	if !ok || accessToken == "" {
+	storedToken, ok := data["access_token"].(string)
+	if !ok || storedToken == "" {
 		http.Error(w, "Access token not found", http.StatusUnauthorized)
 		return
 	}
+	accessToken, err := a.Tokens.Decrypt(storedToken)
+	if err != nil {
+		log.Printf("Failed to decrypt access token of %s: %v", userID, err)
+		http.Error(w, "Failed to read access token", http.StatusInternalServerError)
+		return
+	}
 
 	// Fetch the user's profile picture
 	req, err := http.NewRequest("GET", "https://graph.microsoft.com/v1.0/me/photo/$value", nil)
@@ -4,6 +4,7 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"log"
 	"net/http"
 	"strings"
@@ -15,12 +16,37 @@ type contextKey string
 
 const userContextKey = contextKey("user")
 
-func AuthMiddleware(secretKey string) func(http.Handler) http.Handler {
+const impersonatorContextKey = contextKey("impersonator")
+
+// ImpersonatedByHeader is set on every response to an impersonation token,
+// to the admin's user ID.
+const ImpersonatedByHeader = "X-Impersonated-By"
+
+// SessionChecker reports whether the session an access token was issued for
+// is still active.
+type SessionChecker interface {
+	Active(ctx context.Context, sid string) (bool, error)
+}
+
+// RejectionRecorder is told about requests whose access token was refused,
+// other than for having expired, which is routine.
+type RejectionRecorder interface {
+	TokenRejected(r *http.Request, userID, reason string)
+}
+
+// AuthMiddleware checks the Bearer access token and that its session ("sid"
+// claim) has not been revoked, then puts the claims in the request context.
+// Tokens an admin got to view the app as someone else ("impersonator"
+// claim) are read-only. Refused tokens are reported to rejections, if set.
+func AuthMiddleware(secretKey string, sessions SessionChecker, rejections RejectionRecorder) func(http.Handler) http.Handler {
+	reject := func(r *http.Request, userID, reason string) {
+		if rejections != nil {
+			rejections.TokenRejected(r, userID, reason)
+		}
+	}
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			authHeader := r.Header.Get("Authorization")
-			log.Printf("Authorization header: %s", authHeader)
-
 			if authHeader == "" {
 				http.Error(w, "Missing Authorization header", http.StatusUnauthorized)
 				return
@@ -41,6 +67,10 @@ func AuthMiddleware(secretKey string) func(http.Handler) http.Handler {
 				return []byte(secretKey), nil
 			})
 			if err != nil || !token.Valid {
+				var validation *jwt.ValidationError
+				if !errors.As(err, &validation) || validation.Errors != jwt.ValidationErrorExpired {
+					reject(r, "", "invalid token")
+				}
 				http.Error(w, "Invalid token", http.StatusUnauthorized)
 				return
 			}
@@ -52,11 +82,42 @@ func AuthMiddleware(secretKey string) func(http.Handler) http.Handler {
 				return
 			}
 
+			// Tokens are only honoured while their session is active
+			userID, _ := claims["user_id"].(string)
+			sid, _ := claims["sid"].(string)
+			if sid == "" {
+				reject(r, userID, "token without session")
+				http.Error(w, "Session expired", http.StatusUnauthorized)
+				return
+			}
+			active, err := sessions.Active(r.Context(), sid)
+			if err != nil {
+				log.Printf("Error checking session %s: %v", sid, err)
+				http.Error(w, "Failed to check session", http.StatusInternalServerError)
+				return
+			}
+			if !active {
+				reject(r, userID, "session revoked")
+				http.Error(w, "Session revoked", http.StatusUnauthorized)
+				return
+			}
+
 			// Log the user ID from the token
 			log.Printf("Authenticated user ID: %s", claims["user_id"])
 
 			// Set user info in context
 			ctx := context.WithValue(r.Context(), userContextKey, claims)
+
+			if impersonator, _ := claims["impersonator"].(string); impersonator != "" {
+				ctx = WithImpersonator(ctx, impersonator)
+				w.Header().Set(ImpersonatedByHeader, impersonator)
+				if r.Method != http.MethodGet && r.Method != http.MethodHead && r.Method != http.MethodOptions {
+					log.Printf("Blocked %s %s by admin %s impersonating %s", r.Method, r.URL.Path, impersonator, claims["user_id"])
+					http.Error(w, "Impersonation sessions are read-only", http.StatusForbidden)
+					return
+				}
+			}
+
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
@@ -68,6 +129,26 @@ func GetUserFromContext(ctx context.Context) (jwt.MapClaims, bool) {
 	return claims, ok
 }
 
+// WithImpersonator marks ctx as belonging to a request an admin makes while
+// viewing the app as someone else. Code with effects the user would notice,
+// such as opening their hours ledger or emailing them, checks Impersonating
+// and holds back.
+func WithImpersonator(ctx context.Context, adminID string) context.Context {
+	return context.WithValue(ctx, impersonatorContextKey, adminID)
+}
+
+// ImpersonatorFromContext returns the admin behind an impersonation token.
+func ImpersonatorFromContext(ctx context.Context) (string, bool) {
+	impersonator, _ := ctx.Value(impersonatorContextKey).(string)
+	return impersonator, impersonator != ""
+}
+
+// Impersonating reports whether ctx belongs to an impersonation request.
+func Impersonating(ctx context.Context) bool {
+	_, ok := ImpersonatorFromContext(ctx)
+	return ok
+}
+
 // ExtractUserIDFromContext extracts the user ID from the context
 func ExtractUserIDFromContext(ctx context.Context) (string, error) {
 	claims, ok := GetUserFromContext(ctx)
@@ -0,0 +1,71 @@
+// backend/internal/middleware/auth_middleware_test.go
+
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const testSecret = "test-secret"
+
+type activeSessions struct{}
+
+func (activeSessions) Active(ctx context.Context, sid string) (bool, error) { return true, nil }
+
+func TestAuthMiddlewareImpersonation(t *testing.T) {
+	tests := []struct {
+		name         string
+		impersonator string
+		method       string
+		wantStatus   int
+		wantFlag     bool
+	}{
+		{"own token", "", http.MethodGet, http.StatusOK, false},
+		{"own token writing", "", http.MethodPost, http.StatusOK, false},
+		{"impersonating", "admin-1", http.MethodGet, http.StatusOK, true},
+		{"impersonating writing", "admin-1", http.MethodPost, http.StatusForbidden, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := jwt.MapClaims{"user_id": "parent-1", "role": RoleParent, "sid": "session-1"}
+			if tt.impersonator != "" {
+				claims["impersonator"] = tt.impersonator
+			}
+			token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testSecret))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var flagged, reached bool
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				reached = true
+				flagged = Impersonating(r.Context())
+				if admin, _ := ImpersonatorFromContext(r.Context()); admin != tt.impersonator {
+					t.Errorf("impersonator = %q, want %q", admin, tt.impersonator)
+				}
+			})
+			req := httptest.NewRequest(tt.method, "/", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			rec := httptest.NewRecorder()
+			AuthMiddleware(testSecret, activeSessions{}, nil)(next).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if flagged != tt.wantFlag {
+				t.Errorf("Impersonating = %v, want %v", flagged, tt.wantFlag)
+			}
+			if reached != (tt.wantStatus == http.StatusOK) {
+				t.Errorf("handler reached = %v after status %d", reached, rec.Code)
+			}
+			if got := rec.Header().Get(ImpersonatedByHeader); got != tt.impersonator {
+				t.Errorf("%s = %q, want %q", ImpersonatedByHeader, got, tt.impersonator)
+			}
+		})
+	}
+}
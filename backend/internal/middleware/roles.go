@@ -0,0 +1,65 @@
+// backend/internal/middleware/roles.go
+
+package middleware
+
+import (
+	"context"
+	"log"
+	"net/http"
+)
+
+// Roles carried in the "role" claim of our JWTs.
+const (
+	RoleTutor   = "tutor"
+	RoleParent  = "parent"
+	RoleStudent = "student"
+	RoleAdmin   = "admin"
+
+	// RoleTeamLead is not issued in JWTs; it is read from the "staff_role"
+	// field of a tutor's document and lets the tutor act on any student.
+	RoleTeamLead = "team_lead"
+)
+
+// RequireRole returns middleware that only lets the request through when the
+// caller's "role" claim is one of the given roles. It must run after
+// AuthMiddleware so the claims are already in the context.
+func RequireRole(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := GetUserFromContext(r.Context()); !ok {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			if !HasRole(r.Context(), roles...) {
+				log.Printf("Rejected %s %s for role %q", r.Method, r.URL.Path, GetRoleFromContext(r.Context()))
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// GetRoleFromContext returns the "role" claim of the authenticated user, or "" if there is none.
+func GetRoleFromContext(ctx context.Context) string {
+	claims, ok := GetUserFromContext(ctx)
+	if !ok {
+		return ""
+	}
+	role, _ := claims["role"].(string)
+	return role
+}
+
+// HasRole reports whether the authenticated user holds one of the given roles.
+func HasRole(ctx context.Context, roles ...string) bool {
+	role := GetRoleFromContext(ctx)
+	if role == "" {
+		return false
+	}
+	for _, allowed := range roles {
+		if role == allowed {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+// backend/internal/middleware/roles_test.go
+
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// withRole returns r carrying claims with the given role, as AuthMiddleware
+// would leave them. An empty role leaves the claims out altogether.
+func withRole(r *http.Request, role string) *http.Request {
+	if role == "" {
+		return r
+	}
+	claims := jwt.MapClaims{"user_id": "user-1", "role": role}
+	return r.WithContext(context.WithValue(r.Context(), userContextKey, claims))
+}
+
+func TestRequireRole(t *testing.T) {
+	// The role sets main.go declares for each group of routes.
+	routes := map[string][]string{
+		"tutor":   {RoleTutor, RoleAdmin},
+		"parent":  {RoleParent},
+		"student": {RoleStudent},
+		"admin":   {RoleAdmin},
+	}
+
+	tests := []struct {
+		role string
+		want map[string]int
+	}{
+		{RoleTutor, map[string]int{"tutor": http.StatusOK, "parent": http.StatusForbidden, "student": http.StatusForbidden, "admin": http.StatusForbidden}},
+		{RoleParent, map[string]int{"tutor": http.StatusForbidden, "parent": http.StatusOK, "student": http.StatusForbidden, "admin": http.StatusForbidden}},
+		{RoleStudent, map[string]int{"tutor": http.StatusForbidden, "parent": http.StatusForbidden, "student": http.StatusOK, "admin": http.StatusForbidden}},
+		{RoleAdmin, map[string]int{"tutor": http.StatusOK, "parent": http.StatusForbidden, "student": http.StatusForbidden, "admin": http.StatusOK}},
+		// Team leads are never issued in JWTs; a forged claim gets nowhere.
+		{RoleTeamLead, map[string]int{"tutor": http.StatusForbidden, "parent": http.StatusForbidden, "student": http.StatusForbidden, "admin": http.StatusForbidden}},
+		{"superuser", map[string]int{"tutor": http.StatusForbidden, "parent": http.StatusForbidden, "student": http.StatusForbidden, "admin": http.StatusForbidden}},
+		{"", map[string]int{"tutor": http.StatusUnauthorized, "parent": http.StatusUnauthorized, "student": http.StatusUnauthorized, "admin": http.StatusUnauthorized}},
+	}
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	for _, tt := range tests {
+		for route, roles := range routes {
+			name := tt.role
+			if name == "" {
+				name = "no claims"
+			}
+			t.Run(name+" on "+route+" routes", func(t *testing.T) {
+				rec := httptest.NewRecorder()
+				req := withRole(httptest.NewRequest(http.MethodGet, "/", nil), tt.role)
+				RequireRole(roles...)(ok).ServeHTTP(rec, req)
+				if rec.Code != tt.want[route] {
+					t.Errorf("status = %d, want %d", rec.Code, tt.want[route])
+				}
+			})
+		}
+	}
+}
+
+func TestHasRole(t *testing.T) {
+	tests := []struct {
+		name  string
+		role  string
+		roles []string
+		want  bool
+	}{
+		{"matching role", RoleParent, []string{RoleParent}, true},
+		{"one of several", RoleAdmin, []string{RoleTutor, RoleAdmin}, true},
+		{"other role", RoleParent, []string{RoleTutor, RoleAdmin}, false},
+		{"no roles allowed", RoleAdmin, nil, false},
+		{"no claims", "", []string{RoleTutor, RoleParent, RoleStudent, RoleAdmin}, false},
+		{"case matters", "Admin", []string{RoleAdmin}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := withRole(httptest.NewRequest(http.MethodGet, "/", nil), tt.role)
+			if got := HasRole(req.Context(), tt.roles...); got != tt.want {
+				t.Errorf("HasRole(%q, %v) = %v, want %v", tt.role, tt.roles, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHasRoleEmptyClaim(t *testing.T) {
+	claims := jwt.MapClaims{"user_id": "user-1", "role": ""}
+	ctx := context.WithValue(context.Background(), userContextKey, claims)
+	if HasRole(ctx, "") {
+		t.Error("an empty role claim matched an empty allowed role")
+	}
+}
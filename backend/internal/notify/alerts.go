@@ -0,0 +1,197 @@
+// backend/internal/notify/alerts.go
+
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/config"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/ledger"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/middleware"
+)
+
+// DefaultThresholds is used when LOW_BALANCE_THRESHOLDS is not set: a family
+// is warned when fewer than 2 hours are left and again when it goes negative.
+const DefaultThresholds = "2,0"
+
+// Threshold is a remaining-hours level. A family whose balance drops below it
+// is alerted once, and can be alerted again after the balance recovers.
+type Threshold struct {
+	Below float64
+}
+
+// Key identifies the threshold in the family's alert state.
+func (t Threshold) Key() string {
+	return "below_" + strings.ReplaceAll(strconv.FormatFloat(t.Below, 'f', -1, 64), ".", "_")
+}
+
+// ParseThresholds reads a comma-separated list of hours, e.g. "5,2,0".
+// The result is sorted from the highest level to the lowest, with repeated
+// levels listed once.
+func ParseThresholds(s string) ([]Threshold, error) {
+	if strings.TrimSpace(s) == "" {
+		s = DefaultThresholds
+	}
+	var out []Threshold
+	seen := map[float64]bool{}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		below, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid low balance threshold %q: %w", part, err)
+		}
+		if math.IsNaN(below) || math.IsInf(below, 0) {
+			return nil, fmt.Errorf("invalid low balance threshold %q: not a number of hours", part)
+		}
+		if seen[below] {
+			continue
+		}
+		seen[below] = true
+		out = append(out, Threshold{Below: below})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Below > out[j].Below })
+	return out, nil
+}
+
+// Alerter emails a family when its remaining hours cross a threshold.
+type Alerter struct {
+	store      Store
+	mailer     Mailer
+	thresholds []Threshold
+	bcc        []string
+}
+
+// NewAlerter reads the thresholds from LOW_BALANCE_THRESHOLDS. Alerts are
+// blind-copied to the comma-separated NOTIFY_BCC addresses.
+func NewAlerter(cfg *config.Config, client *firestore.Client, mailer Mailer) (*Alerter, error) {
+	thresholds, err := ParseThresholds(cfg.LOW_BALANCE_THRESHOLDS)
+	if err != nil {
+		return nil, err
+	}
+	var bcc []string
+	for _, addr := range strings.Split(cfg.NOTIFY_BCC, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			bcc = append(bcc, addr)
+		}
+	}
+	return &Alerter{store: NewFirestoreStore(client), mailer: mailer, thresholds: thresholds, bcc: bcc}, nil
+}
+
+// CheckBalance alerts the family if its balance is below a threshold it has
+// not been alerted for yet. Thresholds the balance is back above are cleared.
+// Failures are logged; they never fail the change that triggered the check.
+// Nothing is checked while an admin is impersonating, so that looking at a
+// family's dashboard never emails it.
+func (a *Alerter) CheckBalance(ctx context.Context, b *ledger.Balance) {
+	if b == nil || b.FamilyID == "" {
+		return
+	}
+	if admin, ok := middleware.ImpersonatorFromContext(ctx); ok {
+		log.Printf("Not checking low balance alerts of family %s: admin %s is impersonating", b.FamilyID, admin)
+		return
+	}
+	crossed, err := a.claim(ctx, b.FamilyID, b.Remaining)
+	if err != nil {
+		log.Printf("Error checking low balance alerts of family %s: %v", b.FamilyID, err)
+		return
+	}
+	if len(crossed) == 0 {
+		return
+	}
+
+	// Only the lowest threshold is worth an email; the others are marked
+	// sent along with it.
+	lowest := crossed[len(crossed)-1]
+	if err := a.send(ctx, b, lowest); err != nil {
+		log.Printf("Error sending low balance alert to family %s: %v", b.FamilyID, err)
+		a.release(ctx, b.FamilyID, crossed)
+		return
+	}
+	log.Printf("Sent %s alert to family %s (remaining %.2f hours)", lowest.Key(), b.FamilyID, b.Remaining)
+}
+
+// claim marks the thresholds newly crossed by remaining as sent, and clears
+// the ones that no longer apply, in one update so that concurrent checks do
+// not both send the same alert.
+func (a *Alerter) claim(ctx context.Context, familyID string, remaining float64) ([]Threshold, error) {
+	var crossed []Threshold
+	err := a.store.UpdateSent(ctx, familyID, func(sent map[string]time.Time) bool {
+		crossed = nil
+		changed := false
+		now := time.Now()
+		for _, t := range a.thresholds {
+			_, wasSent := sent[t.Key()]
+			switch {
+			case remaining < t.Below && !wasSent:
+				sent[t.Key()] = now
+				crossed = append(crossed, t)
+				changed = true
+			case remaining >= t.Below && wasSent:
+				delete(sent, t.Key())
+				changed = true
+			}
+		}
+		return changed
+	})
+	return crossed, err
+}
+
+// release forgets thresholds whose alert could not be sent, so that the next
+// check tries again.
+func (a *Alerter) release(ctx context.Context, familyID string, thresholds []Threshold) {
+	keys := make([]string, 0, len(thresholds))
+	for _, t := range thresholds {
+		keys = append(keys, t.Key())
+	}
+	if err := a.store.ForgetSent(ctx, familyID, keys); err != nil {
+		log.Printf("Error releasing low balance alerts of family %s: %v", familyID, err)
+	}
+}
+
+func (a *Alerter) send(ctx context.Context, b *ledger.Balance, t Threshold) error {
+	recipients, err := a.store.Recipients(ctx, b.FamilyID)
+	if err != nil {
+		return err
+	}
+	if len(recipients) == 0 {
+		return fmt.Errorf("household %s has no guardian with a billing email", b.FamilyID)
+	}
+	var to []string
+	for _, r := range recipients {
+		to = append(to, r.Email)
+	}
+	// Greet the recipient by name only when there is just one.
+	name := "there"
+	if len(recipients) == 1 && recipients[0].Name != "" {
+		name = recipients[0].Name
+	}
+
+	var subject, body string
+	if b.Remaining < 0 {
+		subject = "Your Lee Tutoring hours are overdrawn"
+		body = fmt.Sprintf("Hi %s,\n\nYour family has used %.2f more hours than it has purchased. "+
+			"Please purchase more hours so that sessions can continue.\n\nLee Tutoring\n", name, -b.Remaining)
+	} else {
+		subject = "Your Lee Tutoring hours are running low"
+		body = fmt.Sprintf("Hi %s,\n\nYour family has %.2f hours of tutoring left, under the %.2f hour mark. "+
+			"Please purchase more hours to avoid running out.\n\nLee Tutoring\n", name, b.Remaining, t.Below)
+	}
+
+	return a.mailer.Send(ctx, Message{
+		To:      to,
+		Bcc:     a.bcc,
+		Subject: subject,
+		Body:    body,
+	})
+}
@@ -0,0 +1,194 @@
+// backend/internal/notify/alerts_test.go
+
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/ledger"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/middleware"
+)
+
+// fakeMailer records the messages it is asked to send, failing with err
+// when it is set.
+type fakeMailer struct {
+	sent []Message
+	err  error
+}
+
+func (m *fakeMailer) Send(ctx context.Context, msg Message) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+// newTestAlerter returns an Alerter with the default thresholds over a
+// memory store where "family" has one billing guardian.
+func newTestAlerter(t *testing.T) (*Alerter, *MemoryStore, *fakeMailer) {
+	t.Helper()
+	thresholds, err := ParseThresholds(DefaultThresholds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := NewMemoryStore()
+	store.SetRecipients("family", Recipient{Email: "pat@example.com", Name: "Pat"})
+	mailer := &fakeMailer{}
+	return &Alerter{store: store, mailer: mailer, thresholds: thresholds, bcc: []string{"office@leetutoring.com"}}, store, mailer
+}
+
+func TestParseThresholds(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []float64
+	}{
+		{"", []float64{2, 0}},
+		{"5,2,0", []float64{5, 2, 0}},
+		{" 0, 5 ,2", []float64{5, 2, 0}},
+		{"2,2,0,2", []float64{2, 0}},
+		{"1.5,", []float64{1.5}},
+		{"-1", []float64{-1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseThresholds(tt.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var levels []float64
+			for _, th := range got {
+				levels = append(levels, th.Below)
+			}
+			if fmt.Sprint(levels) != fmt.Sprint(tt.want) {
+				t.Errorf("ParseThresholds(%q) = %v, want %v", tt.in, levels, tt.want)
+			}
+		})
+	}
+
+	for _, in := range []string{"two", "2;0", "2,zero", "NaN", "Inf"} {
+		if got, err := ParseThresholds(in); err == nil {
+			t.Errorf("ParseThresholds(%q) = %v, want an error", in, got)
+		}
+	}
+
+	if key := (Threshold{Below: 1.5}).Key(); key != "below_1_5" {
+		t.Errorf("Key = %q, want below_1_5", key)
+	}
+}
+
+func TestCheckBalanceSendsOncePerCrossing(t *testing.T) {
+	a, _, mailer := newTestAlerter(t)
+	ctx := context.Background()
+
+	steps := []struct {
+		remaining   float64
+		wantSubject string // empty when no email is expected
+	}{
+		{5, ""},
+		{1.5, "Your Lee Tutoring hours are running low"},
+		{1, ""},
+		{0.5, ""},
+		{-1, "Your Lee Tutoring hours are overdrawn"},
+		{-2, ""},
+	}
+	for _, step := range steps {
+		before := len(mailer.sent)
+		a.CheckBalance(ctx, &ledger.Balance{FamilyID: "family", Remaining: step.remaining})
+
+		sent := mailer.sent[before:]
+		if step.wantSubject == "" {
+			if len(sent) != 0 {
+				t.Errorf("remaining %v: sent %q, want nothing", step.remaining, sent[0].Subject)
+			}
+			continue
+		}
+		if len(sent) != 1 || sent[0].Subject != step.wantSubject {
+			t.Fatalf("remaining %v: sent %+v, want one %q", step.remaining, sent, step.wantSubject)
+		}
+		msg := sent[0]
+		if fmt.Sprint(msg.To) != "[pat@example.com]" || fmt.Sprint(msg.Bcc) != "[office@leetutoring.com]" || !strings.HasPrefix(msg.Body, "Hi Pat,") {
+			t.Errorf("remaining %v: message = %+v, want one to Pat, copied to the office", step.remaining, msg)
+		}
+	}
+}
+
+func TestCheckBalanceCrossingSeveralSendsLowest(t *testing.T) {
+	a, store, mailer := newTestAlerter(t)
+	ctx := context.Background()
+
+	a.CheckBalance(ctx, &ledger.Balance{FamilyID: "family", Remaining: -1})
+	if len(mailer.sent) != 1 || mailer.sent[0].Subject != "Your Lee Tutoring hours are overdrawn" {
+		t.Fatalf("sent %+v, want one overdrawn alert", mailer.sent)
+	}
+	if len(store.sent["family"]) != 2 {
+		t.Errorf("sent thresholds = %v, want both", store.sent["family"])
+	}
+
+	// Back above 0 but still under 2: only the overdrawn alert can fire again.
+	a.CheckBalance(ctx, &ledger.Balance{FamilyID: "family", Remaining: 1})
+	if len(mailer.sent) != 1 {
+		t.Errorf("sent %d alerts after paying off the overdraft, want no new one", len(mailer.sent)-1)
+	}
+	a.CheckBalance(ctx, &ledger.Balance{FamilyID: "family", Remaining: -0.5})
+	if len(mailer.sent) != 2 || mailer.sent[1].Subject != "Your Lee Tutoring hours are overdrawn" {
+		t.Errorf("sent %+v, want a second overdrawn alert", mailer.sent)
+	}
+}
+
+func TestCheckBalanceResetsAfterRecovery(t *testing.T) {
+	a, store, mailer := newTestAlerter(t)
+	ctx := context.Background()
+
+	for _, remaining := range []float64{1, 1.5, 10, 1.5} {
+		a.CheckBalance(ctx, &ledger.Balance{FamilyID: "family", Remaining: remaining})
+	}
+	if len(mailer.sent) != 2 {
+		t.Fatalf("sent %d alerts, want one before and one after buying hours", len(mailer.sent))
+	}
+
+	// At exactly the threshold the family is not below it.
+	a.CheckBalance(ctx, &ledger.Balance{FamilyID: "family", Remaining: 2})
+	if len(store.sent["family"]) != 0 {
+		t.Errorf("sent thresholds = %v, want none at the threshold", store.sent["family"])
+	}
+}
+
+func TestCheckBalanceReleasesFailedSend(t *testing.T) {
+	a, store, mailer := newTestAlerter(t)
+	ctx := context.Background()
+
+	mailer.err = errors.New("smtp: connection refused")
+	a.CheckBalance(ctx, &ledger.Balance{FamilyID: "family", Remaining: -1})
+	if len(store.sent["family"]) != 0 {
+		t.Fatalf("sent thresholds after a failed send = %v, want none", store.sent["family"])
+	}
+
+	// The next check tries again.
+	mailer.err = nil
+	a.CheckBalance(ctx, &ledger.Balance{FamilyID: "family", Remaining: -1})
+	if len(mailer.sent) != 1 {
+		t.Errorf("sent %d alerts on retry, want 1", len(mailer.sent))
+	}
+
+	// A family nobody can be emailed about is retried too.
+	a.CheckBalance(ctx, &ledger.Balance{FamilyID: "no-billing", Remaining: -1})
+	store.SetRecipients("no-billing")
+	a.CheckBalance(ctx, &ledger.Balance{FamilyID: "no-billing", Remaining: -1})
+	if len(store.sent["no-billing"]) != 0 {
+		t.Errorf("sent thresholds without recipients = %v, want none", store.sent["no-billing"])
+	}
+}
+
+func TestCheckBalanceSkippedWhileImpersonating(t *testing.T) {
+	a, store, mailer := newTestAlerter(t)
+	ctx := middleware.WithImpersonator(context.Background(), "admin-1")
+	a.CheckBalance(ctx, &ledger.Balance{FamilyID: "family", Remaining: -3})
+	if len(mailer.sent) != 0 || len(store.sent) != 0 {
+		t.Errorf("impersonated check sent %v and recorded %v, want nothing", mailer.sent, store.sent)
+	}
+}
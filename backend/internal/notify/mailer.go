@@ -0,0 +1,136 @@
+// backend/internal/notify/mailer.go
+
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/smtp"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/config"
+)
+
+// Message is a plain-text email.
+type Message struct {
+	To      []string
+	Bcc     []string
+	Subject string
+	Body    string
+}
+
+// Mailer sends email.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// NewMailer returns an SMTP mailer when SMTP_HOST is set. Otherwise messages
+// are written to the NOTIFY_OUTBOX file, or to the log if that is empty too,
+// so that nothing is emailed from development machines.
+func NewMailer(cfg *config.Config) Mailer {
+	if cfg.SMTP_HOST == "" {
+		log.Printf("SMTP_HOST is not set; emails go to %s", outboxName(cfg.NOTIFY_OUTBOX))
+		return &FileMailer{Path: cfg.NOTIFY_OUTBOX}
+	}
+	port := cfg.SMTP_PORT
+	if port == "" {
+		port = "587"
+	}
+	from := cfg.NOTIFY_FROM
+	if from == "" {
+		from = cfg.SMTP_USERNAME
+	}
+	return &SMTPMailer{
+		Host:     cfg.SMTP_HOST,
+		Port:     port,
+		Username: cfg.SMTP_USERNAME,
+		Password: cfg.SMTP_PASSWORD,
+		From:     from,
+	}
+}
+
+// SMTPMailer sends email through an SMTP server with PLAIN auth.
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// Send delivers msg to its To and Bcc recipients.
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+	recipients := append(append([]string{}, msg.To...), msg.Bcc...)
+	if err := smtp.SendMail(m.Host+":"+m.Port, auth, m.From, recipients, format(m.From, msg)); err != nil {
+		return fmt.Errorf("send %q to %v: %w", msg.Subject, msg.To, err)
+	}
+	return nil
+}
+
+// FileMailer appends every message to a file instead of sending it. With an
+// empty Path the messages are logged, with the query strings of any links
+// redacted, since those carry sign-in tokens and logs are widely readable.
+type FileMailer struct {
+	Path string
+
+	mu sync.Mutex
+}
+
+// Send records msg.
+func (m *FileMailer) Send(ctx context.Context, msg Message) error {
+	if m.Path == "" {
+		redacted := msg
+		redacted.Body = redactLinks(msg.Body)
+		log.Printf("Email not sent (no SMTP_HOST); set NOTIFY_OUTBOX to keep its links:\n%s", format("", redacted))
+		return nil
+	}
+	data := format("", msg)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f, err := os.OpenFile(m.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = fmt.Fprintf(f, "Date: %s\r\n%s\r\n\r\n", time.Now().Format(time.RFC1123Z), data)
+	return err
+}
+
+// linkQuery matches the query string of a link, e.g. "?token=abc&x=1",
+// keeping the first parameter's name.
+var linkQuery = regexp.MustCompile(`(\?[^\s=?]+=)\S+`)
+
+// redactLinks replaces the query strings of links in body.
+func redactLinks(body string) string {
+	return linkQuery.ReplaceAllString(body, "${1}[redacted]")
+}
+
+// format renders msg as an RFC 822 message. Bcc recipients are not listed.
+func format(from string, msg Message) []byte {
+	var b strings.Builder
+	if from != "" {
+		fmt.Fprintf(&b, "From: %s\r\n", from)
+	}
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(msg.To, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	b.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=\"utf-8\"\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+func outboxName(path string) string {
+	if path == "" {
+		return "the log"
+	}
+	return path
+}
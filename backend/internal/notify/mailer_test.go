@@ -0,0 +1,58 @@
+// backend/internal/notify/mailer_test.go
+
+package notify
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRedactLinks(t *testing.T) {
+	tests := []struct {
+		body string
+		want string
+	}{
+		{"Sign in: https://app.example.com/login?token=abc123\n", "Sign in: https://app.example.com/login?token=[redacted]\n"},
+		{"?token=abc123 works once", "?token=[redacted] works once"},
+		{"https://x.example.com/a?token=abc&return=/b.", "https://x.example.com/a?token=[redacted]"},
+		{"Did you ask? Then sign in.", "Did you ask? Then sign in."},
+		{"No links here.", "No links here."},
+	}
+	for _, tt := range tests {
+		if got := redactLinks(tt.body); got != tt.want {
+			t.Errorf("redactLinks(%q) = %q, want %q", tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestFileMailerLogsWithoutTokens(t *testing.T) {
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	msg := Message{To: []string{"pat@example.com"}, Subject: "Sign in", Body: "https://app.example.com/login?token=secret-token\n"}
+	if err := (&FileMailer{}).Send(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(logged.String(), "secret-token") || !strings.Contains(logged.String(), "pat@example.com") {
+		t.Errorf("logged %q, want the message without its token", logged.String())
+	}
+
+	// The outbox file is for developers, so it keeps the working link.
+	path := filepath.Join(t.TempDir(), "outbox.eml")
+	if err := (&FileMailer{Path: path}).Send(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "?token=secret-token") {
+		t.Errorf("outbox = %q, want the link", data)
+	}
+}
@@ -0,0 +1,173 @@
+// backend/internal/notify/store.go
+
+package notify
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/households"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Recipient is a guardian who receives a family's alerts.
+type Recipient struct {
+	Email string
+	Name  string
+}
+
+// Store keeps which alerts each family has been sent, and finds who to
+// send them to.
+type Store interface {
+	// UpdateSent passes the family's sent thresholds, keyed by
+	// Threshold.Key, to fn and saves them if fn reports a change.
+	// Concurrent updates of one family do not interleave.
+	UpdateSent(ctx context.Context, familyID string, fn func(sent map[string]time.Time) bool) error
+	// ForgetSent removes keys from the family's sent thresholds.
+	ForgetSent(ctx context.Context, familyID string, keys []string) error
+	// Recipients returns the household's guardians who can see its billing.
+	Recipients(ctx context.Context, householdID string) ([]Recipient, error)
+}
+
+// alertState is the document in "hours_alerts" for a family. Sent holds the
+// thresholds the family has been alerted for and when.
+type alertState struct {
+	Sent map[string]time.Time `firestore:"sent"`
+}
+
+type firestoreStore struct {
+	client *firestore.Client
+}
+
+// NewFirestoreStore returns a Store that keeps alert state in the
+// "hours_alerts" collection and reads recipients from households and
+// parents.
+func NewFirestoreStore(client *firestore.Client) Store {
+	return &firestoreStore{client: client}
+}
+
+func (s *firestoreStore) UpdateSent(ctx context.Context, familyID string, fn func(sent map[string]time.Time) bool) error {
+	ref := s.client.Collection("hours_alerts").Doc(familyID)
+	return s.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		state := alertState{Sent: map[string]time.Time{}}
+		snap, err := tx.Get(ref)
+		if err != nil && status.Code(err) != codes.NotFound {
+			return err
+		}
+		if err == nil {
+			if err := snap.DataTo(&state); err != nil {
+				return err
+			}
+			if state.Sent == nil {
+				state.Sent = map[string]time.Time{}
+			}
+		}
+		if !fn(state.Sent) {
+			return nil
+		}
+		return tx.Set(ref, state)
+	})
+}
+
+func (s *firestoreStore) ForgetSent(ctx context.Context, familyID string, keys []string) error {
+	updates := make([]firestore.Update, 0, len(keys))
+	for _, key := range keys {
+		updates = append(updates, firestore.Update{FieldPath: firestore.FieldPath{"sent", key}, Value: firestore.Delete})
+	}
+	_, err := s.client.Collection("hours_alerts").Doc(familyID).Update(ctx, updates)
+	return err
+}
+
+// Recipients prefers a guardian's invoice_email to their sign-in email.
+func (s *firestoreStore) Recipients(ctx context.Context, householdID string) ([]Recipient, error) {
+	snap, err := s.client.Collection(households.Collection).Doc(householdID).Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var h households.Household
+	if err := snap.DataTo(&h); err != nil {
+		return nil, err
+	}
+
+	var out []Recipient
+	for _, parentID := range h.GuardianIDs {
+		if !h.Can(parentID, households.PermissionBilling) {
+			continue
+		}
+		snap, err := s.client.Collection("parents").Doc(parentID).Get(ctx)
+		if err != nil {
+			log.Printf("Error fetching guardian %s of household %s: %v", parentID, householdID, err)
+			continue
+		}
+		data := snap.Data()
+		email, _ := data["email"].(string)
+		if business, ok := data["business"].(map[string]interface{}); ok {
+			if invoiceEmail, _ := business["invoice_email"].(string); invoiceEmail != "" {
+				email = invoiceEmail
+			}
+		}
+		if email == "" {
+			continue
+		}
+		name, _ := data["name"].(string)
+		out = append(out, Recipient{Email: email, Name: name})
+	}
+	return out, nil
+}
+
+// MemoryStore is an in-memory Store for tests and local runs.
+type MemoryStore struct {
+	mu         sync.Mutex
+	sent       map[string]map[string]time.Time
+	recipients map[string][]Recipient
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{sent: map[string]map[string]time.Time{}, recipients: map[string][]Recipient{}}
+}
+
+// SetRecipients sets who the household's alerts go to.
+func (m *MemoryStore) SetRecipients(householdID string, recipients ...Recipient) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.recipients[householdID] = recipients
+}
+
+func (m *MemoryStore) UpdateSent(ctx context.Context, familyID string, fn func(sent map[string]time.Time) bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sent := map[string]time.Time{}
+	for k, v := range m.sent[familyID] {
+		sent[k] = v
+	}
+	if fn(sent) {
+		m.sent[familyID] = sent
+	}
+	return nil
+}
+
+func (m *MemoryStore) ForgetSent(ctx context.Context, familyID string, keys []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.sent[familyID]; !ok {
+		return status.Errorf(codes.NotFound, "no alert state for family %s", familyID)
+	}
+	for _, key := range keys {
+		delete(m.sent[familyID], key)
+	}
+	return nil
+}
+
+func (m *MemoryStore) Recipients(ctx context.Context, householdID string) ([]Recipient, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.recipients[householdID]; !ok {
+		return nil, status.Errorf(codes.NotFound, "household %s not found", householdID)
+	}
+	return append([]Recipient(nil), m.recipients[householdID]...), nil
+}
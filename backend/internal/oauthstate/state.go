@@ -0,0 +1,291 @@
+// backend/internal/oauthstate/state.go
+
+package oauthstate
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// Every OAuth flow, sign-in or the Intuit connection, starts with Begin and
+// ends with Verify. Begin stores the flow under a random nonce and returns a
+// state that names the nonce, the provider and an expiry, signed with the
+// server's secret. The nonce is also set in a cookie, so the callback must
+// come back to the browser that started the flow. Verify checks all of that
+// and deletes the stored flow, so a state cannot be used twice.
+
+// TTL is how long a user has to finish signing in with a provider.
+const TTL = 10 * time.Minute
+
+// LinkCookie holds the identity link request a signed-in user started. Begin
+// only takes ?link= when it matches, so a link URL opened in another browser
+// cannot attach someone else's login to the account.
+const LinkCookie = "identity_link"
+
+var (
+	// ErrInvalidState is returned for a state that is malformed, has a bad
+	// signature, is for another provider or came back to another browser.
+	ErrInvalidState = errors.New("invalid OAuth state")
+	// ErrExpiredState is returned for a state older than TTL.
+	ErrExpiredState = errors.New("expired OAuth state")
+	// ErrReplayedState is returned for a state that was already used.
+	ErrReplayedState = errors.New("OAuth state already used")
+	// ErrLinkNotStarted is returned by Begin for a ?link= that was not
+	// started in the same browser.
+	ErrLinkNotStarted = errors.New("identity link not started in this browser")
+)
+
+// Flow is what Begin remembers about a flow until its callback.
+type Flow struct {
+	Provider string `firestore:"provider"`
+	// Nonce identifies the flow. Providers that take an OpenID nonce are
+	// given it, so it can be checked against their ID token.
+	Nonce string `firestore:"-"`
+	// Verifier is the PKCE code verifier, for providers that support PKCE.
+	Verifier string `firestore:"verifier,omitempty"`
+	// ReturnTo is the app path to go to after signing in.
+	ReturnTo string `firestore:"return_to,omitempty"`
+	// Link is the identity link request the flow was started for, if any.
+	Link      string    `firestore:"link,omitempty"`
+	ExpiresAt time.Time `firestore:"expires_at"`
+}
+
+// ExchangeOptions are the options to pass to oauth2.Config.Exchange.
+func (f *Flow) ExchangeOptions() []oauth2.AuthCodeOption {
+	if f.Verifier == "" {
+		return nil
+	}
+	return []oauth2.AuthCodeOption{oauth2.VerifierOption(f.Verifier)}
+}
+
+// claims are the signed part of a state.
+type claims struct {
+	Provider string `json:"p"`
+	Nonce    string `json:"n"`
+	Expires  int64  `json:"e"`
+}
+
+// Manager issues and checks OAuth states.
+type Manager struct {
+	key   []byte
+	store Store
+	now   func() time.Time
+}
+
+// NewManager returns a Manager that signs states with a key derived from secret.
+func NewManager(secret string, store Store) *Manager {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("oauthstate"))
+	return &Manager{key: mac.Sum(nil), store: store, now: time.Now}
+}
+
+// Options select what Begin asks the provider for.
+type Options struct {
+	// PKCE adds a code challenge to the authorization request.
+	PKCE bool
+	// OpenIDNonce sends the flow's nonce as the OpenID Connect nonce.
+	OpenIDNonce bool
+}
+
+// Begin starts a flow for provider and returns the state and the options to
+// pass to oauth2.Config.AuthCodeURL. The request's ?return= (an app path) and
+// ?link= (an identity link request, which must match LinkCookie) are kept for
+// the callback.
+func (m *Manager) Begin(w http.ResponseWriter, r *http.Request, provider string, opts Options) (string, []oauth2.AuthCodeOption, error) {
+	link := r.URL.Query().Get("link")
+	if link != "" {
+		cookie, err := r.Cookie(LinkCookie)
+		if err != nil || !hmac.Equal([]byte(cookie.Value), []byte(link)) {
+			return "", nil, ErrLinkNotStarted
+		}
+	}
+
+	nonce, err := randomString(32)
+	if err != nil {
+		return "", nil, err
+	}
+	expires := m.now().Add(TTL)
+	flow := Flow{
+		Provider:  provider,
+		Nonce:     nonce,
+		ReturnTo:  SafeReturnPath(r.URL.Query().Get("return")),
+		Link:      link,
+		ExpiresAt: expires,
+	}
+
+	var authOpts []oauth2.AuthCodeOption
+	if opts.PKCE {
+		flow.Verifier = oauth2.GenerateVerifier()
+		authOpts = append(authOpts, oauth2.S256ChallengeOption(flow.Verifier))
+	}
+	if opts.OpenIDNonce {
+		authOpts = append(authOpts, oauth2.SetAuthURLParam("nonce", nonce))
+	}
+
+	if err := m.store.Create(r.Context(), nonce, flow); err != nil {
+		return "", nil, fmt.Errorf("store OAuth state: %w", err)
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     cookieName(provider),
+		Value:    nonce,
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   true,
+		// Apple posts its callback from its own site.
+		SameSite: http.SameSiteNoneMode,
+	})
+
+	return m.sign(claims{Provider: provider, Nonce: nonce, Expires: expires.Unix()}), authOpts, nil
+}
+
+// Verify checks the state a provider's callback came back with and returns
+// the flow it started. The flow can only be returned once.
+func (m *Manager) Verify(w http.ResponseWriter, r *http.Request, provider, state string) (*Flow, error) {
+	c, err := m.parse(state)
+	if err != nil {
+		return nil, err
+	}
+	if c.Provider != provider {
+		return nil, ErrInvalidState
+	}
+	if m.now().Unix() > c.Expires {
+		return nil, ErrExpiredState
+	}
+
+	cookie, err := r.Cookie(cookieName(provider))
+	if err != nil || !hmac.Equal([]byte(cookie.Value), []byte(c.Nonce)) {
+		return nil, ErrInvalidState
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     cookieName(provider),
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteNoneMode,
+	})
+
+	flow, found, err := m.store.Take(r.Context(), c.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("load OAuth state: %w", err)
+	}
+	if !found {
+		return nil, ErrReplayedState
+	}
+	if flow.Provider != provider || m.now().After(flow.ExpiresAt) {
+		return nil, ErrExpiredState
+	}
+	flow.Nonce = c.Nonce
+	return flow, nil
+}
+
+// Error writes the response for an error returned by Begin or Verify.
+func Error(w http.ResponseWriter, err error) {
+	log.Printf("OAuth state error: %v", err)
+	switch {
+	case errors.Is(err, ErrInvalidState), errors.Is(err, ErrReplayedState):
+		http.Error(w, "Invalid OAuth state", http.StatusBadRequest)
+	case errors.Is(err, ErrExpiredState):
+		http.Error(w, "Sign-in took too long, please try again", http.StatusBadRequest)
+	case errors.Is(err, ErrLinkNotStarted):
+		http.Error(w, "Start linking from your account settings in this browser", http.StatusForbidden)
+	default:
+		http.Error(w, "Failed to handle OAuth state", http.StatusInternalServerError)
+	}
+}
+
+func (m *Manager) sign(c claims) string {
+	payload, _ := json.Marshal(c)
+	body := base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, m.key)
+	mac.Write([]byte(body))
+	return body + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (m *Manager) parse(state string) (*claims, error) {
+	body, sig, ok := strings.Cut(state, ".")
+	if !ok {
+		return nil, ErrInvalidState
+	}
+	got, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil {
+		return nil, ErrInvalidState
+	}
+	mac := hmac.New(sha256.New, m.key)
+	mac.Write([]byte(body))
+	if !hmac.Equal(got, mac.Sum(nil)) {
+		return nil, ErrInvalidState
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(body)
+	if err != nil {
+		return nil, ErrInvalidState
+	}
+	var c claims
+	if err := json.Unmarshal(payload, &c); err != nil || c.Nonce == "" {
+		return nil, ErrInvalidState
+	}
+	return &c, nil
+}
+
+// SetLinkCookie binds an identity link request to the browser that asked
+// for it. Like the state cookie it is SameSite=None, because the request
+// comes from the app's own site and Apple posts its callback from its own.
+func SetLinkCookie(w http.ResponseWriter, link string, expires time.Time) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     LinkCookie,
+		Value:    link,
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteNoneMode,
+	})
+}
+
+// ClearLinkCookie removes the cookie set by SetLinkCookie.
+func ClearLinkCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     LinkCookie,
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteNoneMode,
+	})
+}
+
+func cookieName(provider string) string {
+	return "oauthstate_" + provider
+}
+
+// SafeReturnPath only allows paths within the app, so a state cannot send
+// the user to another site after signing in.
+func SafeReturnPath(path string) string {
+	if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") || strings.Contains(path, `\`) {
+		return ""
+	}
+	return path
+}
+
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
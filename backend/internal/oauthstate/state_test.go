@@ -0,0 +1,211 @@
+// backend/internal/oauthstate/state_test.go
+
+package oauthstate
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// begin starts a Google flow returning to /dashboard and returns the state
+// and the cookies set with it.
+func begin(t *testing.T, m *Manager) (string, []*http.Cookie) {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodGet, "/internal/googleauth/oauth?return=/dashboard", nil)
+	w := httptest.NewRecorder()
+	state, _, err := m.Begin(w, r, "google", Options{PKCE: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return state, w.Result().Cookies()
+}
+
+// callback is the provider's redirect back to the browser with cookies.
+func callback(cookies []*http.Cookie) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/internal/googleauth/callback", nil)
+	for _, c := range cookies {
+		r.AddCookie(c)
+	}
+	return r
+}
+
+func TestVerify(t *testing.T) {
+	// flip changes the last character of s.
+	flip := func(s string) string {
+		last := "A"
+		if strings.HasSuffix(s, "A") {
+			last = "B"
+		}
+		return s[:len(s)-1] + last
+	}
+
+	tests := []struct {
+		name string
+		// prepare changes the state, cookies or clock before the callback.
+		prepare  func(m *Manager, state string, cookies []*http.Cookie) (string, []*http.Cookie)
+		provider string
+		want     error
+	}{
+		{name: "valid"},
+		{
+			name: "tampered signature",
+			prepare: func(m *Manager, state string, cookies []*http.Cookie) (string, []*http.Cookie) {
+				return flip(state), cookies
+			},
+			want: ErrInvalidState,
+		},
+		{
+			name: "signed with another secret",
+			prepare: func(m *Manager, state string, cookies []*http.Cookie) (string, []*http.Cookie) {
+				body, _, _ := strings.Cut(state, ".")
+				other := NewManager("other-secret", NewMemoryStore())
+				_, sig, _ := strings.Cut(other.sign(claims{Provider: "google", Nonce: "x"}), ".")
+				return body + "." + sig, cookies
+			},
+			want: ErrInvalidState,
+		},
+		{
+			name: "claims changed",
+			prepare: func(m *Manager, state string, cookies []*http.Cookie) (string, []*http.Cookie) {
+				_, sig, _ := strings.Cut(state, ".")
+				forged, _, _ := strings.Cut(m.sign(claims{Provider: "google", Nonce: "attacker", Expires: time.Now().Add(TTL).Unix()}), ".")
+				return forged + "." + sig, cookies
+			},
+			want: ErrInvalidState,
+		},
+		{
+			name: "malformed",
+			prepare: func(m *Manager, state string, cookies []*http.Cookie) (string, []*http.Cookie) {
+				return "not-a-state", cookies
+			},
+			want: ErrInvalidState,
+		},
+		{name: "other provider", provider: "microsoft", want: ErrInvalidState},
+		{
+			name: "no cookie",
+			prepare: func(m *Manager, state string, cookies []*http.Cookie) (string, []*http.Cookie) {
+				return state, nil
+			},
+			want: ErrInvalidState,
+		},
+		{
+			name: "cookie from another flow",
+			prepare: func(m *Manager, state string, cookies []*http.Cookie) (string, []*http.Cookie) {
+				_, other := begin(t, m)
+				return state, other
+			},
+			want: ErrInvalidState,
+		},
+		{
+			name: "expired",
+			prepare: func(m *Manager, state string, cookies []*http.Cookie) (string, []*http.Cookie) {
+				later := time.Now().Add(TTL + time.Second)
+				m.now = func() time.Time { return later }
+				return state, cookies
+			},
+			want: ErrExpiredState,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemoryStore()
+			m := NewManager("test-secret", store)
+			state, cookies := begin(t, m)
+			if tt.prepare != nil {
+				state, cookies = tt.prepare(m, state, cookies)
+			}
+			provider := tt.provider
+			if provider == "" {
+				provider = "google"
+			}
+
+			flow, err := m.Verify(httptest.NewRecorder(), callback(cookies), provider, state)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Verify error = %v, want %v", err, tt.want)
+			}
+			if err != nil {
+				return
+			}
+			if flow.Provider != "google" || flow.ReturnTo != "/dashboard" || flow.Verifier == "" || flow.Nonce == "" {
+				t.Errorf("flow = %+v, want google's, returning to /dashboard with a verifier", flow)
+			}
+		})
+	}
+}
+
+func TestVerifyOnce(t *testing.T) {
+	store := NewMemoryStore()
+	m := NewManager("test-secret", store)
+	state, cookies := begin(t, m)
+
+	w := httptest.NewRecorder()
+	if _, err := m.Verify(w, callback(cookies), "google", state); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	for _, c := range w.Result().Cookies() {
+		if c.Name == cookieName("google") && c.MaxAge >= 0 {
+			t.Errorf("state cookie = %+v, want it cleared", c)
+		}
+	}
+
+	// The same callback again, e.g. replayed from history, finds no flow.
+	if _, err := m.Verify(httptest.NewRecorder(), callback(cookies), "google", state); !errors.Is(err, ErrReplayedState) {
+		t.Errorf("second Verify error = %v, want %v", err, ErrReplayedState)
+	}
+	nonce := cookies[0].Value
+	if _, found, err := store.Take(context.Background(), nonce); found || err != nil {
+		t.Errorf("second Take = %v, %v; want no flow", found, err)
+	}
+}
+
+func TestBeginLink(t *testing.T) {
+	tests := []struct {
+		name     string
+		cookie   string
+		wantLink string
+		wantErr  error
+	}{
+		{name: "cookie matches", cookie: "link-nonce", wantLink: "link-nonce"},
+		{name: "no cookie", wantErr: ErrLinkNotStarted},
+		{name: "cookie for another request", cookie: "other-nonce", wantErr: ErrLinkNotStarted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemoryStore()
+			m := NewManager("test-secret", store)
+			r := httptest.NewRequest(http.MethodGet, "/internal/googleauth/oauth?link=link-nonce", nil)
+			if tt.cookie != "" {
+				r.AddCookie(&http.Cookie{Name: LinkCookie, Value: tt.cookie})
+			}
+			w := httptest.NewRecorder()
+
+			state, _, err := m.Begin(w, r, "google", Options{})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Begin error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if len(store.flows) != 0 {
+					t.Errorf("flow stored for a refused link")
+				}
+				return
+			}
+
+			callback := httptest.NewRequest(http.MethodGet, "/internal/googleauth/callback", nil)
+			for _, c := range w.Result().Cookies() {
+				callback.AddCookie(c)
+			}
+			flow, err := m.Verify(httptest.NewRecorder(), callback, "google", state)
+			if err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+			if flow.Link != tt.wantLink {
+				t.Errorf("flow.Link = %q, want %q", flow.Link, tt.wantLink)
+			}
+		})
+	}
+}
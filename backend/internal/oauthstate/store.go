@@ -0,0 +1,92 @@
+// backend/internal/oauthstate/store.go
+
+package oauthstate
+
+import (
+	"context"
+	"sync"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Store keeps flows between Begin and Verify.
+type Store interface {
+	Create(ctx context.Context, nonce string, flow Flow) error
+	// Take returns and deletes a flow, so each can be used once.
+	Take(ctx context.Context, nonce string) (*Flow, bool, error)
+}
+
+type firestoreStore struct {
+	client *firestore.Client
+}
+
+// NewFirestoreStore returns a Store that keeps flows in the "oauth_states"
+// collection. Their expires_at field can drive a Firestore TTL policy for
+// flows that were never finished.
+func NewFirestoreStore(client *firestore.Client) Store {
+	return &firestoreStore{client: client}
+}
+
+func (s *firestoreStore) Create(ctx context.Context, nonce string, flow Flow) error {
+	_, err := s.client.Collection("oauth_states").Doc(nonce).Create(ctx, flow)
+	return err
+}
+
+func (s *firestoreStore) Take(ctx context.Context, nonce string) (*Flow, bool, error) {
+	ref := s.client.Collection("oauth_states").Doc(nonce)
+	var flow *Flow
+	err := s.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		flow = nil
+		snap, err := tx.Get(ref)
+		if status.Code(err) == codes.NotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var got Flow
+		if err := snap.DataTo(&got); err != nil {
+			return err
+		}
+		flow = &got
+		return tx.Delete(ref)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return flow, flow != nil, nil
+}
+
+// MemoryStore is an in-memory Store for tests and local runs.
+type MemoryStore struct {
+	mu    sync.Mutex
+	flows map[string]Flow
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{flows: map[string]Flow{}}
+}
+
+func (m *MemoryStore) Create(ctx context.Context, nonce string, flow Flow) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.flows[nonce]; ok {
+		return status.Errorf(codes.AlreadyExists, "OAuth state %s already exists", nonce)
+	}
+	m.flows[nonce] = flow
+	return nil
+}
+
+func (m *MemoryStore) Take(ctx context.Context, nonce string) (*Flow, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	flow, ok := m.flows[nonce]
+	if !ok {
+		return nil, false, nil
+	}
+	delete(m.flows, nonce)
+	return &flow, true, nil
+}
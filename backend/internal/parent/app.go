@@ -6,6 +6,11 @@ import (
 	"cloud.google.com/go/firestore"
 
 	"github.com/NathanielJBrown97/LeeTutoringApp/internal/config"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/households"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/identity"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/invitations"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/studentlinks"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/students"
 	"github.com/gorilla/sessions"
 )
 
@@ -14,4 +19,13 @@ type App struct {
 	Config          *config.Config
 	FirestoreClient *firestore.Client
 	Store           *sessions.CookieStore
+	Students        students.Repository
+	// Households own the students a parent is linked to.
+	Households *households.Manager
+	// Invitations are redeemed to link a student to a parent.
+	Invitations *invitations.Manager
+	// Accounts finds other parent accounts with the same email.
+	Accounts identity.Store
+	// LinkRequests queues the automatic links that need staff review.
+	LinkRequests *studentlinks.Queue
 }
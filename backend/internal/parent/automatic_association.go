@@ -5,15 +5,20 @@ package parent
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
 
-	"cloud.google.com/go/firestore"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/households"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/identity"
 	"github.com/NathanielJBrown97/LeeTutoringApp/internal/middleware"
-	"google.golang.org/api/iterator"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/studentlinks"
 )
 
+// AttemptAutomaticAssociation adds the students whose personal.parent_email
+// is the parent's email to the parent's household. A student that is already
+// in another household, or an email that more than one parent account has,
+// is queued for staff to review instead.
 func (a *App) AttemptAutomaticAssociation(w http.ResponseWriter, r *http.Request) {
 	// Authentication is handled via middleware
 	userID, err := middleware.ExtractUserIDFromContext(r.Context())
@@ -34,58 +39,115 @@ func (a *App) AttemptAutomaticAssociation(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	// Fetch parent document from Firestore
-	parentDocRef := a.FirestoreClient.Collection("parents").Doc(userID)
-	_, err = parentDocRef.Get(r.Context())
-	if err != nil {
-		if status.Code(err) == codes.NotFound {
-			// Parent document doesn't exist, create it
-			_, err := parentDocRef.Set(context.Background(), map[string]interface{}{
-				"associated_students": []string{},
-			})
-			if err != nil {
-				http.Error(w, "Failed to create parent document", http.StatusInternalServerError)
-				return
-			}
-		} else {
-			http.Error(w, "Failed to retrieve parent data", http.StatusInternalServerError)
-			return
+	// Students already in the parent's household stay linked
+	alreadyLinked := map[string]bool{}
+	household, err := a.Households.ForGuardian(r.Context(), userID)
+	if err != nil && !errors.Is(err, households.ErrNotFound) {
+		log.Printf("Error fetching household of parent %s: %v", userID, err)
+		http.Error(w, "Failed to retrieve parent data", http.StatusInternalServerError)
+		return
+	}
+	if household != nil {
+		for _, id := range household.StudentIDs {
+			alreadyLinked[id] = true
 		}
 	}
 
 	// Attempt automatic association
 	// Query students where personal.parent_email == email
-	studentsQuery := a.FirestoreClient.Collection("students").Where("personal.parent_email", "==", email)
-	studentsIter := studentsQuery.Documents(r.Context())
-	defer studentsIter.Stop()
+	found, err := a.Students.FindByParentEmail(r.Context(), email)
+	if err != nil {
+		http.Error(w, "Error querying students", http.StatusInternalServerError)
+		return
+	}
+
+	sharedEmail := false
+	if len(found) > 0 {
+		accounts, err := a.Accounts.FindByEmail(r.Context(), identity.ParentsCollection, email)
+		if err != nil {
+			log.Printf("Error looking up parents with email %s: %v", email, err)
+			http.Error(w, "Error querying parents", http.StatusInternalServerError)
+			return
+		}
+		for _, id := range accounts {
+			if id != userID {
+				sharedEmail = true
+			}
+		}
+	}
 
-	var foundStudentIDs []interface{}
-	for {
-		doc, err := studentsIter.Next()
-		if err == iterator.Done {
-			break
+	var foundStudentIDs, pendingStudentIDs []string
+	for _, student := range found {
+		if alreadyLinked[student.ID] {
+			foundStudentIDs = append(foundStudentIDs, student.ID)
+			continue
 		}
+
+		reason := ""
+		if sharedEmail {
+			reason = studentlinks.ReasonSharedEmail
+		} else {
+			linkedElsewhere, err := a.linkedToOtherParent(r.Context(), student.ID, userID)
+			if err != nil {
+				log.Printf("Error checking parents of student %s: %v", student.ID, err)
+				http.Error(w, "Error querying parents", http.StatusInternalServerError)
+				return
+			}
+			if linkedElsewhere {
+				reason = studentlinks.ReasonLinkedElsewhere
+			}
+		}
+		if reason == "" {
+			foundStudentIDs = append(foundStudentIDs, student.ID)
+			continue
+		}
+
+		queued, err := a.LinkRequests.Submit(r.Context(), studentlinks.Request{
+			ParentID:    userID,
+			ParentEmail: email,
+			StudentID:   student.ID,
+			StudentName: student.Personal.Name,
+			Reason:      reason,
+		})
 		if err != nil {
-			http.Error(w, "Error querying students", http.StatusInternalServerError)
+			log.Printf("Error queueing link of student %s to parent %s: %v", student.ID, userID, err)
+			http.Error(w, "Failed to queue student link", http.StatusInternalServerError)
 			return
 		}
-		foundStudentIDs = append(foundStudentIDs, doc.Ref.ID)
+		if queued {
+			log.Printf("Queued link of student %s to parent %s for review (%s)", student.ID, userID, reason)
+		}
+		pendingStudentIDs = append(pendingStudentIDs, student.ID)
 	}
 
 	if len(foundStudentIDs) > 0 {
-		// Update parent's associated_students field
-		_, err := parentDocRef.Set(context.Background(), map[string]interface{}{
-			"associated_students": firestore.ArrayUnion(foundStudentIDs...),
-		}, firestore.MergeAll)
-		if err != nil {
+		// Add the students to the parent's household
+		if _, err := a.Households.AddStudents(r.Context(), userID, foundStudentIDs...); err != nil {
+			log.Printf("Error adding students %v to household of parent %s: %v", foundStudentIDs, userID, err)
 			http.Error(w, "Failed to update parent document", http.StatusInternalServerError)
 			return
 		}
 	}
 
-	// Return the associated students
+	// Return the associated students, and those waiting for review
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"associatedStudents": foundStudentIDs,
+		"pendingStudents":    pendingStudentIDs,
 	})
 }
+
+// linkedToOtherParent reports whether a household that parentID is not a
+// guardian of has the student.
+func (a *App) linkedToOtherParent(ctx context.Context, studentID, parentID string) (bool, error) {
+	hs, err := a.Households.ListByStudent(ctx, studentID)
+	if err != nil {
+		return false, err
+	}
+	for _, h := range hs {
+		if _, ok := h.Guardians[parentID]; !ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
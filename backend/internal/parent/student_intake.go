@@ -5,21 +5,39 @@ package parent
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/invitations"
 	"github.com/NathanielJBrown97/LeeTutoringApp/internal/middleware"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/students"
 )
 
 type StudentInfo struct {
-	StudentID   string `json:"studentId"`
+	Code        string `json:"code"`
+	StudentID   string `json:"studentId,omitempty"`
 	StudentName string `json:"studentName"`
 	CanLink     bool   `json:"canLink"`
 }
 
-// StudentIntakeHandler handles the submission of student IDs
+// invitationProblem describes why an invitation code cannot be used, or
+// returns "" for errors that are not the parent's to fix.
+func invitationProblem(err error) string {
+	switch {
+	case errors.Is(err, invitations.ErrNotFound):
+		return "Invalid code"
+	case errors.Is(err, invitations.ErrExpired):
+		return "Code has expired"
+	case errors.Is(err, invitations.ErrRedeemed):
+		return "Code has already been used"
+	}
+	return ""
+}
+
+// StudentIntakeHandler looks up the students for the invitation codes a
+// parent entered, so they can confirm them before linking. Codes are not
+// used up here.
 func (a *App) StudentIntakeHandler(w http.ResponseWriter, r *http.Request) {
 	// Authentication is handled via middleware
 	userID, err := middleware.ExtractUserIDFromContext(r.Context())
@@ -27,12 +45,10 @@ func (a *App) StudentIntakeHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Unauthorized: User ID not found", http.StatusUnauthorized)
 		return
 	}
-	// Optionally, use userID for logging or other purposes
-	log.Printf("User ID in StudentIntakeHandler: %s", userID)
 
 	// Parse the JSON request body
 	var requestData struct {
-		StudentIDs []string `json:"studentIds"`
+		Codes []string `json:"codes"`
 	}
 	err = json.NewDecoder(r.Body).Decode(&requestData)
 	if err != nil {
@@ -40,67 +56,71 @@ func (a *App) StudentIntakeHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if len(requestData.StudentIDs) == 0 {
-		http.Error(w, "No student IDs provided", http.StatusBadRequest)
+	if len(requestData.Codes) == 0 {
+		http.Error(w, "No invitation codes provided", http.StatusBadRequest)
 		return
 	}
 
-	// Use the existing Firestore client from App
-	firestoreClient := a.FirestoreClient
-
 	// Prepare to store student names or errors
 	var studentInfos []StudentInfo
 
-	// Loop through the student IDs and fetch names from Firestore
-	for _, studentID := range requestData.StudentIDs {
-		if studentID == "" {
+	// Loop through the codes and fetch the students they are for
+	for _, code := range requestData.Codes {
+		if invitations.NormalizeCode(code) == "" {
 			continue
 		}
 
+		inv, err := a.Invitations.Check(r.Context(), userID, code)
+		if errors.Is(err, invitations.ErrTooManyAttempts) {
+			log.Printf("Parent %s has tried too many invalid invitation codes", userID)
+			http.Error(w, "Too many invalid codes. Please try again later.", http.StatusTooManyRequests)
+			return
+		}
+		if problem := invitationProblem(err); problem != "" {
+			log.Printf("Parent %s entered an unusable invitation code: %v", userID, err)
+			studentInfos = append(studentInfos, StudentInfo{
+				Code:        code,
+				StudentName: problem,
+				CanLink:     false, // Flag that this student cannot be linked
+			})
+			continue
+		}
+		if err != nil {
+			log.Printf("Error checking invitation code for parent %s: %v", userID, err)
+			http.Error(w, "Failed to check invitation code", http.StatusInternalServerError)
+			return
+		}
+
 		// Access the student document in Firestore
-		docRef := firestoreClient.Collection("students").Doc(studentID)
-		doc, err := docRef.Get(context.Background())
+		student, err := a.Students.Get(context.Background(), inv.StudentID)
 		if err != nil {
-			if status.Code(err) == codes.NotFound {
-				log.Printf("Student ID %s: Document not found", studentID)
+			if errors.Is(err, students.ErrNotFound) {
+				log.Printf("Invitation %s is for missing student %s", inv.ID, inv.StudentID)
 				studentInfos = append(studentInfos, StudentInfo{
-					StudentID:   studentID,
-					StudentName: "Not found",
+					Code:        code,
+					StudentName: "Student not found",
 					CanLink:     false, // Flag that this student cannot be linked
 				})
 				continue
-			} else {
-				log.Printf("Error retrieving student ID %s: %v", studentID, err)
-				http.Error(w, "Failed to retrieve student data", http.StatusInternalServerError)
-				return
 			}
+			log.Printf("Error retrieving student ID %s: %v", inv.StudentID, err)
+			http.Error(w, "Failed to retrieve student data", http.StatusInternalServerError)
+			return
 		}
 
-		// Access the personal sub-document data as a map
-		personalData, ok := doc.Data()["personal"].(map[string]interface{})
-		if !ok {
-			log.Printf("Student ID %s: Personal data not found or is not a map", studentID)
-			studentInfos = append(studentInfos, StudentInfo{
-				StudentID:   studentID,
-				StudentName: "No personal data",
-				CanLink:     false, // Flag that this student cannot be linked
-			})
-			continue
-		}
-
-		// Extract the "name" field from the personal data
-		studentName, ok := personalData["name"].(string)
-		if !ok || studentName == "" {
-			log.Printf("Student ID %s: Name field not found or is empty", studentID)
+		if student.Personal.Name == "" {
+			log.Printf("Student ID %s: Name field not found or is empty", inv.StudentID)
 			studentInfos = append(studentInfos, StudentInfo{
-				StudentID:   studentID,
+				Code:        code,
+				StudentID:   inv.StudentID,
 				StudentName: "No name field",
 				CanLink:     false, // Flag that this student cannot be linked
 			})
 		} else {
 			studentInfos = append(studentInfos, StudentInfo{
-				StudentID:   studentID,
-				StudentName: studentName,
+				Code:        code,
+				StudentID:   inv.StudentID,
+				StudentName: student.Personal.Name,
 				CanLink:     true, // Flag that this student can be linked
 			})
 		}
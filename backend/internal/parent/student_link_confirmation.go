@@ -5,22 +5,17 @@ package parent
 import (
 	"context"
 	"encoding/json"
-	"fmt"
+	"errors"
+	"log"
 	"net/http"
 
-	"cloud.google.com/go/firestore"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/invitations"
 	"github.com/NathanielJBrown97/LeeTutoringApp/internal/middleware"
 )
 
-func sliceStringsToInterfaces(slice []string) []interface{} {
-	interfaces := make([]interface{}, len(slice))
-	for i, v := range slice {
-		interfaces[i] = v
-	}
-	return interfaces
-}
-
-// ConfirmLinkStudentsHandler handles the confirmation of linking students
+// ConfirmLinkStudentsHandler redeems the invitation codes a parent confirmed
+// and adds their students to the parent's household. The redeemed invitations record
+// who linked each student and when.
 func (a *App) ConfirmLinkStudentsHandler(w http.ResponseWriter, r *http.Request) {
 	// Authentication is handled via middleware
 	userID, err := middleware.ExtractUserIDFromContext(r.Context())
@@ -31,7 +26,7 @@ func (a *App) ConfirmLinkStudentsHandler(w http.ResponseWriter, r *http.Request)
 
 	// Parse the JSON request body
 	var requestData struct {
-		ConfirmedStudentIDs []string `json:"confirmedStudentIds"`
+		ConfirmedCodes []string `json:"confirmedCodes"`
 	}
 	err = json.NewDecoder(r.Body).Decode(&requestData)
 	if err != nil {
@@ -39,31 +34,53 @@ func (a *App) ConfirmLinkStudentsHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if len(requestData.ConfirmedStudentIDs) == 0 {
-		http.Error(w, "No confirmed student IDs provided", http.StatusBadRequest)
+	if len(requestData.ConfirmedCodes) == 0 {
+		http.Error(w, "No confirmed invitation codes provided", http.StatusBadRequest)
 		return
 	}
 
-	// Convert []string to []interface{}
-	confirmedStudentIDsInterface := sliceStringsToInterfaces(requestData.ConfirmedStudentIDs)
-
-	// Use the existing Firestore client from App
-	firestoreClient := a.FirestoreClient
-
-	// Reference to the parent's document using the userID from the session
-	parentDocRef := firestoreClient.Collection("parents").Doc(userID)
+	// Redeem every code before linking, so nothing is linked with a bad code
+	var redeemedCodes, studentIDs []string
+	release := func() {
+		for _, code := range redeemedCodes {
+			if err := a.Invitations.Release(context.Background(), code); err != nil {
+				log.Printf("Error releasing invitation code for parent %s: %v", userID, err)
+			}
+		}
+	}
+	for _, code := range requestData.ConfirmedCodes {
+		inv, err := a.Invitations.Redeem(r.Context(), userID, code)
+		if err != nil {
+			release()
+			if errors.Is(err, invitations.ErrTooManyAttempts) {
+				http.Error(w, "Too many invalid codes. Please try again later.", http.StatusTooManyRequests)
+				return
+			}
+			if problem := invitationProblem(err); problem != "" {
+				http.Error(w, problem+": "+code, http.StatusBadRequest)
+				return
+			}
+			log.Printf("Error redeeming invitation code for parent %s: %v", userID, err)
+			http.Error(w, "Failed to redeem invitation code", http.StatusInternalServerError)
+			return
+		}
+		redeemedCodes = append(redeemedCodes, code)
+		studentIDs = append(studentIDs, inv.StudentID)
+	}
 
-	// Update the parent's associated_students field
-	_, err = parentDocRef.Set(context.Background(), map[string]interface{}{
-		"associated_students": firestore.ArrayUnion(confirmedStudentIDsInterface...),
-	}, firestore.MergeAll)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to link students: %v", err), http.StatusInternalServerError)
+	// Add the students to the parent's household
+	if _, err := a.Households.AddStudents(r.Context(), userID, studentIDs...); err != nil {
+		log.Printf("Error linking students %v to parent %s: %v", studentIDs, userID, err)
+		release()
+		http.Error(w, "Failed to link students", http.StatusInternalServerError)
 		return
 	}
+	log.Printf("Parent %s linked students %v with invitation codes", userID, studentIDs)
 
 	// Return a success response
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(`{"message":"Students linked successfully"}`))
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":        "Students linked successfully",
+		"linkedStudents": studentIDs,
+	})
 }
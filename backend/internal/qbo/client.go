@@ -0,0 +1,147 @@
+// backend/internal/qbo/client.go
+
+package qbo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/config"
+	"golang.org/x/oauth2"
+)
+
+// QuickBooks environments.
+const (
+	Sandbox    = "sandbox"
+	Production = "production"
+)
+
+const defaultMinorVersion = "65"
+
+var baseURLs = map[string]string{
+	Sandbox:    "https://sandbox-quickbooks.api.intuit.com",
+	Production: "https://quickbooks.api.intuit.com",
+}
+
+// Settings select the QuickBooks company the app talks to.
+type Settings struct {
+	Environment  string
+	BaseURL      string
+	MinorVersion string
+	// RealmID is the company used when a record does not say which one it
+	// belongs to.
+	RealmID string
+}
+
+// SettingsFromConfig reads QBO_ENVIRONMENT (sandbox by default), QBO_BASE_URL,
+// QBO_MINOR_VERSION and INTUIT_REALM_ID. QBO_BASE_URL overrides the URL the
+// environment implies.
+func SettingsFromConfig(cfg *config.Config) (Settings, error) {
+	s := Settings{
+		Environment:  strings.ToLower(strings.TrimSpace(cfg.QBO_ENVIRONMENT)),
+		BaseURL:      strings.TrimRight(strings.TrimSpace(cfg.QBO_BASE_URL), "/"),
+		MinorVersion: strings.TrimSpace(cfg.QBO_MINOR_VERSION),
+		RealmID:      strings.TrimSpace(cfg.INTUIT_REALM_ID),
+	}
+	if s.Environment == "" {
+		s.Environment = Sandbox
+	}
+	if s.BaseURL == "" {
+		base, ok := baseURLs[s.Environment]
+		if !ok {
+			return Settings{}, fmt.Errorf("unknown QBO_ENVIRONMENT %q, want %q or %q", s.Environment, Sandbox, Production)
+		}
+		s.BaseURL = base
+	}
+	if s.MinorVersion == "" {
+		s.MinorVersion = defaultMinorVersion
+	}
+	return s, nil
+}
+
+// TokenFunc returns a valid access token for the company.
+type TokenFunc func(ctx context.Context, realmID string) (*oauth2.Token, error)
+
+// Client calls the QuickBooks Online accounting API.
+type Client struct {
+	settings   Settings
+	tokens     TokenFunc
+	httpClient *http.Client
+}
+
+// NewClient returns a Client that authenticates with tokens. A nil httpClient
+// uses a client with a 30 second timeout.
+func NewClient(settings Settings, tokens TokenFunc, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &Client{settings: settings, tokens: tokens, httpClient: httpClient}
+}
+
+// Environment is "sandbox" or "production".
+func (c *Client) Environment() string {
+	return c.settings.Environment
+}
+
+// DefaultRealm is the configured company ID, or "" if none is configured.
+func (c *Client) DefaultRealm() string {
+	return c.settings.RealmID
+}
+
+// Error is a non-200 response from QuickBooks.
+type Error struct {
+	Entity     string
+	ID         string
+	StatusCode int
+	Body       string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s %s fetch returned status %d: %s", e.Entity, e.ID, e.StatusCode, e.Body)
+}
+
+// Get reads one entity, e.g. Get(ctx, realm, "invoice", "123", &resp), and
+// decodes the JSON response into out. An empty realmID uses the default realm.
+func (c *Client) Get(ctx context.Context, realmID, entity, id string, out interface{}) error {
+	if realmID == "" {
+		realmID = c.settings.RealmID
+	}
+	if realmID == "" {
+		return fmt.Errorf("no realm ID for %s %s", entity, id)
+	}
+
+	token, err := c.tokens(ctx, realmID)
+	if err != nil {
+		return fmt.Errorf("get QBO token: %w", err)
+	}
+
+	u := fmt.Sprintf("%s/v3/company/%s/%s/%s?minorversion=%s",
+		c.settings.BaseURL, url.PathEscape(realmID), entity, url.PathEscape(id), url.QueryEscape(c.settings.MinorVersion))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return fmt.Errorf("build %s request: %w", entity, err)
+	}
+	req.Header.Set("Accept", "application/json")
+	token.SetAuthHeader(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s fetch error: %w", entity, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return &Error{Entity: entity, ID: id, StatusCode: resp.StatusCode, Body: string(body)}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode %s JSON: %w", entity, err)
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+// backend/internal/qbo/client_test.go
+
+package qbo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/config"
+	"golang.org/x/oauth2"
+)
+
+// staticTokens hands out one token per realm and records the realms asked for.
+func staticTokens(realms *[]string) TokenFunc {
+	return func(ctx context.Context, realmID string) (*oauth2.Token, error) {
+		*realms = append(*realms, realmID)
+		return &oauth2.Token{AccessToken: "token-" + realmID, TokenType: "Bearer"}, nil
+	}
+}
+
+func TestClientGet(t *testing.T) {
+	var gotPath, gotMinor, gotAuth, gotAccept string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.EscapedPath()
+		gotMinor = r.URL.Query().Get("minorversion")
+		gotAuth = r.Header.Get("Authorization")
+		gotAccept = r.Header.Get("Accept")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"Invoice": {"Id": "1042", "TotalAmt": 450}}`))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name      string
+		realm     string
+		wantRealm string
+	}{
+		{"explicit realm", "9341452538591482", "9341452538591482"},
+		{"default realm", "", "4620816365178"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var realms []string
+			c := NewClient(Settings{Environment: Sandbox, BaseURL: srv.URL, MinorVersion: "70", RealmID: "4620816365178"}, staticTokens(&realms), srv.Client())
+
+			var resp struct {
+				Invoice struct {
+					ID       string  `json:"Id"`
+					TotalAmt float64 `json:"TotalAmt"`
+				} `json:"Invoice"`
+			}
+			if err := c.Get(context.Background(), tt.realm, "invoice", "1042", &resp); err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+
+			if want := "/v3/company/" + tt.wantRealm + "/invoice/1042"; gotPath != want {
+				t.Errorf("path = %s, want %s", gotPath, want)
+			}
+			if gotMinor != "70" {
+				t.Errorf("minorversion = %q, want %q", gotMinor, "70")
+			}
+			if want := "Bearer token-" + tt.wantRealm; gotAuth != want {
+				t.Errorf("Authorization = %q, want %q", gotAuth, want)
+			}
+			if gotAccept != "application/json" {
+				t.Errorf("Accept = %q, want application/json", gotAccept)
+			}
+			if len(realms) != 1 || realms[0] != tt.wantRealm {
+				t.Errorf("tokens requested for %v, want [%s]", realms, tt.wantRealm)
+			}
+			if resp.Invoice.ID != "1042" || resp.Invoice.TotalAmt != 450 {
+				t.Errorf("decoded %+v", resp.Invoice)
+			}
+		})
+	}
+}
+
+func TestClientGetEscapesIDs(t *testing.T) {
+	var gotPath string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.EscapedPath()
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	var realms []string
+	c := NewClient(Settings{BaseURL: srv.URL, MinorVersion: "65"}, staticTokens(&realms), srv.Client())
+	var out map[string]interface{}
+	if err := c.Get(context.Background(), "123", "invoice", "10/42", &out); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if want := "/v3/company/123/invoice/10%2F42"; gotPath != want {
+		t.Errorf("path = %s, want %s", gotPath, want)
+	}
+}
+
+func TestClientGetErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"Fault": {"type": "AUTHENTICATION"}}`, http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	var realms []string
+	c := NewClient(Settings{BaseURL: srv.URL, MinorVersion: "65", RealmID: "123"}, staticTokens(&realms), srv.Client())
+	var out map[string]interface{}
+
+	err := c.Get(context.Background(), "", "payment", "877", &out)
+	var qboErr *Error
+	if !errors.As(err, &qboErr) {
+		t.Fatalf("Get error = %v, want *Error", err)
+	}
+	if qboErr.Entity != "payment" || qboErr.ID != "877" || qboErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("error = %+v", qboErr)
+	}
+
+	noRealm := NewClient(Settings{BaseURL: srv.URL}, staticTokens(&realms), srv.Client())
+	if err := noRealm.Get(context.Background(), "", "invoice", "1042", &out); err == nil {
+		t.Error("Get without any realm succeeded")
+	}
+	if len(realms) != 1 {
+		t.Errorf("tokens requested %d times, want 1 (none without a realm)", len(realms))
+	}
+
+	tokenErr := errors.New("not connected")
+	failing := NewClient(Settings{BaseURL: srv.URL, RealmID: "123"}, func(ctx context.Context, realmID string) (*oauth2.Token, error) {
+		return nil, tokenErr
+	}, srv.Client())
+	if err := failing.Get(context.Background(), "", "invoice", "1042", &out); !errors.Is(err, tokenErr) {
+		t.Errorf("Get with a failing token error = %v, want %v", err, tokenErr)
+	}
+}
+
+func TestSettingsFromConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.Config
+		want    Settings
+		wantErr bool
+	}{
+		{
+			name: "defaults to sandbox",
+			cfg:  config.Config{INTUIT_REALM_ID: " 123 "},
+			want: Settings{Environment: Sandbox, BaseURL: "https://sandbox-quickbooks.api.intuit.com", MinorVersion: defaultMinorVersion, RealmID: "123"},
+		},
+		{
+			name: "production",
+			cfg:  config.Config{QBO_ENVIRONMENT: "Production", QBO_MINOR_VERSION: "70"},
+			want: Settings{Environment: Production, BaseURL: "https://quickbooks.api.intuit.com", MinorVersion: "70"},
+		},
+		{
+			name: "base URL override",
+			cfg:  config.Config{QBO_ENVIRONMENT: "sandbox", QBO_BASE_URL: "http://localhost:9000/"},
+			want: Settings{Environment: Sandbox, BaseURL: "http://localhost:9000", MinorVersion: defaultMinorVersion},
+		},
+		{
+			name:    "unknown environment",
+			cfg:     config.Config{QBO_ENVIRONMENT: "staging"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SettingsFromConfig(&tt.cfg)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("SettingsFromConfig = %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SettingsFromConfig: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("SettingsFromConfig = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
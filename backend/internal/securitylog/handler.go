@@ -0,0 +1,62 @@
+// backend/internal/securitylog/handler.go
+
+package securitylog
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultLimit = 100
+	maxLimit     = 500
+)
+
+// EventsHandler handles GET /api/admin/security-events. The optional query
+// parameters type, user_id, since and until (RFC 3339) narrow the events,
+// and limit caps how many are returned, newest first.
+func EventsHandler(l Log) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params := r.URL.Query()
+		q := Query{
+			Type:   params.Get("type"),
+			UserID: params.Get("user_id"),
+			Limit:  defaultLimit,
+		}
+		for name, t := range map[string]*time.Time{"since": &q.Since, "until": &q.Until} {
+			v := params.Get(name)
+			if v == "" {
+				continue
+			}
+			parsed, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				http.Error(w, "Invalid "+name+", expected an RFC 3339 time", http.StatusBadRequest)
+				return
+			}
+			*t = parsed
+		}
+		if v := params.Get("limit"); v != "" {
+			limit, err := strconv.Atoi(v)
+			if err != nil || limit < 1 || limit > maxLimit {
+				http.Error(w, "Invalid limit, expected 1 to "+strconv.Itoa(maxLimit), http.StatusBadRequest)
+				return
+			}
+			q.Limit = limit
+		}
+
+		events, err := l.Query(r.Context(), q)
+		if err != nil {
+			log.Printf("Error querying security events: %v", err)
+			http.Error(w, "Failed to load security events", http.StatusInternalServerError)
+			return
+		}
+		if events == nil {
+			events = []Event{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(events)
+	}
+}
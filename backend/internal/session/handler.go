@@ -0,0 +1,119 @@
+// backend/internal/session/handler.go
+
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/middleware"
+	"github.com/gorilla/mux"
+)
+
+// refreshCookie holds the refresh token. It is only sent to /api/auth, and
+// SameSite=None because the app is served from another site.
+const refreshCookie = "refresh_token"
+
+func setRefreshCookie(w http.ResponseWriter, token string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     refreshCookie,
+		Value:    token,
+		Path:     "/api/auth",
+		Expires:  time.Now().Add(RefreshTTL),
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteNoneMode,
+	})
+}
+
+func clearRefreshCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     refreshCookie,
+		Path:     "/api/auth",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteNoneMode,
+	})
+}
+
+// Login starts a session for u, sets its refresh cookie and returns the
+// access token.
+func (m *Manager) Login(w http.ResponseWriter, r *http.Request, u User) (string, error) {
+	access, refresh, err := m.Start(r.Context(), u, r.UserAgent())
+	if err != nil {
+		return "", err
+	}
+	setRefreshCookie(w, refresh)
+	return access, nil
+}
+
+// RefreshHandler handles POST /api/auth/refresh. It swaps the refresh cookie
+// for a new one and returns {"token": "<access token>"}.
+func (m *Manager) RefreshHandler(w http.ResponseWriter, r *http.Request) {
+	cookie, err := r.Cookie(refreshCookie)
+	if err != nil || cookie.Value == "" {
+		http.Error(w, "Not signed in", http.StatusUnauthorized)
+		return
+	}
+
+	access, refresh, err := m.Refresh(r.Context(), cookie.Value)
+	switch {
+	case errors.Is(err, ErrRefreshTokenReused):
+		log.Printf("Refresh token reused, session revoked")
+		clearRefreshCookie(w)
+		http.Error(w, "Session revoked", http.StatusUnauthorized)
+		return
+	case errors.Is(err, ErrInvalidRefreshToken), errors.Is(err, ErrSessionEnded):
+		clearRefreshCookie(w)
+		http.Error(w, "Session expired", http.StatusUnauthorized)
+		return
+	case err != nil:
+		log.Printf("Error refreshing session: %v", err)
+		http.Error(w, "Failed to refresh session", http.StatusInternalServerError)
+		return
+	}
+
+	setRefreshCookie(w, refresh)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"token": access})
+}
+
+// LogoutHandler handles POST /api/auth/logout, ending the session of the
+// refresh cookie.
+func (m *Manager) LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(refreshCookie); err == nil && cookie.Value != "" {
+		if err := m.Revoke(r.Context(), cookie.Value, "logout"); err != nil && !errors.Is(err, ErrInvalidRefreshToken) {
+			log.Printf("Error revoking session on logout: %v", err)
+			http.Error(w, "Failed to log out", http.StatusInternalServerError)
+			return
+		}
+	}
+	clearRefreshCookie(w)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RevokeUserSessionsHandler handles POST
+// /api/admin/users/{user_id}/sessions/revoke, signing the user out everywhere.
+func (m *Manager) RevokeUserSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["user_id"]
+	if userID == "" {
+		http.Error(w, "User ID is required", http.StatusBadRequest)
+		return
+	}
+	adminID, _ := middleware.ExtractUserIDFromContext(r.Context())
+
+	revoked, err := m.RevokeUser(r.Context(), userID, "revoked by admin "+adminID)
+	if err != nil {
+		log.Printf("Error revoking sessions of %s: %v", userID, err)
+		http.Error(w, "Failed to revoke sessions", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Admin %s revoked %d session(s) of %s", adminID, revoked, userID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"revoked": revoked})
+}
@@ -0,0 +1,354 @@
+// backend/internal/session/session.go
+
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// A sign-in starts a session, stored in the "sessions" collection. The
+// browser gets a short-lived access token (the JWT the frontend sends in the
+// Authorization header) and a refresh token in an HttpOnly cookie. Every
+// refresh swaps the refresh token for a new one; presenting one that was
+// already swapped means it leaked, and the session is revoked. The middleware
+// rejects access tokens whose session has been revoked.
+
+const (
+	// AccessTTL is how long an access token is valid.
+	AccessTTL = 15 * time.Minute
+	// RefreshTTL is how long a session lasts without signing in again.
+	RefreshTTL = 30 * 24 * time.Hour
+	// reuseGrace lets the previous refresh token be used for a moment after it
+	// is swapped, for tabs that refresh at the same time.
+	reuseGrace = 30 * time.Second
+	// ImpersonationTTL is how long an admin's read-only session as another
+	// user lasts. It cannot be refreshed.
+	ImpersonationTTL = 30 * time.Minute
+	// activeCacheTTL is how long a session is trusted to still be active
+	// without reading it again. Revocations on this instance apply at once.
+	activeCacheTTL = 30 * time.Second
+)
+
+var (
+	// ErrInvalidRefreshToken is returned for a refresh token that is malformed
+	// or belongs to no session.
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	// ErrSessionEnded is returned when refreshing a revoked or expired session.
+	ErrSessionEnded = errors.New("session has ended")
+	// ErrRefreshTokenReused is returned when a swapped refresh token is used
+	// again. The session is revoked.
+	ErrRefreshTokenReused = errors.New("refresh token reused")
+	// ErrNotImpersonation is returned when stopping a session that is not an
+	// impersonation.
+	ErrNotImpersonation = errors.New("not an impersonation session")
+)
+
+// User is who a session is for.
+type User struct {
+	UserID string
+	Email  string
+	Role   string
+}
+
+// Session is a document in the "sessions" collection.
+type Session struct {
+	ID     string `firestore:"-" json:"id"`
+	UserID string `firestore:"user_id" json:"user_id"`
+	Email  string `firestore:"email" json:"email"`
+	Role   string `firestore:"role" json:"role"`
+	// RefreshHash is the SHA-256 of the current refresh token's secret.
+	RefreshHash string `firestore:"refresh_hash" json:"-"`
+	// PrevHash is the refresh token swapped out at RotatedAt.
+	PrevHash      string     `firestore:"prev_hash,omitempty" json:"-"`
+	RotatedAt     time.Time  `firestore:"rotated_at" json:"rotated_at"`
+	UserAgent     string     `firestore:"user_agent,omitempty" json:"user_agent,omitempty"`
+	CreatedAt     time.Time  `firestore:"created_at" json:"created_at"`
+	ExpiresAt     time.Time  `firestore:"expires_at" json:"expires_at"`
+	RevokedAt     *time.Time `firestore:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+	RevokedReason string     `firestore:"revoked_reason,omitempty" json:"revoked_reason,omitempty"`
+	// ImpersonatorID is the admin viewing the app as UserID. Such sessions
+	// are read-only and have no refresh token.
+	ImpersonatorID string `firestore:"impersonator_id,omitempty" json:"impersonator_id,omitempty"`
+}
+
+// Active reports whether the session can still be used at now.
+func (s *Session) Active(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+func (s *Session) revoke(now time.Time, reason string) {
+	if s.RevokedAt == nil {
+		s.RevokedAt = &now
+		s.RevokedReason = reason
+	}
+}
+
+// Manager starts, refreshes and revokes sessions and signs their access tokens.
+type Manager struct {
+	secret []byte
+	store  Store
+	now    func() time.Time
+
+	mu     sync.Mutex
+	active map[string]time.Time
+}
+
+// NewManager returns a Manager that signs access tokens with secretKey.
+func NewManager(secretKey string, store Store) *Manager {
+	return &Manager{secret: []byte(secretKey), store: store, now: time.Now, active: map[string]time.Time{}}
+}
+
+// Start creates a session for u and returns its access token and refresh token.
+func (m *Manager) Start(ctx context.Context, u User, userAgent string) (access, refresh string, err error) {
+	secret, err := randomHex(32)
+	if err != nil {
+		return "", "", err
+	}
+	now := m.now()
+	sess := &Session{
+		UserID:      u.UserID,
+		Email:       u.Email,
+		Role:        u.Role,
+		RefreshHash: hashSecret(secret),
+		RotatedAt:   now,
+		UserAgent:   userAgent,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(RefreshTTL),
+	}
+	if err := m.store.Create(ctx, sess); err != nil {
+		return "", "", fmt.Errorf("create session: %w", err)
+	}
+	access, err = m.accessToken(sess)
+	if err != nil {
+		return "", "", err
+	}
+	return access, sess.ID + "." + secret, nil
+}
+
+// Impersonate starts a read-only session as target for the admin adminID and
+// returns its access token, which lasts as long as the session.
+func (m *Manager) Impersonate(ctx context.Context, adminID string, target User, userAgent string) (string, *Session, error) {
+	// Nobody gets the refresh token, so the session cannot be extended.
+	unused, err := randomHex(32)
+	if err != nil {
+		return "", nil, err
+	}
+	now := m.now()
+	sess := &Session{
+		UserID:         target.UserID,
+		Email:          target.Email,
+		Role:           target.Role,
+		RefreshHash:    hashSecret(unused),
+		RotatedAt:      now,
+		UserAgent:      userAgent,
+		CreatedAt:      now,
+		ExpiresAt:      now.Add(ImpersonationTTL),
+		ImpersonatorID: adminID,
+	}
+	if err := m.store.Create(ctx, sess); err != nil {
+		return "", nil, fmt.Errorf("create impersonation session: %w", err)
+	}
+	access, err := m.accessToken(sess)
+	if err != nil {
+		return "", nil, err
+	}
+	return access, sess, nil
+}
+
+// StopImpersonation ends an impersonation session and returns it.
+func (m *Manager) StopImpersonation(ctx context.Context, id, reason string) (*Session, error) {
+	var sess *Session
+	err := m.store.Update(ctx, id, func(s *Session) error {
+		if s.ImpersonatorID == "" {
+			return ErrNotImpersonation
+		}
+		s.revoke(m.now(), reason)
+		sess = s
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	m.forget(id)
+	return sess, nil
+}
+
+// Refresh swaps a refresh token for a new access token and refresh token.
+func (m *Manager) Refresh(ctx context.Context, refreshToken string) (access, refresh string, err error) {
+	id, secret, ok := strings.Cut(refreshToken, ".")
+	if !ok || id == "" || secret == "" {
+		return "", "", ErrInvalidRefreshToken
+	}
+	newSecret, err := randomHex(32)
+	if err != nil {
+		return "", "", err
+	}
+
+	presented := hashSecret(secret)
+	var sess *Session
+	var reused bool
+	err = m.store.Update(ctx, id, func(s *Session) error {
+		sess, reused = s, false
+		now := m.now()
+		if !s.Active(now) || s.ImpersonatorID != "" {
+			return ErrSessionEnded
+		}
+		switch {
+		case equal(presented, s.RefreshHash):
+			s.PrevHash = s.RefreshHash
+			s.RotatedAt = now
+		case equal(presented, s.PrevHash) && now.Sub(s.RotatedAt) < reuseGrace:
+			// Another tab refreshed first; the cookie they share gets the new token.
+		default:
+			reused = true
+			s.revoke(now, "refresh token reused")
+			return nil
+		}
+		s.RefreshHash = hashSecret(newSecret)
+		return nil
+	})
+	if errors.Is(err, ErrSessionNotFound) {
+		return "", "", ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return "", "", err
+	}
+	if reused {
+		m.forget(id)
+		return "", "", ErrRefreshTokenReused
+	}
+
+	access, err = m.accessToken(sess)
+	if err != nil {
+		return "", "", err
+	}
+	return access, id + "." + newSecret, nil
+}
+
+// Revoke ends the session a refresh token belongs to.
+func (m *Manager) Revoke(ctx context.Context, refreshToken, reason string) error {
+	id, _, ok := strings.Cut(refreshToken, ".")
+	if !ok || id == "" {
+		return ErrInvalidRefreshToken
+	}
+	return m.RevokeSession(ctx, id, reason)
+}
+
+// RevokeSession ends a session by ID.
+func (m *Manager) RevokeSession(ctx context.Context, id, reason string) error {
+	err := m.store.Update(ctx, id, func(s *Session) error {
+		s.revoke(m.now(), reason)
+		return nil
+	})
+	m.forget(id)
+	if errors.Is(err, ErrSessionNotFound) {
+		return nil
+	}
+	return err
+}
+
+// RevokeUser ends every active session of a user and returns how many it ended.
+func (m *Manager) RevokeUser(ctx context.Context, userID, reason string) (int, error) {
+	sessions, err := m.store.ListByUser(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	now := m.now()
+	revoked := 0
+	for _, s := range sessions {
+		if !s.Active(now) {
+			continue
+		}
+		if err := m.RevokeSession(ctx, s.ID, reason); err != nil {
+			return revoked, fmt.Errorf("revoke session %s: %w", s.ID, err)
+		}
+		revoked++
+	}
+	return revoked, nil
+}
+
+// Get returns the session with ID sid if it can still be used.
+func (m *Manager) Get(ctx context.Context, sid string) (*Session, bool, error) {
+	sess, found, err := m.store.Get(ctx, sid)
+	if err != nil || !found || !sess.Active(m.now()) {
+		return nil, false, err
+	}
+	return sess, true, nil
+}
+
+// Active reports whether the session with ID sid can still be used. It
+// satisfies middleware.SessionChecker.
+func (m *Manager) Active(ctx context.Context, sid string) (bool, error) {
+	now := m.now()
+	m.mu.Lock()
+	until, ok := m.active[sid]
+	m.mu.Unlock()
+	if ok && now.Before(until) {
+		return true, nil
+	}
+
+	sess, found, err := m.store.Get(ctx, sid)
+	if err != nil {
+		return false, err
+	}
+	if !found || !sess.Active(now) {
+		m.forget(sid)
+		return false, nil
+	}
+	m.mu.Lock()
+	m.active[sid] = now.Add(activeCacheTTL)
+	m.mu.Unlock()
+	return true, nil
+}
+
+func (m *Manager) forget(sid string) {
+	m.mu.Lock()
+	delete(m.active, sid)
+	m.mu.Unlock()
+}
+
+// accessToken signs the JWT for a session. Its "sid" claim ties it to the
+// session so that revoking the session revokes the token. Impersonation
+// tokens carry the admin in "impersonator" and last as long as the session.
+func (m *Manager) accessToken(s *Session) (string, error) {
+	claims := jwt.MapClaims{
+		"user_id": s.UserID,
+		"email":   s.Email,
+		"role":    s.Role,
+		"sid":     s.ID,
+		"exp":     m.now().Add(AccessTTL).Unix(),
+	}
+	if s.ImpersonatorID != "" {
+		claims["impersonator"] = s.ImpersonatorID
+		claims["exp"] = s.ExpiresAt.Unix()
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(m.secret)
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func equal(a, b string) bool {
+	return b != "" && subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
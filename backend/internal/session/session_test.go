@@ -0,0 +1,187 @@
+// backend/internal/session/session_test.go
+
+package session
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const testSecret = "test-secret"
+
+// newTestManager returns a Manager over a memory store and a pointer to the
+// time its clock reads.
+func newTestManager(t *testing.T) (*Manager, *MemoryStore, *time.Time) {
+	t.Helper()
+	store := NewMemoryStore()
+	m := NewManager(testSecret, store)
+	now := time.Now()
+	m.now = func() time.Time { return now }
+	return m, store, &now
+}
+
+// start signs userID in and returns the session ID and refresh token.
+func start(t *testing.T, m *Manager, userID string) (string, string) {
+	t.Helper()
+	_, refresh, err := m.Start(context.Background(), User{UserID: userID, Role: "parent"}, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sid, _, _ := strings.Cut(refresh, ".")
+	return sid, refresh
+}
+
+func TestRefreshRotates(t *testing.T) {
+	ctx := context.Background()
+	m, _, _ := newTestManager(t)
+	sid, r1 := start(t, m, "parent-1")
+
+	access, r2, err := m.Refresh(ctx, r1)
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if r2 == r1 || !strings.HasPrefix(r2, sid+".") {
+		t.Errorf("refresh token = %q, want a new one for session %s", r2, sid)
+	}
+	claims := jwt.MapClaims{}
+	if _, err := jwt.ParseWithClaims(access, claims, func(*jwt.Token) (interface{}, error) { return []byte(testSecret), nil }); err != nil {
+		t.Fatal(err)
+	}
+	if claims["sid"] != sid || claims["user_id"] != "parent-1" {
+		t.Errorf("access token claims = %v, want sid %s for parent-1", claims, sid)
+	}
+	if _, _, err := m.Refresh(ctx, r2); err != nil {
+		t.Errorf("Refresh with the new token: %v", err)
+	}
+
+	for _, token := range []string{"", "no-dot", "." + strings.Repeat("a", 64), sid + ".", "unknown.secret"} {
+		if _, _, err := m.Refresh(ctx, token); !errors.Is(err, ErrInvalidRefreshToken) {
+			t.Errorf("Refresh(%q) error = %v, want %v", token, err, ErrInvalidRefreshToken)
+		}
+	}
+}
+
+func TestRefreshGraceWindow(t *testing.T) {
+	ctx := context.Background()
+	m, _, now := newTestManager(t)
+
+	// Within the grace window, the token another tab just swapped still works.
+	_, r1 := start(t, m, "parent-1")
+	if _, _, err := m.Refresh(ctx, r1); err != nil {
+		t.Fatal(err)
+	}
+	*now = now.Add(reuseGrace - time.Second)
+	_, r3, err := m.Refresh(ctx, r1)
+	if err != nil {
+		t.Fatalf("Refresh with the previous token inside the grace window: %v", err)
+	}
+	if _, _, err := m.Refresh(ctx, r3); err != nil {
+		t.Errorf("Refresh with the token it returned: %v", err)
+	}
+
+	// After it, the swapped token counts as reused.
+	sid, r1 := start(t, m, "parent-2")
+	_, r2, err := m.Refresh(ctx, r1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	*now = now.Add(reuseGrace)
+	if _, _, err := m.Refresh(ctx, r1); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("Refresh with the previous token after the grace window error = %v, want %v", err, ErrRefreshTokenReused)
+	}
+	if _, _, err := m.Refresh(ctx, r2); !errors.Is(err, ErrSessionEnded) {
+		t.Errorf("Refresh with the current token after reuse error = %v, want %v", err, ErrSessionEnded)
+	}
+	if active, _ := m.Active(ctx, sid); active {
+		t.Error("session still active after reuse")
+	}
+}
+
+func TestReuseRevokesWholeSession(t *testing.T) {
+	ctx := context.Background()
+	m, store, _ := newTestManager(t)
+	sid, r1 := start(t, m, "parent-1")
+	otherSID, _ := start(t, m, "parent-1")
+
+	if active, _ := m.Active(ctx, sid); !active {
+		t.Fatal("new session not active")
+	}
+
+	// r1 is two rotations old, so even at once it is a reuse.
+	_, r2, err := m.Refresh(ctx, r1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, r3, err := m.Refresh(ctx, r2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := m.Refresh(ctx, r1); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("Refresh with an old token error = %v, want %v", err, ErrRefreshTokenReused)
+	}
+
+	// Every token of the session stops working, including the newest one
+	// and access tokens the cache had vouched for.
+	for _, token := range []string{r2, r3} {
+		if _, _, err := m.Refresh(ctx, token); !errors.Is(err, ErrSessionEnded) {
+			t.Errorf("Refresh after reuse error = %v, want %v", err, ErrSessionEnded)
+		}
+	}
+	if active, _ := m.Active(ctx, sid); active {
+		t.Error("access tokens of the session still active after reuse")
+	}
+	if s, _, _ := store.Get(ctx, sid); s.RevokedReason != "refresh token reused" {
+		t.Errorf("revoked reason = %q, want refresh token reused", s.RevokedReason)
+	}
+
+	// The user's other sign-ins are not affected.
+	if active, _ := m.Active(ctx, otherSID); !active {
+		t.Error("another session of the user was revoked")
+	}
+}
+
+func TestRevokeUserInvalidatesActiveCache(t *testing.T) {
+	ctx := context.Background()
+	m, store, now := newTestManager(t)
+	elsewhere, _ := start(t, m, "parent-1")
+	here, _ := start(t, m, "parent-1")
+	other, _ := start(t, m, "parent-2")
+	for _, sid := range []string{elsewhere, here, other} {
+		if active, err := m.Active(ctx, sid); !active || err != nil {
+			t.Fatalf("Active(%s) = %v, %v; want true", sid, active, err)
+		}
+	}
+
+	// A revocation made by another instance is only seen once the cache
+	// entry runs out.
+	if err := store.Update(ctx, elsewhere, func(s *Session) error {
+		s.revoke(*now, "signed out elsewhere")
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if active, _ := m.Active(ctx, elsewhere); !active {
+		t.Error("cached session not trusted within the cache TTL")
+	}
+	*now = now.Add(activeCacheTTL)
+	if active, _ := m.Active(ctx, elsewhere); active {
+		t.Error("revoked session still active after the cache TTL")
+	}
+
+	// RevokeUser on this instance applies at once.
+	n, err := m.RevokeUser(ctx, "parent-1", "password changed")
+	if err != nil || n != 1 {
+		t.Fatalf("RevokeUser = %d, %v; want 1", n, err)
+	}
+	if active, _ := m.Active(ctx, here); active {
+		t.Error("cached session still active after RevokeUser")
+	}
+	if active, _ := m.Active(ctx, other); !active {
+		t.Error("another user's session was revoked")
+	}
+}
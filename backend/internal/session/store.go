@@ -0,0 +1,165 @@
+// backend/internal/session/store.go
+
+package session
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrSessionNotFound is returned by Store.Update for a session that does not exist.
+var ErrSessionNotFound = errors.New("session not found")
+
+// Store keeps sessions.
+type Store interface {
+	// Create stores s and sets its ID.
+	Create(ctx context.Context, s *Session) error
+	Get(ctx context.Context, id string) (*Session, bool, error)
+	// Update applies fn to the session atomically. If fn returns an error the
+	// session is left as it was.
+	Update(ctx context.Context, id string, fn func(*Session) error) error
+	ListByUser(ctx context.Context, userID string) ([]*Session, error)
+}
+
+type firestoreStore struct {
+	client *firestore.Client
+}
+
+// NewFirestoreStore returns a Store backed by the "sessions" collection. The
+// expires_at field can drive a Firestore TTL policy.
+func NewFirestoreStore(client *firestore.Client) Store {
+	return &firestoreStore{client: client}
+}
+
+func (f *firestoreStore) sessions() *firestore.CollectionRef {
+	return f.client.Collection("sessions")
+}
+
+func (f *firestoreStore) Create(ctx context.Context, s *Session) error {
+	ref := f.sessions().NewDoc()
+	if _, err := ref.Create(ctx, s); err != nil {
+		return err
+	}
+	s.ID = ref.ID
+	return nil
+}
+
+func (f *firestoreStore) Get(ctx context.Context, id string) (*Session, bool, error) {
+	snap, err := f.sessions().Doc(id).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	var s Session
+	if err := snap.DataTo(&s); err != nil {
+		return nil, false, err
+	}
+	s.ID = id
+	return &s, true, nil
+}
+
+func (f *firestoreStore) Update(ctx context.Context, id string, fn func(*Session) error) error {
+	ref := f.sessions().Doc(id)
+	return f.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		snap, err := tx.Get(ref)
+		if status.Code(err) == codes.NotFound {
+			return ErrSessionNotFound
+		}
+		if err != nil {
+			return err
+		}
+		var s Session
+		if err := snap.DataTo(&s); err != nil {
+			return err
+		}
+		s.ID = id
+		if err := fn(&s); err != nil {
+			return err
+		}
+		return tx.Set(ref, &s)
+	})
+}
+
+func (f *firestoreStore) ListByUser(ctx context.Context, userID string) ([]*Session, error) {
+	snaps, err := f.sessions().Where("user_id", "==", userID).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*Session, 0, len(snaps))
+	for _, snap := range snaps {
+		var s Session
+		if err := snap.DataTo(&s); err != nil {
+			return nil, err
+		}
+		s.ID = snap.Ref.ID
+		out = append(out, &s)
+	}
+	return out, nil
+}
+
+// MemoryStore is an in-memory Store for tests and local runs.
+type MemoryStore struct {
+	mu       sync.Mutex
+	sessions map[string]Session
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{sessions: map[string]Session{}}
+}
+
+func (m *MemoryStore) Create(ctx context.Context, s *Session) error {
+	id, err := randomHex(10)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s.ID = id
+	m.sessions[id] = *s
+	return nil
+}
+
+func (m *MemoryStore) Get(ctx context.Context, id string) (*Session, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.sessions[id]
+	if !ok {
+		return nil, false, nil
+	}
+	return &s, true, nil
+}
+
+func (m *MemoryStore) Update(ctx context.Context, id string, fn func(*Session) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.sessions[id]
+	if !ok {
+		return ErrSessionNotFound
+	}
+	if err := fn(&s); err != nil {
+		return err
+	}
+	m.sessions[id] = s
+	return nil
+}
+
+func (m *MemoryStore) ListByUser(ctx context.Context, userID string) ([]*Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []*Session
+	for _, s := range m.sessions {
+		if s.UserID == userID {
+			s := s
+			out = append(out, &s)
+		}
+	}
+	return out, nil
+}
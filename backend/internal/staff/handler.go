@@ -0,0 +1,121 @@
+// backend/internal/staff/handler.go
+
+package staff
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/middleware"
+	"github.com/gorilla/mux"
+)
+
+// memberRequest is the body of POST /api/admin/staff and
+// PUT /api/admin/staff/{staff_id}.
+type memberRequest struct {
+	Email   string   `json:"email"`
+	Aliases []string `json:"aliases"`
+	Name    string   `json:"name"`
+	Role    string   `json:"role"`
+	Active  *bool    `json:"active"`
+}
+
+func (req memberRequest) member(id string) *Member {
+	m := &Member{
+		ID:      id,
+		Email:   req.Email,
+		Aliases: req.Aliases,
+		Name:    req.Name,
+		Role:    req.Role,
+		Active:  true,
+	}
+	if req.Active != nil {
+		m.Active = *req.Active
+	}
+	return m
+}
+
+// StaffHandler handles /api/admin/staff. GET lists the directory; POST adds
+// a member and returns it.
+func (d *Directory) StaffHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		var req memberRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+		m := req.member("")
+		if err := d.Create(r.Context(), m); err != nil {
+			writeError(w, "add", m, err)
+			return
+		}
+		adminID, _ := middleware.ExtractUserIDFromContext(r.Context())
+		log.Printf("Admin %s added staff member %s (%s, %s)", adminID, m.ID, m.Email, m.Role)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(m)
+		return
+	}
+
+	members, err := d.List(r.Context())
+	if err != nil {
+		log.Printf("Error listing staff: %v", err)
+		http.Error(w, "Failed to list staff", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(members)
+}
+
+// MemberHandler handles /api/admin/staff/{staff_id}. PUT replaces the member
+// and returns it; DELETE removes it.
+func (d *Directory) MemberHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["staff_id"]
+	if id == "" {
+		http.Error(w, "Staff ID is required", http.StatusBadRequest)
+		return
+	}
+	adminID, _ := middleware.ExtractUserIDFromContext(r.Context())
+
+	if r.Method == http.MethodDelete {
+		if err := d.Delete(r.Context(), id); err != nil {
+			writeError(w, "remove", &Member{ID: id}, err)
+			return
+		}
+		log.Printf("Admin %s removed staff member %s", adminID, id)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	var req memberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	m := req.member(id)
+	if err := d.Update(r.Context(), m); err != nil {
+		writeError(w, "update", m, err)
+		return
+	}
+	log.Printf("Admin %s updated staff member %s (%s, %s, active=%t)", adminID, m.ID, m.Email, m.Role, m.Active)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(m)
+}
+
+func writeError(w http.ResponseWriter, action string, m *Member, err error) {
+	switch {
+	case errors.Is(err, ErrMemberNotFound):
+		http.Error(w, "Staff member not found", http.StatusNotFound)
+	case errors.Is(err, ErrEmailTaken):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.As(err, new(*validationError)):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		log.Printf("Error trying to %s staff member %s: %v", action, m.ID, err)
+		http.Error(w, "Failed to "+action+" staff member", http.StatusInternalServerError)
+	}
+}
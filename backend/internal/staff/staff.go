@@ -0,0 +1,330 @@
+// backend/internal/staff/staff.go
+
+package staff
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/mail"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/middleware"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/securitylog"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/session"
+)
+
+// The staff directory lists everyone who signs in as a tutor. A login whose
+// email is the email or an alias of an active member signs in to the tutors
+// collection; every other login is a student or a parent. Admins manage the
+// directory through the /api/admin/staff endpoints, so onboarding a tutor
+// needs no deploy.
+
+// Collection holds the directory.
+const Collection = "staff"
+
+var (
+	// ErrEmailTaken is returned when an email or alias already belongs to
+	// another member.
+	ErrEmailTaken = errors.New("email belongs to another staff member")
+	// ErrMemberNotFound is returned for a member ID that does not exist.
+	ErrMemberNotFound = errors.New("staff member not found")
+)
+
+// Member is one person in the directory.
+type Member struct {
+	ID string `firestore:"-" json:"id"`
+	// Email is the address the member signs in with.
+	Email string `firestore:"email" json:"email"`
+	// Aliases are other addresses that sign in as the member.
+	Aliases []string `firestore:"aliases" json:"aliases"`
+	// Name is the display name, as it appears in students'
+	// business.associated_tutors.
+	Name string `firestore:"name" json:"name"`
+	// Role is middleware.RoleTutor, RoleTeamLead or RoleAdmin.
+	Role   string `firestore:"role" json:"role"`
+	Active bool   `firestore:"active" json:"active"`
+	// Emails is Email and Aliases in lower case, for lookups.
+	Emails    []string  `firestore:"emails" json:"-"`
+	CreatedAt time.Time `firestore:"created_at" json:"created_at"`
+	UpdatedAt time.Time `firestore:"updated_at" json:"updated_at"`
+}
+
+// SessionRole is the "role" claim the member's JWTs carry. Team leads sign in
+// as tutors; ResolveTutor reads their staff_role from the tutor document.
+func (m *Member) SessionRole() string {
+	if m.Role == middleware.RoleAdmin {
+		return middleware.RoleAdmin
+	}
+	return middleware.RoleTutor
+}
+
+// normalize validates m and fills in Emails.
+func (m *Member) normalize() error {
+	m.Name = strings.TrimSpace(m.Name)
+	if m.Name == "" {
+		return &validationError{"name is required"}
+	}
+	switch m.Role {
+	case middleware.RoleTutor, middleware.RoleTeamLead, middleware.RoleAdmin:
+	default:
+		return &validationError{fmt.Sprintf("role must be %s, %s or %s", middleware.RoleTutor, middleware.RoleTeamLead, middleware.RoleAdmin)}
+	}
+
+	m.Emails = nil
+	seen := map[string]bool{}
+	addresses := append([]string{m.Email}, m.Aliases...)
+	m.Aliases = []string{}
+	for i, address := range addresses {
+		address = strings.TrimSpace(address)
+		if address == "" && i > 0 {
+			continue
+		}
+		if parsed, err := mail.ParseAddress(address); err != nil || parsed.Address != address {
+			return &validationError{fmt.Sprintf("invalid email %q", address)}
+		}
+		key := NormalizeEmail(address)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		m.Emails = append(m.Emails, key)
+		if i == 0 {
+			m.Email = address
+		} else {
+			m.Aliases = append(m.Aliases, address)
+		}
+	}
+	return nil
+}
+
+// validationError is a member that cannot be saved as given.
+type validationError struct {
+	msg string
+}
+
+func (e *validationError) Error() string { return e.msg }
+
+// NormalizeEmail is the form emails are looked up by.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// Directory looks up staff and keeps tutor accounts in step with their
+// member records.
+type Directory struct {
+	store    Store
+	client   *firestore.Client
+	sessions *session.Manager
+	events   *securitylog.Recorder
+}
+
+// NewDirectory returns a Directory over store. When a member changes, the
+// staff_role of their tutor documents in client is updated and their
+// sessions are revoked through sessions, so the change applies at once.
+// Changes to who holds which role are recorded in events.
+func NewDirectory(store Store, client *firestore.Client, sessions *session.Manager, events *securitylog.Recorder) *Directory {
+	return &Directory{store: store, client: client, sessions: sessions, events: events}
+}
+
+// Lookup returns the member with email as their email or an alias, active
+// or not.
+func (d *Directory) Lookup(ctx context.Context, email string) (*Member, bool, error) {
+	if strings.TrimSpace(email) == "" {
+		return nil, false, nil
+	}
+	return d.store.FindByEmail(ctx, NormalizeEmail(email))
+}
+
+// List returns every member.
+func (d *Directory) List(ctx context.Context) ([]*Member, error) {
+	return d.store.List(ctx)
+}
+
+// Create adds m to the directory and sets its ID.
+func (d *Directory) Create(ctx context.Context, m *Member) error {
+	if err := m.normalize(); err != nil {
+		return err
+	}
+	if err := d.checkEmails(ctx, m); err != nil {
+		return err
+	}
+	m.CreatedAt = time.Now()
+	m.UpdatedAt = m.CreatedAt
+	if err := d.store.Create(ctx, m); err != nil {
+		return err
+	}
+	d.syncAccounts(ctx, nil, m, "added to the staff directory")
+	d.recordRoleChange(ctx, nil, m)
+	return nil
+}
+
+// Update replaces the member with m's ID.
+func (d *Directory) Update(ctx context.Context, m *Member) error {
+	before, found, err := d.store.Get(ctx, m.ID)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrMemberNotFound
+	}
+	if err := m.normalize(); err != nil {
+		return err
+	}
+	if err := d.checkEmails(ctx, m); err != nil {
+		return err
+	}
+	m.CreatedAt = before.CreatedAt
+	m.UpdatedAt = time.Now()
+	if err := d.store.Update(ctx, m); err != nil {
+		return err
+	}
+	d.syncAccounts(ctx, before, m, "staff record changed")
+	d.recordRoleChange(ctx, before, m)
+	return nil
+}
+
+// Delete removes a member. Their tutor accounts can no longer sign in.
+func (d *Directory) Delete(ctx context.Context, id string) error {
+	before, found, err := d.store.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrMemberNotFound
+	}
+	if err := d.store.Delete(ctx, id); err != nil {
+		return err
+	}
+	d.syncAccounts(ctx, before, nil, "removed from the staff directory")
+	d.recordRoleChange(ctx, before, nil)
+	return nil
+}
+
+// SignOut ends the sessions of the active members with one of roles, so
+// that a new rule applies to them from their next sign-in.
+func (d *Directory) SignOut(ctx context.Context, reason string, roles ...string) error {
+	members, err := d.store.List(ctx)
+	if err != nil {
+		return err
+	}
+	for _, m := range members {
+		if m.Active && contains(roles, m.Role) {
+			d.syncAccounts(ctx, m, m, reason)
+		}
+	}
+	return nil
+}
+
+func (d *Directory) checkEmails(ctx context.Context, m *Member) error {
+	for _, email := range m.Emails {
+		other, found, err := d.store.FindByEmail(ctx, email)
+		if err != nil {
+			return err
+		}
+		if found && other.ID != m.ID {
+			return fmt.Errorf("%w: %s", ErrEmailTaken, email)
+		}
+	}
+	return nil
+}
+
+// syncAccounts sets the staff_role of the tutor documents of a member that
+// changed from before to after, either of which may be nil, and signs those
+// tutors out so their next token carries the new role. Failures are logged
+// rather than returned: the directory is already updated and is what the
+// next sign-in reads.
+func (d *Directory) syncAccounts(ctx context.Context, before, after *Member, reason string) {
+	if d.client == nil {
+		return
+	}
+	// Tutor documents keep the email as the provider sent it.
+	var emails []string
+	seen := map[string]bool{}
+	for _, m := range []*Member{before, after} {
+		if m == nil {
+			continue
+		}
+		for _, email := range append(append([]string{m.Email}, m.Aliases...), m.Emails...) {
+			if !seen[email] {
+				seen[email] = true
+				emails = append(emails, email)
+			}
+		}
+	}
+	if len(emails) == 0 {
+		return
+	}
+	if len(emails) > 30 {
+		// The most an "in" query allows.
+		emails = emails[:30]
+	}
+
+	docs, err := d.client.Collection("tutors").Where("email", "in", emails).Documents(ctx).GetAll()
+	if err != nil {
+		log.Printf("Error finding tutor accounts of staff emails %v: %v", emails, err)
+		return
+	}
+	for _, doc := range docs {
+		email, _ := doc.Data()["email"].(string)
+		var staffRole interface{} = firestore.Delete
+		if after != nil && after.Active && contains(after.Emails, NormalizeEmail(email)) {
+			staffRole = after.Role
+		}
+		if _, err := doc.Ref.Update(ctx, []firestore.Update{{Path: "staff_role", Value: staffRole}}); err != nil {
+			log.Printf("Error updating staff_role of tutor %s: %v", doc.Ref.ID, err)
+		}
+		if d.sessions == nil {
+			continue
+		}
+		revoked, err := d.sessions.RevokeUser(ctx, doc.Ref.ID, reason)
+		if err != nil {
+			log.Printf("Error revoking sessions of tutor %s: %v", doc.Ref.ID, err)
+			continue
+		}
+		if revoked > 0 {
+			log.Printf("Revoked %d session(s) of tutor %s: %s", revoked, doc.Ref.ID, reason)
+		}
+	}
+}
+
+// recordRoleChange records the role a member signs in with going from
+// before to after, either of which may be nil, if it changed. A member who
+// is not active holds no role.
+func (d *Directory) recordRoleChange(ctx context.Context, before, after *Member) {
+	role := func(m *Member) string {
+		if m == nil || !m.Active {
+			return "none"
+		}
+		return m.Role
+	}
+	from, to := role(before), role(after)
+	if from == to {
+		return
+	}
+	m := after
+	if m == nil {
+		m = before
+	}
+	adminID, _ := middleware.ExtractUserIDFromContext(ctx)
+	d.events.Record(ctx, securitylog.Event{
+		Type:    securitylog.RoleChange,
+		UserID:  m.ID,
+		Email:   m.Email,
+		Role:    to,
+		ActorID: adminID,
+		Detail:  "staff role " + from + " to " + to,
+	})
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
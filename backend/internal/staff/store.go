@@ -0,0 +1,181 @@
+// backend/internal/staff/store.go
+
+package staff
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Store keeps the directory.
+type Store interface {
+	List(ctx context.Context) ([]*Member, error)
+	Get(ctx context.Context, id string) (*Member, bool, error)
+	// FindByEmail returns the member with a normalized email among their Emails.
+	FindByEmail(ctx context.Context, email string) (*Member, bool, error)
+	// Create stores m and sets its ID.
+	Create(ctx context.Context, m *Member) error
+	Update(ctx context.Context, m *Member) error
+	Delete(ctx context.Context, id string) error
+}
+
+type firestoreStore struct {
+	client *firestore.Client
+}
+
+// NewFirestoreStore returns a Store backed by the "staff" collection.
+func NewFirestoreStore(client *firestore.Client) Store {
+	return &firestoreStore{client: client}
+}
+
+func (s *firestoreStore) List(ctx context.Context) ([]*Member, error) {
+	snaps, err := s.client.Collection(Collection).OrderBy("name", firestore.Asc).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*Member, 0, len(snaps))
+	for _, snap := range snaps {
+		m, err := memberFromSnapshot(snap)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	return out, nil
+}
+
+func (s *firestoreStore) Get(ctx context.Context, id string) (*Member, bool, error) {
+	snap, err := s.client.Collection(Collection).Doc(id).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	m, err := memberFromSnapshot(snap)
+	if err != nil {
+		return nil, false, err
+	}
+	return m, true, nil
+}
+
+func (s *firestoreStore) FindByEmail(ctx context.Context, email string) (*Member, bool, error) {
+	snaps, err := s.client.Collection(Collection).Where("emails", "array-contains", email).Limit(1).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, false, err
+	}
+	if len(snaps) == 0 {
+		return nil, false, nil
+	}
+	m, err := memberFromSnapshot(snaps[0])
+	if err != nil {
+		return nil, false, err
+	}
+	return m, true, nil
+}
+
+func (s *firestoreStore) Create(ctx context.Context, m *Member) error {
+	ref := s.client.Collection(Collection).NewDoc()
+	if _, err := ref.Create(ctx, m); err != nil {
+		return err
+	}
+	m.ID = ref.ID
+	return nil
+}
+
+func (s *firestoreStore) Update(ctx context.Context, m *Member) error {
+	_, err := s.client.Collection(Collection).Doc(m.ID).Set(ctx, m)
+	return err
+}
+
+func (s *firestoreStore) Delete(ctx context.Context, id string) error {
+	_, err := s.client.Collection(Collection).Doc(id).Delete(ctx)
+	return err
+}
+
+func memberFromSnapshot(snap *firestore.DocumentSnapshot) (*Member, error) {
+	var m Member
+	if err := snap.DataTo(&m); err != nil {
+		return nil, fmt.Errorf("decode staff member %s: %w", snap.Ref.ID, err)
+	}
+	m.ID = snap.Ref.ID
+	return &m, nil
+}
+
+// MemoryStore is an in-memory Store for tests and local runs.
+type MemoryStore struct {
+	mu      sync.Mutex
+	members map[string]Member
+	nextID  int
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{members: map[string]Member{}}
+}
+
+func (s *MemoryStore) List(ctx context.Context) ([]*Member, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]*Member, 0, len(s.members))
+	for _, m := range s.members {
+		m := m
+		out = append(out, &m)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+func (s *MemoryStore) Get(ctx context.Context, id string) (*Member, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, ok := s.members[id]
+	if !ok {
+		return nil, false, nil
+	}
+	return &m, true, nil
+}
+
+func (s *MemoryStore) FindByEmail(ctx context.Context, email string) (*Member, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, m := range s.members {
+		if contains(m.Emails, email) {
+			m := m
+			return &m, true, nil
+		}
+	}
+	return nil, false, nil
+}
+
+func (s *MemoryStore) Create(ctx context.Context, m *Member) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	m.ID = fmt.Sprintf("staff-%d", s.nextID)
+	s.members[m.ID] = *m
+	return nil
+}
+
+func (s *MemoryStore) Update(ctx context.Context, m *Member) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.members[m.ID]; !ok {
+		return ErrMemberNotFound
+	}
+	s.members[m.ID] = *m
+	return nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.members, id)
+	return nil
+}
@@ -0,0 +1,18 @@
+// backend/internal/studentdashboard/app.go
+
+package studentdashboard
+
+import (
+	"context"
+
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/students"
+)
+
+// AssignmentLister lists the coursework of a Google Classroom course.
+type AssignmentLister func(ctx context.Context, courseID string) ([]Assignment, error)
+
+// App holds the dependencies for the studentdashboard package
+type App struct {
+	Students    students.Repository
+	Assignments AssignmentLister
+}
@@ -0,0 +1,73 @@
+// backend/internal/studentdashboard/assignments.go
+
+package studentdashboard
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	classroom "google.golang.org/api/classroom/v1"
+	"google.golang.org/api/option"
+)
+
+// Assignment is a piece of coursework set in the student's Google Classroom.
+type Assignment struct {
+	ID          string `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	// DueDate is "YYYY-MM-DD", or empty for coursework without a due date.
+	DueDate string `json:"due_date,omitempty"`
+	Link    string `json:"link"`
+}
+
+// ClassroomAssignments lists coursework through the Classroom API. Like
+// AssignHomeworkHandler, it uses the credentials in
+// GOOGLE_APPLICATION_CREDENTIALS.
+func ClassroomAssignments(ctx context.Context, courseID string) ([]Assignment, error) {
+	svc, err := classroom.NewService(ctx, option.WithScopes(classroom.ClassroomCourseworkStudentsReadonlyScope))
+	if err != nil {
+		return nil, fmt.Errorf("create classroom service: %w", err)
+	}
+
+	var out []Assignment
+	err = svc.Courses.CourseWork.List(courseID).CourseWorkStates("PUBLISHED").Pages(ctx, func(page *classroom.ListCourseWorkResponse) error {
+		for _, cw := range page.CourseWork {
+			a := Assignment{
+				ID:          cw.Id,
+				Title:       cw.Title,
+				Description: cw.Description,
+				Link:        cw.AlternateLink,
+			}
+			if d := cw.DueDate; d != nil {
+				a.DueDate = fmt.Sprintf("%04d-%02d-%02d", d.Year, d.Month, d.Day)
+			}
+			out = append(out, a)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list coursework of %s: %w", courseID, err)
+	}
+	return out, nil
+}
+
+// upcoming keeps the assignments due today or later, soonest first.
+// Assignments without a due date go last.
+func upcoming(assignments []Assignment, now time.Time) []Assignment {
+	today := now.Format("2006-01-02")
+	out := make([]Assignment, 0, len(assignments))
+	for _, a := range assignments {
+		if a.DueDate == "" || a.DueDate >= today {
+			out = append(out, a)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].DueDate == "" {
+			return false
+		}
+		return out[j].DueDate == "" || out[i].DueDate < out[j].DueDate
+	})
+	return out
+}
@@ -0,0 +1,240 @@
+// backend/internal/studentdashboard/handler.go
+
+package studentdashboard
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/middleware"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/students"
+)
+
+// StudentDetailResponse is the student's view of their own record. It has the
+// shape of the tutor dashboard's StudentDetailResponse, without the fields
+// only staff see: business notes, scheduling, hours and billing, contact
+// numbers and tutors' engagement ratings.
+type StudentDetailResponse struct {
+	ID                 string              `json:"id"`
+	Personal           studentPersonal     `json:"personal"`
+	Business           studentBusiness     `json:"business"`
+	HomeworkCompletion []studentHomework   `json:"homeworkCompletion"`
+	TestData           []students.TestData `json:"testData"`
+	TestDates          []students.TestDate `json:"testDates"`
+	Goals              []students.Goal     `json:"goals"`
+}
+
+// studentPersonal is the part of the personal details a student can see.
+type studentPersonal struct {
+	Name           string `json:"name"`
+	StudentEmail   string `json:"student_email"`
+	HighSchool     string `json:"high_school"`
+	Grade          string `json:"grade"`
+	Accommodations string `json:"accommodations"`
+	Interests      string `json:"interests"`
+}
+
+// studentBusiness is the part of the business details a student can see.
+type studentBusiness struct {
+	TestFocus        string                   `json:"test_focus"`
+	TestAppointment  students.TestAppointment `json:"test_appointment"`
+	AssociatedTutors []string                 `json:"associated_tutors"`
+	TeamLead         string                   `json:"team_lead"`
+	Status           string                   `json:"status"`
+	RegisteredTests  string                   `json:"registered_tests,omitempty"`
+}
+
+// studentHomework is a session as the dashboards read it, with "percentage"
+// as well as "percentage_complete".
+type studentHomework struct {
+	ID                 string `json:"id"`
+	Date               string `json:"date"`
+	Attendance         string `json:"attendance"`
+	Duration           string `json:"duration"`
+	Feedback           string `json:"feedback"`
+	PercentageComplete string `json:"percentage_complete"`
+	Percentage         string `json:"percentage"`
+	Tutor              string `json:"tutor"`
+	Timestamp          string `json:"timestamp"`
+}
+
+func newStudentPersonal(p students.Personal) studentPersonal {
+	return studentPersonal{
+		Name:           p.Name,
+		StudentEmail:   p.StudentEmail,
+		HighSchool:     p.HighSchool,
+		Grade:          p.Grade,
+		Accommodations: p.Accommodations,
+		Interests:      p.Interests,
+	}
+}
+
+func newStudentBusiness(b students.Business) studentBusiness {
+	return studentBusiness{
+		TestFocus:        b.TestFocus,
+		TestAppointment:  b.TestAppointment,
+		AssociatedTutors: b.AssociatedTutors,
+		TeamLead:         b.TeamLead,
+		Status:           b.Status,
+		RegisteredTests:  b.RegisteredTests,
+	}
+}
+
+func newStudentHomework(sessions []students.HomeworkCompletion) []studentHomework {
+	out := make([]studentHomework, 0, len(sessions))
+	for _, hw := range sessions {
+		out = append(out, studentHomework{
+			ID:                 hw.ID,
+			Date:               hw.Date,
+			Attendance:         hw.Attendance,
+			Duration:           hw.Duration,
+			Feedback:           hw.Feedback,
+			PercentageComplete: hw.PercentageComplete,
+			Percentage:         hw.PercentageComplete,
+			Tutor:              hw.Tutor,
+			Timestamp:          hw.Timestamp,
+		})
+	}
+	return out
+}
+
+// newStudentDetailResponse builds the student's view of a loaded student.
+func newStudentDetailResponse(d *students.Detail) StudentDetailResponse {
+	return StudentDetailResponse{
+		ID:                 d.ID,
+		Personal:           newStudentPersonal(d.Personal),
+		Business:           newStudentBusiness(d.Business),
+		HomeworkCompletion: newStudentHomework(d.HomeworkCompletion),
+		TestData:           nonNil(d.TestData),
+		TestDates:          nonNil(d.TestDates),
+		Goals:              nonNil(d.Goals),
+	}
+}
+
+// nonNil makes empty lists encode as [] rather than null.
+func nonNil[T any](list []T) []T {
+	if list == nil {
+		return []T{}
+	}
+	return list
+}
+
+// currentStudent loads the student document of the caller. Students sign in
+// with their document ID as user_id.
+func (a *App) currentStudent(w http.ResponseWriter, r *http.Request) (*students.Student, bool) {
+	studentID, err := middleware.ExtractUserIDFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unable to identify student user", http.StatusUnauthorized)
+		return nil, false
+	}
+	student, err := a.Students.Get(r.Context(), studentID)
+	if errors.Is(err, students.ErrNotFound) {
+		log.Printf("No student document for user %s", studentID)
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return nil, false
+	}
+	if err != nil {
+		log.Printf("Error fetching student %s: %v", studentID, err)
+		http.Error(w, "Error fetching student data", http.StatusInternalServerError)
+		return nil, false
+	}
+	return student, true
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// DashboardHandler handles GET /api/student/dashboard: the caller's profile
+// with their sessions, scores, test dates and goals.
+func (a *App) DashboardHandler(w http.ResponseWriter, r *http.Request) {
+	student, ok := a.currentStudent(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, newStudentDetailResponse(students.LoadDetail(r.Context(), a.Students, student)))
+}
+
+// AssignmentsHandler handles GET /api/student/assignments: coursework in the
+// caller's Google Classroom that is not due yet.
+func (a *App) AssignmentsHandler(w http.ResponseWriter, r *http.Request) {
+	student, ok := a.currentStudent(w, r)
+	if !ok {
+		return
+	}
+	if student.Business.ClassroomID == "" {
+		writeJSON(w, []Assignment{})
+		return
+	}
+	assignments, err := a.Assignments(r.Context(), student.Business.ClassroomID)
+	if err != nil {
+		log.Printf("Error fetching assignments of student %s: %v", student.ID, err)
+		http.Error(w, "Error fetching assignments", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, upcoming(assignments, time.Now()))
+}
+
+// SessionsHandler handles GET /api/student/sessions: the caller's session history.
+func (a *App) SessionsHandler(w http.ResponseWriter, r *http.Request) {
+	student, ok := a.currentStudent(w, r)
+	if !ok {
+		return
+	}
+	sessions, err := a.Students.ListHomework(r.Context(), student.ID)
+	if err != nil {
+		log.Printf("Error fetching sessions of student %s: %v", student.ID, err)
+		http.Error(w, "Error fetching sessions", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, newStudentHomework(sessions))
+}
+
+// TestDatesHandler handles GET /api/student/test-dates.
+func (a *App) TestDatesHandler(w http.ResponseWriter, r *http.Request) {
+	student, ok := a.currentStudent(w, r)
+	if !ok {
+		return
+	}
+	dates, err := a.Students.ListTestDates(r.Context(), student.ID)
+	if err != nil {
+		log.Printf("Error fetching test dates of student %s: %v", student.ID, err)
+		http.Error(w, "Error fetching test dates", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, nonNil(dates))
+}
+
+// ScoresHandler handles GET /api/student/scores: the caller's test results.
+func (a *App) ScoresHandler(w http.ResponseWriter, r *http.Request) {
+	student, ok := a.currentStudent(w, r)
+	if !ok {
+		return
+	}
+	scores, err := a.Students.ListTestData(r.Context(), student.ID)
+	if err != nil {
+		log.Printf("Error fetching scores of student %s: %v", student.ID, err)
+		http.Error(w, "Error fetching scores", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, nonNil(scores))
+}
+
+// GoalsHandler handles GET /api/student/goals: the caller's target colleges.
+func (a *App) GoalsHandler(w http.ResponseWriter, r *http.Request) {
+	student, ok := a.currentStudent(w, r)
+	if !ok {
+		return
+	}
+	goals, err := a.Students.ListGoals(r.Context(), student.ID)
+	if err != nil {
+		log.Printf("Error fetching goals of student %s: %v", student.ID, err)
+		http.Error(w, "Error fetching goals", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, nonNil(goals))
+}
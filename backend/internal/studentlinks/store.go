@@ -0,0 +1,182 @@
+// backend/internal/studentlinks/store.go
+
+package studentlinks
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Store keeps link requests.
+type Store interface {
+	Create(ctx context.Context, req *Request) error
+	Get(ctx context.Context, id string) (*Request, bool, error)
+	// Update applies fn to the request atomically. If fn returns an error the
+	// request is left as it was.
+	Update(ctx context.Context, id string, fn func(*Request) error) error
+	// ListByStatus returns the requests with status, oldest first.
+	ListByStatus(ctx context.Context, status string) ([]*Request, error)
+	// ListByParent returns the requests of a parent, oldest first.
+	ListByParent(ctx context.Context, parentID string) ([]*Request, error)
+}
+
+type firestoreStore struct {
+	client *firestore.Client
+}
+
+// NewFirestoreStore returns a Store backed by the "student_link_requests"
+// collection. Decided requests are kept as the record of the decision.
+func NewFirestoreStore(client *firestore.Client) Store {
+	return &firestoreStore{client: client}
+}
+
+func (f *firestoreStore) requests() *firestore.CollectionRef {
+	return f.client.Collection("student_link_requests")
+}
+
+func (f *firestoreStore) Create(ctx context.Context, req *Request) error {
+	_, err := f.requests().Doc(req.ID).Create(ctx, req)
+	return err
+}
+
+func (f *firestoreStore) Get(ctx context.Context, id string) (*Request, bool, error) {
+	snap, err := f.requests().Doc(id).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	var req Request
+	if err := snap.DataTo(&req); err != nil {
+		return nil, false, err
+	}
+	req.ID = id
+	return &req, true, nil
+}
+
+func (f *firestoreStore) Update(ctx context.Context, id string, fn func(*Request) error) error {
+	ref := f.requests().Doc(id)
+	return f.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		snap, err := tx.Get(ref)
+		if status.Code(err) == codes.NotFound {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		var req Request
+		if err := snap.DataTo(&req); err != nil {
+			return err
+		}
+		req.ID = id
+		if err := fn(&req); err != nil {
+			return err
+		}
+		return tx.Set(ref, &req)
+	})
+}
+
+func (f *firestoreStore) ListByStatus(ctx context.Context, status string) ([]*Request, error) {
+	return f.query(ctx, f.requests().Where("status", "==", status))
+}
+
+func (f *firestoreStore) ListByParent(ctx context.Context, parentID string) ([]*Request, error) {
+	return f.query(ctx, f.requests().Where("parent_id", "==", parentID))
+}
+
+func (f *firestoreStore) query(ctx context.Context, q firestore.Query) ([]*Request, error) {
+	snaps, err := q.Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*Request, 0, len(snaps))
+	for _, snap := range snaps {
+		var req Request
+		if err := snap.DataTo(&req); err != nil {
+			return nil, err
+		}
+		req.ID = snap.Ref.ID
+		out = append(out, &req)
+	}
+	sortOldestFirst(out)
+	return out, nil
+}
+
+func sortOldestFirst(reqs []*Request) {
+	sort.SliceStable(reqs, func(i, j int) bool {
+		return reqs[i].CreatedAt.Before(reqs[j].CreatedAt)
+	})
+}
+
+// MemoryStore is an in-memory Store for tests and local runs.
+type MemoryStore struct {
+	mu       sync.Mutex
+	requests map[string]Request
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{requests: map[string]Request{}}
+}
+
+func (m *MemoryStore) Create(ctx context.Context, req *Request) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.requests[req.ID]; ok {
+		return status.Errorf(codes.AlreadyExists, "link request %s already exists", req.ID)
+	}
+	m.requests[req.ID] = *req
+	return nil
+}
+
+func (m *MemoryStore) Get(ctx context.Context, id string) (*Request, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	req, ok := m.requests[id]
+	if !ok {
+		return nil, false, nil
+	}
+	return &req, true, nil
+}
+
+func (m *MemoryStore) Update(ctx context.Context, id string, fn func(*Request) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	req, ok := m.requests[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if err := fn(&req); err != nil {
+		return err
+	}
+	m.requests[id] = req
+	return nil
+}
+
+func (m *MemoryStore) ListByStatus(ctx context.Context, status string) ([]*Request, error) {
+	return m.filter(func(req *Request) bool { return req.Status == status }), nil
+}
+
+func (m *MemoryStore) ListByParent(ctx context.Context, parentID string) ([]*Request, error) {
+	return m.filter(func(req *Request) bool { return req.ParentID == parentID }), nil
+}
+
+func (m *MemoryStore) filter(keep func(*Request) bool) []*Request {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []*Request
+	for _, req := range m.requests {
+		req := req
+		if keep(&req) {
+			out = append(out, &req)
+		}
+	}
+	sortOldestFirst(out)
+	return out
+}
@@ -0,0 +1,178 @@
+// backend/internal/studentlinks/studentlinks.go
+
+package studentlinks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/notify"
+)
+
+// When a parent signs in, students whose parent_email matches are linked to
+// them automatically. If the match is not clear-cut the link waits here for
+// a team lead or admin to approve or reject it, and the parent is emailed
+// the outcome.
+
+// Statuses of a Request.
+const (
+	StatusPending  = "pending"
+	StatusApproved = "approved"
+	StatusRejected = "rejected"
+)
+
+// Reasons a link needs review.
+const (
+	// ReasonSharedEmail means more than one parent account has the email.
+	ReasonSharedEmail = "shared_email"
+	// ReasonLinkedElsewhere means the student is linked to another parent.
+	ReasonLinkedElsewhere = "linked_to_other_parent"
+)
+
+var (
+	ErrNotFound = errors.New("link request not found")
+	ErrDecided  = errors.New("link request has already been decided")
+)
+
+// Request is a document in the "student_link_requests" collection. Its ID
+// is made from the parent and student, so a pair is only queued once.
+type Request struct {
+	ID          string    `firestore:"-" json:"id"`
+	ParentID    string    `firestore:"parent_id" json:"parent_id"`
+	ParentEmail string    `firestore:"parent_email" json:"parent_email"`
+	StudentID   string    `firestore:"student_id" json:"student_id"`
+	StudentName string    `firestore:"student_name" json:"student_name"`
+	Reason      string    `firestore:"reason" json:"reason"`
+	Status      string    `firestore:"status" json:"status"`
+	CreatedAt   time.Time `firestore:"created_at" json:"created_at"`
+	// DecidedBy is the user ID of the staff member who approved or rejected it.
+	DecidedBy string     `firestore:"decided_by,omitempty" json:"decided_by,omitempty"`
+	DecidedAt *time.Time `firestore:"decided_at,omitempty" json:"decided_at,omitempty"`
+	// RejectReason is shown to the parent.
+	RejectReason string `firestore:"reject_reason,omitempty" json:"reject_reason,omitempty"`
+}
+
+// RequestID returns the ID of the request linking parentID to studentID.
+func RequestID(parentID, studentID string) string {
+	return parentID + "_" + studentID
+}
+
+// Linker adds a student to a parent's household.
+type Linker func(ctx context.Context, parentID, studentID string) error
+
+// Queue holds the links waiting for review.
+type Queue struct {
+	store  Store
+	link   Linker
+	mailer notify.Mailer
+	now    func() time.Time
+}
+
+// NewQueue returns a Queue over store that links approved students with
+// link and emails parents through mailer.
+func NewQueue(store Store, link Linker, mailer notify.Mailer) *Queue {
+	return &Queue{store: store, link: link, mailer: mailer, now: time.Now}
+}
+
+// Submit queues req for review unless the same parent and student were
+// queued before, whatever the outcome was then. It reports whether req was
+// queued.
+func (q *Queue) Submit(ctx context.Context, req Request) (bool, error) {
+	req.ID = RequestID(req.ParentID, req.StudentID)
+	if _, exists, err := q.store.Get(ctx, req.ID); err != nil || exists {
+		return false, err
+	}
+	req.Status = StatusPending
+	req.CreatedAt = q.now()
+	if err := q.store.Create(ctx, &req); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// List returns the requests with status, oldest first.
+func (q *Queue) List(ctx context.Context, status string) ([]*Request, error) {
+	return q.store.ListByStatus(ctx, status)
+}
+
+// ForParent returns the requests of a parent, oldest first.
+func (q *Queue) ForParent(ctx context.Context, parentID string) ([]*Request, error) {
+	return q.store.ListByParent(ctx, parentID)
+}
+
+// Approve links the student of a pending request to its parent.
+func (q *Queue) Approve(ctx context.Context, id, staffID string) (*Request, error) {
+	req, err := q.decide(ctx, id, staffID, StatusApproved, "")
+	if err != nil {
+		return nil, err
+	}
+	if err := q.link(ctx, req.ParentID, req.StudentID); err != nil {
+		// Put the request back so it can be approved again.
+		if undoErr := q.store.Update(ctx, id, func(r *Request) error {
+			r.Status, r.DecidedBy, r.DecidedAt = StatusPending, "", nil
+			return nil
+		}); undoErr != nil {
+			log.Printf("Error returning link request %s to pending: %v", id, undoErr)
+		}
+		return nil, fmt.Errorf("link student %s to parent %s: %w", req.StudentID, req.ParentID, err)
+	}
+	q.notify(ctx, req)
+	return req, nil
+}
+
+// Reject closes a pending request without linking. reason is emailed to
+// the parent.
+func (q *Queue) Reject(ctx context.Context, id, staffID, reason string) (*Request, error) {
+	req, err := q.decide(ctx, id, staffID, StatusRejected, reason)
+	if err != nil {
+		return nil, err
+	}
+	q.notify(ctx, req)
+	return req, nil
+}
+
+func (q *Queue) decide(ctx context.Context, id, staffID, status, reason string) (*Request, error) {
+	var decided Request
+	err := q.store.Update(ctx, id, func(r *Request) error {
+		if r.Status != StatusPending {
+			return ErrDecided
+		}
+		now := q.now()
+		r.Status, r.DecidedBy, r.DecidedAt, r.RejectReason = status, staffID, &now, reason
+		decided = *r
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &decided, nil
+}
+
+// notify emails the parent the outcome of req. A failure is logged: the
+// decision stands either way.
+func (q *Queue) notify(ctx context.Context, req *Request) {
+	if req.ParentEmail == "" {
+		log.Printf("Not emailing the outcome of link request %s: parent has no email", req.ID)
+		return
+	}
+	name := req.StudentName
+	if name == "" {
+		name = "your student"
+	}
+	msg := notify.Message{To: []string{req.ParentEmail}}
+	if req.Status == StatusApproved {
+		msg.Subject = "Your student is linked to your Lee Tutoring account"
+		msg.Body = fmt.Sprintf("Hi,\n\n%s is now linked to your Lee Tutoring account. "+
+			"Sign in to see their sessions, scores and hours.\n\nLee Tutoring\n", name)
+	} else {
+		msg.Subject = "We could not link your student"
+		msg.Body = fmt.Sprintf("Hi,\n\nWe could not link %s to your Lee Tutoring account: %s\n\n"+
+			"If this is a mistake, ask your tutor for an invitation code.\n\nLee Tutoring\n", name, req.RejectReason)
+	}
+	if err := q.mailer.Send(ctx, msg); err != nil {
+		log.Printf("Error emailing the outcome of link request %s: %v", req.ID, err)
+	}
+}
@@ -0,0 +1,269 @@
+// backend/internal/students/decode.go
+
+package students
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The student documents were written over the years by the Sheets importers,
+// the Apps Script triggers and the dashboards, so the same field can hold a
+// string in one document and a number in another. Everything that reads a
+// document goes through these helpers so that the rest of the code only sees
+// the typed model. The conversions between those types are the ones the
+// writers are known to produce; anything else is an error naming the field,
+// rather than a guess.
+
+// decoder converts the fields of one document, keeping the first error.
+type decoder struct {
+	err error
+}
+
+func (d *decoder) fail(field string, v interface{}) {
+	if d.err == nil {
+		d.err = fmt.Errorf("%w: %s is %T %v", ErrBadField, field, v, v)
+	}
+}
+
+func decodeStudent(id string, data map[string]interface{}) (*Student, error) {
+	d := &decoder{}
+	personal := d.asMap("personal", data["personal"])
+	business := d.asMap("business", data["business"])
+	appointment := d.asMap("business.test_appointment", business["test_appointment"])
+
+	s := &Student{
+		ID: id,
+		Personal: Personal{
+			Name:           d.asString("personal.name", personal["name"]),
+			StudentEmail:   d.asString("personal.student_email", personal["student_email"]),
+			StudentNumber:  d.asString("personal.student_number", personal["student_number"]),
+			ParentEmail:    d.asString("personal.parent_email", personal["parent_email"]),
+			ParentNumber:   d.asString("personal.parent_number", personal["parent_number"]),
+			HighSchool:     d.asString("personal.high_school", personal["high_school"]),
+			Grade:          d.asString("personal.grade", personal["grade"]),
+			Accommodations: d.asString("personal.accommodations", personal["accommodations"]),
+			Interests:      d.asString("personal.interests", personal["interests"]),
+		},
+		Business: Business{
+			FirebaseID: d.asString("business.firebase_id", business["firebase_id"]),
+			Scheduler:  d.asString("business.scheduler", business["scheduler"]),
+			TestFocus:  d.asString("business.test_focus", business["test_focus"]),
+			TestAppointment: TestAppointment{
+				RegisteredForTest: d.asBool("business.test_appointment.registered_for_test", appointment["registered_for_test"]),
+				TestDate:          d.asString("business.test_appointment.test_date", appointment["test_date"]),
+			},
+			AssociatedTutors: d.asStrings("business.associated_tutors", business["associated_tutors"]),
+			TeamLead:         d.asString("business.team_lead", business["team_lead"]),
+			Status:           d.asString("business.status", business["status"]),
+			Notes:            d.asString("business.notes", business["notes"]),
+			RegisteredTests:  d.asString("business.registered_tests", business["registered_tests"]),
+			RemainingHours:   d.asFloat("business.remaining_hours", business["remaining_hours"]),
+			LifetimeHours:    d.asFloat("business.lifetime_hours", business["lifetime_hours"]),
+			ClassroomID:      d.asString("business.classroom_id", business["classroom_id"]),
+			DriveURL:         d.asString("business.drive_url", business["drive_url"]),
+		},
+	}
+	return s, d.err
+}
+
+func decodeHomework(id string, data map[string]interface{}) (HomeworkCompletion, error) {
+	d := &decoder{}
+	hw := HomeworkCompletion{
+		ID:                 id,
+		Date:               d.asString("date", data["date"]),
+		Attendance:         d.asString("attendance", data["attendance"]),
+		Duration:           d.asString("duration", data["duration"]),
+		Feedback:           d.asString("feedback", data["feedback"]),
+		PercentageComplete: d.asString("percentage_complete", data["percentage_complete"]),
+		Engagement:         d.asString("engagement", data["engagement"]),
+		Tutor:              d.asString("tutor", data["tutor"]),
+		Timestamp:          d.asString("timestamp", data["timestamp"]),
+	}
+	return hw, d.err
+}
+
+func decodeTestData(id string, data map[string]interface{}) (TestData, error) {
+	d := &decoder{}
+	td := TestData{
+		ID:        id,
+		Date:      d.asString("date", data["date"]),
+		Test:      d.asString("test", data["test"]),
+		Type:      d.asString("type", data["type"]),
+		Baseline:  d.asBool("baseline", data["baseline"]),
+		UpdatedAt: d.asString("updated_at", data["updated_at"]),
+	}
+	if data["ACT_Scores"] != nil {
+		act := d.asMap("ACT_Scores", data["ACT_Scores"])
+		td.ACTScores = &ACTScores{
+			English: d.asScore("ACT_Scores.English", act["English"]),
+			Math:    d.asScore("ACT_Scores.Math", act["Math"]),
+			Reading: d.asScore("ACT_Scores.Reading", act["Reading"]),
+			Science: d.asScore("ACT_Scores.Science", act["Science"]),
+			Total:   d.asScore("ACT_Scores.ACT_Total", act["ACT_Total"]),
+		}
+	}
+	if data["SAT_Scores"] != nil {
+		sat := d.asMap("SAT_Scores", data["SAT_Scores"])
+		td.SATScores = &SATScores{
+			EBRW:    d.asScore("SAT_Scores.EBRW", sat["EBRW"]),
+			Math:    d.asScore("SAT_Scores.Math", sat["Math"]),
+			Reading: d.asScore("SAT_Scores.Reading", sat["Reading"]),
+			Writing: d.asScore("SAT_Scores.Writing", sat["Writing"]),
+			Total:   d.asScore("SAT_Scores.SAT_Total", sat["SAT_Total"]),
+		}
+	}
+	return td, d.err
+}
+
+func decodeTestDate(id string, data map[string]interface{}) (TestDate, error) {
+	d := &decoder{}
+	// The Apps Script trigger used to write title-cased keys; fall back to them.
+	pick := func(key, legacy string) string {
+		if v := d.asString(key, data[key]); v != "" {
+			return v
+		}
+		return d.asString(legacy, data[legacy])
+	}
+	td := TestDate{
+		ID:                          id,
+		TestType:                    pick("test_type", "Test Type"),
+		TestDate:                    pick("test_date", "Test Date"),
+		RegularRegistrationDeadline: pick("regular_registration_deadline", "Regular Registration Deadline"),
+		LateRegistrationDeadline:    pick("late_registration_deadline", "Late Registration Deadline"),
+		ScoreReleaseDate:            pick("score_release_date", "Score Release Date"),
+		Notes:                       pick("notes", "Notes"),
+	}
+	return td, d.err
+}
+
+func decodeGoal(id string, data map[string]interface{}) (Goal, error) {
+	d := &decoder{}
+	college := d.asString("College", data["College"])
+	if college == "" {
+		college = d.asString("university", data["university"])
+	}
+	g := Goal{
+		ID:             id,
+		College:        college,
+		ACTPercentiles: d.asStrings("ACT_percentiles", data["ACT_percentiles"]),
+		SATPercentiles: d.asStrings("SAT_percentiles", data["SAT_percentiles"]),
+	}
+	return g, d.err
+}
+
+func (d *decoder) asMap(field string, v interface{}) map[string]interface{} {
+	switch t := v.(type) {
+	case nil:
+	case map[string]interface{}:
+		return t
+	default:
+		d.fail(field, v)
+	}
+	return map[string]interface{}{}
+}
+
+// asString accepts the numbers, booleans and timestamps that importers wrote
+// into text fields, formatted as they would be shown.
+func (d *decoder) asString(field string, v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case int64:
+		return strconv.FormatInt(t, 10)
+	case int:
+		return strconv.Itoa(t)
+	case bool:
+		return strconv.FormatBool(t)
+	case time.Time:
+		return t.Format(time.RFC3339)
+	default:
+		d.fail(field, v)
+		return ""
+	}
+}
+
+func (d *decoder) asStrings(field string, v interface{}) []string {
+	switch t := v.(type) {
+	case nil:
+		return []string{}
+	case []string:
+		return t
+	case []interface{}:
+		out := make([]string, 0, len(t))
+		for i, item := range t {
+			out = append(out, d.asString(fmt.Sprintf("%s[%d]", field, i), item))
+		}
+		return out
+	default:
+		d.fail(field, v)
+		return []string{}
+	}
+}
+
+func (d *decoder) asBool(field string, v interface{}) bool {
+	switch t := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return t
+	case string:
+		if strings.TrimSpace(t) == "" {
+			return false
+		}
+		b, err := strconv.ParseBool(strings.TrimSpace(t))
+		if err != nil {
+			d.fail(field, v)
+		}
+		return b
+	default:
+		d.fail(field, v)
+		return false
+	}
+}
+
+// asFloat reads hours, where a missing or blank value is zero.
+func (d *decoder) asFloat(field string, v interface{}) float64 {
+	if f := d.asScore(field, v); f != nil {
+		return *f
+	}
+	return 0
+}
+
+// asScore returns nil for missing or blank values, which the score sheets
+// write as "" or "-", so that "no score" stays distinguishable from a score
+// of zero.
+func (d *decoder) asScore(field string, v interface{}) *float64 {
+	var f float64
+	switch t := v.(type) {
+	case nil:
+		return nil
+	case float64:
+		f = t
+	case int64:
+		f = float64(t)
+	case int:
+		f = float64(t)
+	case string:
+		trimmed := strings.TrimSpace(t)
+		if trimmed == "" || trimmed == "-" {
+			return nil
+		}
+		parsed, err := strconv.ParseFloat(trimmed, 64)
+		if err != nil {
+			d.fail(field, v)
+			return nil
+		}
+		f = parsed
+	default:
+		d.fail(field, v)
+		return nil
+	}
+	return &f
+}
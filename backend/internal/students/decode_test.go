@@ -0,0 +1,173 @@
+// backend/internal/students/decode_test.go
+
+package students
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDecodeStudentMixedTypes(t *testing.T) {
+	// Field types as the importers and dashboards have actually written them.
+	data := map[string]interface{}{
+		"personal": map[string]interface{}{
+			"name":           "Ada Lovelace",
+			"student_number": float64(5551234),
+			"grade":          int64(11),
+		},
+		"business": map[string]interface{}{
+			"associated_tutors": []interface{}{"Edward", "Kyra"},
+			"remaining_hours":   "3.5",
+			"lifetime_hours":    int64(40),
+			"test_appointment": map[string]interface{}{
+				"registered_for_test": "true",
+				"test_date":           time.Date(2024, 10, 26, 0, 0, 0, 0, time.UTC),
+			},
+		},
+	}
+
+	s, err := decodeStudent("student-1", data)
+	if err != nil {
+		t.Fatalf("decodeStudent: %v", err)
+	}
+	want := &Student{
+		ID: "student-1",
+		Personal: Personal{
+			Name:          "Ada Lovelace",
+			StudentNumber: "5551234",
+			Grade:         "11",
+		},
+		Business: Business{
+			AssociatedTutors: []string{"Edward", "Kyra"},
+			RemainingHours:   3.5,
+			LifetimeHours:    40,
+			TestAppointment: TestAppointment{
+				RegisteredForTest: true,
+				TestDate:          "2024-10-26T00:00:00Z",
+			},
+		},
+	}
+	if !reflect.DeepEqual(s, want) {
+		t.Errorf("decodeStudent =\n%+v\nwant\n%+v", s, want)
+	}
+}
+
+func TestDecodeStudentMissingSubdocuments(t *testing.T) {
+	s, err := decodeStudent("student-1", map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("decodeStudent: %v", err)
+	}
+	if s.Personal.Name != "" || s.Business.LifetimeHours != 0 || len(s.Business.AssociatedTutors) != 0 {
+		t.Errorf("decodeStudent of an empty document = %+v", s)
+	}
+}
+
+func TestDecodeStudentUnexpectedTypes(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  map[string]interface{}
+		field string
+	}{
+		{"personal not a map", map[string]interface{}{"personal": "Ada"}, "personal "},
+		{"map in a text field", map[string]interface{}{
+			"personal": map[string]interface{}{"name": map[string]interface{}{"first": "Ada"}},
+		}, "personal.name "},
+		{"hours not a number", map[string]interface{}{
+			"business": map[string]interface{}{"lifetime_hours": "forty"},
+		}, "business.lifetime_hours "},
+		{"hours of the wrong type", map[string]interface{}{
+			"business": map[string]interface{}{"remaining_hours": true},
+		}, "business.remaining_hours "},
+		{"tutors not a list", map[string]interface{}{
+			"business": map[string]interface{}{"associated_tutors": "Edward"},
+		}, "business.associated_tutors "},
+		{"list item not text", map[string]interface{}{
+			"business": map[string]interface{}{"associated_tutors": []interface{}{"Edward", []interface{}{"Kyra"}}},
+		}, "business.associated_tutors[1] "},
+		{"registered not a boolean", map[string]interface{}{
+			"business": map[string]interface{}{
+				"test_appointment": map[string]interface{}{"registered_for_test": "maybe"},
+			},
+		}, "business.test_appointment.registered_for_test "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := decodeStudent("student-1", tt.data)
+			if !errors.Is(err, ErrBadField) {
+				t.Fatalf("decodeStudent error = %v, want %v", err, ErrBadField)
+			}
+			if !strings.Contains(err.Error(), tt.field) {
+				t.Errorf("error %q does not name %q", err, strings.TrimSpace(tt.field))
+			}
+		})
+	}
+}
+
+func TestDecodeTestDataScores(t *testing.T) {
+	td, err := decodeTestData("Official ACT 10-26-2024", map[string]interface{}{
+		"type":     "Official",
+		"test":     "ACT",
+		"date":     "10/26/2024",
+		"baseline": false,
+		"ACT_Scores": map[string]interface{}{
+			"English":   float64(30),
+			"Math":      "28",
+			"Reading":   "-",
+			"Science":   "",
+			"ACT_Total": int64(29),
+		},
+	})
+	if err != nil {
+		t.Fatalf("decodeTestData: %v", err)
+	}
+	if td.SATScores != nil {
+		t.Errorf("SATScores = %+v, want nil for a document without them", td.SATScores)
+	}
+	act := td.ACTScores
+	if act == nil || act.English == nil || *act.English != 30 || act.Math == nil || *act.Math != 28 ||
+		act.Reading != nil || act.Science != nil || act.Total == nil || *act.Total != 29 {
+		t.Errorf("ACTScores = %+v", act)
+	}
+
+	_, err = decodeTestData("x", map[string]interface{}{
+		"ACT_Scores": map[string]interface{}{"Math": "twenty"},
+	})
+	if !errors.Is(err, ErrBadField) || !strings.Contains(err.Error(), "ACT_Scores.Math") {
+		t.Errorf("decodeTestData with a bad score error = %v, want %v naming ACT_Scores.Math", err, ErrBadField)
+	}
+}
+
+func TestDecodeTestDateLegacyKeys(t *testing.T) {
+	td, err := decodeTestDate("ACT 10-26-2024", map[string]interface{}{
+		"Test Type": "ACT",
+		"Test Date": "10/26/2024",
+		"notes":     "Bring a calculator",
+	})
+	if err != nil {
+		t.Fatalf("decodeTestDate: %v", err)
+	}
+	if td.TestType != "ACT" || td.TestDate != "10/26/2024" || td.Notes != "Bring a calculator" {
+		t.Errorf("decodeTestDate = %+v", td)
+	}
+}
+
+func TestDecodeGoal(t *testing.T) {
+	g, err := decodeGoal("MIT", map[string]interface{}{
+		"university":      "MIT",
+		"ACT_percentiles": []interface{}{float64(34), float64(35), float64(36)},
+	})
+	if err != nil {
+		t.Fatalf("decodeGoal: %v", err)
+	}
+	want := Goal{ID: "MIT", College: "MIT", ACTPercentiles: []string{"34", "35", "36"}, SATPercentiles: []string{}}
+	if !reflect.DeepEqual(g, want) {
+		t.Errorf("decodeGoal = %+v, want %+v", g, want)
+	}
+
+	if _, err := decodeGoal("MIT", map[string]interface{}{"SAT_percentiles": float64(1500)}); !errors.Is(err, ErrBadField) {
+		t.Errorf("decodeGoal with a number for a list error = %v, want %v", err, ErrBadField)
+	}
+}
@@ -0,0 +1,281 @@
+// backend/internal/students/firestore.go
+
+package students
+
+import (
+	"context"
+	"fmt"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Subcollection names under each student document.
+const (
+	homeworkCollection = "Homework Completion"
+	testDataCollection = "Test Data"
+	testDateCollection = "Test Dates"
+	goalsCollection    = "Goals"
+)
+
+type firestoreRepository struct {
+	client *firestore.Client
+}
+
+// NewFirestoreRepository returns a Repository backed by the "students" collection.
+func NewFirestoreRepository(client *firestore.Client) Repository {
+	return &firestoreRepository{client: client}
+}
+
+func (r *firestoreRepository) doc(id string) *firestore.DocumentRef {
+	return r.client.Collection("students").Doc(id)
+}
+
+// translate maps Firestore status codes onto the package errors.
+func translate(err error) error {
+	switch status.Code(err) {
+	case codes.NotFound:
+		return ErrNotFound
+	case codes.AlreadyExists:
+		return ErrExists
+	default:
+		return err
+	}
+}
+
+func (r *firestoreRepository) Get(ctx context.Context, id string) (*Student, error) {
+	snap, err := r.doc(id).Get(ctx)
+	if err != nil {
+		return nil, translate(err)
+	}
+	s, err := decodeStudent(snap.Ref.ID, snap.Data())
+	if err != nil {
+		return nil, fmt.Errorf("decode students/%s: %w", snap.Ref.ID, err)
+	}
+	return s, nil
+}
+
+func (r *firestoreRepository) query(ctx context.Context, q firestore.Query) ([]*Student, error) {
+	snaps, err := q.Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*Student, 0, len(snaps))
+	for _, snap := range snaps {
+		s, err := decodeStudent(snap.Ref.ID, snap.Data())
+		if err != nil {
+			return nil, fmt.Errorf("decode students/%s: %w", snap.Ref.ID, err)
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+func (r *firestoreRepository) List(ctx context.Context) ([]*Student, error) {
+	return r.query(ctx, r.client.Collection("students").Query)
+}
+
+func (r *firestoreRepository) FindByName(ctx context.Context, name string) (*Student, error) {
+	found, err := r.query(ctx, r.client.Collection("students").Where("personal.name", "==", name).Limit(1))
+	if err != nil {
+		return nil, err
+	}
+	if len(found) == 0 {
+		return nil, ErrNotFound
+	}
+	return found[0], nil
+}
+
+func (r *firestoreRepository) FindByParentEmail(ctx context.Context, email string) ([]*Student, error) {
+	return r.query(ctx, r.client.Collection("students").Where("personal.parent_email", "==", email))
+}
+
+func (r *firestoreRepository) FindByClassroomID(ctx context.Context, classroomID string) ([]*Student, error) {
+	return r.query(ctx, r.client.Collection("students").Where("business.classroom_id", "==", classroomID))
+}
+
+func (r *firestoreRepository) Create(ctx context.Context, s *Student) (string, error) {
+	ref := r.client.Collection("students").NewDoc()
+	s.ID = ref.ID
+	s.Business.FirebaseID = ref.ID
+	if s.Business.AssociatedTutors == nil {
+		s.Business.AssociatedTutors = []string{}
+	}
+	if _, err := ref.Set(ctx, s); err != nil {
+		return "", err
+	}
+	return ref.ID, nil
+}
+
+func (r *firestoreRepository) UpdatePersonal(ctx context.Context, id string, p Personal) error {
+	_, err := r.doc(id).Update(ctx, []firestore.Update{
+		{Path: "personal.name", Value: p.Name},
+		{Path: "personal.student_email", Value: p.StudentEmail},
+		{Path: "personal.student_number", Value: p.StudentNumber},
+		{Path: "personal.parent_email", Value: p.ParentEmail},
+		{Path: "personal.parent_number", Value: p.ParentNumber},
+		{Path: "personal.high_school", Value: p.HighSchool},
+		{Path: "personal.grade", Value: p.Grade},
+		{Path: "personal.accommodations", Value: p.Accommodations},
+		{Path: "personal.interests", Value: p.Interests},
+	})
+	return translate(err)
+}
+
+func (r *firestoreRepository) UpdateBusinessDetails(ctx context.Context, id string, d BusinessDetails) error {
+	tutors := d.AssociatedTutors
+	if tutors == nil {
+		tutors = []string{}
+	}
+	_, err := r.doc(id).Update(ctx, []firestore.Update{
+		{Path: "business.associated_tutors", Value: tutors},
+		{Path: "business.scheduler", Value: d.Scheduler},
+		{Path: "business.status", Value: d.Status},
+		{Path: "business.team_lead", Value: d.TeamLead},
+		{Path: "business.test_focus", Value: d.TestFocus},
+		{Path: "business.test_appointment", Value: d.TestAppointment},
+		{Path: "business.notes", Value: d.Notes},
+	})
+	return translate(err)
+}
+
+func (r *firestoreRepository) SetLifetimeHours(ctx context.Context, id string, hours float64) error {
+	_, err := r.doc(id).Update(ctx, []firestore.Update{{Path: "business.lifetime_hours", Value: hours}})
+	return translate(err)
+}
+
+func (r *firestoreRepository) SetRemainingHours(ctx context.Context, id string, hours float64) error {
+	_, err := r.doc(id).Update(ctx, []firestore.Update{{Path: "business.remaining_hours", Value: hours}})
+	return translate(err)
+}
+
+func (r *firestoreRepository) SetClassroomID(ctx context.Context, id, classroomID string) error {
+	_, err := r.doc(id).Update(ctx, []firestore.Update{{Path: "business.classroom_id", Value: classroomID}})
+	return translate(err)
+}
+
+func (r *firestoreRepository) ListHomework(ctx context.Context, studentID string) ([]HomeworkCompletion, error) {
+	snaps, err := r.doc(studentID).Collection(homeworkCollection).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]HomeworkCompletion, 0, len(snaps))
+	for _, snap := range snaps {
+		doc, err := decodeHomework(snap.Ref.ID, snap.Data())
+		if err != nil {
+			return nil, fmt.Errorf("decode students/%s/%s/%s: %w", studentID, homeworkCollection, snap.Ref.ID, err)
+		}
+		out = append(out, doc)
+	}
+	return out, nil
+}
+
+func (r *firestoreRepository) SaveHomework(ctx context.Context, studentID string, hw HomeworkCompletion) error {
+	_, err := r.doc(studentID).Collection(homeworkCollection).Doc(hw.ID).Set(ctx, hw)
+	return err
+}
+
+func (r *firestoreRepository) UpdateHomework(ctx context.Context, studentID string, hw HomeworkCompletion) error {
+	_, err := r.doc(studentID).Collection(homeworkCollection).Doc(hw.ID).Update(ctx, []firestore.Update{
+		{Path: "attendance", Value: hw.Attendance},
+		{Path: "date", Value: hw.Date},
+		{Path: "duration", Value: hw.Duration},
+		{Path: "feedback", Value: hw.Feedback},
+		{Path: "percentage_complete", Value: hw.PercentageComplete},
+		{Path: "engagement", Value: hw.Engagement},
+		{Path: "tutor", Value: hw.Tutor},
+		{Path: "timestamp", Value: hw.Timestamp},
+	})
+	return translate(err)
+}
+
+func (r *firestoreRepository) DeleteHomework(ctx context.Context, studentID, homeworkID string) error {
+	_, err := r.doc(studentID).Collection(homeworkCollection).Doc(homeworkID).Delete(ctx)
+	return err
+}
+
+func (r *firestoreRepository) ListTestData(ctx context.Context, studentID string) ([]TestData, error) {
+	snaps, err := r.doc(studentID).Collection(testDataCollection).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]TestData, 0, len(snaps))
+	for _, snap := range snaps {
+		doc, err := decodeTestData(snap.Ref.ID, snap.Data())
+		if err != nil {
+			return nil, fmt.Errorf("decode students/%s/%s/%s: %w", studentID, testDataCollection, snap.Ref.ID, err)
+		}
+		out = append(out, doc)
+	}
+	return out, nil
+}
+
+func (r *firestoreRepository) SaveTestData(ctx context.Context, studentID string, td TestData) error {
+	_, err := r.doc(studentID).Collection(testDataCollection).Doc(td.ID).Set(ctx, td)
+	return err
+}
+
+func (r *firestoreRepository) DeleteTestData(ctx context.Context, studentID, testDataID string) error {
+	_, err := r.doc(studentID).Collection(testDataCollection).Doc(testDataID).Delete(ctx)
+	return err
+}
+
+func (r *firestoreRepository) ListTestDates(ctx context.Context, studentID string) ([]TestDate, error) {
+	snaps, err := r.doc(studentID).Collection(testDateCollection).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]TestDate, 0, len(snaps))
+	for _, snap := range snaps {
+		doc, err := decodeTestDate(snap.Ref.ID, snap.Data())
+		if err != nil {
+			return nil, fmt.Errorf("decode students/%s/%s/%s: %w", studentID, testDateCollection, snap.Ref.ID, err)
+		}
+		out = append(out, doc)
+	}
+	return out, nil
+}
+
+func (r *firestoreRepository) CreateTestDate(ctx context.Context, studentID string, td TestDate) error {
+	_, err := r.doc(studentID).Collection(testDateCollection).Doc(td.ID).Create(ctx, td)
+	return translate(err)
+}
+
+func (r *firestoreRepository) SaveTestDate(ctx context.Context, studentID string, td TestDate) error {
+	_, err := r.doc(studentID).Collection(testDateCollection).Doc(td.ID).Set(ctx, td)
+	return err
+}
+
+func (r *firestoreRepository) UpdateTestDateNotes(ctx context.Context, studentID, testDateID, notes string) error {
+	_, err := r.doc(studentID).Collection(testDateCollection).Doc(testDateID).Update(ctx, []firestore.Update{
+		{Path: "notes", Value: notes},
+	})
+	return translate(err)
+}
+
+func (r *firestoreRepository) ListGoals(ctx context.Context, studentID string) ([]Goal, error) {
+	snaps, err := r.doc(studentID).Collection(goalsCollection).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Goal, 0, len(snaps))
+	for _, snap := range snaps {
+		doc, err := decodeGoal(snap.Ref.ID, snap.Data())
+		if err != nil {
+			return nil, fmt.Errorf("decode students/%s/%s/%s: %w", studentID, goalsCollection, snap.Ref.ID, err)
+		}
+		out = append(out, doc)
+	}
+	return out, nil
+}
+
+func (r *firestoreRepository) SaveGoal(ctx context.Context, studentID string, g Goal) error {
+	_, err := r.doc(studentID).Collection(goalsCollection).Doc(g.ID).Set(ctx, g)
+	return err
+}
+
+func (r *firestoreRepository) DeleteGoal(ctx context.Context, studentID, goalID string) error {
+	_, err := r.doc(studentID).Collection(goalsCollection).Doc(goalID).Delete(ctx)
+	return err
+}
@@ -0,0 +1,304 @@
+// backend/internal/students/memory.go
+
+package students
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// memoryRecord is a student and its subcollections, keyed by document ID.
+type memoryRecord struct {
+	student   Student
+	homework  map[string]HomeworkCompletion
+	testData  map[string]TestData
+	testDates map[string]TestDate
+	goals     map[string]Goal
+}
+
+type memoryRepository struct {
+	mu      sync.Mutex
+	nextID  int
+	records map[string]*memoryRecord
+}
+
+// NewMemoryRepository returns an empty in-memory Repository. Like Firestore,
+// it lists documents in ID order.
+func NewMemoryRepository() Repository {
+	return &memoryRepository{records: map[string]*memoryRecord{}}
+}
+
+// record returns the record for id, or ErrNotFound. The caller must hold mu.
+func (m *memoryRepository) record(id string) (*memoryRecord, error) {
+	rec, ok := m.records[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return rec, nil
+}
+
+// sortedKeys returns the keys of a subcollection in ID order.
+func sortedKeys[V any](docs map[string]V) []string {
+	keys := make([]string, 0, len(docs))
+	for k := range docs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (m *memoryRepository) Get(ctx context.Context, id string) (*Student, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rec, err := m.record(id)
+	if err != nil {
+		return nil, err
+	}
+	s := rec.student
+	return &s, nil
+}
+
+func (m *memoryRepository) filter(match func(*Student) bool) []*Student {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []*Student
+	for _, id := range sortedKeys(m.records) {
+		s := m.records[id].student
+		if match(&s) {
+			out = append(out, &s)
+		}
+	}
+	return out
+}
+
+func (m *memoryRepository) List(ctx context.Context) ([]*Student, error) {
+	return m.filter(func(*Student) bool { return true }), nil
+}
+
+func (m *memoryRepository) FindByName(ctx context.Context, name string) (*Student, error) {
+	found := m.filter(func(s *Student) bool { return s.Personal.Name == name })
+	if len(found) == 0 {
+		return nil, ErrNotFound
+	}
+	return found[0], nil
+}
+
+func (m *memoryRepository) FindByParentEmail(ctx context.Context, email string) ([]*Student, error) {
+	return m.filter(func(s *Student) bool { return s.Personal.ParentEmail == email }), nil
+}
+
+func (m *memoryRepository) FindByClassroomID(ctx context.Context, classroomID string) ([]*Student, error) {
+	return m.filter(func(s *Student) bool { return s.Business.ClassroomID == classroomID }), nil
+}
+
+func (m *memoryRepository) Create(ctx context.Context, s *Student) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextID++
+	id := fmt.Sprintf("student-%04d", m.nextID)
+	s.ID = id
+	s.Business.FirebaseID = id
+	if s.Business.AssociatedTutors == nil {
+		s.Business.AssociatedTutors = []string{}
+	}
+	m.records[id] = &memoryRecord{
+		student:   *s,
+		homework:  map[string]HomeworkCompletion{},
+		testData:  map[string]TestData{},
+		testDates: map[string]TestDate{},
+		goals:     map[string]Goal{},
+	}
+	return id, nil
+}
+
+// update applies fn to the stored student under the lock.
+func (m *memoryRepository) update(id string, fn func(*Student)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rec, err := m.record(id)
+	if err != nil {
+		return err
+	}
+	fn(&rec.student)
+	return nil
+}
+
+func (m *memoryRepository) UpdatePersonal(ctx context.Context, id string, p Personal) error {
+	return m.update(id, func(s *Student) { s.Personal = p })
+}
+
+func (m *memoryRepository) UpdateBusinessDetails(ctx context.Context, id string, d BusinessDetails) error {
+	return m.update(id, func(s *Student) {
+		s.Business.AssociatedTutors = d.AssociatedTutors
+		s.Business.Scheduler = d.Scheduler
+		s.Business.Status = d.Status
+		s.Business.TeamLead = d.TeamLead
+		s.Business.TestFocus = d.TestFocus
+		s.Business.TestAppointment = d.TestAppointment
+		s.Business.Notes = d.Notes
+	})
+}
+
+func (m *memoryRepository) SetLifetimeHours(ctx context.Context, id string, hours float64) error {
+	return m.update(id, func(s *Student) { s.Business.LifetimeHours = hours })
+}
+
+func (m *memoryRepository) SetRemainingHours(ctx context.Context, id string, hours float64) error {
+	return m.update(id, func(s *Student) { s.Business.RemainingHours = hours })
+}
+
+func (m *memoryRepository) SetClassroomID(ctx context.Context, id, classroomID string) error {
+	return m.update(id, func(s *Student) { s.Business.ClassroomID = classroomID })
+}
+
+// subcollection runs fn against the record of studentID under the lock.
+// Like Firestore, subcollections of a missing student are simply empty for reads,
+// so only writes report ErrNotFound.
+func (m *memoryRepository) subcollection(studentID string, fn func(*memoryRecord) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rec, err := m.record(studentID)
+	if err != nil {
+		return err
+	}
+	return fn(rec)
+}
+
+func (m *memoryRepository) ListHomework(ctx context.Context, studentID string) ([]HomeworkCompletion, error) {
+	out := []HomeworkCompletion{}
+	err := m.subcollection(studentID, func(rec *memoryRecord) error {
+		for _, id := range sortedKeys(rec.homework) {
+			out = append(out, rec.homework[id])
+		}
+		return nil
+	})
+	if err == ErrNotFound {
+		return out, nil
+	}
+	return out, err
+}
+
+func (m *memoryRepository) SaveHomework(ctx context.Context, studentID string, hw HomeworkCompletion) error {
+	return m.subcollection(studentID, func(rec *memoryRecord) error {
+		rec.homework[hw.ID] = hw
+		return nil
+	})
+}
+
+func (m *memoryRepository) UpdateHomework(ctx context.Context, studentID string, hw HomeworkCompletion) error {
+	return m.subcollection(studentID, func(rec *memoryRecord) error {
+		if _, ok := rec.homework[hw.ID]; !ok {
+			return ErrNotFound
+		}
+		rec.homework[hw.ID] = hw
+		return nil
+	})
+}
+
+func (m *memoryRepository) DeleteHomework(ctx context.Context, studentID, homeworkID string) error {
+	return m.subcollection(studentID, func(rec *memoryRecord) error {
+		delete(rec.homework, homeworkID)
+		return nil
+	})
+}
+
+func (m *memoryRepository) ListTestData(ctx context.Context, studentID string) ([]TestData, error) {
+	out := []TestData{}
+	err := m.subcollection(studentID, func(rec *memoryRecord) error {
+		for _, id := range sortedKeys(rec.testData) {
+			out = append(out, rec.testData[id])
+		}
+		return nil
+	})
+	if err == ErrNotFound {
+		return out, nil
+	}
+	return out, err
+}
+
+func (m *memoryRepository) SaveTestData(ctx context.Context, studentID string, td TestData) error {
+	return m.subcollection(studentID, func(rec *memoryRecord) error {
+		rec.testData[td.ID] = td
+		return nil
+	})
+}
+
+func (m *memoryRepository) DeleteTestData(ctx context.Context, studentID, testDataID string) error {
+	return m.subcollection(studentID, func(rec *memoryRecord) error {
+		delete(rec.testData, testDataID)
+		return nil
+	})
+}
+
+func (m *memoryRepository) ListTestDates(ctx context.Context, studentID string) ([]TestDate, error) {
+	out := []TestDate{}
+	err := m.subcollection(studentID, func(rec *memoryRecord) error {
+		for _, id := range sortedKeys(rec.testDates) {
+			out = append(out, rec.testDates[id])
+		}
+		return nil
+	})
+	if err == ErrNotFound {
+		return out, nil
+	}
+	return out, err
+}
+
+func (m *memoryRepository) CreateTestDate(ctx context.Context, studentID string, td TestDate) error {
+	return m.subcollection(studentID, func(rec *memoryRecord) error {
+		if _, ok := rec.testDates[td.ID]; ok {
+			return ErrExists
+		}
+		rec.testDates[td.ID] = td
+		return nil
+	})
+}
+
+func (m *memoryRepository) SaveTestDate(ctx context.Context, studentID string, td TestDate) error {
+	return m.subcollection(studentID, func(rec *memoryRecord) error {
+		rec.testDates[td.ID] = td
+		return nil
+	})
+}
+
+func (m *memoryRepository) UpdateTestDateNotes(ctx context.Context, studentID, testDateID, notes string) error {
+	return m.subcollection(studentID, func(rec *memoryRecord) error {
+		td, ok := rec.testDates[testDateID]
+		if !ok {
+			return ErrNotFound
+		}
+		td.Notes = notes
+		rec.testDates[testDateID] = td
+		return nil
+	})
+}
+
+func (m *memoryRepository) ListGoals(ctx context.Context, studentID string) ([]Goal, error) {
+	out := []Goal{}
+	err := m.subcollection(studentID, func(rec *memoryRecord) error {
+		for _, id := range sortedKeys(rec.goals) {
+			out = append(out, rec.goals[id])
+		}
+		return nil
+	})
+	if err == ErrNotFound {
+		return out, nil
+	}
+	return out, err
+}
+
+func (m *memoryRepository) SaveGoal(ctx context.Context, studentID string, g Goal) error {
+	return m.subcollection(studentID, func(rec *memoryRecord) error {
+		rec.goals[g.ID] = g
+		return nil
+	})
+}
+
+func (m *memoryRepository) DeleteGoal(ctx context.Context, studentID, goalID string) error {
+	return m.subcollection(studentID, func(rec *memoryRecord) error {
+		delete(rec.goals, goalID)
+		return nil
+	})
+}
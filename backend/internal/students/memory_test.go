@@ -0,0 +1,125 @@
+// backend/internal/students/memory_test.go
+
+package students
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestMemoryRepositoryStudents(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepository()
+
+	id, err := repo.Create(ctx, &Student{Personal: Personal{Name: "Ada Lovelace", ParentEmail: "pat@example.com"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := repo.Get(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.ID != id || s.Business.FirebaseID != id || s.Business.AssociatedTutors == nil {
+		t.Errorf("created student = %+v, want ID and firebase_id %s and empty tutors", s, id)
+	}
+
+	if _, err := repo.Get(ctx, "student-9999"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get of a missing student error = %v, want %v", err, ErrNotFound)
+	}
+	if found, err := repo.FindByName(ctx, "Ada Lovelace"); err != nil || found.ID != id {
+		t.Errorf("FindByName = %v, %v; want %s", found, err, id)
+	}
+	if _, err := repo.FindByName(ctx, "Alan Turing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("FindByName of a missing name error = %v, want %v", err, ErrNotFound)
+	}
+	if found, err := repo.FindByParentEmail(ctx, "pat@example.com"); err != nil || len(found) != 1 {
+		t.Errorf("FindByParentEmail = %v, %v; want one student", found, err)
+	}
+
+	if err := repo.SetLifetimeHours(ctx, id, 40); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.SetClassroomID(ctx, id, "classroom-1"); err != nil {
+		t.Fatal(err)
+	}
+	err = repo.UpdateBusinessDetails(ctx, id, BusinessDetails{AssociatedTutors: []string{"Edward"}, Status: "Active"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, _ = repo.Get(ctx, id)
+	if s.Business.LifetimeHours != 40 || s.Business.ClassroomID != "classroom-1" || s.Business.Status != "Active" {
+		t.Errorf("updated business = %+v", s.Business)
+	}
+	if err := repo.SetClassroomID(ctx, "student-9999", "classroom-1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("SetClassroomID of a missing student error = %v, want %v", err, ErrNotFound)
+	}
+	if found, err := repo.FindByClassroomID(ctx, "classroom-1"); err != nil || len(found) != 1 || found[0].ID != id {
+		t.Errorf("FindByClassroomID = %v, %v; want %s", found, err, id)
+	}
+}
+
+func TestMemoryRepositorySubcollections(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepository()
+	id, err := repo.Create(ctx, &Student{Personal: Personal{Name: "Ada Lovelace"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hw := HomeworkCompletion{ID: HomeworkDocID("10/01/2024"), Date: "10/01/2024", Duration: "1.5"}
+	if err := repo.UpdateHomework(ctx, id, hw); !errors.Is(err, ErrNotFound) {
+		t.Errorf("UpdateHomework before saving error = %v, want %v", err, ErrNotFound)
+	}
+	if err := repo.SaveHomework(ctx, id, hw); err != nil {
+		t.Fatal(err)
+	}
+	hw.Duration = "2"
+	if err := repo.UpdateHomework(ctx, id, hw); err != nil {
+		t.Errorf("UpdateHomework: %v", err)
+	}
+
+	td := TestDate{ID: TestDateDocID("ACT", "10/26/2024"), TestType: "ACT", TestDate: "10/26/2024"}
+	if td.ID != "ACT 10-26-2024" {
+		t.Errorf("TestDateDocID = %q", td.ID)
+	}
+	if err := repo.CreateTestDate(ctx, id, td); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.CreateTestDate(ctx, id, td); !errors.Is(err, ErrExists) {
+		t.Errorf("second CreateTestDate error = %v, want %v", err, ErrExists)
+	}
+	td.Notes = "Registered"
+	if err := repo.SaveTestDate(ctx, id, td); err != nil {
+		t.Errorf("SaveTestDate over an existing date: %v", err)
+	}
+
+	if err := repo.SaveGoal(ctx, id, Goal{ID: "MIT", College: "MIT"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.SaveGoal(ctx, "student-9999", Goal{ID: "MIT"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("SaveGoal for a missing student error = %v, want %v", err, ErrNotFound)
+	}
+
+	d, err := GetDetail(ctx, repo, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.HomeworkCompletion) != 1 || d.HomeworkCompletion[0].Duration != "2" {
+		t.Errorf("homework = %+v", d.HomeworkCompletion)
+	}
+	if len(d.TestDates) != 1 || d.TestDates[0].Notes != "Registered" {
+		t.Errorf("test dates = %+v", d.TestDates)
+	}
+	if len(d.Goals) != 1 || len(d.TestData) != 0 {
+		t.Errorf("goals = %+v, test data = %+v", d.Goals, d.TestData)
+	}
+	if total := TotalHours(d.HomeworkCompletion); total != 2 {
+		t.Errorf("TotalHours = %v, want 2", total)
+	}
+
+	// Reads of a missing student's subcollections are empty, as in Firestore.
+	if goals, err := repo.ListGoals(ctx, "student-9999"); err != nil || len(goals) != 0 {
+		t.Errorf("ListGoals of a missing student = %v, %v", goals, err)
+	}
+}
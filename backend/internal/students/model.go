@@ -0,0 +1,149 @@
+// backend/internal/students/model.go
+
+package students
+
+// Student is a document in the "students" collection.
+type Student struct {
+	ID       string   `firestore:"-" json:"id"`
+	Personal Personal `firestore:"personal" json:"personal"`
+	Business Business `firestore:"business" json:"business"`
+}
+
+// Personal is the "personal" sub-document of a student.
+type Personal struct {
+	Name           string `firestore:"name" json:"name"`
+	StudentEmail   string `firestore:"student_email" json:"student_email"`
+	StudentNumber  string `firestore:"student_number" json:"student_number"`
+	ParentEmail    string `firestore:"parent_email" json:"parent_email"`
+	ParentNumber   string `firestore:"parent_number" json:"parent_number"`
+	HighSchool     string `firestore:"high_school" json:"high_school"`
+	Grade          string `firestore:"grade" json:"grade"`
+	Accommodations string `firestore:"accommodations" json:"accommodations"`
+	Interests      string `firestore:"interests" json:"interests"`
+}
+
+// TestAppointment is the student's next registered test.
+type TestAppointment struct {
+	RegisteredForTest bool   `firestore:"registered_for_test" json:"registered_for_test"`
+	TestDate          string `firestore:"test_date" json:"test_date"`
+}
+
+// Business is the "business" sub-document of a student.
+type Business struct {
+	FirebaseID       string          `firestore:"firebase_id" json:"firebase_id"`
+	Scheduler        string          `firestore:"scheduler" json:"scheduler"`
+	TestFocus        string          `firestore:"test_focus" json:"test_focus"`
+	TestAppointment  TestAppointment `firestore:"test_appointment" json:"test_appointment"`
+	AssociatedTutors []string        `firestore:"associated_tutors" json:"associated_tutors"`
+	TeamLead         string          `firestore:"team_lead" json:"team_lead"`
+	Status           string          `firestore:"status" json:"status"`
+	Notes            string          `firestore:"notes" json:"notes"`
+	RegisteredTests  string          `firestore:"registered_tests,omitempty" json:"registered_tests,omitempty"`
+	RemainingHours   float64         `firestore:"remaining_hours" json:"remaining_hours"`
+	LifetimeHours    float64         `firestore:"lifetime_hours" json:"lifetime_hours"`
+	ClassroomID      string          `firestore:"classroom_id" json:"classroom_id"`
+	DriveURL         string          `firestore:"drive_url" json:"drive_url"`
+}
+
+// BusinessDetails are the business fields staff edit by hand. Hours are
+// deliberately left out; they are only changed through the hours handlers.
+type BusinessDetails struct {
+	AssociatedTutors []string
+	Scheduler        string
+	Status           string
+	TeamLead         string
+	TestFocus        string
+	TestAppointment  TestAppointment
+	Notes            string
+}
+
+// Details returns the hand-edited business fields.
+func (b Business) Details() BusinessDetails {
+	return BusinessDetails{
+		AssociatedTutors: b.AssociatedTutors,
+		Scheduler:        b.Scheduler,
+		Status:           b.Status,
+		TeamLead:         b.TeamLead,
+		TestFocus:        b.TestFocus,
+		TestAppointment:  b.TestAppointment,
+		Notes:            b.Notes,
+	}
+}
+
+// HomeworkCompletion is a document in a student's "Homework Completion" subcollection.
+// Each document is one tutoring session, keyed by its date with dashes.
+type HomeworkCompletion struct {
+	ID                 string `firestore:"-" json:"id"`
+	Date               string `firestore:"date" json:"date"`
+	Attendance         string `firestore:"attendance" json:"attendance"`
+	Duration           string `firestore:"duration" json:"duration"`
+	Feedback           string `firestore:"feedback" json:"feedback"`
+	PercentageComplete string `firestore:"percentage_complete" json:"percentage_complete"`
+	Engagement         string `firestore:"engagement" json:"engagement"`
+	Tutor              string `firestore:"tutor" json:"tutor"`
+	Timestamp          string `firestore:"timestamp" json:"timestamp"`
+}
+
+// ACTScores are the section scores of an ACT. Missing sections are nil.
+type ACTScores struct {
+	English *float64 `firestore:"English" json:"English"`
+	Math    *float64 `firestore:"Math" json:"Math"`
+	Reading *float64 `firestore:"Reading" json:"Reading"`
+	Science *float64 `firestore:"Science" json:"Science"`
+	Total   *float64 `firestore:"ACT_Total" json:"ACT_Total"`
+}
+
+// Array returns the scores in the order the parent dashboard charts expect:
+// English, Math, Reading, Science, Total.
+func (s *ACTScores) Array() []*float64 {
+	return []*float64{s.English, s.Math, s.Reading, s.Science, s.Total}
+}
+
+// SATScores are the section scores of an SAT or PSAT. Missing sections are nil.
+type SATScores struct {
+	EBRW    *float64 `firestore:"EBRW" json:"EBRW"`
+	Math    *float64 `firestore:"Math" json:"Math"`
+	Reading *float64 `firestore:"Reading" json:"Reading"`
+	Writing *float64 `firestore:"Writing" json:"Writing"`
+	Total   *float64 `firestore:"SAT_Total" json:"SAT_Total"`
+}
+
+// Array returns the scores in the order the parent dashboard charts expect:
+// EBRW, Math, Reading, Writing, Total.
+func (s *SATScores) Array() []*float64 {
+	return []*float64{s.EBRW, s.Math, s.Reading, s.Writing, s.Total}
+}
+
+// TestData is a document in a student's "Test Data" subcollection,
+// keyed "<type> <test> <date with dashes>".
+type TestData struct {
+	ID        string     `firestore:"-" json:"id"`
+	Date      string     `firestore:"date" json:"date"`
+	Test      string     `firestore:"test" json:"test"`
+	Type      string     `firestore:"type" json:"type"`
+	Baseline  bool       `firestore:"baseline" json:"baseline"`
+	UpdatedAt string     `firestore:"updated_at,omitempty" json:"updated_at,omitempty"`
+	ACTScores *ACTScores `firestore:"ACT_Scores" json:"ACT_Scores"`
+	SATScores *SATScores `firestore:"SAT_Scores" json:"SAT_Scores"`
+}
+
+// TestDate is a document in a student's "Test Dates" subcollection,
+// keyed "<test type> <test date with dashes>".
+type TestDate struct {
+	ID                          string `firestore:"-" json:"id"`
+	TestType                    string `firestore:"test_type" json:"test_type"`
+	TestDate                    string `firestore:"test_date" json:"test_date"`
+	RegularRegistrationDeadline string `firestore:"regular_registration_deadline" json:"regular_registration_deadline"`
+	LateRegistrationDeadline    string `firestore:"late_registration_deadline" json:"late_registration_deadline"`
+	ScoreReleaseDate            string `firestore:"score_release_date" json:"score_release_date"`
+	Notes                       string `firestore:"notes" json:"notes"`
+}
+
+// Goal is a document in a student's "Goals" subcollection, keyed by college name.
+// Percentiles are the 25th, 50th and 75th percentile scores.
+type Goal struct {
+	ID             string   `firestore:"-" json:"id"`
+	College        string   `firestore:"College" json:"College"`
+	ACTPercentiles []string `firestore:"ACT_percentiles" json:"ACT_percentiles"`
+	SATPercentiles []string `firestore:"SAT_percentiles" json:"SAT_percentiles"`
+}
@@ -0,0 +1,148 @@
+// backend/internal/students/repository.go
+
+package students
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// ErrNotFound is returned when a student or one of its subcollection documents does not exist.
+var ErrNotFound = errors.New("student not found")
+
+// ErrExists is returned by create methods when the document is already there.
+var ErrExists = errors.New("document already exists")
+
+// ErrBadField is returned when a stored field has a type the model cannot
+// hold. The error names the document and field.
+var ErrBadField = errors.New("field has an unexpected type")
+
+// Repository reads and writes students and their subcollections.
+// NewFirestoreRepository is the production implementation and
+// NewMemoryRepository is an in-memory fake for tests and local tools.
+type Repository interface {
+	Get(ctx context.Context, id string) (*Student, error)
+	List(ctx context.Context) ([]*Student, error)
+	FindByName(ctx context.Context, name string) (*Student, error)
+	FindByParentEmail(ctx context.Context, email string) ([]*Student, error)
+	// FindByClassroomID returns the students whose Google Classroom is classroomID.
+	FindByClassroomID(ctx context.Context, classroomID string) ([]*Student, error)
+	// Create stores a new student under a generated ID and returns that ID.
+	Create(ctx context.Context, s *Student) (string, error)
+	UpdatePersonal(ctx context.Context, id string, p Personal) error
+	UpdateBusinessDetails(ctx context.Context, id string, d BusinessDetails) error
+	SetLifetimeHours(ctx context.Context, id string, hours float64) error
+	SetRemainingHours(ctx context.Context, id string, hours float64) error
+	SetClassroomID(ctx context.Context, id, classroomID string) error
+
+	ListHomework(ctx context.Context, studentID string) ([]HomeworkCompletion, error)
+	// SaveHomework creates or replaces the session document hw.ID.
+	SaveHomework(ctx context.Context, studentID string, hw HomeworkCompletion) error
+	// UpdateHomework replaces an existing session document and returns ErrNotFound if there is none.
+	UpdateHomework(ctx context.Context, studentID string, hw HomeworkCompletion) error
+	DeleteHomework(ctx context.Context, studentID, homeworkID string) error
+
+	ListTestData(ctx context.Context, studentID string) ([]TestData, error)
+	SaveTestData(ctx context.Context, studentID string, td TestData) error
+	DeleteTestData(ctx context.Context, studentID, testDataID string) error
+
+	ListTestDates(ctx context.Context, studentID string) ([]TestDate, error)
+	// CreateTestDate stores td unless a document with the same ID exists, in which case it returns ErrExists.
+	CreateTestDate(ctx context.Context, studentID string, td TestDate) error
+	// SaveTestDate creates or replaces the test date document td.ID.
+	SaveTestDate(ctx context.Context, studentID string, td TestDate) error
+	UpdateTestDateNotes(ctx context.Context, studentID, testDateID, notes string) error
+
+	ListGoals(ctx context.Context, studentID string) ([]Goal, error)
+	SaveGoal(ctx context.Context, studentID string, g Goal) error
+	DeleteGoal(ctx context.Context, studentID, goalID string) error
+}
+
+// Detail is a student together with all of its subcollections.
+type Detail struct {
+	Student
+	HomeworkCompletion []HomeworkCompletion
+	TestData           []TestData
+	TestDates          []TestDate
+	Goals              []Goal
+}
+
+// GetDetail loads a student and its subcollections. A subcollection that
+// fails to load is logged and left empty rather than failing the whole read.
+func GetDetail(ctx context.Context, repo Repository, id string) (*Detail, error) {
+	s, err := repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return LoadDetail(ctx, repo, s), nil
+}
+
+// LoadDetail loads the subcollections of an already fetched student.
+func LoadDetail(ctx context.Context, repo Repository, s *Student) *Detail {
+	d := &Detail{Student: *s}
+
+	var err error
+	if d.HomeworkCompletion, err = repo.ListHomework(ctx, s.ID); err != nil {
+		log.Printf("Error fetching 'Homework Completion' for student %s: %v", s.ID, err)
+		d.HomeworkCompletion = []HomeworkCompletion{}
+	}
+	if d.TestData, err = repo.ListTestData(ctx, s.ID); err != nil {
+		log.Printf("Error fetching 'Test Data' for student %s: %v", s.ID, err)
+		d.TestData = []TestData{}
+	}
+	if d.TestDates, err = repo.ListTestDates(ctx, s.ID); err != nil {
+		log.Printf("Error fetching 'Test Dates' for student %s: %v", s.ID, err)
+		d.TestDates = []TestDate{}
+	}
+	if d.Goals, err = repo.ListGoals(ctx, s.ID); err != nil {
+		log.Printf("Error fetching 'Goals' for student %s: %v", s.ID, err)
+		d.Goals = []Goal{}
+	}
+	return d
+}
+
+// HomeworkDocID is the document ID of a session on the given date ("MM/DD/YYYY" becomes "MM-DD-YYYY").
+func HomeworkDocID(date string) string {
+	return strings.ReplaceAll(date, "/", "-")
+}
+
+// TestDataDocID is the document ID of a test result, e.g. "Official ACT 10-26-2024".
+func TestDataDocID(testType, test, date string) string {
+	return testType + " " + test + " " + strings.ReplaceAll(date, "/", "-")
+}
+
+// TestDateDocID is the document ID of a test date, e.g. "ACT 10-26-2024".
+func TestDateDocID(testType, date string) string {
+	return testType + " " + strings.ReplaceAll(date, "/", "-")
+}
+
+// Hours parses the session duration in hours. ok is false when the duration is missing or not a number.
+func (hw HomeworkCompletion) Hours() (hours float64, ok bool) {
+	if strings.TrimSpace(hw.Duration) == "" {
+		return 0, false
+	}
+	parsed, err := strconv.ParseFloat(strings.TrimSpace(hw.Duration), 64)
+	if err != nil {
+		return 0, false
+	}
+	return parsed, true
+}
+
+// TotalHours sums the durations of the given sessions, skipping the ones that do not parse.
+func TotalHours(sessions []HomeworkCompletion) float64 {
+	var total float64
+	for _, hw := range sessions {
+		hours, ok := hw.Hours()
+		if !ok {
+			if hw.Duration != "" {
+				log.Printf("Skipping session %s: unable to parse duration %q", hw.ID, hw.Duration)
+			}
+			continue
+		}
+		total += hours
+	}
+	return total
+}
@@ -0,0 +1,233 @@
+// backend/internal/tokencrypt/keyring.go
+
+package tokencrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Provider OAuth tokens and the QuickBooks tokens are stored encrypted. Each
+// value gets its own random data key; the value is sealed with the data key
+// and the data key is wrapped with a key-encryption key from the keyring.
+// An encrypted value is a string of the form
+//
+//	enc:v1:<key id>:<wrapped data key>:<sealed value>
+//
+// so it fits in the same Firestore fields the plaintext used to. Values
+// without the prefix are plaintext written before encryption was turned on,
+// and are returned as they are until cmd/importer/encryptTokens rewrites them.
+
+const prefix = "enc:v1:"
+
+var (
+	// ErrUnknownKey is returned for a value wrapped with a key that is not in
+	// the keyring.
+	ErrUnknownKey = errors.New("value was encrypted with an unknown key")
+	// ErrMalformed is returned for a value with the prefix that cannot be parsed.
+	ErrMalformed = errors.New("malformed encrypted value")
+)
+
+// KeyWrapper wraps data keys with a key-encryption key. LocalKey keeps the
+// key in memory; a KMS-backed wrapper can be added without changing callers.
+type KeyWrapper interface {
+	// KeyID names the key. It is stored with every value the key wraps.
+	KeyID() string
+	Wrap(dataKey []byte) ([]byte, error)
+	Unwrap(wrapped []byte) ([]byte, error)
+}
+
+// LocalKey is an AES-256-GCM key held in memory, read from config in
+// production and made up in dev and tests.
+type LocalKey struct {
+	id   string
+	aead cipher.AEAD
+}
+
+// NewLocalKey returns a LocalKey for a 32-byte key.
+func NewLocalKey(id string, key []byte) (*LocalKey, error) {
+	if id == "" || strings.Contains(id, ":") {
+		return nil, fmt.Errorf("invalid key ID %q", id)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("key %s is %d bytes, want 32", id, len(key))
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	return &LocalKey{id: id, aead: aead}, nil
+}
+
+func (k *LocalKey) KeyID() string { return k.id }
+
+func (k *LocalKey) Wrap(dataKey []byte) ([]byte, error) {
+	return seal(k.aead, dataKey, []byte(k.id))
+}
+
+func (k *LocalKey) Unwrap(wrapped []byte) ([]byte, error) {
+	return open(k.aead, wrapped, []byte(k.id))
+}
+
+// Keyring encrypts with its primary key and decrypts with any of its keys.
+// To rotate, make a new key the primary, keep the old one in the keyring
+// until cmd/importer/encryptTokens has rewritten every value, then drop it.
+type Keyring struct {
+	primary KeyWrapper
+	keys    map[string]KeyWrapper
+}
+
+// NewKeyring returns a Keyring that encrypts with primary and can still
+// decrypt values wrapped with older.
+func NewKeyring(primary KeyWrapper, older ...KeyWrapper) *Keyring {
+	k := &Keyring{primary: primary, keys: map[string]KeyWrapper{primary.KeyID(): primary}}
+	for _, w := range older {
+		k.keys[w.KeyID()] = w
+	}
+	return k
+}
+
+// ParseKeyring reads TOKEN_ENCRYPTION_KEYS, a comma-separated list of
+// id:base64-key pairs such as "2025a:<openssl rand -base64 32>". The first
+// key is the primary.
+func ParseKeyring(spec string) (*Keyring, error) {
+	var wrappers []KeyWrapper
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, encoded, ok := strings.Cut(part, ":")
+		if !ok {
+			return nil, fmt.Errorf("TOKEN_ENCRYPTION_KEYS entry %q is not id:key", id)
+		}
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, fmt.Errorf("TOKEN_ENCRYPTION_KEYS key %s is not base64: %w", id, err)
+		}
+		w, err := NewLocalKey(strings.TrimSpace(id), key)
+		if err != nil {
+			return nil, err
+		}
+		wrappers = append(wrappers, w)
+	}
+	if len(wrappers) == 0 {
+		return nil, errors.New("TOKEN_ENCRYPTION_KEYS is not set")
+	}
+	return NewKeyring(wrappers[0], wrappers[1:]...), nil
+}
+
+// Encrypt seals plaintext with a new data key wrapped by the primary key.
+// The empty string stays empty so that "no token" still reads as such.
+func (k *Keyring) Encrypt(plaintext string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", err
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := seal(aead, []byte(plaintext), nil)
+	if err != nil {
+		return "", err
+	}
+	wrapped, err := k.primary.Wrap(dataKey)
+	if err != nil {
+		return "", fmt.Errorf("wrap data key: %w", err)
+	}
+	return prefix + k.primary.KeyID() + ":" +
+		base64.RawStdEncoding.EncodeToString(wrapped) + ":" +
+		base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens a value written by Encrypt. Plaintext values are returned as is.
+func (k *Keyring) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	parts := strings.Split(strings.TrimPrefix(value, prefix), ":")
+	if len(parts) != 3 {
+		return "", ErrMalformed
+	}
+	w, ok := k.keys[parts[0]]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownKey, parts[0])
+	}
+	wrapped, err := base64.RawStdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", ErrMalformed
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", ErrMalformed
+	}
+	dataKey, err := w.Unwrap(wrapped)
+	if err != nil {
+		return "", fmt.Errorf("unwrap data key: %w", err)
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := open(aead, sealed, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// NeedsRewrite reports whether value is plaintext or wrapped with a key other
+// than the primary.
+func (k *Keyring) NeedsRewrite(value string) bool {
+	if value == "" {
+		return false
+	}
+	if !IsEncrypted(value) {
+		return true
+	}
+	id, _, _ := strings.Cut(strings.TrimPrefix(value, prefix), ":")
+	return id != k.primary.KeyID()
+}
+
+// IsEncrypted reports whether value was written by Encrypt.
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal returns the nonce followed by the ciphertext.
+func seal(aead cipher.AEAD, plaintext, additional []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additional), nil
+}
+
+func open(aead cipher.AEAD, sealed, additional []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, ErrMalformed
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, additional)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt: %w", err)
+	}
+	return plaintext, nil
+}
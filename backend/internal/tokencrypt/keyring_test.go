@@ -0,0 +1,183 @@
+// backend/internal/tokencrypt/keyring_test.go
+
+package tokencrypt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// testKey returns a LocalKey whose 32 bytes are all b.
+func testKey(t *testing.T, id string, b byte) *LocalKey {
+	t.Helper()
+	k, err := NewLocalKey(id, bytes.Repeat([]byte{b}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func TestEncryptDecryptRoundTrip(t *testing.T) {
+	ring := NewKeyring(testKey(t, "2025a", 1))
+
+	for _, plaintext := range []string{"ya29.a0AfH6SMBx-refresh", "AB11730000000sdK9", "ünïcödé token", strings.Repeat("x", 4096)} {
+		enc, err := ring.Encrypt(plaintext)
+		if err != nil {
+			t.Fatalf("Encrypt: %v", err)
+		}
+		if !IsEncrypted(enc) || !strings.HasPrefix(enc, "enc:v1:2025a:") || strings.Contains(enc, plaintext) {
+			t.Errorf("Encrypt(%.20q) = %.60q, want an enc:v1:2025a: value without the plaintext", plaintext, enc)
+		}
+		got, err := ring.Decrypt(enc)
+		if err != nil {
+			t.Fatalf("Decrypt: %v", err)
+		}
+		if got != plaintext {
+			t.Errorf("Decrypt = %.20q, want %.20q", got, plaintext)
+		}
+		if ring.NeedsRewrite(enc) {
+			t.Errorf("value wrapped with the primary key needs a rewrite")
+		}
+	}
+
+	// Each value gets its own data key and nonce.
+	a, _ := ring.Encrypt("same token")
+	b, _ := ring.Encrypt("same token")
+	if a == b {
+		t.Error("encrypting the same token twice gave the same value")
+	}
+}
+
+func TestEmptyAndPlaintextValues(t *testing.T) {
+	ring := NewKeyring(testKey(t, "2025a", 1))
+
+	if enc, err := ring.Encrypt(""); enc != "" || err != nil {
+		t.Errorf("Encrypt(\"\") = %q, %v; want \"\"", enc, err)
+	}
+	if ring.NeedsRewrite("") {
+		t.Error("empty value needs a rewrite")
+	}
+
+	// Tokens stored before encryption was turned on read back as they are.
+	legacy := "1//0gLegacyRefreshToken"
+	if got, err := ring.Decrypt(legacy); got != legacy || err != nil {
+		t.Errorf("Decrypt(plaintext) = %q, %v; want %q", got, err, legacy)
+	}
+	if !ring.NeedsRewrite(legacy) {
+		t.Error("plaintext value does not need a rewrite")
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	oldKey := testKey(t, "2024a", 1)
+	newKey := testKey(t, "2025a", 2)
+
+	before := NewKeyring(oldKey)
+	enc, err := before.Encrypt("refresh-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// After rotation the old key is kept for reading.
+	after := NewKeyring(newKey, oldKey)
+	got, err := after.Decrypt(enc)
+	if err != nil || got != "refresh-token" {
+		t.Fatalf("Decrypt with the older key = %q, %v", got, err)
+	}
+	if !after.NeedsRewrite(enc) {
+		t.Fatal("value wrapped with the older key does not need a rewrite")
+	}
+
+	// Rewriting, as encryptTokens does, moves the value to the primary key.
+	rewritten, err := after.Encrypt(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(rewritten, "enc:v1:2025a:") || after.NeedsRewrite(rewritten) {
+		t.Errorf("rewritten value %.30q is not wrapped with the primary key", rewritten)
+	}
+
+	// Once the old key is dropped, only rewritten values can be read.
+	dropped := NewKeyring(newKey)
+	if got, err := dropped.Decrypt(rewritten); err != nil || got != "refresh-token" {
+		t.Errorf("Decrypt of rewritten value = %q, %v", got, err)
+	}
+	if _, err := dropped.Decrypt(enc); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Decrypt with a dropped key error = %v, want %v", err, ErrUnknownKey)
+	}
+}
+
+func TestDecryptTampered(t *testing.T) {
+	ring := NewKeyring(testKey(t, "2025a", 1), testKey(t, "2024a", 2))
+	enc, err := ring.Encrypt("refresh-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(strings.TrimPrefix(enc, prefix), ":")
+
+	// flip changes one byte of a base64 part.
+	flip := func(part string) string {
+		raw, err := base64.RawStdEncoding.DecodeString(part)
+		if err != nil {
+			t.Fatal(err)
+		}
+		raw[len(raw)-1] ^= 0x01
+		return base64.RawStdEncoding.EncodeToString(raw)
+	}
+
+	tests := []struct {
+		name  string
+		value string
+		want  error
+	}{
+		{"sealed value changed", prefix + parts[0] + ":" + parts[1] + ":" + flip(parts[2]), nil},
+		{"wrapped key changed", prefix + parts[0] + ":" + flip(parts[1]) + ":" + parts[2], nil},
+		{"other key named", prefix + "2024a:" + parts[1] + ":" + parts[2], nil},
+		{"unknown key named", prefix + "1999z:" + parts[1] + ":" + parts[2], ErrUnknownKey},
+		{"truncated", prefix + parts[0] + ":" + parts[1], ErrMalformed},
+		{"not base64", prefix + parts[0] + ":" + parts[1] + ":%%%", ErrMalformed},
+		{"sealed value too short", prefix + parts[0] + ":" + parts[1] + ":AAAA", ErrMalformed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ring.Decrypt(tt.value)
+			if err == nil {
+				t.Fatalf("Decrypt = %q, want an error", got)
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("Decrypt error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseKeyring(t *testing.T) {
+	key := func(b byte) string { return base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, 32)) }
+
+	ring, err := ParseKeyring(" 2025a:" + key(2) + " , 2024a:" + key(1))
+	if err != nil {
+		t.Fatalf("ParseKeyring: %v", err)
+	}
+	enc, err := NewKeyring(testKey(t, "2024a", 1)).Encrypt("refresh-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := ring.Decrypt(enc); err != nil || got != "refresh-token" {
+		t.Errorf("Decrypt with the second key = %q, %v", got, err)
+	}
+	if !ring.NeedsRewrite(enc) {
+		t.Error("first key is not the primary")
+	}
+
+	for _, spec := range []string{"", " , ", "2025a", "2025a:not base64", "2025a:" + base64.StdEncoding.EncodeToString([]byte("short"))} {
+		if _, err := ParseKeyring(spec); err == nil {
+			t.Errorf("ParseKeyring(%q) succeeded", spec)
+		}
+	}
+	if _, err := NewLocalKey("a:b", bytes.Repeat([]byte{1}, 32)); err == nil {
+		t.Error("NewLocalKey accepted an ID with a colon")
+	}
+}
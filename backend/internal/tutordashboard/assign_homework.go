@@ -8,6 +8,8 @@ import (
 	"net/http"
 	"time"
 
+	"cloud.google.com/go/firestore"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/students"
 	classroom "google.golang.org/api/classroom/v1"
 	"google.golang.org/api/option"
 )
@@ -33,85 +35,114 @@ type DueDate struct {
 	Day   int
 }
 
-// AssignHomeworkHandler handles incoming requests to assign homework.
-func AssignHomeworkHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	var req HomeworkRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
-		return
-	}
-
-	dueDate, err := parseDate(req.Date)
-	if err != nil {
-		http.Error(w, "Invalid date format", http.StatusBadRequest)
-		return
-	}
-
-	// Initialize the Classroom API client.
-	ctx := context.Background()
-	// Assumes that credentials are provided via the GOOGLE_APPLICATION_CREDENTIALS env variable.
-	svc, err := classroom.NewService(ctx, option.WithScopes(
-		classroom.ClassroomCourseworkStudentsScope,
-		classroom.ClassroomCourseworkMeScope,
-	))
-	if err != nil {
-		log.Printf("Failed to create classroom service: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
-	}
-
-	// Construct the assignment title and description.
-	title := fmt.Sprintf("%s Homework Due %d.%d", capitalizeFirstLetter(req.Section), dueDate.Month, dueDate.Day)
-	descriptionText := buildDescription(req)
-
-	// Build the coursework object.
-	// For materials, we're using a SharedDriveFile.
-	courseWork := &classroom.CourseWork{
-		Title:       title,
-		Description: descriptionText,
-		Materials: []*classroom.Material{
-			{
-				DriveFile: &classroom.SharedDriveFile{
-					DriveFile: &classroom.DriveFile{
-						Id: req.StudentFolderID,
+// AssignHomeworkHandler returns a handler that posts a homework assignment to
+// a Google Classroom. The tutor must be allowed to edit every student whose
+// classroom it is.
+func AssignHomeworkHandler(client *firestore.Client, repo students.Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !requireTutor(w, r) {
+			return
+		}
+
+		var req HomeworkRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+		if req.ClassID == "" {
+			http.Error(w, "Missing required field: class_id", http.StatusBadRequest)
+			return
+		}
+
+		// The classroom is the student's, so check the tutor may edit each
+		// student in it before assigning anything.
+		targets, err := repo.FindByClassroomID(r.Context(), req.ClassID)
+		if err != nil {
+			log.Printf("Failed to find students in classroom %s: %v", req.ClassID, err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if len(targets) == 0 {
+			http.Error(w, "No student has this classroom", http.StatusBadRequest)
+			return
+		}
+		for _, student := range targets {
+			if !authorizeStudent(w, r, client, student.ID) {
+				return
+			}
+		}
+
+		dueDate, err := parseDate(req.Date)
+		if err != nil {
+			http.Error(w, "Invalid date format", http.StatusBadRequest)
+			return
+		}
+
+		// Initialize the Classroom API client.
+		ctx := context.Background()
+		// Assumes that credentials are provided via the GOOGLE_APPLICATION_CREDENTIALS env variable.
+		svc, err := classroom.NewService(ctx, option.WithScopes(
+			classroom.ClassroomCourseworkStudentsScope,
+			classroom.ClassroomCourseworkMeScope,
+		))
+		if err != nil {
+			log.Printf("Failed to create classroom service: %v", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		// Construct the assignment title and description.
+		title := fmt.Sprintf("%s Homework Due %d.%d", capitalizeFirstLetter(req.Section), dueDate.Month, dueDate.Day)
+		descriptionText := buildDescription(req)
+
+		// Build the coursework object.
+		// For materials, we're using a SharedDriveFile.
+		courseWork := &classroom.CourseWork{
+			Title:       title,
+			Description: descriptionText,
+			Materials: []*classroom.Material{
+				{
+					DriveFile: &classroom.SharedDriveFile{
+						DriveFile: &classroom.DriveFile{
+							Id: req.StudentFolderID,
+						},
+						ShareMode: "VIEW",
 					},
-					ShareMode: "VIEW",
 				},
 			},
-		},
-
-		DueDate: &classroom.Date{
-			Year:  int64(dueDate.Year),
-			Month: int64(dueDate.Month),
-			Day:   int64(dueDate.Day),
-		},
-		DueTime: &classroom.TimeOfDay{
-			Hours:   23,
-			Minutes: 59,
-			Seconds: 59,
-		},
-		MaxPoints: 100,
-		WorkType:  "ASSIGNMENT",
-	}
-
-	createdCourseWork, err := svc.Courses.CourseWork.Create(req.ClassID, courseWork).Do()
-	if err != nil {
-		log.Printf("Failed to create coursework: %v", err)
-		http.Error(w, "Failed to create assignment", http.StatusInternalServerError)
-		return
-	}
 
-	response := map[string]interface{}{
-		"status":     "success",
-		"courseWork": createdCourseWork,
+			DueDate: &classroom.Date{
+				Year:  int64(dueDate.Year),
+				Month: int64(dueDate.Month),
+				Day:   int64(dueDate.Day),
+			},
+			DueTime: &classroom.TimeOfDay{
+				Hours:   23,
+				Minutes: 59,
+				Seconds: 59,
+			},
+			MaxPoints: 100,
+			WorkType:  "ASSIGNMENT",
+		}
+
+		createdCourseWork, err := svc.Courses.CourseWork.Create(req.ClassID, courseWork).Do()
+		if err != nil {
+			log.Printf("Failed to create coursework: %v", err)
+			http.Error(w, "Failed to create assignment", http.StatusInternalServerError)
+			return
+		}
+
+		response := map[string]interface{}{
+			"status":     "success",
+			"courseWork": createdCourseWork,
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
 	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
 }
 
 // parseDate converts a "YYYY-MM-DD" string into a DueDate struct.
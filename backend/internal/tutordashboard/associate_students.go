@@ -9,33 +9,32 @@ import (
 	"strings"
 
 	"cloud.google.com/go/firestore"
-	"google.golang.org/api/iterator"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/staff"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/students"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
-// Hardcoded mapping from tutor email to the tutor name as it appears in students' associated_tutors arrays.
-var tutorNameMapping = map[string]string{
-	"edward@leetutoring.com":    "Edward",
-	"eli@leetutoring.com":       "Eli",
-	"ben@leetutoring.com":       "Ben",
-	"nathaniel@leetutoring.com": "Edward",
-}
-
 // AssociateStudentsForTutor iterates through all student documents in the "students" collection.
-// For each student, it looks for the tutor's name (using the mapping) in the business.associated_tutors array.
+// For each student, it looks for the tutor's name (their display name in the staff directory) in the
+// business.associated_tutors array.
 // If a match is found, it extracts the student's firebase_id (from the "business" subdocument) and
 // the student's name (from the "personal" subdocument), then writes a document in the tutor's
 // "Associated Students" subcollection. The document ID is set to the student's firebase_id and
 // the document stores both the student's name and firebase_id.
 // This function now skips writing if the student is already associated.
-func AssociateStudentsForTutor(ctx context.Context, client *firestore.Client, tutorUserID string, tutorEmail string) error {
-	// Determine the expected tutor name using the mapping.
-	tutorName, ok := tutorNameMapping[tutorEmail]
-	if !ok {
-		log.Printf("No mapping found for tutor email: %s", tutorEmail)
+func AssociateStudentsForTutor(ctx context.Context, client *firestore.Client, repo students.Repository, directory *staff.Directory, tutorUserID string, tutorEmail string) error {
+	// Determine the expected tutor name from the staff directory.
+	member, ok, err := directory.Lookup(ctx, tutorEmail)
+	if err != nil {
+		log.Printf("Error looking up staff member %s: %v", tutorEmail, err)
+		return err
+	}
+	if !ok || member.Name == "" {
+		log.Printf("No staff member found for tutor email: %s", tutorEmail)
 		return nil // Alternatively, you could return an error here.
 	}
+	tutorName := member.Name
 	log.Printf("Associating students for tutor: %s (%s)", tutorEmail, tutorName)
 
 	// Reference to the tutor document in the "tutors" collection.
@@ -44,47 +43,25 @@ func AssociateStudentsForTutor(ctx context.Context, client *firestore.Client, tu
 	associatedStudentsColl := tutorDocRef.Collection("Associated Students")
 
 	// Iterate over all student documents in the "students" collection.
-	studentsIter := client.Collection("students").Documents(ctx)
-	defer studentsIter.Stop()
-
-	for {
-		doc, err := studentsIter.Next()
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			log.Printf("Error iterating student docs: %v", err)
-			return err
-		}
-
-		data := doc.Data()
-
-		// Extract the "business" subdocument.
-		business, ok := data["business"].(map[string]interface{})
-		if !ok {
-			continue
-		}
+	all, err := repo.List(ctx)
+	if err != nil {
+		log.Printf("Error iterating student docs: %v", err)
+		return err
+	}
 
+	for _, student := range all {
 		// Get the student's firebase_id from the business subdocument.
-		firebaseID, ok := business["firebase_id"].(string)
-		if !ok || firebaseID == "" {
-			continue
-		}
-
-		// Extract the associated_tutors array from the business subdocument.
-		tutorsArr, ok := business["associated_tutors"].([]interface{})
-		if !ok {
+		firebaseID := student.Business.FirebaseID
+		if firebaseID == "" {
 			continue
 		}
 
-		// Check if the expected tutor name exists in the array (case-insensitive, trimmed).
+		// Check if the expected tutor name exists in the associated_tutors array (case-insensitive, trimmed).
 		matched := false
-		for _, v := range tutorsArr {
-			if nameStr, ok := v.(string); ok {
-				if strings.EqualFold(strings.TrimSpace(nameStr), strings.TrimSpace(tutorName)) {
-					matched = true
-					break
-				}
+		for _, nameStr := range student.Business.AssociatedTutors {
+			if strings.EqualFold(strings.TrimSpace(nameStr), strings.TrimSpace(tutorName)) {
+				matched = true
+				break
 			}
 		}
 		if matched {
@@ -99,11 +76,7 @@ func AssociateStudentsForTutor(ctx context.Context, client *firestore.Client, tu
 				return err
 			}
 
-			// Extract the student's name from the "personal" subdocument.
-			var studentName string
-			if personal, ok := data["personal"].(map[string]interface{}); ok {
-				studentName, _ = personal["name"].(string)
-			}
+			studentName := student.Personal.Name
 			if studentName == "" {
 				studentName = "Unknown"
 			}
@@ -125,19 +98,20 @@ func AssociateStudentsForTutor(ctx context.Context, client *firestore.Client, tu
 	return nil
 }
 
-// AssociateStudentsHandler is an HTTP handler that triggers the student association process for a tutor.
-// For testing purposes, the tutor's user ID and email are retrieved from query parameters.
-// In production, these values should be extracted from the authenticated user's context.
-func AssociateStudentsHandler(client *firestore.Client) http.HandlerFunc {
+// AssociateStudentsHandler is an HTTP handler that triggers the student association process
+// for the authenticated tutor.
+func AssociateStudentsHandler(client *firestore.Client, repo students.Repository, directory *staff.Directory) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		tutorUserID := r.URL.Query().Get("tutorUserID")
-		tutorEmail := r.URL.Query().Get("tutorEmail")
-		if tutorUserID == "" || tutorEmail == "" {
-			http.Error(w, "Missing tutorUserID or tutorEmail", http.StatusBadRequest)
+		tutor, ok := currentTutor(w, r)
+		if !ok {
+			return
+		}
+		if tutor.Email == "" {
+			http.Error(w, "Tutor has no email on file", http.StatusBadRequest)
 			return
 		}
 
-		if err := AssociateStudentsForTutor(r.Context(), client, tutorUserID, tutorEmail); err != nil {
+		if err := AssociateStudentsForTutor(r.Context(), client, repo, directory, tutor.UserID, tutor.Email); err != nil {
 			http.Error(w, "Failed to associate students", http.StatusInternalServerError)
 			return
 		}
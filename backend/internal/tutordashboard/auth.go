@@ -0,0 +1,67 @@
+// backend/internal/tutordashboard/auth.go
+
+package tutordashboard
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"cloud.google.com/go/firestore"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/middleware"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// requireTutor writes a 403 and returns false unless the caller is a tutor or an admin.
+// Routes are already wrapped in middleware.RequireRole in main.go; this keeps the
+// handlers safe if they are ever mounted without it.
+func requireTutor(w http.ResponseWriter, r *http.Request) bool {
+	if !middleware.HasRole(r.Context(), middleware.RoleTutor, middleware.RoleAdmin) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// authorizeStudent writes an error and returns false unless the authenticated tutor may
+// act on the given student. Tutors are limited to the students in their
+// "Associated Students" subcollection; team leads and admins may act on any student.
+func authorizeStudent(w http.ResponseWriter, r *http.Request, client *firestore.Client, firebaseID string) bool {
+	tutor, ok := currentTutor(w, r)
+	if !ok {
+		return false
+	}
+	if tutor.CanOverride() {
+		return true
+	}
+
+	_, err := client.Collection("tutors").Doc(tutor.UserID).
+		Collection("Associated Students").Doc(firebaseID).Get(r.Context())
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			log.Printf("Tutor %s is not associated with student %s", tutor.UserID, firebaseID)
+			http.Error(w, "Unauthorized access to student data", http.StatusForbidden)
+			return false
+		}
+		log.Printf("Error checking association of student %s for tutor %s: %v", firebaseID, tutor.UserID, err)
+		http.Error(w, "Failed to verify student association", http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
+// associatedStudentIDs returns the firebase IDs in the "Associated Students"
+// subcollection of the tutor, for handlers that filter many students at once
+// rather than checking one with authorizeStudent.
+func associatedStudentIDs(ctx context.Context, client *firestore.Client, tutorID string) (map[string]bool, error) {
+	refs, err := client.Collection("tutors").Doc(tutorID).Collection("Associated Students").DocumentRefs(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[string]bool, len(refs))
+	for _, ref := range refs {
+		ids[ref.ID] = true
+	}
+	return ids, nil
+}
@@ -0,0 +1,312 @@
+// backend/internal/tutordashboard/auth_test.go
+
+package tutordashboard
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/ledger"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/middleware"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/staff"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/students"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// These tests read tutors and their "Associated Students" from Firestore,
+// so they only run against the emulator, e.g.
+//
+//	gcloud emulators firestore start --host-port=localhost:8085
+//	FIRESTORE_EMULATOR_HOST=localhost:8085 go test ./internal/tutordashboard/
+
+const testSecret = "test-secret"
+
+type activeSessions struct{}
+
+func (activeSessions) Active(ctx context.Context, sid string) (bool, error) { return true, nil }
+
+// testStaff are the people the tests act as, keyed by name. IDs are made
+// unique per run, since the emulator keeps its data between runs.
+type testStaff map[string]struct{ id, role string }
+
+// emulatorClient returns a client for the Firestore emulator, skipping the
+// test when FIRESTORE_EMULATOR_HOST is not set.
+func emulatorClient(t *testing.T) *firestore.Client {
+	t.Helper()
+	if os.Getenv("FIRESTORE_EMULATOR_HOST") == "" {
+		t.Skip("FIRESTORE_EMULATOR_HOST is not set")
+	}
+	client, err := firestore.NewClient(context.Background(), "lee-tutoring-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// seedStaff writes a tutor document for a tutor associated with student, an
+// unassociated tutor, a team lead and an admin, and returns them.
+func seedStaff(t *testing.T, client *firestore.Client, student string) testStaff {
+	t.Helper()
+	ctx := context.Background()
+	run := fmt.Sprintf("%d", time.Now().UnixNano())
+	staff := testStaff{
+		"associated":   {"tutor-associated-" + run, middleware.RoleTutor},
+		"unassociated": {"tutor-unassociated-" + run, middleware.RoleTutor},
+		"team lead":    {"tutor-lead-" + run, middleware.RoleTutor},
+		"admin":        {"admin-" + run, middleware.RoleAdmin},
+	}
+	for name, s := range staff {
+		tutor := Tutor{UserID: s.id, Email: s.id + "@leetutoring.com"}
+		if name == "team lead" {
+			tutor.StaffRole = middleware.RoleTeamLead
+		}
+		if _, err := client.Collection("tutors").Doc(s.id).Set(ctx, tutor); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err := client.Collection("tutors").Doc(staff["associated"].id).
+		Collection("Associated Students").Doc(student).Set(ctx, map[string]interface{}{"firebase_id": student})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return staff
+}
+
+// serveTutor sends body to h behind the middleware main.go puts on tutor
+// routes, with an access token for userID and role.
+func serveTutor(t *testing.T, client *firestore.Client, h http.Handler, userID, role, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": userID,
+		"role":    role,
+		"sid":     "session-1",
+	}).SignedString([]byte(testSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chain := middleware.AuthMiddleware(testSecret, activeSessions{}, nil)(
+		middleware.RequireRole(middleware.RoleTutor, middleware.RoleAdmin)(
+			ResolveTutor(client)(h)))
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	chain.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAuthorizeStudentDeleteGoal(t *testing.T) {
+	client := emulatorClient(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		want int
+	}{
+		{"associated", http.StatusOK},
+		{"unassociated", http.StatusForbidden},
+		{"team lead", http.StatusOK},
+		{"admin", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := students.NewMemoryRepository()
+			id, err := repo.Create(ctx, &students.Student{Personal: students.Personal{Name: "Ada Lovelace"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := repo.SaveGoal(ctx, id, students.Goal{ID: "MIT", College: "MIT"}); err != nil {
+				t.Fatal(err)
+			}
+			// Memory IDs repeat between runs; tutors are unique to this one.
+			staff := seedStaff(t, client, id)
+
+			s := staff[tt.name]
+			body := `{"firebase_id": "` + id + `", "college": "MIT"}`
+			rec := serveTutor(t, client, DeleteGoalHandler(client, repo), s.id, s.role, body)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body.String())
+			}
+
+			goals, err := repo.ListGoals(ctx, id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if deleted := len(goals) == 0; deleted != (tt.want == http.StatusOK) {
+				t.Errorf("goal deleted = %v after status %d", deleted, rec.Code)
+			}
+		})
+	}
+}
+
+func TestUnassociatedTutorCannotWrite(t *testing.T) {
+	client := emulatorClient(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		handler func(repo students.Repository, directory *staff.Directory) http.Handler
+		// body is the request for the student with ID id.
+		body func(id string) string
+		want int
+	}{
+		{
+			// Associating only picks up students who list the tutor, so it
+			// succeeds without giving the tutor this student.
+			name: "associate students",
+			handler: func(repo students.Repository, directory *staff.Directory) http.Handler {
+				return AssociateStudentsHandler(client, repo, directory)
+			},
+			body: func(string) string { return `{}` },
+			want: http.StatusOK,
+		},
+		{
+			name: "create homework completion",
+			handler: func(repo students.Repository, _ *staff.Directory) http.Handler {
+				return CreateHomeworkCompletionHandler(client, repo, ledger.New(client, repo))
+			},
+			body: func(id string) string {
+				return `{"firebase_id": "` + id + `", "attendance": "On Time", "date": "03/01/2025", "duration": "1", "timestamp": "2025-03-01T17:00:00Z"}`
+			},
+			want: http.StatusForbidden,
+		},
+		{
+			name: "edit homework completion hours",
+			handler: func(repo students.Repository, _ *staff.Directory) http.Handler {
+				return EditHomeworkCompletionHandler(client, repo, ledger.New(client, repo))
+			},
+			body: func(id string) string {
+				return `{"firebase_id": "` + id + `", "attendance": "On Time", "date": "02/01/2025", "duration": "0.25", "timestamp": "2025-02-01T17:00:00Z"}`
+			},
+			want: http.StatusForbidden,
+		},
+		{
+			name: "delete homework completion",
+			handler: func(repo students.Repository, _ *staff.Directory) http.Handler {
+				return DeleteHomeworkCompletionHandler(client, repo, ledger.New(client, repo))
+			},
+			body: func(id string) string { return `{"firebase_id": "` + id + `", "event_id": "02-01-2025"}` },
+			want: http.StatusForbidden,
+		},
+		{
+			name: "assign homework",
+			handler: func(repo students.Repository, _ *staff.Directory) http.Handler {
+				return AssignHomeworkHandler(client, repo)
+			},
+			body: func(id string) string {
+				return `{"class_id": "classroom-` + id + `", "section": "math", "date": "2025-03-08", "work": "1-20"}`
+			},
+			want: http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := students.NewMemoryRepository()
+			id, err := repo.Create(ctx, &students.Student{Personal: students.Personal{Name: "Ada Lovelace"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			details := students.BusinessDetails{AssociatedTutors: []string{"Ann Associated"}}
+			if err := repo.UpdateBusinessDetails(ctx, id, details); err != nil {
+				t.Fatal(err)
+			}
+			if err := repo.SetClassroomID(ctx, id, "classroom-"+id); err != nil {
+				t.Fatal(err)
+			}
+			if err := repo.SetLifetimeHours(ctx, id, 2); err != nil {
+				t.Fatal(err)
+			}
+			session := students.HomeworkCompletion{ID: "02-01-2025", Date: "02/01/2025", Attendance: "On Time", Duration: "2"}
+			if err := repo.SaveHomework(ctx, id, session); err != nil {
+				t.Fatal(err)
+			}
+			s := seedStaff(t, client, id)["unassociated"]
+
+			members := staff.NewMemoryStore()
+			email := s.id + "@leetutoring.com"
+			member := &staff.Member{Email: email, Emails: []string{email}, Name: "Uma Unassociated", Role: s.role, Active: true}
+			if err := members.Create(ctx, member); err != nil {
+				t.Fatal(err)
+			}
+			directory := staff.NewDirectory(members, client, nil, nil)
+
+			rec := serveTutor(t, client, tt.handler(repo, directory), s.id, s.role, tt.body(id))
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body.String())
+			}
+
+			homework, err := repo.ListHomework(ctx, id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(homework) != 1 || homework[0] != session {
+				t.Errorf("homework = %+v, want only %+v", homework, session)
+			}
+			if got, _ := repo.Get(ctx, id); got.Business.LifetimeHours != 2 {
+				t.Errorf("lifetime hours = %v, want 2", got.Business.LifetimeHours)
+			}
+			snap, err := client.Collection("tutors").Doc(s.id).Collection("Associated Students").Doc(id).Get(ctx)
+			if err == nil && snap.Exists() {
+				t.Error("tutor was associated with the student")
+			}
+		})
+	}
+}
+
+func TestFetchStudentsByNamesOnlyAssociated(t *testing.T) {
+	client := emulatorClient(t)
+	ctx := context.Background()
+
+	repo := students.NewMemoryRepository()
+	var ids []string
+	for _, name := range []string{"Ada Lovelace", "Alan Turing"} {
+		id, err := repo.Create(ctx, &students.Student{Personal: students.Personal{Name: name}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	staff := seedStaff(t, client, ids[0])
+	app := &App{FirestoreClient: client, Students: repo}
+
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"associated", ids[:1]},
+		{"unassociated", nil},
+		{"team lead", ids},
+		{"admin", ids},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := staff[tt.name]
+			body := `{"names": ["Ada Lovelace", "Alan Turing"]}`
+			rec := serveTutor(t, client, http.HandlerFunc(app.FetchStudentsByNamesHandler), s.id, s.role, body)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+			}
+
+			var got []StudentDetailResponse
+			if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			var gotIDs []string
+			for _, d := range got {
+				gotIDs = append(gotIDs, d.ID)
+			}
+			if fmt.Sprint(gotIDs) != fmt.Sprint(tt.want) {
+				t.Errorf("students = %v, want %v", gotIDs, tt.want)
+			}
+		})
+	}
+}
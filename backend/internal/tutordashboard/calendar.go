@@ -24,6 +24,7 @@ type Tutor struct {
 	Name         string    `firestore:"name"`
 	Picture      string    `firestore:"picture"`
 	CalendarID   string    `firestore:"calendar_id,omitempty"`
+	StaffRole    string    `firestore:"staff_role,omitempty"`
 }
 
 // getTutor retrieves the tutor document from the "tutors" collection using the userID.
@@ -48,30 +49,34 @@ func getTutorCalendarID(tutor *Tutor) string {
 	return "primary"
 }
 
-// CalendarEventsHandler handles HTTP requests to fetch today's calendar events.
-// It is now a method on the App struct.
+// CalendarEventsHandler handles HTTP requests to fetch today's calendar events
+// for the authenticated tutor.
 func (app *App) CalendarEventsHandler(w http.ResponseWriter, r *http.Request) {
-	// Assume the tutor's userID is passed as a query parameter.
-	userID := r.URL.Query().Get("user_id")
-	if userID == "" {
-		http.Error(w, "Missing user_id parameter", http.StatusBadRequest)
+	principal, ok := currentTutor(w, r)
+	if !ok {
 		return
 	}
+	tutor := principal.Tutor
 
 	ctx := r.Context()
 
-	// Fetch tutor credentials from Firestore using app.FirestoreClient.
-	tutor, err := getTutor(ctx, app.FirestoreClient, userID)
+	// Construct an OAuth2 token from the Firestore credentials, which are
+	// stored encrypted.
+	accessToken, err := app.Tokens.Decrypt(tutor.AccessToken)
 	if err != nil {
-		log.Printf("Error fetching tutor data: %v", err)
-		http.Error(w, "Tutor not found", http.StatusNotFound)
+		log.Printf("Error decrypting access token of tutor %s: %v", tutor.UserID, err)
+		http.Error(w, "Failed to read calendar credentials", http.StatusInternalServerError)
+		return
+	}
+	refreshToken, err := app.Tokens.Decrypt(tutor.RefreshToken)
+	if err != nil {
+		log.Printf("Error decrypting refresh token of tutor %s: %v", tutor.UserID, err)
+		http.Error(w, "Failed to read calendar credentials", http.StatusInternalServerError)
 		return
 	}
-
-	// Construct an OAuth2 token from the Firestore credentials.
 	token := &oauth2.Token{
-		AccessToken:  tutor.AccessToken,
-		RefreshToken: tutor.RefreshToken,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
 		Expiry:       tutor.Expiry,
 	}
 
@@ -6,6 +6,7 @@ import (
 	"net/http"
 
 	"cloud.google.com/go/firestore"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/students"
 )
 
 // CreateGoalRequest defines the expected payload for creating a new goal.
@@ -20,7 +21,7 @@ type CreateGoalRequest struct {
 // It finds the student's document in the "students" collection using FirebaseID, then creates a document in
 // the subcollection "Goals" (with the document ID equal to the college name) containing the fields:
 // "College" (string), "SAT_percentiles" (array), and "ACT_percentiles" (array).
-func CreateGoalHandler(client *firestore.Client) http.HandlerFunc {
+func CreateGoalHandler(client *firestore.Client, repo students.Repository) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Handle preflight OPTIONS request.
 		if r.Method == http.MethodOptions {
@@ -28,6 +29,10 @@ func CreateGoalHandler(client *firestore.Client) http.HandlerFunc {
 			return
 		}
 
+		if !requireTutor(w, r) {
+			return
+		}
+
 		ctx := r.Context()
 
 		var req CreateGoalRequest
@@ -41,18 +46,20 @@ func CreateGoalHandler(client *firestore.Client) http.HandlerFunc {
 			http.Error(w, "Missing required fields", http.StatusBadRequest)
 			return
 		}
+		if !authorizeStudent(w, r, client, req.FirebaseID) {
+			return
+		}
 
 		// Build the data for the new goal document.
-		goalData := map[string]interface{}{
-			"College":         req.College,
-			"SAT_percentiles": []string{req.SatPercentiles["p25"], req.SatPercentiles["p50"], req.SatPercentiles["p75"]},
-			"ACT_percentiles": []string{req.ActPercentiles["p25"], req.ActPercentiles["p50"], req.ActPercentiles["p75"]},
+		goal := students.Goal{
+			ID:             req.College,
+			College:        req.College,
+			SATPercentiles: []string{req.SatPercentiles["p25"], req.SatPercentiles["p50"], req.SatPercentiles["p75"]},
+			ACTPercentiles: []string{req.ActPercentiles["p25"], req.ActPercentiles["p50"], req.ActPercentiles["p75"]},
 		}
 
 		// Write the new goal document in the student's subcollection "Goals" (using the college name as the document ID).
-		_, err := client.Collection("students").Doc(req.FirebaseID).
-			Collection("Goals").Doc(req.College).Set(ctx, goalData)
-		if err != nil {
+		if err := repo.SaveGoal(ctx, req.FirebaseID, goal); err != nil {
 			http.Error(w, "Failed to create new goal: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
@@ -3,10 +3,12 @@ package tutordashboard
 
 import (
 	"encoding/json"
+	"log"
 	"net/http"
-	"strings"
 
 	"cloud.google.com/go/firestore"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/ledger"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/students"
 )
 
 // CreateHomeworkCompletionRequest defines the expected payload for creating a new homework completion entry.
@@ -23,7 +25,7 @@ type CreateHomeworkCompletionRequest struct {
 }
 
 // CreateHomeworkCompletionHandler returns an HTTP handler function that processes a new homework completion creation request.
-func CreateHomeworkCompletionHandler(client *firestore.Client) http.HandlerFunc {
+func CreateHomeworkCompletionHandler(client *firestore.Client, repo students.Repository, hours *ledger.Ledger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Handle preflight OPTIONS request.
 		if r.Method == http.MethodOptions {
@@ -31,6 +33,10 @@ func CreateHomeworkCompletionHandler(client *firestore.Client) http.HandlerFunc
 			return
 		}
 
+		if !requireTutor(w, r) {
+			return
+		}
+
 		ctx := r.Context()
 
 		var req CreateHomeworkCompletionRequest
@@ -44,30 +50,42 @@ func CreateHomeworkCompletionHandler(client *firestore.Client) http.HandlerFunc
 			http.Error(w, "Missing required fields", http.StatusBadRequest)
 			return
 		}
+		if !authorizeStudent(w, r, client, req.FirebaseID) {
+			return
+		}
 
-		// Build the document ID by replacing slashes with dashes in the date.
-		docID := strings.ReplaceAll(req.Date, "/", "-")
+		// Build the homework completion object. The document ID is the date with dashes.
+		homework := students.HomeworkCompletion{
+			ID:                 students.HomeworkDocID(req.Date),
+			Attendance:         req.Attendance,
+			Date:               req.Date, // Stored with slashes.
+			Duration:           req.Duration,
+			Feedback:           req.Feedback,
+			PercentageComplete: req.PercentageComplete,
+			Engagement:         req.Engagement,
+			Tutor:              req.Tutor,
+			Timestamp:          req.Timestamp,
+		}
 
-		// Build the homework completion object.
-		homeworkData := map[string]interface{}{
-			"attendance":          req.Attendance,
-			"date":                req.Date, // Stored with slashes.
-			"duration":            req.Duration,
-			"feedback":            req.Feedback,
-			"percentage_complete": req.PercentageComplete,
-			"engagement":          req.Engagement,
-			"tutor":               req.Tutor,
-			"timestamp":           req.Timestamp,
+		sessionHours, ok := homework.Hours()
+		if !ok {
+			http.Error(w, "Duration must be a number of hours", http.StatusBadRequest)
+			return
 		}
 
 		// Write the new homework completion document in the "Homework Completion" subcollection.
-		_, err := client.Collection("students").Doc(req.FirebaseID).
-			Collection("Homework Completion").Doc(docID).Set(ctx, homeworkData)
-		if err != nil {
+		if err := repo.SaveHomework(ctx, req.FirebaseID, homework); err != nil {
 			http.Error(w, "Failed to create homework completion: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
 
+		// Debit the session from the family's hours.
+		if err := recordSession(ctx, hours, repo, req.FirebaseID, homework.ID, sessionHours); err != nil {
+			log.Printf("Error recording hours of new session: %v", err)
+			http.Error(w, "Homework completion saved, but its hours were not recorded; please try again", http.StatusInternalServerError)
+			return
+		}
+
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("Homework completion created successfully"))
 	}
@@ -4,9 +4,9 @@ package tutordashboard
 import (
 	"encoding/json"
 	"net/http"
-	"strings"
 
 	"cloud.google.com/go/firestore"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/students"
 )
 
 // CreateTestDataRequest defines the expected payload for creating a new test data entry.
@@ -21,7 +21,7 @@ type CreateTestDataRequest struct {
 }
 
 // CreateTestDataHandler returns an HTTP handler function that processes a new test data creation request.
-func CreateTestDataHandler(client *firestore.Client) http.HandlerFunc {
+func CreateTestDataHandler(client *firestore.Client, repo students.Repository) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Handle preflight OPTIONS request.
 		if r.Method == http.MethodOptions {
@@ -29,6 +29,10 @@ func CreateTestDataHandler(client *firestore.Client) http.HandlerFunc {
 			return
 		}
 
+		if !requireTutor(w, r) {
+			return
+		}
+
 		ctx := r.Context()
 
 		var req CreateTestDataRequest
@@ -42,24 +46,23 @@ func CreateTestDataHandler(client *firestore.Client) http.HandlerFunc {
 			http.Error(w, "Missing required fields", http.StatusBadRequest)
 			return
 		}
+		if !authorizeStudent(w, r, client, req.FirebaseID) {
+			return
+		}
 
-		// Build the document ID.
-		// Convert the date string from "MM/DD/YYYY" to "MM-DD-YYYY" for the document ID.
-		docID := req.Type + " " + req.Test + " " + strings.ReplaceAll(req.Date, "/", "-")
-
-		// Build the test data object.
-		testData := map[string]interface{}{
-			"date":       req.Date,      // Stored with slashes.
-			"baseline":   req.Baseline,
-			"test":       req.Test,
-			"type":       req.Type,
-			"ACT_Scores": req.ACT_Scores,
-			"SAT_Scores": req.SAT_Scores,
+		// Build the test data object. The document ID is "<type> <test> <date with dashes>".
+		testData := students.TestData{
+			ID:        students.TestDataDocID(req.Type, req.Test, req.Date),
+			Date:      req.Date, // Stored with slashes.
+			Baseline:  req.Baseline,
+			Test:      req.Test,
+			Type:      req.Type,
+			ACTScores: actScoresFromRequest(req.ACT_Scores),
+			SATScores: satScoresFromRequest(req.SAT_Scores),
 		}
 
 		// Write the new test data document in the "Test Data" subcollection.
-		_, err := client.Collection("students").Doc(req.FirebaseID).
-			Collection("Test Data").Doc(docID).Set(ctx, testData)
+		err := repo.SaveTestData(ctx, req.FirebaseID, testData)
 		if err != nil {
 			http.Error(w, "Failed to create test data: "+err.Error(), http.StatusInternalServerError)
 			return
@@ -69,3 +72,41 @@ func CreateTestDataHandler(client *firestore.Client) http.HandlerFunc {
 		w.Write([]byte("Test data created successfully"))
 	}
 }
+
+// actScoresFromRequest converts the ACT_Scores payload into the typed scores.
+// Sections that were not sent stay nil.
+func actScoresFromRequest(m map[string]float64) *students.ACTScores {
+	if m == nil {
+		return nil
+	}
+	return &students.ACTScores{
+		English: scoreFromRequest(m, "English"),
+		Math:    scoreFromRequest(m, "Math"),
+		Reading: scoreFromRequest(m, "Reading"),
+		Science: scoreFromRequest(m, "Science"),
+		Total:   scoreFromRequest(m, "ACT_Total"),
+	}
+}
+
+// satScoresFromRequest converts the SAT_Scores payload into the typed scores.
+// Sections that were not sent stay nil.
+func satScoresFromRequest(m map[string]float64) *students.SATScores {
+	if m == nil {
+		return nil
+	}
+	return &students.SATScores{
+		EBRW:    scoreFromRequest(m, "EBRW"),
+		Math:    scoreFromRequest(m, "Math"),
+		Reading: scoreFromRequest(m, "Reading"),
+		Writing: scoreFromRequest(m, "Writing"),
+		Total:   scoreFromRequest(m, "SAT_Total"),
+	}
+}
+
+func scoreFromRequest(m map[string]float64, key string) *float64 {
+	v, ok := m[key]
+	if !ok {
+		return nil
+	}
+	return &v
+}
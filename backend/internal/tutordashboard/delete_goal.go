@@ -6,6 +6,7 @@ import (
 	"net/http"
 
 	"cloud.google.com/go/firestore"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/students"
 )
 
 // DeleteGoalRequest defines the expected payload for deleting a goal.
@@ -17,7 +18,7 @@ type DeleteGoalRequest struct {
 // DeleteGoalHandler returns an HTTP handler function that deletes a goal.
 // It finds the student document in the "students" collection by the given firebase_id,
 // then deletes the document (with document ID equal to the college name) from the subcollection "Goals".
-func DeleteGoalHandler(client *firestore.Client) http.HandlerFunc {
+func DeleteGoalHandler(client *firestore.Client, repo students.Repository) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Handle preflight OPTIONS request.
 		if r.Method == http.MethodOptions {
@@ -25,6 +26,10 @@ func DeleteGoalHandler(client *firestore.Client) http.HandlerFunc {
 			return
 		}
 
+		if !requireTutor(w, r) {
+			return
+		}
+
 		ctx := r.Context()
 
 		var req DeleteGoalRequest
@@ -38,11 +43,12 @@ func DeleteGoalHandler(client *firestore.Client) http.HandlerFunc {
 			http.Error(w, "Missing required fields", http.StatusBadRequest)
 			return
 		}
+		if !authorizeStudent(w, r, client, req.FirebaseID) {
+			return
+		}
 
 		// Delete the goal document from the "Goals" subcollection.
-		_, err := client.Collection("students").Doc(req.FirebaseID).
-			Collection("Goals").Doc(req.College).Delete(ctx)
-		if err != nil {
+		if err := repo.DeleteGoal(ctx, req.FirebaseID, req.College); err != nil {
 			http.Error(w, "Failed to delete goal: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
@@ -3,9 +3,12 @@ package tutordashboard
 
 import (
 	"encoding/json"
+	"log"
 	"net/http"
 
 	"cloud.google.com/go/firestore"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/ledger"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/students"
 )
 
 // DeleteEventRequest defines the payload required to delete an event.
@@ -15,7 +18,7 @@ type DeleteEventRequest struct {
 }
 
 // DeleteEventHandler returns an HTTP handler function that deletes a given event.
-func DeleteHomeworkCompletionHandler(client *firestore.Client) http.HandlerFunc {
+func DeleteHomeworkCompletionHandler(client *firestore.Client, repo students.Repository, hours *ledger.Ledger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Handle preflight OPTIONS request.
 		if r.Method == http.MethodOptions {
@@ -23,6 +26,10 @@ func DeleteHomeworkCompletionHandler(client *firestore.Client) http.HandlerFunc
 			return
 		}
 
+		if !requireTutor(w, r) {
+			return
+		}
+
 		var req DeleteEventRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			http.Error(w, "Invalid request payload", http.StatusBadRequest)
@@ -34,16 +41,24 @@ func DeleteHomeworkCompletionHandler(client *firestore.Client) http.HandlerFunc
 			http.Error(w, "Missing required fields", http.StatusBadRequest)
 			return
 		}
+		if !authorizeStudent(w, r, client, req.FirebaseID) {
+			return
+		}
 
 		ctx := r.Context()
 		// Delete the event document from the "Events" subcollection.
-		_, err := client.Collection("students").Doc(req.FirebaseID).
-			Collection("Homework Completion").Doc(req.EventID).Delete(ctx)
-		if err != nil {
+		if err := repo.DeleteHomework(ctx, req.FirebaseID, req.EventID); err != nil {
 			http.Error(w, "Failed to delete event: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
 
+		// Give the session's hours back to the family.
+		if err := recordSession(ctx, hours, repo, req.FirebaseID, req.EventID, 0); err != nil {
+			log.Printf("Error returning hours of deleted session: %v", err)
+			http.Error(w, "Event deleted, but its hours were not returned; please try again", http.StatusInternalServerError)
+			return
+		}
+
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("Event deleted successfully"))
 	}
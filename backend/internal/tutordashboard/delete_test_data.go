@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"cloud.google.com/go/firestore"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/students"
 )
 
 // DeleteTestDataRequest defines the expected payload for deleting a test data entry.
@@ -14,7 +15,7 @@ type DeleteTestDataRequest struct {
 }
 
 // DeleteTestDataHandler returns an HTTP handler function that processes a delete test data request.
-func DeleteTestDataHandler(client *firestore.Client) http.HandlerFunc {
+func DeleteTestDataHandler(client *firestore.Client, repo students.Repository) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Handle preflight OPTIONS request.
 		if r.Method == http.MethodOptions {
@@ -22,6 +23,10 @@ func DeleteTestDataHandler(client *firestore.Client) http.HandlerFunc {
 			return
 		}
 
+		if !requireTutor(w, r) {
+			return
+		}
+
 		ctx := r.Context()
 
 		var req DeleteTestDataRequest
@@ -35,11 +40,12 @@ func DeleteTestDataHandler(client *firestore.Client) http.HandlerFunc {
 			http.Error(w, "Missing required fields", http.StatusBadRequest)
 			return
 		}
+		if !authorizeStudent(w, r, client, req.FirebaseID) {
+			return
+		}
 
 		// Delete the test data document from the "Test Data" subcollection.
-		_, err := client.Collection("students").Doc(req.FirebaseID).
-			Collection("Test Data").Doc(req.TestDataID).Delete(ctx)
-		if err != nil {
+		if err := repo.DeleteTestData(ctx, req.FirebaseID, req.TestDataID); err != nil {
 			http.Error(w, "Failed to delete test data: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 
 	"cloud.google.com/go/firestore"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/students"
 )
 
 // EditBusinessDetailsRequest defines the expected payload for editing a student's business details.
@@ -17,15 +18,12 @@ type EditBusinessDetailsRequest struct {
 	TeamLead         string   `json:"team_lead"`         // e.g., "Edward", "Eli", "Ben", "Kieran", "Kyra", "Patrick".
 	TestFocus        string   `json:"test_focus"`        // e.g., "ACT" or "SAT".
 	// TestAppointment holds details about test registration.
-	TestAppointment struct {
-		RegisteredForTest bool   `json:"registered_for_test"` // true if registered for test.
-		TestDate          string `json:"test_date"`           // Test date in "YYYY-MM-DD" format.
-	} `json:"test_appointment"`
-	Notes string `json:"notes"` // Additional notes.
+	TestAppointment students.TestAppointment `json:"test_appointment"`
+	Notes           string                   `json:"notes"` // Additional notes.
 }
 
 // EditBusinessDetailsHandler returns an HTTP handler function that processes an edit business details request.
-func EditBusinessDetailsHandler(client *firestore.Client) http.HandlerFunc {
+func EditBusinessDetailsHandler(client *firestore.Client, repo students.Repository) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Handle preflight OPTIONS request.
 		if r.Method == http.MethodOptions {
@@ -33,6 +31,10 @@ func EditBusinessDetailsHandler(client *firestore.Client) http.HandlerFunc {
 			return
 		}
 
+		if !requireTutor(w, r) {
+			return
+		}
+
 		ctx := r.Context()
 
 		var req EditBusinessDetailsRequest
@@ -46,21 +48,23 @@ func EditBusinessDetailsHandler(client *firestore.Client) http.HandlerFunc {
 			http.Error(w, "Missing required field: firebase_id", http.StatusBadRequest)
 			return
 		}
+		if !authorizeStudent(w, r, client, req.FirebaseID) {
+			return
+		}
 
-		// Build the update data. Note: Using field paths to update the nested "business" subdocument.
-		updates := []firestore.Update{
-			{Path: "business.associated_tutors", Value: req.AssociatedTutors},
-			{Path: "business.scheduler", Value: req.Scheduler},
-			{Path: "business.status", Value: req.Status},
-			{Path: "business.team_lead", Value: req.TeamLead},
-			{Path: "business.test_focus", Value: req.TestFocus},
-			{Path: "business.test_appointment", Value: req.TestAppointment},
-			{Path: "business.notes", Value: req.Notes},
+		// Build the hand-edited business fields. Hours are not touched here.
+		details := students.BusinessDetails{
+			AssociatedTutors: req.AssociatedTutors,
+			Scheduler:        req.Scheduler,
+			Status:           req.Status,
+			TeamLead:         req.TeamLead,
+			TestFocus:        req.TestFocus,
+			TestAppointment:  req.TestAppointment,
+			Notes:            req.Notes,
 		}
 
 		// Update the student's document in the "students" collection.
-		_, err := client.Collection("students").Doc(req.FirebaseID).Update(ctx, updates)
-		if err != nil {
+		if err := repo.UpdateBusinessDetails(ctx, req.FirebaseID, details); err != nil {
 			http.Error(w, "Failed to update business details: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
@@ -3,10 +3,12 @@ package tutordashboard
 
 import (
 	"encoding/json"
+	"log"
 	"net/http"
-	"strings"
 
 	"cloud.google.com/go/firestore"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/ledger"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/students"
 )
 
 // EditHomeworkCompletionRequest defines the expected payload for editing a homework completion entry.
@@ -23,7 +25,7 @@ type EditHomeworkCompletionRequest struct {
 }
 
 // EditHomeworkCompletionHandler returns an HTTP handler function that processes an edit homework completion request.
-func EditHomeworkCompletionHandler(client *firestore.Client) http.HandlerFunc {
+func EditHomeworkCompletionHandler(client *firestore.Client, repo students.Repository, hours *ledger.Ledger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Handle preflight OPTIONS request.
 		if r.Method == http.MethodOptions {
@@ -31,6 +33,10 @@ func EditHomeworkCompletionHandler(client *firestore.Client) http.HandlerFunc {
 			return
 		}
 
+		if !requireTutor(w, r) {
+			return
+		}
+
 		ctx := r.Context()
 
 		var req EditHomeworkCompletionRequest
@@ -44,30 +50,45 @@ func EditHomeworkCompletionHandler(client *firestore.Client) http.HandlerFunc {
 			http.Error(w, "Missing required fields", http.StatusBadRequest)
 			return
 		}
+		if !authorizeStudent(w, r, client, req.FirebaseID) {
+			return
+		}
 
-		// Build the document ID by replacing slashes with dashes in the date.
-		docID := strings.ReplaceAll(req.Date, "/", "-")
+		// Build the updated session. The document ID is the date with dashes.
+		homework := students.HomeworkCompletion{
+			ID:                 students.HomeworkDocID(req.Date),
+			Attendance:         req.Attendance,
+			Date:               req.Date,
+			Duration:           req.Duration,
+			Feedback:           req.Feedback,
+			PercentageComplete: req.PercentageComplete,
+			Engagement:         req.Engagement,
+			Tutor:              req.Tutor,
+			Timestamp:          req.Timestamp,
+		}
 
-		// Build the update data.
-		updates := []firestore.Update{
-			{Path: "attendance", Value: req.Attendance},
-			{Path: "date", Value: req.Date},
-			{Path: "duration", Value: req.Duration},
-			{Path: "feedback", Value: req.Feedback},
-			{Path: "percentage_complete", Value: req.PercentageComplete},
-			{Path: "engagement", Value: req.Engagement},
-			{Path: "tutor", Value: req.Tutor},
-			{Path: "timestamp", Value: req.Timestamp},
+		sessionHours, ok := homework.Hours()
+		if !ok && homework.Duration != "" {
+			http.Error(w, "Duration must be a number of hours", http.StatusBadRequest)
+			return
 		}
 
 		// Update the homework completion document in the "Homework Completion" subcollection.
-		_, err := client.Collection("students").Doc(req.FirebaseID).
-			Collection("Homework Completion").Doc(docID).Update(ctx, updates)
-		if err != nil {
+		if err := repo.UpdateHomework(ctx, req.FirebaseID, homework); err != nil {
 			http.Error(w, "Failed to update homework completion: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
 
+		// Move the family's hours by the difference. A session without a duration is
+		// left as it is in the ledger and shows up in the reconciliation report.
+		if ok {
+			if err := recordSession(ctx, hours, repo, req.FirebaseID, homework.ID, sessionHours); err != nil {
+				log.Printf("Error recording hours of edited session: %v", err)
+				http.Error(w, "Homework completion updated, but its hours were not recorded; please try again", http.StatusInternalServerError)
+				return
+			}
+		}
+
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("Homework completion updated successfully"))
 	}
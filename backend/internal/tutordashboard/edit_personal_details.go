@@ -6,6 +6,7 @@ import (
 	"net/http"
 
 	"cloud.google.com/go/firestore"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/students"
 )
 
 // EditPersonalDetailsRequest defines the expected payload for editing a student's personal details.
@@ -23,7 +24,7 @@ type EditPersonalDetailsRequest struct {
 }
 
 // EditPersonalDetailsHandler returns an HTTP handler function that processes an edit personal details request.
-func EditPersonalDetailsHandler(client *firestore.Client) http.HandlerFunc {
+func EditPersonalDetailsHandler(client *firestore.Client, repo students.Repository) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Handle preflight OPTIONS request.
 		if r.Method == http.MethodOptions {
@@ -31,6 +32,10 @@ func EditPersonalDetailsHandler(client *firestore.Client) http.HandlerFunc {
 			return
 		}
 
+		if !requireTutor(w, r) {
+			return
+		}
+
 		ctx := r.Context()
 
 		var req EditPersonalDetailsRequest
@@ -44,23 +49,25 @@ func EditPersonalDetailsHandler(client *firestore.Client) http.HandlerFunc {
 			http.Error(w, "Missing required field: firebase_id", http.StatusBadRequest)
 			return
 		}
+		if !authorizeStudent(w, r, client, req.FirebaseID) {
+			return
+		}
 
-		// Build the update data using field paths to update the nested "personal" subdocument.
-		updates := []firestore.Update{
-			{Path: "personal.name", Value: req.Name},
-			{Path: "personal.accommodations", Value: req.Accommodations},
-			{Path: "personal.grade", Value: req.Grade},
-			{Path: "personal.high_school", Value: req.HighSchool},
-			{Path: "personal.parent_email", Value: req.ParentEmail},
-			{Path: "personal.student_email", Value: req.StudentEmail},
-			{Path: "personal.interests", Value: req.Interests},
-			{Path: "personal.parent_number", Value: req.ParentNumber},
-			{Path: "personal.student_number", Value: req.StudentNumber},
+		// Build the new "personal" subdocument.
+		personal := students.Personal{
+			Name:           req.Name,
+			Accommodations: req.Accommodations,
+			Grade:          req.Grade,
+			HighSchool:     req.HighSchool,
+			ParentEmail:    req.ParentEmail,
+			StudentEmail:   req.StudentEmail,
+			Interests:      req.Interests,
+			ParentNumber:   req.ParentNumber,
+			StudentNumber:  req.StudentNumber,
 		}
 
 		// Update the student's document in the "students" collection.
-		_, err := client.Collection("students").Doc(req.FirebaseID).Update(ctx, updates)
-		if err != nil {
+		if err := repo.UpdatePersonal(ctx, req.FirebaseID, personal); err != nil {
 			http.Error(w, "Failed to update personal details: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
@@ -4,9 +4,9 @@ package tutordashboard
 import (
 	"encoding/json"
 	"net/http"
-	"strings"
 
 	"cloud.google.com/go/firestore"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/students"
 )
 
 // EditTestDataRequest defines the expected payload for editing a test data entry.
@@ -21,7 +21,7 @@ type EditTestDataRequest struct {
 }
 
 // EditTestDataHandler returns an HTTP handler function that processes an edit request.
-func EditTestDataHandler(client *firestore.Client) http.HandlerFunc {
+func EditTestDataHandler(client *firestore.Client, repo students.Repository) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Handle preflight OPTIONS request.
 		if r.Method == http.MethodOptions {
@@ -29,6 +29,10 @@ func EditTestDataHandler(client *firestore.Client) http.HandlerFunc {
 			return
 		}
 
+		if !requireTutor(w, r) {
+			return
+		}
+
 		ctx := r.Context()
 
 		var req EditTestDataRequest
@@ -42,24 +46,23 @@ func EditTestDataHandler(client *firestore.Client) http.HandlerFunc {
 			http.Error(w, "Missing required fields", http.StatusBadRequest)
 			return
 		}
+		if !authorizeStudent(w, r, client, req.FirebaseID) {
+			return
+		}
 
-		// Build the document ID the same way as for creation.
-		docID := req.Type + " " + req.Test + " " + strings.ReplaceAll(req.Date, "/", "-")
-
-		// Build the test data object.
-		testData := map[string]interface{}{
-			"date":       req.Date, // Stored with slashes.
-			"baseline":   req.Baseline,
-			"test":       req.Test,
-			"type":       req.Type,
-			"ACT_Scores": req.ACT_Scores,
-			"SAT_Scores": req.SAT_Scores,
+		// Build the test data object with the same document ID as for creation.
+		testData := students.TestData{
+			ID:        students.TestDataDocID(req.Type, req.Test, req.Date),
+			Date:      req.Date, // Stored with slashes.
+			Baseline:  req.Baseline,
+			Test:      req.Test,
+			Type:      req.Type,
+			ACTScores: actScoresFromRequest(req.ACT_Scores),
+			SATScores: satScoresFromRequest(req.SAT_Scores),
 		}
 
-		// Update the document in the "Test Data" subcollection.
-		// Using Set with firestore.MergeAll will merge the new values with the existing document.
-		_, err := client.Collection("students").Doc(req.FirebaseID).
-			Collection("Test Data").Doc(docID).Set(ctx, testData, firestore.MergeAll)
+		// Replace the document in the "Test Data" subcollection.
+		err := repo.SaveTestData(ctx, req.FirebaseID, testData)
 		if err != nil {
 			http.Error(w, "Failed to edit test data: "+err.Error(), http.StatusInternalServerError)
 			return
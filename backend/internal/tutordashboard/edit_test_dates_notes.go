@@ -6,6 +6,7 @@ import (
 	"net/http"
 
 	"cloud.google.com/go/firestore"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/students"
 )
 
 // EditTestDatesNotesRequest defines the expected payload for editing test data notes.
@@ -16,7 +17,7 @@ type EditTestDatesNotesRequest struct {
 }
 
 // EditTestDatesNotesHandler returns an HTTP handler function that processes a request to update notes in the TestData subcollection.
-func EditTestDatesNotesHandler(client *firestore.Client) http.HandlerFunc {
+func EditTestDatesNotesHandler(client *firestore.Client, repo students.Repository) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Handle preflight OPTIONS request.
 		if r.Method == http.MethodOptions {
@@ -24,6 +25,10 @@ func EditTestDatesNotesHandler(client *firestore.Client) http.HandlerFunc {
 			return
 		}
 
+		if !requireTutor(w, r) {
+			return
+		}
+
 		ctx := r.Context()
 		var req EditTestDatesNotesRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -36,16 +41,12 @@ func EditTestDatesNotesHandler(client *firestore.Client) http.HandlerFunc {
 			http.Error(w, "Missing required fields", http.StatusBadRequest)
 			return
 		}
-
-		// Prepare the update for the 'notes' field.
-		updates := []firestore.Update{
-			{Path: "notes", Value: req.Notes},
+		if !authorizeStudent(w, r, client, req.FirebaseID) {
+			return
 		}
 
-		// Update the document in the "TestData" subcollection.
-		_, err := client.Collection("students").Doc(req.FirebaseID).
-			Collection("Test Dates").Doc(req.DocumentName).Update(ctx, updates)
-		if err != nil {
+		// Update the notes of the document in the "Test Dates" subcollection.
+		if err := repo.UpdateTestDateNotes(ctx, req.FirebaseID, req.DocumentName, req.Notes); err != nil {
 			http.Error(w, "Failed to update test data notes: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
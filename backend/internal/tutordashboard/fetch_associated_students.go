@@ -16,17 +16,15 @@ type AssociatedStudent struct {
 }
 
 // FetchAssociatedStudentsHandler returns an HTTP handler that fetches all associated students
-// for a given tutor. It expects the tutor's user ID to be provided as a query parameter "tutorUserID".
-// The handler queries the "Associated Students" subcollection under the tutor's document and returns
-// an array of AssociatedStudent objects.
+// for the authenticated tutor. The handler queries the "Associated Students" subcollection under
+// the tutor's document and returns an array of AssociatedStudent objects.
 func FetchAssociatedStudentsHandler(client *firestore.Client) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// For testing purposes, we extract tutorUserID from query parameters.
-		tutorUserID := r.URL.Query().Get("tutorUserID")
-		if tutorUserID == "" {
-			http.Error(w, "Missing tutorUserID", http.StatusBadRequest)
+		tutor, ok := currentTutor(w, r)
+		if !ok {
 			return
 		}
+		tutorUserID := tutor.UserID
 
 		ctx := r.Context()
 		// Reference the "Associated Students" subcollection for the given tutor.
@@ -17,17 +17,14 @@ type TutorProfile struct {
 	UserID  string `json:"user_id"`
 }
 
-// FetchTutorProfileHandler returns an HTTP handler that fetches a tutor's profile.
-// For testing purposes, it accepts a query parameter "tutorUserID".
-// In production, you would extract the tutor's ID from the authenticated context.
+// FetchTutorProfileHandler returns an HTTP handler that fetches the authenticated tutor's profile.
 func FetchTutorProfileHandler(client *firestore.Client) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// For testing, fetch tutorUserID from query parameters.
-		tutorUserID := r.URL.Query().Get("tutorUserID")
-		if tutorUserID == "" {
-			http.Error(w, "Missing tutorUserID", http.StatusBadRequest)
+		tutor, ok := currentTutor(w, r)
+		if !ok {
 			return
 		}
+		tutorUserID := tutor.UserID
 
 		// Retrieve the tutor document from the "tutors" collection.
 		doc, err := client.Collection("tutors").Doc(tutorUserID).Get(r.Context())
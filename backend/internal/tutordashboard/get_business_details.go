@@ -7,15 +7,16 @@ import (
 	"net/http"
 
 	"cloud.google.com/go/firestore"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/students"
 )
 
 // GetBusinessDetailsResponse defines the structure of the response payload.
 type GetBusinessDetailsResponse struct {
-	Business map[string]interface{} `json:"business"`
+	Business students.Business `json:"business"`
 }
 
 // GetBusinessDetailsHandler returns an HTTP handler that retrieves a student's business details.
-func GetBusinessDetailsHandler(client *firestore.Client) http.HandlerFunc {
+func GetBusinessDetailsHandler(client *firestore.Client, repo students.Repository) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Add CORS headers to allow requests from your frontend.
 		w.Header().Set("Access-Control-Allow-Origin", "*")
@@ -28,35 +29,29 @@ func GetBusinessDetailsHandler(client *firestore.Client) http.HandlerFunc {
 			return
 		}
 
+		if !requireTutor(w, r) {
+			return
+		}
+
 		// Expect firebase_id to be provided as a query parameter.
 		firebaseID := r.URL.Query().Get("firebase_id")
 		if firebaseID == "" {
 			http.Error(w, "Missing firebase_id parameter", http.StatusBadRequest)
 			return
 		}
+		if !authorizeStudent(w, r, client, firebaseID) {
+			return
+		}
 
 		ctx := r.Context()
-		docRef := client.Collection("students").Doc(firebaseID)
-		docSnap, err := docRef.Get(ctx)
+		student, err := repo.Get(ctx, firebaseID)
 		if err != nil {
 			http.Error(w, fmt.Sprintf("Error retrieving student document: %v", err), http.StatusInternalServerError)
 			return
 		}
 
-		// Extract the "business" subdocument.
-		var businessData map[string]interface{}
-		if val, ok := docSnap.Data()["business"]; ok {
-			if castVal, ok := val.(map[string]interface{}); ok {
-				businessData = castVal
-			} else {
-				businessData = make(map[string]interface{})
-			}
-		} else {
-			businessData = make(map[string]interface{})
-		}
-
 		response := GetBusinessDetailsResponse{
-			Business: businessData,
+			Business: student.Business,
 		}
 
 		w.Header().Set("Content-Type", "application/json")
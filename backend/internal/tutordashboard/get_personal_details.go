@@ -7,15 +7,16 @@ import (
 	"net/http"
 
 	"cloud.google.com/go/firestore"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/students"
 )
 
 // GetPersonalDetailsResponse defines the structure of the response payload.
 type GetPersonalDetailsResponse struct {
-	Personal map[string]interface{} `json:"personal"`
+	Personal students.Personal `json:"personal"`
 }
 
 // GetPersonalDetailsHandler returns an HTTP handler that retrieves a student's personal details.
-func GetPersonalDetailsHandler(client *firestore.Client) http.HandlerFunc {
+func GetPersonalDetailsHandler(client *firestore.Client, repo students.Repository) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Handle OPTIONS preflight requests.
 		if r.Method == http.MethodOptions {
@@ -23,35 +24,29 @@ func GetPersonalDetailsHandler(client *firestore.Client) http.HandlerFunc {
 			return
 		}
 
+		if !requireTutor(w, r) {
+			return
+		}
+
 		// Expect firebase_id as a query parameter.
 		firebaseID := r.URL.Query().Get("firebase_id")
 		if firebaseID == "" {
 			http.Error(w, "Missing firebase_id parameter", http.StatusBadRequest)
 			return
 		}
+		if !authorizeStudent(w, r, client, firebaseID) {
+			return
+		}
 
 		ctx := r.Context()
-		docRef := client.Collection("students").Doc(firebaseID)
-		docSnap, err := docRef.Get(ctx)
+		student, err := repo.Get(ctx, firebaseID)
 		if err != nil {
 			http.Error(w, fmt.Sprintf("Error retrieving student document: %v", err), http.StatusInternalServerError)
 			return
 		}
 
-		// Extract the "personal" subdocument.
-		var personalData map[string]interface{}
-		if val, ok := docSnap.Data()["personal"]; ok {
-			if castVal, ok := val.(map[string]interface{}); ok {
-				personalData = castVal
-			} else {
-				personalData = make(map[string]interface{})
-			}
-		} else {
-			personalData = make(map[string]interface{})
-		}
-
 		response := GetPersonalDetailsResponse{
-			Personal: personalData,
+			Personal: student.Personal,
 		}
 
 		w.Header().Set("Content-Type", "application/json")
@@ -0,0 +1,38 @@
+// backend/internal/tutordashboard/hours.go
+
+package tutordashboard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/ledger"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/students"
+)
+
+// recordSession brings the family's hours ledger in line with a session that
+// was just written or deleted, then refreshes the student's lifetime_hours.
+// The session is already saved when this runs, so an error means the ledger
+// is behind it; handlers return a 500 so the client retries. Saving a session
+// and settling it in the ledger are both idempotent, so a retry is safe.
+func recordSession(ctx context.Context, hours *ledger.Ledger, repo students.Repository, studentID, homeworkID string, sessionHours float64) error {
+	familyID, err := hours.FamilyForStudent(ctx, studentID)
+	if errors.Is(err, ledger.ErrNoFamily) {
+		log.Printf("Student %s has no family yet; session %s is not in any ledger", studentID, homeworkID)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("find family of student %s: %w", studentID, err)
+	}
+
+	balance, err := hours.SetSessionHours(ctx, familyID, studentID, homeworkID, sessionHours)
+	if err != nil {
+		return fmt.Errorf("record session %s of student %s in ledger %s: %w", homeworkID, studentID, familyID, err)
+	}
+	if err := repo.SetLifetimeHours(ctx, studentID, balance.StudentUsage[studentID]); err != nil {
+		return fmt.Errorf("update lifetime_hours of student %s: %w", studentID, err)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+// backend/internal/tutordashboard/invitations.go
+
+package tutordashboard
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/identity"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/invitations"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/students"
+	"github.com/gorilla/mux"
+)
+
+// InvitationsHandler handles /api/tutor/students/{student_id}/invitations.
+// POST makes a single-use code a parent can redeem to link the student and
+// returns it with a link to the intake page that fills it in; the code is
+// only shown then. GET lists who made each invitation and who redeemed it.
+func (a *App) InvitationsHandler(w http.ResponseWriter, r *http.Request) {
+	studentID := mux.Vars(r)["student_id"]
+	if studentID == "" {
+		http.Error(w, "Student ID is required", http.StatusBadRequest)
+		return
+	}
+	if !authorizeStudent(w, r, a.FirestoreClient, studentID) {
+		return
+	}
+	if r.Method == http.MethodPost {
+		a.createInvitation(w, r, studentID)
+		return
+	}
+
+	invs, err := a.Invitations.ListForStudent(r.Context(), studentID)
+	if err != nil {
+		log.Printf("Error listing invitations for student %s: %v", studentID, err)
+		http.Error(w, "Failed to list invitations", http.StatusInternalServerError)
+		return
+	}
+	if invs == nil {
+		invs = []*invitations.Invitation{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(invs)
+}
+
+func (a *App) createInvitation(w http.ResponseWriter, r *http.Request, studentID string) {
+	tutor, ok := currentTutor(w, r)
+	if !ok {
+		return
+	}
+
+	if _, err := a.Students.Get(r.Context(), studentID); err != nil {
+		if errors.Is(err, students.ErrNotFound) {
+			http.Error(w, "Student not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Error fetching student %s: %v", studentID, err)
+		http.Error(w, "Failed to fetch student", http.StatusInternalServerError)
+		return
+	}
+
+	code, inv, err := a.Invitations.Create(r.Context(), studentID, tutor.UserID)
+	if err != nil {
+		log.Printf("Error creating invitation for student %s: %v", studentID, err)
+		http.Error(w, "Failed to create invitation", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Tutor %s created invitation %s for student %s", tutor.UserID, inv.ID, studentID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(struct {
+		Code      string    `json:"code"`
+		URL       string    `json:"url"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}{
+		Code:      code,
+		URL:       identity.FrontendURL + "/studentintake?code=" + url.QueryEscape(code),
+		ExpiresAt: inv.ExpiresAt,
+	})
+}
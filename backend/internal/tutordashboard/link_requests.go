@@ -0,0 +1,126 @@
+// backend/internal/tutordashboard/link_requests.go
+
+package tutordashboard
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/studentlinks"
+	"github.com/gorilla/mux"
+)
+
+// requireReviewer writes a 403 and returns the tutor, false unless the
+// caller is a team lead or admin, who review the links parents are waiting on.
+func requireReviewer(w http.ResponseWriter, r *http.Request) (*TutorPrincipal, bool) {
+	tutor, ok := currentTutor(w, r)
+	if !ok {
+		return nil, false
+	}
+	if !tutor.CanOverride() {
+		http.Error(w, "Only team leads and admins can review link requests", http.StatusForbidden)
+		return nil, false
+	}
+	return tutor, true
+}
+
+// LinkRequestsHandler handles GET /api/tutor/link-requests?status=pending,
+// listing the links waiting for review oldest first. status may also be
+// approved or rejected.
+func (a *App) LinkRequestsHandler(w http.ResponseWriter, r *http.Request) {
+	if _, ok := requireReviewer(w, r); !ok {
+		return
+	}
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "":
+		status = studentlinks.StatusPending
+	case studentlinks.StatusPending, studentlinks.StatusApproved, studentlinks.StatusRejected:
+	default:
+		http.Error(w, "Invalid status", http.StatusBadRequest)
+		return
+	}
+
+	reqs, err := a.LinkRequests.List(r.Context(), status)
+	if err != nil {
+		log.Printf("Error listing %s link requests: %v", status, err)
+		http.Error(w, "Failed to list link requests", http.StatusInternalServerError)
+		return
+	}
+	if reqs == nil {
+		reqs = []*studentlinks.Request{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reqs)
+}
+
+// ApproveLinkRequestHandler handles POST
+// /api/tutor/link-requests/{request_id}/approve, linking the student to the
+// parent and emailing the parent.
+func (a *App) ApproveLinkRequestHandler(w http.ResponseWriter, r *http.Request) {
+	tutor, ok := requireReviewer(w, r)
+	if !ok {
+		return
+	}
+	id := mux.Vars(r)["request_id"]
+
+	req, err := a.LinkRequests.Approve(r.Context(), id, tutor.UserID)
+	if err != nil {
+		writeLinkRequestError(w, "approve", id, err)
+		return
+	}
+	log.Printf("%s approved link of student %s to parent %s", tutor.UserID, req.StudentID, req.ParentID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(req)
+}
+
+// RejectLinkRequestHandler handles POST
+// /api/tutor/link-requests/{request_id}/reject with {"reason": "..."}. The
+// reason is emailed to the parent.
+func (a *App) RejectLinkRequestHandler(w http.ResponseWriter, r *http.Request) {
+	tutor, ok := requireReviewer(w, r)
+	if !ok {
+		return
+	}
+	id := mux.Vars(r)["request_id"]
+
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	reason := strings.TrimSpace(body.Reason)
+	if reason == "" {
+		http.Error(w, "A reason is required", http.StatusBadRequest)
+		return
+	}
+
+	req, err := a.LinkRequests.Reject(r.Context(), id, tutor.UserID, reason)
+	if err != nil {
+		writeLinkRequestError(w, "reject", id, err)
+		return
+	}
+	log.Printf("%s rejected link of student %s to parent %s: %s", tutor.UserID, req.StudentID, req.ParentID, reason)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(req)
+}
+
+func writeLinkRequestError(w http.ResponseWriter, action, id string, err error) {
+	switch {
+	case errors.Is(err, studentlinks.ErrNotFound):
+		http.Error(w, "Link request not found", http.StatusNotFound)
+	case errors.Is(err, studentlinks.ErrDecided):
+		http.Error(w, "Link request has already been decided", http.StatusConflict)
+	default:
+		log.Printf("Error trying to %s link request %s: %v", action, id, err)
+		http.Error(w, "Failed to "+action+" link request", http.StatusInternalServerError)
+	}
+}
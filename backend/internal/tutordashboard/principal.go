@@ -0,0 +1,99 @@
+// backend/internal/tutordashboard/principal.go
+
+package tutordashboard
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"cloud.google.com/go/firestore"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/middleware"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TutorPrincipal is the authenticated tutor behind a request. It is built from the
+// JWT claims and the tutor's document in the "tutors" collection.
+type TutorPrincipal struct {
+	UserID string
+	Email  string
+	Role   string
+	Tutor  *Tutor
+}
+
+type contextKey string
+
+const tutorPrincipalKey = contextKey("tutorPrincipal")
+
+// ResolveTutor returns middleware that loads tutors/{user_id} for the authenticated
+// caller and stores a *TutorPrincipal in the request context. It must run after
+// middleware.AuthMiddleware.
+func ResolveTutor(client *firestore.Client) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := middleware.GetUserFromContext(r.Context())
+			if !ok {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			userID, _ := claims["user_id"].(string)
+			if userID == "" {
+				http.Error(w, "Unable to identify tutor user", http.StatusUnauthorized)
+				return
+			}
+
+			tutor, err := getTutor(r.Context(), client, userID)
+			if err != nil {
+				if status.Code(err) == codes.NotFound {
+					log.Printf("No tutor document for user %s", userID)
+					http.Error(w, "Forbidden", http.StatusForbidden)
+					return
+				}
+				log.Printf("Error loading tutor %s: %v", userID, err)
+				http.Error(w, "Failed to load tutor", http.StatusInternalServerError)
+				return
+			}
+
+			email, _ := claims["email"].(string)
+			if tutor.Email != "" {
+				email = tutor.Email
+			}
+			role := middleware.GetRoleFromContext(r.Context())
+			if role != middleware.RoleAdmin && tutor.StaffRole == middleware.RoleTeamLead {
+				role = middleware.RoleTeamLead
+			}
+			principal := &TutorPrincipal{
+				UserID: userID,
+				Email:  email,
+				Role:   role,
+				Tutor:  tutor,
+			}
+
+			ctx := context.WithValue(r.Context(), tutorPrincipalKey, principal)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// CanOverride reports whether the tutor may act on students outside their
+// "Associated Students" subcollection.
+func (p *TutorPrincipal) CanOverride() bool {
+	return p.Role == middleware.RoleAdmin || p.Role == middleware.RoleTeamLead
+}
+
+// TutorFromContext returns the tutor principal stored by ResolveTutor.
+func TutorFromContext(ctx context.Context) (*TutorPrincipal, bool) {
+	principal, ok := ctx.Value(tutorPrincipalKey).(*TutorPrincipal)
+	return principal, ok && principal != nil
+}
+
+// currentTutor fetches the principal for a handler, writing a 401 if ResolveTutor did not run.
+func currentTutor(w http.ResponseWriter, r *http.Request) (*TutorPrincipal, bool) {
+	principal, ok := TutorFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unable to identify tutor user", http.StatusUnauthorized)
+		return nil, false
+	}
+	return principal, true
+}
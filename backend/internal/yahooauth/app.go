@@ -5,6 +5,8 @@ package yahooauth
 import (
 	"cloud.google.com/go/firestore"
 	"github.com/NathanielJBrown97/LeeTutoringApp/internal/config"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/identity"
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/oauthstate"
 	"golang.org/x/oauth2"
 )
 
@@ -20,4 +22,6 @@ type App struct {
 	OAuthConfig     *oauth2.Config
 	FirestoreClient *firestore.Client
 	SecretKey       string
+	Identity        *identity.Resolver
+	States          *oauthstate.Manager
 }
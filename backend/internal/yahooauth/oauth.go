@@ -1,44 +1,25 @@
 package yahooauth
 
 import (
-	"crypto/rand"
-	"encoding/base64"
 	"log"
 	"net/http"
-	"time"
 
+	"github.com/NathanielJBrown97/LeeTutoringApp/internal/oauthstate"
 	"golang.org/x/oauth2"
 )
 
-func generateStateOauthCookie(w http.ResponseWriter) string {
-	expiration := time.Now().Add(1 * time.Hour)
+func (a *App) OAuthHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("Yahoo OAuthHandler triggered")
 
-	b := make([]byte, 16)
-	_, err := rand.Read(b)
+	state, stateOpts, err := a.States.Begin(w, r, "yahoo", oauthstate.Options{OpenIDNonce: true})
 	if err != nil {
-		log.Printf("Error generating random bytes for state: %v", err)
-		return "state" // Fallback to a fixed state in case of error
+		oauthstate.Error(w, err)
+		return
 	}
-	state := base64.URLEncoding.EncodeToString(b)
-	cookie := http.Cookie{
-		Name:     "oauthstate",
-		Value:    state,
-		Expires:  expiration,
-		HttpOnly: true,
-		Secure:   true,
-		Path:     "/",
-		SameSite: http.SameSiteNoneMode,
-	}
-	http.SetCookie(w, &cookie)
-
-	return state
-}
-
-func (a *App) OAuthHandler(w http.ResponseWriter, r *http.Request) {
-	log.Println("Yahoo OAuthHandler triggered")
 
-	state := generateStateOauthCookie(w)
-	url := a.OAuthConfig.AuthCodeURL(state, oauth2.AccessTypeOffline, oauth2.SetAuthURLParam("nonce", state))
+	// Yahoo does not support PKCE.
+	opts := append([]oauth2.AuthCodeOption{oauth2.AccessTypeOffline}, stateOpts...)
+	url := a.OAuthConfig.AuthCodeURL(state, opts...)
 
 	log.Println("Redirecting to Yahoo OAuth URL:", url)
 	http.Redirect(w, r, url, http.StatusTemporaryRedirect)
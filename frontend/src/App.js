@@ -10,6 +10,7 @@ import StudentDashboard from './components/StudentDashboard';
 import StudentIntake from './components/StudentIntake';
 import BookingPage from './components/BookingPage';
 import AuthRedirect from './components/AuthRedirect';
+import MFA from './components/MFA';
 import ParentProfile from './components/ParentProfile';
 import NoScrollWrapper from './components/NoScrollWrapper';
 
@@ -25,50 +26,67 @@ function AppRoutes() {
   }
 
   return (
-    <Routes>
-      {/* Public Routes (not logged in) */}
-      {!authState.authenticated && (
-        <>
-          <Route
-            path="/"
-            element={
-              <NoScrollWrapper>
-                <SignIn />
-              </NoScrollWrapper>
-            }
-          />
-          <Route path="/auth-redirect" element={<AuthRedirect />} />
-          <Route path="*" element={<Navigate to="/" />} />
-        </>
+    <>
+      {/* Admins viewing the app as someone else get a read-only session */}
+      {authState.user?.impersonator && (
+        <div
+          style={{
+            backgroundColor: '#b29600',
+            color: '#fff',
+            textAlign: 'center',
+            padding: '8px',
+            fontWeight: 'bold',
+          }}
+        >
+          Viewing as {authState.user.email || authState.user.id} (read-only). Close this window when you are done.
+        </div>
       )}
+      <Routes>
+        {/* Public Routes (not logged in) */}
+        {!authState.authenticated && (
+          <>
+            <Route
+              path="/"
+              element={
+                <NoScrollWrapper>
+                  <SignIn />
+                </NoScrollWrapper>
+              }
+            />
+            <Route path="/auth-redirect" element={<AuthRedirect />} />
+            <Route path="/mfa" element={<MFA />} />
+            <Route path="*" element={<Navigate to="/" />} />
+          </>
+        )}
 
-      {/* Private Routes (logged in) */}
-      {authState.authenticated && (
-        <>
-          <Route path="/parentdashboard" element={<ParentDashboard />} />
-          <Route path="/tutordashboard" element={<TutorDashboard />} />
-          <Route path="/studentdashboard" element={<StudentDashboard />} />
-          <Route path="/studentintake" element={<StudentIntake />} />
-          <Route path="/booking" element={<BookingPage />} />
-          <Route path="/parentprofile" element={<ParentProfile />} />
-          {/* Default route based on role */}
-          <Route
-            path="*"
-            element={
-              <Navigate
-                to={
-                  authState.user?.role === 'tutor'
-                    ? '/tutordashboard'
-                    : authState.user?.role === 'student'
-                    ? '/studentdashboard'
-                    : '/parentdashboard'
-                }
-              />
-            }
-          />
-        </>
-      )}
-    </Routes>
+        {/* Private Routes (logged in) */}
+        {authState.authenticated && (
+          <>
+            <Route path="/parentdashboard" element={<ParentDashboard />} />
+            <Route path="/tutordashboard" element={<TutorDashboard />} />
+            <Route path="/studentdashboard" element={<StudentDashboard />} />
+            <Route path="/studentintake" element={<StudentIntake />} />
+            <Route path="/booking" element={<BookingPage />} />
+            <Route path="/parentprofile" element={<ParentProfile />} />
+            {/* Default route based on role */}
+            <Route
+              path="*"
+              element={
+                <Navigate
+                  to={
+                    (authState.user?.role === 'tutor' || authState.user?.role === 'admin')
+                      ? '/tutordashboard'
+                      : authState.user?.role === 'student'
+                      ? '/studentdashboard'
+                      : '/parentdashboard'
+                  }
+                />
+              }
+            />
+          </>
+        )}
+      </Routes>
+    </>
   );
 }
 
@@ -11,7 +11,9 @@ const AuthRedirect = () => {
   useEffect(() => {
     // Extract the token from the URL fragment (removes the '#' at the beginning)
     const token = window.location.hash.substr(1);
-    console.log('Extracted token:', token);
+
+    // Page the sign-in was started from, if any (always an app path)
+    const next = new URLSearchParams(window.location.search).get('next');
 
     if (token) {
       // Save token in localStorage and update our AuthContext state
@@ -31,7 +33,10 @@ const AuthRedirect = () => {
       }
 
       // Based on the role, navigate to the appropriate dashboard
-      if (decoded && decoded.role === 'tutor') {
+      if (next && next.startsWith('/') && !next.startsWith('//')) {
+        navigate(next);
+        console.log('Navigated to', next);
+      } else if (decoded && (decoded.role === 'tutor' || decoded.role === 'admin')) {
         navigate('/tutordashboard');
         console.log('Navigated to /tutordashboard');
       } else if (decoded && decoded.role === 'student') {
@@ -0,0 +1,133 @@
+// File: src/components/MFA.js
+import React, { useState, useEffect } from 'react';
+import { useNavigate } from 'react-router-dom';
+import { API_BASE_URL } from '../config';
+
+// MFA finishes a staff sign-in that needs a code from an authenticator app.
+// The backend sends staff here with a challenge in the URL fragment, and
+// ?enroll=1 when their role requires an authenticator they have not set up.
+const MFA = () => {
+  const navigate = useNavigate();
+  const [challenge] = useState(() => window.location.hash.substr(1));
+  const [enroll] = useState(
+    () => new URLSearchParams(window.location.search).get('enroll') === '1'
+  );
+  const [setup, setSetup] = useState(null);
+  const [code, setCode] = useState('');
+  const [result, setResult] = useState(null);
+  const [error, setError] = useState(null);
+
+  const post = async (path, body) => {
+    const response = await fetch(`${API_BASE_URL}${path}`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      // The refresh cookie is set when the sign-in completes
+      credentials: 'include',
+      body: JSON.stringify(body),
+    });
+    if (!response.ok) {
+      const errorText = await response.text();
+      throw new Error(errorText.trim() || `Error ${response.status}`);
+    }
+    return response.json();
+  };
+
+  useEffect(() => {
+    if (!challenge) {
+      navigate('/');
+      return;
+    }
+    // Keep the challenge out of the browser history
+    window.history.replaceState({}, document.title, window.location.pathname);
+    if (enroll) {
+      post('/api/auth/mfa/enroll', { challenge })
+        .then(setSetup)
+        .catch((err) => setError(err.message));
+    }
+    // eslint-disable-next-line react-hooks/exhaustive-deps
+  }, [challenge, enroll, navigate]);
+
+  const finish = (login) => {
+    let redirect = '/auth-redirect';
+    if (login.next) {
+      redirect += `?next=${encodeURIComponent(login.next)}`;
+    }
+    navigate(`${redirect}#${login.token}`);
+  };
+
+  const handleSubmit = async (e) => {
+    e.preventDefault();
+    setError(null);
+    try {
+      const login = await post('/api/auth/mfa/verify', { challenge, code });
+      if (login.recovery_codes && login.recovery_codes.length > 0) {
+        // Show the recovery codes once before going on
+        setResult(login);
+      } else {
+        finish(login);
+      }
+    } catch (err) {
+      setError(err.message);
+    }
+  };
+
+  if (result) {
+    return (
+      <div style={{ maxWidth: '420px', margin: '40px auto' }}>
+        <h2>Save your recovery codes</h2>
+        <p>
+          Each code signs you in once if you lose your authenticator. They will not be shown
+          again.
+        </p>
+        <pre>{result.recovery_codes.join('\n')}</pre>
+        <button onClick={() => finish(result)}>Continue</button>
+      </div>
+    );
+  }
+
+  return (
+    <div style={{ maxWidth: '420px', margin: '40px auto' }}>
+      <h2>Two-step verification</h2>
+      {enroll && (
+        <div>
+          <p>Your account requires an authenticator app. Add this account to your app:</p>
+          {setup ? (
+            <>
+              <p>
+                <a href={setup.uri}>Open in authenticator app</a>
+              </p>
+              <p>
+                Or enter this key: <code>{setup.secret}</code>
+              </p>
+            </>
+          ) : (
+            !error && <p>Loading...</p>
+          )}
+        </div>
+      )}
+      <form onSubmit={handleSubmit}>
+        <label>
+          {enroll
+            ? 'Enter the code your app shows:'
+            : 'Enter the code from your authenticator app, or a recovery code:'}
+          <input
+            type="text"
+            autoComplete="one-time-code"
+            value={code}
+            onChange={(e) => setCode(e.target.value)}
+            style={{ display: 'block', marginTop: '8px' }}
+          />
+        </label>
+        <button type="submit" disabled={!code}>
+          Verify
+        </button>
+      </form>
+      {error && <p style={{ color: 'red' }}>{error}</p>}
+      <p>
+        <a href="/">Start over</a>
+      </p>
+    </div>
+  );
+};
+
+export default MFA;
@@ -250,7 +250,7 @@ function MySchedule({ tutorId, backendUrl }) {
         console.log('MySchedule: timeMin:', timeMin, 'timeMax:', timeMax);
 
         // Append query parameters to request events for the full week with singleEvents=true.
-        const fetchUrl = `${backendUrl}/api/tutor/calendar-events?user_id=${encodeURIComponent(tutorId)}&timeMin=${encodeURIComponent(timeMin)}&timeMax=${encodeURIComponent(timeMax)}&singleEvents=true`;
+        const fetchUrl = `${backendUrl}/api/tutor/calendar-events?timeMin=${encodeURIComponent(timeMin)}&timeMax=${encodeURIComponent(timeMax)}&singleEvents=true`;
         console.log('MySchedule: Fetching events from:', fetchUrl);
         const token = localStorage.getItem('authToken');
         const res = await fetch(fetchUrl, {
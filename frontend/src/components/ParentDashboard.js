@@ -315,7 +315,7 @@ const ParentDashboard = () => {
           // E.g. some data structure that shows success or not
           setAttemptedAutoAssociation(true);
           // Now fetch the associated students
-          fetchAssociatedStudents(token);
+          fetchAssociatedStudents(token, (data.pendingStudents || []).length);
         })
         .catch((err) => {
           // If the attempt fails, navigate to StudentIntake
@@ -325,7 +325,8 @@ const ParentDashboard = () => {
     }, [attemptedAutoAssociation, navigate]);
 
   // 3) Fetch associated students
-  const fetchAssociatedStudents = (token) => {
+  // pendingCount is how many matched students are waiting for staff review
+  const fetchAssociatedStudents = (token, pendingCount = 0) => {
     fetch(`${API_BASE_URL}/api/associated-students`, {
       method: 'GET',
       headers: { Authorization: `Bearer ${token}` },
@@ -335,7 +336,7 @@ const ParentDashboard = () => {
         if (!data.associatedStudents || data.associatedStudents.length === 0) {
           // If no associated students, redirect to StudentIntake
           console.warn('No students found, redirecting...');
-          navigate('/studentintake');
+          navigate('/studentintake', { state: { pendingCount } });
           return;
         }
         setAssociatedStudents(data.associatedStudents);
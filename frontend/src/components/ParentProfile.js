@@ -4,6 +4,222 @@ import React, { useEffect, useState } from 'react';
 import { API_BASE_URL } from '../config';
 import './ParentProfile.css'; // Optional: Create a CSS file for styling
 
+const PERMISSIONS = [
+  { key: 'academic', label: 'Academic' },
+  { key: 'billing', label: 'Billing' },
+  { key: 'manage', label: 'Manage' },
+];
+
+// Household shows the guardians who share the parent's students, and lets
+// guardians who can manage the household invite, change and remove them.
+// Invitations to join other households are accepted or declined here too.
+const Household = ({ userID }) => {
+  const [household, setHousehold] = useState(null);
+  const [invitations, setInvitations] = useState([]);
+  const [notice, setNotice] = useState(null);
+  const [email, setEmail] = useState('');
+  const [permissions, setPermissions] = useState(['academic']);
+  const [error, setError] = useState(null);
+
+  const request = async (path, method, body) => {
+    const token = localStorage.getItem('authToken');
+    const response = await fetch(`${API_BASE_URL}${path}`, {
+      method,
+      headers: {
+        Authorization: `Bearer ${token}`,
+        'Content-Type': 'application/json',
+      },
+      body: body ? JSON.stringify(body) : undefined,
+    });
+    if (!response.ok) {
+      const errorText = await response.text();
+      throw new Error(errorText.trim() || `Error ${response.status}`);
+    }
+    return response.status === 204 ? null : response.json();
+  };
+
+  const loadHousehold = async () => {
+    try {
+      setHousehold(await request('/api/household', 'GET'));
+    } catch (err) {
+      // A parent without students has no household yet.
+      setHousehold(null);
+    }
+  };
+
+  const loadInvitations = async () => {
+    try {
+      setInvitations(await request('/api/household/invitations', 'GET'));
+    } catch (err) {
+      console.error('Error fetching household invitations:', err);
+    }
+  };
+
+  useEffect(() => {
+    loadHousehold();
+    loadInvitations();
+  }, []);
+
+  const run = async (fn) => {
+    setError(null);
+    setNotice(null);
+    try {
+      await fn();
+    } catch (err) {
+      console.error('Error updating household:', err);
+      setError(err.message);
+    }
+  };
+
+  const togglePermission = (key) => {
+    setPermissions((prev) =>
+      prev.includes(key) ? prev.filter((p) => p !== key) : [...prev, key]
+    );
+  };
+
+  const inviteGuardian = (e) => {
+    e.preventDefault();
+    run(async () => {
+      const { message } = await request('/api/household/guardians', 'POST', { email, permissions });
+      setEmail('');
+      setNotice(message);
+      await loadHousehold();
+    });
+  };
+
+  const answerInvitation = (invitation, accept) => {
+    if (accept && household && !window.confirm('Join this household? You will see its students.')) {
+      return;
+    }
+    run(async () => {
+      await request(`/api/household/invitations/${invitation.household_id}`, accept ? 'POST' : 'DELETE');
+      await loadInvitations();
+      await loadHousehold();
+    });
+  };
+
+  const changePermissions = (guardian, key) => {
+    const next = guardian.permissions.includes(key)
+      ? guardian.permissions.filter((p) => p !== key)
+      : [...guardian.permissions, key];
+    run(async () => {
+      setHousehold(await request(`/api/household/guardians/${guardian.id}`, 'PUT', { permissions: next }));
+    });
+  };
+
+  const removeGuardian = (guardian) => {
+    const leaving = guardian.id === userID;
+    const prompt = leaving
+      ? 'Leave this household? You will no longer see its students.'
+      : `Remove ${guardian.name || guardian.email} from this household?`;
+    if (!window.confirm(prompt)) {
+      return;
+    }
+    run(async () => {
+      await request(`/api/household/guardians/${guardian.id}`, 'DELETE');
+      await loadHousehold();
+    });
+  };
+
+  const canManage = household?.permissions?.includes('manage');
+
+  return (
+    <div className="household">
+      <h3>Household</h3>
+      {invitations.map((inv) => (
+        <div className="household-invitation" key={inv.household_id}>
+          <p>
+            {inv.invited_by_name || 'Another parent'} invited you to join their household
+            ({inv.permissions.join(', ')}).
+          </p>
+          <button type="button" onClick={() => answerInvitation(inv, true)}>
+            Accept
+          </button>
+          <button type="button" onClick={() => answerInvitation(inv, false)}>
+            Decline
+          </button>
+        </div>
+      ))}
+      {!household ? (
+        <p>Link a student to start your household.</p>
+      ) : (
+        <table className="household-guardians">
+          <thead>
+            <tr>
+              <th>Guardian</th>
+              {PERMISSIONS.map((p) => (
+                <th key={p.key}>{p.label}</th>
+              ))}
+              <th />
+            </tr>
+          </thead>
+          <tbody>
+            {household.guardians.map((g) => (
+              <tr key={g.id}>
+                <td>
+                  {g.name || 'N/A'}
+                  <br />
+                  <small>{g.email}</small>
+                </td>
+                {PERMISSIONS.map((p) => (
+                  <td key={p.key}>
+                    <input
+                      type="checkbox"
+                      checked={(g.permissions || []).includes(p.key)}
+                      disabled={!canManage}
+                      onChange={() => changePermissions(g, p.key)}
+                    />
+                  </td>
+                ))}
+                <td>
+                  {(canManage || g.id === userID) && (
+                    <button type="button" onClick={() => removeGuardian(g)}>
+                      {g.id === userID ? 'Leave' : 'Remove'}
+                    </button>
+                  )}
+                </td>
+              </tr>
+            ))}
+          </tbody>
+        </table>
+      )}
+      {canManage && household.invitations?.length > 0 && (
+        <p>
+          Waiting for: {household.invitations.map((inv) => inv.email).join(', ')}
+        </p>
+      )}
+      {(!household || canManage) && (
+        <form className="household-add" onSubmit={inviteGuardian}>
+          <p>
+            Invite another parent or guardian. They join once they sign in with
+            this email and accept.
+          </p>
+          <input
+            type="email"
+            placeholder="Their email"
+            value={email}
+            onChange={(e) => setEmail(e.target.value)}
+            required
+          />
+          {PERMISSIONS.map((p) => (
+            <label key={p.key}>
+              <input
+                type="checkbox"
+                checked={permissions.includes(p.key)}
+                onChange={() => togglePermission(p.key)}
+              />
+              {p.label}
+            </label>
+          ))}
+          <button type="submit">Invite</button>
+        </form>
+      )}
+      {notice && <p>{notice}</p>}
+      {error && <p style={{ color: 'red' }}>{error}</p>}
+    </div>
+  );
+};
+
 const ParentProfile = () => {
   const [parentData, setParentData] = useState(null);
   const [loading, setLoading] = useState(true);
@@ -83,6 +299,7 @@ const ParentProfile = () => {
           </p>
         </div>
       </div>
+      <Household userID={parentData.user_id} />
     </div>
   );
 };
@@ -1,6 +1,6 @@
 // src/components/SignIn.js
 
-import React, { useEffect } from 'react';
+import React, { useEffect, useState } from 'react';
 import { API_BASE_URL } from '../config';
 import {
   Box,
@@ -9,6 +9,8 @@ import {
   Typography,
   Stack,
   Button,
+  Divider,
+  TextField,
   useMediaQuery,
   useTheme,
 } from '@mui/material';
@@ -17,7 +19,7 @@ import Logo from '../assets/logo.png';
 import loginImage from '../assets/login.jpg';
 
 // Importing icons from react-icons
-import { FaGoogle, FaMicrosoft, FaFacebook, FaApple, FaYahoo } from 'react-icons/fa';
+import { FaGoogle, FaMicrosoft, FaFacebook, FaApple, FaYahoo, FaEnvelope } from 'react-icons/fa';
 
 // Brand colors
 const brandBlue = '#0e1027';
@@ -163,6 +165,8 @@ const IconWrapper = styled(Box)(({ theme }) => ({
 const SignIn = () => {
   const theme = useTheme();
   const isMobile = useMediaQuery(theme.breakpoints.down('md'));
+  const [email, setEmail] = useState('');
+  const [linkStatus, setLinkStatus] = useState(null); // null | 'sending' | 'sent' | 'error'
 
   // Lock scrolling on mount, restore on unmount
   useEffect(() => {
@@ -188,6 +192,22 @@ const SignIn = () => {
   const handleAppleLogin = () => {
     window.location.href = `${API_BASE_URL}/internal/appleauth/oauth`;
   };
+  // Email a one-time sign-in link for families without any of the above
+  const handleEmailLogin = async (event) => {
+    event.preventDefault();
+    setLinkStatus('sending');
+    try {
+      const response = await fetch(`${API_BASE_URL}/api/auth/magic-link`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ email }),
+      });
+      setLinkStatus(response.ok ? 'sent' : 'error');
+    } catch (error) {
+      console.error('Error requesting sign-in link:', error);
+      setLinkStatus('error');
+    }
+  };
 
   // Platform data (with Yahoo icon)
   const platforms = [
@@ -320,6 +340,46 @@ const SignIn = () => {
                 </StyledButton>
               ))}
             </Stack>
+
+            {/* Email sign-in link */}
+            <Divider sx={{ my: 3, color: '#555' }}>or</Divider>
+            {linkStatus === 'sent' ? (
+              <Typography variant="body1" align="center" sx={{ color: '#555' }}>
+                Check your inbox for a sign-in link. It expires in 15 minutes.
+              </Typography>
+            ) : (
+              <Box component="form" onSubmit={handleEmailLogin}>
+                <Stack spacing={2}>
+                  <TextField
+                    type="email"
+                    label="Email address"
+                    value={email}
+                    onChange={(e) => setEmail(e.target.value)}
+                    required
+                    fullWidth
+                    error={linkStatus === 'error'}
+                    helperText={
+                      linkStatus === 'error'
+                        ? 'Could not send a sign-in link. Please try again.'
+                        : ''
+                    }
+                  />
+                  <StyledButton
+                    type="submit"
+                    disabled={linkStatus === 'sending'}
+                    startIcon={
+                      <IconWrapper>
+                        <FaEnvelope size={24} />
+                      </IconWrapper>
+                    }
+                    fullWidth
+                    aria-label="Email me a sign-in link"
+                  >
+                    Email me a sign-in link
+                  </StyledButton>
+                </Stack>
+              </Box>
+            )}
           </CardContent>
         </StyledCard>
       </SignInContainer>
@@ -2,7 +2,7 @@
 
 import React, { useState, useEffect } from 'react';
 import { API_BASE_URL } from '../config';
-import { useNavigate } from 'react-router-dom';
+import { useLocation, useNavigate, useSearchParams } from 'react-router-dom';
 
 // MUI imports
 import {
@@ -132,12 +132,17 @@ const StyledButton = styled(Button, {
 /* ===================== MAIN COMPONENT ===================== */
 
 const StudentIntake = () => {
+  // A tutor's invitation link fills in the first code
+  const [searchParams] = useSearchParams();
+  // Students matched by email that are waiting for our team to confirm
+  const pendingCount = useLocation().state?.pendingCount || 0;
   const [numStudents, setNumStudents] = useState(1);
-  const [studentIDs, setStudentIDs] = useState(['']);
+  const [codes, setCodes] = useState([searchParams.get('code') || '']);
   const [studentInfos, setStudentInfos] = useState([]);
   const [confirmationStep, setConfirmationStep] = useState(false);
   const [loading, setLoading] = useState(false);
   const [readyToProceed, setReadyToProceed] = useState(false);
+  const [errorMessage, setErrorMessage] = useState('');
 
   const navigate = useNavigate();
   const theme = useTheme();
@@ -155,17 +160,18 @@ const StudentIntake = () => {
   const handleNumStudentsChange = (e) => {
     const count = parseInt(e.target.value, 10) || 1;
     setNumStudents(count);
-    setStudentIDs(Array(count).fill(''));
+    setCodes(Array(count).fill(''));
   };
 
-  const handleStudentIDChange = (index, value) => {
-    const newIDs = [...studentIDs];
-    newIDs[index] = value;
-    setStudentIDs(newIDs);
+  const handleCodeChange = (index, value) => {
+    const newCodes = [...codes];
+    newCodes[index] = value;
+    setCodes(newCodes);
   };
 
-  const handleSubmitStudentIDs = () => {
+  const handleSubmitCodes = () => {
     setLoading(true);
+    setErrorMessage('');
     const token = localStorage.getItem('authToken');
 
     fetch(`${API_BASE_URL}/api/submitStudentIDs`, {
@@ -174,7 +180,7 @@ const StudentIntake = () => {
         'Content-Type': 'application/json',
         Authorization: `Bearer ${token}`,
       },
-      body: JSON.stringify({ studentIds: studentIDs }),
+      body: JSON.stringify({ codes }),
     })
       .then(async (response) => {
         if (!response.ok) {
@@ -195,7 +201,8 @@ const StudentIntake = () => {
         setLoading(false);
       })
       .catch((error) => {
-        console.error('Error submitting student IDs:', error);
+        console.error('Error submitting invitation codes:', error);
+        setErrorMessage('Could not check your codes. Please try again.');
         setLoading(false);
       });
   };
@@ -216,12 +223,13 @@ const StudentIntake = () => {
 
   const handleProceed = () => {
     setLoading(true);
+    setErrorMessage('');
     const token = localStorage.getItem('authToken');
 
-    // Extract confirmed student IDs
-    const confirmedStudentIds = studentInfos
+    // Extract the codes of confirmed students
+    const confirmedCodes = studentInfos
       .filter((info) => info.canLink && info.confirmed === true)
-      .map((info) => info.studentId);
+      .map((info) => info.code);
 
     // Proceed to link confirmed students
     fetch(`${API_BASE_URL}/api/confirmLinkStudents`, {
@@ -230,7 +238,7 @@ const StudentIntake = () => {
         'Content-Type': 'application/json',
         Authorization: `Bearer ${token}`,
       },
-      body: JSON.stringify({ confirmedStudentIds }),
+      body: JSON.stringify({ confirmedCodes }),
     })
       .then(async (response) => {
         if (!response.ok) {
@@ -252,11 +260,11 @@ const StudentIntake = () => {
         if (unconfirmedStudents.length > 0) {
           // Reset the intake form for unconfirmed students
           setNumStudents(unconfirmedStudents.length);
-          setStudentIDs(Array(unconfirmedStudents.length).fill(''));
+          setCodes(Array(unconfirmedStudents.length).fill(''));
           setStudentInfos([]);
           setConfirmationStep(false);
           setReadyToProceed(false);
-          alert('Some students were not confirmed. Please re-enter their IDs.');
+          alert('Some students were not confirmed. Please re-enter their codes.');
         } else {
           // Redirect to Parent Dashboard
           navigate('/parentdashboard');
@@ -264,6 +272,7 @@ const StudentIntake = () => {
       })
       .catch((error) => {
         console.error('Error confirming student links:', error);
+        setErrorMessage('Could not link your students. Please check your codes and try again.');
         setLoading(false);
       });
   };
@@ -294,11 +303,25 @@ const StudentIntake = () => {
             <Divider sx={{ my: 2 }} />
 
             <Typography variant="body1" align="center" sx={{ mb: 3 }}>
-              Please refer to the email from{' '}
+              Enter the invitation code your tutor sent you for each student. Codes
+              can be used once and expire after two weeks; ask your tutor or{' '}
               <strong style={{ color: brandGold }}>admin@leetutoring.com</strong>{' '}
-              for your student ID(s). Copy/paste them below to link your student(s).
+              for a new one.
             </Typography>
 
+            {pendingCount > 0 && (
+              <Typography variant="body2" align="center" sx={{ color: brandBlue, mb: 2 }}>
+                We found {pendingCount} student(s) matching your email. Our team is
+                confirming the link and will email you once it is done.
+              </Typography>
+            )}
+
+            {errorMessage && (
+              <Typography variant="body2" align="center" sx={{ color: 'red', mb: 2 }}>
+                {errorMessage}
+              </Typography>
+            )}
+
             {/* If loading, show spinner */}
             {loading && (
               <Box display="flex" justifyContent="center" alignItems="center" mb={2}>
@@ -306,7 +329,7 @@ const StudentIntake = () => {
               </Box>
             )}
 
-            {/* STEP 1: Enter # of students and IDs */}
+            {/* STEP 1: Enter # of students and codes */}
             {!confirmationStep && (
               <Stack spacing={2}>
                 <TextField
@@ -319,23 +342,24 @@ const StudentIntake = () => {
                   inputProps={{ min: 1 }}
                 />
 
-                {studentIDs.map((id, index) => (
+                {codes.map((code, index) => (
                   <TextField
                     key={index}
-                    label={`Student ID ${index + 1}`}
+                    label={`Invitation Code ${index + 1}`}
+                    placeholder="XXXX-XXXX-XXXX"
                     variant="outlined"
                     fullWidth
-                    value={id}
-                    onChange={(e) => handleStudentIDChange(index, e.target.value)}
+                    value={code}
+                    onChange={(e) => handleCodeChange(index, e.target.value)}
                   />
                 ))}
 
                 <StyledButton
-                  onClick={handleSubmitStudentIDs}
+                  onClick={handleSubmitCodes}
                   disabled={loading}
                   sx={{ mt: 1 }}
                 >
-                  Submit Student IDs
+                  Submit Codes
                 </StyledButton>
               </Stack>
             )}
@@ -364,13 +388,13 @@ const StudentIntake = () => {
                       }}
                     >
                       <ListItemText
-                        primary={`Student ID: ${info.studentId}`}
+                        primary={`Invitation Code: ${info.code}`}
                         secondary={`Student Name: ${info.studentName}`}
                         sx={{ mb: 1 }}
                       />
                       {!info.canLink && (
                         <Typography variant="body2" sx={{ color: 'red' }}>
-                          Cannot link this student. Please check the ID.
+                          Cannot link this student. Please check the code.
                         </Typography>
                       )}
 
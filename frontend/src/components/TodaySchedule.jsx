@@ -252,7 +252,7 @@ function TodaySchedule({ tutorId, backendUrl, onStudentNamesUpdate }) {
       try {
         const token = localStorage.getItem('authToken');
         const res = await fetch(
-          `${backendUrl}/api/tutor/calendar-events?user_id=${encodeURIComponent(tutorId)}`,
+          `${backendUrl}/api/tutor/calendar-events`,
           {
             method: 'GET',
             headers: {
@@ -555,6 +555,7 @@ function StudentsTab({ tutorId, tutorEmail, backendUrl, filterTodayAppointments
   const [showEditPersonalDialog, setShowEditPersonalDialog] = useState(false);
   const [editingPersonal, setEditingPersonal] = useState(null);
   const [showAssignHWDialog, setShowAssignHWDialog] = useState(false);
+  const [parentInvitation, setParentInvitation] = useState(null);
   const [menuAnchorEl, setMenuAnchorEl] = useState(null);
   const menuOpen = Boolean(menuAnchorEl);
 
@@ -639,7 +640,7 @@ function StudentsTab({ tutorId, tutorEmail, backendUrl, filterTodayAppointments
       try {
         const token = localStorage.getItem('authToken');
         const res = await fetch(
-          `${backendUrl}/api/tutor/fetch-associated-students?tutorUserID=${encodeURIComponent(tutorId)}&tutorEmail=${encodeURIComponent(tutorEmail)}`,
+          `${backendUrl}/api/tutor/fetch-associated-students`,
           {
             method: 'GET',
             headers: {
@@ -670,7 +671,7 @@ function StudentsTab({ tutorId, tutorEmail, backendUrl, filterTodayAppointments
         const details = await Promise.all(
           studentIds.map(async (student) => {
             const res = await fetch(
-              `${backendUrl}/api/tutor/students/${student.id}?tutorUserID=${encodeURIComponent(tutorId)}&tutorEmail=${encodeURIComponent(tutorEmail)}`,
+              `${backendUrl}/api/tutor/students/${student.id}`,
               {
                 method: 'GET',
                 headers: {
@@ -723,6 +724,29 @@ function StudentsTab({ tutorId, tutorEmail, backendUrl, filterTodayAppointments
     handleMenuClose();
   };
 
+  // Make a single-use code the student's parent can redeem to link them
+  const handleInviteParentFromMenu = () => {
+    handleMenuClose();
+    const token = localStorage.getItem('authToken');
+    fetch(`${backendUrl}/api/tutor/students/${selectedStudent.id}/invitations`, {
+      method: 'POST',
+      headers: {
+        'Authorization': `Bearer ${token}`,
+      },
+    })
+      .then((res) => {
+        if (!res.ok) {
+          throw new Error(`Error ${res.status}`);
+        }
+        return res.json();
+      })
+      .then((data) => setParentInvitation(data))
+      .catch((err) => {
+        console.error("Error creating parent invitation:", err);
+        alert("Failed to create parent invitation");
+      });
+  };
+
   if (loading) {
     return (
       <Box display="flex" justifyContent="center" alignItems="center" sx={{ padding: '24px' }}>
@@ -772,7 +796,7 @@ function StudentsTab({ tutorId, tutorEmail, backendUrl, filterTodayAppointments
         console.error('Failed to create new goal. Status:', res.status);
       } else {
         const res2 = await fetch(
-          `${backendUrl}/api/tutor/students/${selectedStudent.id}?tutorUserID=${encodeURIComponent(tutorId)}&tutorEmail=${encodeURIComponent(tutorEmail)}`,
+          `${backendUrl}/api/tutor/students/${selectedStudent.id}`,
           {
             method: 'GET',
             headers: {
@@ -821,7 +845,7 @@ function StudentsTab({ tutorId, tutorEmail, backendUrl, filterTodayAppointments
       }
       alert("Homework completion record deleted successfully.");
       const res2 = await fetch(
-        `${backendUrl}/api/tutor/students/${selectedStudent.id}?tutorUserID=${encodeURIComponent(tutorId)}&tutorEmail=${encodeURIComponent(tutorEmail)}`,
+        `${backendUrl}/api/tutor/students/${selectedStudent.id}`,
         {
           method: 'GET',
           headers: {
@@ -865,7 +889,7 @@ function StudentsTab({ tutorId, tutorEmail, backendUrl, filterTodayAppointments
         } else {
           alert("Test data updated successfully.");
           return fetch(
-            `${backendUrl}/api/tutor/students/${selectedStudent.id}?tutorUserID=${encodeURIComponent(tutorId)}&tutorEmail=${encodeURIComponent(tutorEmail)}`,
+            `${backendUrl}/api/tutor/students/${selectedStudent.id}`,
             {
               method: 'GET',
               headers: {
@@ -918,7 +942,7 @@ function StudentsTab({ tutorId, tutorEmail, backendUrl, filterTodayAppointments
       }
       alert("Test data deleted successfully.");
       const res2 = await fetch(
-        `${backendUrl}/api/tutor/students/${selectedStudent.id}?tutorUserID=${encodeURIComponent(tutorId)}&tutorEmail=${encodeURIComponent(tutorEmail)}`,
+        `${backendUrl}/api/tutor/students/${selectedStudent.id}`,
         {
           method: 'GET',
           headers: {
@@ -961,7 +985,7 @@ function StudentsTab({ tutorId, tutorEmail, backendUrl, filterTodayAppointments
       }
       alert("Goal deleted successfully.");
       const res2 = await fetch(
-        `${backendUrl}/api/tutor/students/${selectedStudent.id}?tutorUserID=${encodeURIComponent(tutorId)}&tutorEmail=${encodeURIComponent(tutorEmail)}`,
+        `${backendUrl}/api/tutor/students/${selectedStudent.id}`,
         {
           method: 'GET',
           headers: {
@@ -1003,7 +1027,7 @@ function StudentsTab({ tutorId, tutorEmail, backendUrl, filterTodayAppointments
       }
       alert("Event deleted successfully.");
       const res2 = await fetch(
-        `${backendUrl}/api/tutor/students/${selectedStudent.id}?tutorUserID=${encodeURIComponent(tutorId)}&tutorEmail=${encodeURIComponent(tutorEmail)}`,
+        `${backendUrl}/api/tutor/students/${selectedStudent.id}`,
         {
           method: 'GET',
           headers: {
@@ -1298,6 +1322,33 @@ function StudentsTab({ tutorId, tutorEmail, backendUrl, filterTodayAppointments
           />
         )}
 
+        {parentInvitation && (
+          <Dialog open onClose={() => setParentInvitation(null)}>
+            <DialogTitle>Parent Invitation</DialogTitle>
+            <DialogContent>
+              <Typography gutterBottom>
+                Send this code or link to the student's parent. It can be used once and expires on{' '}
+                {new Date(parentInvitation.expires_at).toLocaleDateString()}.
+              </Typography>
+              <Typography variant="h5" sx={{ fontWeight: 'bold', letterSpacing: 2, my: 2 }}>
+                {parentInvitation.code}
+              </Typography>
+              <TextField
+                label="Link"
+                value={parentInvitation.url}
+                fullWidth
+                InputProps={{ readOnly: true }}
+              />
+            </DialogContent>
+            <DialogActions>
+              <Button onClick={() => navigator.clipboard.writeText(parentInvitation.url)}>
+                Copy Link
+              </Button>
+              <Button onClick={() => setParentInvitation(null)}>Done</Button>
+            </DialogActions>
+          </Dialog>
+        )}
+
         {showAssignHWDialog && (
           <AssignHomeworkDialog
             open={showAssignHWDialog}
@@ -1354,7 +1405,7 @@ function StudentsTab({ tutorId, tutorEmail, backendUrl, filterTodayAppointments
                   } else {
                     alert("Test data created successfully.");
                     return fetch(
-                      `${backendUrl}/api/tutor/students/${selectedStudent.id}?tutorUserID=${encodeURIComponent(tutorId)}&tutorEmail=${encodeURIComponent(tutorEmail)}`,
+                      `${backendUrl}/api/tutor/students/${selectedStudent.id}`,
                       {
                         method: 'GET',
                         headers: {
@@ -1399,7 +1450,7 @@ function StudentsTab({ tutorId, tutorEmail, backendUrl, filterTodayAppointments
                   } else {
                     alert("Homework completion record created successfully.");
                     return fetch(
-                      `${backendUrl}/api/tutor/students/${selectedStudent.id}?tutorUserID=${encodeURIComponent(tutorId)}&tutorEmail=${encodeURIComponent(tutorEmail)}`,
+                      `${backendUrl}/api/tutor/students/${selectedStudent.id}`,
                       {
                         method: 'GET',
                         headers: {
@@ -1446,7 +1497,7 @@ function StudentsTab({ tutorId, tutorEmail, backendUrl, filterTodayAppointments
                   } else {
                     alert("Homework completion record updated successfully.");
                     return fetch(
-                      `${backendUrl}/api/tutor/students/${selectedStudent.id}?tutorUserID=${encodeURIComponent(tutorId)}&tutorEmail=${encodeURIComponent(tutorEmail)}`,
+                      `${backendUrl}/api/tutor/students/${selectedStudent.id}`,
                       {
                         method: 'GET',
                         headers: {
@@ -1550,7 +1601,7 @@ function StudentsTab({ tutorId, tutorEmail, backendUrl, filterTodayAppointments
                     throw new Error("Failed to update personal details.");
                   }
                   return fetch(
-                    `${backendUrl}/api/tutor/students/${selectedStudent.id}?tutorUserID=${encodeURIComponent(tutorId)}&tutorEmail=${encodeURIComponent(tutorEmail)}`,
+                    `${backendUrl}/api/tutor/students/${selectedStudent.id}`,
                     {
                       method: 'GET',
                       headers: {
@@ -1607,7 +1658,7 @@ function StudentsTab({ tutorId, tutorEmail, backendUrl, filterTodayAppointments
                 } else {
                   alert("Business details updated successfully.");
                   return fetch(
-                    `${backendUrl}/api/tutor/students/${selectedStudent.id}?tutorUserID=${encodeURIComponent(tutorId)}&tutorEmail=${encodeURIComponent(tutorEmail)}`,
+                    `${backendUrl}/api/tutor/students/${selectedStudent.id}`,
                     {
                       method: 'GET',
                       headers: {
@@ -1665,6 +1716,7 @@ function StudentsTab({ tutorId, tutorEmail, backendUrl, filterTodayAppointments
           }}
         >
           <MenuItem onClick={handleAssignHWFromMenu}>Assign HW</MenuItem>
+          <MenuItem onClick={handleInviteParentFromMenu}>Invite Parent</MenuItem>
           {/* Additional menu items can be added here */}
         </Menu>
         {ExpandedViewContent}
@@ -1693,6 +1745,7 @@ function StudentsTab({ tutorId, tutorEmail, backendUrl, filterTodayAppointments
           }}
         >
           <MenuItem onClick={handleAssignHWFromMenu}>Assign HW</MenuItem>
+          <MenuItem onClick={handleInviteParentFromMenu}>Invite Parent</MenuItem>
           {/* Additional menu items can be added here */}
         </Menu>
         {ExpandedViewContent}
@@ -1805,7 +1858,7 @@ const TutorDashboard = () => {
       try {
         const token = localStorage.getItem('authToken');
         const response = await fetch(
-          `${backendUrl}/api/tutor/profile?tutorUserID=${encodeURIComponent(user.id)}`,
+          `${backendUrl}/api/tutor/profile`,
           {
             method: 'GET',
             headers: {
@@ -1836,7 +1889,7 @@ const TutorDashboard = () => {
       try {
         const token = localStorage.getItem('authToken');
         const response = await fetch(
-          `${backendUrl}/api/tutor/associate-students?tutorUserID=${encodeURIComponent(profile.user_id)}&tutorEmail=${encodeURIComponent(profile.email)}`,
+          `${backendUrl}/api/tutor/associate-students`,
           {
             method: 'GET',
             headers: {
@@ -1990,7 +2043,7 @@ const TutorDashboard = () => {
       }
       alert("Homework completion record deleted successfully.");
       const res2 = await fetch(
-        `${backendUrl}/api/tutor/students/${selectedTodayStudent.id}?tutorUserID=${encodeURIComponent(profile.user_id)}&tutorEmail=${encodeURIComponent(profile.email)}`,
+        `${backendUrl}/api/tutor/students/${selectedTodayStudent.id}`,
         {
           method: 'GET',
           headers: {
@@ -2032,7 +2085,7 @@ const TutorDashboard = () => {
       }
       alert("Event deleted successfully.");
       const res2 = await fetch(
-        `${backendUrl}/api/tutor/students/${selectedTodayStudent.id}?tutorUserID=${encodeURIComponent(profile.user_id)}&tutorEmail=${encodeURIComponent(profile.email)}`,
+        `${backendUrl}/api/tutor/students/${selectedTodayStudent.id}`,
         {
           method: 'GET',
           headers: {
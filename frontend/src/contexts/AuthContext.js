@@ -1,6 +1,6 @@
 // src/contexts/AuthContext.js
 
-import React, { createContext, useEffect, useState } from 'react';
+import React, { createContext, useCallback, useEffect, useState } from 'react';
 import { jwtDecode } from 'jwt-decode';
 import { API_BASE_URL } from '../config';
 
@@ -15,8 +15,29 @@ export const AuthProvider = ({ children }) => {
     error: null,
   });
 
+  // Swap the HttpOnly refresh cookie for a new access token. Access tokens
+  // only last 15 minutes; the refresh cookie keeps the user signed in.
+  const refreshAccessToken = useCallback(async () => {
+    try {
+      const response = await fetch(`${API_BASE_URL}/api/auth/refresh`, {
+        method: 'POST',
+        credentials: 'include',
+      });
+      if (!response.ok) {
+        throw new Error(`Refresh failed with status ${response.status}`);
+      }
+      const data = await response.json();
+      localStorage.setItem('authToken', data.token);
+      setToken(data.token);
+      return true;
+    } catch (error) {
+      console.log('Could not refresh session:', error);
+      return false;
+    }
+  }, []);
+
   useEffect(() => {
-    console.log('Token from state:', token);
+    let refreshTimer;
 
     if (token) {
       try {
@@ -27,16 +48,25 @@ export const AuthProvider = ({ children }) => {
         // Check if the token is expired
         const currentTime = Date.now() / 1000; // in seconds
         if (decoded.exp < currentTime) {
-          console.log('Token has expired');
-          localStorage.removeItem('authToken');
-          setToken(null);
-          setAuthState({
-            authenticated: false,
-            user: null,
-            loading: false,
-            error: 'Session has expired. Please sign in again.',
+          console.log('Token has expired, refreshing');
+          setAuthState((prev) => ({ ...prev, loading: true }));
+          refreshAccessToken().then((refreshed) => {
+            if (!refreshed) {
+              localStorage.removeItem('authToken');
+              setToken(null);
+              setAuthState({
+                authenticated: false,
+                user: null,
+                loading: false,
+                error: 'Session has expired. Please sign in again.',
+              });
+            }
           });
         } else {
+          // Refresh a minute before the token expires
+          const refreshIn = Math.max((decoded.exp - currentTime - 60) * 1000, 0);
+          refreshTimer = setTimeout(refreshAccessToken, refreshIn);
+
           console.log('Token is valid');
           // Store user information including role in the state
           setAuthState({
@@ -46,6 +76,8 @@ export const AuthProvider = ({ children }) => {
               email: decoded.email,
               role: decoded.role, // Ensure your token includes this property
               associatedStudents: decoded.associated_students || [],
+              // Set when an admin is viewing the app as this user (read-only)
+              impersonator: decoded.impersonator || null,
             },
             loading: false,
             error: null,
@@ -71,10 +103,19 @@ export const AuthProvider = ({ children }) => {
         error: null,
       });
     }
-  }, [token]);
 
-  // Function to update the token state
+    return () => clearTimeout(refreshTimer);
+  }, [token, refreshAccessToken]);
+
+  // Function to update the token state. Clearing it signs out, which also
+  // ends the session on the server.
   const updateToken = (newToken) => {
+    if (!newToken) {
+      fetch(`${API_BASE_URL}/api/auth/logout`, {
+        method: 'POST',
+        credentials: 'include',
+      }).catch((error) => console.log('Logout request failed:', error));
+    }
     setToken(newToken);
   };
 